@@ -0,0 +1,109 @@
+// Multi-Model Authorization Microservice - ACL Patterns
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// aclRegexPrefix marks an ACL object or action as a regular expression, e.g.
+// "regex:^/reports/[0-9]+$". Tenant-scoped patterns keep it after the tenant prefix.
+const aclRegexPrefix = "regex:"
+
+var (
+	// aclParamPattern matches ":name" path segments, which match a single segment
+	aclParamPattern = regexp.MustCompile(`/:[^/]+`)
+
+	// aclPatterns caches compiled patterns by their source; invalid patterns are cached as nil
+	aclPatterns sync.Map
+)
+
+// registerACLFunctions registers the pattern matcher used by the ACL model
+func registerACLFunctions(enforcer casbin.IEnforcer) {
+	enforcer.AddFunction("aclMatch", func(args ...interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return false, fmt.Errorf("aclMatch: expected 2 arguments, got %d", len(args))
+		}
+		value, _ := args[0].(string)
+		pattern, _ := args[1].(string)
+		return aclPatternMatch(value, pattern), nil
+	})
+}
+
+// aclPatternMatch reports whether an ACL request field matches a rule field. Rule objects
+// and actions match exactly unless they are patterns: "*" matches any characters, a
+// "/:name" segment matches one path segment, and "regex:" introduces a regular expression
+// that must match the whole value.
+func aclPatternMatch(value, pattern string) bool {
+	if value == pattern {
+		return true
+	}
+	if !isACLPattern(pattern) {
+		return false
+	}
+	re := compileACLPattern(pattern)
+	return re != nil && re.MatchString(value)
+}
+
+// isACLPattern reports whether a rule field is a pattern rather than a literal name
+func isACLPattern(pattern string) bool {
+	_, _, isRegex := splitACLRegex(pattern)
+	return isRegex || strings.Contains(pattern, "*") || aclParamPattern.MatchString(pattern)
+}
+
+// splitACLRegex splits a regex pattern into the literal tenant prefix ("" or "<tenant>/")
+// and the expression
+func splitACLRegex(pattern string) (prefix, expr string, ok bool) {
+	if strings.HasPrefix(pattern, aclRegexPrefix) {
+		return "", pattern[len(aclRegexPrefix):], true
+	}
+	if i := strings.Index(pattern, "/"+aclRegexPrefix); i > 0 && tenantNamePattern.MatchString(pattern[:i]) {
+		return pattern[:i+1], pattern[i+1+len(aclRegexPrefix):], true
+	}
+	return "", "", false
+}
+
+// compileACLPattern translates a pattern to an anchored regular expression, returning nil
+// for invalid expressions
+func compileACLPattern(pattern string) *regexp.Regexp {
+	if cached, ok := aclPatterns.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+
+	var source string
+	if prefix, expr, ok := splitACLRegex(pattern); ok {
+		source = "^" + regexp.QuoteMeta(prefix) + "(?:" + expr + ")$"
+	} else {
+		// Quote the literal parts, then widen wildcards and path parameters
+		source = regexp.QuoteMeta(pattern)
+		source = strings.ReplaceAll(source, `\*`, ".*")
+		source = aclParamPattern.ReplaceAllLiteralString(source, "/[^/]+")
+		source = "^" + source + "$"
+	}
+
+	re, err := regexp.Compile(source)
+	if err != nil {
+		re = nil
+	}
+	aclPatterns.Store(pattern, re)
+	return re
+}
+
+// validateACLRule rejects ACL rules whose object or action is an invalid regular expression
+func validateACLRule(object, action string) error {
+	for field, pattern := range map[string]string{"object": object, "action": action} {
+		if _, expr, ok := splitACLRegex(pattern); ok {
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("invalid %s pattern %q: %v", field, pattern, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Multi-Model Authorization Microservice - ACL Pattern Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestACLPatterns_Matching(t *testing.T) {
+	cases := []struct {
+		value, pattern string
+		expected       bool
+	}{
+		{"/projects/p1/docs/d1", "/projects/*/docs/*", true},
+		{"/projects/p1/docs", "/projects/*/docs/*", false},
+		{"/projects/p1", "/projects/:id", true},
+		{"/projects/p1/docs", "/projects/:id", false},
+		{"anything", "*", true},
+		{"report.pdf", "report.pdf", true},
+		{"reportXpdf", "report.pdf", false}, // Literal parts are not regular expressions
+		{"doc:1", "doc:2", false},           // Colons outside path segments are literal
+		{"read", "regex:read|list", true},
+		{"readme", "regex:read|list", false}, // Expressions match the whole value
+		{"acme//reports/42", "acme/regex:/reports/[0-9]+", true},
+		{"other//reports/42", "acme/regex:/reports/[0-9]+", false},
+		{"anything", "regex:(", false},
+	}
+	for _, c := range cases {
+		if got := aclPatternMatch(c.value, c.pattern); got != c.expected {
+			t.Errorf("aclPatternMatch(%q, %q) = %v, expected %v", c.value, c.pattern, got, c.expected)
+		}
+	}
+}
+
+func TestACLPatterns_Enforcement(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/acl/policies", service.addACLPolicyHandler).Methods("POST")
+	api.HandleFunc("/acl/policies", service.deleteACLPolicyHandler).Methods("DELETE")
+
+	send := func(method, url, body string) int {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := send("POST", "/api/v1/acl/policies", `{"subject": "alice", "object": "/projects/*/docs/*", "action": "regex:read|list"}`); code != http.StatusCreated {
+		t.Fatalf("Failed to add pattern rule: %d", code)
+	}
+	send("POST", "/api/v1/acl/policies", `{"subject": "alice", "object": "/projects/secret/docs/*", "action": "*", "effect": "deny"}`)
+	if code := send("POST", "/api/v1/acl/policies", `{"subject": "alice", "object": "regex:[", "action": "read"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid expression, got %d", code)
+	}
+
+	checks := []struct {
+		object, action string
+		expected       bool
+	}{
+		{"/projects/p1/docs/d1", "read", true},
+		{"/projects/p1/docs/d1", "list", true},
+		{"/projects/p1/docs/d1", "write", false},
+		{"/projects/secret/docs/d1", "read", false}, // Pattern deny rules still win
+	}
+	for _, check := range checks {
+		if allowed, _ := service.Enforce(ModelACL, "alice", check.object, check.action, nil); allowed != check.expected {
+			t.Errorf("%s %s: expected %v", check.object, check.action, check.expected)
+		}
+	}
+
+	grants, err := service.ListSubjects("/projects/p1/docs/d1", "read", []AccessControlModel{ModelACL})
+	if err != nil || len(grants) != 1 || grants[0].Subject != "alice" {
+		t.Errorf("Expected alice to be listed through the pattern rule, got %v (%v)", grants, err)
+	}
+
+	query := url.Values{"subject": {"alice"}, "object": {"/projects/*/docs/*"}, "action": {"regex:read|list"}}
+	if code := send("DELETE", "/api/v1/acl/policies?"+query.Encode(), ""); code != http.StatusOK {
+		t.Fatalf("Failed to remove pattern rule: %d", code)
+	}
+	if allowed, _ := service.Enforce(ModelACL, "alice", "/projects/p1/docs/d1", "read", nil); allowed {
+		t.Error("Expected access to end with the pattern rule")
+	}
+}
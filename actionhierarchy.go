@@ -0,0 +1,345 @@
+// Multi-Model Authorization Microservice - Action Hierarchy
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// changeKindActionImplication is the change kind of action hierarchy entries in webhook events
+const changeKindActionImplication = "action_implication"
+
+// allActions stands for every action in the actions an action implies, e.g. "admin"
+// implying "*"
+const allActions = "*"
+
+// errActionImplicationNotFound is returned for operations on an action that implies nothing
+var errActionImplicationNotFound = errors.New("action implication not found")
+
+// ActionImplication stores the actions granting an action also grants, e.g. "write"
+// implies "read". Implications are transitive and apply to ACL and RBAC rules and ABAC
+// policies alike, but only to those allowing access: denying "write" does not deny "read".
+type ActionImplication struct {
+	Action    string    `json:"action" gorm:"primaryKey"`
+	Implies   []string  `json:"implies" gorm:"serializer:json"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// validate checks the action and the actions it implies
+func (implication *ActionImplication) validate() error {
+	var v validator
+	v.identifier("action", implication.Action)
+	if len(implication.Implies) == 0 {
+		v.fail("implies", "must name at least one action")
+	}
+	for i, implied := range implication.Implies {
+		field := fmt.Sprintf("implies[%d]", i)
+		v.identifier(field, implied)
+		if implied == implication.Action {
+			v.fail(field, "an action cannot imply itself")
+		}
+	}
+	if implication.Action == allActions {
+		v.fail("action", "%q cannot imply other actions", allActions)
+	}
+	return v.err()
+}
+
+// actionHierarchy holds the action implications in memory along with every action each
+// action implies directly or transitively, since matchers consult it for every rule
+type actionHierarchy struct {
+	mu           sync.RWMutex
+	implications map[string]*ActionImplication
+	implied      map[string]map[string]bool // Transitive closure by implying action
+	revision     uint64                     // Incremented on every change to invalidate cached decisions
+}
+
+// implies reports whether granting an action also grants another one
+func (ah *actionHierarchy) implies(granted, requested string) bool {
+	if ah == nil || granted == requested {
+		return false
+	}
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+	implied := ah.implied[granted]
+	return implied[requested] || implied[allActions]
+}
+
+// impliedBy returns every action an action implies, sorted
+func (ah *actionHierarchy) impliedBy(action string) []string {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+
+	actions := make([]string, 0, len(ah.implied[action]))
+	for implied := range ah.implied[action] {
+		actions = append(actions, implied)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// Revision returns the number of changes to the hierarchy so far
+func (ah *actionHierarchy) Revision() uint64 {
+	if ah == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&ah.revision)
+}
+
+// list returns the stored implications sorted by action
+func (ah *actionHierarchy) list() []ActionImplication {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+
+	implications := make([]ActionImplication, 0, len(ah.implications))
+	for _, implication := range ah.implications {
+		implications = append(implications, *implication)
+	}
+	sort.Slice(implications, func(i, j int) bool { return implications[i].Action < implications[j].Action })
+	return implications
+}
+
+// get returns the stored implication of an action
+func (ah *actionHierarchy) get(action string) (*ActionImplication, bool) {
+	ah.mu.RLock()
+	defer ah.mu.RUnlock()
+
+	implication, exists := ah.implications[action]
+	return implication, exists
+}
+
+// load replaces the implications held in memory with those stored in the database
+func (ah *actionHierarchy) load(db *gorm.DB) error {
+	var records []ActionImplication
+	if err := db.Find(&records).Error; err != nil {
+		return fmt.Errorf("failed to load action implications: %v", err)
+	}
+
+	implications := make(map[string]*ActionImplication, len(records))
+	for i := range records {
+		implications[records[i].Action] = &records[i]
+	}
+
+	ah.mu.Lock()
+	ah.replace(implications)
+	ah.mu.Unlock()
+	return nil
+}
+
+// set stores the actions an action implies, replacing those it implied before
+func (ah *actionHierarchy) set(db *gorm.DB, implication *ActionImplication) error {
+	if err := implication.validate(); err != nil {
+		return err
+	}
+
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+
+	implication.UpdatedAt = time.Now()
+	if existing, exists := ah.implications[implication.Action]; exists {
+		implication.CreatedAt = existing.CreatedAt
+	} else {
+		implication.CreatedAt = implication.UpdatedAt
+	}
+	if err := db.Save(implication).Error; err != nil {
+		return fmt.Errorf("failed to save action implication: %v", err)
+	}
+
+	implications := make(map[string]*ActionImplication, len(ah.implications)+1)
+	for action, existing := range ah.implications {
+		implications[action] = existing
+	}
+	implications[implication.Action] = implication
+	ah.replace(implications)
+	return nil
+}
+
+// remove deletes the implications of an action
+func (ah *actionHierarchy) remove(db *gorm.DB, action string) error {
+	ah.mu.Lock()
+	defer ah.mu.Unlock()
+
+	if _, exists := ah.implications[action]; !exists {
+		return errActionImplicationNotFound
+	}
+	if err := db.Delete(&ActionImplication{}, "action = ?", action).Error; err != nil {
+		return fmt.Errorf("failed to delete action implication: %v", err)
+	}
+
+	implications := make(map[string]*ActionImplication, len(ah.implications))
+	for existing, implication := range ah.implications {
+		if existing != action {
+			implications[existing] = implication
+		}
+	}
+	ah.replace(implications)
+	return nil
+}
+
+// replace installs implications and recomputes what every action implies. Cycles are
+// harmless: "read" implying "write" and "write" implying "read" makes them equivalent.
+// The caller holds the write lock.
+func (ah *actionHierarchy) replace(implications map[string]*ActionImplication) {
+	implied := make(map[string]map[string]bool, len(implications))
+	for action := range implications {
+		reached := make(map[string]bool)
+		pending := append([]string(nil), implications[action].Implies...)
+		for len(pending) > 0 {
+			next := pending[len(pending)-1]
+			pending = pending[:len(pending)-1]
+			if reached[next] {
+				continue
+			}
+			reached[next] = true
+			if implication, exists := implications[next]; exists {
+				pending = append(pending, implication.Implies...)
+			}
+		}
+		delete(reached, action)
+		implied[action] = reached
+	}
+
+	ah.implications = implications
+	ah.implied = implied
+	atomic.AddUint64(&ah.revision, 1)
+}
+
+// registerActionHierarchy loads the action hierarchy, registers the function the ACL and
+// RBAC matchers consult it with and applies it to the actions ABAC policies are scoped to
+func (s *AuthService) registerActionHierarchy(enforcers ...casbin.IEnforcer) error {
+	s.actionHierarchy = &actionHierarchy{}
+	if err := s.actionHierarchy.load(s.db); err != nil {
+		return err
+	}
+	s.policyEngine.actions = s.actionHierarchy
+	for _, enforcer := range enforcers {
+		s.addImpliedActionFunction(enforcer)
+	}
+	return nil
+}
+
+// addImpliedActionFunction registers the function the ACL and RBAC matchers check implied
+// actions with. Only allow rules grant the actions their action implies.
+func (s *AuthService) addImpliedActionFunction(enforcer casbin.IEnforcer) {
+	enforcer.AddFunction("impliedAction", func(args ...interface{}) (interface{}, error) {
+		if len(args) != 3 {
+			return false, fmt.Errorf("impliedAction: expected 3 arguments, got %d", len(args))
+		}
+		requested, _ := args[0].(string)
+		granted, _ := args[1].(string)
+		effect, _ := args[2].(string)
+		return effect != "deny" && s.actionHierarchy.implies(granted, requested), nil
+	})
+}
+
+// appliesToAction reports whether a policy is scoped to action, either directly or, for
+// allow policies, through an action in its list implying it
+func (pe *PolicyEngine) appliesToAction(policy *ABACPolicy, action string) bool {
+	if policy.AppliesToAction(action) {
+		return true
+	}
+	if policy.Effect != "allow" {
+		return false
+	}
+	for _, candidate := range policy.Actions {
+		if pe.actions.implies(candidate, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// getActionImplicationsHandler lists the action hierarchy
+func (s *AuthService) getActionImplicationsHandler(w http.ResponseWriter, r *http.Request) {
+	implications := s.actionHierarchy.list()
+
+	response := map[string]interface{}{
+		"actions": implications,
+		"count":   len(implications),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getActionImplicationHandler returns the actions an action implies directly and
+// transitively
+func (s *AuthService) getActionImplicationHandler(w http.ResponseWriter, r *http.Request) {
+	action := mux.Vars(r)["action"]
+	implication, exists := s.actionHierarchy.get(action)
+	if !exists {
+		writeJSONError(w, http.StatusNotFound, "Action implication not found")
+		return
+	}
+
+	response := map[string]interface{}{
+		"action":  implication,
+		"implied": s.actionHierarchy.impliedBy(action),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// putActionImplicationHandler sets the actions an action implies
+func (s *AuthService) putActionImplicationHandler(w http.ResponseWriter, r *http.Request) {
+	var implication ActionImplication
+	if err := json.NewDecoder(r.Body).Decode(&implication); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	implication.Action = mux.Vars(r)["action"]
+	if err := implication.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	_, existed := s.actionHierarchy.get(implication.Action)
+	if err := s.actionHierarchy.set(s.db, &implication); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.publishChange(changeKindActionImplication, map[bool]string{true: changeUpdated, false: changeAdded}[existed], implication.Action, &implication, actorFromRequest(r))
+
+	response := map[string]interface{}{
+		"message": "Action implication saved successfully",
+		"action":  implication,
+		"implied": s.actionHierarchy.impliedBy(implication.Action),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// deleteActionImplicationHandler removes the implications of an action
+func (s *AuthService) deleteActionImplicationHandler(w http.ResponseWriter, r *http.Request) {
+	action := mux.Vars(r)["action"]
+	err := s.actionHierarchy.remove(s.db, action)
+	if errors.Is(err, errActionImplicationNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Action implication not found")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.publishChange(changeKindActionImplication, changeRemoved, action, nil, actorFromRequest(r))
+
+	response := map[string]interface{}{
+		"message": "Action implication deleted successfully",
+		"removed": true,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,132 @@
+// Multi-Model Authorization Microservice - Action Hierarchy Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setupActionHierarchy(t *testing.T) (*AuthService, func(method, url, body string) (int, map[string]interface{})) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/actions", service.getActionImplicationsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/actions/{action}", service.getActionImplicationHandler).Methods("GET")
+	router.HandleFunc("/api/v1/actions/{action}", service.putActionImplicationHandler).Methods("PUT")
+	router.HandleFunc("/api/v1/actions/{action}", service.deleteActionImplicationHandler).Methods("DELETE")
+
+	return service, func(method, url, body string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var response map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr.Code, response
+	}
+}
+
+func TestActionHierarchy_AppliesToEveryModel(t *testing.T) {
+	service, send := setupActionHierarchy(t)
+	if err := service.enableDecisionCache(100, time.Minute); err != nil {
+		t.Fatalf("Failed to enable decision cache: %v", err)
+	}
+
+	service.aclEnforcer.AddPolicy("alice", "doc1", "write", "allow")
+	service.rbacEnforcer.AddPolicy("editor", "doc1", "write", "allow")
+	service.rbacEnforcer.AddGroupingPolicy("bob", "editor")
+	service.policyEngine.AddPolicy(&ABACPolicy{
+		ID: "engineers-write", Name: "Engineers write", Effect: "allow", Actions: []string{"write"},
+		Conditions: []PolicyCondition{{Type: "user", Field: "department", Operator: "eq", Value: "engineering"}},
+	})
+	service.saveUserAttribute("carol", "department", "engineering")
+
+	checks := []struct {
+		model         AccessControlModel
+		subject, verb string
+	}{
+		{ModelACL, "alice", "read"},
+		{ModelRBAC, "bob", "read"},
+		{ModelABAC, "carol", "read"},
+	}
+	for _, check := range checks {
+		if allowed, _ := service.Enforce(check.model, check.subject, "doc1", check.verb, nil); allowed {
+			t.Errorf("%s: expected %s to be denied before write implies read", check.model, check.verb)
+		}
+	}
+
+	if code, response := send("PUT", "/api/v1/actions/write", `{"implies": ["read"]}`); code != http.StatusOK {
+		t.Fatalf("Failed to set implication: %d %v", code, response)
+	}
+	// Cached denials are dropped once the hierarchy changes
+	for _, check := range checks {
+		if allowed, _ := service.Enforce(check.model, check.subject, "doc1", check.verb, nil); !allowed {
+			t.Errorf("%s: expected write to grant read", check.model)
+		}
+		if allowed, _ := service.Enforce(check.model, check.subject, "doc1", "delete", nil); allowed {
+			t.Errorf("%s: expected write not to grant delete", check.model)
+		}
+	}
+
+	// Deny rules stay exact: denying write does not deny read
+	service.aclEnforcer.AddPolicy("dave", "doc1", "read", "allow")
+	service.aclEnforcer.AddPolicy("dave", "doc1", "write", "deny")
+	if allowed, _ := service.Enforce(ModelACL, "dave", "doc1", "read", nil); !allowed {
+		t.Error("Expected a denied write not to deny read")
+	}
+
+	if code, _ := send("DELETE", "/api/v1/actions/write", ""); code != http.StatusOK {
+		t.Fatalf("Failed to remove implication: %d", code)
+	}
+	if allowed, _ := service.Enforce(ModelACL, "alice", "doc1", "read", nil); allowed {
+		t.Error("Expected read to be denied once the implication is removed")
+	}
+}
+
+func TestActionHierarchy_TransitiveAndWildcard(t *testing.T) {
+	service, send := setupActionHierarchy(t)
+	send("PUT", "/api/v1/actions/write", `{"implies": ["read", "comment"]}`)
+	send("PUT", "/api/v1/actions/admin", `{"implies": ["write", "*"]}`)
+	code, response := send("PUT", "/api/v1/actions/maintain", `{"implies": ["write"]}`)
+	if code != http.StatusOK {
+		t.Fatalf("Failed to set implication: %d %v", code, response)
+	}
+	if implied, _ := json.Marshal(response["implied"]); string(implied) != `["comment","read","write"]` {
+		t.Errorf("Expected maintain to imply write and what it implies, got %s", implied)
+	}
+
+	service.rbacEnforcer.AddPolicy("owner", "repo", "admin", "allow")
+	service.rbacEnforcer.AddGroupingPolicy("erin", "owner")
+	for _, action := range []string{"read", "write", "purge"} {
+		if allowed, _ := service.Enforce(ModelRBAC, "erin", "repo", action, nil); !allowed {
+			t.Errorf("Expected admin to grant %s", action)
+		}
+	}
+
+	for _, body := range []struct{ action, body string }{
+		{"read", `{"implies": []}`},
+		{"read", `{"implies": ["read"]}`},
+		{"*", `{"implies": ["read"]}`},
+	} {
+		if code, _ := send("PUT", "/api/v1/actions/"+body.action, body.body); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s %s, got %d", body.action, body.body, code)
+		}
+	}
+
+	// Implications are stored and survive a reload
+	if err := service.actionHierarchy.load(service.db); err != nil {
+		t.Fatalf("Failed to reload the hierarchy: %v", err)
+	}
+	if code, response := send("GET", "/api/v1/actions", ""); code != http.StatusOK || response["count"] != float64(3) {
+		t.Errorf("Expected 3 implications, got %d %v", code, response)
+	}
+	if !service.actionHierarchy.implies("maintain", "comment") || service.actionHierarchy.implies("read", "write") {
+		t.Error("Unexpected implications after reload")
+	}
+}
@@ -0,0 +1,255 @@
+// Multi-Model Authorization Microservice - ReBAC Custom Actions
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// changeKindAction is the change kind of custom action mappings in webhook events
+const changeKindAction = "rebac_action"
+
+// errActionNotFound is returned for operations on an action that is not registered
+var errActionNotFound = errors.New("action not found")
+
+// builtinActionPermissions maps the common action verbs to the permissions relationships
+// grant. Actions missing here and from the registry are checked as permissions themselves.
+var builtinActionPermissions = map[string]string{
+	"view":       "read",
+	"edit":       "write",
+	"update":     "write",
+	"modify":     "write",
+	"remove":     "delete",
+	"manage":     "admin",
+	"administer": "admin",
+}
+
+// ActionMapping registers a domain-specific action and the permission it is checked as in
+// ReBAC, e.g. "export" checked as "read". Registered actions take precedence over the
+// built-in verbs.
+type ActionMapping struct {
+	Action     string    `json:"action" gorm:"primaryKey"`
+	Permission string    `json:"permission"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// validate checks the action and permission names
+func (mapping *ActionMapping) validate() error {
+	var v validator
+	v.identifier("action", mapping.Action)
+	v.identifier("permission", mapping.Permission)
+	return v.err()
+}
+
+// loadActions loads the custom action mappings from the database
+func (rg *RelationshipGraph) loadActions() error {
+	var mappings []ActionMapping
+	if err := rg.db.Find(&mappings).Error; err != nil {
+		return fmt.Errorf("failed to load action mappings: %v", err)
+	}
+
+	actions := make(map[string]*ActionMapping, len(mappings))
+	for i := range mappings {
+		actions[mappings[i].Action] = &mappings[i]
+	}
+	rg.actions = actions
+	return nil
+}
+
+// reloadActions replaces the action mappings with the stored ones after another instance
+// changed them
+func (rg *RelationshipGraph) reloadActions() error {
+	unlock := rg.writeLock()
+	defer unlock()
+
+	if err := rg.loadActions(); err != nil {
+		return err
+	}
+	rg.bumpRevision()
+	return nil
+}
+
+// Actions returns the custom action mappings sorted by action
+func (rg *RelationshipGraph) Actions() []ActionMapping {
+	unlock := rg.readLock()
+	defer unlock()
+
+	mappings := make([]ActionMapping, 0, len(rg.actions))
+	for _, mapping := range rg.actions {
+		mappings = append(mappings, *mapping)
+	}
+	sort.Slice(mappings, func(i, j int) bool { return mappings[i].Action < mappings[j].Action })
+	return mappings
+}
+
+// Action returns the custom mapping of an action
+func (rg *RelationshipGraph) Action(action string) (*ActionMapping, bool) {
+	unlock := rg.readLock()
+	defer unlock()
+
+	mapping, exists := rg.actions[action]
+	return mapping, exists
+}
+
+// ActionPermission returns the permission an action is checked as
+func (rg *RelationshipGraph) ActionPermission(action string) string {
+	unlock := rg.readLock()
+	defer unlock()
+	return rg.mapActionToPermission(action)
+}
+
+// SetAction registers or replaces the mapping of an action
+func (rg *RelationshipGraph) SetAction(mapping *ActionMapping) error {
+	if err := mapping.validate(); err != nil {
+		return err
+	}
+
+	unlock := rg.writeLock()
+	defer unlock()
+
+	mapping.UpdatedAt = time.Now()
+	if existing, exists := rg.actions[mapping.Action]; exists {
+		mapping.CreatedAt = existing.CreatedAt
+	} else {
+		mapping.CreatedAt = mapping.UpdatedAt
+	}
+	if err := rg.db.Save(mapping).Error; err != nil {
+		return fmt.Errorf("failed to save action mapping: %v", err)
+	}
+
+	// Replace the map so checks holding the previous one are unaffected
+	actions := make(map[string]*ActionMapping, len(rg.actions)+1)
+	for action, existing := range rg.actions {
+		actions[action] = existing
+	}
+	actions[mapping.Action] = mapping
+	rg.actions = actions
+	rg.bumpRevision()
+	return rg.recordWrite()
+}
+
+// RemoveAction deletes the mapping of an action, returning it to the built-in mapping
+func (rg *RelationshipGraph) RemoveAction(action string) error {
+	unlock := rg.writeLock()
+	defer unlock()
+
+	if _, exists := rg.actions[action]; !exists {
+		return errActionNotFound
+	}
+	if err := rg.db.Delete(&ActionMapping{}, "action = ?", action).Error; err != nil {
+		return fmt.Errorf("failed to delete action mapping: %v", err)
+	}
+
+	actions := make(map[string]*ActionMapping, len(rg.actions))
+	for existing, mapping := range rg.actions {
+		if existing != action {
+			actions[existing] = mapping
+		}
+	}
+	rg.actions = actions
+	rg.bumpRevision()
+	return rg.recordWrite()
+}
+
+// getActionsHandler lists the custom action mappings along with the built-in ones
+func (s *AuthService) getActionsHandler(w http.ResponseWriter, r *http.Request) {
+	actions := s.relationshipGraph.Actions()
+
+	response := map[string]interface{}{
+		"actions": actions,
+		"count":   len(actions),
+		"builtin": builtinActionPermissions,
+		"model":   "rebac",
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getActionHandler returns the permission an action is checked as
+func (s *AuthService) getActionHandler(w http.ResponseWriter, r *http.Request) {
+	mapping, exists := s.relationshipGraph.Action(mux.Vars(r)["action"])
+	if !exists {
+		writeJSONError(w, http.StatusNotFound, "Action not found")
+		return
+	}
+
+	response := map[string]interface{}{
+		"action": mapping,
+		"model":  "rebac",
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// putActionHandler registers or replaces the mapping of an action
+func (s *AuthService) putActionHandler(w http.ResponseWriter, r *http.Request) {
+	var mapping ActionMapping
+	if err := json.NewDecoder(r.Body).Decode(&mapping); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	mapping.Action = mux.Vars(r)["action"]
+	if err := mapping.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	_, existed := s.relationshipGraph.Action(mapping.Action)
+	if err := s.relationshipGraph.SetAction(&mapping); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.publishChange(changeKindAction, map[bool]string{true: changeUpdated, false: changeAdded}[existed], mapping.Action, &mapping, actorFromRequest(r))
+
+	response := map[string]interface{}{
+		"message": "Action mapping saved successfully",
+		"action":  mapping,
+		"model":   "rebac",
+	}
+	s.relationshipGraph.addConsistencyToken(response)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// deleteActionHandler removes the mapping of an action
+func (s *AuthService) deleteActionHandler(w http.ResponseWriter, r *http.Request) {
+	action := mux.Vars(r)["action"]
+	err := s.relationshipGraph.RemoveAction(action)
+	if errors.Is(err, errActionNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Action not found")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.publishChange(changeKindAction, changeRemoved, action, nil, actorFromRequest(r))
+
+	response := map[string]interface{}{
+		"message": "Action mapping deleted successfully",
+		"removed": true,
+		"model":   "rebac",
+	}
+	s.relationshipGraph.addConsistencyToken(response)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// migrateActions creates the action mapping table
+func migrateActions(db *gorm.DB) error {
+	if err := db.AutoMigrate(&ActionMapping{}); err != nil {
+		return fmt.Errorf("failed to migrate action mapping table: %v", err)
+	}
+	return nil
+}
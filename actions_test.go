@@ -0,0 +1,77 @@
+// Multi-Model Authorization Microservice - ReBAC Custom Action Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestActions_CustomVerbsMapToPermissions(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/relationships/actions", service.getActionsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/relationships/actions/{action}", service.putActionHandler).Methods("PUT")
+	router.HandleFunc("/api/v1/relationships/actions/{action}", service.deleteActionHandler).Methods("DELETE")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	graph := service.relationshipGraph
+	graph.AddRelationship("alice", "viewer", "report")
+
+	if allowed, _ := graph.CheckReBACAccess("alice", "report", "export"); allowed {
+		t.Fatal("Expected an unregistered action to be checked as a permission of its own")
+	}
+
+	if rr := send("PUT", "/api/v1/relationships/actions/export", `{"permission": "read"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+	if allowed, _ := graph.CheckReBACAccess("alice", "report", "export"); !allowed {
+		t.Error("Expected export to be granted by the read permission of viewers")
+	}
+
+	// Registered actions take precedence over the built-in verbs
+	send("PUT", "/api/v1/relationships/actions/view", `{"permission": "approve"}`)
+	if allowed, _ := graph.CheckReBACAccess("alice", "report", "view"); allowed {
+		t.Error("Expected the registered view mapping to replace the built-in one")
+	}
+
+	var list struct {
+		Actions []ActionMapping   `json:"actions"`
+		Builtin map[string]string `json:"builtin"`
+	}
+	json.Unmarshal(send("GET", "/api/v1/relationships/actions", "").Body.Bytes(), &list)
+	if len(list.Actions) != 2 || list.Actions[0].Action != "export" || list.Builtin["edit"] != "write" {
+		t.Errorf("Expected the registered and built-in actions, got %+v", list)
+	}
+
+	// Mappings survive a restart
+	if err := graph.loadActions(); err != nil {
+		t.Fatalf("Failed to reload actions: %v", err)
+	}
+	if graph.ActionPermission("export") != "read" {
+		t.Error("Expected the export mapping to be persisted")
+	}
+
+	if rr := send("DELETE", "/api/v1/relationships/actions/view", ""); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	if allowed, _ := graph.CheckReBACAccess("alice", "report", "view"); !allowed {
+		t.Error("Expected view to return to the built-in mapping")
+	}
+	if rr := send("DELETE", "/api/v1/relationships/actions/view", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unregistered action, got %d", rr.Code)
+	}
+	if rr := send("PUT", "/api/v1/relationships/actions/export", `{"permission": ""}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a permission, got %d", rr.Code)
+	}
+}
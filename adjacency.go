@@ -0,0 +1,89 @@
+// Multi-Model Authorization Microservice - ReBAC Adjacency Index
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// adjacencyIndex lists the relations leaving each subject, so traversals expand a node by
+// looking up its subject:relationship buckets instead of scanning every bucket in the graph.
+// Like the reverse index it is maintained by addToMemory/removeFromMemory.
+type adjacencyIndex struct {
+	outgoing map[string]map[string]int // Subject to relationship to tuple multiplicity
+}
+
+// newAdjacencyIndex creates an empty adjacency index
+func newAdjacencyIndex() *adjacencyIndex {
+	return &adjacencyIndex{outgoing: make(map[string]map[string]int)}
+}
+
+// add indexes a tuple under its subject
+func (ai *adjacencyIndex) add(rel Relationship) {
+	relations, exists := ai.outgoing[rel.Subject]
+	if !exists {
+		relations = make(map[string]int)
+		ai.outgoing[rel.Subject] = relations
+	}
+	relations[rel.Relationship]++
+}
+
+// remove drops copies tuples of a subject:relationship bucket and frees emptied buckets
+func (ai *adjacencyIndex) remove(subject, relationship string, copies int) {
+	relations, exists := ai.outgoing[subject]
+	if !exists || copies == 0 {
+		return
+	}
+	relations[relationship] -= copies
+	if relations[relationship] <= 0 {
+		delete(relations, relationship)
+	}
+	if len(relations) == 0 {
+		delete(ai.outgoing, subject)
+	}
+}
+
+// relationsOf returns the relationships leaving subject in a stable order
+func (ai *adjacencyIndex) relationsOf(subject string) []string {
+	relations := ai.outgoing[subject]
+	result := make([]string, 0, len(relations))
+	for relationship := range relations {
+		result = append(result, relationship)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// outgoingFrom returns the resident tuples leaving subject, including duplicate copies,
+// grouped by relationship
+func (rg *RelationshipGraph) outgoingFrom(subject string) []Relationship {
+	var relationships []Relationship
+	for _, relationship := range rg.adjacency.relationsOf(subject) {
+		relationships = append(relationships, rg.relationships[fmt.Sprintf("%s:%s", subject, relationship)]...)
+	}
+	return relationships
+}
+
+// verifyAdjacency checks that the adjacency index mirrors the forward tuples exactly
+func (rg *RelationshipGraph) verifyAdjacency() error {
+	indexed := 0
+	for subject, relations := range rg.adjacency.outgoing {
+		if len(relations) == 0 {
+			return fmt.Errorf("empty adjacency bucket for %q", subject)
+		}
+		for relationship, count := range relations {
+			stored := len(rg.relationships[fmt.Sprintf("%s:%s", subject, relationship)])
+			if stored != count {
+				return fmt.Errorf("%s:%s indexed %d times but stored %d times", subject, relationship, count, stored)
+			}
+			indexed += count
+		}
+	}
+	if indexed != rg.reverse.size {
+		return fmt.Errorf("adjacency index holds %d tuples but reverse index holds %d", indexed, rg.reverse.size)
+	}
+	return nil
+}
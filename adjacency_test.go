@@ -0,0 +1,76 @@
+// Multi-Model Authorization Microservice - ReBAC Adjacency Index Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAdjacency_TraversalsUseSubjectIndex(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	rg, err := NewRelationshipGraph(db)
+	if err != nil {
+		t.Fatalf("Failed to create relationship graph: %v", err)
+	}
+
+	// Subjects may contain colons, which the old key scans had to special-case
+	rg.AddRelationship("alice", "owner", "folder:docs")
+	rg.AddRelationship("folder:docs", "parent", "doc:readme")
+	rg.AddRelationship("folder:docs", "parent", "doc:readme")
+	rg.AddRelationship("alice", "viewer", "doc:readme")
+	assertIndexConsistent(t, rg)
+
+	if relations := rg.adjacency.relationsOf("alice"); len(relations) != 2 || relations[0] != "owner" || relations[1] != "viewer" {
+		t.Errorf("Unexpected relations of alice: %v", relations)
+	}
+	if found, path := rg.FindRelationshipPath("alice", "doc:readme", 3); !found || path == "" {
+		t.Error("Expected a path from alice to the readme")
+	}
+	if direct := rg.GetDirectRelationships("folder:docs", "doc:readme"); len(direct) != 2 {
+		t.Errorf("Expected both copies of the parent tuple, got %+v", direct)
+	}
+	if listed, _ := rg.ListRelationships("alice"); len(listed) != 2 {
+		t.Errorf("Expected alice's 2 tuples, got %+v", listed)
+	}
+
+	rg.RemoveRelationship("folder:docs", "parent", "doc:readme")
+	assertIndexConsistent(t, rg)
+	if _, exists := rg.adjacency.outgoing["folder:docs"]; exists {
+		t.Error("Empty adjacency bucket should be freed")
+	}
+	if hops := rg.outgoingHops("folder:docs"); len(hops) != 0 {
+		t.Errorf("Expected no hops from the folder, got %+v", hops)
+	}
+}
+
+func BenchmarkRelationshipGraph_CheckLargeGraph(b *testing.B) {
+	db, err := setupTestDB()
+	if err != nil {
+		b.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	rg, err := NewRelationshipGraph(db)
+	if err != nil {
+		b.Fatalf("Failed to create relationship graph: %v", err)
+	}
+	rg.SetCheckCacheSize(0)
+
+	// Populate memory directly; a traversal's cost should not depend on the graph's size
+	for i := 0; i < 200000; i++ {
+		rg.addToMemory(fmt.Sprintf("user%d", i), "member", fmt.Sprintf("team%d", i%1000))
+		rg.addToMemory(fmt.Sprintf("team%d", i%1000), "viewer", fmt.Sprintf("doc%d", i))
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rg.CheckReBACAccess(fmt.Sprintf("user%d", i%1000), fmt.Sprintf("doc%d", i%200000), "read")
+	}
+}
@@ -262,7 +262,7 @@ func TestAPI_FullWorkflow(t *testing.T) {
 			{
 				model: ModelACL,
 				setup: func() {
-					service.aclEnforcer.AddPolicy("alice", "test_resource", "read")
+					service.aclEnforcer.AddPolicy("alice", "test_resource", "read", "allow")
 				},
 				expected: true,
 			},
@@ -270,7 +270,7 @@ func TestAPI_FullWorkflow(t *testing.T) {
 				model: ModelRBAC,
 				setup: func() {
 					service.rbacEnforcer.AddRoleForUser("alice", "reader")
-					service.rbacEnforcer.AddPolicy("reader", "test_resource", "read")
+					service.rbacEnforcer.AddPolicy("reader", "test_resource", "read", "allow")
 				},
 				expected: true,
 			},
@@ -369,6 +369,33 @@ func TestAPI_ErrorHandling(t *testing.T) {
 		}
 	})
 
+	t.Run("Invalid max_depth Values", func(t *testing.T) {
+		for _, value := range []string{"0", "-3", "abc"} {
+			req, _ := http.NewRequest("GET", "/api/v1/relationships/paths?subject=alice&object=document1&max_depth="+value, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("max_depth=%s: Expected status 400, got %d", value, rr.Code)
+			}
+		}
+
+		// Oversized depths are capped at the configured limit
+		req, _ := http.NewRequest("GET", "/api/v1/relationships/paths?subject=alice&object=document1&max_depth=1000000", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for capped max_depth, got %d", rr.Code)
+		}
+
+		var response map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		if response["max_depth"] != float64(defaultMaxDepthLimit) {
+			t.Errorf("Expected max_depth to be capped at %d, got %v", defaultMaxDepthLimit, response["max_depth"])
+		}
+	})
+
 	t.Run("Invalid Model Types", func(t *testing.T) {
 		invalidAuth := EnforceRequest{
 			Model:   "invalid_model",
@@ -0,0 +1,375 @@
+// Multi-Model Authorization Microservice - Change Approval Workflow
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"casbin-authorization-server/authzpb"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// maxChangeBodySize bounds the request bodies held for approval
+const maxChangeBodySize = 10 << 20
+
+// Statuses of change requests
+const (
+	changeStatusPending  = "pending"
+	changeStatusApplied  = "applied"  // Approved, and the request succeeded
+	changeStatusFailed   = "failed"   // Approved, but the request was rejected when applied
+	changeStatusRejected = "rejected" // Declined by a reviewer
+)
+
+// approvalRoutes lists the policy and relationship writes held for approval, keyed like
+// apiOperations
+var approvalRoutes = map[string]bool{
+	"POST /acl/policies":                                 true,
+	"DELETE /acl/policies":                               true,
+	"DELETE /acl/policies/{id}":                          true,
+	"POST /acl/policies/bulk":                            true,
+	"DELETE /acl/policies/bulk":                          true,
+	"POST /acl/policies/{id}/restore":                    true,
+	"POST /rbac/policies":                                true,
+	"DELETE /rbac/policies/{id}":                         true,
+	"POST /rbac/policies/bulk":                           true,
+	"DELETE /rbac/policies/bulk":                         true,
+	"POST /rbac/policies/{id}/restore":                   true,
+	"POST /rbac/roles/{roleId}/parents":                  true,
+	"DELETE /rbac/roles/{roleId}/parents/{parentId}":     true,
+	"POST /rbac/resources/{objectId}/groups":             true,
+	"DELETE /rbac/resources/{objectId}/groups/{groupId}": true,
+	"DELETE /users/{userId}":                             true,
+	"POST /users/{userId}/roles":                         true,
+	"DELETE /users/{userId}/roles/{roleId}":              true,
+	"POST /abac/policies":                                true,
+	"PUT /abac/policies/{id}":                            true,
+	"DELETE /abac/policies/{id}":                         true,
+	"POST /abac/policies/{id}/restore":                   true,
+	"POST /relationships":                                true,
+	"POST /relationships/bulk":                           true,
+	"DELETE /relationships/{id}":                         true,
+	"PUT /relationships/namespaces/{name}":               true,
+	"DELETE /relationships/namespaces/{name}":            true,
+	"PUT /relationships/actions/{action}":                true,
+	"DELETE /relationships/actions/{action}":             true,
+	"PUT /actions/{action}":                              true,
+	"DELETE /actions/{action}":                           true,
+	"POST /import":                                       true,
+	"POST /transactions":                                 true,
+	"POST /scim/v2/Users":                                true,
+	"PUT /scim/v2/Users/{id}":                            true,
+	"PATCH /scim/v2/Users/{id}":                          true,
+	"DELETE /scim/v2/Users/{id}":                         true,
+	"POST /scim/v2/Groups":                               true,
+	"PUT /scim/v2/Groups/{id}":                           true,
+	"PATCH /scim/v2/Groups/{id}":                         true,
+	"DELETE /scim/v2/Groups/{id}":                        true,
+	"POST /ldap/sync/run":                                true,
+}
+
+// approvalGRPCMethods lists the gRPC writes that approvalRoutes holds over HTTP. The gRPC
+// API cannot hold changes, so they are refused while changes need approval.
+var approvalGRPCMethods = map[string]bool{
+	authzpb.RBACService_AddPolicy_FullMethodName:           true,
+	authzpb.RBACService_RemovePolicy_FullMethodName:        true,
+	authzpb.RBACService_AddRoleForUser_FullMethodName:      true,
+	authzpb.RBACService_RemoveRoleForUser_FullMethodName:   true,
+	authzpb.ABACService_AddPolicy_FullMethodName:           true,
+	authzpb.ABACService_RemovePolicy_FullMethodName:        true,
+	authzpb.ReBACService_AddRelationship_FullMethodName:    true,
+	authzpb.ReBACService_RemoveRelationship_FullMethodName: true,
+}
+
+// ChangeRequest is a policy or relationship write waiting for, or decided by, a second
+// admin. The original request is stored and replayed once approved.
+type ChangeRequest struct {
+	ID          string          `json:"id" gorm:"primaryKey"`
+	Method      string          `json:"method"`
+	Path        string          `json:"path"`
+	Query       string          `json:"query,omitempty"`
+	Tenant      string          `json:"tenant,omitempty"` // X-Tenant header of the request
+	Body        json.RawMessage `json:"body,omitempty"`
+	Status      string          `json:"status" gorm:"index"`
+	RequestedBy string          `json:"requested_by"`
+	DecidedBy   string          `json:"decided_by,omitempty"`
+	Reason      string          `json:"reason,omitempty"`      // Why the change was rejected
+	ResultCode  int             `json:"result_code,omitempty"` // HTTP status of the applied request
+	Result      json.RawMessage `json:"result,omitempty"`      // Response of the applied request
+	CreatedAt   time.Time       `json:"created_at"`
+	DecidedAt   *time.Time      `json:"decided_at,omitempty"`
+}
+
+// RejectChangeRequest gives the reason for declining a change
+type RejectChangeRequest struct {
+	Reason string `json:"reason"`
+}
+
+// approvedChangeKey marks the context of a request replayed for an approved change
+type approvedChangeKey struct{}
+
+// changeRouterKey is the context key holding the router approved changes are replayed on
+type changeRouterKey struct{}
+
+// changeRecorder captures the response of a replayed change
+type changeRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (c *changeRecorder) Header() http.Header { return c.header }
+
+func (c *changeRecorder) Write(data []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	return c.body.Write(data)
+}
+
+func (c *changeRecorder) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+// approvalMiddleware holds the policy and relationship writes routed by api as pending
+// change requests instead of applying them. Other requests pass through, carrying api so
+// approvals can replay changes on it.
+func (s *AuthService) approvalMiddleware(api *mux.Router) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Context().Value(approvedChangeKey{}) == nil && requiresApproval(r) {
+				s.holdChange(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), changeRouterKey{}, api)))
+		})
+	}
+}
+
+// requiresApproval reports whether a request is a write held for approval
+func requiresApproval(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return false
+	}
+	return approvalRoutes[r.Method+" "+strings.TrimPrefix(template, "/api/v1")]
+}
+
+// holdChange stores a write as a pending change request
+func (s *AuthService) holdChange(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxChangeBodySize))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		body = nil
+	} else if !json.Valid(body) {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	change := ChangeRequest{
+		ID:          newRequestID(),
+		Method:      r.Method,
+		Path:        r.URL.Path,
+		Query:       r.URL.RawQuery,
+		Tenant:      r.Header.Get(tenantHeader),
+		Body:        body,
+		Status:      changeStatusPending,
+		RequestedBy: actorFromRequest(r),
+	}
+	if err := s.db.Create(&change).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to store change request: %v", err))
+		return
+	}
+	serviceMetrics.Inc("change_requests_total")
+
+	response := map[string]interface{}{
+		"message": "Change pending approval",
+		"change":  change,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(response)
+}
+
+// loadChange reads a change request, writing 404 when it does not exist
+func (s *AuthService) loadChange(w http.ResponseWriter, id string) (*ChangeRequest, bool) {
+	var change ChangeRequest
+	err := s.db.First(&change, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Change request not found")
+		return nil, false
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read change request: %v", err))
+		return nil, false
+	}
+	return &change, true
+}
+
+// decideChange moves a pending change to a decided status. It fails when the change was
+// decided concurrently.
+func (s *AuthService) decideChange(change *ChangeRequest, status, decidedBy, reason string) (bool, error) {
+	now := time.Now()
+	result := s.db.Model(&ChangeRequest{}).
+		Where("id = ? AND status = ?", change.ID, changeStatusPending).
+		Updates(map[string]interface{}{"status": status, "decided_by": decidedBy, "reason": reason, "decided_at": now})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to update change request: %v", result.Error)
+	}
+	change.Status, change.DecidedBy, change.Reason, change.DecidedAt = status, decidedBy, reason, &now
+	return result.RowsAffected == 1, nil
+}
+
+// listChangesHandler lists change requests, newest first, optionally by status
+func (s *AuthService) listChangesHandler(w http.ResponseWriter, r *http.Request) {
+	query := s.db.Order("created_at desc")
+	if status := r.URL.Query().Get("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	changes := make([]ChangeRequest, 0)
+	if err := query.Find(&changes).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list change requests: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"changes": changes,
+		"count":   len(changes),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getChangeHandler returns a change request
+func (s *AuthService) getChangeHandler(w http.ResponseWriter, r *http.Request) {
+	change, ok := s.loadChange(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(change)
+}
+
+// approveChangeHandler applies a pending change on behalf of its requester. The approver
+// must be a different admin.
+func (s *AuthService) approveChangeHandler(w http.ResponseWriter, r *http.Request) {
+	api, _ := r.Context().Value(changeRouterKey{}).(*mux.Router)
+	if api == nil {
+		writeJSONError(w, http.StatusConflict, "Change approval is disabled")
+		return
+	}
+
+	change, ok := s.loadChange(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	approver := actorFromRequest(r)
+	if approver == change.RequestedBy {
+		writeJSONError(w, http.StatusForbidden, "Changes must be approved by a different admin")
+		return
+	}
+	decided, err := s.decideChange(change, changeStatusApplied, approver, "")
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !decided {
+		writeJSONError(w, http.StatusConflict, "Change request is no longer pending")
+		return
+	}
+
+	// Replay the request as its requester; the approval context lets it through
+	target := change.Path
+	if change.Query != "" {
+		target += "?" + change.Query
+	}
+	ctx := context.WithValue(context.Background(), approvedChangeKey{}, change.ID)
+	replay, err := http.NewRequestWithContext(ctx, change.Method, target, bytes.NewReader(change.Body))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to replay change: %v", err))
+		return
+	}
+	replay.Header.Set("Content-Type", "application/json")
+	replay.Header.Set("X-Actor", change.RequestedBy)
+	if change.Tenant != "" {
+		replay.Header.Set(tenantHeader, change.Tenant)
+	}
+
+	recorder := &changeRecorder{header: make(http.Header)}
+	api.ServeHTTP(recorder, replay)
+
+	change.ResultCode = recorder.status
+	change.Result = json.RawMessage(bytes.TrimSpace(recorder.body.Bytes()))
+	if !json.Valid(change.Result) {
+		change.Result, _ = json.Marshal(string(change.Result))
+	}
+	if change.ResultCode >= http.StatusBadRequest {
+		change.Status = changeStatusFailed
+	}
+	if err := s.db.Model(&ChangeRequest{}).Where("id = ?", change.ID).
+		Updates(map[string]interface{}{"status": change.Status, "result_code": change.ResultCode, "result": change.Result}).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Change applied, but failed to record the result: %v", err))
+		return
+	}
+	serviceMetrics.Inc("change_requests_" + change.Status + "_total")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(change)
+}
+
+// rejectChangeHandler declines a pending change
+func (s *AuthService) rejectChangeHandler(w http.ResponseWriter, r *http.Request) {
+	var request RejectChangeRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
+	}
+	if err := request.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	change, ok := s.loadChange(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	decided, err := s.decideChange(change, changeStatusRejected, actorFromRequest(r), request.Reason)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !decided {
+		writeJSONError(w, http.StatusConflict, "Change request is no longer pending")
+		return
+	}
+	serviceMetrics.Inc("change_requests_rejected_total")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(change)
+}
@@ -0,0 +1,204 @@
+// Multi-Model Authorization Microservice - Change Approval Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"casbin-authorization-server/authzpb"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestApprovals_FourEyes(t *testing.T) {
+	service := setupTestService(t)
+	service.changeApproval = true
+	router := mux.NewRouter()
+	service.registerAdminRoutes(router.PathPrefix("/api/v1").Subrouter())
+
+	send := func(actor, method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Actor", actor)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	submit := func(actor, method, path, body string) ChangeRequest {
+		rr := send(actor, method, path, body)
+		var response struct {
+			Change ChangeRequest `json:"change"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		if rr.Code != http.StatusAccepted || response.Change.Status != changeStatusPending {
+			t.Fatalf("Expected %s %s to be held for approval, got %d %s", method, path, rr.Code, rr.Body.String())
+		}
+		return response.Change
+	}
+
+	change := submit("alice", "POST", "/api/v1/acl/policies", `{"subject": "bob", "object": "doc1", "action": "read"}`)
+	if allowed, _, _ := enforceRule(service.aclEnforcer, "bob", "doc1", "read"); allowed {
+		t.Fatal("Expected the rule to wait for approval")
+	}
+
+	if rr := send("alice", "POST", "/api/v1/changes/"+change.ID+"/approve", ""); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 when approving one's own change, got %d", rr.Code)
+	}
+	rr := send("carol", "POST", "/api/v1/changes/"+change.ID+"/approve", "")
+	var approved ChangeRequest
+	json.Unmarshal(rr.Body.Bytes(), &approved)
+	if rr.Code != http.StatusOK || approved.Status != changeStatusApplied || approved.DecidedBy != "carol" || approved.ResultCode != http.StatusCreated {
+		t.Fatalf("Unexpected approval: %d %s", rr.Code, rr.Body.String())
+	}
+	if allowed, _, _ := enforceRule(service.aclEnforcer, "bob", "doc1", "read"); !allowed {
+		t.Error("Expected the approved rule to be applied")
+	}
+	if revisions, _ := service.PolicyHistory(changeKindACL, "bob:doc1:read"); len(revisions) != 1 || revisions[0].Actor != "alice" {
+		t.Errorf("Expected the rule to be recorded as added by its requester, got %+v", revisions)
+	}
+	if rr := send("dave", "POST", "/api/v1/changes/"+change.ID+"/approve", ""); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 when approving a decided change, got %d", rr.Code)
+	}
+
+	change = submit("alice", "DELETE", "/api/v1/acl/policies/bob:doc1:read", "")
+	if rr := send("carol", "POST", "/api/v1/changes/"+change.ID+"/reject", `{"reason": "still needed"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Reject failed: %d %s", rr.Code, rr.Body.String())
+	}
+	if allowed, _, _ := enforceRule(service.aclEnforcer, "bob", "doc1", "read"); !allowed {
+		t.Error("Expected the rejected removal not to be applied")
+	}
+
+	rr = send("carol", "GET", "/api/v1/changes?status=rejected", "")
+	var list struct {
+		Changes []ChangeRequest `json:"changes"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &list)
+	if len(list.Changes) != 1 || list.Changes[0].Reason != "still needed" {
+		t.Errorf("Expected the rejected change to be listed, got %s", rr.Body.String())
+	}
+}
+
+func TestApprovals_FailedChange(t *testing.T) {
+	service := setupTestService(t)
+	service.changeApproval = true
+	router := mux.NewRouter()
+	service.registerAdminRoutes(router.PathPrefix("/api/v1").Subrouter())
+
+	req, _ := http.NewRequest("POST", "/api/v1/acl/policies", bytes.NewBufferString(`{"subject": "bob"}`))
+	req.Header.Set("X-Actor", "alice")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var held struct {
+		Change ChangeRequest `json:"change"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &held)
+
+	req, _ = http.NewRequest("POST", "/api/v1/changes/"+held.Change.ID+"/approve", nil)
+	req.Header.Set("X-Actor", "carol")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var change ChangeRequest
+	json.Unmarshal(rr.Body.Bytes(), &change)
+	if change.Status != changeStatusFailed || change.ResultCode != http.StatusBadRequest {
+		t.Errorf("Expected an invalid change to fail when applied, got %s", rr.Body.String())
+	}
+}
+
+func TestApprovals_GRPCWritesRefused(t *testing.T) {
+	service := setupTestService(t)
+	service.changeApproval = true
+	conn := setupTestGRPC(t, service)
+	ctx := context.Background()
+	rbac := authzpb.NewRBACServiceClient(conn)
+	rebac := authzpb.NewReBACServiceClient(conn)
+
+	if _, err := rbac.AddPolicy(ctx, &authzpb.PolicyRequest{Model: "acl", Subject: "bob", Object: "doc1", Action: "read"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for a policy write, got %v", err)
+	}
+	if _, err := rebac.AddRelationship(ctx, &authzpb.Relationship{Subject: "bob", Relationship: "owner", Object: "doc1"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for a relationship write, got %v", err)
+	}
+	if allowed, _, _ := enforceRule(service.aclEnforcer, "bob", "doc1", "read"); allowed {
+		t.Error("Expected the refused write not to be applied")
+	}
+
+	// Reads are not held
+	if _, err := rbac.ListPolicies(ctx, &authzpb.ListPoliciesRequest{Model: "acl"}); err != nil {
+		t.Errorf("Expected reads to pass, got %v", err)
+	}
+}
+
+func TestApprovals_HeldWrites(t *testing.T) {
+	service := setupTestService(t)
+	service.changeApproval = true
+	router := mux.NewRouter()
+	service.registerAdminRoutes(router.PathPrefix("/api/v1").Subrouter())
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{"POST", "/api/v1/scim/v2/Users", `{"userName": "bob"}`},
+		{"PUT", "/api/v1/scim/v2/Users/u1", `{"userName": "bob"}`},
+		{"PATCH", "/api/v1/scim/v2/Users/u1", `{"Operations": [{"op": "replace", "path": "active", "value": false}]}`},
+		{"DELETE", "/api/v1/scim/v2/Users/u1", ""},
+		{"POST", "/api/v1/scim/v2/Groups", `{"displayName": "editors"}`},
+		{"PUT", "/api/v1/scim/v2/Groups/g1", `{"displayName": "editors"}`},
+		{"PATCH", "/api/v1/scim/v2/Groups/g1", `{"Operations": [{"op": "add", "path": "members", "value": [{"value": "u1"}]}]}`},
+		{"DELETE", "/api/v1/scim/v2/Groups/g1", ""},
+		{"POST", "/api/v1/ldap/sync/run", ""},
+		{"PUT", "/api/v1/relationships/actions/export", `{"permission": "read"}`},
+		{"DELETE", "/api/v1/relationships/actions/export", ""},
+		{"PUT", "/api/v1/actions/write", `{"implies": ["read"]}`},
+		{"DELETE", "/api/v1/actions/write", ""},
+	}
+
+	send := func(actor, method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("X-Actor", actor)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	var first string
+	for _, tt := range tests {
+		rr := send("alice", tt.method, tt.path, tt.body)
+		var held struct {
+			Change ChangeRequest `json:"change"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &held)
+		if rr.Code != http.StatusAccepted || held.Change.Status != changeStatusPending {
+			t.Errorf("Expected %s %s to be held for approval, got %d %s", tt.method, tt.path, rr.Code, rr.Body.String())
+		}
+		if first == "" {
+			first = held.Change.ID
+		}
+	}
+
+	var count int64
+	service.db.Model(&SCIMUser{}).Count(&count)
+	if count != 0 {
+		t.Fatalf("Expected no user to be provisioned before approval, got %d", count)
+	}
+	rr := send("carol", "POST", "/api/v1/changes/"+first+"/approve", "")
+	var approved ChangeRequest
+	json.Unmarshal(rr.Body.Bytes(), &approved)
+	if approved.Status != changeStatusApplied || approved.ResultCode != http.StatusCreated {
+		t.Fatalf("Expected the approved user to be provisioned, got %s", rr.Body.String())
+	}
+	service.db.Model(&SCIMUser{}).Count(&count)
+	if count != 1 {
+		t.Errorf("Expected the approved user to be stored, got %d users", count)
+	}
+}
@@ -0,0 +1,224 @@
+// Multi-Model Authorization Microservice - ABAC Attribute Cache
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultAttributeCacheSize is the number of users (or objects) whose attributes are kept in memory
+	defaultAttributeCacheSize = 100000
+
+	// defaultAttributeCacheTTL bounds how long cached attributes are served before being reloaded
+	defaultAttributeCacheTTL = 5 * time.Minute
+)
+
+// attributeCache is a size-bounded LRU cache of attribute sets with a TTL. Entries are
+// loaded from the database on first use, including empty sets so that lookups of
+// entities without attributes do not hit the database every time.
+type attributeCache struct {
+	mu       sync.Mutex
+	kind     string // "user" or "object", used in metric names
+	capacity int
+	ttl      time.Duration
+	order    *list.List               // Front is the most recently used entry
+	entries  map[string]*list.Element // Entity ID to LRU element
+
+	// generation counts invalidations, so a load that overlapped one is not cached
+	generation uint64
+}
+
+// attributeCacheEntry holds the cached attributes of one user or object
+type attributeCacheEntry struct {
+	id         string
+	attributes map[string]string
+	expires    time.Time
+}
+
+// AttributeCacheStats describes the state of an attribute cache
+type AttributeCacheStats struct {
+	Entries  int    `json:"entries"`
+	Capacity int    `json:"capacity"`
+	TTL      string `json:"ttl"`
+}
+
+// newAttributeCache creates an empty attribute cache
+func newAttributeCache(kind string, capacity int, ttl time.Duration) *attributeCache {
+	return &attributeCache{
+		kind:     kind,
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// attributeCachesFromEnv builds the user and object attribute caches from
+// ABAC_ATTRIBUTE_CACHE_SIZE and ABAC_ATTRIBUTE_CACHE_TTL. A size of 0 disables caching.
+func attributeCachesFromEnv() (*attributeCache, *attributeCache, error) {
+	size, ttl, err := attributeCacheConfigFromEnv()
+	if err != nil || size == 0 {
+		return nil, nil, err
+	}
+	return newAttributeCache("user", size, ttl), newAttributeCache("object", size, ttl), nil
+}
+
+// attributeCacheConfigFromEnv reads ABAC_ATTRIBUTE_CACHE_SIZE and ABAC_ATTRIBUTE_CACHE_TTL
+func attributeCacheConfigFromEnv() (int, time.Duration, error) {
+	size := defaultAttributeCacheSize
+	if sizeStr := os.Getenv("ABAC_ATTRIBUTE_CACHE_SIZE"); sizeStr != "" {
+		parsed, err := strconv.Atoi(sizeStr)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("invalid ABAC_ATTRIBUTE_CACHE_SIZE value: %s", sizeStr)
+		}
+		size = parsed
+	}
+
+	ttl := defaultAttributeCacheTTL
+	if ttlStr := os.Getenv("ABAC_ATTRIBUTE_CACHE_TTL"); ttlStr != "" {
+		parsed, err := time.ParseDuration(ttlStr)
+		if err != nil || parsed <= 0 {
+			return 0, 0, fmt.Errorf("invalid ABAC_ATTRIBUTE_CACHE_TTL value: %s", ttlStr)
+		}
+		ttl = parsed
+	}
+
+	return size, ttl, nil
+}
+
+// get returns a copy of the attributes of id, calling load on a miss or after expiry
+func (ac *attributeCache) get(id string, load func(string) (map[string]string, error)) (map[string]string, error) {
+	return ac.getAt(id, time.Now(), load)
+}
+
+// getAt is get with an explicit clock
+func (ac *attributeCache) getAt(id string, now time.Time, load func(string) (map[string]string, error)) (map[string]string, error) {
+	ac.mu.Lock()
+	if elem, exists := ac.entries[id]; exists {
+		entry := elem.Value.(*attributeCacheEntry)
+		if now.Before(entry.expires) {
+			ac.order.MoveToFront(elem)
+			attributes := copyAttributes(entry.attributes)
+			ac.mu.Unlock()
+			serviceMetrics.Inc("abac_" + ac.kind + "_attribute_cache_hits_total")
+			return attributes, nil
+		}
+		ac.order.Remove(elem)
+		delete(ac.entries, id)
+		serviceMetrics.Inc("abac_" + ac.kind + "_attribute_cache_expirations_total")
+	}
+	generation := ac.generation
+	ac.mu.Unlock()
+
+	serviceMetrics.Inc("abac_" + ac.kind + "_attribute_cache_misses_total")
+	attributes, err := load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	// The attributes may have changed after they were read; the next read reloads them
+	if ac.generation != generation {
+		return attributes, nil
+	}
+
+	// A concurrent load may have filled the entry meanwhile; keep the newer copy
+	if elem, exists := ac.entries[id]; exists {
+		ac.order.Remove(elem)
+	}
+	ac.entries[id] = ac.order.PushFront(&attributeCacheEntry{
+		id:         id,
+		attributes: copyAttributes(attributes),
+		expires:    now.Add(ac.ttl),
+	})
+
+	for ac.order.Len() > ac.capacity {
+		oldest := ac.order.Back()
+		ac.order.Remove(oldest)
+		delete(ac.entries, oldest.Value.(*attributeCacheEntry).id)
+		serviceMetrics.Inc("abac_" + ac.kind + "_attribute_cache_evictions_total")
+	}
+
+	return attributes, nil
+}
+
+// invalidate drops the cached attributes of id so the next read reloads them
+func (ac *attributeCache) invalidate(id string) {
+	if ac == nil {
+		return
+	}
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	ac.generation++
+	if elem, exists := ac.entries[id]; exists {
+		ac.order.Remove(elem)
+		delete(ac.entries, id)
+	}
+}
+
+// invalidateUserAttributes drops the cached attributes of a user after they changed, along
+// with the cached decisions that may depend on them
+func (s *AuthService) invalidateUserAttributes(userID string) {
+	s.userAttrs.invalidate(userID)
+	atomic.AddUint64(&s.attributeRevision, 1)
+}
+
+// invalidateObjectAttributes drops the cached attributes of an object after they changed,
+// along with the cached decisions that may depend on them
+func (s *AuthService) invalidateObjectAttributes(objectID string) {
+	s.objectAttrs.invalidate(objectID)
+	atomic.AddUint64(&s.attributeRevision, 1)
+}
+
+// resize changes the capacity and TTL of the cache, evicting the least recently used
+// entries beyond the new capacity. Cached entries keep their expiry.
+func (ac *attributeCache) resize(capacity int, ttl time.Duration) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	ac.capacity, ac.ttl = capacity, ttl
+	for ac.order.Len() > ac.capacity {
+		oldest := ac.order.Back()
+		ac.order.Remove(oldest)
+		delete(ac.entries, oldest.Value.(*attributeCacheEntry).id)
+		serviceMetrics.Inc("abac_" + ac.kind + "_attribute_cache_evictions_total")
+	}
+}
+
+// Stats reports the size and configuration of the cache
+func (ac *attributeCache) Stats() AttributeCacheStats {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	return AttributeCacheStats{Entries: ac.order.Len(), Capacity: ac.capacity, TTL: ac.ttl.String()}
+}
+
+// copyAttributes returns a copy of an attribute set so callers cannot modify cached data
+func copyAttributes(attributes map[string]string) map[string]string {
+	copied := make(map[string]string, len(attributes))
+	for k, v := range attributes {
+		copied[k] = v
+	}
+	return copied
+}
+
+// getUserAttributes returns the attributes of a user through the cache
+func (s *AuthService) getUserAttributes(userID string) (map[string]string, error) {
+	if s.userAttrs == nil {
+		return s.getUserAttributesFromDB(userID)
+	}
+	return s.userAttrs.get(userID, s.getUserAttributesFromDB)
+}
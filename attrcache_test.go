@@ -0,0 +1,107 @@
+// Multi-Model Authorization Microservice - ABAC Attribute Cache Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttributeCache_LRUAndTTL(t *testing.T) {
+	cache := newAttributeCache("test", 2, time.Minute)
+	loads := 0
+	load := func(id string) (map[string]string, error) {
+		loads++
+		return map[string]string{"id": id}, nil
+	}
+
+	now := time.Now()
+	cache.getAt("alice", now, load)
+	cache.getAt("bob", now, load)
+	attrs, _ := cache.getAt("alice", now, load)
+	if loads != 2 || attrs["id"] != "alice" {
+		t.Fatalf("Expected cached read for alice, loads=%d attrs=%v", loads, attrs)
+	}
+
+	// Callers get copies, so they cannot change cached data
+	attrs["id"] = "mallory"
+	if again, _ := cache.getAt("alice", now, load); again["id"] != "alice" {
+		t.Error("Cached attributes were modified through a returned map")
+	}
+
+	// carol evicts bob, the least recently used entry
+	evictions := serviceMetrics.Get("abac_test_attribute_cache_evictions_total")
+	cache.getAt("carol", now, load)
+	if cache.Stats().Entries != 2 || serviceMetrics.Get("abac_test_attribute_cache_evictions_total") != evictions+1 {
+		t.Errorf("Expected capacity to be enforced, got %+v", cache.Stats())
+	}
+	cache.getAt("bob", now, load)
+	if loads != 4 {
+		t.Errorf("Expected evicted entry to be reloaded, loads=%d", loads)
+	}
+
+	// Entries are reloaded once their TTL has passed
+	cache.getAt("bob", now.Add(2*time.Minute), load)
+	if loads != 5 {
+		t.Errorf("Expected expired entry to be reloaded, loads=%d", loads)
+	}
+}
+
+func TestAttributeCache_InvalidatedOnWrites(t *testing.T) {
+	service := setupTestService(t)
+
+	service.saveUserAttribute("alice", "department", "engineering")
+	if attrs, _ := service.getUserAttributes("alice"); attrs["department"] != "engineering" {
+		t.Fatalf("Unexpected attributes: %v", attrs)
+	}
+
+	service.saveUserAttribute("alice", "department", "sales")
+	if attrs, _ := service.getUserAttributes("alice"); attrs["department"] != "sales" {
+		t.Errorf("Expected write to invalidate the cached attributes, got %v", attrs)
+	}
+
+	// Objects without attributes are cached as empty and reported as nil
+	if attrs := service.getObjectAttributes("doc1"); attrs != nil {
+		t.Errorf("Expected no attributes, got %v", attrs)
+	}
+	service.saveObjectAttribute("doc1", "classification", "internal")
+	if attrs := service.getObjectAttributes("doc1"); attrs["classification"] != "internal" {
+		t.Errorf("Expected write to invalidate the cached empty set, got %v", attrs)
+	}
+
+	// With caching disabled reads go straight to the database
+	service.userAttrs, service.objectAttrs = nil, nil
+	if attrs, _ := service.getUserAttributes("alice"); attrs["department"] != "sales" {
+		t.Errorf("Expected uncached read, got %v", attrs)
+	}
+	service.saveObjectAttribute("doc1", "classification", "public")
+	if attrs := service.getObjectAttributes("doc1"); attrs["classification"] != "public" {
+		t.Errorf("Expected uncached read, got %v", attrs)
+	}
+}
+
+func TestAttributeCache_InvalidationDuringLoad(t *testing.T) {
+	cache := newAttributeCache("test", 10, time.Minute)
+	clearance := "secret"
+	load := func(id string) (map[string]string, error) {
+		read := map[string]string{"clearance": clearance}
+		// The attribute is revoked after it was read, before the load returns
+		clearance = "none"
+		cache.invalidate(id)
+		return read, nil
+	}
+
+	if attrs, _ := cache.get("alice", load); attrs["clearance"] != "secret" {
+		t.Fatalf("Expected the loaded attributes, got %v", attrs)
+	}
+	if cache.Stats().Entries != 0 {
+		t.Fatal("Expected attributes invalidated during the load not to be cached")
+	}
+	if attrs, _ := cache.get("alice", func(string) (map[string]string, error) {
+		return map[string]string{"clearance": clearance}, nil
+	}); attrs["clearance"] != "none" {
+		t.Errorf("Expected the revoked attribute to be reloaded, got %v", attrs)
+	}
+}
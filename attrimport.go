@@ -0,0 +1,335 @@
+// Multi-Model Authorization Microservice - ABAC Attribute Import
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxAttributeImportRows bounds the rows of one attribute import
+	maxAttributeImportRows = 100000
+	// maxAttributeImportBytes bounds the body of an attribute import
+	maxAttributeImportBytes = 64 << 20
+	// maxAttributeImportErrors bounds the row errors listed in an import response
+	maxAttributeImportErrors = 1000
+)
+
+// Formats of attribute imports
+const (
+	attributeImportCSV    = "csv"
+	attributeImportNDJSON = "ndjson"
+)
+
+// AttributeImportRow is one attribute of an import: a CSV record under an
+// "id,attribute,value[,type]" header, or an NDJSON line
+type AttributeImportRow struct {
+	ID        string `json:"id"` // User or object the attribute belongs to
+	Attribute string `json:"attribute"`
+	Value     string `json:"value"`
+	Type      string `json:"type,omitempty"` // Value type; the type the name already has when empty
+	line      int
+}
+
+// AttributeImportError reports a row that was not imported
+type AttributeImportError struct {
+	Line      int    `json:"line"` // Line of the row in the file, counting the CSV header
+	ID        string `json:"id,omitempty"`
+	Attribute string `json:"attribute,omitempty"`
+	Error     string `json:"error"`
+}
+
+// errTooManyImportRows is returned when an import holds more than maxAttributeImportRows rows
+var errTooManyImportRows = fmt.Errorf("at most %d attribute rows can be imported at once", maxAttributeImportRows)
+
+// validate checks the names and value of a row
+func (row *AttributeImportRow) validate() error {
+	var v validator
+	v.identifier("id", row.ID)
+	v.attributeName("attribute", row.Attribute)
+	v.text("value", row.Value)
+	return v.err()
+}
+
+// parseAttributeCSV reads attribute rows from CSV with a header naming the id, attribute,
+// value and optional type columns in any order. Records with the wrong number of fields
+// are returned as row errors.
+func parseAttributeCSV(body io.Reader) ([]AttributeImportRow, []AttributeImportError, error) {
+	reader := csv.NewReader(body)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	columns := map[string]int{"type": -1}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "id", "attribute", "value", "type":
+			columns[name] = i
+		default:
+			return nil, nil, fmt.Errorf("unknown CSV column %q; columns are id, attribute, value and type", name)
+		}
+	}
+	for _, required := range []string{"id", "attribute", "value"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("CSV header is missing the %s column", required)
+		}
+	}
+
+	var rows []AttributeImportRow
+	var failed []AttributeImportError
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount) {
+			failed = append(failed, AttributeImportError{Line: parseErr.StartLine, Error: fmt.Sprintf("has %d fields, the header has %d", len(record), len(header))})
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if len(rows)+len(failed) >= maxAttributeImportRows {
+			return nil, nil, errTooManyImportRows
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := AttributeImportRow{ID: record[columns["id"]], Attribute: record[columns["attribute"]], Value: record[columns["value"]], line: line}
+		if columns["type"] >= 0 {
+			row.Type = record[columns["type"]]
+		}
+		rows = append(rows, row)
+	}
+	return rows, failed, nil
+}
+
+// parseAttributeNDJSON reads attribute rows from newline-delimited JSON objects. Blank
+// lines are skipped and lines that are not rows are returned as row errors.
+func parseAttributeNDJSON(body io.Reader) ([]AttributeImportRow, []AttributeImportError, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxAttributeValueLength+64*1024)
+
+	var rows []AttributeImportRow
+	var failed []AttributeImportError
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if len(rows)+len(failed) >= maxAttributeImportRows {
+			return nil, nil, errTooManyImportRows
+		}
+		var row AttributeImportRow
+		if err := json.Unmarshal([]byte(text), &row); err != nil {
+			failed = append(failed, AttributeImportError{Line: line, Error: "invalid JSON"})
+			continue
+		}
+		row.line = line
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("invalid NDJSON: %w", err)
+	}
+	return rows, failed, nil
+}
+
+// importUserAttributesHandler imports user attributes from CSV or NDJSON
+func (s *AuthService) importUserAttributesHandler(w http.ResponseWriter, r *http.Request) {
+	s.importAttributes(w, r, "user")
+}
+
+// importObjectAttributesHandler imports object attributes from CSV or NDJSON
+func (s *AuthService) importObjectAttributesHandler(w http.ResponseWriter, r *http.Request) {
+	s.importAttributes(w, r, "object")
+}
+
+// importAttributes sets the attributes of many users or objects at once. The body is CSV
+// (format=csv or a text/csv content type) or NDJSON. Every row is checked like a single
+// attribute write; rows that fail are reported with their line and skipped, and the others
+// are saved in one database transaction.
+func (s *AuthService) importAttributes(w http.ResponseWriter, r *http.Request, kind string) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = attributeImportNDJSON
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+			format = attributeImportCSV
+		}
+	}
+
+	body := http.MaxBytesReader(w, r.Body, maxAttributeImportBytes)
+	var rows []AttributeImportRow
+	var failed []AttributeImportError
+	var err error
+	switch format {
+	case attributeImportCSV:
+		rows, failed, err = parseAttributeCSV(body)
+	case attributeImportNDJSON:
+		rows, failed, err = parseAttributeNDJSON(body)
+	default:
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("format must be '%s' or '%s'", attributeImportCSV, attributeImportNDJSON))
+		return
+	}
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("imports must be at most %d bytes", maxAttributeImportBytes))
+		return
+	case errors.Is(err, errTooManyImportRows):
+		writeJSONError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case err != nil:
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+	quota, err := s.scopeQuota(scope)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	definitions, err := s.attributeDefinitions(kind)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	// Rows are resolved in file order. Names without a type in the file or the database
+	// take the type of their first row, which also saves a lookup per row.
+	var accepted []AttributeImportRow
+	var warnings []string
+	types := make(map[string]string)
+	held := make(map[string]map[string]bool) // Attributes of each ID, for quotas
+	reject := func(row AttributeImportRow, err error) {
+		failed = append(failed, AttributeImportError{Line: row.line, ID: row.ID, Attribute: row.Attribute, Error: err.Error()})
+	}
+	for _, row := range rows {
+		if err := row.validate(); err != nil {
+			reject(row, err)
+			continue
+		}
+		row.ID = scope.qualify(row.ID)
+
+		if definition, exists := definitions[row.Attribute]; len(definitions) > 0 {
+			problem := fmt.Sprintf("unknown %s attribute %q", kind, row.Attribute)
+			if exists {
+				problem = definition.checkValue(row.Value)
+			}
+			if problem != "" && s.schemaEnforce {
+				reject(row, errors.New("attribute schema violation: "+problem))
+				continue
+			}
+			if problem != "" {
+				warnings = append(warnings, fmt.Sprintf("line %d: %s", row.line, problem))
+			}
+		}
+
+		requested := row.Type
+		if requested == "" {
+			requested = types[row.Attribute]
+		} else if known := types[row.Attribute]; known != "" && known != requested {
+			reject(row, fmt.Errorf("%s attribute %q is a %s in an earlier row, not a %s", kind, row.Attribute, known, requested))
+			continue
+		}
+		valueType, value, err := s.resolveAttributeValue(kind, row.Attribute, row.Value, requested)
+		if err != nil {
+			reject(row, err)
+			continue
+		}
+		types[row.Attribute] = valueType
+		row.Type, row.Value = valueType, value
+
+		if limit := quota.MaxAttributesPerSubject; limit > 0 {
+			if held[row.ID] == nil {
+				names, err := s.attributeNamesOf(kind, row.ID)
+				if err != nil {
+					writeJSONError(w, http.StatusInternalServerError, err.Error())
+					return
+				}
+				held[row.ID] = make(map[string]bool, len(names))
+				for _, name := range names {
+					held[row.ID][name] = true
+				}
+			}
+			if !held[row.ID][row.Attribute] && len(held[row.ID]) >= limit {
+				serviceMetrics.Inc("quota_rejections_total")
+				reject(row, fmt.Errorf("%s already has the %d attributes the tenant quota allows", kind, limit))
+				continue
+			}
+			held[row.ID][row.Attribute] = true
+		}
+		accepted = append(accepted, row)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		for _, row := range accepted {
+			save := saveUserAttributeIn
+			if kind == "object" {
+				save = saveObjectAttributeIn
+			}
+			if err := save(tx, row.ID, row.Attribute, row.Value, row.Type); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Import rolled back: %v", err))
+		return
+	}
+
+	changeKind := changeKindUserAttribute
+	if kind == "object" {
+		changeKind = changeKindObjectAttribute
+	}
+	actor := actorFromRequest(r)
+	invalidated := make(map[string]bool)
+	for _, row := range accepted {
+		s.attributeTypes.set(kind, row.Attribute, row.Type)
+		if !invalidated[row.ID] {
+			invalidated[row.ID] = true
+			if kind == "object" {
+				s.invalidateObjectAttributes(row.ID)
+			} else {
+				s.invalidateUserAttributes(row.ID)
+			}
+		}
+		s.publishChange(changeKind, changeUpdated, row.ID+"."+row.Attribute, attributeChange(kind, row.ID, row.Attribute, row.Value), actor)
+	}
+
+	sort.SliceStable(failed, func(i, j int) bool { return failed[i].Line < failed[j].Line })
+	response := map[string]interface{}{
+		"imported": len(accepted),
+		"failed":   len(failed),
+		"model":    "abac",
+	}
+	if len(failed) > maxAttributeImportErrors {
+		response["errors_truncated"] = true
+		failed = failed[:maxAttributeImportErrors]
+	}
+	response["errors"] = failed
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,98 @@
+// Multi-Model Authorization Microservice - ABAC Attribute Import Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type importResult struct {
+	Imported int                    `json:"imported"`
+	Failed   int                    `json:"failed"`
+	Errors   []AttributeImportError `json:"errors"`
+}
+
+func setupAttributeImport(t *testing.T) (*AuthService, func(url, contentType, body string) (int, importResult)) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/users/attributes/import", service.importUserAttributesHandler).Methods("POST")
+	router.HandleFunc("/api/v1/objects/attributes/import", service.importObjectAttributesHandler).Methods("POST")
+
+	return service, func(url, contentType, body string) (int, importResult) {
+		req, _ := http.NewRequest("POST", url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var response importResult
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr.Code, response
+	}
+}
+
+func TestAttributeImport_CSVReportsFailedRows(t *testing.T) {
+	service, send := setupAttributeImport(t)
+	service.saveTypedUserAttribute("bob", "level", "2", valueTypeNumber)
+
+	csv := strings.Join([]string{
+		"id,attribute,value,type",
+		"alice,department,engineering,",
+		"alice,level,3,number",
+		"bob,level,senior,", // level is a number
+		"carol,department",
+		`carol,"bad name",x,`,
+		"carol,department,sales,",
+	}, "\n")
+	code, response := send("/api/v1/users/attributes/import", "text/csv", csv)
+	if code != http.StatusOK || response.Imported != 3 || response.Failed != 3 {
+		t.Fatalf("Expected 3 rows imported and 3 failed, got %d: %+v", code, response)
+	}
+	for i, line := range []int{4, 5, 6} {
+		if response.Errors[i].Line != line {
+			t.Errorf("Expected error %d on line %d, got %+v", i, line, response.Errors[i])
+		}
+	}
+
+	attributes, _ := service.getUserAttributesFromDB("alice")
+	if attributes["department"] != "engineering" || attributes["level"] != "3" {
+		t.Errorf("Unexpected attributes of alice: %v", attributes)
+	}
+	if attributes, _ := service.getUserAttributesFromDB("bob"); attributes["level"] != "2" {
+		t.Errorf("Expected the invalid row to leave bob unchanged, got %v", attributes)
+	}
+	if attributes, _ := service.getUserAttributesFromDB("carol"); attributes["department"] != "sales" {
+		t.Errorf("Expected carol's valid row to be imported, got %v", attributes)
+	}
+
+	if code, _ := send("/api/v1/users/attributes/import", "text/csv", "user,attribute,value\nalice,a,b"); code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown column to be rejected, got %d", code)
+	}
+}
+
+func TestAttributeImport_NDJSON(t *testing.T) {
+	service, send := setupAttributeImport(t)
+
+	var body strings.Builder
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&body, `{"id": "doc%d", "attribute": "classification", "value": "internal"}`+"\n", i)
+	}
+	body.WriteString("\n{not json}\n")
+	code, response := send("/api/v1/objects/attributes/import", "application/x-ndjson", body.String())
+	if code != http.StatusOK || response.Imported != 2000 || response.Failed != 1 || response.Errors[0].Line != 2002 {
+		t.Fatalf("Expected 2000 rows imported and line 2002 failed, got %d: %+v", code, response)
+	}
+	if attributes := service.getObjectAttributes("doc1999"); attributes["classification"] != "internal" {
+		t.Errorf("Unexpected attributes of doc1999: %v", attributes)
+	}
+
+	if code, _ := send("/api/v1/objects/attributes/import?format=xml", "application/xml", "<rows/>"); code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown format to be rejected, got %d", code)
+	}
+}
@@ -0,0 +1,220 @@
+// Multi-Model Authorization Microservice - Decision Audit Log
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxReplayDecisions bounds how many audited decisions a single replay may evaluate
+const maxReplayDecisions = 10000
+
+// errInvalidReplayWindow is returned when the replay window is empty or inverted
+var errInvalidReplayWindow = errors.New("from must not be after to")
+
+// DecisionRecord represents an audited authorization decision in the database
+type DecisionRecord struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	RequestID  string    `json:"request_id,omitempty"`
+	Model      string    `json:"model" gorm:"index"`
+	Subject    string    `json:"subject" gorm:"index"`
+	Object     string    `json:"object" gorm:"index"`
+	Action     string    `json:"action"`
+	Attributes string    `json:"attributes,omitempty"` // JSON-encoded request attributes
+	Allowed    bool      `json:"allowed"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// DecisionDifference describes an audited decision whose outcome changed on replay
+type DecisionDifference struct {
+	Decision DecisionRecord `json:"decision"`
+	Original bool           `json:"original"`
+	Current  bool           `json:"current"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// ReplayRequest selects the window of audited decisions to replay
+type ReplayRequest struct {
+	From  time.Time          `json:"from"`
+	To    time.Time          `json:"to"`
+	Model AccessControlModel `json:"model,omitempty"`
+	Limit int                `json:"limit,omitempty"`
+}
+
+// ReplayReport summarizes the outcome of replaying audited decisions
+type ReplayReport struct {
+	From        time.Time            `json:"from"`
+	To          time.Time            `json:"to"`
+	Replayed    int                  `json:"replayed"`
+	Unchanged   int                  `json:"unchanged"`
+	NowAllowed  int                  `json:"now_allowed"`
+	NowDenied   int                  `json:"now_denied"`
+	Errors      int                  `json:"errors"`
+	Differences []DecisionDifference `json:"differences"`
+}
+
+// recordDecision writes an authorization decision to the audit log when auditing is enabled
+func (s *AuthService) recordDecision(requestID string, model AccessControlModel, subject, object, action string, attributes map[string]string, allowed bool) {
+	if !s.auditDecisions {
+		return
+	}
+
+	if model == "" {
+		model = ModelRBAC
+	}
+
+	record := DecisionRecord{
+		RequestID: requestID,
+		Model:     string(model),
+		Subject:   subject,
+		Object:    object,
+		Action:    action,
+		Allowed:   allowed,
+	}
+	if len(attributes) > 0 {
+		encoded, err := json.Marshal(attributes)
+		if err == nil {
+			record.Attributes = string(encoded)
+		}
+	}
+
+	if err := s.db.Create(&record).Error; err != nil {
+		log.Printf("Failed to record authorization decision: %v", err)
+	}
+}
+
+// ReplayDecisions re-evaluates audited decisions in the window against the current policy state
+func (s *AuthService) ReplayDecisions(req ReplayRequest) (*ReplayReport, error) {
+	if req.To.IsZero() {
+		req.To = time.Now()
+	}
+	if req.From.After(req.To) {
+		return nil, errInvalidReplayWindow
+	}
+	if req.Limit <= 0 || req.Limit > maxReplayDecisions {
+		req.Limit = maxReplayDecisions
+	}
+
+	query := s.readDB().Where("created_at >= ? AND created_at <= ?", req.From, req.To)
+	if req.Model != "" {
+		query = query.Where("model = ?", req.Model)
+	}
+
+	var records []DecisionRecord
+	if err := query.Order("id").Limit(req.Limit).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load audited decisions: %v", err)
+	}
+
+	report := &ReplayReport{
+		From:        req.From,
+		To:          req.To,
+		Differences: []DecisionDifference{},
+	}
+
+	for _, record := range records {
+		var attributes map[string]string
+		if record.Attributes != "" {
+			json.Unmarshal([]byte(record.Attributes), &attributes)
+		}
+
+		report.Replayed++
+		allowed, err := s.Enforce(AccessControlModel(record.Model), record.Subject, record.Object, record.Action, attributes)
+		if err != nil {
+			report.Errors++
+			report.Differences = append(report.Differences, DecisionDifference{
+				Decision: record,
+				Original: record.Allowed,
+				Current:  false,
+				Error:    err.Error(),
+			})
+			continue
+		}
+
+		switch {
+		case allowed == record.Allowed:
+			report.Unchanged++
+			continue
+		case allowed:
+			report.NowAllowed++
+		default:
+			report.NowDenied++
+		}
+
+		report.Differences = append(report.Differences, DecisionDifference{
+			Decision: record,
+			Original: record.Allowed,
+			Current:  allowed,
+		})
+	}
+
+	return report, nil
+}
+
+// getDecisionsHandler lists audited decisions, newest first
+func (s *AuthService) getDecisionsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := 100
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		if parsed < 1000 {
+			limit = parsed
+		} else {
+			limit = 1000
+		}
+	}
+
+	query := s.readDB().Order("id desc").Limit(limit)
+	if subject := r.URL.Query().Get("subject"); subject != "" {
+		query = query.Where("subject = ?", subject)
+	}
+	if model := r.URL.Query().Get("model"); model != "" {
+		query = query.Where("model = ?", model)
+	}
+
+	var decisions []DecisionRecord
+	if err := query.Find(&decisions).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve decisions: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"decisions": decisions,
+		"count":     len(decisions),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// replayDecisionsHandler replays a window of audited decisions and reports differences
+func (s *AuthService) replayDecisionsHandler(w http.ResponseWriter, r *http.Request) {
+	var req ReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	report, err := s.ReplayDecisions(req)
+	if err == errInvalidReplayWindow {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Replay error: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
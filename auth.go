@@ -0,0 +1,374 @@
+// Multi-Model Authorization Microservice - API Authentication
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Client roles. Read clients may check authorizations and read data; admin clients may
+// also change policies, attributes and relationships.
+const (
+	roleRead  = "read"
+	roleAdmin = "admin"
+)
+
+// Principal is an authenticated API client
+type Principal struct {
+	Name   string `json:"name"`
+	Role   string `json:"role"`
+	Method string `json:"method"` // "api_key" or "jwt"
+}
+
+// APIKeyConfig is a static API key
+type APIKeyConfig struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	Role string `json:"role"`
+}
+
+// JWTConfig configures verification of HS256-signed bearer tokens
+type JWTConfig struct {
+	Secret    string `json:"secret"`
+	Issuer    string `json:"issuer,omitempty"`     // Required "iss" claim (optional)
+	Audience  string `json:"audience,omitempty"`   // Required "aud" entry (optional)
+	RoleClaim string `json:"role_claim,omitempty"` // Claim holding the role or roles (default "role")
+}
+
+// AuthConfig is the format of AUTH_CONFIG_FILE
+type AuthConfig struct {
+	APIKeys            []APIKeyConfig            `json:"api_keys"`
+	JWT                *JWTConfig                `json:"jwt,omitempty"`
+	AdminAuthorization *AdminAuthorizationConfig `json:"admin_authorization,omitempty"`
+}
+
+// authenticator verifies API keys and bearer tokens
+type authenticator struct {
+	keys  []APIKeyConfig
+	jwt   *JWTConfig
+	admin *adminAuthorizer // Decides per principal instead of client roles (nil when not configured)
+}
+
+// principalKey is the context key holding the authenticated principal
+type principalKey struct{}
+
+// principalFromContext returns the authenticated client, or nil when authentication is off
+func principalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalKey{}).(*Principal)
+	return principal
+}
+
+// validRole reports whether role is a known client role
+func validRole(role string) bool {
+	return role == roleRead || role == roleAdmin
+}
+
+// authenticatorFromEnv configures authentication from AUTH_CONFIG_FILE, AUTH_API_KEYS
+// ("name:key:role" entries), AUTH_JWT_* and AUTH_ADMIN_ROLES. It returns nil when nothing
+// is configured, which leaves the API open.
+func authenticatorFromEnv() (*authenticator, error) {
+	var config AuthConfig
+	if path := os.Getenv("AUTH_CONFIG_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read AUTH_CONFIG_FILE: %v", err)
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("invalid AUTH_CONFIG_FILE: %v", err)
+		}
+	}
+
+	if keysStr := os.Getenv("AUTH_API_KEYS"); keysStr != "" {
+		for _, entry := range strings.Split(keysStr, ",") {
+			parts := strings.Split(strings.TrimSpace(entry), ":")
+			if len(parts) != 3 {
+				return nil, fmt.Errorf("invalid AUTH_API_KEYS entry %q: expected name:key:role", entry)
+			}
+			config.APIKeys = append(config.APIKeys, APIKeyConfig{Name: parts[0], Key: parts[1], Role: parts[2]})
+		}
+	}
+
+	if secret := os.Getenv("AUTH_JWT_SECRET"); secret != "" {
+		if config.JWT == nil {
+			config.JWT = &JWTConfig{}
+		}
+		config.JWT.Secret = secret
+	}
+	if config.JWT != nil {
+		if issuer := os.Getenv("AUTH_JWT_ISSUER"); issuer != "" {
+			config.JWT.Issuer = issuer
+		}
+		if audience := os.Getenv("AUTH_JWT_AUDIENCE"); audience != "" {
+			config.JWT.Audience = audience
+		}
+		if claim := os.Getenv("AUTH_JWT_ROLE_CLAIM"); claim != "" {
+			config.JWT.RoleClaim = claim
+		}
+	}
+	if err := adminBindingsFromEnv(&config); err != nil {
+		return nil, err
+	}
+
+	return newAuthenticator(config)
+}
+
+// newAuthenticator validates a configuration; it returns nil when it configures nothing
+func newAuthenticator(config AuthConfig) (*authenticator, error) {
+	if len(config.APIKeys) == 0 && config.JWT == nil {
+		if config.AdminAuthorization != nil {
+			return nil, fmt.Errorf("admin authorization needs API keys or JWT authentication")
+		}
+		return nil, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range config.APIKeys {
+		if key.Name == "" || key.Key == "" {
+			return nil, fmt.Errorf("API keys need a name and a key")
+		}
+		if !validRole(key.Role) {
+			return nil, fmt.Errorf("invalid role %q for API key %s: must be '%s' or '%s'", key.Role, key.Name, roleRead, roleAdmin)
+		}
+		if seen[key.Key] {
+			return nil, fmt.Errorf("API key of %s is not unique", key.Name)
+		}
+		seen[key.Key] = true
+	}
+
+	if config.JWT != nil {
+		if config.JWT.Secret == "" {
+			return nil, fmt.Errorf("JWT authentication needs a secret")
+		}
+		if config.JWT.RoleClaim == "" {
+			config.JWT.RoleClaim = "role"
+		}
+	}
+
+	auth := &authenticator{keys: config.APIKeys, jwt: config.JWT}
+	if config.AdminAuthorization != nil {
+		var err error
+		if auth.admin, err = newAdminAuthorizer(config.AdminAuthorization); err != nil {
+			return nil, err
+		}
+	}
+	return auth, nil
+}
+
+// authenticate resolves a credential, either an API key or a JWT, to a principal
+func (a *authenticator) authenticate(credential string, now time.Time) (*Principal, error) {
+	if credential == "" {
+		return nil, fmt.Errorf("missing credentials")
+	}
+
+	// Compare against every key in constant time
+	var match *APIKeyConfig
+	for i := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(a.keys[i].Key), []byte(credential)) == 1 {
+			match = &a.keys[i]
+		}
+	}
+	if match != nil {
+		return &Principal{Name: match.Name, Role: match.Role, Method: "api_key"}, nil
+	}
+
+	if a.jwt != nil && strings.Count(credential, ".") == 2 {
+		return a.verifyJWT(credential, now)
+	}
+	return nil, fmt.Errorf("invalid credentials")
+}
+
+// verifyJWT checks an HS256 token's signature and registered claims and reads its role
+func (a *authenticator) verifyJWT(token string, now time.Time) (*Principal, error) {
+	parts := strings.Split(token, ".")
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header")
+	}
+	if header.Alg != "HS256" {
+		return nil, fmt.Errorf("unsupported token algorithm %q", header.Alg)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature")
+	}
+	mac := hmac.New(sha256.New, []byte(a.jwt.Secret))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, fmt.Errorf("invalid token signature")
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	if exp, ok := claims["exp"].(float64); ok && !now.Before(time.Unix(int64(exp), 0)) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0)) {
+		return nil, fmt.Errorf("token not yet valid")
+	}
+	if a.jwt.Issuer != "" && claims["iss"] != a.jwt.Issuer {
+		return nil, fmt.Errorf("unexpected token issuer")
+	}
+	if a.jwt.Audience != "" && !claimContains(claims["aud"], a.jwt.Audience) {
+		return nil, fmt.Errorf("unexpected token audience")
+	}
+
+	// The strongest role listed in the claim applies
+	role := ""
+	switch {
+	case claimContains(claims[a.jwt.RoleClaim], roleAdmin):
+		role = roleAdmin
+	case claimContains(claims[a.jwt.RoleClaim], roleRead):
+		role = roleRead
+	default:
+		return nil, fmt.Errorf("token grants no role")
+	}
+
+	name, _ := claims["sub"].(string)
+	if name == "" {
+		return nil, fmt.Errorf("token has no subject")
+	}
+	return &Principal{Name: name, Role: role, Method: "jwt"}, nil
+}
+
+// decodeJWTSegment decodes a base64url-encoded JSON token segment
+func decodeJWTSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// claimContains reports whether a string or string-array claim contains value
+func claimContains(claim interface{}, value string) bool {
+	switch v := claim.(type) {
+	case string:
+		return v == value
+	case []interface{}:
+		for _, entry := range v {
+			if entry == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// credentialFromRequest reads a bearer token or an X-API-Key header. Requests forwarded by
+// Envoy's ext_authz filter carry the end user's Authorization header, so Envoy
+// authenticates with X-API-Key.
+func credentialFromRequest(r *http.Request) string {
+	if strings.HasPrefix(r.URL.Path, extAuthzPathPrefix) {
+		return r.Header.Get("X-API-Key")
+	}
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// requiredHTTPRole returns the role needed for a request: reads and authorization checks
+// need a read client, everything else an admin
+func requiredHTTPRole(r *http.Request) string {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead || r.URL.Path == "/api/v1/authorizations" || r.URL.Path == "/api/v1/authorizations/token" ||
+		r.URL.Path == "/api/v1/kubernetes/subjectaccessreviews" || strings.HasPrefix(r.URL.Path, extAuthzPathPrefix) {
+		return roleRead
+	}
+	return roleAdmin
+}
+
+// middleware authenticates HTTP requests and enforces client roles, or admin roles when
+// admin authorization is configured. Health checks and CORS preflights stay open. A nil authenticator lets every request through.
+func (a *authenticator) middleware(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions || r.URL.Path == "/api/v1/health" || r.URL.Path == "/api/v1/health/ready" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := a.authenticate(credentialFromRequest(r), time.Now())
+		if err != nil {
+			serviceMetrics.Inc("auth_failures_total")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="authorization-service"`)
+			writeJSONError(w, http.StatusUnauthorized, fmt.Sprintf("Unauthorized: %v", err))
+			return
+		}
+		if a.admin != nil {
+			if resource, action := adminHTTPRequest(r); !a.admin.allows(principal, resource, action) {
+				serviceMetrics.Inc("admin_authz_denials_total")
+				writeJSONError(w, http.StatusForbidden, fmt.Sprintf("Forbidden: %s may not %s %s", principal.Name, action, resource))
+				return
+			}
+		} else if requiredHTTPRole(r) == roleAdmin && principal.Role != roleAdmin {
+			writeJSONError(w, http.StatusForbidden, "Admin role required")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	})
+}
+
+// grpcReadOnlyMethods lists the gRPC methods read clients may call
+var grpcReadOnlyMethods = map[string]bool{
+	"Enforce":           true,
+	"ListPolicies":      true,
+	"GetRolesForUser":   true,
+	"ListRelationships": true,
+	"Check":             true, // Envoy ext_authz
+}
+
+// authenticateGRPC authenticates a gRPC call from authorization or x-api-key metadata
+// and enforces client or admin roles. It returns ctx unchanged when authentication is off
+// and for health checks, which load balancers make without credentials.
+func (a *authenticator) authenticateGRPC(ctx context.Context, fullMethod string) (context.Context, error) {
+	if a == nil || strings.HasPrefix(fullMethod, grpcHealthMethodPrefix) {
+		return ctx, nil
+	}
+
+	credential := grpcMetadataValue(ctx, "x-api-key")
+	if header := grpcMetadataValue(ctx, "authorization"); strings.HasPrefix(header, "Bearer ") {
+		credential = strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))
+	}
+	principal, err := a.authenticate(credential, time.Now())
+	if err != nil {
+		serviceMetrics.Inc("auth_failures_total")
+		return ctx, status.Errorf(codes.Unauthenticated, "unauthorized: %v", err)
+	}
+
+	if a.admin != nil {
+		if resource, action := adminGRPCRequest(fullMethod); !a.admin.allows(principal, resource, action) {
+			serviceMetrics.Inc("admin_authz_denials_total")
+			return ctx, status.Errorf(codes.PermissionDenied, "%s may not %s %s", principal.Name, action, resource)
+		}
+		return context.WithValue(ctx, principalKey{}, principal), nil
+	}
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	if !grpcReadOnlyMethods[method] && principal.Role != roleAdmin {
+		return ctx, status.Error(codes.PermissionDenied, "admin role required")
+	}
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
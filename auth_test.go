@@ -0,0 +1,194 @@
+// Multi-Model Authorization Microservice - API Authentication Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"casbin-authorization-server/authzpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// signTestJWT builds an HS256 token with the given claims
+func signTestJWT(secret string, claims map[string]interface{}) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, _ := json.Marshal(claims)
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuth_MiddlewareSeparatesReadAndAdminClients(t *testing.T) {
+	service := setupTestService(t)
+	auth, err := newAuthenticator(AuthConfig{APIKeys: []APIKeyConfig{
+		{Name: "app", Key: "read-key", Role: roleRead},
+		{Name: "deployer", Key: "admin-key", Role: roleAdmin},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to configure authentication: %v", err)
+	}
+	service.authenticator = auth
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/acl/policies", service.addACLPolicyHandler).Methods("POST")
+	router.HandleFunc("/api/v1/acl/policies", service.getACLPoliciesHandler).Methods("GET")
+	router.Use(service.authenticator.middleware)
+
+	send := func(method, url, body string, headers map[string]string) int {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	policy := `{"subject": "alice", "object": "data1", "action": "read"}`
+	check := `{"model": "acl", "subject": "alice", "object": "data1", "action": "read"}`
+	readKey := map[string]string{"X-API-Key": "read-key"}
+	adminKey := map[string]string{"Authorization": "Bearer admin-key"}
+
+	if code := send("GET", "/api/v1/health", "", nil); code != http.StatusOK {
+		t.Errorf("Expected health checks to stay open, got %d", code)
+	}
+	if code := send("POST", "/api/v1/acl/policies", policy, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", code)
+	}
+	if code := send("POST", "/api/v1/acl/policies", policy, map[string]string{"X-API-Key": "wrong"}); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an unknown key, got %d", code)
+	}
+	if code := send("POST", "/api/v1/acl/policies", policy, readKey); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a read client changing policies, got %d", code)
+	}
+	if code := send("POST", "/api/v1/acl/policies", policy, adminKey); code != http.StatusCreated {
+		t.Errorf("Expected 201 for an admin client, got %d", code)
+	}
+	if code := send("POST", "/api/v1/authorizations", check, readKey); code != http.StatusOK {
+		t.Errorf("Expected read clients to check authorizations, got %d", code)
+	}
+	if code := send("GET", "/api/v1/acl/policies", "", readKey); code != http.StatusOK {
+		t.Errorf("Expected read clients to list policies, got %d", code)
+	}
+
+	// Changes are attributed to the authenticated client rather than X-Actor
+	var metadata PolicyMetadata
+	service.db.Where("kind = ? AND resource_key = ?", labelKindACL, "alice:data1:read").First(&metadata)
+	if metadata.CreatedBy != "deployer" {
+		t.Errorf("Expected the policy to be created by deployer, got %q", metadata.CreatedBy)
+	}
+}
+
+func TestAuth_JWTVerification(t *testing.T) {
+	auth, err := newAuthenticator(AuthConfig{JWT: &JWTConfig{Secret: "s3cret", Issuer: "idp", Audience: "authz", RoleClaim: "roles"}})
+	if err != nil {
+		t.Fatalf("Failed to configure authentication: %v", err)
+	}
+	now := time.Now()
+	valid := map[string]interface{}{
+		"sub":   "ci-pipeline",
+		"iss":   "idp",
+		"aud":   []string{"authz", "other"},
+		"exp":   now.Add(time.Hour).Unix(),
+		"roles": []string{"read", "admin"},
+	}
+
+	principal, err := auth.authenticate(signTestJWT("s3cret", valid), now)
+	if err != nil || principal.Name != "ci-pipeline" || principal.Role != roleAdmin || principal.Method != "jwt" {
+		t.Fatalf("Expected an admin principal, got %+v: %v", principal, err)
+	}
+
+	invalid := map[string]func(claims map[string]interface{}){
+		"expired":      func(c map[string]interface{}) { c["exp"] = now.Add(-time.Minute).Unix() },
+		"not yet":      func(c map[string]interface{}) { c["nbf"] = now.Add(time.Minute).Unix() },
+		"issuer":       func(c map[string]interface{}) { c["iss"] = "elsewhere" },
+		"audience":     func(c map[string]interface{}) { c["aud"] = "other" },
+		"no role":      func(c map[string]interface{}) { c["roles"] = []string{"viewer"} },
+		"no subject":   func(c map[string]interface{}) { delete(c, "sub") },
+	}
+	for name, mutate := range invalid {
+		claims := map[string]interface{}{}
+		for key, value := range valid {
+			claims[key] = value
+		}
+		mutate(claims)
+		if _, err := auth.authenticate(signTestJWT("s3cret", claims), now); err == nil {
+			t.Errorf("Expected the %s token to be rejected", name)
+		}
+	}
+	if _, err := auth.authenticate(signTestJWT("other-secret", valid), now); err == nil {
+		t.Error("Expected a token signed with another secret to be rejected")
+	}
+}
+
+func TestAuth_ConfigurationFromEnvAndFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.json")
+	os.WriteFile(path, []byte(`{"api_keys": [{"name": "file-admin", "key": "k1", "role": "admin"}], "jwt": {"secret": "from-file"}}`), 0600)
+	t.Setenv("AUTH_CONFIG_FILE", path)
+	t.Setenv("AUTH_API_KEYS", "app:k2:read")
+	t.Setenv("AUTH_JWT_AUDIENCE", "authz")
+
+	auth, err := authenticatorFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to configure authentication: %v", err)
+	}
+	if len(auth.keys) != 2 || auth.jwt.Secret != "from-file" || auth.jwt.Audience != "authz" || auth.jwt.RoleClaim != "role" {
+		t.Errorf("Unexpected configuration: %+v %+v", auth.keys, auth.jwt)
+	}
+
+	for _, keys := range []string{"app:k2", "app:k2:owner", "a:k:read,b:k:admin"} {
+		t.Setenv("AUTH_API_KEYS", keys)
+		if _, err := authenticatorFromEnv(); err == nil {
+			t.Errorf("Expected AUTH_API_KEYS=%s to be rejected", keys)
+		}
+	}
+
+	// Without configuration the API stays open
+	t.Setenv("AUTH_CONFIG_FILE", "")
+	t.Setenv("AUTH_API_KEYS", "")
+	if auth, err := authenticatorFromEnv(); auth != nil || err != nil {
+		t.Errorf("Expected authentication to be off, got %+v %v", auth, err)
+	}
+}
+
+func TestAuth_GRPCRoles(t *testing.T) {
+	service := setupTestService(t)
+	service.authenticator, _ = newAuthenticator(AuthConfig{APIKeys: []APIKeyConfig{
+		{Name: "app", Key: "read-key", Role: roleRead},
+		{Name: "deployer", Key: "admin-key", Role: roleAdmin},
+	}})
+	conn := setupTestGRPC(t, service)
+	rbac := authzpb.NewRBACServiceClient(conn)
+	policy := &authzpb.PolicyRequest{Model: "rbac", Subject: "editor", Object: "doc1", Action: "write"}
+
+	if _, err := rbac.AddPolicy(context.Background(), policy); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without credentials, got %v", err)
+	}
+	readCtx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "read-key")
+	if _, err := rbac.AddPolicy(readCtx, policy); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a read client, got %v", err)
+	}
+	if _, err := rbac.ListPolicies(readCtx, &authzpb.ListPoliciesRequest{Model: "rbac"}); err != nil {
+		t.Errorf("Expected read clients to list policies, got %v", err)
+	}
+	adminCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer admin-key")
+	if _, err := rbac.AddPolicy(adminCtx, policy); err != nil {
+		t.Errorf("Expected admin clients to add policies, got %v", err)
+	}
+}
@@ -0,0 +1,1667 @@
+// Multi-Model Authorization Microservice - gRPC API
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+//
+// Regenerate the Go bindings in authzpb/ with:
+//   protoc --go_out=. --go_opt=module=casbin-authorization-server \
+//     --go-grpc_out=. --go-grpc_opt=module=casbin-authorization-server \
+//     proto/authorization.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: proto/authorization.proto
+
+package authzpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type EnforceRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"` // acl, rbac, abac or rebac (default rbac)
+	Subject       string                 `protobuf:"bytes,2,opt,name=subject,proto3" json:"subject,omitempty"`
+	Object        string                 `protobuf:"bytes,3,opt,name=object,proto3" json:"object,omitempty"`
+	Action        string                 `protobuf:"bytes,4,opt,name=action,proto3" json:"action,omitempty"`
+	Attributes    map[string]string      `protobuf:"bytes,5,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Freshness     string                 `protobuf:"bytes,6,opt,name=freshness,proto3" json:"freshness,omitempty"` // "strong" bypasses in-memory caches
+	Tenant        string                 `protobuf:"bytes,7,opt,name=tenant,proto3" json:"tenant,omitempty"`       // Tenant whose data decides the check (global when empty)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnforceRequest) Reset() {
+	*x = EnforceRequest{}
+	mi := &file_proto_authorization_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnforceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnforceRequest) ProtoMessage() {}
+
+func (x *EnforceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnforceRequest.ProtoReflect.Descriptor instead.
+func (*EnforceRequest) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *EnforceRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *EnforceRequest) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *EnforceRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *EnforceRequest) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *EnforceRequest) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *EnforceRequest) GetFreshness() string {
+	if x != nil {
+		return x.Freshness
+	}
+	return ""
+}
+
+func (x *EnforceRequest) GetTenant() string {
+	if x != nil {
+		return x.Tenant
+	}
+	return ""
+}
+
+type EnforceResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Allowed       bool                   `protobuf:"varint,1,opt,name=allowed,proto3" json:"allowed,omitempty"`
+	Model         string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	Path          string                 `protobuf:"bytes,4,opt,name=path,proto3" json:"path,omitempty"` // Relationship path that granted a ReBAC decision
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EnforceResponse) Reset() {
+	*x = EnforceResponse{}
+	mi := &file_proto_authorization_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EnforceResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EnforceResponse) ProtoMessage() {}
+
+func (x *EnforceResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EnforceResponse.ProtoReflect.Descriptor instead.
+func (*EnforceResponse) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *EnforceResponse) GetAllowed() bool {
+	if x != nil {
+		return x.Allowed
+	}
+	return false
+}
+
+func (x *EnforceResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *EnforceResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *EnforceResponse) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+type PolicyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"` // acl or rbac
+	Subject       string                 `protobuf:"bytes,2,opt,name=subject,proto3" json:"subject,omitempty"`
+	Object        string                 `protobuf:"bytes,3,opt,name=object,proto3" json:"object,omitempty"`
+	Action        string                 `protobuf:"bytes,4,opt,name=action,proto3" json:"action,omitempty"`
+	Labels        []string               `protobuf:"bytes,5,rep,name=labels,proto3" json:"labels,omitempty"`
+	Effect        string                 `protobuf:"bytes,6,opt,name=effect,proto3" json:"effect,omitempty"` // allow (default) or deny; deny rules override allow rules
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PolicyRequest) Reset() {
+	*x = PolicyRequest{}
+	mi := &file_proto_authorization_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PolicyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PolicyRequest) ProtoMessage() {}
+
+func (x *PolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PolicyRequest.ProtoReflect.Descriptor instead.
+func (*PolicyRequest) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PolicyRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *PolicyRequest) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *PolicyRequest) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *PolicyRequest) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *PolicyRequest) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *PolicyRequest) GetEffect() string {
+	if x != nil {
+		return x.Effect
+	}
+	return ""
+}
+
+type PolicyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	Subject       string                 `protobuf:"bytes,2,opt,name=subject,proto3" json:"subject,omitempty"`
+	Object        string                 `protobuf:"bytes,3,opt,name=object,proto3" json:"object,omitempty"`
+	Action        string                 `protobuf:"bytes,4,opt,name=action,proto3" json:"action,omitempty"`
+	Effect        string                 `protobuf:"bytes,5,opt,name=effect,proto3" json:"effect,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PolicyResponse) Reset() {
+	*x = PolicyResponse{}
+	mi := &file_proto_authorization_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PolicyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PolicyResponse) ProtoMessage() {}
+
+func (x *PolicyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PolicyResponse.ProtoReflect.Descriptor instead.
+func (*PolicyResponse) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PolicyResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *PolicyResponse) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *PolicyResponse) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *PolicyResponse) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *PolicyResponse) GetEffect() string {
+	if x != nil {
+		return x.Effect
+	}
+	return ""
+}
+
+type ListPoliciesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"` // acl or rbac
+	Label         string                 `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPoliciesRequest) Reset() {
+	*x = ListPoliciesRequest{}
+	mi := &file_proto_authorization_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPoliciesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPoliciesRequest) ProtoMessage() {}
+
+func (x *ListPoliciesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPoliciesRequest.ProtoReflect.Descriptor instead.
+func (*ListPoliciesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListPoliciesRequest) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ListPoliciesRequest) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+type Policy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subject       string                 `protobuf:"bytes,1,opt,name=subject,proto3" json:"subject,omitempty"`
+	Object        string                 `protobuf:"bytes,2,opt,name=object,proto3" json:"object,omitempty"`
+	Action        string                 `protobuf:"bytes,3,opt,name=action,proto3" json:"action,omitempty"`
+	Effect        string                 `protobuf:"bytes,4,opt,name=effect,proto3" json:"effect,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Policy) Reset() {
+	*x = Policy{}
+	mi := &file_proto_authorization_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Policy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Policy) ProtoMessage() {}
+
+func (x *Policy) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Policy.ProtoReflect.Descriptor instead.
+func (*Policy) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Policy) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *Policy) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *Policy) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *Policy) GetEffect() string {
+	if x != nil {
+		return x.Effect
+	}
+	return ""
+}
+
+type ListPoliciesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Model         string                 `protobuf:"bytes,1,opt,name=model,proto3" json:"model,omitempty"`
+	Policies      []*Policy              `protobuf:"bytes,2,rep,name=policies,proto3" json:"policies,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPoliciesResponse) Reset() {
+	*x = ListPoliciesResponse{}
+	mi := &file_proto_authorization_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPoliciesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPoliciesResponse) ProtoMessage() {}
+
+func (x *ListPoliciesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPoliciesResponse.ProtoReflect.Descriptor instead.
+func (*ListPoliciesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListPoliciesResponse) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *ListPoliciesResponse) GetPolicies() []*Policy {
+	if x != nil {
+		return x.Policies
+	}
+	return nil
+}
+
+type RoleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Role          string                 `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`
+	Labels        []string               `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoleRequest) Reset() {
+	*x = RoleRequest{}
+	mi := &file_proto_authorization_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoleRequest) ProtoMessage() {}
+
+func (x *RoleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoleRequest.ProtoReflect.Descriptor instead.
+func (*RoleRequest) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *RoleRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *RoleRequest) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *RoleRequest) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+type RoleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Role          string                 `protobuf:"bytes,2,opt,name=role,proto3" json:"role,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RoleResponse) Reset() {
+	*x = RoleResponse{}
+	mi := &file_proto_authorization_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RoleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RoleResponse) ProtoMessage() {}
+
+func (x *RoleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RoleResponse.ProtoReflect.Descriptor instead.
+func (*RoleResponse) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *RoleResponse) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *RoleResponse) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+type GetRolesForUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRolesForUserRequest) Reset() {
+	*x = GetRolesForUserRequest{}
+	mi := &file_proto_authorization_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRolesForUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRolesForUserRequest) ProtoMessage() {}
+
+func (x *GetRolesForUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRolesForUserRequest.ProtoReflect.Descriptor instead.
+func (*GetRolesForUserRequest) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetRolesForUserRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+type GetRolesForUserResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Roles         []string               `protobuf:"bytes,2,rep,name=roles,proto3" json:"roles,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRolesForUserResponse) Reset() {
+	*x = GetRolesForUserResponse{}
+	mi := &file_proto_authorization_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRolesForUserResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRolesForUserResponse) ProtoMessage() {}
+
+func (x *GetRolesForUserResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRolesForUserResponse.ProtoReflect.Descriptor instead.
+func (*GetRolesForUserResponse) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetRolesForUserResponse) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *GetRolesForUserResponse) GetRoles() []string {
+	if x != nil {
+		return x.Roles
+	}
+	return nil
+}
+
+type SetAttributesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"` // User or object ID
+	Attributes    map[string]string      `protobuf:"bytes,2,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetAttributesRequest) Reset() {
+	*x = SetAttributesRequest{}
+	mi := &file_proto_authorization_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetAttributesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetAttributesRequest) ProtoMessage() {}
+
+func (x *SetAttributesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetAttributesRequest.ProtoReflect.Descriptor instead.
+func (*SetAttributesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *SetAttributesRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SetAttributesRequest) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+type SetAttributesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Attributes    map[string]string      `protobuf:"bytes,2,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Warnings      []string               `protobuf:"bytes,3,rep,name=warnings,proto3" json:"warnings,omitempty"` // Schema problems accepted in warn mode
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetAttributesResponse) Reset() {
+	*x = SetAttributesResponse{}
+	mi := &file_proto_authorization_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetAttributesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetAttributesResponse) ProtoMessage() {}
+
+func (x *SetAttributesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetAttributesResponse.ProtoReflect.Descriptor instead.
+func (*SetAttributesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SetAttributesResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SetAttributesResponse) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *SetAttributesResponse) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+type PolicyCondition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Field         string                 `protobuf:"bytes,2,opt,name=field,proto3" json:"field,omitempty"`
+	Operator      string                 `protobuf:"bytes,3,opt,name=operator,proto3" json:"operator,omitempty"`
+	Value         string                 `protobuf:"bytes,4,opt,name=value,proto3" json:"value,omitempty"`
+	LogicOp       string                 `protobuf:"bytes,5,opt,name=logic_op,json=logicOp,proto3" json:"logic_op,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PolicyCondition) Reset() {
+	*x = PolicyCondition{}
+	mi := &file_proto_authorization_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PolicyCondition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PolicyCondition) ProtoMessage() {}
+
+func (x *PolicyCondition) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PolicyCondition.ProtoReflect.Descriptor instead.
+func (*PolicyCondition) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *PolicyCondition) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *PolicyCondition) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *PolicyCondition) GetOperator() string {
+	if x != nil {
+		return x.Operator
+	}
+	return ""
+}
+
+func (x *PolicyCondition) GetValue() string {
+	if x != nil {
+		return x.Value
+	}
+	return ""
+}
+
+func (x *PolicyCondition) GetLogicOp() string {
+	if x != nil {
+		return x.LogicOp
+	}
+	return ""
+}
+
+type ABACPolicy struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	Effect        string                 `protobuf:"bytes,4,opt,name=effect,proto3" json:"effect,omitempty"`
+	Priority      int32                  `protobuf:"varint,5,opt,name=priority,proto3" json:"priority,omitempty"`
+	Actions       []string               `protobuf:"bytes,6,rep,name=actions,proto3" json:"actions,omitempty"`
+	Conditions    []*PolicyCondition     `protobuf:"bytes,7,rep,name=conditions,proto3" json:"conditions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ABACPolicy) Reset() {
+	*x = ABACPolicy{}
+	mi := &file_proto_authorization_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ABACPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ABACPolicy) ProtoMessage() {}
+
+func (x *ABACPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ABACPolicy.ProtoReflect.Descriptor instead.
+func (*ABACPolicy) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ABACPolicy) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ABACPolicy) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ABACPolicy) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *ABACPolicy) GetEffect() string {
+	if x != nil {
+		return x.Effect
+	}
+	return ""
+}
+
+func (x *ABACPolicy) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *ABACPolicy) GetActions() []string {
+	if x != nil {
+		return x.Actions
+	}
+	return nil
+}
+
+func (x *ABACPolicy) GetConditions() []*PolicyCondition {
+	if x != nil {
+		return x.Conditions
+	}
+	return nil
+}
+
+type RemoveABACPolicyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveABACPolicyRequest) Reset() {
+	*x = RemoveABACPolicyRequest{}
+	mi := &file_proto_authorization_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveABACPolicyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveABACPolicyRequest) ProtoMessage() {}
+
+func (x *RemoveABACPolicyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveABACPolicyRequest.ProtoReflect.Descriptor instead.
+func (*RemoveABACPolicyRequest) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *RemoveABACPolicyRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type RemoveABACPolicyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveABACPolicyResponse) Reset() {
+	*x = RemoveABACPolicyResponse{}
+	mi := &file_proto_authorization_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveABACPolicyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveABACPolicyResponse) ProtoMessage() {}
+
+func (x *RemoveABACPolicyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveABACPolicyResponse.ProtoReflect.Descriptor instead.
+func (*RemoveABACPolicyResponse) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *RemoveABACPolicyResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ListABACPoliciesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListABACPoliciesRequest) Reset() {
+	*x = ListABACPoliciesRequest{}
+	mi := &file_proto_authorization_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListABACPoliciesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListABACPoliciesRequest) ProtoMessage() {}
+
+func (x *ListABACPoliciesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListABACPoliciesRequest.ProtoReflect.Descriptor instead.
+func (*ListABACPoliciesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{17}
+}
+
+type ListABACPoliciesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Policies      []*ABACPolicy          `protobuf:"bytes,1,rep,name=policies,proto3" json:"policies,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListABACPoliciesResponse) Reset() {
+	*x = ListABACPoliciesResponse{}
+	mi := &file_proto_authorization_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListABACPoliciesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListABACPoliciesResponse) ProtoMessage() {}
+
+func (x *ListABACPoliciesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListABACPoliciesResponse.ProtoReflect.Descriptor instead.
+func (*ListABACPoliciesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *ListABACPoliciesResponse) GetPolicies() []*ABACPolicy {
+	if x != nil {
+		return x.Policies
+	}
+	return nil
+}
+
+type Relationship struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subject       string                 `protobuf:"bytes,1,opt,name=subject,proto3" json:"subject,omitempty"`
+	Relationship  string                 `protobuf:"bytes,2,opt,name=relationship,proto3" json:"relationship,omitempty"`
+	Object        string                 `protobuf:"bytes,3,opt,name=object,proto3" json:"object,omitempty"`
+	Labels        []string               `protobuf:"bytes,4,rep,name=labels,proto3" json:"labels,omitempty"`
+	ExpiresAt     string                 `protobuf:"bytes,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"` // RFC 3339; empty for tuples that never expire
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Relationship) Reset() {
+	*x = Relationship{}
+	mi := &file_proto_authorization_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Relationship) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Relationship) ProtoMessage() {}
+
+func (x *Relationship) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Relationship.ProtoReflect.Descriptor instead.
+func (*Relationship) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Relationship) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *Relationship) GetRelationship() string {
+	if x != nil {
+		return x.Relationship
+	}
+	return ""
+}
+
+func (x *Relationship) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *Relationship) GetLabels() []string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+func (x *Relationship) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
+type RelationshipResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subject       string                 `protobuf:"bytes,1,opt,name=subject,proto3" json:"subject,omitempty"`
+	Relationship  string                 `protobuf:"bytes,2,opt,name=relationship,proto3" json:"relationship,omitempty"`
+	Object        string                 `protobuf:"bytes,3,opt,name=object,proto3" json:"object,omitempty"`
+	ExpiresAt     string                 `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RelationshipResponse) Reset() {
+	*x = RelationshipResponse{}
+	mi := &file_proto_authorization_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RelationshipResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RelationshipResponse) ProtoMessage() {}
+
+func (x *RelationshipResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RelationshipResponse.ProtoReflect.Descriptor instead.
+func (*RelationshipResponse) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *RelationshipResponse) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *RelationshipResponse) GetRelationship() string {
+	if x != nil {
+		return x.Relationship
+	}
+	return ""
+}
+
+func (x *RelationshipResponse) GetObject() string {
+	if x != nil {
+		return x.Object
+	}
+	return ""
+}
+
+func (x *RelationshipResponse) GetExpiresAt() string {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return ""
+}
+
+type ListRelationshipsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Subject       string                 `protobuf:"bytes,1,opt,name=subject,proto3" json:"subject,omitempty"`
+	Label         string                 `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRelationshipsRequest) Reset() {
+	*x = ListRelationshipsRequest{}
+	mi := &file_proto_authorization_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRelationshipsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRelationshipsRequest) ProtoMessage() {}
+
+func (x *ListRelationshipsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRelationshipsRequest.ProtoReflect.Descriptor instead.
+func (*ListRelationshipsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *ListRelationshipsRequest) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *ListRelationshipsRequest) GetLabel() string {
+	if x != nil {
+		return x.Label
+	}
+	return ""
+}
+
+type ListRelationshipsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Relationships []*Relationship        `protobuf:"bytes,1,rep,name=relationships,proto3" json:"relationships,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRelationshipsResponse) Reset() {
+	*x = ListRelationshipsResponse{}
+	mi := &file_proto_authorization_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRelationshipsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRelationshipsResponse) ProtoMessage() {}
+
+func (x *ListRelationshipsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_authorization_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRelationshipsResponse.ProtoReflect.Descriptor instead.
+func (*ListRelationshipsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_authorization_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ListRelationshipsResponse) GetRelationships() []*Relationship {
+	if x != nil {
+		return x.Relationships
+	}
+	return nil
+}
+
+var File_proto_authorization_proto protoreflect.FileDescriptor
+
+const file_proto_authorization_proto_rawDesc = "" +
+	"\n" +
+	"\x19proto/authorization.proto\x12\x10authorization.v1\"\xb7\x02\n" +
+	"\x0eEnforceRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x18\n" +
+	"\asubject\x18\x02 \x01(\tR\asubject\x12\x16\n" +
+	"\x06object\x18\x03 \x01(\tR\x06object\x12\x16\n" +
+	"\x06action\x18\x04 \x01(\tR\x06action\x12P\n" +
+	"\n" +
+	"attributes\x18\x05 \x03(\v20.authorization.v1.EnforceRequest.AttributesEntryR\n" +
+	"attributes\x12\x1c\n" +
+	"\tfreshness\x18\x06 \x01(\tR\tfreshness\x12\x16\n" +
+	"\x06tenant\x18\a \x01(\tR\x06tenant\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"o\n" +
+	"\x0fEnforceResponse\x12\x18\n" +
+	"\aallowed\x18\x01 \x01(\bR\aallowed\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12\x12\n" +
+	"\x04path\x18\x04 \x01(\tR\x04path\"\x9f\x01\n" +
+	"\rPolicyRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x18\n" +
+	"\asubject\x18\x02 \x01(\tR\asubject\x12\x16\n" +
+	"\x06object\x18\x03 \x01(\tR\x06object\x12\x16\n" +
+	"\x06action\x18\x04 \x01(\tR\x06action\x12\x16\n" +
+	"\x06labels\x18\x05 \x03(\tR\x06labels\x12\x16\n" +
+	"\x06effect\x18\x06 \x01(\tR\x06effect\"\x88\x01\n" +
+	"\x0ePolicyResponse\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x18\n" +
+	"\asubject\x18\x02 \x01(\tR\asubject\x12\x16\n" +
+	"\x06object\x18\x03 \x01(\tR\x06object\x12\x16\n" +
+	"\x06action\x18\x04 \x01(\tR\x06action\x12\x16\n" +
+	"\x06effect\x18\x05 \x01(\tR\x06effect\"A\n" +
+	"\x13ListPoliciesRequest\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x12\x14\n" +
+	"\x05label\x18\x02 \x01(\tR\x05label\"j\n" +
+	"\x06Policy\x12\x18\n" +
+	"\asubject\x18\x01 \x01(\tR\asubject\x12\x16\n" +
+	"\x06object\x18\x02 \x01(\tR\x06object\x12\x16\n" +
+	"\x06action\x18\x03 \x01(\tR\x06action\x12\x16\n" +
+	"\x06effect\x18\x04 \x01(\tR\x06effect\"b\n" +
+	"\x14ListPoliciesResponse\x12\x14\n" +
+	"\x05model\x18\x01 \x01(\tR\x05model\x124\n" +
+	"\bpolicies\x18\x02 \x03(\v2\x18.authorization.v1.PolicyR\bpolicies\"M\n" +
+	"\vRoleRequest\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x12\n" +
+	"\x04role\x18\x02 \x01(\tR\x04role\x12\x16\n" +
+	"\x06labels\x18\x03 \x03(\tR\x06labels\"6\n" +
+	"\fRoleResponse\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x12\n" +
+	"\x04role\x18\x02 \x01(\tR\x04role\",\n" +
+	"\x16GetRolesForUserRequest\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\"C\n" +
+	"\x17GetRolesForUserResponse\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x14\n" +
+	"\x05roles\x18\x02 \x03(\tR\x05roles\"\xbd\x01\n" +
+	"\x14SetAttributesRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12V\n" +
+	"\n" +
+	"attributes\x18\x02 \x03(\v26.authorization.v1.SetAttributesRequest.AttributesEntryR\n" +
+	"attributes\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xdb\x01\n" +
+	"\x15SetAttributesResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12W\n" +
+	"\n" +
+	"attributes\x18\x02 \x03(\v27.authorization.v1.SetAttributesResponse.AttributesEntryR\n" +
+	"attributes\x12\x1a\n" +
+	"\bwarnings\x18\x03 \x03(\tR\bwarnings\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\x88\x01\n" +
+	"\x0fPolicyCondition\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x14\n" +
+	"\x05field\x18\x02 \x01(\tR\x05field\x12\x1a\n" +
+	"\boperator\x18\x03 \x01(\tR\boperator\x12\x14\n" +
+	"\x05value\x18\x04 \x01(\tR\x05value\x12\x19\n" +
+	"\blogic_op\x18\x05 \x01(\tR\alogicOp\"\xe3\x01\n" +
+	"\n" +
+	"ABACPolicy\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x16\n" +
+	"\x06effect\x18\x04 \x01(\tR\x06effect\x12\x1a\n" +
+	"\bpriority\x18\x05 \x01(\x05R\bpriority\x12\x18\n" +
+	"\aactions\x18\x06 \x03(\tR\aactions\x12A\n" +
+	"\n" +
+	"conditions\x18\a \x03(\v2!.authorization.v1.PolicyConditionR\n" +
+	"conditions\")\n" +
+	"\x17RemoveABACPolicyRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"*\n" +
+	"\x18RemoveABACPolicyResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x19\n" +
+	"\x17ListABACPoliciesRequest\"T\n" +
+	"\x18ListABACPoliciesResponse\x128\n" +
+	"\bpolicies\x18\x01 \x03(\v2\x1c.authorization.v1.ABACPolicyR\bpolicies\"\x9b\x01\n" +
+	"\fRelationship\x12\x18\n" +
+	"\asubject\x18\x01 \x01(\tR\asubject\x12\"\n" +
+	"\frelationship\x18\x02 \x01(\tR\frelationship\x12\x16\n" +
+	"\x06object\x18\x03 \x01(\tR\x06object\x12\x16\n" +
+	"\x06labels\x18\x04 \x03(\tR\x06labels\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x05 \x01(\tR\texpiresAt\"\x8b\x01\n" +
+	"\x14RelationshipResponse\x12\x18\n" +
+	"\asubject\x18\x01 \x01(\tR\asubject\x12\"\n" +
+	"\frelationship\x18\x02 \x01(\tR\frelationship\x12\x16\n" +
+	"\x06object\x18\x03 \x01(\tR\x06object\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x04 \x01(\tR\texpiresAt\"J\n" +
+	"\x18ListRelationshipsRequest\x12\x18\n" +
+	"\asubject\x18\x01 \x01(\tR\asubject\x12\x14\n" +
+	"\x05label\x18\x02 \x01(\tR\x05label\"a\n" +
+	"\x19ListRelationshipsResponse\x12D\n" +
+	"\rrelationships\x18\x01 \x03(\v2\x1e.authorization.v1.RelationshipR\rrelationships2f\n" +
+	"\x14AuthorizationService\x12N\n" +
+	"\aEnforce\x12 .authorization.v1.EnforceRequest\x1a!.authorization.v1.EnforceResponse2\x9c\x04\n" +
+	"\vRBACService\x12N\n" +
+	"\tAddPolicy\x12\x1f.authorization.v1.PolicyRequest\x1a .authorization.v1.PolicyResponse\x12Q\n" +
+	"\fRemovePolicy\x12\x1f.authorization.v1.PolicyRequest\x1a .authorization.v1.PolicyResponse\x12]\n" +
+	"\fListPolicies\x12%.authorization.v1.ListPoliciesRequest\x1a&.authorization.v1.ListPoliciesResponse\x12O\n" +
+	"\x0eAddRoleForUser\x12\x1d.authorization.v1.RoleRequest\x1a\x1e.authorization.v1.RoleResponse\x12R\n" +
+	"\x11RemoveRoleForUser\x12\x1d.authorization.v1.RoleRequest\x1a\x1e.authorization.v1.RoleResponse\x12f\n" +
+	"\x0fGetRolesForUser\x12(.authorization.v1.GetRolesForUserRequest\x1a).authorization.v1.GetRolesForUserResponse2\xf2\x03\n" +
+	"\vABACService\x12d\n" +
+	"\x11SetUserAttributes\x12&.authorization.v1.SetAttributesRequest\x1a'.authorization.v1.SetAttributesResponse\x12f\n" +
+	"\x13SetObjectAttributes\x12&.authorization.v1.SetAttributesRequest\x1a'.authorization.v1.SetAttributesResponse\x12G\n" +
+	"\tAddPolicy\x12\x1c.authorization.v1.ABACPolicy\x1a\x1c.authorization.v1.ABACPolicy\x12e\n" +
+	"\fRemovePolicy\x12).authorization.v1.RemoveABACPolicyRequest\x1a*.authorization.v1.RemoveABACPolicyResponse\x12e\n" +
+	"\fListPolicies\x12).authorization.v1.ListABACPoliciesRequest\x1a*.authorization.v1.ListABACPoliciesResponse2\xb5\x02\n" +
+	"\fReBACService\x12Y\n" +
+	"\x0fAddRelationship\x12\x1e.authorization.v1.Relationship\x1a&.authorization.v1.RelationshipResponse\x12\\\n" +
+	"\x12RemoveRelationship\x12\x1e.authorization.v1.Relationship\x1a&.authorization.v1.RelationshipResponse\x12l\n" +
+	"\x11ListRelationships\x12*.authorization.v1.ListRelationshipsRequest\x1a+.authorization.v1.ListRelationshipsResponseB%Z#casbin-authorization-server/authzpbb\x06proto3"
+
+var (
+	file_proto_authorization_proto_rawDescOnce sync.Once
+	file_proto_authorization_proto_rawDescData []byte
+)
+
+func file_proto_authorization_proto_rawDescGZIP() []byte {
+	file_proto_authorization_proto_rawDescOnce.Do(func() {
+		file_proto_authorization_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_authorization_proto_rawDesc), len(file_proto_authorization_proto_rawDesc)))
+	})
+	return file_proto_authorization_proto_rawDescData
+}
+
+var file_proto_authorization_proto_msgTypes = make([]protoimpl.MessageInfo, 26)
+var file_proto_authorization_proto_goTypes = []any{
+	(*EnforceRequest)(nil),            // 0: authorization.v1.EnforceRequest
+	(*EnforceResponse)(nil),           // 1: authorization.v1.EnforceResponse
+	(*PolicyRequest)(nil),             // 2: authorization.v1.PolicyRequest
+	(*PolicyResponse)(nil),            // 3: authorization.v1.PolicyResponse
+	(*ListPoliciesRequest)(nil),       // 4: authorization.v1.ListPoliciesRequest
+	(*Policy)(nil),                    // 5: authorization.v1.Policy
+	(*ListPoliciesResponse)(nil),      // 6: authorization.v1.ListPoliciesResponse
+	(*RoleRequest)(nil),               // 7: authorization.v1.RoleRequest
+	(*RoleResponse)(nil),              // 8: authorization.v1.RoleResponse
+	(*GetRolesForUserRequest)(nil),    // 9: authorization.v1.GetRolesForUserRequest
+	(*GetRolesForUserResponse)(nil),   // 10: authorization.v1.GetRolesForUserResponse
+	(*SetAttributesRequest)(nil),      // 11: authorization.v1.SetAttributesRequest
+	(*SetAttributesResponse)(nil),     // 12: authorization.v1.SetAttributesResponse
+	(*PolicyCondition)(nil),           // 13: authorization.v1.PolicyCondition
+	(*ABACPolicy)(nil),                // 14: authorization.v1.ABACPolicy
+	(*RemoveABACPolicyRequest)(nil),   // 15: authorization.v1.RemoveABACPolicyRequest
+	(*RemoveABACPolicyResponse)(nil),  // 16: authorization.v1.RemoveABACPolicyResponse
+	(*ListABACPoliciesRequest)(nil),   // 17: authorization.v1.ListABACPoliciesRequest
+	(*ListABACPoliciesResponse)(nil),  // 18: authorization.v1.ListABACPoliciesResponse
+	(*Relationship)(nil),              // 19: authorization.v1.Relationship
+	(*RelationshipResponse)(nil),      // 20: authorization.v1.RelationshipResponse
+	(*ListRelationshipsRequest)(nil),  // 21: authorization.v1.ListRelationshipsRequest
+	(*ListRelationshipsResponse)(nil), // 22: authorization.v1.ListRelationshipsResponse
+	nil,                               // 23: authorization.v1.EnforceRequest.AttributesEntry
+	nil,                               // 24: authorization.v1.SetAttributesRequest.AttributesEntry
+	nil,                               // 25: authorization.v1.SetAttributesResponse.AttributesEntry
+}
+var file_proto_authorization_proto_depIdxs = []int32{
+	23, // 0: authorization.v1.EnforceRequest.attributes:type_name -> authorization.v1.EnforceRequest.AttributesEntry
+	5,  // 1: authorization.v1.ListPoliciesResponse.policies:type_name -> authorization.v1.Policy
+	24, // 2: authorization.v1.SetAttributesRequest.attributes:type_name -> authorization.v1.SetAttributesRequest.AttributesEntry
+	25, // 3: authorization.v1.SetAttributesResponse.attributes:type_name -> authorization.v1.SetAttributesResponse.AttributesEntry
+	13, // 4: authorization.v1.ABACPolicy.conditions:type_name -> authorization.v1.PolicyCondition
+	14, // 5: authorization.v1.ListABACPoliciesResponse.policies:type_name -> authorization.v1.ABACPolicy
+	19, // 6: authorization.v1.ListRelationshipsResponse.relationships:type_name -> authorization.v1.Relationship
+	0,  // 7: authorization.v1.AuthorizationService.Enforce:input_type -> authorization.v1.EnforceRequest
+	2,  // 8: authorization.v1.RBACService.AddPolicy:input_type -> authorization.v1.PolicyRequest
+	2,  // 9: authorization.v1.RBACService.RemovePolicy:input_type -> authorization.v1.PolicyRequest
+	4,  // 10: authorization.v1.RBACService.ListPolicies:input_type -> authorization.v1.ListPoliciesRequest
+	7,  // 11: authorization.v1.RBACService.AddRoleForUser:input_type -> authorization.v1.RoleRequest
+	7,  // 12: authorization.v1.RBACService.RemoveRoleForUser:input_type -> authorization.v1.RoleRequest
+	9,  // 13: authorization.v1.RBACService.GetRolesForUser:input_type -> authorization.v1.GetRolesForUserRequest
+	11, // 14: authorization.v1.ABACService.SetUserAttributes:input_type -> authorization.v1.SetAttributesRequest
+	11, // 15: authorization.v1.ABACService.SetObjectAttributes:input_type -> authorization.v1.SetAttributesRequest
+	14, // 16: authorization.v1.ABACService.AddPolicy:input_type -> authorization.v1.ABACPolicy
+	15, // 17: authorization.v1.ABACService.RemovePolicy:input_type -> authorization.v1.RemoveABACPolicyRequest
+	17, // 18: authorization.v1.ABACService.ListPolicies:input_type -> authorization.v1.ListABACPoliciesRequest
+	19, // 19: authorization.v1.ReBACService.AddRelationship:input_type -> authorization.v1.Relationship
+	19, // 20: authorization.v1.ReBACService.RemoveRelationship:input_type -> authorization.v1.Relationship
+	21, // 21: authorization.v1.ReBACService.ListRelationships:input_type -> authorization.v1.ListRelationshipsRequest
+	1,  // 22: authorization.v1.AuthorizationService.Enforce:output_type -> authorization.v1.EnforceResponse
+	3,  // 23: authorization.v1.RBACService.AddPolicy:output_type -> authorization.v1.PolicyResponse
+	3,  // 24: authorization.v1.RBACService.RemovePolicy:output_type -> authorization.v1.PolicyResponse
+	6,  // 25: authorization.v1.RBACService.ListPolicies:output_type -> authorization.v1.ListPoliciesResponse
+	8,  // 26: authorization.v1.RBACService.AddRoleForUser:output_type -> authorization.v1.RoleResponse
+	8,  // 27: authorization.v1.RBACService.RemoveRoleForUser:output_type -> authorization.v1.RoleResponse
+	10, // 28: authorization.v1.RBACService.GetRolesForUser:output_type -> authorization.v1.GetRolesForUserResponse
+	12, // 29: authorization.v1.ABACService.SetUserAttributes:output_type -> authorization.v1.SetAttributesResponse
+	12, // 30: authorization.v1.ABACService.SetObjectAttributes:output_type -> authorization.v1.SetAttributesResponse
+	14, // 31: authorization.v1.ABACService.AddPolicy:output_type -> authorization.v1.ABACPolicy
+	16, // 32: authorization.v1.ABACService.RemovePolicy:output_type -> authorization.v1.RemoveABACPolicyResponse
+	18, // 33: authorization.v1.ABACService.ListPolicies:output_type -> authorization.v1.ListABACPoliciesResponse
+	20, // 34: authorization.v1.ReBACService.AddRelationship:output_type -> authorization.v1.RelationshipResponse
+	20, // 35: authorization.v1.ReBACService.RemoveRelationship:output_type -> authorization.v1.RelationshipResponse
+	22, // 36: authorization.v1.ReBACService.ListRelationships:output_type -> authorization.v1.ListRelationshipsResponse
+	22, // [22:37] is the sub-list for method output_type
+	7,  // [7:22] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_proto_authorization_proto_init() }
+func file_proto_authorization_proto_init() {
+	if File_proto_authorization_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_authorization_proto_rawDesc), len(file_proto_authorization_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   26,
+			NumExtensions: 0,
+			NumServices:   4,
+		},
+		GoTypes:           file_proto_authorization_proto_goTypes,
+		DependencyIndexes: file_proto_authorization_proto_depIdxs,
+		MessageInfos:      file_proto_authorization_proto_msgTypes,
+	}.Build()
+	File_proto_authorization_proto = out.File
+	file_proto_authorization_proto_goTypes = nil
+	file_proto_authorization_proto_depIdxs = nil
+}
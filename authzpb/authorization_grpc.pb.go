@@ -0,0 +1,870 @@
+// Multi-Model Authorization Microservice - gRPC API
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+//
+// Regenerate the Go bindings in authzpb/ with:
+//   protoc --go_out=. --go_opt=module=casbin-authorization-server \
+//     --go-grpc_out=. --go-grpc_opt=module=casbin-authorization-server \
+//     proto/authorization.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             v5.29.3
+// source: proto/authorization.proto
+
+package authzpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	AuthorizationService_Enforce_FullMethodName = "/authorization.v1.AuthorizationService/Enforce"
+)
+
+// AuthorizationServiceClient is the client API for AuthorizationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// AuthorizationService performs authorization checks across all models
+type AuthorizationServiceClient interface {
+	Enforce(ctx context.Context, in *EnforceRequest, opts ...grpc.CallOption) (*EnforceResponse, error)
+}
+
+type authorizationServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAuthorizationServiceClient(cc grpc.ClientConnInterface) AuthorizationServiceClient {
+	return &authorizationServiceClient{cc}
+}
+
+func (c *authorizationServiceClient) Enforce(ctx context.Context, in *EnforceRequest, opts ...grpc.CallOption) (*EnforceResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EnforceResponse)
+	err := c.cc.Invoke(ctx, AuthorizationService_Enforce_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AuthorizationServiceServer is the server API for AuthorizationService service.
+// All implementations must embed UnimplementedAuthorizationServiceServer
+// for forward compatibility.
+//
+// AuthorizationService performs authorization checks across all models
+type AuthorizationServiceServer interface {
+	Enforce(context.Context, *EnforceRequest) (*EnforceResponse, error)
+	mustEmbedUnimplementedAuthorizationServiceServer()
+}
+
+// UnimplementedAuthorizationServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedAuthorizationServiceServer struct{}
+
+func (UnimplementedAuthorizationServiceServer) Enforce(context.Context, *EnforceRequest) (*EnforceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Enforce not implemented")
+}
+func (UnimplementedAuthorizationServiceServer) mustEmbedUnimplementedAuthorizationServiceServer() {}
+func (UnimplementedAuthorizationServiceServer) testEmbeddedByValue()                              {}
+
+// UnsafeAuthorizationServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to AuthorizationServiceServer will
+// result in compilation errors.
+type UnsafeAuthorizationServiceServer interface {
+	mustEmbedUnimplementedAuthorizationServiceServer()
+}
+
+func RegisterAuthorizationServiceServer(s grpc.ServiceRegistrar, srv AuthorizationServiceServer) {
+	// If the following call panics, it indicates UnimplementedAuthorizationServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&AuthorizationService_ServiceDesc, srv)
+}
+
+func _AuthorizationService_Enforce_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EnforceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AuthorizationServiceServer).Enforce(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: AuthorizationService_Enforce_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AuthorizationServiceServer).Enforce(ctx, req.(*EnforceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// AuthorizationService_ServiceDesc is the grpc.ServiceDesc for AuthorizationService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var AuthorizationService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "authorization.v1.AuthorizationService",
+	HandlerType: (*AuthorizationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Enforce",
+			Handler:    _AuthorizationService_Enforce_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/authorization.proto",
+}
+
+const (
+	RBACService_AddPolicy_FullMethodName         = "/authorization.v1.RBACService/AddPolicy"
+	RBACService_RemovePolicy_FullMethodName      = "/authorization.v1.RBACService/RemovePolicy"
+	RBACService_ListPolicies_FullMethodName      = "/authorization.v1.RBACService/ListPolicies"
+	RBACService_AddRoleForUser_FullMethodName    = "/authorization.v1.RBACService/AddRoleForUser"
+	RBACService_RemoveRoleForUser_FullMethodName = "/authorization.v1.RBACService/RemoveRoleForUser"
+	RBACService_GetRolesForUser_FullMethodName   = "/authorization.v1.RBACService/GetRolesForUser"
+)
+
+// RBACServiceClient is the client API for RBACService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// RBACService manages ACL/RBAC policies and RBAC role assignments
+type RBACServiceClient interface {
+	AddPolicy(ctx context.Context, in *PolicyRequest, opts ...grpc.CallOption) (*PolicyResponse, error)
+	RemovePolicy(ctx context.Context, in *PolicyRequest, opts ...grpc.CallOption) (*PolicyResponse, error)
+	ListPolicies(ctx context.Context, in *ListPoliciesRequest, opts ...grpc.CallOption) (*ListPoliciesResponse, error)
+	AddRoleForUser(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*RoleResponse, error)
+	RemoveRoleForUser(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*RoleResponse, error)
+	GetRolesForUser(ctx context.Context, in *GetRolesForUserRequest, opts ...grpc.CallOption) (*GetRolesForUserResponse, error)
+}
+
+type rBACServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRBACServiceClient(cc grpc.ClientConnInterface) RBACServiceClient {
+	return &rBACServiceClient{cc}
+}
+
+func (c *rBACServiceClient) AddPolicy(ctx context.Context, in *PolicyRequest, opts ...grpc.CallOption) (*PolicyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PolicyResponse)
+	err := c.cc.Invoke(ctx, RBACService_AddPolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rBACServiceClient) RemovePolicy(ctx context.Context, in *PolicyRequest, opts ...grpc.CallOption) (*PolicyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PolicyResponse)
+	err := c.cc.Invoke(ctx, RBACService_RemovePolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rBACServiceClient) ListPolicies(ctx context.Context, in *ListPoliciesRequest, opts ...grpc.CallOption) (*ListPoliciesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPoliciesResponse)
+	err := c.cc.Invoke(ctx, RBACService_ListPolicies_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rBACServiceClient) AddRoleForUser(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*RoleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RoleResponse)
+	err := c.cc.Invoke(ctx, RBACService_AddRoleForUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rBACServiceClient) RemoveRoleForUser(ctx context.Context, in *RoleRequest, opts ...grpc.CallOption) (*RoleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RoleResponse)
+	err := c.cc.Invoke(ctx, RBACService_RemoveRoleForUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rBACServiceClient) GetRolesForUser(ctx context.Context, in *GetRolesForUserRequest, opts ...grpc.CallOption) (*GetRolesForUserResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRolesForUserResponse)
+	err := c.cc.Invoke(ctx, RBACService_GetRolesForUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RBACServiceServer is the server API for RBACService service.
+// All implementations must embed UnimplementedRBACServiceServer
+// for forward compatibility.
+//
+// RBACService manages ACL/RBAC policies and RBAC role assignments
+type RBACServiceServer interface {
+	AddPolicy(context.Context, *PolicyRequest) (*PolicyResponse, error)
+	RemovePolicy(context.Context, *PolicyRequest) (*PolicyResponse, error)
+	ListPolicies(context.Context, *ListPoliciesRequest) (*ListPoliciesResponse, error)
+	AddRoleForUser(context.Context, *RoleRequest) (*RoleResponse, error)
+	RemoveRoleForUser(context.Context, *RoleRequest) (*RoleResponse, error)
+	GetRolesForUser(context.Context, *GetRolesForUserRequest) (*GetRolesForUserResponse, error)
+	mustEmbedUnimplementedRBACServiceServer()
+}
+
+// UnimplementedRBACServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRBACServiceServer struct{}
+
+func (UnimplementedRBACServiceServer) AddPolicy(context.Context, *PolicyRequest) (*PolicyResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AddPolicy not implemented")
+}
+func (UnimplementedRBACServiceServer) RemovePolicy(context.Context, *PolicyRequest) (*PolicyResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RemovePolicy not implemented")
+}
+func (UnimplementedRBACServiceServer) ListPolicies(context.Context, *ListPoliciesRequest) (*ListPoliciesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListPolicies not implemented")
+}
+func (UnimplementedRBACServiceServer) AddRoleForUser(context.Context, *RoleRequest) (*RoleResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AddRoleForUser not implemented")
+}
+func (UnimplementedRBACServiceServer) RemoveRoleForUser(context.Context, *RoleRequest) (*RoleResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RemoveRoleForUser not implemented")
+}
+func (UnimplementedRBACServiceServer) GetRolesForUser(context.Context, *GetRolesForUserRequest) (*GetRolesForUserResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetRolesForUser not implemented")
+}
+func (UnimplementedRBACServiceServer) mustEmbedUnimplementedRBACServiceServer() {}
+func (UnimplementedRBACServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeRBACServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RBACServiceServer will
+// result in compilation errors.
+type UnsafeRBACServiceServer interface {
+	mustEmbedUnimplementedRBACServiceServer()
+}
+
+func RegisterRBACServiceServer(s grpc.ServiceRegistrar, srv RBACServiceServer) {
+	// If the following call panics, it indicates UnimplementedRBACServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RBACService_ServiceDesc, srv)
+}
+
+func _RBACService_AddPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RBACServiceServer).AddPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RBACService_AddPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RBACServiceServer).AddPolicy(ctx, req.(*PolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RBACService_RemovePolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RBACServiceServer).RemovePolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RBACService_RemovePolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RBACServiceServer).RemovePolicy(ctx, req.(*PolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RBACService_ListPolicies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPoliciesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RBACServiceServer).ListPolicies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RBACService_ListPolicies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RBACServiceServer).ListPolicies(ctx, req.(*ListPoliciesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RBACService_AddRoleForUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RBACServiceServer).AddRoleForUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RBACService_AddRoleForUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RBACServiceServer).AddRoleForUser(ctx, req.(*RoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RBACService_RemoveRoleForUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RBACServiceServer).RemoveRoleForUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RBACService_RemoveRoleForUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RBACServiceServer).RemoveRoleForUser(ctx, req.(*RoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RBACService_GetRolesForUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRolesForUserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RBACServiceServer).GetRolesForUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RBACService_GetRolesForUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RBACServiceServer).GetRolesForUser(ctx, req.(*GetRolesForUserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RBACService_ServiceDesc is the grpc.ServiceDesc for RBACService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RBACService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "authorization.v1.RBACService",
+	HandlerType: (*RBACServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddPolicy",
+			Handler:    _RBACService_AddPolicy_Handler,
+		},
+		{
+			MethodName: "RemovePolicy",
+			Handler:    _RBACService_RemovePolicy_Handler,
+		},
+		{
+			MethodName: "ListPolicies",
+			Handler:    _RBACService_ListPolicies_Handler,
+		},
+		{
+			MethodName: "AddRoleForUser",
+			Handler:    _RBACService_AddRoleForUser_Handler,
+		},
+		{
+			MethodName: "RemoveRoleForUser",
+			Handler:    _RBACService_RemoveRoleForUser_Handler,
+		},
+		{
+			MethodName: "GetRolesForUser",
+			Handler:    _RBACService_GetRolesForUser_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/authorization.proto",
+}
+
+const (
+	ABACService_SetUserAttributes_FullMethodName   = "/authorization.v1.ABACService/SetUserAttributes"
+	ABACService_SetObjectAttributes_FullMethodName = "/authorization.v1.ABACService/SetObjectAttributes"
+	ABACService_AddPolicy_FullMethodName           = "/authorization.v1.ABACService/AddPolicy"
+	ABACService_RemovePolicy_FullMethodName        = "/authorization.v1.ABACService/RemovePolicy"
+	ABACService_ListPolicies_FullMethodName        = "/authorization.v1.ABACService/ListPolicies"
+)
+
+// ABACServiceClient is the client API for ABACService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ABACService manages attributes and ABAC policies
+type ABACServiceClient interface {
+	SetUserAttributes(ctx context.Context, in *SetAttributesRequest, opts ...grpc.CallOption) (*SetAttributesResponse, error)
+	SetObjectAttributes(ctx context.Context, in *SetAttributesRequest, opts ...grpc.CallOption) (*SetAttributesResponse, error)
+	AddPolicy(ctx context.Context, in *ABACPolicy, opts ...grpc.CallOption) (*ABACPolicy, error)
+	RemovePolicy(ctx context.Context, in *RemoveABACPolicyRequest, opts ...grpc.CallOption) (*RemoveABACPolicyResponse, error)
+	ListPolicies(ctx context.Context, in *ListABACPoliciesRequest, opts ...grpc.CallOption) (*ListABACPoliciesResponse, error)
+}
+
+type aBACServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewABACServiceClient(cc grpc.ClientConnInterface) ABACServiceClient {
+	return &aBACServiceClient{cc}
+}
+
+func (c *aBACServiceClient) SetUserAttributes(ctx context.Context, in *SetAttributesRequest, opts ...grpc.CallOption) (*SetAttributesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetAttributesResponse)
+	err := c.cc.Invoke(ctx, ABACService_SetUserAttributes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aBACServiceClient) SetObjectAttributes(ctx context.Context, in *SetAttributesRequest, opts ...grpc.CallOption) (*SetAttributesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetAttributesResponse)
+	err := c.cc.Invoke(ctx, ABACService_SetObjectAttributes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aBACServiceClient) AddPolicy(ctx context.Context, in *ABACPolicy, opts ...grpc.CallOption) (*ABACPolicy, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ABACPolicy)
+	err := c.cc.Invoke(ctx, ABACService_AddPolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aBACServiceClient) RemovePolicy(ctx context.Context, in *RemoveABACPolicyRequest, opts ...grpc.CallOption) (*RemoveABACPolicyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RemoveABACPolicyResponse)
+	err := c.cc.Invoke(ctx, ABACService_RemovePolicy_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aBACServiceClient) ListPolicies(ctx context.Context, in *ListABACPoliciesRequest, opts ...grpc.CallOption) (*ListABACPoliciesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListABACPoliciesResponse)
+	err := c.cc.Invoke(ctx, ABACService_ListPolicies_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ABACServiceServer is the server API for ABACService service.
+// All implementations must embed UnimplementedABACServiceServer
+// for forward compatibility.
+//
+// ABACService manages attributes and ABAC policies
+type ABACServiceServer interface {
+	SetUserAttributes(context.Context, *SetAttributesRequest) (*SetAttributesResponse, error)
+	SetObjectAttributes(context.Context, *SetAttributesRequest) (*SetAttributesResponse, error)
+	AddPolicy(context.Context, *ABACPolicy) (*ABACPolicy, error)
+	RemovePolicy(context.Context, *RemoveABACPolicyRequest) (*RemoveABACPolicyResponse, error)
+	ListPolicies(context.Context, *ListABACPoliciesRequest) (*ListABACPoliciesResponse, error)
+	mustEmbedUnimplementedABACServiceServer()
+}
+
+// UnimplementedABACServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedABACServiceServer struct{}
+
+func (UnimplementedABACServiceServer) SetUserAttributes(context.Context, *SetAttributesRequest) (*SetAttributesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetUserAttributes not implemented")
+}
+func (UnimplementedABACServiceServer) SetObjectAttributes(context.Context, *SetAttributesRequest) (*SetAttributesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SetObjectAttributes not implemented")
+}
+func (UnimplementedABACServiceServer) AddPolicy(context.Context, *ABACPolicy) (*ABACPolicy, error) {
+	return nil, status.Error(codes.Unimplemented, "method AddPolicy not implemented")
+}
+func (UnimplementedABACServiceServer) RemovePolicy(context.Context, *RemoveABACPolicyRequest) (*RemoveABACPolicyResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RemovePolicy not implemented")
+}
+func (UnimplementedABACServiceServer) ListPolicies(context.Context, *ListABACPoliciesRequest) (*ListABACPoliciesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListPolicies not implemented")
+}
+func (UnimplementedABACServiceServer) mustEmbedUnimplementedABACServiceServer() {}
+func (UnimplementedABACServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeABACServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ABACServiceServer will
+// result in compilation errors.
+type UnsafeABACServiceServer interface {
+	mustEmbedUnimplementedABACServiceServer()
+}
+
+func RegisterABACServiceServer(s grpc.ServiceRegistrar, srv ABACServiceServer) {
+	// If the following call panics, it indicates UnimplementedABACServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ABACService_ServiceDesc, srv)
+}
+
+func _ABACService_SetUserAttributes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetAttributesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ABACServiceServer).SetUserAttributes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ABACService_SetUserAttributes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ABACServiceServer).SetUserAttributes(ctx, req.(*SetAttributesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ABACService_SetObjectAttributes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetAttributesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ABACServiceServer).SetObjectAttributes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ABACService_SetObjectAttributes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ABACServiceServer).SetObjectAttributes(ctx, req.(*SetAttributesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ABACService_AddPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ABACPolicy)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ABACServiceServer).AddPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ABACService_AddPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ABACServiceServer).AddPolicy(ctx, req.(*ABACPolicy))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ABACService_RemovePolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveABACPolicyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ABACServiceServer).RemovePolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ABACService_RemovePolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ABACServiceServer).RemovePolicy(ctx, req.(*RemoveABACPolicyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ABACService_ListPolicies_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListABACPoliciesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ABACServiceServer).ListPolicies(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ABACService_ListPolicies_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ABACServiceServer).ListPolicies(ctx, req.(*ListABACPoliciesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ABACService_ServiceDesc is the grpc.ServiceDesc for ABACService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ABACService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "authorization.v1.ABACService",
+	HandlerType: (*ABACServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SetUserAttributes",
+			Handler:    _ABACService_SetUserAttributes_Handler,
+		},
+		{
+			MethodName: "SetObjectAttributes",
+			Handler:    _ABACService_SetObjectAttributes_Handler,
+		},
+		{
+			MethodName: "AddPolicy",
+			Handler:    _ABACService_AddPolicy_Handler,
+		},
+		{
+			MethodName: "RemovePolicy",
+			Handler:    _ABACService_RemovePolicy_Handler,
+		},
+		{
+			MethodName: "ListPolicies",
+			Handler:    _ABACService_ListPolicies_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/authorization.proto",
+}
+
+const (
+	ReBACService_AddRelationship_FullMethodName    = "/authorization.v1.ReBACService/AddRelationship"
+	ReBACService_RemoveRelationship_FullMethodName = "/authorization.v1.ReBACService/RemoveRelationship"
+	ReBACService_ListRelationships_FullMethodName  = "/authorization.v1.ReBACService/ListRelationships"
+)
+
+// ReBACServiceClient is the client API for ReBACService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// ReBACService manages relationship tuples
+type ReBACServiceClient interface {
+	AddRelationship(ctx context.Context, in *Relationship, opts ...grpc.CallOption) (*RelationshipResponse, error)
+	RemoveRelationship(ctx context.Context, in *Relationship, opts ...grpc.CallOption) (*RelationshipResponse, error)
+	ListRelationships(ctx context.Context, in *ListRelationshipsRequest, opts ...grpc.CallOption) (*ListRelationshipsResponse, error)
+}
+
+type reBACServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReBACServiceClient(cc grpc.ClientConnInterface) ReBACServiceClient {
+	return &reBACServiceClient{cc}
+}
+
+func (c *reBACServiceClient) AddRelationship(ctx context.Context, in *Relationship, opts ...grpc.CallOption) (*RelationshipResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RelationshipResponse)
+	err := c.cc.Invoke(ctx, ReBACService_AddRelationship_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reBACServiceClient) RemoveRelationship(ctx context.Context, in *Relationship, opts ...grpc.CallOption) (*RelationshipResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RelationshipResponse)
+	err := c.cc.Invoke(ctx, ReBACService_RemoveRelationship_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reBACServiceClient) ListRelationships(ctx context.Context, in *ListRelationshipsRequest, opts ...grpc.CallOption) (*ListRelationshipsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRelationshipsResponse)
+	err := c.cc.Invoke(ctx, ReBACService_ListRelationships_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReBACServiceServer is the server API for ReBACService service.
+// All implementations must embed UnimplementedReBACServiceServer
+// for forward compatibility.
+//
+// ReBACService manages relationship tuples
+type ReBACServiceServer interface {
+	AddRelationship(context.Context, *Relationship) (*RelationshipResponse, error)
+	RemoveRelationship(context.Context, *Relationship) (*RelationshipResponse, error)
+	ListRelationships(context.Context, *ListRelationshipsRequest) (*ListRelationshipsResponse, error)
+	mustEmbedUnimplementedReBACServiceServer()
+}
+
+// UnimplementedReBACServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedReBACServiceServer struct{}
+
+func (UnimplementedReBACServiceServer) AddRelationship(context.Context, *Relationship) (*RelationshipResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method AddRelationship not implemented")
+}
+func (UnimplementedReBACServiceServer) RemoveRelationship(context.Context, *Relationship) (*RelationshipResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RemoveRelationship not implemented")
+}
+func (UnimplementedReBACServiceServer) ListRelationships(context.Context, *ListRelationshipsRequest) (*ListRelationshipsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListRelationships not implemented")
+}
+func (UnimplementedReBACServiceServer) mustEmbedUnimplementedReBACServiceServer() {}
+func (UnimplementedReBACServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeReBACServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ReBACServiceServer will
+// result in compilation errors.
+type UnsafeReBACServiceServer interface {
+	mustEmbedUnimplementedReBACServiceServer()
+}
+
+func RegisterReBACServiceServer(s grpc.ServiceRegistrar, srv ReBACServiceServer) {
+	// If the following call panics, it indicates UnimplementedReBACServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ReBACService_ServiceDesc, srv)
+}
+
+func _ReBACService_AddRelationship_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Relationship)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReBACServiceServer).AddRelationship(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReBACService_AddRelationship_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReBACServiceServer).AddRelationship(ctx, req.(*Relationship))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReBACService_RemoveRelationship_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Relationship)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReBACServiceServer).RemoveRelationship(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReBACService_RemoveRelationship_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReBACServiceServer).RemoveRelationship(ctx, req.(*Relationship))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReBACService_ListRelationships_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRelationshipsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReBACServiceServer).ListRelationships(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReBACService_ListRelationships_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReBACServiceServer).ListRelationships(ctx, req.(*ListRelationshipsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ReBACService_ServiceDesc is the grpc.ServiceDesc for ReBACService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ReBACService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "authorization.v1.ReBACService",
+	HandlerType: (*ReBACServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddRelationship",
+			Handler:    _ReBACService_AddRelationship_Handler,
+		},
+		{
+			MethodName: "RemoveRelationship",
+			Handler:    _ReBACService_RemoveRelationship_Handler,
+		},
+		{
+			MethodName: "ListRelationships",
+			Handler:    _ReBACService_ListRelationships_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "proto/authorization.proto",
+}
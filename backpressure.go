@@ -0,0 +1,132 @@
+// Multi-Model Authorization Microservice - Enforcement Backpressure
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultMaxInFlight is how many enforce requests may be evaluated concurrently
+	defaultMaxInFlight = 512
+
+	// defaultQueueDepth is how many enforce requests may wait for a free slot
+	defaultQueueDepth = 1024
+
+	// defaultQueueTimeout is how long a queued request waits before it is shed
+	defaultQueueTimeout = time.Second
+)
+
+// concurrencyLimiter bounds in-flight requests and the queue waiting for them. Requests
+// beyond both limits are shed immediately with 503 instead of piling up goroutines and
+// database connections.
+type concurrencyLimiter struct {
+	slots        chan struct{}
+	maxQueue     int64
+	queued       atomic.Int64
+	queueTimeout time.Duration
+}
+
+// newConcurrencyLimiter creates a limiter with the given in-flight and queue limits
+func newConcurrencyLimiter(maxInFlight, maxQueue int, queueTimeout time.Duration) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		slots:        make(chan struct{}, maxInFlight),
+		maxQueue:     int64(maxQueue),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// enforceLimiterFromEnv configures the enforce limiter from ENFORCE_MAX_INFLIGHT,
+// ENFORCE_QUEUE_DEPTH and ENFORCE_QUEUE_TIMEOUT. A max in-flight of 0 disables it.
+func enforceLimiterFromEnv() (*concurrencyLimiter, error) {
+	maxInFlight := defaultMaxInFlight
+	if value := os.Getenv("ENFORCE_MAX_INFLIGHT"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid ENFORCE_MAX_INFLIGHT value: %s", value)
+		}
+		maxInFlight = parsed
+	}
+	if maxInFlight == 0 {
+		return nil, nil
+	}
+
+	queueDepth := defaultQueueDepth
+	if value := os.Getenv("ENFORCE_QUEUE_DEPTH"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid ENFORCE_QUEUE_DEPTH value: %s", value)
+		}
+		queueDepth = parsed
+	}
+
+	queueTimeout := defaultQueueTimeout
+	if value := os.Getenv("ENFORCE_QUEUE_TIMEOUT"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid ENFORCE_QUEUE_TIMEOUT value: %s", value)
+		}
+		queueTimeout = parsed
+	}
+
+	return newConcurrencyLimiter(maxInFlight, queueDepth, queueTimeout), nil
+}
+
+// acquire takes an in-flight slot, waiting in the queue if there is room.
+// It reports false when the request should be shed.
+func (cl *concurrencyLimiter) acquire(r *http.Request) bool {
+	select {
+	case cl.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if cl.queued.Add(1) > cl.maxQueue {
+		cl.queued.Add(-1)
+		return false
+	}
+	defer cl.queued.Add(-1)
+
+	timer := time.NewTimer(cl.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case cl.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// release frees an in-flight slot
+func (cl *concurrencyLimiter) release() {
+	<-cl.slots
+}
+
+// wrap applies the limiter to a handler; a nil limiter leaves the handler unchanged
+func (cl *concurrencyLimiter) wrap(next http.Handler) http.Handler {
+	if cl == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !cl.acquire(r) {
+			serviceMetrics.Inc("enforce_requests_shed_total")
+			w.Header().Set("Retry-After", "1")
+			writeJSONError(w, http.StatusServiceUnavailable, "Server is overloaded, retry later")
+			return
+		}
+		defer cl.release()
+
+		next.ServeHTTP(w, r)
+	})
+}
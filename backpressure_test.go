@@ -0,0 +1,93 @@
+// Multi-Model Authorization Microservice - Backpressure Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBackpressure_ShedsWhenSaturated(t *testing.T) {
+	limiter := newConcurrencyLimiter(1, 1, 50*time.Millisecond)
+
+	release := make(chan struct{})
+	started := make(chan struct{}, 3)
+	handler := limiter.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+
+	serve := func() int {
+		req, _ := http.NewRequest("POST", "/api/v1/authorizations", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Occupy the only slot
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		codes[0] = serve()
+	}()
+	<-started
+
+	// Second request waits in the queue, third is shed immediately
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		codes[1] = serve()
+	}()
+	for limiter.queued.Load() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	before := serviceMetrics.Get("enforce_requests_shed_total")
+	req, _ := http.NewRequest("POST", "/api/v1/authorizations", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 503 with Retry-After when queue is full, got %d", rr.Code)
+	}
+	if serviceMetrics.Get("enforce_requests_shed_total") != before+1 {
+		t.Error("Expected shed counter to be incremented")
+	}
+
+	close(release)
+	wg.Wait()
+	if codes[0] != http.StatusOK {
+		t.Errorf("Expected in-flight request to complete, got %d", codes[0])
+	}
+	if codes[1] != http.StatusOK && codes[1] != http.StatusServiceUnavailable {
+		t.Errorf("Unexpected status for queued request: %d", codes[1])
+	}
+}
+
+func TestBackpressure_QueueTimeout(t *testing.T) {
+	limiter := newConcurrencyLimiter(1, 5, 10*time.Millisecond)
+	limiter.slots <- struct{}{} // Saturate
+
+	handler := limiter.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req, _ := http.NewRequest("POST", "/", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected queued request to be shed after timeout, got %d", rr.Code)
+	}
+
+	t.Setenv("ENFORCE_MAX_INFLIGHT", "0")
+	if disabled, err := enforceLimiterFromEnv(); err != nil || disabled != nil {
+		t.Errorf("Expected limiter to be disabled, got %v %v", disabled, err)
+	}
+	t.Setenv("ENFORCE_MAX_INFLIGHT", "-1")
+	if _, err := enforceLimiterFromEnv(); err == nil {
+		t.Error("Expected error for invalid ENFORCE_MAX_INFLIGHT")
+	}
+}
@@ -0,0 +1,238 @@
+// Multi-Model Authorization Microservice - ACL/RBAC Bulk Policy Writes
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// maxBulkPolicies bounds the rules accepted by one bulk write
+const maxBulkPolicies = 1000
+
+// Further statuses of the rules of a bulk write
+const (
+	bulkStatusExists   = "exists"
+	bulkStatusRemoved  = "removed"
+	bulkStatusNotFound = "not_found"
+)
+
+// BulkPolicyRequest is a batch of ACL or RBAC rules to add or remove. Effects are ignored
+// on removal.
+type BulkPolicyRequest struct {
+	Policies []PolicyRequest `json:"policies"`
+}
+
+// BulkPolicyResult reports the outcome of one rule of a bulk write
+type BulkPolicyResult struct {
+	Index   int    `json:"index"` // Position of the rule in the request
+	Subject string `json:"subject"`
+	Object  string `json:"object"`
+	Action  string `json:"action"`
+	Effect  string `json:"effect,omitempty"`
+	Status  string `json:"status"`          // "created", "exists", "removed", "not_found" or "failed"
+	Error   string `json:"error,omitempty"` // Why the rule was rejected
+}
+
+// bulkAddACLPoliciesHandler adds a batch of ACL rules
+func (s *AuthService) bulkAddACLPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	s.bulkAddRules(w, r, ModelACL, s.aclEnforcer, labelKindACL, validateACLRule)
+}
+
+// bulkRemoveACLPoliciesHandler removes a batch of ACL rules
+func (s *AuthService) bulkRemoveACLPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	s.bulkRemoveRules(w, r, ModelACL, s.aclEnforcer, labelKindACL)
+}
+
+// bulkAddRBACPoliciesHandler adds a batch of RBAC rules
+func (s *AuthService) bulkAddRBACPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	s.bulkAddRules(w, r, ModelRBAC, s.rbacEnforcer, labelKindRBAC, nil)
+}
+
+// bulkRemoveRBACPoliciesHandler removes a batch of RBAC rules
+func (s *AuthService) bulkRemoveRBACPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	s.bulkRemoveRules(w, r, ModelRBAC, s.rbacEnforcer, labelKindRBAC)
+}
+
+// decodeBulkPolicies reads a bulk policy request and checks its size
+func decodeBulkPolicies(w http.ResponseWriter, r *http.Request) (*BulkPolicyRequest, bool) {
+	var req BulkPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return nil, false
+	}
+
+	if len(req.Policies) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "policies must not be empty")
+		return nil, false
+	}
+	if len(req.Policies) > maxBulkPolicies {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d policies can be written at once", maxBulkPolicies))
+		return nil, false
+	}
+	return &req, true
+}
+
+// bulkAddRules adds the valid new rules of a batch with a single AddPolicies call, which
+// the adapter saves in one transaction. Rules whose subject, object and action already have
+// a rule, whatever its effect, are reported as existing.
+func (s *AuthService) bulkAddRules(w http.ResponseWriter, r *http.Request, model AccessControlModel, enforcer casbin.IEnforcer, labelKind string, validate func(object, action string) error) {
+	req, ok := decodeBulkPolicies(w, r)
+	if !ok {
+		return
+	}
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	results := make([]BulkPolicyResult, len(req.Policies))
+	var rules [][]string
+	batched := make(map[string]bool)
+	for i, item := range req.Policies {
+		result := BulkPolicyResult{Index: i, Subject: item.Subject, Object: item.Object, Action: item.Action, Status: bulkStatusFailed}
+		err := item.validate()
+		effect, effectErr := normalizeEffect(item.Effect)
+		if err == nil {
+			err = effectErr
+		}
+		if err == nil && validate != nil {
+			err = validate(item.Object, item.Action)
+		}
+
+		switch {
+		case err != nil:
+			result.Error = err.Error()
+		default:
+			result.Effect = effect
+			rule := []string{scope.qualify(item.Subject), scope.qualify(item.Object), item.Action, effect}
+			existing, err := enforcer.GetFilteredPolicy(0, rule[:3]...)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to add policies: %v", err))
+				return
+			}
+			if len(existing) > 0 || batched[ruleKey(rule)] {
+				result.Status = bulkStatusExists
+				break
+			}
+			batched[ruleKey(rule)] = true
+			rules = append(rules, rule)
+			result.Status = bulkStatusCreated
+		}
+		results[i] = result
+	}
+
+	if !s.checkQuota(w, scope, quotaPolicies, len(rules)) {
+		return
+	}
+	if len(rules) > 0 {
+		if _, err := enforcer.AddPolicies(rules); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to add policies: %v", err))
+			return
+		}
+	}
+
+	actor := actorFromRequest(r)
+	for _, rule := range rules {
+		key := ruleKey(rule)
+		s.recordPolicyMetadata(labelKind, key, actor)
+		s.publishChange(ruleChangeKind(labelKind), changeAdded, key, ruleChange(rule[0], rule[1], rule[2], rule[3]), actor)
+	}
+	for i, item := range req.Policies {
+		if results[i].Status != bulkStatusCreated || len(item.Labels) == 0 {
+			continue
+		}
+		key := labelKey(scope.qualify(item.Subject), scope.qualify(item.Object), item.Action)
+		if err := s.setLabels(labelKind, key, item.Labels); err != nil {
+			results[i].Error = fmt.Sprintf("created, but failed to label: %v", err)
+		}
+	}
+
+	writeBulkPolicyResults(w, model, results, bulkStatusCreated, bulkStatusExists)
+}
+
+// bulkRemoveRules removes the rules of a batch, whatever their effect, with a single
+// RemovePolicies call
+func (s *AuthService) bulkRemoveRules(w http.ResponseWriter, r *http.Request, model AccessControlModel, enforcer casbin.IEnforcer, labelKind string) {
+	req, ok := decodeBulkPolicies(w, r)
+	if !ok {
+		return
+	}
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	results := make([]BulkPolicyResult, len(req.Policies))
+	var rules [][]string
+	batched := make(map[string]bool)
+	for i, item := range req.Policies {
+		result := BulkPolicyResult{Index: i, Subject: item.Subject, Object: item.Object, Action: item.Action, Status: bulkStatusFailed}
+		if err := item.validate(); err != nil {
+			result.Error = err.Error()
+			results[i] = result
+			continue
+		}
+
+		subject, object := scope.qualify(item.Subject), scope.qualify(item.Object)
+		existing, err := enforcer.GetFilteredPolicy(0, subject, object, item.Action)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to remove policies: %v", err))
+			return
+		}
+		key := labelKey(subject, object, item.Action)
+		if len(existing) == 0 || batched[key] {
+			result.Status = bulkStatusNotFound
+		} else {
+			batched[key] = true
+			rules = append(rules, existing...)
+			result.Effect = ruleEffect(existing[0])
+			result.Status = bulkStatusRemoved
+		}
+		results[i] = result
+	}
+
+	if len(rules) > 0 {
+		if _, err := enforcer.RemovePolicies(rules); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to remove policies: %v", err))
+			return
+		}
+	}
+
+	actor := actorFromRequest(r)
+	for _, rule := range rules {
+		key := ruleKey(rule)
+		s.removeLabels(labelKind, key)
+		s.removePolicyMetadata(labelKind, key)
+		s.publishChange(ruleChangeKind(labelKind), changeRemoved, key, ruleChange(rule[0], rule[1], rule[2], ""), actor)
+	}
+
+	writeBulkPolicyResults(w, model, results, bulkStatusRemoved, bulkStatusNotFound)
+}
+
+// writeBulkPolicyResults responds with the results of a bulk write and the number of rules
+// with each of its statuses
+func writeBulkPolicyResults(w http.ResponseWriter, model AccessControlModel, results []BulkPolicyResult, statuses ...string) {
+	response := map[string]interface{}{
+		"results": results,
+		"model":   string(model),
+	}
+	counts := map[string]int{bulkStatusFailed: 0}
+	for _, status := range statuses {
+		counts[status] = 0
+	}
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	for status, count := range counts {
+		response[status] = count
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
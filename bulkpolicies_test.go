@@ -0,0 +1,92 @@
+// Multi-Model Authorization Microservice - ACL/RBAC Bulk Policy Write Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBulkPolicies_AddAndRemove(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/acl/policies/bulk", service.bulkAddACLPoliciesHandler).Methods("POST")
+	router.HandleFunc("/api/v1/acl/policies/bulk", service.bulkRemoveACLPoliciesHandler).Methods("DELETE")
+
+	if _, err := addRule(service.aclEnforcer, "carol", "doc1", "read", effectAllow); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+
+	send := func(method, body string) map[string]interface{} {
+		req, _ := http.NewRequest(method, "/api/v1/acl/policies/bulk", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s failed: %d %s", method, rr.Code, rr.Body.String())
+		}
+		var response map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return response
+	}
+
+	added := send("POST", `{"policies": [
+		{"subject": "alice", "object": "doc1", "action": "read"},
+		{"subject": "bob", "object": "doc1", "action": "read", "effect": "deny", "labels": ["migration"]},
+		{"subject": "alice", "object": "doc1", "action": "read", "effect": "deny"},
+		{"subject": "carol", "object": "doc1", "action": "read"},
+		{"subject": "dave", "object": "doc1", "action": "read", "effect": "maybe"},
+		{"subject": "erin", "object": "", "action": "read"}
+	]}`)
+	if added["created"] != 2.0 || added["exists"] != 2.0 || added["failed"] != 2.0 {
+		t.Fatalf("Unexpected counts: %v", added)
+	}
+	if allowed, denied, _ := enforceRule(service.aclEnforcer, "bob", "doc1", "read"); allowed || !denied {
+		t.Error("Expected bob's deny rule to be added")
+	}
+	if labels, _ := service.labelsByKey(labelKindACL); len(labels[labelKey("bob", "doc1", "read")]) != 1 {
+		t.Errorf("Expected bob's rule to be labeled, got %v", labels)
+	}
+
+	removed := send("DELETE", `{"policies": [
+		{"subject": "alice", "object": "doc1", "action": "read"},
+		{"subject": "bob", "object": "doc1", "action": "read"},
+		{"subject": "bob", "object": "doc1", "action": "read"},
+		{"subject": "zoe", "object": "doc1", "action": "read"}
+	]}`)
+	if removed["removed"] != 2.0 || removed["not_found"] != 2.0 {
+		t.Fatalf("Unexpected counts: %v", removed)
+	}
+	policies, _ := service.aclEnforcer.GetPolicy()
+	if len(policies) != 1 || policies[0][0] != "carol" {
+		t.Errorf("Expected only carol's rule to remain, got %v", policies)
+	}
+}
+
+func TestBulkPolicies_RBACUsesItsEnforcer(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/rbac/policies/bulk", service.bulkAddRBACPoliciesHandler).Methods("POST")
+
+	req, _ := http.NewRequest("POST", "/api/v1/rbac/policies/bulk", bytes.NewBufferString(`{"policies": [
+		{"subject": "editor", "object": "doc1", "action": "write"},
+		{"subject": "viewer", "object": "doc1", "action": "read"}
+	]}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	rules, _ := service.rbacEnforcer.GetPolicy()
+	aclRules, _ := service.aclEnforcer.GetPolicy()
+	if len(rules) != 2 || len(aclRules) != 0 {
+		t.Errorf("Expected 2 RBAC rules and no ACL rules, got %v and %v", rules, aclRules)
+	}
+}
@@ -0,0 +1,214 @@
+// Multi-Model Authorization Microservice - ReBAC Bulk Relationship Writes
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxBulkRelationships bounds the tuples accepted by one bulk write
+const maxBulkRelationships = 1000
+
+// Statuses of the tuples of a bulk write
+const (
+	bulkStatusCreated = "created"
+	bulkStatusFailed  = "failed"
+)
+
+// BulkRelationshipRequest is a batch of tuples to add
+type BulkRelationshipRequest struct {
+	Relationships []AddRelationshipRequest `json:"relationships"`
+}
+
+// BulkRelationshipResult reports the outcome of one tuple of a bulk write
+type BulkRelationshipResult struct {
+	Index        int      `json:"index"` // Position of the tuple in the request
+	Subject      string   `json:"subject"`
+	Relationship string   `json:"relationship"`
+	Object       string   `json:"object"`
+	Status       string   `json:"status"`             // "created" or "failed"
+	Error        string   `json:"error,omitempty"`    // Why the tuple was rejected
+	Warnings     []string `json:"warnings,omitempty"` // Type problems of a tuple written anyway
+}
+
+// AddRelationships adds a batch of tuples in a single database transaction. Tuples that
+// are invalid, violate enforced types or would exceed a constraint are reported as failed
+// and skipped; the others are written together, and constraints count the tuples written
+// earlier in the batch. An error means the transaction failed and nothing was written.
+func (rg *RelationshipGraph) AddRelationships(tuples []LabeledRelationship) ([]BulkRelationshipResult, error) {
+	unlock := rg.writeLock()
+	defer unlock()
+
+	now := time.Now()
+
+	results := make([]BulkRelationshipResult, len(tuples))
+	err := rg.db.Transaction(func(tx *gorm.DB) error {
+		for i, tuple := range tuples {
+			result := BulkRelationshipResult{
+				Index:        i,
+				Subject:      tuple.Subject,
+				Relationship: tuple.Relationship.Relationship,
+				Object:       tuple.Object,
+				Status:       bulkStatusFailed,
+			}
+			problems := rg.typeProblems(tuple.Subject, tuple.Relationship.Relationship, tuple.Object)
+
+			switch {
+			case tuple.Subject == "" || tuple.Relationship.Relationship == "" || tuple.Object == "":
+				result.Error = "subject, relationship and object are required"
+			case tuple.ExpiresAt != nil && !tuple.ExpiresAt.After(now):
+				result.Error = "expires_at must be in the future"
+			case tuple.Caveat != nil && tuple.Caveat.validate() != nil:
+				result.Error = tuple.Caveat.validate().Error()
+			case rg.enforceTypes && len(problems) > 0:
+				result.Error = (&TypeError{Problems: problems}).Error()
+			default:
+				if err := rg.checkConstraintsIn(tx, tuple.Subject, tuple.Relationship.Relationship, tuple.Object); err != nil {
+					var cardinalityErr *CardinalityError
+					if !errors.As(err, &cardinalityErr) {
+						return err
+					}
+					result.Error = err.Error()
+					break
+				}
+				record := RelationshipRecord{
+					Subject:      tuple.Subject,
+					Relationship: tuple.Relationship.Relationship,
+					Object:       tuple.Object,
+					ExpiresAt:    tuple.ExpiresAt,
+					Caveat:       tuple.Caveat,
+				}
+				if tuple.Metadata != nil {
+					record.TupleMetadata = *tuple.Metadata
+				}
+				if err := tx.Create(&record).Error; err != nil {
+					return fmt.Errorf("failed to save relationship %d: %v", i, err)
+				}
+				result.Status = bulkStatusCreated
+				result.Warnings = problems
+			}
+			results[i] = result
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	created := 0
+	for _, result := range results {
+		if result.Status == bulkStatusCreated {
+			created++
+		}
+	}
+	if created == 0 {
+		return results, nil
+	}
+
+	rg.bumpRevision()
+	for i, result := range results {
+		if result.Status == bulkStatusCreated {
+			rg.applyAdded(result.Subject, result.Relationship, result.Object, tuples[i].ExpiresAt, tuples[i].Caveat)
+		}
+	}
+	// The batch is a single write for consistency tokens
+	return results, rg.recordWrite()
+}
+
+// bulkAddRelationshipsHandler adds up to maxBulkRelationships tuples in one transaction and
+// reports the outcome of each
+func (s *AuthService) bulkAddRelationshipsHandler(w http.ResponseWriter, r *http.Request) {
+	var req BulkRelationshipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if len(req.Relationships) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "relationships must not be empty")
+		return
+	}
+	if len(req.Relationships) > maxBulkRelationships {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d relationships can be written at once", maxBulkRelationships))
+		return
+	}
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	// Invalid tuples are left empty, which AddRelationships reports as failed
+	actor := actorFromRequest(r)
+	tuples := make([]LabeledRelationship, len(req.Relationships))
+	invalid := make(map[int]error)
+	for i, item := range req.Relationships {
+		if err := item.validate(); err != nil {
+			invalid[i] = err
+			continue
+		}
+		tuples[i] = LabeledRelationship{
+			Relationship: Relationship{Subject: scope.qualify(item.Subject), Relationship: item.Relationship, Object: scope.qualify(item.Object)},
+			Labels:       item.Labels,
+			ExpiresAt:    item.ExpiresAt,
+			Caveat:       item.Caveat,
+			Metadata:     &TupleMetadata{CreatedBy: actor, Source: item.Source, Note: item.Note},
+		}
+	}
+
+	if !s.checkQuota(w, scope, quotaRelationships, len(tuples)-len(invalid)) {
+		return
+	}
+
+	results, err := s.relationshipGraph.AddRelationships(tuples)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to add relationships: %v", err))
+		return
+	}
+
+	created := 0
+	for i, result := range results {
+		// Results carry the names the client sent
+		result.Subject, result.Object = req.Relationships[i].Subject, req.Relationships[i].Object
+		result.Relationship = req.Relationships[i].Relationship
+		if err, exists := invalid[i]; exists {
+			result.Error = err.Error()
+		}
+		results[i] = result
+		if result.Status != bulkStatusCreated {
+			continue
+		}
+		created++
+
+		tuple := tuples[i]
+		key := labelKey(tuple.Subject, tuple.Relationship.Relationship, tuple.Object)
+		if len(tuple.Labels) > 0 {
+			if err := s.setLabels(labelKindRelationship, key, tuple.Labels); err != nil {
+				results[i].Error = fmt.Sprintf("created, but failed to label: %v", err)
+			}
+		}
+		change := relationshipChange(tuple.Subject, tuple.Relationship.Relationship, tuple.Object, tuple.ExpiresAt)
+		change.Caveat = tuple.Caveat
+		change.Metadata = tuple.Metadata
+		s.publishChange(changeKindRelationship, changeAdded, key, change, actor)
+	}
+
+	response := map[string]interface{}{
+		"results": results,
+		"created": created,
+		"failed":  len(results) - created,
+		"model":   "rebac",
+	}
+	s.relationshipGraph.addConsistencyToken(response)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
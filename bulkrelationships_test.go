@@ -0,0 +1,94 @@
+// Multi-Model Authorization Microservice - ReBAC Bulk Relationship Write Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBulkRelationships_ReportsEachTuple(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/relationships/bulk", service.bulkAddRelationshipsHandler).Methods("POST")
+	service.relationshipGraph.constraints = []RelationshipConstraint{
+		{Relationship: "owner", Per: "object", Max: 1},
+	}
+
+	body := `{"relationships": [
+		{"subject": "alice", "relationship": "owner", "object": "doc1"},
+		{"subject": "bob", "relationship": "owner", "object": "doc1"},
+		{"subject": "", "relationship": "viewer", "object": "doc1"},
+		{"subject": "carol", "relationship": "viewer", "object": "doc1", "expires_at": "2000-01-01T00:00:00Z"},
+		{"subject": "dave", "relationship": "viewer", "object": "doc1", "labels": ["import"]}
+	]}`
+	req, _ := http.NewRequest("POST", "/api/v1/relationships/bulk", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Results          []BulkRelationshipResult `json:"results"`
+		Created          int                      `json:"created"`
+		Failed           int                      `json:"failed"`
+		ConsistencyToken string                   `json:"consistency_token"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if response.Created != 2 || response.Failed != 3 || response.ConsistencyToken == "" {
+		t.Fatalf("Unexpected response: %s", rr.Body.String())
+	}
+
+	// The second owner is rejected because of the first one in the same batch
+	expected := []string{bulkStatusCreated, bulkStatusFailed, bulkStatusFailed, bulkStatusFailed, bulkStatusCreated}
+	for i, result := range response.Results {
+		if result.Index != i || result.Status != expected[i] {
+			t.Errorf("Result %d: expected %s, got %+v", i, expected[i], result)
+		}
+	}
+
+	rg := service.relationshipGraph
+	if allowed, _ := rg.CheckReBACAccess("dave", "doc1", "read"); !allowed {
+		t.Error("Expected the created tuples to be in memory")
+	}
+	if allowed, _ := rg.CheckReBACAccess("bob", "doc1", "read"); allowed {
+		t.Error("Expected the rejected tuple not to be written")
+	}
+	var count int64
+	service.db.Model(&RelationshipRecord{}).Count(&count)
+	if count != 2 {
+		t.Errorf("Expected 2 stored relationships, got %d", count)
+	}
+}
+
+func TestBulkRelationships_RejectsOversizedBatches(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/relationships/bulk", service.bulkAddRelationshipsHandler).Methods("POST")
+
+	tuples := make([]string, maxBulkRelationships+1)
+	for i := range tuples {
+		tuples[i] = fmt.Sprintf(`{"subject": "user%d", "relationship": "viewer", "object": "doc1"}`, i)
+	}
+	for body, code := range map[string]int{
+		`{"relationships": []}`: http.StatusBadRequest,
+		`{"relationships": [` + strings.Join(tuples, ",") + `]}`: http.StatusRequestEntityTooLarge,
+	} {
+		req, _ := http.NewRequest("POST", "/api/v1/relationships/bulk", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != code {
+			t.Errorf("Expected %d, got %d", code, rr.Code)
+		}
+	}
+}
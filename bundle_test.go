@@ -0,0 +1,189 @@
+// Multi-Model Authorization Microservice - Policy Import Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setupBundleRouter(service *AuthService) http.Handler {
+	router := setupTestRouter(service)
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/export", service.exportHandler).Methods("GET")
+	api.HandleFunc("/import", service.importHandler).Methods("POST")
+	return router
+}
+
+func TestBundle_RoundTripIntoFreshEnvironment(t *testing.T) {
+	staging := setupTestService(t)
+	addRule(staging.aclEnforcer, "alice", "report", "read", effectAllow)
+	addRule(staging.rbacEnforcer, "contractor", "finance", "delete", effectDeny)
+	staging.rbacEnforcer.AddGroupingPolicy("bob", "contractor")
+	staging.setLabels(labelKindACL, "alice:report:read", []string{"app:billing"})
+	staging.policyEngine.AddPolicy(&ABACPolicy{ID: "finance", Name: "Finance", Effect: "allow", Priority: 10,
+		Conditions: []PolicyCondition{{Type: "user", Field: "department", Operator: "eq", Value: "finance"}}})
+	staging.saveUserAttribute("carol", "department", "finance")
+	staging.saveObjectAttribute("ledger", "classification", "internal")
+	expiresAt := time.Now().Add(time.Hour)
+	staging.relationshipGraph.AddExpiringRelationship("dave", "viewer", "ledger", &expiresAt)
+
+	req, _ := http.NewRequest("GET", "/api/v1/export", nil)
+	rr := httptest.NewRecorder()
+	setupBundleRouter(staging).ServeHTTP(rr, req)
+	bundle := rr.Body.Bytes()
+
+	production := setupTestService(t)
+	router := setupBundleRouter(production)
+	for i := 0; i < 2; i++ {
+		req, _ = http.NewRequest("POST", "/api/v1/import", bytes.NewBuffer(bundle))
+		req.Header.Set("X-Actor", "deployer")
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Import failed: %d %s", rr.Code, rr.Body.String())
+		}
+	}
+
+	checks := []struct {
+		model                   AccessControlModel
+		subject, object, action string
+		expected                bool
+	}{
+		{ModelACL, "alice", "report", "read", true},
+		{ModelRBAC, "bob", "finance", "delete", false},
+		{ModelABAC, "carol", "ledger", "read", true},
+		{ModelReBAC, "dave", "ledger", "read", true},
+	}
+	for _, check := range checks {
+		if allowed, _ := production.Enforce(check.model, check.subject, check.object, check.action, nil); allowed != check.expected {
+			t.Errorf("%s %s: expected %v after import", check.model, check.subject, check.expected)
+		}
+	}
+
+	// Importing twice is idempotent, and the tuple keeps its expiry
+	var relationships []RelationshipRecord
+	production.db.Find(&relationships)
+	if len(relationships) != 1 || relationships[0].ExpiresAt == nil || !relationships[0].ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Expected one expiring tuple, got %+v", relationships)
+	}
+	exported, _ := production.ExportPolicies("")
+	diff := DiffExports(mustExport(t, staging), exported)
+	for _, change := range diff.Modified {
+		if change.Kind != changeKindABACPolicy {
+			t.Errorf("Unexpected change after round trip: %+v", change)
+		}
+	}
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 {
+		t.Errorf("Expected the environments to match, got %s", diff.Text())
+	}
+	if exported.ACL[0].Metadata == nil || exported.ACL[0].Metadata.CreatedBy != "deployer" {
+		t.Errorf("Expected imported rules to be attributed to the importer, got %+v", exported.ACL[0].Metadata)
+	}
+}
+
+func mustExport(t *testing.T, service *AuthService) *PolicyExport {
+	export, err := service.ExportPolicies("")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	return export
+}
+
+func TestBundle_ReplaceModeRemovesMissingEntries(t *testing.T) {
+	service := setupTestService(t)
+	addRule(service.aclEnforcer, "alice", "report", "read", effectAllow)
+	addRule(service.aclEnforcer, "bob", "report", "read", effectAllow)
+	addRule(service.aclEnforcer, "carol", "ticket", "read", effectAllow)
+	service.setLabels(labelKindACL, "alice:report:read", []string{"app:billing"})
+	service.setLabels(labelKindACL, "bob:report:read", []string{"app:billing"})
+	service.relationshipGraph.AddRelationship("dave", "owner", "ledger")
+
+	bundle := &PolicyExport{
+		Label: "app:billing",
+		ACL:   []LabeledRule{{Values: []string{"alice", "report", "read", "deny"}, Labels: []string{"app:billing"}}},
+	}
+	result, err := service.ImportPolicies(bundle, importModeReplace, "deployer")
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if result.Imported[changeKindACL] != 1 || result.Removed[changeKindACL] != 1 {
+		t.Errorf("Unexpected counts: %+v", result)
+	}
+
+	// Only bob's billing rule is removed; carol's unlabeled rule and absent sections are kept
+	rules, _ := service.aclEnforcer.GetPolicy()
+	if len(rules) != 2 || strings.Join(rules[0], ",") != "carol,ticket,read,allow" || strings.Join(rules[1], ",") != "alice,report,read,deny" {
+		t.Errorf("Unexpected rules after replace: %v", rules)
+	}
+	if relationships, _ := service.relationshipGraph.ListRelationships(""); len(relationships) != 1 {
+		t.Errorf("Expected relationships to be untouched, got %v", relationships)
+	}
+
+	if _, err := service.ImportPolicies(bundle, "overwrite", "deployer"); err == nil {
+		t.Error("Expected an unknown mode to be rejected")
+	}
+}
+
+func TestBundle_CasbinCSV(t *testing.T) {
+	service := setupTestService(t)
+	router := setupBundleRouter(service)
+	csvPolicies := "# RBAC seed\np, editor, doc1, write, allow\np, contractor, doc1, delete, deny\ng, alice, editor\n"
+
+	req, _ := http.NewRequest("POST", "/api/v1/import?format=csv&model=rbac", strings.NewReader(csvPolicies))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("CSV import failed: %d %s", rr.Code, rr.Body.String())
+	}
+	if allowed, _ := service.Enforce(ModelRBAC, "alice", "doc1", "write", nil); !allowed {
+		t.Error("Expected alice to inherit editor's permission")
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/export?format=csv&model=rbac", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	expected := "p,editor,doc1,write,allow\np,contractor,doc1,delete,deny\ng,alice,editor\n"
+	if rr.Code != http.StatusOK || rr.Body.String() != expected || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/csv") {
+		t.Errorf("Unexpected CSV export: %d %q", rr.Code, rr.Body.String())
+	}
+
+	for _, url := range []string{"/api/v1/export?format=csv&model=abac", "/api/v1/export?format=csv"} {
+		req, _ = http.NewRequest("GET", url, nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", url, rr.Code)
+		}
+	}
+}
+
+func TestBundle_InvalidImportsChangeNothing(t *testing.T) {
+	service := setupTestService(t)
+	router := setupBundleRouter(service)
+
+	invalid := map[string]string{
+		"/api/v1/import":                      `{"acl": [{"values": ["alice", "report", "read"]}], "abac_policies": [{"id": "p1", "name": "P1", "effect": "maybe"}]}`,
+		"/api/v1/import?mode=overwrite":       `{"acl": []}`,
+		"/api/v1/import?format=csv&model=acl": "p, alice, report, read\ng, alice, admin\n",
+		"/api/v1/import?format=json":          `not json`,
+	}
+	for url, body := range invalid {
+		req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d %s", url, rr.Code, rr.Body.String())
+		}
+	}
+
+	if rules, _ := service.aclEnforcer.GetPolicy(); len(rules) != 0 {
+		t.Errorf("Expected nothing to be imported, got %v", rules)
+	}
+}
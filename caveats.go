@@ -0,0 +1,147 @@
+// Multi-Model Authorization Microservice - Caveated Relationships
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RelationshipCaveat limits a tuple to requests meeting its conditions, e.g. a viewer
+// tuple that only grants access from the corporate network. Every condition must hold.
+type RelationshipCaveat struct {
+	Conditions []CaveatCondition `json:"conditions"`
+}
+
+// CaveatCondition compares a request attribute with a value, like an ABAC environment
+// condition. "time" (the hour), "date" and "day" default to the current time.
+type CaveatCondition struct {
+	Attribute string `json:"attribute"`
+	Operator  string `json:"operator"` // An ABAC operator, e.g. "cidr", "in" or "gte"
+	Value     string `json:"value"`
+}
+
+// validate checks that a caveat can be evaluated
+func (c *RelationshipCaveat) validate() error {
+	if len(c.Conditions) == 0 {
+		return errors.New("caveat must have at least one condition")
+	}
+	for i, condition := range c.Conditions {
+		if condition.Attribute == "" {
+			return fmt.Errorf("caveat condition %d: attribute is required", i)
+		}
+		if !slices.Contains(abacOperators, condition.Operator) {
+			return fmt.Errorf("caveat condition %d: unsupported operator %q", i, condition.Operator)
+		}
+		if condition.Operator == "cidr" {
+			for _, cidr := range strings.Split(condition.Value, ",") {
+				if _, _, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+					return fmt.Errorf("caveat condition %d: invalid CIDR range %q", i, cidr)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// holds reports whether every condition is met by the environment of a request. Conditions
+// on attributes the request does not provide fail, so a check without context never passes
+// a caveated tuple.
+func (c *RelationshipCaveat) holds(environment map[string]string) bool {
+	var engine PolicyEngine
+	for _, condition := range c.Conditions {
+		actual, provided := environment[condition.Attribute]
+		if !provided || !engine.evaluateOperator(actual, condition.Operator, condition.Value, "") {
+			return false
+		}
+	}
+	return true
+}
+
+// trackCaveat remembers the caveat of an in-memory tuple. Like trackExpiry it must be
+// called before the tuple is added to memory: an unconditional copy of the tuple keeps it
+// unconditional, and of several caveated copies the latest caveat wins.
+func (rg *RelationshipGraph) trackCaveat(rel Relationship, caveat *RelationshipCaveat) {
+	if caveat == nil {
+		delete(rg.caveats, rel)
+		return
+	}
+	rg.hasCaveats = true
+
+	// An unconditional copy that is already resident keeps the tuple unconditional
+	_, caveated := rg.caveats[rel]
+	if rg.reverse.incoming[rel.Object][rel] > 0 && !caveated {
+		return
+	}
+	if rg.caveats == nil {
+		rg.caveats = make(map[Relationship]*RelationshipCaveat)
+	}
+	rg.caveats[rel] = caveat
+}
+
+// caveatHolds reports whether a resident tuple grants access in the graph's request
+// context: always for unconditional tuples, and for caveated tuples only when a context
+// was given and meets the caveat
+func (rg *RelationshipGraph) caveatHolds(rel Relationship) bool {
+	caveat, caveated := rg.caveats[rel]
+	return !caveated || (rg.caveatContext != nil && caveat.holds(rg.caveatContext))
+}
+
+// withCaveatContext returns a view of the graph that evaluates caveated tuples against the
+// attributes of a request. Graphs without caveats are returned as they are. The view
+// shares the graph's tuples and lock but not its check cache, as its results depend on
+// the request.
+func (rg *RelationshipGraph) withCaveatContext(attributes map[string]string) *RelationshipGraph {
+	// Taking the lock drops expired tuples, so the revision the base graph's cache relies on is bumped
+	unlock := rg.readLock()
+	defer unlock()
+	if !rg.hasCaveats {
+		return rg
+	}
+
+	view := *rg
+	view.checkCache = nil
+	view.caveatContext = environmentAttributes(attributes)
+	return &view
+}
+
+// RelationshipCaveats returns the caveats of the caveated tuples of subject (or of everyone
+// when subject is empty), keyed by "subject:relationship:object"
+func (rg *RelationshipGraph) RelationshipCaveats(subject string) (map[string]*RelationshipCaveat, error) {
+	var records []RelationshipRecord
+	now := time.Now()
+	query := rg.db.Order("id").Where("caveat IS NOT NULL AND (expires_at IS NULL OR expires_at > ?)", now)
+	if subject != "" {
+		query = query.Where("subject = ?", subject)
+	}
+	if err := query.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	// Of several caveated copies of a tuple the latest caveat wins
+	latest := make(map[Relationship]*RelationshipCaveat)
+	for _, record := range records {
+		latest[Relationship{Subject: record.Subject, Relationship: record.Relationship, Object: record.Object}] = record.Caveat
+	}
+
+	// An unconditional copy of a tuple grants access regardless of the caveats
+	caveats := make(map[string]*RelationshipCaveat, len(latest))
+	for rel, caveat := range latest {
+		var unconditional int64
+		if err := rg.db.Model(&RelationshipRecord{}).
+			Where("subject = ? AND relationship = ? AND object = ? AND caveat IS NULL AND (expires_at IS NULL OR expires_at > ?)", rel.Subject, rel.Relationship, rel.Object, now).
+			Count(&unconditional).Error; err != nil {
+			return nil, err
+		}
+		if unconditional == 0 {
+			caveats[labelKey(rel.Subject, rel.Relationship, rel.Object)] = caveat
+		}
+	}
+	return caveats, nil
+}
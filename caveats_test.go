@@ -0,0 +1,107 @@
+// Multi-Model Authorization Microservice - Caveated Relationship Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCaveats_GrantOnlyWhenConditionsHold(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	service.relationshipGraph.SetCheckCacheSize(100)
+
+	body := `{"subject": "alice", "relationship": "viewer", "object": "doc1",
+		"caveat": {"conditions": [{"attribute": "ip", "operator": "cidr", "value": "10.0.0.0/8, 192.168.0.0/16"}]}}`
+	req, _ := http.NewRequest("POST", "/api/v1/relationships", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Failed to add caveated relationship: %d %s", rr.Code, rr.Body.String())
+	}
+
+	for _, tc := range []struct {
+		attributes map[string]string
+		allowed    bool
+	}{
+		{map[string]string{"ip": "10.1.2.3"}, true},
+		{map[string]string{"ip": "192.168.5.1"}, true},
+		{map[string]string{"ip": "8.8.8.8"}, false},
+		{nil, false},
+	} {
+		if allowed, _ := service.Enforce(ModelReBAC, "alice", "doc1", "read", tc.attributes); allowed != tc.allowed {
+			t.Errorf("Expected allowed=%v with attributes %v", tc.allowed, tc.attributes)
+		}
+	}
+
+	// The tuple is listed with its caveat
+	req, _ = http.NewRequest("GET", "/api/v1/relationships?subject=alice", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var listing struct {
+		Caveats map[string]RelationshipCaveat `json:"caveats"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &listing)
+	if caveat, ok := listing.Caveats["alice:viewer:doc1"]; !ok || len(caveat.Conditions) != 1 {
+		t.Errorf("Expected the caveat to be listed, got %s", rr.Body.String())
+	}
+
+	// An unconditional copy grants access regardless of the caveat
+	service.relationshipGraph.AddRelationship("alice", "viewer", "doc1")
+	if allowed, _ := service.Enforce(ModelReBAC, "alice", "doc1", "read", nil); !allowed {
+		t.Error("Expected the unconditional copy to grant access")
+	}
+}
+
+func TestCaveats_ConditionalGroupMembership(t *testing.T) {
+	service := setupTestService(t)
+	rg := service.relationshipGraph
+
+	managed := &RelationshipCaveat{Conditions: []CaveatCondition{{Attribute: "device", Operator: "eq", Value: "managed"}}}
+	if err := rg.AddConditionalRelationship("bob", "member", "engineering", nil, managed); err != nil {
+		t.Fatalf("Failed to add caveated membership: %v", err)
+	}
+	rg.AddRelationship("engineering", "editor", "repo")
+
+	if allowed, _ := service.Enforce(ModelReBAC, "bob", "repo", "write", map[string]string{"device": "managed"}); !allowed {
+		t.Error("Expected access from a managed device")
+	}
+	if allowed, _ := service.Enforce(ModelReBAC, "bob", "repo", "write", map[string]string{"device": "personal"}); allowed {
+		t.Error("Expected no access from a personal device")
+	}
+
+	// Caveats survive a reload from the database
+	if err := rg.loadFromDatabase(); err != nil {
+		t.Fatalf("Failed to reload relationships: %v", err)
+	}
+	if allowed, _ := service.Enforce(ModelReBAC, "bob", "repo", "write", nil); allowed {
+		t.Error("Expected the reloaded membership to keep its caveat")
+	}
+}
+
+func TestCaveats_RejectsInvalidCaveats(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+
+	for _, caveat := range []string{
+		`{"conditions": []}`,
+		`{"conditions": [{"attribute": "ip", "operator": "cidr", "value": "10.0.0.0"}]}`,
+		`{"conditions": [{"attribute": "device", "operator": "like", "value": "managed"}]}`,
+	} {
+		body := `{"subject": "alice", "relationship": "viewer", "object": "doc1", "caveat": ` + caveat + `}`
+		req, _ := http.NewRequest("POST", "/api/v1/relationships", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for caveat %s, got %d", caveat, rr.Code)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Multi-Model Authorization Microservice - Command Line
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// runCommand executes a command-line subcommand such as "diff" or "selftest". It reports
+// false when args do not name a subcommand, in which case the server starts as usual.
+func runCommand(args []string, stdout io.Writer) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	switch args[0] {
+	case "diff":
+		return true, runDiffCommand(args[1:], stdout)
+	case "selftest":
+		return true, runSelfTestCommand(args[1:], stdout)
+	default:
+		return false, nil
+	}
+}
+
+// readExportFile loads an export bundle written by GET /api/v1/export
+func readExportFile(path string) (*PolicyExport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read export %s: %v", path, err)
+	}
+
+	var export PolicyExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("failed to parse export %s: %v", path, err)
+	}
+	return &export, nil
+}
+
+// runDiffCommand implements "diff [-json] <before.json> [after.json]". Without a second
+// bundle the first one is compared against the live database.
+func runDiffCommand(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the machine-readable change set")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		return fmt.Errorf("usage: diff [-json] <before.json> [after.json]")
+	}
+
+	before, err := readExportFile(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var after *PolicyExport
+	if flags.NArg() == 2 {
+		after, err = readExportFile(flags.Arg(1))
+	} else {
+		var service *AuthService
+		service, err = NewAuthService()
+		if err == nil {
+			after, err = service.ExportPolicies(before.Label)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	diff := DiffExports(before, after)
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(diff)
+	}
+	_, err = fmt.Fprint(stdout, diff.Text())
+	return err
+}
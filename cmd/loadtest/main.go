@@ -0,0 +1,150 @@
+// Multi-Model Authorization Microservice - Load Test Harness
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+// Command loadtest seeds a running authorization service with generated users, objects,
+// relationships and policies, replays a mixed authorization workload against it and
+// reports latency percentiles per model. The data and the workload are derived from a
+// seed, so runs with the same flags are reproducible.
+//
+// Usage:
+//
+//	go run ./cmd/loadtest -url http://localhost:8080 -users 1000 -requests 20000
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// config holds the size of the seeded data and of the workload
+type config struct {
+	URL         string         `json:"url"`
+	APIKey      string         `json:"-"`
+	Seed        int64          `json:"seed"`
+	Prefix      string         `json:"prefix"` // Prefix of every generated name, keeping the data apart from real data
+	Users       int            `json:"users"`
+	Objects     int            `json:"objects"`
+	Teams       int            `json:"teams"`
+	Roles       int            `json:"roles"`
+	Policies    int            `json:"policies"` // ACL rules, RBAC rules and ABAC policies (a tenth of it) to seed
+	Tuples      int            `json:"relationships"`
+	Requests    int            `json:"requests"`
+	Concurrency int            `json:"concurrency"`
+	Mix         map[string]int `json:"mix"` // Share of the workload per model
+	SkipSeed    bool           `json:"skip_seed"`
+	Timeout     time.Duration  `json:"-"`
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "loadtest: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run parses the flags, seeds the data unless asked not to, replays the workload and
+// prints the report
+func run(args []string, stdout io.Writer) error {
+	cfg, asJSON, err := parseFlags(args)
+	if err != nil {
+		return err
+	}
+
+	client := &apiClient{
+		baseURL: strings.TrimSuffix(cfg.URL, "/"),
+		apiKey:  cfg.APIKey,
+		http:    &http.Client{Timeout: cfg.Timeout},
+	}
+	data := generateDataset(cfg)
+
+	if !cfg.SkipSeed {
+		start := time.Now()
+		if err := seed(client, data, cfg.Concurrency); err != nil {
+			return fmt.Errorf("failed to seed data: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "seeded %d users, %d objects, %d tuples and %d rules in %s\n",
+			len(data.users), len(data.objects), len(data.tuples), len(data.aclRules)+len(data.rbacRules)+len(data.abacPolicies), time.Since(start).Round(time.Millisecond))
+	}
+
+	workload := generateWorkload(cfg, data)
+	report := replay(client, workload, cfg.Concurrency)
+	report.Config = cfg
+
+	if asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	_, err = fmt.Fprint(stdout, report.Text())
+	return err
+}
+
+// parseFlags reads the configuration from the command line
+func parseFlags(args []string) (*config, bool, error) {
+	flags := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	cfg := &config{}
+	flags.StringVar(&cfg.URL, "url", "http://localhost:8080", "base URL of the authorization service")
+	flags.StringVar(&cfg.APIKey, "api-key", os.Getenv("LOADTEST_API_KEY"), "admin API key, when authentication is enabled (default $LOADTEST_API_KEY)")
+	flags.Int64Var(&cfg.Seed, "seed", 1, "seed of the generated data and workload")
+	flags.StringVar(&cfg.Prefix, "prefix", "loadtest", "prefix of the generated names")
+	flags.IntVar(&cfg.Users, "users", 1000, "number of users")
+	flags.IntVar(&cfg.Objects, "objects", 1000, "number of objects")
+	flags.IntVar(&cfg.Teams, "teams", 50, "number of ReBAC teams")
+	flags.IntVar(&cfg.Roles, "roles", 20, "number of RBAC roles")
+	flags.IntVar(&cfg.Policies, "policies", 1000, "number of ACL and of RBAC rules; a tenth as many ABAC policies")
+	flags.IntVar(&cfg.Tuples, "relationships", 5000, "number of ReBAC relationship tuples")
+	flags.IntVar(&cfg.Requests, "requests", 10000, "number of authorization checks to replay")
+	flags.IntVar(&cfg.Concurrency, "concurrency", 16, "number of concurrent clients")
+	mix := flags.String("mix", "acl=25,rbac=25,abac=25,rebac=25", "share of the checks per model")
+	flags.BoolVar(&cfg.SkipSeed, "skip-seed", false, "replay against data seeded by an earlier run with the same flags")
+	flags.DurationVar(&cfg.Timeout, "timeout", 10*time.Second, "timeout of each request")
+	asJSON := flags.Bool("json", false, "print the machine-readable report")
+	if err := flags.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	if cfg.Users <= 0 || cfg.Objects <= 0 || cfg.Teams <= 0 || cfg.Roles <= 0 || cfg.Requests <= 0 || cfg.Concurrency <= 0 {
+		return nil, false, fmt.Errorf("users, objects, teams, roles, requests and concurrency must be positive")
+	}
+	if cfg.Policies < 0 || cfg.Tuples < 0 {
+		return nil, false, fmt.Errorf("policies and relationships must not be negative")
+	}
+	var err error
+	if cfg.Mix, err = parseMix(*mix); err != nil {
+		return nil, false, err
+	}
+	return cfg, *asJSON, nil
+}
+
+// parseMix reads a workload mix such as "acl=25,rbac=25,abac=25,rebac=25"
+func parseMix(value string) (map[string]int, error) {
+	mix := make(map[string]int)
+	total := 0
+	for _, entry := range strings.Split(value, ",") {
+		model, weight, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			return nil, fmt.Errorf("invalid mix entry %q: expected model=weight", entry)
+		}
+		if !isModel(model) {
+			return nil, fmt.Errorf("invalid mix entry %q: unknown model", entry)
+		}
+		share, err := strconv.Atoi(weight)
+		if err != nil || share < 0 {
+			return nil, fmt.Errorf("invalid mix entry %q: weight must be a non-negative integer", entry)
+		}
+		mix[model] += share
+		total += share
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("mix must give at least one model a positive weight")
+	}
+	return mix, nil
+}
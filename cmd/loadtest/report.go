@@ -0,0 +1,109 @@
+// Multi-Model Authorization Microservice - Load Test Report
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ModelStats summarizes the checks of one model; latencies are in milliseconds
+type ModelStats struct {
+	Model    string  `json:"model"`
+	Requests int     `json:"requests"`
+	Errors   int     `json:"errors"`
+	Allowed  int     `json:"allowed"`
+	P50      float64 `json:"p50_ms"`
+	P95      float64 `json:"p95_ms"`
+	P99      float64 `json:"p99_ms"`
+	Max      float64 `json:"max_ms"`
+}
+
+// Report is the outcome of a load test run
+type Report struct {
+	Config     *config      `json:"config"`
+	Requests   int          `json:"requests"`
+	Errors     int          `json:"errors"`
+	Duration   float64      `json:"duration_seconds"`
+	Throughput float64      `json:"requests_per_second"`
+	Models     []ModelStats `json:"models"`
+	Overall    ModelStats   `json:"overall"`
+}
+
+// newReport computes the statistics per model and overall
+func newReport(outcomes []outcome, elapsed time.Duration) *Report {
+	report := &Report{Requests: len(outcomes), Duration: elapsed.Seconds()}
+	if elapsed > 0 {
+		report.Throughput = float64(len(outcomes)) / elapsed.Seconds()
+	}
+
+	byModel := make(map[string][]outcome)
+	for _, o := range outcomes {
+		byModel[o.model] = append(byModel[o.model], o)
+		if o.err {
+			report.Errors++
+		}
+	}
+	for _, model := range models {
+		if len(byModel[model]) > 0 {
+			report.Models = append(report.Models, summarize(model, byModel[model]))
+		}
+	}
+	report.Overall = summarize("all", outcomes)
+	return report
+}
+
+// summarize computes the latency percentiles of a set of checks
+func summarize(model string, outcomes []outcome) ModelStats {
+	stats := ModelStats{Model: model, Requests: len(outcomes)}
+	latencies := make([]time.Duration, len(outcomes))
+	for i, o := range outcomes {
+		latencies[i] = o.latency
+		if o.err {
+			stats.Errors++
+		} else if o.allowed {
+			stats.Allowed++
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	stats.P50 = milliseconds(percentile(latencies, 50))
+	stats.P95 = milliseconds(percentile(latencies, 95))
+	stats.P99 = milliseconds(percentile(latencies, 99))
+	if len(latencies) > 0 {
+		stats.Max = milliseconds(latencies[len(latencies)-1])
+	}
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// milliseconds converts a duration to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// Text renders the report as a table for terminals
+func (report *Report) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d checks in %.2fs (%.0f/s), %d errors\n\n", report.Requests, report.Duration, report.Throughput, report.Errors)
+	fmt.Fprintf(&b, "%-6s %9s %7s %8s %9s %9s %9s %9s\n", "MODEL", "REQUESTS", "ERRORS", "ALLOWED", "P50(ms)", "P95(ms)", "P99(ms)", "MAX(ms)")
+	for _, stats := range append(report.Models, report.Overall) {
+		fmt.Fprintf(&b, "%-6s %9d %7d %8d %9.2f %9.2f %9.2f %9.2f\n", stats.Model, stats.Requests, stats.Errors, stats.Allowed, stats.P50, stats.P95, stats.P99, stats.Max)
+	}
+	return b.String()
+}
@@ -0,0 +1,79 @@
+// Multi-Model Authorization Microservice - Load Test Report Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPercentile_NearestRank(t *testing.T) {
+	var latencies []time.Duration
+	for i := 1; i <= 200; i++ {
+		latencies = append(latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	if got := percentile(latencies, 50); got != 100*time.Millisecond {
+		t.Errorf("Expected p50 of 100ms, got %s", got)
+	}
+	if got := percentile(latencies, 99); got != 198*time.Millisecond {
+		t.Errorf("Expected p99 of 198ms, got %s", got)
+	}
+	if got := percentile(latencies[:1], 95); got != time.Millisecond {
+		t.Errorf("Expected the only latency, got %s", got)
+	}
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("Expected 0 without latencies, got %s", got)
+	}
+}
+
+func TestReport_SummarizesPerModel(t *testing.T) {
+	report := newReport([]outcome{
+		{model: "acl", latency: 2 * time.Millisecond, allowed: true},
+		{model: "acl", latency: 4 * time.Millisecond},
+		{model: "rebac", latency: 8 * time.Millisecond, err: true},
+	}, time.Second)
+
+	if report.Errors != 1 || report.Throughput != 3 || len(report.Models) != 2 {
+		t.Fatalf("Expected 2 models with 1 error, got %+v", report)
+	}
+	if acl := report.Models[0]; acl.Model != "acl" || acl.Allowed != 1 || acl.P50 != 2 || acl.Max != 4 {
+		t.Errorf("Unexpected ACL stats: %+v", acl)
+	}
+	if report.Overall.Requests != 3 || report.Overall.P99 != 8 {
+		t.Errorf("Unexpected overall stats: %+v", report.Overall)
+	}
+}
+
+func TestWorkload_ReproducibleFromSeed(t *testing.T) {
+	cfg, _, err := parseFlags([]string{"-users", "10", "-objects", "10", "-requests", "100", "-mix", "acl=1,rebac=3"})
+	if err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	first := generateWorkload(cfg, generateDataset(cfg))
+	second := generateWorkload(cfg, generateDataset(cfg))
+	if !reflect.DeepEqual(first, second) {
+		t.Error("Expected the same workload for the same seed")
+	}
+	for _, c := range first {
+		if c.Model != "acl" && c.Model != "rebac" {
+			t.Fatalf("Expected only models of the mix, got %+v", c)
+		}
+	}
+
+	cfg.Seed = 2
+	if reflect.DeepEqual(first, generateWorkload(cfg, generateDataset(cfg))) {
+		t.Error("Expected another seed to change the workload")
+	}
+
+	if _, err := parseMix("acl=1,graph=2"); err == nil {
+		t.Error("Expected an unknown model to be rejected")
+	}
+	if _, err := parseMix("acl=0"); err == nil {
+		t.Error("Expected a mix without weight to be rejected")
+	}
+}
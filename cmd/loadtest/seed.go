@@ -0,0 +1,303 @@
+// Multi-Model Authorization Microservice - Load Test Data
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// models lists the access control models in report order
+var models = []string{"acl", "rbac", "abac", "rebac"}
+
+// isModel reports whether name is one of the access control models
+func isModel(name string) bool {
+	for _, model := range models {
+		if model == name {
+			return true
+		}
+	}
+	return false
+}
+
+// bulkSize matches the largest batch the bulk endpoints accept
+const bulkSize = 1000
+
+// departments are the ABAC department attribute values users and objects are spread over
+var departments = []string{"engineering", "sales", "finance", "hr", "legal", "support", "marketing", "operations"}
+
+// rule is an ACL or RBAC rule as sent to the bulk policy endpoints
+type rule struct {
+	Subject string `json:"subject"`
+	Object  string `json:"object"`
+	Action  string `json:"action"`
+	Effect  string `json:"effect,omitempty"`
+}
+
+// tuple is a ReBAC relationship tuple as sent to the bulk relationship endpoint
+type tuple struct {
+	Subject      string `json:"subject"`
+	Relationship string `json:"relationship"`
+	Object       string `json:"object"`
+}
+
+// abacCondition and abacPolicy mirror the ABAC policy payload of the service
+type abacCondition struct {
+	Type     string `json:"type"`
+	Field    string `json:"field"`
+	Operator string `json:"operator"`
+	Value    string `json:"value"`
+	LogicOp  string `json:"logic_op,omitempty"`
+}
+
+type abacPolicy struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Effect     string          `json:"effect"`
+	Priority   int             `json:"priority"`
+	Actions    []string        `json:"actions,omitempty"`
+	Conditions []abacCondition `json:"conditions"`
+}
+
+// dataset is the generated data, identical for every run with the same flags
+type dataset struct {
+	users        []string
+	objects      []string
+	roles        []string
+	userRoles    map[string]string
+	userDept     map[string]string
+	objectAttrs  map[string]map[string]string
+	aclRules     []rule
+	rbacRules    []rule
+	abacPolicies []abacPolicy
+	tuples       []tuple
+}
+
+// generateDataset derives the users, objects, rules, policies and tuples from the seed
+func generateDataset(cfg *config) *dataset {
+	rng := rand.New(rand.NewSource(cfg.Seed))
+	data := &dataset{
+		userRoles:   make(map[string]string, cfg.Users),
+		userDept:    make(map[string]string, cfg.Users),
+		objectAttrs: make(map[string]map[string]string, cfg.Objects),
+	}
+
+	for i := 0; i < cfg.Users; i++ {
+		data.users = append(data.users, fmt.Sprintf("%s_user_%d", cfg.Prefix, i))
+	}
+	for i := 0; i < cfg.Objects; i++ {
+		data.objects = append(data.objects, fmt.Sprintf("%s_doc_%d", cfg.Prefix, i))
+	}
+	for i := 0; i < cfg.Roles; i++ {
+		data.roles = append(data.roles, fmt.Sprintf("%s_role_%d", cfg.Prefix, i))
+	}
+	teams := make([]string, cfg.Teams)
+	for i := range teams {
+		teams[i] = fmt.Sprintf("%s_team_%d", cfg.Prefix, i)
+	}
+
+	abacPolicies := cfg.Policies / 10
+	if abacPolicies == 0 && cfg.Policies > 0 {
+		abacPolicies = 1
+	}
+
+	for _, user := range data.users {
+		data.userRoles[user] = data.roles[rng.Intn(len(data.roles))]
+		data.userDept[user] = departments[rng.Intn(len(departments))]
+	}
+	for _, object := range data.objects {
+		attributes := map[string]string{"department": departments[rng.Intn(len(departments))]}
+		if abacPolicies > 0 {
+			attributes["project"] = fmt.Sprintf("project_%d", rng.Intn(abacPolicies))
+		}
+		data.objectAttrs[object] = attributes
+	}
+
+	// Random rules repeat now and then; the service reports those as existing
+	for i := 0; i < cfg.Policies; i++ {
+		data.aclRules = append(data.aclRules, rule{
+			Subject: data.users[rng.Intn(len(data.users))],
+			Object:  data.objects[rng.Intn(len(data.objects))],
+			Action:  workloadActions["acl"][rng.Intn(len(workloadActions["acl"]))],
+			Effect:  "allow",
+		})
+		data.rbacRules = append(data.rbacRules, rule{
+			Subject: data.roles[rng.Intn(len(data.roles))],
+			Object:  data.objects[rng.Intn(len(data.objects))],
+			Action:  workloadActions["rbac"][rng.Intn(len(workloadActions["rbac"]))],
+			Effect:  "allow",
+		})
+	}
+
+	// Each ABAC policy lets one department read the objects of one project
+	for i := 0; i < abacPolicies; i++ {
+		data.abacPolicies = append(data.abacPolicies, abacPolicy{
+			ID:       fmt.Sprintf("%s_policy_%d", cfg.Prefix, i),
+			Name:     fmt.Sprintf("Load test policy %d", i),
+			Effect:   "allow",
+			Priority: i,
+			Actions:  []string{"read"},
+			Conditions: []abacCondition{
+				{Type: "user", Field: "department", Operator: "eq", Value: departments[rng.Intn(len(departments))], LogicOp: "and"},
+				{Type: "object", Field: "project", Operator: "eq", Value: fmt.Sprintf("project_%d", i)},
+			},
+		})
+	}
+
+	// Users join teams, teams are granted objects, and users own some objects directly
+	for i := 0; i < cfg.Tuples; i++ {
+		var t tuple
+		switch rng.Intn(3) {
+		case 0:
+			t = tuple{Subject: data.users[rng.Intn(len(data.users))], Relationship: "member", Object: teams[rng.Intn(len(teams))]}
+		case 1:
+			t = tuple{Subject: teams[rng.Intn(len(teams))], Relationship: []string{"viewer", "editor"}[rng.Intn(2)], Object: data.objects[rng.Intn(len(data.objects))]}
+		default:
+			t = tuple{Subject: data.users[rng.Intn(len(data.users))], Relationship: []string{"owner", "viewer", "editor"}[rng.Intn(3)], Object: data.objects[rng.Intn(len(data.objects))]}
+		}
+		data.tuples = append(data.tuples, t)
+	}
+	return data
+}
+
+// apiClient sends requests to the authorization service
+type apiClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+// do issues a request with a JSON body and returns the response status and body
+func (c *apiClient) do(method, path string, body interface{}) (int, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	message, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, message, err
+}
+
+// send issues a write and fails unless it succeeded. Conflicts are not errors, since a
+// previous run may already have seeded the same data.
+func (c *apiClient) send(method, path string, body interface{}) error {
+	status, message, err := c.do(method, path, body)
+	if err != nil {
+		return err
+	}
+	if status >= 300 && status != http.StatusConflict {
+		return fmt.Errorf("%s %s returned %d: %s", method, path, status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// seed writes the dataset through the service's API
+func seed(client *apiClient, data *dataset, concurrency int) error {
+	for start := 0; start < len(data.aclRules); start += bulkSize {
+		batch := data.aclRules[start:min(start+bulkSize, len(data.aclRules))]
+		if err := client.send("POST", "/api/v1/acl/policies/bulk", map[string]interface{}{"policies": batch}); err != nil {
+			return err
+		}
+	}
+	for start := 0; start < len(data.rbacRules); start += bulkSize {
+		batch := data.rbacRules[start:min(start+bulkSize, len(data.rbacRules))]
+		if err := client.send("POST", "/api/v1/rbac/policies/bulk", map[string]interface{}{"policies": batch}); err != nil {
+			return err
+		}
+	}
+	for start := 0; start < len(data.tuples); start += bulkSize {
+		batch := data.tuples[start:min(start+bulkSize, len(data.tuples))]
+		if err := client.send("POST", "/api/v1/relationships/bulk", map[string]interface{}{"relationships": batch}); err != nil {
+			return err
+		}
+	}
+	for i := range data.abacPolicies {
+		// Policies seeded by a previous run are replaced, since adding them again fails
+		policy := &data.abacPolicies[i]
+		status, _, err := client.do("GET", "/api/v1/abac/policies/"+url.PathEscape(policy.ID), nil)
+		if err != nil {
+			return err
+		}
+		if status == http.StatusOK {
+			err = client.send("PUT", "/api/v1/abac/policies/"+url.PathEscape(policy.ID), policy)
+		} else {
+			err = client.send("POST", "/api/v1/abac/policies", policy)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// Roles and attributes have no bulk endpoints, so they are written concurrently
+	var requests []func() error
+	for _, user := range data.users {
+		requests = append(requests, func() error {
+			return client.send("POST", "/api/v1/users/"+url.PathEscape(user)+"/roles", map[string]string{"role": data.userRoles[user]})
+		}, func() error {
+			return client.send("PUT", "/api/v1/users/"+url.PathEscape(user)+"/attributes", map[string]interface{}{"attributes": map[string]string{"department": data.userDept[user]}})
+		})
+	}
+	for _, object := range data.objects {
+		requests = append(requests, func() error {
+			return client.send("PUT", "/api/v1/objects/"+url.PathEscape(object)+"/attributes", map[string]interface{}{"object": object, "attributes": data.objectAttrs[object]})
+		})
+	}
+	return runConcurrently(requests, concurrency)
+}
+
+// runConcurrently runs the requests on at most concurrency goroutines and returns the
+// first error
+func runConcurrently(requests []func() error, concurrency int) error {
+	queue := make(chan func() error)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var first error
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for request := range queue {
+				if err := request(); err != nil {
+					mu.Lock()
+					if first == nil {
+						first = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, request := range requests {
+		queue <- request
+	}
+	close(queue)
+	wg.Wait()
+	return first
+}
@@ -0,0 +1,101 @@
+// Multi-Model Authorization Microservice - Load Test Workload
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// workloadActions are the actions checked per model
+var workloadActions = map[string][]string{
+	"acl":   {"read", "write"},
+	"rbac":  {"read", "write", "delete"},
+	"abac":  {"read"},
+	"rebac": {"read", "write", "delete"},
+}
+
+// check is one authorization request of the workload
+type check struct {
+	Model   string `json:"model"`
+	Subject string `json:"subject"`
+	Object  string `json:"object"`
+	Action  string `json:"action"`
+}
+
+// generateWorkload derives the checks from the seed, picking models by the mix. Checks
+// are generated up front, so the workload does not depend on the concurrency.
+func generateWorkload(cfg *config, data *dataset) []check {
+	rng := rand.New(rand.NewSource(cfg.Seed + 1))
+
+	total := 0
+	for _, model := range models {
+		total += cfg.Mix[model]
+	}
+
+	checks := make([]check, cfg.Requests)
+	for i := range checks {
+		pick := rng.Intn(total)
+		model := models[0]
+		for _, candidate := range models {
+			if pick < cfg.Mix[candidate] {
+				model = candidate
+				break
+			}
+			pick -= cfg.Mix[candidate]
+		}
+
+		actions := workloadActions[model]
+		checks[i] = check{
+			Model:   model,
+			Subject: data.users[rng.Intn(len(data.users))],
+			Object:  data.objects[rng.Intn(len(data.objects))],
+			Action:  actions[rng.Intn(len(actions))],
+		}
+	}
+	return checks
+}
+
+// outcome is the result of one check
+type outcome struct {
+	model   string
+	latency time.Duration
+	allowed bool
+	err     bool
+}
+
+// replay sends the checks on concurrency clients and collects their latencies
+func replay(client *apiClient, checks []check, concurrency int) *Report {
+	outcomes := make([]outcome, len(checks))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range queue {
+				began := time.Now()
+				status, _, err := client.do("POST", "/api/v1/authorizations", &checks[index])
+				outcomes[index] = outcome{
+					model:   checks[index].Model,
+					latency: time.Since(began),
+					allowed: status == http.StatusOK,
+					err:     err != nil || (status != http.StatusOK && status != http.StatusForbidden),
+				}
+			}
+		}()
+	}
+	for i := range checks {
+		queue <- i
+	}
+	close(queue)
+	wg.Wait()
+
+	return newReport(outcomes, time.Since(start))
+}
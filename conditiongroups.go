@@ -0,0 +1,113 @@
+// Multi-Model Authorization Microservice - ABAC Condition Groups
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+)
+
+// maxConditionGroupDepth bounds the nesting of condition groups
+const maxConditionGroupDepth = 8
+
+// ConditionGroup combines conditions and nested groups with one logic operator, so a
+// policy can express (A AND B) OR (C AND D). The logic_op of conditions inside a group is
+// ignored.
+type ConditionGroup struct {
+	Operator   string            `json:"operator"` // "and" or "or" ("and" when empty)
+	Conditions []PolicyCondition `json:"conditions,omitempty"`
+	Groups     []ConditionGroup  `json:"groups,omitempty"`
+}
+
+// evaluateGroup evaluates the conditions and nested groups of a group, stopping as soon as
+// the result is decided
+func (pe *PolicyEngine) evaluateGroup(group *ConditionGroup, ctx *PolicyEvaluationContext) bool {
+	// An "or" group is decided by its first true member, an "and" group by its first false one
+	decisive := group.Operator == "or"
+	for i := range group.Conditions {
+		if pe.evaluateCondition(&group.Conditions[i], ctx) == decisive {
+			return decisive
+		}
+	}
+	for i := range group.Groups {
+		if pe.evaluateGroup(&group.Groups[i], ctx) == decisive {
+			return decisive
+		}
+	}
+	return !decisive
+}
+
+// allConditions returns the conditions of a policy, including those inside condition
+// groups and, without an operator, the attributes its expression reads, e.g. to check them
+// against the attribute schema
+func (policy *ABACPolicy) allConditions() []PolicyCondition {
+	conditions := append([]PolicyCondition(nil), policy.Conditions...)
+	if policy.Expression != "" {
+		if compiled, err := policyExpressions.compile(policy.Expression); err == nil {
+			conditions = append(conditions, compiled.references...)
+		}
+	}
+	if policy.Group != nil {
+		policy.Group.walk(func(condition *PolicyCondition) {
+			conditions = append(conditions, *condition)
+		})
+	}
+	return conditions
+}
+
+// walk calls fn with every condition of the group and its nested groups
+func (group *ConditionGroup) walk(fn func(*PolicyCondition)) {
+	for i := range group.Conditions {
+		fn(&group.Conditions[i])
+	}
+	for i := range group.Groups {
+		group.Groups[i].walk(fn)
+	}
+}
+
+// mapConditions returns a copy of the group with every condition replaced by fn's result
+func (group *ConditionGroup) mapConditions(fn func(PolicyCondition) PolicyCondition) *ConditionGroup {
+	if group == nil {
+		return nil
+	}
+	mapped := &ConditionGroup{Operator: group.Operator}
+	for _, condition := range group.Conditions {
+		mapped.Conditions = append(mapped.Conditions, fn(condition))
+	}
+	for i := range group.Groups {
+		mapped.Groups = append(mapped.Groups, *group.Groups[i].mapConditions(fn))
+	}
+	return mapped
+}
+
+// validateConditionGroup rejects policies mixing flat conditions with a condition group,
+// unknown group operators, empty groups and groups nested too deeply
+func validateConditionGroup(policy *ABACPolicy) error {
+	if policy.Group == nil {
+		return nil
+	}
+	if len(policy.Conditions) > 0 {
+		return fmt.Errorf("a policy has either conditions or a condition_group, not both")
+	}
+	return policy.Group.validate(1)
+}
+
+// validate checks a group at the given nesting depth
+func (group *ConditionGroup) validate(depth int) error {
+	if depth > maxConditionGroupDepth {
+		return fmt.Errorf("condition groups can be nested at most %d levels deep", maxConditionGroupDepth)
+	}
+	if group.Operator != "" && group.Operator != "and" && group.Operator != "or" {
+		return fmt.Errorf("condition group operator must be 'and' or 'or', got %q", group.Operator)
+	}
+	if len(group.Conditions) == 0 && len(group.Groups) == 0 {
+		return fmt.Errorf("condition groups must contain conditions or groups")
+	}
+	for i := range group.Groups {
+		if err := group.Groups[i].validate(depth + 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
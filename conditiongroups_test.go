@@ -0,0 +1,98 @@
+// Multi-Model Authorization Microservice - ABAC Condition Group Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConditionGroups_NestedEvaluation(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/abac/policies", service.addABACPolicyHandler).Methods("POST")
+
+	post := func(body string) int {
+		req, _ := http.NewRequest("POST", "/api/v1/abac/policies", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// (finance AND manager) OR (audit AND level >= 5)
+	code := post(`{
+		"id": "ledger-access", "name": "Ledger access", "effect": "allow",
+		"condition_group": {"operator": "or", "groups": [
+			{"operator": "and", "conditions": [
+				{"type": "user", "field": "department", "operator": "eq", "value": "finance"},
+				{"type": "user", "field": "position", "operator": "eq", "value": "manager"}
+			]},
+			{"conditions": [
+				{"type": "user", "field": "department", "operator": "eq", "value": "audit"},
+				{"type": "user", "field": "level", "operator": "gte", "value": "5"}
+			]}
+		]}
+	}`)
+	if code != http.StatusOK {
+		t.Fatalf("Failed to add grouped policy: %d", code)
+	}
+
+	users := map[string]map[string]string{
+		"fiona": {"department": "finance", "position": "manager"},
+		"frank": {"department": "finance", "position": "analyst", "level": "9"},
+		"ann":   {"department": "audit", "level": "7"},
+		"andy":  {"department": "audit", "position": "manager", "level": "2"},
+	}
+	expected := map[string]bool{"fiona": true, "frank": false, "ann": true, "andy": false}
+	for user, attributes := range users {
+		for name, value := range attributes {
+			service.saveUserAttribute(user, name, value)
+		}
+	}
+
+	// Groups are stored with the policy and survive a reload
+	if err := service.policyEngine.LoadPolicies(); err != nil {
+		t.Fatalf("Failed to reload policies: %v", err)
+	}
+	for user, want := range expected {
+		if allowed, _ := service.Enforce(ModelABAC, user, "ledger", "read", nil); allowed != want {
+			t.Errorf("%s: expected %v", user, want)
+		}
+	}
+
+	for _, body := range []string{
+		`{"id": "mixed", "name": "Mixed", "effect": "allow", "conditions": [{"type": "user", "field": "a", "operator": "eq", "value": "1"}],
+		  "condition_group": {"conditions": [{"type": "user", "field": "b", "operator": "eq", "value": "2"}]}}`,
+		`{"id": "xor", "name": "Xor", "effect": "allow", "condition_group": {"operator": "xor", "conditions": [{"type": "user", "field": "a", "operator": "eq", "value": "1"}]}}`,
+		`{"id": "empty", "name": "Empty", "effect": "allow", "condition_group": {"operator": "or", "groups": [{"operator": "and"}]}}`,
+	} {
+		if code := post(body); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, code)
+		}
+	}
+}
+
+func TestConditionGroups_FlatConditionsUnchanged(t *testing.T) {
+	engine := &PolicyEngine{}
+	ctx := &PolicyEvaluationContext{UserAttributes: map[string]string{"a": "1", "b": "2"}}
+	flat := &ABACPolicy{Conditions: []PolicyCondition{
+		{Type: "user", Field: "a", Operator: "eq", Value: "0", LogicOp: "or"},
+		{Type: "user", Field: "b", Operator: "eq", Value: "2"},
+	}}
+	if !engine.evaluatePolicy(flat, ctx) {
+		t.Error("Expected flat conditions to combine with their logic_op")
+	}
+
+	grouped := &ABACPolicy{Group: &ConditionGroup{Conditions: flat.Conditions}}
+	if engine.evaluatePolicy(grouped, ctx) {
+		t.Error("Expected the group operator to override logic_op")
+	}
+	if conditions := grouped.allConditions(); len(conditions) != 2 {
+		t.Errorf("Expected group conditions to be listed, got %v", conditions)
+	}
+}
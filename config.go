@@ -0,0 +1,480 @@
+// Multi-Model Authorization Microservice - Configuration File
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// CONFIG_FILE names a YAML file that provides defaults for the environment variables in
+// configVariables; variables set in the environment win over the file. The file is read
+// again on SIGHUP and when polling notices it changed. A reload applies the tunable values
+// (cache sizes and TTLs, CORS origins, enabled models and traversal depths) to the running
+// service; the port and database only change on the next start.
+
+// defaultConfigReloadInterval is how often the configuration file is checked for changes
+// when reload_interval is not set
+const defaultConfigReloadInterval = 10 * time.Second
+
+// ServiceConfig is the structure of the configuration file
+type ServiceConfig struct {
+	Server struct {
+		Port string `yaml:"port"`
+	} `yaml:"server"`
+	Database struct {
+		Driver string `yaml:"driver"` // sqlite, postgres or mysql
+		DSN    string `yaml:"dsn"`
+		SQLite struct {
+			JournalMode string `yaml:"journal_mode"`
+			BusyTimeout string `yaml:"busy_timeout"`
+			Synchronous string `yaml:"synchronous"`
+		} `yaml:"sqlite"`
+		MaxOpenConns    int    `yaml:"max_open_conns"`
+		MaxIdleConns    int    `yaml:"max_idle_conns"`
+		ConnMaxLifetime string `yaml:"conn_max_lifetime"`
+		ConnMaxIdleTime string `yaml:"conn_max_idle_time"`
+	} `yaml:"database"`
+	Models []string `yaml:"models"` // Models accepting authorization requests; all when empty
+	Cache  struct {
+		DecisionSize   *int   `yaml:"decision_size"`
+		DecisionTTL    string `yaml:"decision_ttl"`
+		AttributeSize  *int   `yaml:"attribute_size"`
+		AttributeTTL   string `yaml:"attribute_ttl"`
+		ReBACCheckSize *int   `yaml:"rebac_check_size"`
+	} `yaml:"cache"`
+	CORS struct {
+		AllowedOrigins []string `yaml:"allowed_origins"`
+	} `yaml:"cors"`
+	ReBAC struct {
+		DefaultMaxDepth int `yaml:"default_max_depth"`
+		MaxDepthLimit   int `yaml:"max_depth_limit"`
+	} `yaml:"rebac"`
+	ReloadInterval string `yaml:"reload_interval"` // "0" disables polling; SIGHUP still reloads
+}
+
+// configVariables lists the environment variables the configuration file provides, and
+// whether a reload applies them to the running service
+var configVariables = map[string]bool{
+	"PORT":                      false,
+	"DB_DRIVER":                 false,
+	"DB_DSN":                    false,
+	"SQLITE_JOURNAL_MODE":       false,
+	"SQLITE_BUSY_TIMEOUT":       false,
+	"SQLITE_SYNCHRONOUS":        false,
+	"DB_MAX_OPEN_CONNS":         false,
+	"DB_MAX_IDLE_CONNS":         false,
+	"DB_CONN_MAX_LIFETIME":      false,
+	"DB_CONN_MAX_IDLE_TIME":     false,
+	"ENABLED_MODELS":            true,
+	"DECISION_CACHE_SIZE":       true,
+	"DECISION_CACHE_TTL":        true,
+	"ABAC_ATTRIBUTE_CACHE_SIZE": true,
+	"ABAC_ATTRIBUTE_CACHE_TTL":  true,
+	"REBAC_CHECK_CACHE_SIZE":    true,
+	"CORS_ALLOWED_ORIGINS":      true,
+	"REBAC_DEFAULT_MAX_DEPTH":   true,
+	"MAX_DEPTH_LIMIT":           true,
+}
+
+// parseServiceConfig decodes a configuration file, rejecting unknown keys
+func parseServiceConfig(data []byte) (*ServiceConfig, error) {
+	config := &ServiceConfig{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid configuration file: %v", err)
+	}
+	return config, nil
+}
+
+// environment returns the values of the environment variables the file sets
+func (c *ServiceConfig) environment() map[string]string {
+	env := make(map[string]string)
+	set := func(name, value string) {
+		if value != "" {
+			env[name] = value
+		}
+	}
+	setInt := func(name string, value *int) {
+		if value != nil {
+			env[name] = strconv.Itoa(*value)
+		}
+	}
+	setPositive := func(name string, value int) {
+		if value != 0 {
+			env[name] = strconv.Itoa(value)
+		}
+	}
+
+	set("PORT", c.Server.Port)
+	set("DB_DRIVER", c.Database.Driver)
+	set("DB_DSN", c.Database.DSN)
+	set("SQLITE_JOURNAL_MODE", c.Database.SQLite.JournalMode)
+	set("SQLITE_BUSY_TIMEOUT", c.Database.SQLite.BusyTimeout)
+	set("SQLITE_SYNCHRONOUS", c.Database.SQLite.Synchronous)
+	setPositive("DB_MAX_OPEN_CONNS", c.Database.MaxOpenConns)
+	setPositive("DB_MAX_IDLE_CONNS", c.Database.MaxIdleConns)
+	set("DB_CONN_MAX_LIFETIME", c.Database.ConnMaxLifetime)
+	set("DB_CONN_MAX_IDLE_TIME", c.Database.ConnMaxIdleTime)
+	set("ENABLED_MODELS", strings.Join(c.Models, ","))
+	setInt("DECISION_CACHE_SIZE", c.Cache.DecisionSize)
+	set("DECISION_CACHE_TTL", c.Cache.DecisionTTL)
+	setInt("ABAC_ATTRIBUTE_CACHE_SIZE", c.Cache.AttributeSize)
+	set("ABAC_ATTRIBUTE_CACHE_TTL", c.Cache.AttributeTTL)
+	setInt("REBAC_CHECK_CACHE_SIZE", c.Cache.ReBACCheckSize)
+	set("CORS_ALLOWED_ORIGINS", strings.Join(c.CORS.AllowedOrigins, ","))
+	setPositive("REBAC_DEFAULT_MAX_DEPTH", c.ReBAC.DefaultMaxDepth)
+	setPositive("MAX_DEPTH_LIMIT", c.ReBAC.MaxDepthLimit)
+	return env
+}
+
+// configFile is a loaded configuration file and the environment variables taken from it
+type configFile struct {
+	path     string
+	interval time.Duration // How often the file is checked for changes (0 disables polling)
+
+	mu       sync.Mutex
+	modTime  time.Time
+	provided map[string]string // Variables set from the file, with their values
+	external map[string]bool   // Variables set in the environment, which the file never changes
+}
+
+// loadConfigFile reads the file named by CONFIG_FILE and sets the environment variables it
+// provides that are not set already. It returns nil when CONFIG_FILE is not set.
+func loadConfigFile() (*configFile, error) {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CONFIG_FILE: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CONFIG_FILE: %v", err)
+	}
+	config, err := parseServiceConfig(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cf := &configFile{
+		path:     path,
+		interval: defaultConfigReloadInterval,
+		modTime:  info.ModTime(),
+		provided: make(map[string]string),
+		external: make(map[string]bool),
+	}
+	if config.ReloadInterval != "" {
+		cf.interval, err = time.ParseDuration(config.ReloadInterval)
+		if err != nil || cf.interval < 0 {
+			return nil, fmt.Errorf("invalid reload_interval value: %s", config.ReloadInterval)
+		}
+	}
+
+	env := config.environment()
+	for name := range configVariables {
+		if _, exists := os.LookupEnv(name); exists {
+			cf.external[name] = true
+			continue
+		}
+		if value, exists := env[name]; exists {
+			os.Setenv(name, value)
+			cf.provided[name] = value
+		}
+	}
+	return cf, nil
+}
+
+// reloadConfig reads the configuration file again and applies the tunable values it
+// changed. Nothing is applied when the file or one of its values is invalid.
+func (s *AuthService) reloadConfig(cf *configFile) error {
+	cf.mu.Lock()
+	defer cf.mu.Unlock()
+
+	info, err := os.Stat(cf.path)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file: %v", err)
+	}
+	data, err := os.ReadFile(cf.path)
+	if err != nil {
+		return fmt.Errorf("failed to read configuration file: %v", err)
+	}
+	config, err := parseServiceConfig(data)
+	if err != nil {
+		return err
+	}
+	cf.modTime = info.ModTime()
+
+	env := config.environment()
+	changed := make(map[string]bool)
+	for name, reloadable := range configVariables {
+		if cf.external[name] || env[name] == cf.provided[name] {
+			continue
+		}
+		if !reloadable {
+			log.Printf("%s changed in the configuration file; restart the service to apply it", name)
+			continue
+		}
+		changed[name] = true
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	// Set the new values and parse them like at startup, restoring the old ones on errors
+	setVariables := func(values map[string]string) {
+		for name := range changed {
+			if value, exists := values[name]; exists {
+				os.Setenv(name, value)
+			} else {
+				os.Unsetenv(name)
+			}
+		}
+	}
+	setVariables(env)
+	if err := s.applyTunables(changed); err != nil {
+		setVariables(cf.provided)
+		return err
+	}
+	for name := range changed {
+		if value, exists := env[name]; exists {
+			cf.provided[name] = value
+		} else {
+			delete(cf.provided, name)
+		}
+	}
+	return nil
+}
+
+// applyTunables applies the reloadable environment variables to the running service.
+// Caches are resized in place; enabling or disabling one takes a restart.
+func (s *AuthService) applyTunables(changed map[string]bool) error {
+	settings, err := runtimeSettingsFromEnv()
+	if err != nil {
+		return err
+	}
+	decisionSize, decisionTTL, err := decisionCacheConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	attributeSize, attributeTTL, err := attributeCacheConfigFromEnv()
+	if err != nil {
+		return err
+	}
+	checkSize, err := rebacCheckCacheSizeFromEnv()
+	if err != nil {
+		return err
+	}
+
+	s.settings.Store(settings)
+	if changed["DECISION_CACHE_SIZE"] || changed["DECISION_CACHE_TTL"] {
+		if s.decisions != nil && decisionSize > 0 {
+			s.decisions.resize(decisionSize, decisionTTL)
+		} else if (s.decisions == nil) != (decisionSize == 0) {
+			log.Printf("Enabling or disabling the decision cache takes a restart")
+		}
+	}
+	if changed["ABAC_ATTRIBUTE_CACHE_SIZE"] || changed["ABAC_ATTRIBUTE_CACHE_TTL"] {
+		if s.userAttrs != nil && attributeSize > 0 {
+			s.userAttrs.resize(attributeSize, attributeTTL)
+			s.objectAttrs.resize(attributeSize, attributeTTL)
+		} else if (s.userAttrs == nil) != (attributeSize == 0) {
+			log.Printf("Enabling or disabling the attribute caches takes a restart")
+		}
+	}
+	if changed["REBAC_CHECK_CACHE_SIZE"] {
+		s.relationshipGraph.SetCheckCacheSize(checkSize)
+	}
+	return nil
+}
+
+// watchConfigFile reloads the configuration file on SIGHUP and, unless polling is
+// disabled, when its modification time changes
+func (s *AuthService) watchConfigFile(cf *configFile) {
+	if cf == nil {
+		return
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	var tick <-chan time.Time
+	if cf.interval > 0 {
+		tick = time.NewTicker(cf.interval).C
+	}
+
+	reload := func(reason string) {
+		if err := s.reloadConfig(cf); err != nil {
+			serviceMetrics.Inc("config_reload_failures_total")
+			log.Printf("Configuration reload (%s) failed, keeping the current settings: %v", reason, err)
+			return
+		}
+		serviceMetrics.Inc("config_reloads_total")
+		log.Printf("Configuration reloaded from %s (%s)", cf.path, reason)
+	}
+
+	go func() {
+		for {
+			select {
+			case <-signals:
+				reload("SIGHUP")
+			case <-tick:
+				info, err := os.Stat(cf.path)
+				if err != nil {
+					continue
+				}
+				cf.mu.Lock()
+				modified := !info.ModTime().Equal(cf.modTime)
+				cf.mu.Unlock()
+				if modified {
+					reload("file changed")
+				}
+			}
+		}
+	}()
+}
+
+// databaseFromEnv returns the database named by DB_DRIVER (sqlite, postgres or mysql) and
+// DB_DSN. Without them the service uses the SQLite file casbin.db.
+func databaseFromEnv() (gorm.Dialector, error) {
+	driver, dsn := os.Getenv("DB_DRIVER"), os.Getenv("DB_DSN")
+	if dsn == "" && (driver == "" || driver == "sqlite") {
+		dsn = "casbin.db"
+	}
+	return dialectorFor("DB_DSN", driver, dsn)
+}
+
+// dialectorFor returns the dialector of a DB_DRIVER value connecting to dsn, which is read
+// from the variable dsnVar
+func dialectorFor(dsnVar, driver, dsn string) (gorm.Dialector, error) {
+	switch driver {
+	case "", "sqlite":
+		options, err := sqliteOptionsFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		return sqlite.Open(options.apply(dsn)), nil
+	case "postgres":
+		if dsn == "" {
+			return nil, fmt.Errorf("%s is required with DB_DRIVER=postgres", dsnVar)
+		}
+		return postgres.Open(dsn), nil
+	case "mysql":
+		if dsn == "" {
+			return nil, fmt.Errorf("%s is required with DB_DRIVER=mysql", dsnVar)
+		}
+		return mysql.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("invalid DB_DRIVER value: %s", driver)
+	}
+}
+
+// runtimeSettings are the values a configuration reload can change while the service runs
+type runtimeSettings struct {
+	defaultMaxDepth int                         // Traversal depth used when callers do not specify max_depth
+	maxDepthLimit   int                         // Absolute maximum traversal depth accepted from callers
+	corsOrigins     []string                    // Origins allowed to call the API; "*" allows any
+	enabledModels   map[AccessControlModel]bool // Models accepting authorization requests (nil enables all)
+}
+
+// defaultRuntimeSettings are the settings without any configuration
+var defaultRuntimeSettings = &runtimeSettings{
+	defaultMaxDepth: defaultMaxDepth,
+	maxDepthLimit:   defaultMaxDepthLimit,
+	corsOrigins:     []string{"*"},
+}
+
+// runtimeSettingsFromEnv reads REBAC_DEFAULT_MAX_DEPTH, MAX_DEPTH_LIMIT,
+// CORS_ALLOWED_ORIGINS and ENABLED_MODELS (comma-separated)
+func runtimeSettingsFromEnv() (*runtimeSettings, error) {
+	settings := *defaultRuntimeSettings
+
+	// Allow operators to tighten or relax the traversal depth cap
+	if limitStr := os.Getenv("MAX_DEPTH_LIMIT"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid MAX_DEPTH_LIMIT value: %s", limitStr)
+		}
+		settings.maxDepthLimit = limit
+	}
+	if depthStr := os.Getenv("REBAC_DEFAULT_MAX_DEPTH"); depthStr != "" {
+		depth, err := strconv.Atoi(depthStr)
+		if err != nil || depth <= 0 {
+			return nil, fmt.Errorf("invalid REBAC_DEFAULT_MAX_DEPTH value: %s", depthStr)
+		}
+		settings.defaultMaxDepth = depth
+	}
+
+	if originsStr := os.Getenv("CORS_ALLOWED_ORIGINS"); originsStr != "" {
+		settings.corsOrigins = nil
+		for _, origin := range strings.Split(originsStr, ",") {
+			if origin = strings.TrimSpace(origin); origin != "" {
+				settings.corsOrigins = append(settings.corsOrigins, origin)
+			}
+		}
+	}
+
+	if modelsStr := os.Getenv("ENABLED_MODELS"); modelsStr != "" {
+		settings.enabledModels = make(map[AccessControlModel]bool)
+		for _, name := range strings.Split(modelsStr, ",") {
+			model := AccessControlModel(strings.ToLower(strings.TrimSpace(name)))
+			switch model {
+			case ModelACL, ModelRBAC, ModelABAC, ModelReBAC:
+				settings.enabledModels[model] = true
+			default:
+				return nil, fmt.Errorf("invalid ENABLED_MODELS value: %s", modelsStr)
+			}
+		}
+	}
+
+	return &settings, nil
+}
+
+// currentSettings returns the settings in effect, or the defaults when none were stored
+func (s *AuthService) currentSettings() *runtimeSettings {
+	if settings := s.settings.Load(); settings != nil {
+		return settings
+	}
+	return defaultRuntimeSettings
+}
+
+// modelEnabled reports whether a model accepts authorization requests
+func (rs *runtimeSettings) modelEnabled(model AccessControlModel) bool {
+	return rs.enabledModels == nil || rs.enabledModels[model]
+}
+
+// allowedOrigin returns the Access-Control-Allow-Origin value for a request's origin, or
+// "" when the origin is not allowed
+func (rs *runtimeSettings) allowedOrigin(origin string) string {
+	for _, allowed := range rs.corsOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+		if origin != "" && strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
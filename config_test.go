@@ -0,0 +1,175 @@
+// Multi-Model Authorization Microservice - Configuration File Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setupTestConfigFile writes a configuration file and points CONFIG_FILE at it. The
+// variables the file may set are unset for the test and restored afterwards.
+func setupTestConfigFile(t *testing.T, content string) string {
+	for name := range configVariables {
+		if value, exists := os.LookupEnv(name); exists {
+			t.Cleanup(func() { os.Setenv(name, value) })
+		} else {
+			t.Cleanup(func() { os.Unsetenv(name) })
+		}
+		os.Unsetenv(name)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write configuration file: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	return path
+}
+
+func TestConfigFile_ProvidesDefaults(t *testing.T) {
+	setupTestConfigFile(t, `
+server:
+  port: "9000"
+database:
+  driver: sqlite
+  dsn: "file::memory:"
+models: [rbac, rebac]
+cache:
+  decision_size: 0
+  attribute_ttl: 1m
+cors:
+  allowed_origins: ["https://app.example.com"]
+rebac:
+  max_depth_limit: 20
+reload_interval: "0"
+`)
+	os.Setenv("MAX_DEPTH_LIMIT", "15")
+
+	cf, err := loadConfigFile()
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	for name, expected := range map[string]string{
+		"PORT":                     "9000",
+		"DB_DSN":                   "file::memory:",
+		"ENABLED_MODELS":           "rbac,rebac",
+		"DECISION_CACHE_SIZE":      "0",
+		"ABAC_ATTRIBUTE_CACHE_TTL": "1m",
+		"CORS_ALLOWED_ORIGINS":     "https://app.example.com",
+		"MAX_DEPTH_LIMIT":          "15", // The environment wins
+	} {
+		if value := os.Getenv(name); value != expected {
+			t.Errorf("Expected %s=%s, got %q", name, expected, value)
+		}
+	}
+	if cf.interval != 0 {
+		t.Errorf("Expected polling to be disabled, got %v", cf.interval)
+	}
+
+	settings, err := runtimeSettingsFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to read settings: %v", err)
+	}
+	if settings.maxDepthLimit != 15 || settings.modelEnabled(ModelACL) || !settings.modelEnabled(ModelReBAC) {
+		t.Errorf("Unexpected settings: %+v", settings)
+	}
+
+	for name, content := range map[string]string{
+		"unknown key":  "cache:\n  decision_sizes: 10\n",
+		"bad interval": "reload_interval: soon\n",
+		"invalid YAML": "models: [rbac\n",
+		"wrong type":   "rebac:\n  max_depth_limit: deep\n",
+	} {
+		setupTestConfigFile(t, content)
+		if _, err := loadConfigFile(); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestConfigFile_Reload(t *testing.T) {
+	path := setupTestConfigFile(t, "server:\n  port: \"9000\"\nrebac:\n  max_depth_limit: 8\n")
+	cf, err := loadConfigFile()
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+	service := setupTestService(t)
+	settings, _ := runtimeSettingsFromEnv()
+	service.settings.Store(settings)
+	service.enableDecisionCache(100, time.Minute)
+
+	if depth, _ := service.parseMaxDepth("50"); depth != 8 {
+		t.Errorf("Expected max_depth to be capped at 8, got %d", depth)
+	}
+
+	os.WriteFile(path, []byte(`
+server:
+  port: "9100"
+models: [acl]
+cache:
+  decision_size: 10
+  attribute_size: 5
+  rebac_check_size: 0
+cors:
+  allowed_origins: ["https://app.example.com"]
+rebac:
+  default_max_depth: 3
+  max_depth_limit: 12
+`), 0o600)
+	if err := service.reloadConfig(cf); err != nil {
+		t.Fatalf("Reload failed: %v", err)
+	}
+
+	if depth, _ := service.parseMaxDepth("50"); depth != 12 {
+		t.Errorf("Expected the new depth limit to apply, got %d", depth)
+	}
+	if depth, _ := service.parseMaxDepth(""); depth != 3 {
+		t.Errorf("Expected the new default depth to apply, got %d", depth)
+	}
+	if stats := service.decisions.Stats(); stats.Capacity != 10 {
+		t.Errorf("Expected the decision cache to be resized, got %+v", stats)
+	}
+	if stats := service.userAttrs.Stats(); stats.Capacity != 5 {
+		t.Errorf("Expected the attribute cache to be resized, got %+v", stats)
+	}
+	if service.relationshipGraph.checkCache != nil {
+		t.Error("Expected the ReBAC check cache to be disabled")
+	}
+	if _, err := service.Enforce(ModelRBAC, "alice", "data", "read", nil); err == nil {
+		t.Error("Expected RBAC checks to fail once the model is disabled")
+	}
+	if os.Getenv("PORT") != "9000" {
+		t.Errorf("Expected the port to need a restart, got %s", os.Getenv("PORT"))
+	}
+
+	// Only allowed origins are echoed back
+	handler := service.corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for origin, expected := range map[string]string{"https://app.example.com": "https://app.example.com", "https://evil.example.com": ""} {
+		req := httptest.NewRequest("GET", "/api/v1/health", nil)
+		req.Header.Set("Origin", origin)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if allowed := rr.Header().Get("Access-Control-Allow-Origin"); allowed != expected {
+			t.Errorf("Origin %s: expected %q, got %q", origin, expected, allowed)
+		}
+	}
+
+	// An invalid value keeps the current settings
+	os.WriteFile(path, []byte("rebac:\n  max_depth_limit: 4\ncache:\n  decision_ttl: -1s\n"), 0o600)
+	if err := service.reloadConfig(cf); err == nil {
+		t.Error("Expected an invalid TTL to fail the reload")
+	}
+	if depth, _ := service.parseMaxDepth("50"); depth != 12 {
+		t.Errorf("Expected the previous depth limit to be kept, got %d", depth)
+	}
+	if os.Getenv("MAX_DEPTH_LIMIT") != "12" {
+		t.Errorf("Expected the previous environment to be restored, got %s", os.Getenv("MAX_DEPTH_LIMIT"))
+	}
+}
@@ -0,0 +1,146 @@
+// Multi-Model Authorization Microservice - ReBAC Consistency Tokens
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// consistencyTokenPrefix marks the payload of a consistency token
+const consistencyTokenPrefix = "rebac:"
+
+// errInvalidConsistencyToken is returned for tokens this service did not issue
+var errInvalidConsistencyToken = errors.New("invalid consistency token")
+
+// RelationshipSequence is the single-row counter of relationship and namespace writes,
+// shared by every instance using the database. Consistency tokens carry its value.
+type RelationshipSequence struct {
+	ID    uint   `gorm:"primaryKey"`
+	Value uint64 // Number of writes so far
+}
+
+// encodeConsistencyToken returns the opaque token of a write sequence number, similar to
+// Zanzibar's zookies
+func encodeConsistencyToken(sequence uint64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(consistencyTokenPrefix + strconv.FormatUint(sequence, 10)))
+}
+
+// decodeConsistencyToken returns the write sequence number of a token
+func decodeConsistencyToken(token string) (uint64, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || !strings.HasPrefix(string(payload), consistencyTokenPrefix) {
+		return 0, errInvalidConsistencyToken
+	}
+	sequence, err := strconv.ParseUint(strings.TrimPrefix(string(payload), consistencyTokenPrefix), 10, 64)
+	if err != nil {
+		return 0, errInvalidConsistencyToken
+	}
+	return sequence, nil
+}
+
+// migrateRelationshipSequence creates the sequence table and its row
+func migrateRelationshipSequence(db *gorm.DB) error {
+	if err := db.AutoMigrate(&RelationshipSequence{}); err != nil {
+		return fmt.Errorf("failed to migrate relationship sequence table: %v", err)
+	}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&RelationshipSequence{ID: 1}).Error; err != nil {
+		return fmt.Errorf("failed to initialize relationship sequence: %v", err)
+	}
+	return nil
+}
+
+// currentSequence reads the latest write sequence number from the database
+func currentSequence(db *gorm.DB) (uint64, error) {
+	var sequence RelationshipSequence
+	if err := db.First(&sequence, 1).Error; err != nil {
+		return 0, fmt.Errorf("failed to read relationship sequence: %v", err)
+	}
+	return sequence.Value, nil
+}
+
+// syncSequence marks the graph as reflecting every write up to the current sequence.
+// Call it before reading the tuples, so writes racing the load are not counted.
+func (rg *RelationshipGraph) syncSequence() error {
+	sequence, err := currentSequence(rg.db)
+	if err != nil {
+		return err
+	}
+	atomic.StoreUint64(&rg.sequence, sequence)
+	return nil
+}
+
+// recordWrite advances the shared write sequence after a tuple or namespace change. The
+// graph's own sequence only follows when no other instance wrote in between, since their
+// changes are not in memory.
+func (rg *RelationshipGraph) recordWrite() error {
+	var sequence uint64
+	err := rg.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&RelationshipSequence{}).Where("id = ?", 1).
+			UpdateColumn("value", gorm.Expr("value + 1")).Error; err != nil {
+			return err
+		}
+		var err error
+		sequence, err = currentSequence(tx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to advance relationship sequence: %v", err)
+	}
+	atomic.CompareAndSwapUint64(&rg.sequence, sequence-1, sequence)
+	return nil
+}
+
+// ConsistencyToken returns a token covering every write committed so far
+func (rg *RelationshipGraph) ConsistencyToken() (string, error) {
+	sequence, err := currentSequence(rg.db)
+	if err != nil {
+		return "", err
+	}
+	return encodeConsistencyToken(sequence), nil
+}
+
+// satisfies reports whether the in-memory graph is at least as fresh as a token's write
+func (rg *RelationshipGraph) satisfies(sequence uint64) bool {
+	return atomic.LoadUint64(&rg.sequence) >= sequence
+}
+
+// freshnessForToken returns the freshness an enforce request needs to honor its consistency
+// token: strong when the graph deciding the model may not contain the token's write yet
+func (s *AuthService) freshnessForToken(model AccessControlModel, freshness, token string) (string, error) {
+	if token == "" || freshness == freshnessStrong {
+		return freshness, nil
+	}
+	sequence, err := decodeConsistencyToken(token)
+	if err != nil {
+		return "", err
+	}
+
+	usesGraph := model == ModelReBAC || ((model == ModelRBAC || model == "") && s.rbacGroupBindings)
+	if usesGraph && s.relationshipGraph != nil && !s.relationshipGraph.satisfies(sequence) {
+		serviceMetrics.Inc("rebac_consistency_token_upgrades_total")
+		return freshnessStrong, nil
+	}
+	return freshness, nil
+}
+
+// addConsistencyToken adds the token covering a write to its response. The write has
+// already succeeded, so a failure to read the sequence only omits the token.
+func (rg *RelationshipGraph) addConsistencyToken(response map[string]interface{}) {
+	token, err := rg.ConsistencyToken()
+	if err != nil {
+		log.Printf("Consistency token error: %v", err)
+		return
+	}
+	response["consistency_token"] = token
+}
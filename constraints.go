@@ -0,0 +1,113 @@
+// Multi-Model Authorization Microservice - ReBAC Relationship Constraints
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// RelationshipConstraint limits how many tuples of a relationship type may share
+// the same object (e.g. at most one owner) or the same subject (e.g. at most N groups)
+type RelationshipConstraint struct {
+	Relationship string `json:"relationship"`
+	Per          string `json:"per"` // "object" or "subject"
+	Max          int    `json:"max"`
+}
+
+// CardinalityError is returned when a write would exceed a relationship constraint
+type CardinalityError struct {
+	Constraint RelationshipConstraint `json:"constraint"`
+	Scope      string                 `json:"scope"` // The object or subject the limit applies to
+	Current    int64                  `json:"current"`
+}
+
+func (e *CardinalityError) Error() string {
+	return fmt.Sprintf("%s %s already has %d %s relationship(s), the maximum is %d",
+		e.Constraint.Per, e.Scope, e.Current, e.Constraint.Relationship, e.Constraint.Max)
+}
+
+// parseRelationshipConstraints parses a comma-separated list of relationship:per:max
+// entries, e.g. "owner:object:1,member:subject:10"
+func parseRelationshipConstraints(spec string) ([]RelationshipConstraint, error) {
+	var constraints []RelationshipConstraint
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.Split(entry, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid relationship constraint %q, expected relationship:per:max", entry)
+		}
+		if parts[1] != "object" && parts[1] != "subject" {
+			return nil, fmt.Errorf("invalid relationship constraint %q, per must be 'object' or 'subject'", entry)
+		}
+		max, err := strconv.Atoi(parts[2])
+		if err != nil || max <= 0 {
+			return nil, fmt.Errorf("invalid relationship constraint %q, max must be a positive integer", entry)
+		}
+
+		constraints = append(constraints, RelationshipConstraint{Relationship: parts[0], Per: parts[1], Max: max})
+	}
+	return constraints, nil
+}
+
+// checkConstraints verifies that adding the tuple keeps every matching constraint satisfied.
+// Counts are read from the database so they also cover partitions that are not resident.
+func (rg *RelationshipGraph) checkConstraints(subject, relationship, object string) error {
+	return rg.checkConstraintsIn(rg.db, subject, relationship, object)
+}
+
+// checkConstraintsIn checks the constraints against db, which can be a transaction that
+// already holds earlier writes of a batch
+func (rg *RelationshipGraph) checkConstraintsIn(db *gorm.DB, subject, relationship, object string) error {
+	for _, constraint := range rg.constraints {
+		if constraint.Relationship != relationship {
+			continue
+		}
+
+		// Re-adding an existing tuple does not change the count
+		query := db.Model(&RelationshipRecord{}).Where("relationship = ?", relationship)
+		scope := object
+		if constraint.Per == "object" {
+			query = query.Where("object = ? AND subject <> ?", object, subject)
+		} else {
+			scope = subject
+			query = query.Where("subject = ? AND object <> ?", subject, object)
+		}
+
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check relationship constraints: %v", err)
+		}
+		if count >= int64(constraint.Max) {
+			return &CardinalityError{Constraint: constraint, Scope: scope, Current: count}
+		}
+	}
+	return nil
+}
+
+// getRelationshipConstraintsHandler lists the configured relationship constraints
+func (s *AuthService) getRelationshipConstraintsHandler(w http.ResponseWriter, r *http.Request) {
+	constraints := s.relationshipGraph.constraints
+	if constraints == nil {
+		constraints = []RelationshipConstraint{}
+	}
+
+	response := map[string]interface{}{
+		"constraints": constraints,
+		"model":       "rebac",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,69 @@
+// Multi-Model Authorization Microservice - Relationship Constraint Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConstraints_Parse(t *testing.T) {
+	constraints, err := parseRelationshipConstraints(" owner:object:1, member:subject:2 ,")
+	if err != nil {
+		t.Fatalf("Failed to parse constraints: %v", err)
+	}
+	if len(constraints) != 2 || constraints[1] != (RelationshipConstraint{Relationship: "member", Per: "subject", Max: 2}) {
+		t.Errorf("Unexpected constraints: %+v", constraints)
+	}
+
+	for _, spec := range []string{"owner:object", "owner:group:1", "owner:object:0"} {
+		if _, err := parseRelationshipConstraints(spec); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestConstraints_EnforcedOnWrite(t *testing.T) {
+	service := setupTestService(t)
+	service.relationshipGraph.constraints = []RelationshipConstraint{
+		{Relationship: "owner", Per: "object", Max: 1},
+		{Relationship: "member", Per: "subject", Max: 2},
+	}
+	rg := service.relationshipGraph
+
+	if err := rg.AddRelationship("alice", "owner", "doc1"); err != nil {
+		t.Fatalf("First owner should be accepted: %v", err)
+	}
+	var cardinalityErr *CardinalityError
+	if err := rg.AddRelationship("bob", "owner", "doc1"); !errors.As(err, &cardinalityErr) {
+		t.Errorf("Expected cardinality error for second owner, got %v", err)
+	}
+	if rg.HasDirectRelationship("bob", "owner", "doc1") {
+		t.Error("Rejected tuple must not be stored")
+	}
+	if err := rg.AddRelationship("bob", "owner", "doc2"); err != nil {
+		t.Errorf("Owner of another object should be accepted: %v", err)
+	}
+	if err := rg.AddRelationship("bob", "editor", "doc1"); err != nil {
+		t.Errorf("Unconstrained relationship should be accepted: %v", err)
+	}
+
+	rg.AddRelationship("carol", "member", "team1")
+	rg.AddRelationship("carol", "member", "team2")
+	if err := rg.AddRelationship("carol", "member", "team3"); !errors.As(err, &cardinalityErr) {
+		t.Errorf("Expected cardinality error for third group, got %v", err)
+	}
+
+	router := setupTestRouter(service)
+	req, _ := http.NewRequest("POST", "/api/v1/relationships", bytes.NewBufferString(`{"subject": "dave", "relationship": "owner", "object": "doc1"}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d", rr.Code)
+	}
+}
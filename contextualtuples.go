@@ -0,0 +1,36 @@
+// Multi-Model Authorization Microservice - Contextual Tuples
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+// maxContextualTuples bounds the contextual tuples a single check may assume
+const maxContextualTuples = 100
+
+// withContextualTuples returns a view of the graph that also holds tuples, for a check that
+// assumes them without storing them, e.g. "assume alice is a member of incident_team". The
+// view reads stored tuples from the database like a strong read, so neither the graph nor
+// its check cache ever see the contextual tuples.
+func (rg *RelationshipGraph) withContextualTuples(tuples []Relationship) *RelationshipGraph {
+	if len(tuples) == 0 {
+		return rg
+	}
+
+	view := rg.requestView()
+	for _, rel := range tuples {
+		view.addToMemory(rel.Subject, rel.Relationship, rel.Object)
+	}
+	return view
+}
+
+// qualifyTuples returns tuples with tenant-qualified subjects and objects
+func (t tenantScope) qualifyTuples(tuples []Relationship) []Relationship {
+	if len(tuples) == 0 || t.global() {
+		return tuples
+	}
+	qualified := make([]Relationship, len(tuples))
+	for i, rel := range tuples {
+		qualified[i] = Relationship{Subject: t.qualify(rel.Subject), Relationship: rel.Relationship, Object: t.qualify(rel.Object)}
+	}
+	return qualified
+}
@@ -0,0 +1,84 @@
+// Multi-Model Authorization Microservice - Contextual Tuple Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextualTuples_AssumedForOneCheck(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/authorizations", service.authorizationHandler).Methods("POST")
+	if err := service.enableDecisionCache(100, time.Minute); err != nil {
+		t.Fatalf("Failed to enable the decision cache: %v", err)
+	}
+	service.relationshipGraph.SetCheckCacheSize(100)
+	service.relationshipGraph.AddRelationship("incident_team", "group_access", "runbook")
+
+	check := func(body string) (int, EnforceResponse) {
+		req, _ := http.NewRequest("POST", "/api/v1/authorizations", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var response EnforceResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr.Code, response
+	}
+	plain := `{"model": "rebac", "subject": "alice", "object": "runbook", "action": "read"}`
+	assumed := `{"model": "rebac", "subject": "alice", "object": "runbook", "action": "read", "explain": true,
+		"contextual_tuples": [{"subject": "alice", "relationship": "member", "object": "incident_team"}]}`
+
+	if code, _ := check(plain); code != http.StatusForbidden {
+		t.Fatalf("Expected alice to be denied without the contextual tuple, got %d", code)
+	}
+	code, response := check(assumed)
+	if code != http.StatusOK || response.Explanation == nil || len(response.Explanation.Path) != 2 {
+		t.Fatalf("Expected the contextual membership to grant access through the team, got %d %+v", code, response)
+	}
+
+	// Neither the graph, its check cache nor the decision cache keep the assumption
+	if code, _ := check(plain); code != http.StatusForbidden {
+		t.Errorf("Expected alice to be denied again, got %d", code)
+	}
+	if service.relationshipGraph.HasDirectRelationship("alice", "member", "incident_team") {
+		t.Error("Expected the contextual tuple not to be stored")
+	}
+}
+
+func TestContextualTuples_Validation(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/authorizations", service.authorizationHandler).Methods("POST")
+
+	tuples := make([]string, maxContextualTuples+1)
+	for i := range tuples {
+		tuples[i] = fmt.Sprintf(`{"subject": "user%d", "relationship": "member", "object": "team"}`, i)
+	}
+	for _, body := range []string{
+		`{"model": "acl", "subject": "alice", "object": "doc", "action": "read",
+			"contextual_tuples": [{"subject": "alice", "relationship": "member", "object": "team"}]}`,
+		// RBAC only reads tuples with group bindings
+		`{"model": "rbac", "subject": "alice", "object": "doc", "action": "read",
+			"contextual_tuples": [{"subject": "alice", "relationship": "member", "object": "team"}]}`,
+		`{"model": "rebac", "subject": "alice", "object": "doc", "action": "read",
+			"contextual_tuples": [{"subject": "alice", "relationship": "", "object": "team"}]}`,
+		`{"model": "rebac", "subject": "alice", "object": "doc", "action": "read", "contextual_tuples": [` + strings.Join(tuples, ",") + `]}`,
+	} {
+		req, _ := http.NewRequest("POST", "/api/v1/authorizations", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %.80s, got %d %s", body, rr.Code, rr.Body.String())
+		}
+	}
+}
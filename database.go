@@ -0,0 +1,161 @@
+// Multi-Model Authorization Microservice - Database Connections
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Defaults of the SQLite pragmas. WAL lets checks read while a write commits, and the busy
+// timeout makes concurrent writers wait for the lock instead of failing with "database is
+// locked". NORMAL synchronization is durable in WAL mode except on power loss.
+const (
+	defaultSQLiteJournalMode = "WAL"
+	defaultSQLiteBusyTimeout = 5 * time.Second
+	defaultSQLiteSynchronous = "NORMAL"
+)
+
+// validSQLiteJournalModes and validSQLiteSynchronous list the accepted pragma values
+var (
+	validSQLiteJournalModes = map[string]bool{"DELETE": true, "TRUNCATE": true, "PERSIST": true, "MEMORY": true, "WAL": true, "OFF": true}
+	validSQLiteSynchronous  = map[string]bool{"OFF": true, "NORMAL": true, "FULL": true, "EXTRA": true}
+)
+
+// sqliteOptions are the pragmas applied to every SQLite connection
+type sqliteOptions struct {
+	journalMode string
+	busyTimeout time.Duration
+	synchronous string
+}
+
+// sqliteOptionsFromEnv reads SQLITE_JOURNAL_MODE, SQLITE_BUSY_TIMEOUT and SQLITE_SYNCHRONOUS
+func sqliteOptionsFromEnv() (*sqliteOptions, error) {
+	options := &sqliteOptions{
+		journalMode: defaultSQLiteJournalMode,
+		busyTimeout: defaultSQLiteBusyTimeout,
+		synchronous: defaultSQLiteSynchronous,
+	}
+
+	if mode := os.Getenv("SQLITE_JOURNAL_MODE"); mode != "" {
+		if !validSQLiteJournalModes[strings.ToUpper(mode)] {
+			return nil, fmt.Errorf("invalid SQLITE_JOURNAL_MODE value: %s", mode)
+		}
+		options.journalMode = strings.ToUpper(mode)
+	}
+	if timeoutStr := os.Getenv("SQLITE_BUSY_TIMEOUT"); timeoutStr != "" {
+		timeout, err := time.ParseDuration(timeoutStr)
+		if err != nil || timeout < 0 {
+			return nil, fmt.Errorf("invalid SQLITE_BUSY_TIMEOUT value: %s", timeoutStr)
+		}
+		options.busyTimeout = timeout
+	}
+	if synchronous := os.Getenv("SQLITE_SYNCHRONOUS"); synchronous != "" {
+		if !validSQLiteSynchronous[strings.ToUpper(synchronous)] {
+			return nil, fmt.Errorf("invalid SQLITE_SYNCHRONOUS value: %s", synchronous)
+		}
+		options.synchronous = strings.ToUpper(synchronous)
+	}
+	return options, nil
+}
+
+// apply adds the pragmas to a SQLite DSN as connection parameters, so the driver sets them
+// on every connection of the pool. Parameters already in the DSN are kept.
+func (options *sqliteOptions) apply(dsn string) string {
+	params := []struct{ name, value string }{
+		{"_journal_mode", options.journalMode},
+		{"_busy_timeout", strconv.FormatInt(options.busyTimeout.Milliseconds(), 10)},
+		{"_synchronous", options.synchronous},
+	}
+
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	for _, param := range params {
+		if strings.Contains(dsn, param.name+"=") {
+			continue
+		}
+		dsn += separator + param.name + "=" + param.value
+		separator = "&"
+	}
+	return dsn
+}
+
+// connectionPool sizes the pool of database connections. Zero values keep the defaults of
+// database/sql.
+type connectionPool struct {
+	maxOpen     int
+	maxIdle     int
+	maxLifetime time.Duration
+	maxIdleTime time.Duration
+}
+
+// connectionPoolFromEnv reads DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS, DB_CONN_MAX_LIFETIME and
+// DB_CONN_MAX_IDLE_TIME
+func connectionPoolFromEnv() (*connectionPool, error) {
+	pool := &connectionPool{}
+	for name, target := range map[string]*int{"DB_MAX_OPEN_CONNS": &pool.maxOpen, "DB_MAX_IDLE_CONNS": &pool.maxIdle} {
+		if valueStr := os.Getenv(name); valueStr != "" {
+			value, err := strconv.Atoi(valueStr)
+			if err != nil || value <= 0 {
+				return nil, fmt.Errorf("invalid %s value: %s", name, valueStr)
+			}
+			*target = value
+		}
+	}
+	for name, target := range map[string]*time.Duration{"DB_CONN_MAX_LIFETIME": &pool.maxLifetime, "DB_CONN_MAX_IDLE_TIME": &pool.maxIdleTime} {
+		if valueStr := os.Getenv(name); valueStr != "" {
+			value, err := time.ParseDuration(valueStr)
+			if err != nil || value <= 0 {
+				return nil, fmt.Errorf("invalid %s value: %s", name, valueStr)
+			}
+			*target = value
+		}
+	}
+	return pool, nil
+}
+
+// configure applies the pool settings to a database connection
+func (pool *connectionPool) configure(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("failed to configure connection pool: %v", err)
+	}
+	if pool.maxOpen > 0 {
+		sqlDB.SetMaxOpenConns(pool.maxOpen)
+	}
+	if pool.maxIdle > 0 {
+		sqlDB.SetMaxIdleConns(pool.maxIdle)
+	}
+	if pool.maxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(pool.maxLifetime)
+	}
+	if pool.maxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(pool.maxIdleTime)
+	}
+	return nil
+}
+
+// openDatabase connects to a database and sizes its connection pool from the environment
+func openDatabase(dialector gorm.Dialector) (*gorm.DB, error) {
+	pool, err := connectionPoolFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	if err := pool.configure(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
@@ -0,0 +1,55 @@
+// Multi-Model Authorization Microservice - Database Connection Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDatabase_SQLitePragmas(t *testing.T) {
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("DB_DSN", filepath.Join(t.TempDir(), "authz.db"))
+	t.Setenv("SQLITE_BUSY_TIMEOUT", "2s")
+	t.Setenv("DB_MAX_OPEN_CONNS", "4")
+
+	dialector, err := databaseFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to configure database: %v", err)
+	}
+	db, err := openDatabase(dialector)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	var journalMode string
+	var busyTimeout int
+	db.Raw("PRAGMA journal_mode").Scan(&journalMode)
+	db.Raw("PRAGMA busy_timeout").Scan(&busyTimeout)
+	if journalMode != "wal" || busyTimeout != 2000 {
+		t.Errorf("Expected WAL with a 2s busy timeout, got %s and %dms", journalMode, busyTimeout)
+	}
+	if sqlDB, _ := db.DB(); sqlDB.Stats().MaxOpenConnections != 4 {
+		t.Errorf("Expected at most 4 connections, got %d", sqlDB.Stats().MaxOpenConnections)
+	}
+
+	// Parameters given in the DSN win
+	options := &sqliteOptions{journalMode: "WAL", busyTimeout: time.Second, synchronous: "NORMAL"}
+	if dsn := options.apply("file:authz.db?_journal_mode=DELETE"); dsn != "file:authz.db?_journal_mode=DELETE&_busy_timeout=1000&_synchronous=NORMAL" {
+		t.Errorf("Unexpected DSN %s", dsn)
+	}
+
+	for name, value := range map[string]string{"SQLITE_JOURNAL_MODE": "fast", "SQLITE_SYNCHRONOUS": "sometimes", "SQLITE_BUSY_TIMEOUT": "-1s", "DB_MAX_IDLE_CONNS": "0", "DB_CONN_MAX_LIFETIME": "soon"} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := databaseFromEnv(); err == nil {
+				if _, err := connectionPoolFromEnv(); err == nil {
+					t.Errorf("Expected %s=%s to be rejected", name, value)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+// Multi-Model Authorization Microservice - Decision Cache
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// defaultDecisionCacheSize is the number of enforce results kept in memory
+	defaultDecisionCacheSize = 10000
+
+	// defaultDecisionCacheTTL bounds how long a result is served, which limits staleness
+	// after changes this instance does not see, such as writes by other instances
+	defaultDecisionCacheTTL = 30 * time.Second
+)
+
+// decisionCacheKey identifies an enforce request
+type decisionCacheKey struct {
+	model      AccessControlModel
+	tenant     string
+	subject    string
+	object     string
+	action     string
+	attributes uint64 // Hash of the request attributes
+}
+
+// decisionCacheEntry is a cached enforce result stamped with the revision of the data it
+// was computed from
+type decisionCacheEntry struct {
+	key      decisionCacheKey
+	revision uint64
+	decision Decision
+	expires  time.Time
+}
+
+// DecisionCacheStats describes the state of the decision cache
+type DecisionCacheStats struct {
+	Entries  int    `json:"entries"`
+	Capacity int    `json:"capacity"`
+	TTL      string `json:"ttl"`
+}
+
+// decisionCache is a size-bounded LRU cache of enforce results with a TTL. Like the ReBAC
+// check cache, entries are only served while the revision of the data their model depends
+// on is unchanged, so writes invalidate them without tracking which decisions they affect.
+type decisionCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List // Front is the most recently used entry
+	entries  map[decisionCacheKey]*list.Element
+
+	acl  *revisionWatcher // Counts ACL rule changes
+	rbac *revisionWatcher // Counts RBAC rule and role assignment changes
+}
+
+// revisionWatcher is a Casbin watcher that counts policy changes made through an enforcer.
+// With an invalidation bus it also notifies other instances, which reload their policies.
+type revisionWatcher struct {
+	revision uint64
+
+	mu       sync.Mutex
+	callback func(string) // Reloads the enforcer's policies, set by Casbin
+	notify   func()       // Tells other instances about a change (nil when running alone)
+}
+
+// SetUpdateCallback implements persist.Watcher. Casbin sets a callback that reloads the
+// enforcer's policies.
+func (rw *revisionWatcher) SetUpdateCallback(callback func(string)) error {
+	rw.mu.Lock()
+	rw.callback = callback
+	rw.mu.Unlock()
+	return nil
+}
+
+// Update implements persist.Watcher and is called by Casbin after every policy change
+func (rw *revisionWatcher) Update() error {
+	atomic.AddUint64(&rw.revision, 1)
+	rw.mu.Lock()
+	notify := rw.notify
+	rw.mu.Unlock()
+	if notify != nil {
+		notify()
+	}
+	return nil
+}
+
+// reload applies a policy change made by another instance
+func (rw *revisionWatcher) reload() {
+	rw.mu.Lock()
+	callback := rw.callback
+	rw.mu.Unlock()
+	if callback != nil {
+		callback("")
+	}
+	atomic.AddUint64(&rw.revision, 1)
+}
+
+// Close implements persist.Watcher
+func (rw *revisionWatcher) Close() {}
+
+// Revision returns the number of changes seen so far
+func (rw *revisionWatcher) Revision() uint64 {
+	return atomic.LoadUint64(&rw.revision)
+}
+
+// newDecisionCache creates an empty decision cache
+func newDecisionCache(capacity int, ttl time.Duration) *decisionCache {
+	return &decisionCache{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[decisionCacheKey]*list.Element),
+		acl:      &revisionWatcher{},
+		rbac:     &revisionWatcher{},
+	}
+}
+
+// decisionCacheConfigFromEnv reads DECISION_CACHE_SIZE and DECISION_CACHE_TTL. A size of
+// 0 disables the cache.
+func decisionCacheConfigFromEnv() (int, time.Duration, error) {
+	size := defaultDecisionCacheSize
+	if sizeStr := os.Getenv("DECISION_CACHE_SIZE"); sizeStr != "" {
+		parsed, err := strconv.Atoi(sizeStr)
+		if err != nil || parsed < 0 {
+			return 0, 0, fmt.Errorf("invalid DECISION_CACHE_SIZE value: %s", sizeStr)
+		}
+		size = parsed
+	}
+
+	ttl := defaultDecisionCacheTTL
+	if ttlStr := os.Getenv("DECISION_CACHE_TTL"); ttlStr != "" {
+		parsed, err := time.ParseDuration(ttlStr)
+		if err != nil || parsed <= 0 {
+			return 0, 0, fmt.Errorf("invalid DECISION_CACHE_TTL value: %s", ttlStr)
+		}
+		ttl = parsed
+	}
+
+	return size, ttl, nil
+}
+
+// enableDecisionCache caches enforce results and registers the watchers that count ACL
+// and RBAC changes. A size of 0 disables the cache.
+func (s *AuthService) enableDecisionCache(size int, ttl time.Duration) error {
+	if size == 0 {
+		s.decisions = nil
+		return nil
+	}
+
+	cache := newDecisionCache(size, ttl)
+	if err := s.aclEnforcer.SetWatcher(cache.acl); err != nil {
+		return fmt.Errorf("failed to watch ACL policies: %v", err)
+	}
+	if err := s.rbacEnforcer.SetWatcher(cache.rbac); err != nil {
+		return fmt.Errorf("failed to watch RBAC policies: %v", err)
+	}
+	s.decisions = cache
+	return nil
+}
+
+// decisionRevision returns the revision of the data decisions of a model depend on. Every
+// counter only grows, so their sum changes whenever any of them does. ABAC decisions also
+// depend on the time, date and day in the environment, which change at most hourly, and so
+// do RBAC decisions once rules carry conditions.
+func (s *AuthService) decisionRevision(model AccessControlModel) uint64 {
+	switch model {
+	case ModelACL:
+		return s.decisions.acl.Revision() + s.actionHierarchy.Revision()
+	case ModelRBAC:
+		revision := s.decisions.rbac.Revision() + s.actionHierarchy.Revision()
+		if s.rbacGroupBindings {
+			revision += s.relationshipGraph.Revision()
+		}
+		if s.ruleConditions.any() {
+			revision += atomic.LoadUint64(&s.attributeRevision) + uint64(time.Now().Unix()/3600)
+		}
+		return revision
+	case ModelABAC:
+		hour := uint64(time.Now().Unix() / 3600)
+		return s.policyEngine.Revision() + s.actionHierarchy.Revision() + atomic.LoadUint64(&s.attributeRevision) + hour
+	case ModelReBAC:
+		return s.relationshipGraph.Revision()
+	default:
+		return 0
+	}
+}
+
+// hashAttributes hashes request attributes independently of their order
+func hashAttributes(attributes map[string]string) uint64 {
+	if len(attributes) == 0 {
+		return 0
+	}
+
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := fnv.New64a()
+	for _, name := range names {
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(attributes[name]))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// get returns the cached result for key if it is unexpired and was computed at revision
+func (dc *decisionCache) get(key decisionCacheKey, revision uint64, now time.Time) (decision Decision, found bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	elem, exists := dc.entries[key]
+	if !exists {
+		serviceMetrics.Inc("decision_cache_misses_total")
+		return Decision{}, false
+	}
+	entry := elem.Value.(*decisionCacheEntry)
+	if entry.revision != revision || !now.Before(entry.expires) {
+		dc.order.Remove(elem)
+		delete(dc.entries, key)
+		serviceMetrics.Inc("decision_cache_misses_total")
+		return Decision{}, false
+	}
+
+	dc.order.MoveToFront(elem)
+	serviceMetrics.Inc("decision_cache_hits_total")
+	return entry.decision, true
+}
+
+// put stores a result, evicting the least recently used entries when the cache is full
+func (dc *decisionCache) put(key decisionCacheKey, revision uint64, decision Decision, now time.Time) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if elem, exists := dc.entries[key]; exists {
+		dc.order.Remove(elem)
+	}
+	dc.entries[key] = dc.order.PushFront(&decisionCacheEntry{
+		key:      key,
+		revision: revision,
+		decision: decision,
+		expires:  now.Add(dc.ttl),
+	})
+
+	for dc.order.Len() > dc.capacity {
+		oldest := dc.order.Back()
+		dc.order.Remove(oldest)
+		delete(dc.entries, oldest.Value.(*decisionCacheEntry).key)
+		serviceMetrics.Inc("decision_cache_evictions_total")
+	}
+}
+
+// resize changes the capacity and TTL of the cache, evicting the least recently used
+// entries beyond the new capacity. Cached entries keep their expiry.
+func (dc *decisionCache) resize(capacity int, ttl time.Duration) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	dc.capacity, dc.ttl = capacity, ttl
+	for dc.order.Len() > dc.capacity {
+		oldest := dc.order.Back()
+		dc.order.Remove(oldest)
+		delete(dc.entries, oldest.Value.(*decisionCacheEntry).key)
+		serviceMetrics.Inc("decision_cache_evictions_total")
+	}
+}
+
+// Stats reports the size and configuration of the cache
+func (dc *decisionCache) Stats() DecisionCacheStats {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	return DecisionCacheStats{Entries: dc.order.Len(), Capacity: dc.capacity, TTL: dc.ttl.String()}
+}
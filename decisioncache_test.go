@@ -0,0 +1,122 @@
+// Multi-Model Authorization Microservice - Decision Cache Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecisionCache_LRUAndTTL(t *testing.T) {
+	cache := newDecisionCache(2, time.Minute)
+	now := time.Now()
+	alice := decisionCacheKey{model: ModelACL, subject: "alice", object: "doc1", action: "read"}
+	bob := decisionCacheKey{model: ModelACL, subject: "bob", object: "doc1", action: "read"}
+	carol := decisionCacheKey{model: ModelACL, subject: "carol", object: "doc1", action: "read"}
+
+	cache.put(alice, 1, Decision{Allowed: true}, now)
+	cache.put(bob, 1, Decision{}, now)
+	if decision, found := cache.get(alice, 1, now); !found || !decision.Allowed {
+		t.Fatal("Expected a cached allow for alice")
+	}
+	if _, found := cache.get(alice, 2, now); found {
+		t.Error("Expected a result of an older revision to be ignored")
+	}
+
+	// carol evicts bob, the least recently used entry
+	cache.put(alice, 2, Decision{Allowed: true}, now)
+	cache.get(alice, 2, now)
+	cache.put(carol, 2, Decision{Allowed: true}, now)
+	if _, found := cache.get(bob, 1, now); found || cache.Stats().Entries != 2 {
+		t.Errorf("Expected bob to be evicted, got %+v", cache.Stats())
+	}
+
+	if _, found := cache.get(alice, 2, now.Add(time.Minute)); found {
+		t.Error("Expected the entry to expire after the TTL")
+	}
+
+	if hashAttributes(map[string]string{"a": "1", "b": "2"}) != hashAttributes(map[string]string{"b": "2", "a": "1"}) ||
+		hashAttributes(map[string]string{"a": "1b"}) == hashAttributes(map[string]string{"a": "1", "b": ""}) {
+		t.Error("Expected attribute hashes to ignore order but not boundaries")
+	}
+}
+
+func TestDecisionCache_InvalidatedByChanges(t *testing.T) {
+	service := setupTestService(t)
+	if err := service.enableDecisionCache(100, time.Minute); err != nil {
+		t.Fatalf("Failed to enable the decision cache: %v", err)
+	}
+
+	check := func(model AccessControlModel, subject, object, action string, attributes map[string]string) bool {
+		allowed, err := service.Enforce(model, subject, object, action, attributes)
+		if err != nil {
+			t.Fatalf("Enforce failed: %v", err)
+		}
+		return allowed
+	}
+	cached := func(model AccessControlModel, subject, object, action string) bool {
+		hits := serviceMetrics.Get("decision_cache_hits_total")
+		check(model, subject, object, action, nil)
+		return serviceMetrics.Get("decision_cache_hits_total") == hits+1
+	}
+
+	// Repeated checks are served from the cache
+	check(ModelACL, "alice", "doc1", "read", nil)
+	if !cached(ModelACL, "alice", "doc1", "read") {
+		t.Error("Expected the repeated ACL check to be cached")
+	}
+
+	// ACL and RBAC writes through the enforcers invalidate their model's decisions only
+	check(ModelRBAC, "bob", "doc1", "write", nil)
+	addRule(service.aclEnforcer, "alice", "doc1", "read", effectAllow)
+	if !check(ModelACL, "alice", "doc1", "read", nil) {
+		t.Error("Expected the new ACL rule to apply")
+	}
+	if !cached(ModelRBAC, "bob", "doc1", "write") {
+		t.Error("Expected RBAC decisions to survive an ACL change")
+	}
+	addRule(service.rbacEnforcer, "editor", "doc1", "write", effectAllow)
+	service.rbacEnforcer.AddGroupingPolicy("bob", "editor")
+	if !check(ModelRBAC, "bob", "doc1", "write", nil) {
+		t.Error("Expected the role assignment to apply")
+	}
+	removeRule(service.aclEnforcer, "alice", "doc1", "read")
+	if check(ModelACL, "alice", "doc1", "read", nil) {
+		t.Error("Expected the removed ACL rule to stop applying")
+	}
+
+	// ABAC decisions depend on policies, stored attributes and request attributes
+	service.policyEngine.AddPolicy(&ABACPolicy{ID: "finance", Name: "Finance", Effect: "allow",
+		Conditions: []PolicyCondition{{Type: "user", Field: "department", Operator: "eq", Value: "finance"}}})
+	service.policyEngine.AddPolicy(&ABACPolicy{ID: "office", Name: "Office", Effect: "allow",
+		Conditions: []PolicyCondition{{Type: "environment", Field: "location", Operator: "eq", Value: "office"}}})
+	if check(ModelABAC, "carol", "ledger", "read", nil) {
+		t.Error("Expected carol to be denied without attributes")
+	}
+	service.saveUserAttribute("carol", "department", "finance")
+	if !check(ModelABAC, "carol", "ledger", "read", nil) {
+		t.Error("Expected the attribute write to apply")
+	}
+	if !check(ModelABAC, "dave", "ledger", "read", map[string]string{"location": "office"}) ||
+		check(ModelABAC, "dave", "ledger", "read", map[string]string{"location": "home"}) {
+		t.Error("Expected request attributes to be part of the cache key")
+	}
+
+	// Tuple writes invalidate ReBAC decisions
+	if check(ModelReBAC, "erin", "doc2", "read", nil) {
+		t.Error("Expected erin to be denied without tuples")
+	}
+	service.relationshipGraph.AddRelationship("erin", "viewer", "doc2")
+	if !check(ModelReBAC, "erin", "doc2", "read", nil) {
+		t.Error("Expected the new tuple to apply")
+	}
+
+	// Strong reads bypass the cache
+	hits := serviceMetrics.Get("decision_cache_hits_total")
+	service.EnforceWithFreshness(ModelReBAC, "erin", "doc2", "read", nil, freshnessStrong)
+	if serviceMetrics.Get("decision_cache_hits_total") != hits {
+		t.Error("Expected strong reads to bypass the cache")
+	}
+}
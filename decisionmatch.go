@@ -0,0 +1,112 @@
+// Multi-Model Authorization Microservice - Decision Matches
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import "github.com/casbin/casbin/v2"
+
+// maxRoleChainDepth bounds the role inheritance followed to find a role chain, like
+// enforcement does
+const maxRoleChainDepth = 10
+
+// DecisionMatch identifies what produced a decision, so callers can log and display the
+// reason without asking for an explanation
+type DecisionMatch struct {
+	Rule      []string `json:"rule,omitempty"`       // ACL/RBAC: rule that decided, as subject, object, action and effect
+	RoleChain []string `json:"role_chain,omitempty"` // RBAC: the subject followed by the roles leading to the rule's subject
+	Group     string   `json:"group,omitempty"`      // RBAC: ReBAC group whose role binding decided
+	PolicyID  string   `json:"policy_id,omitempty"`  // ABAC: policy that decided
+}
+
+// Decision is the outcome of an authorization check with what produced it
+type Decision struct {
+	Allowed bool
+	Match   *DecisionMatch // What decided (nil for ReBAC and when nothing matched)
+	Hops    []PathHop      // ReBAC: path that granted access
+}
+
+// ruleMatch describes a decision by an ACL/RBAC rule. For RBAC, the role chain leads from
+// holder, the subject or the group whose binding applied, to the rule's subject.
+func ruleMatch(enforcer casbin.IEnforcer, model AccessControlModel, holder string, rule []string) *DecisionMatch {
+	if rule == nil {
+		return nil
+	}
+	match := &DecisionMatch{Rule: rule}
+	if model == ModelRBAC {
+		match.RoleChain = roleChain(enforcer, holder, rule[0])
+	}
+	return match
+}
+
+// roleChain returns the shortest chain of role assignments from subject to role, starting
+// with subject, or nil when subject does not hold the role
+func roleChain(enforcer casbin.IEnforcer, subject, role string) []string {
+	if subject == role {
+		return []string{subject}
+	}
+
+	previous := map[string]string{subject: ""}
+	frontier := []string{subject}
+	for depth := 0; depth < maxRoleChainDepth && len(frontier) > 0; depth++ {
+		var next []string
+		for _, member := range frontier {
+			roles, err := enforcer.GetRolesForUser(member)
+			if err != nil {
+				return nil
+			}
+			for _, candidate := range roles {
+				if _, seen := previous[candidate]; seen {
+					continue
+				}
+				previous[candidate] = member
+				if candidate == role {
+					var chain []string
+					for node := role; node != ""; node = previous[node] {
+						chain = append([]string{node}, chain...)
+					}
+					return chain
+				}
+				next = append(next, candidate)
+			}
+		}
+		frontier = next
+	}
+	return nil
+}
+
+// local returns the match with tenant-local names
+func (match *DecisionMatch) local(scope tenantScope) *DecisionMatch {
+	if match == nil || scope.global() {
+		return match
+	}
+	localized := *match
+	localized.Rule = localNames(scope, match.Rule)
+	localized.RoleChain = localNames(scope, match.RoleChain)
+	localized.Group = scope.local(match.Group)
+	return &localized
+}
+
+// localNames returns names without the tenant prefix of scope
+func localNames(scope tenantScope, names []string) []string {
+	if names == nil {
+		return nil
+	}
+	local := make([]string, len(names))
+	for i, name := range names {
+		local[i] = scope.local(name)
+	}
+	return local
+}
+
+// localHops returns a path without the tenant prefix of scope
+func localHops(scope tenantScope, hops []PathHop) []PathHop {
+	if hops == nil || scope.global() {
+		return hops
+	}
+	local := make([]PathHop, len(hops))
+	for i, hop := range hops {
+		local[i] = PathHop{Subject: scope.local(hop.Subject), Relation: hop.Relation, Object: scope.local(hop.Object)}
+	}
+	return local
+}
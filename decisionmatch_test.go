@@ -0,0 +1,77 @@
+// Multi-Model Authorization Microservice - Decision Match Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDecisionMatch_ReportedForAllModels(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/authorizations", service.authorizationHandler).Methods("POST")
+	if err := service.enableDecisionCache(100, time.Minute); err != nil {
+		t.Fatalf("Failed to enable the decision cache: %v", err)
+	}
+
+	addRule(service.aclEnforcer, "alice", "doc1", "read", effectAllow)
+	addRule(service.rbacEnforcer, "admin", "finance", "write", effectAllow)
+	service.rbacEnforcer.AddGroupingPolicy("bob", "manager")
+	service.rbacEnforcer.AddGroupingPolicy("manager", "admin")
+	service.policyEngine.AddPolicy(&ABACPolicy{
+		ID: "hr-records", Name: "HR records", Effect: "allow", Priority: 10,
+		Conditions: []PolicyCondition{{Type: "user", Field: "department", Operator: "eq", Value: "hr"}},
+	})
+	service.saveUserAttribute("carol", "department", "hr")
+	service.relationshipGraph.AddRelationship("dave", "member", "team")
+	service.relationshipGraph.AddRelationship("team", "viewer", "report")
+
+	check := func(model, subject, object, action string) EnforceResponse {
+		body := `{"model": "` + model + `", "subject": "` + subject + `", "object": "` + object + `", "action": "` + action + `"}`
+		req, _ := http.NewRequest("POST", "/api/v1/authorizations", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var response EnforceResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return response
+	}
+
+	// Cached decisions keep what produced them
+	for i := 0; i < 2; i++ {
+		response := check("acl", "alice", "doc1", "read")
+		if response.Matched == nil || !reflect.DeepEqual(response.Matched.Rule, []string{"alice", "doc1", "read", "allow"}) {
+			t.Errorf("Expected the ACL rule, got %+v", response.Matched)
+		}
+	}
+
+	response := check("rbac", "bob", "finance", "write")
+	if !response.Allowed || response.Matched == nil || !reflect.DeepEqual(response.Matched.RoleChain, []string{"bob", "manager", "admin"}) ||
+		response.Matched.Rule[0] != "admin" {
+		t.Errorf("Expected the admin rule reached through manager, got %+v", response.Matched)
+	}
+
+	response = check("abac", "carol", "record", "read")
+	if !response.Allowed || response.Matched == nil || response.Matched.PolicyID != "hr-records" {
+		t.Errorf("Expected the hr-records policy, got %+v", response.Matched)
+	}
+
+	response = check("rebac", "dave", "report", "read")
+	if !response.Allowed || len(response.Hops) != 2 || response.Path != "dave -[member]-> team -[viewer]-> report" {
+		t.Errorf("Expected the path through the team, got %q %+v", response.Path, response.Hops)
+	}
+
+	// Nothing matched a default deny
+	response = check("acl", "eve", "doc1", "read")
+	if response.Allowed || response.Matched != nil || response.Path != "" {
+		t.Errorf("Expected a default deny without a match, got %+v", response)
+	}
+}
@@ -0,0 +1,309 @@
+// Multi-Model Authorization Microservice - Demo Mode
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// demoLabel marks everything the demo dataset creates so it can be exported or removed as a slice
+const demoLabel = "demo"
+
+// DemoScenario is an example authorization request against the demo dataset with its expected result
+type DemoScenario struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Request     EnforceRequest `json:"request"`
+	Expected    bool           `json:"expected"`
+}
+
+// DemoScenarioResult is the outcome of running a demo scenario
+type DemoScenarioResult struct {
+	DemoScenario
+	Allowed bool   `json:"allowed"`
+	Passed  bool   `json:"passed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// demoDataset describes the TechCorp sample organization: alice is the CEO, diana the HR
+// manager, bob the engineering manager, charlie an engineer, frank a junior developer and
+// eve an external contractor
+type demoDataset struct {
+	ACL              [][]string
+	Roles            [][]string // user, role
+	RolePermissions  [][]string // role, object, action
+	UserAttributes   map[string]map[string]string
+	ObjectAttributes map[string]map[string]string
+	ABACPolicies     []ABACPolicy
+	Relationships    []Relationship
+}
+
+// techCorpDataset returns the sample organization loaded in demo mode
+func techCorpDataset() *demoDataset {
+	return &demoDataset{
+		ACL: [][]string{
+			{"eve", "onboarding_guide.pdf", "read"},
+			{"frank", "onboarding_guide.pdf", "read"},
+		},
+		Roles: [][]string{
+			{"alice", "executive"},
+			{"diana", "hr_manager"},
+			{"bob", "engineer"},
+			{"charlie", "engineer"},
+			{"frank", "engineer"},
+		},
+		RolePermissions: [][]string{
+			{"executive", "quarterly_report.pdf", "read"},
+			{"executive", "quarterly_report.pdf", "write"},
+			{"hr_manager", "salary_bands.xlsx", "read"},
+			{"hr_manager", "salary_bands.xlsx", "write"},
+			{"engineer", "build_server", "deploy"},
+		},
+		UserAttributes: map[string]map[string]string{
+			"alice":   {"org": "techcorp", "department": "executive", "clearance": "5"},
+			"diana":   {"org": "techcorp", "department": "hr", "clearance": "4"},
+			"bob":     {"org": "techcorp", "department": "engineering", "clearance": "3"},
+			"charlie": {"org": "techcorp", "department": "engineering", "clearance": "2"},
+			"frank":   {"org": "techcorp", "department": "engineering", "clearance": "1"},
+			"eve":     {"org": "contractor", "department": "engineering", "clearance": "0"},
+		},
+		ObjectAttributes: map[string]map[string]string{
+			"payroll_report.xlsx": {"department": "hr", "sensitivity": "high"},
+			"roadmap.md":          {"department": "engineering", "sensitivity": "internal"},
+		},
+		ABACPolicies: []ABACPolicy{
+			{
+				ID:          "demo-after-hours",
+				Name:        "TechCorp after-hours lockout",
+				Description: "TechCorp staff cannot access documents before 07:00",
+				Effect:      "deny",
+				Priority:    100,
+				Conditions: []PolicyCondition{
+					{Type: "user", Field: "org", Operator: "eq", Value: "techcorp", LogicOp: "and"},
+					{Type: "environment", Field: "time", Operator: "lt", Value: "7"},
+				},
+			},
+			{
+				ID:          "demo-department-docs",
+				Name:        "Department documents",
+				Description: "TechCorp staff read internal documents of their own engineering department",
+				Effect:      "allow",
+				Priority:    10,
+				Conditions: []PolicyCondition{
+					{Type: "user", Field: "org", Operator: "eq", Value: "techcorp", LogicOp: "and"},
+					{Type: "user", Field: "department", Operator: "eq", Value: "engineering", LogicOp: "and"},
+					{Type: "object", Field: "department", Operator: "eq", Value: "engineering", LogicOp: "and"},
+					{Type: "object", Field: "sensitivity", Operator: "eq", Value: "internal"},
+				},
+			},
+			{
+				ID:          "demo-hr-sensitive",
+				Name:        "HR sensitive records",
+				Description: "HR staff with clearance 4 or higher read sensitive HR records",
+				Effect:      "allow",
+				Priority:    10,
+				Conditions: []PolicyCondition{
+					{Type: "user", Field: "department", Operator: "eq", Value: "hr", LogicOp: "and"},
+					{Type: "user", Field: "clearance", Operator: "gte", Value: "4", LogicOp: "and"},
+					{Type: "object", Field: "department", Operator: "eq", Value: "hr"},
+				},
+			},
+		},
+		Relationships: []Relationship{
+			// Document ownership
+			{Subject: "alice", Relationship: "owner", Object: "company_strategy.pdf"},
+			{Subject: "diana", Relationship: "owner", Object: "employee_records.xlsx"},
+			{Subject: "bob", Relationship: "owner", Object: "engineering_docs.md"},
+
+			// Team memberships and team access rights
+			{Subject: "bob", Relationship: "member", Object: "engineering_team"},
+			{Subject: "charlie", Relationship: "member", Object: "engineering_team"},
+			{Subject: "frank", Relationship: "member", Object: "engineering_team"},
+			{Subject: "engineering_team", Relationship: "group_access", Object: "source_code.zip"},
+			{Subject: "engineering_team", Relationship: "group_access", Object: "engineering_docs.md"},
+
+			// Individual access rights
+			{Subject: "charlie", Relationship: "editor", Object: "engineering_docs.md"},
+
+			// Folder hierarchy
+			{Subject: "diana", Relationship: "owner", Object: "hr_folder"},
+			{Subject: "hr_folder", Relationship: "parent", Object: "benefits_policy.pdf"},
+		},
+	}
+}
+
+// demoScenarios enumerates example requests against the demo dataset
+func demoScenarios() []DemoScenario {
+	scenario := func(name, description string, model AccessControlModel, subject, object, action string, attributes map[string]string, expected bool) DemoScenario {
+		return DemoScenario{
+			Name:        name,
+			Description: description,
+			Request:     EnforceRequest{Model: model, Subject: subject, Object: object, Action: action, Attributes: attributes},
+			Expected:    expected,
+		}
+	}
+	officeHours := map[string]string{"hour": "10"}
+	earlyMorning := map[string]string{"hour": "5"}
+
+	return []DemoScenario{
+		scenario("acl-contractor-onboarding", "Contractor reads the onboarding guide through a direct ACL entry", ModelACL, "eve", "onboarding_guide.pdf", "read", nil, true),
+		scenario("acl-contractor-no-write", "ACL entries grant only the listed action", ModelACL, "eve", "onboarding_guide.pdf", "write", nil, false),
+		scenario("rbac-ceo-report", "CEO edits the quarterly report through the executive role", ModelRBAC, "alice", "quarterly_report.pdf", "write", nil, true),
+		scenario("rbac-hr-salary", "HR manager reads salary bands through the hr_manager role", ModelRBAC, "diana", "salary_bands.xlsx", "read", nil, true),
+		scenario("rbac-engineer-no-salary", "Engineers have no role granting salary data", ModelRBAC, "charlie", "salary_bands.xlsx", "read", nil, false),
+		scenario("rbac-engineer-deploy", "Engineers deploy through the engineer role", ModelRBAC, "frank", "build_server", "deploy", nil, true),
+		scenario("abac-engineer-roadmap", "Engineers read internal engineering documents during office hours", ModelABAC, "charlie", "roadmap.md", "read", officeHours, true),
+		scenario("abac-after-hours", "The after-hours deny policy outranks the department policy", ModelABAC, "charlie", "roadmap.md", "read", earlyMorning, false),
+		scenario("abac-contractor-roadmap", "Contractors are not TechCorp staff even in the engineering department", ModelABAC, "eve", "roadmap.md", "read", officeHours, false),
+		scenario("abac-hr-payroll", "HR staff with clearance 4 read the payroll report", ModelABAC, "diana", "payroll_report.xlsx", "read", officeHours, true),
+		scenario("abac-engineer-payroll", "Engineering staff cannot read HR records", ModelABAC, "bob", "payroll_report.xlsx", "read", officeHours, false),
+		scenario("rebac-ceo-strategy", "CEO edits the strategy document as its owner", ModelReBAC, "alice", "company_strategy.pdf", "write", nil, true),
+		scenario("rebac-hr-records", "HR manager updates employee records as their owner", ModelReBAC, "diana", "employee_records.xlsx", "write", nil, true),
+		scenario("rebac-engineer-code", "Engineers read source code through engineering_team membership", ModelReBAC, "frank", "source_code.zip", "read", nil, true),
+		scenario("rebac-editor-docs", "Charlie edits the engineering docs as a direct editor", ModelReBAC, "charlie", "engineering_docs.md", "write", nil, true),
+		scenario("rebac-junior-no-delete", "Team access grants read and write but not delete on the engineering docs", ModelReBAC, "frank", "engineering_docs.md", "delete", nil, false),
+		scenario("rebac-hr-folder", "Ownership of the HR folder extends to documents inside it", ModelReBAC, "diana", "benefits_policy.pdf", "read", nil, true),
+		scenario("rebac-engineer-no-hr", "Engineers have no relationship to HR records", ModelReBAC, "frank", "employee_records.xlsx", "read", nil, false),
+	}
+}
+
+// LoadDemoData loads the demo dataset. It is idempotent, so restarting in demo mode
+// against the same database does not duplicate anything. Every created rule is labeled "demo".
+func (s *AuthService) LoadDemoData() error {
+	data := techCorpDataset()
+	label := []string{demoLabel}
+
+	for _, rule := range data.ACL {
+		if _, err := addRule(s.aclEnforcer, rule[0], rule[1], rule[2], effectAllow); err != nil {
+			return fmt.Errorf("failed to add demo ACL policy: %v", err)
+		}
+		if err := s.setLabels(labelKindACL, labelKey(rule...), label); err != nil {
+			return err
+		}
+	}
+
+	for _, role := range data.Roles {
+		if _, err := s.rbacEnforcer.AddRoleForUser(role[0], role[1]); err != nil {
+			return fmt.Errorf("failed to add demo role: %v", err)
+		}
+		if err := s.setLabels(labelKindRole, labelKey(role...), label); err != nil {
+			return err
+		}
+	}
+
+	for _, rule := range data.RolePermissions {
+		if _, err := addRule(s.rbacEnforcer, rule[0], rule[1], rule[2], effectAllow); err != nil {
+			return fmt.Errorf("failed to add demo role permission: %v", err)
+		}
+		if err := s.setLabels(labelKindRBAC, labelKey(rule...), label); err != nil {
+			return err
+		}
+	}
+
+	for user, attributes := range data.UserAttributes {
+		for name, value := range attributes {
+			if err := s.saveUserAttribute(user, name, value); err != nil {
+				return err
+			}
+		}
+	}
+	for object, attributes := range data.ObjectAttributes {
+		for name, value := range attributes {
+			if err := s.saveObjectAttribute(object, name, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := range data.ABACPolicies {
+		policy := data.ABACPolicies[i]
+		if _, exists := s.policyEngine.getPolicy(policy.ID); !exists {
+			policy.CreatedAt = time.Now()
+			policy.UpdatedAt = time.Now()
+			if err := s.policyEngine.AddPolicy(&policy); err != nil {
+				return fmt.Errorf("failed to add demo ABAC policy: %v", err)
+			}
+		}
+		if err := s.setLabels(labelKindABAC, policy.ID, label); err != nil {
+			return err
+		}
+	}
+
+	for _, rel := range data.Relationships {
+		if !s.relationshipGraph.HasDirectRelationship(rel.Subject, rel.Relationship, rel.Object) {
+			if err := s.relationshipGraph.AddRelationship(rel.Subject, rel.Relationship, rel.Object); err != nil {
+				return fmt.Errorf("failed to add demo relationship: %v", err)
+			}
+		}
+		if err := s.setLabels(labelKindRelationship, labelKey(rel.Subject, rel.Relationship, rel.Object), label); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunDemoScenario evaluates a scenario against the current data
+func (s *AuthService) RunDemoScenario(scenario DemoScenario) DemoScenarioResult {
+	result := DemoScenarioResult{DemoScenario: scenario}
+	allowed, err := s.Enforce(scenario.Request.Model, scenario.Request.Subject, scenario.Request.Object, scenario.Request.Action, scenario.Request.Attributes)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Allowed = allowed
+	result.Passed = allowed == scenario.Expected
+	return result
+}
+
+// getDemoScenariosHandler lists the demo scenarios. With run=true every scenario is
+// evaluated and reported with its actual result.
+func (s *AuthService) getDemoScenariosHandler(w http.ResponseWriter, r *http.Request) {
+	scenarios := demoScenarios()
+
+	response := map[string]interface{}{
+		"label": demoLabel,
+		"count": len(scenarios),
+	}
+
+	if r.URL.Query().Get("run") == "true" {
+		results := make([]DemoScenarioResult, 0, len(scenarios))
+		passed := 0
+		for _, scenario := range scenarios {
+			result := s.RunDemoScenario(scenario)
+			if result.Passed {
+				passed++
+			}
+			results = append(results, result)
+		}
+		response["scenarios"] = results
+		response["passed"] = passed
+	} else {
+		response["scenarios"] = scenarios
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// runDemoScenarioHandler evaluates a single demo scenario by name
+func (s *AuthService) runDemoScenarioHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	for _, scenario := range demoScenarios() {
+		if scenario.Name == name {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(s.RunDemoScenario(scenario))
+			return
+		}
+	}
+
+	writeJSONError(w, http.StatusNotFound, "Demo scenario not found")
+}
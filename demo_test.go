@@ -0,0 +1,101 @@
+// Multi-Model Authorization Microservice - Demo Mode Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestDemo_ScenariosMatchDataset(t *testing.T) {
+	service := setupTestService(t)
+
+	// Demo data is loaded on top of the regular initial data
+	if err := service.initializeData(); err != nil {
+		t.Fatalf("Failed to initialize data: %v", err)
+	}
+	if err := service.LoadDemoData(); err != nil {
+		t.Fatalf("Failed to load demo data: %v", err)
+	}
+
+	for _, scenario := range demoScenarios() {
+		result := service.RunDemoScenario(scenario)
+		if result.Error != "" || !result.Passed {
+			t.Errorf("%s: expected %v, got %v (%s)", scenario.Name, scenario.Expected, result.Allowed, result.Error)
+		}
+	}
+
+	export, err := service.ExportPolicies(demoLabel)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	dataset := techCorpDataset()
+	if len(export.Relationships) != len(dataset.Relationships) || len(export.ABACPolicies) != len(dataset.ABACPolicies) || len(export.RBACRoles) != len(dataset.Roles) {
+		t.Errorf("Expected all demo data to carry the demo label, got %+v", export)
+	}
+
+	// Loading again must not duplicate anything
+	if err := service.LoadDemoData(); err != nil {
+		t.Fatalf("Failed to reload demo data: %v", err)
+	}
+	var count int64
+	service.db.Model(&RelationshipRecord{}).Where("subject = ? AND object = ?", "bob", "engineering_docs.md").Count(&count)
+	if count != 1 {
+		t.Errorf("Expected demo relationships to be loaded once, got %d", count)
+	}
+}
+
+func TestDemo_Endpoints(t *testing.T) {
+	service := setupTestService(t)
+	if err := service.LoadDemoData(); err != nil {
+		t.Fatalf("Failed to load demo data: %v", err)
+	}
+
+	router := mux.NewRouter()
+	service.registerAdminRoutes(router.PathPrefix("/api/v1").Subrouter())
+	req, _ := http.NewRequest("GET", "/api/v1/demo/scenarios", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound && rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected demo endpoints to be absent outside demo mode, got %d", rr.Code)
+	}
+
+	service.demoMode = true
+	router = mux.NewRouter()
+	service.registerAdminRoutes(router.PathPrefix("/api/v1").Subrouter())
+
+	req, _ = http.NewRequest("GET", "/api/v1/demo/scenarios?run=true", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var listing struct {
+		Count     int                  `json:"count"`
+		Passed    int                  `json:"passed"`
+		Scenarios []DemoScenarioResult `json:"scenarios"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &listing)
+	if rr.Code != http.StatusOK || listing.Count != len(demoScenarios()) || listing.Passed != listing.Count {
+		t.Errorf("Unexpected scenario run: %d %s", rr.Code, rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/api/v1/demo/scenarios/rebac-hr-folder", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var result DemoScenarioResult
+	json.Unmarshal(rr.Body.Bytes(), &result)
+	if rr.Code != http.StatusOK || !result.Allowed || !result.Passed {
+		t.Errorf("Unexpected scenario result: %d %s", rr.Code, rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/api/v1/demo/scenarios/unknown", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown scenario, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,256 @@
+// Multi-Model Authorization Microservice - Export Diffs
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change kinds reported by DiffExports
+const (
+	changeKindACL               = "acl"
+	changeKindRBACPolicy        = "rbac_policy"
+	changeKindRBACRole          = "rbac_role"
+	changeKindRBACResourceGroup = "rbac_resource_group"
+	changeKindABACPolicy        = "abac_policy"
+	changeKindRelationship      = "relationship"
+	changeKindUserAttribute     = "user_attribute"
+	changeKindObjectAttribute   = "object_attribute"
+)
+
+// PolicyChange is a single difference between two exports. Before is empty for
+// additions and After is empty for removals.
+type PolicyChange struct {
+	Kind   string      `json:"kind"`
+	Key    string      `json:"key"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// PolicyDiff is the change set between two exports
+type PolicyDiff struct {
+	Added    []PolicyChange `json:"added"`
+	Removed  []PolicyChange `json:"removed"`
+	Modified []PolicyChange `json:"modified"`
+}
+
+// ExportDiffRequest represents a request to compare two exports
+type ExportDiffRequest struct {
+	Before *PolicyExport `json:"before"`
+	After  *PolicyExport `json:"after"` // The live state when omitted
+}
+
+// Empty reports whether the exports were equivalent
+func (d *PolicyDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// diffEntries compares two keyed sets of one kind and records the differences
+func (d *PolicyDiff) diffEntries(kind string, before, after map[string]interface{}) {
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, exists := before[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		old, hadOld := before[key]
+		updated, hasNew := after[key]
+		switch {
+		case !hadOld:
+			d.Added = append(d.Added, PolicyChange{Kind: kind, Key: key, After: updated})
+		case !hasNew:
+			d.Removed = append(d.Removed, PolicyChange{Kind: kind, Key: key, Before: old})
+		case !reflect.DeepEqual(old, updated):
+			d.Modified = append(d.Modified, PolicyChange{Kind: kind, Key: key, Before: old, After: updated})
+		}
+	}
+}
+
+// sortedLabels normalizes labels into a stable order so reordering alone is not a change
+func sortedLabels(labels []string) []string {
+	normalized := normalizeLabels(labels)
+	sort.Strings(normalized)
+	return normalized
+}
+
+// rulesByKey indexes exported Casbin rules by their values. Provenance is ignored since
+// it changes whenever a rule is re-created without changing its meaning.
+func rulesByKey(rules []LabeledRule) map[string]interface{} {
+	indexed := make(map[string]interface{}, len(rules))
+	for _, rule := range rules {
+		indexed[ruleKey(rule.Values)] = LabeledRule{Values: rule.Values, Labels: sortedLabels(rule.Labels)}
+	}
+	return indexed
+}
+
+// abacPoliciesByID indexes ABAC policies by ID, dropping timestamps and database IDs
+func abacPoliciesByID(policies []*ABACPolicy) map[string]interface{} {
+	indexed := make(map[string]interface{}, len(policies))
+	for _, policy := range policies {
+		comparable := ABACPolicy{
+			ID:          policy.ID,
+			Name:        policy.Name,
+			Description: policy.Description,
+			Effect:      policy.Effect,
+			Priority:    policy.Priority,
+			Actions:     policy.Actions,
+			Labels:      sortedLabels(policy.Labels),
+			Conditions:  make([]PolicyCondition, 0, len(policy.Conditions)),
+			Group:       policy.Group,
+			Expression:  policy.Expression,
+			Obligations: policy.Obligations,
+			Advice:      policy.Advice,
+		}
+		for _, condition := range policy.Conditions {
+			condition.ID = 0
+			condition.PolicyID = ""
+			comparable.Conditions = append(comparable.Conditions, condition)
+		}
+		indexed[policy.ID] = comparable
+	}
+	return indexed
+}
+
+// relationshipsByKey indexes relationship tuples as "subject:relationship:object"
+func relationshipsByKey(relationships []LabeledRelationship) map[string]interface{} {
+	indexed := make(map[string]interface{}, len(relationships))
+	for _, rel := range relationships {
+		indexed[labelKey(rel.Subject, rel.Relationship.Relationship, rel.Object)] = LabeledRelationship{Relationship: rel.Relationship, Labels: sortedLabels(rel.Labels)}
+	}
+	return indexed
+}
+
+// attributesByKey indexes attribute values as "entity.attribute"
+func attributesByKey(attributes map[string]map[string]string) map[string]interface{} {
+	indexed := make(map[string]interface{})
+	for entity, values := range attributes {
+		for name, value := range values {
+			indexed[entity+"."+name] = value
+		}
+	}
+	return indexed
+}
+
+// DiffExports computes the added, removed and modified policies, tuples and attributes
+// between two exports
+func DiffExports(before, after *PolicyExport) *PolicyDiff {
+	diff := &PolicyDiff{Added: []PolicyChange{}, Removed: []PolicyChange{}, Modified: []PolicyChange{}}
+
+	diff.diffEntries(changeKindACL, rulesByKey(before.ACL), rulesByKey(after.ACL))
+	diff.diffEntries(changeKindRBACPolicy, rulesByKey(before.RBACPolicies), rulesByKey(after.RBACPolicies))
+	diff.diffEntries(changeKindRBACRole, rulesByKey(before.RBACRoles), rulesByKey(after.RBACRoles))
+	diff.diffEntries(changeKindRBACResourceGroup, rulesByKey(before.RBACResourceGroups), rulesByKey(after.RBACResourceGroups))
+	diff.diffEntries(changeKindABACPolicy, abacPoliciesByID(before.ABACPolicies), abacPoliciesByID(after.ABACPolicies))
+	diff.diffEntries(changeKindRelationship, relationshipsByKey(before.Relationships), relationshipsByKey(after.Relationships))
+	diff.diffEntries(changeKindUserAttribute, attributesByKey(before.UserAttributes), attributesByKey(after.UserAttributes))
+	diff.diffEntries(changeKindObjectAttribute, attributesByKey(before.ObjectAttributes), attributesByKey(after.ObjectAttributes))
+
+	return diff
+}
+
+// describeChangeValue renders one side of a change for the text report
+func describeChangeValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case LabeledRule:
+		if len(v.Labels) > 0 {
+			return fmt.Sprintf("labels [%s]", strings.Join(v.Labels, ", "))
+		}
+		return "no labels"
+	case LabeledRelationship:
+		if len(v.Labels) > 0 {
+			return fmt.Sprintf("labels [%s]", strings.Join(v.Labels, ", "))
+		}
+		return "no labels"
+	default:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+}
+
+// Text renders the change set for change-management tickets, one change per line
+func (d *PolicyDiff) Text() string {
+	if d.Empty() {
+		return "No changes\n"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d added, %d removed, %d modified\n", len(d.Added), len(d.Removed), len(d.Modified))
+	for _, change := range d.Added {
+		fmt.Fprintf(&b, "+ %s %s", change.Kind, change.Key)
+		if change.Kind == changeKindUserAttribute || change.Kind == changeKindObjectAttribute {
+			fmt.Fprintf(&b, " = %s", describeChangeValue(change.After))
+		}
+		b.WriteString("\n")
+	}
+	for _, change := range d.Removed {
+		fmt.Fprintf(&b, "- %s %s\n", change.Kind, change.Key)
+	}
+	for _, change := range d.Modified {
+		fmt.Fprintf(&b, "~ %s %s: %s -> %s\n", change.Kind, change.Key, describeChangeValue(change.Before), describeChangeValue(change.After))
+	}
+	return b.String()
+}
+
+// diffExportsHandler compares two export bundles, or a bundle against the live state
+// when "after" is omitted. format=text returns the human-readable report.
+func (s *AuthService) diffExportsHandler(w http.ResponseWriter, r *http.Request) {
+	var request ExportDiffRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	if request.Before == nil {
+		writeJSONError(w, http.StatusBadRequest, "before export is required")
+		return
+	}
+
+	against := "bundle"
+	if request.After == nil {
+		live, err := s.ExportPolicies(request.Before.Label)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Export error: %v", err))
+			return
+		}
+		request.After = live
+		against = "live"
+	}
+
+	diff := DiffExports(request.Before, request.After)
+
+	if r.URL.Query().Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, diff.Text())
+		return
+	}
+
+	response := map[string]interface{}{
+		"against":  against,
+		"added":    diff.Added,
+		"removed":  diff.Removed,
+		"modified": diff.Modified,
+		"summary": map[string]int{
+			"added":    len(diff.Added),
+			"removed":  len(diff.Removed),
+			"modified": len(diff.Modified),
+		},
+		"text": diff.Text(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,130 @@
+// Multi-Model Authorization Microservice - Export Diff Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiff_ExportChanges(t *testing.T) {
+	service := setupTestService(t)
+
+	service.aclEnforcer.AddPolicy("alice", "doc1", "read", "allow")
+	service.aclEnforcer.AddPolicy("bob", "doc1", "read", "allow")
+	service.relationshipGraph.AddRelationship("alice", "owner", "doc1")
+	service.saveUserAttribute("alice", "department", "engineering")
+	service.policyEngine.AddPolicy(&ABACPolicy{ID: "p1", Name: "Policy", Effect: "allow", Priority: 1})
+
+	before, err := service.ExportPolicies("")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	if diff := DiffExports(before, before); !diff.Empty() {
+		t.Fatalf("Expected identical exports to have no changes, got %+v", diff)
+	}
+
+	service.aclEnforcer.RemovePolicy("bob", "doc1", "read", "allow")
+	service.aclEnforcer.AddPolicy("carol", "doc1", "write", "allow")
+	service.relationshipGraph.AddRelationship("bob", "viewer", "doc1")
+	service.saveUserAttribute("alice", "department", "sales")
+	service.policyEngine.RemovePolicy("p1")
+	service.policyEngine.AddPolicy(&ABACPolicy{ID: "p1", Name: "Policy", Effect: "deny", Priority: 1})
+
+	after, err := service.ExportPolicies("")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	diff := DiffExports(before, after)
+	if len(diff.Added) != 2 || len(diff.Removed) != 1 || len(diff.Modified) != 2 {
+		t.Fatalf("Unexpected change set: %+v", diff)
+	}
+	if diff.Added[0].Kind != changeKindACL || diff.Added[0].Key != "carol:doc1:write" {
+		t.Errorf("Expected added ACL rule, got %+v", diff.Added[0])
+	}
+	if diff.Added[1].Kind != changeKindRelationship || diff.Removed[0].Key != "bob:doc1:read" {
+		t.Errorf("Unexpected additions/removals: %+v %+v", diff.Added, diff.Removed)
+	}
+	if diff.Modified[0].Kind != changeKindABACPolicy || diff.Modified[1].Kind != changeKindUserAttribute {
+		t.Errorf("Unexpected modifications: %+v", diff.Modified)
+	}
+
+	text := diff.Text()
+	for _, expected := range []string{"2 added, 1 removed, 2 modified", "+ acl carol:doc1:write", "- acl bob:doc1:read", `~ user_attribute alice.department: "engineering" -> "sales"`} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in report:\n%s", expected, text)
+		}
+	}
+}
+
+func TestDiff_HandlerAgainstLiveState(t *testing.T) {
+	service := setupTestService(t)
+	service.relationshipGraph.AddRelationship("alice", "owner", "doc1")
+
+	before, _ := service.ExportPolicies("")
+	service.relationshipGraph.RemoveRelationship("alice", "owner", "doc1")
+
+	body, _ := json.Marshal(map[string]interface{}{"before": before})
+	req, _ := http.NewRequest("POST", "/api/v1/export/diff", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(service.diffExportsHandler).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var response struct {
+		Against string         `json:"against"`
+		Removed []PolicyChange `json:"removed"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if response.Against != "live" || len(response.Removed) != 1 || response.Removed[0].Key != "alice:owner:doc1" {
+		t.Errorf("Unexpected diff against live state: %s", rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/api/v1/export/diff?format=text", bytes.NewBuffer(body))
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(service.diffExportsHandler).ServeHTTP(rr, req)
+	if !strings.HasPrefix(rr.Body.String(), "0 added, 1 removed, 0 modified") {
+		t.Errorf("Unexpected text report: %s", rr.Body.String())
+	}
+}
+
+func TestDiff_Command(t *testing.T) {
+	dir := t.TempDir()
+	before := filepath.Join(dir, "before.json")
+	after := filepath.Join(dir, "after.json")
+	os.WriteFile(before, []byte(`{"acl": [{"values": ["alice", "doc1", "read"]}], "rbac_policies": [], "rbac_roles": [], "abac_policies": [], "relationships": []}`), 0o644)
+	os.WriteFile(after, []byte(`{"acl": [], "rbac_policies": [], "rbac_roles": [], "abac_policies": [], "relationships": [{"subject": "alice", "relationship": "owner", "object": "doc1"}]}`), 0o644)
+
+	var out bytes.Buffer
+	handled, err := runCommand([]string{"diff", before, after}, &out)
+	if !handled || err != nil {
+		t.Fatalf("Expected diff command to run, got handled=%v err=%v", handled, err)
+	}
+	if !strings.Contains(out.String(), "+ relationship alice:owner:doc1") || !strings.Contains(out.String(), "- acl alice:doc1:read") {
+		t.Errorf("Unexpected diff output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if _, err := runCommand([]string{"diff", "-json", before, after}, &out); err != nil {
+		t.Fatalf("JSON diff failed: %v", err)
+	}
+	var diff PolicyDiff
+	if err := json.Unmarshal(out.Bytes(), &diff); err != nil || len(diff.Added) != 1 || len(diff.Removed) != 1 {
+		t.Errorf("Unexpected JSON diff: %s", out.String())
+	}
+
+	if handled, _ := runCommand(nil, &out); handled {
+		t.Error("Expected no subcommand to start the server")
+	}
+}
@@ -0,0 +1,93 @@
+// Multi-Model Authorization Microservice - ACL/RBAC Policy Effects
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/casbin/casbin/v2"
+	"gorm.io/gorm"
+)
+
+// Effects of ACL/RBAC rules. A matching deny rule overrides any number of matching allow
+// rules, e.g. to deny a contractor role delete on finance documents its parent role allows.
+const (
+	effectAllow = "allow"
+	effectDeny  = "deny"
+)
+
+// normalizeEffect defaults an empty effect to allow and rejects unknown effects
+func normalizeEffect(effect string) (string, error) {
+	switch effect {
+	case "", effectAllow:
+		return effectAllow, nil
+	case effectDeny:
+		return effectDeny, nil
+	default:
+		return "", fmt.Errorf("effect must be 'allow' or 'deny'")
+	}
+}
+
+// ruleEffect returns the effect of a Casbin policy rule (subject, object, action, effect)
+func ruleEffect(rule []string) string {
+	if len(rule) > 3 && rule[3] != "" {
+		return rule[3]
+	}
+	return effectAllow
+}
+
+// ruleKey identifies a Casbin rule by its values without the effect, so labels and
+// provenance stay attached when a rule is switched between allow and deny
+func ruleKey(rule []string) string {
+	if len(rule) > 3 {
+		rule = rule[:3]
+	}
+	return labelKey(rule...)
+}
+
+// addRule adds an ACL/RBAC rule unless the subject, object and action already have a rule,
+// whatever its effect
+func addRule(enforcer casbin.IEnforcer, subject, object, action, effect string) (bool, error) {
+	existing, err := enforcer.GetFilteredPolicy(0, subject, object, action)
+	if err != nil {
+		return false, err
+	}
+	if len(existing) > 0 {
+		return false, nil
+	}
+	return enforcer.AddPolicy(subject, object, action, effect)
+}
+
+// removeRule removes the ACL/RBAC rule for the subject, object and action whatever its effect
+func removeRule(enforcer casbin.IEnforcer, subject, object, action string) (bool, error) {
+	return enforcer.RemoveFilteredPolicy(0, subject, object, action)
+}
+
+// enforceRule evaluates an ACL/RBAC request and reports whether a matching deny rule decided it
+func enforceRule(enforcer casbin.IEnforcer, subject, object, action string) (allowed bool, denied bool, err error) {
+	allowed, denied, _, err = matchRule(enforcer, subject, object, action)
+	return allowed, denied, err
+}
+
+// matchRule is enforceRule that also returns the rule that decided, or nil when no rule
+// matched
+func matchRule(enforcer casbin.IEnforcer, subject, object, action string) (allowed bool, denied bool, rule []string, err error) {
+	allowed, rule, err = enforcer.EnforceEx(subject, object, action)
+	if err != nil {
+		return false, false, nil, err
+	}
+	if len(rule) == 0 {
+		rule = nil
+	}
+	return allowed, !allowed && rule != nil && ruleEffect(rule) == effectDeny, rule, nil
+}
+
+// migrateRuleEffects marks rules stored before effects existed as allow rules, since the
+// policy definition now requires an effect on every rule
+func migrateRuleEffects(db *gorm.DB, table string) error {
+	return db.Table(table).
+		Where("ptype = ? AND (v3 IS NULL OR v3 = '')", "p").
+		Update("v3", effectAllow).Error
+}
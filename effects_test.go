@@ -0,0 +1,128 @@
+// Multi-Model Authorization Microservice - ACL/RBAC Policy Effect Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEffects_DenyOverridesInheritedRoleAllow(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/rbac/policies", service.addRBACPolicyHandler).Methods("POST")
+	router.HandleFunc("/api/v1/rbac/policies", service.getRBACPoliciesHandler).Methods("GET")
+	router.HandleFunc("/api/v1/rbac/policies/{id}", service.deleteRBACPolicyHandler).Methods("DELETE")
+
+	post := func(body string) int {
+		req, _ := http.NewRequest("POST", "/api/v1/rbac/policies", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	// Contractors are staff, but must not delete finance documents
+	service.rbacEnforcer.AddRoleForUser("contractor", "staff")
+	service.rbacEnforcer.AddRoleForUser("alice", "staff")
+	service.rbacEnforcer.AddRoleForUser("bob", "contractor")
+	if code := post(`{"subject": "staff", "object": "finance_docs", "action": "delete"}`); code != http.StatusCreated {
+		t.Fatalf("Expected 201 for allow rule, got %d", code)
+	}
+	if code := post(`{"subject": "contractor", "object": "finance_docs", "action": "delete", "effect": "deny"}`); code != http.StatusCreated {
+		t.Fatalf("Expected 201 for deny rule, got %d", code)
+	}
+
+	if allowed, _ := service.Enforce(ModelRBAC, "alice", "finance_docs", "delete", nil); !allowed {
+		t.Error("Expected staff to delete finance documents")
+	}
+	if allowed, _ := service.Enforce(ModelRBAC, "bob", "finance_docs", "delete", nil); allowed {
+		t.Error("Expected the contractor deny rule to override the inherited allow rule")
+	}
+
+	// A triple has a single effect, and unknown effects are rejected
+	if code := post(`{"subject": "contractor", "object": "finance_docs", "action": "delete", "effect": "allow"}`); code != http.StatusConflict {
+		t.Errorf("Expected 409 for a rule with the same subject, object and action, got %d", code)
+	}
+	if code := post(`{"subject": "contractor", "object": "finance_docs", "action": "read", "effect": "maybe"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown effect, got %d", code)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/v1/rbac/policies", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var listing struct {
+		Policies [][]string `json:"policies"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &listing)
+	effects := map[string]string{}
+	for _, rule := range listing.Policies {
+		effects[rule[0]] = ruleEffect(rule)
+	}
+	if effects["staff"] != effectAllow || effects["contractor"] != effectDeny {
+		t.Errorf("Expected listed rules to carry their effect, got %v", listing.Policies)
+	}
+
+	// Removing the deny rule restores the inherited permission
+	req, _ = http.NewRequest("DELETE", "/api/v1/rbac/policies/contractor:finance_docs:delete", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200 removing the deny rule, got %d", rr.Code)
+	}
+	if allowed, _ := service.Enforce(ModelRBAC, "bob", "finance_docs", "delete", nil); !allowed {
+		t.Error("Expected access once the deny rule was removed")
+	}
+}
+
+func TestEffects_DenyThroughGroupBinding(t *testing.T) {
+	service := setupTestService(t)
+	service.rbacGroupBindings = true
+
+	service.relationshipGraph.AddRelationship("alice", "member", "engineering")
+	service.relationshipGraph.AddRelationship("bob", "member", "engineering")
+	service.relationshipGraph.AddRelationship("bob", "member", "contractors")
+	service.rbacEnforcer.AddPolicy("engineering", "repository", "push", "allow")
+	service.rbacEnforcer.AddPolicy("contractors", "repository", "push", "deny")
+
+	if allowed, _ := service.Enforce(ModelRBAC, "alice", "repository", "push", nil); !allowed {
+		t.Error("Expected the engineering group binding to allow push")
+	}
+	if allowed, _ := service.Enforce(ModelRBAC, "bob", "repository", "push", nil); allowed {
+		t.Error("Expected the contractors deny to override the engineering allow")
+	}
+
+	// A deny on the subject itself also wins over group bindings
+	service.rbacEnforcer.AddPolicy("alice", "repository", "push", "deny")
+	if allowed, _ := service.Enforce(ModelRBAC, "alice", "repository", "push", nil); allowed {
+		t.Error("Expected alice's own deny rule to override her group binding")
+	}
+}
+
+func TestEffects_MigratesRulesWithoutEffect(t *testing.T) {
+	service := setupTestService(t)
+
+	// Rules written before effects existed only have subject, object and action
+	if err := service.db.Exec("INSERT INTO acl_rules (ptype, v0, v1, v2) VALUES ('p', 'alice', 'data1', 'read')").Error; err != nil {
+		t.Fatalf("Failed to insert legacy rule: %v", err)
+	}
+	if err := migrateRuleEffects(service.db, "acl_rules"); err != nil {
+		t.Fatalf("Failed to migrate rule effects: %v", err)
+	}
+	if err := service.aclEnforcer.LoadPolicy(); err != nil {
+		t.Fatalf("Failed to load policies: %v", err)
+	}
+
+	allowed, err := service.Enforce(ModelACL, "alice", "data1", "read", nil)
+	if err != nil || !allowed {
+		t.Errorf("Expected the migrated rule to allow access: %v %v", allowed, err)
+	}
+	if has, _ := service.aclEnforcer.HasPolicy("alice", "data1", "read", "allow"); !has {
+		t.Error("Expected the migrated rule to carry the allow effect")
+	}
+}
@@ -0,0 +1,421 @@
+// Multi-Model Authorization Microservice - ABAC Environment Providers
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultEnvironmentIPAttribute is the request attribute holding the client IP address
+	defaultEnvironmentIPAttribute = "ip"
+
+	// defaultBusinessHours are the opening hours of the business calendar
+	defaultBusinessHours = "09:00-17:00"
+
+	// defaultBusinessDays are the working days of the business calendar
+	defaultBusinessDays = "mon-fri"
+
+	// tlsClientCertAttribute is the request attribute holding the PEM client certificate
+	// that the caller's TLS terminator received, optionally URL-encoded
+	tlsClientCertAttribute = "tls.client_cert"
+)
+
+// EnvironmentRequest is what an environment provider derives attributes from
+type EnvironmentRequest struct {
+	Time       time.Time         // Time of the check
+	Attributes map[string]string // Attributes of the authorization request
+}
+
+// EnvironmentProvider computes environment attributes of ABAC checks that callers would
+// otherwise have to send, e.g. the country of the client IP address. Providers return no
+// attributes when the request lacks what they need.
+type EnvironmentProvider interface {
+	Name() string
+	EnvironmentAttributes(ctx context.Context, request EnvironmentRequest) (map[string]string, error)
+}
+
+// environmentFor returns the environment of an ABAC check: the current time, the attributes
+// of the environment providers and the request attributes, which win over both
+func (s *AuthService) environmentFor(reqAttrs map[string]string) map[string]string {
+	env := environmentAttributes(reqAttrs)
+	if len(s.environmentProviders) == 0 {
+		return env
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultResolverTimeout)
+	defer cancel()
+	request := EnvironmentRequest{Time: time.Now(), Attributes: reqAttrs}
+	for _, provider := range s.environmentProviders {
+		provided, err := provider.EnvironmentAttributes(ctx, request)
+		if err != nil {
+			// The check goes on without the provider's attributes
+			serviceMetrics.Inc("abac_environment_provider_errors_total")
+			log.Printf("Environment provider %s failed: %v", provider.Name(), err)
+			continue
+		}
+		for name, value := range provided {
+			if _, sent := reqAttrs[name]; !sent {
+				env[name] = value
+			}
+		}
+	}
+	return env
+}
+
+// ipRange is a range of addresses of one autonomous system
+type ipRange struct {
+	first, last netip.Addr
+	asn         string
+	country     string
+	org         string
+}
+
+// geoIPProvider looks up the country and autonomous system of the client IP address in an
+// ip2asn-combined TSV file (range start, range end, AS number, country code, AS name)
+type geoIPProvider struct {
+	attribute string    // Request attribute holding the IP address
+	ranges    []ipRange // Sorted by first address
+}
+
+// loadGeoIPProvider reads the ranges of an ip2asn file. Unrouted ranges (AS 0) are skipped.
+func loadGeoIPProvider(path, attribute string) (*geoIPProvider, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open IP database: %v", err)
+	}
+	defer file.Close()
+
+	provider := &geoIPProvider{attribute: attribute}
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("IP database line %d: expected range start, range end, AS number and country", line)
+		}
+		first, errFirst := netip.ParseAddr(fields[0])
+		last, errLast := netip.ParseAddr(fields[1])
+		if errFirst != nil || errLast != nil || first.Is4() != last.Is4() || last.Less(first) {
+			return nil, fmt.Errorf("IP database line %d: invalid address range %s-%s", line, fields[0], fields[1])
+		}
+		if fields[2] == "0" {
+			continue
+		}
+		entry := ipRange{first: first, last: last, asn: fields[2], country: fields[3]}
+		if len(fields) > 4 {
+			entry.org = fields[4]
+		}
+		provider.ranges = append(provider.ranges, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read IP database: %v", err)
+	}
+	sort.Slice(provider.ranges, func(i, j int) bool { return provider.ranges[i].first.Less(provider.ranges[j].first) })
+	return provider, nil
+}
+
+// Name names the provider in logs
+func (gp *geoIPProvider) Name() string {
+	return "geoip"
+}
+
+// EnvironmentAttributes returns geo.country, geo.asn and geo.as_org for the client IP
+func (gp *geoIPProvider) EnvironmentAttributes(_ context.Context, request EnvironmentRequest) (map[string]string, error) {
+	ip, err := netip.ParseAddr(strings.TrimSpace(request.Attributes[gp.attribute]))
+	if err != nil {
+		return nil, nil
+	}
+	ip = ip.Unmap()
+
+	// The range containing ip is the last one starting at or before it
+	i := sort.Search(len(gp.ranges), func(i int) bool { return ip.Less(gp.ranges[i].first) }) - 1
+	if i < 0 || gp.ranges[i].last.Less(ip) {
+		return nil, nil
+	}
+	found := gp.ranges[i]
+	attributes := map[string]string{"geo.country": found.country, "geo.asn": found.asn}
+	if found.org != "" {
+		attributes["geo.as_org"] = found.org
+	}
+	return attributes, nil
+}
+
+// businessCalendar tells working hours from the time of a check
+type businessCalendar struct {
+	location *time.Location
+	open     int // Minutes after midnight
+	close    int // Minutes after midnight; checks before close are in business hours
+	days     [7]bool
+	holidays map[string]bool // YYYY-MM-DD
+}
+
+// weekdayNames maps the day names accepted in business day lists
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseBusinessDays parses days such as "mon-fri" or "mon,wed,fri"
+func parseBusinessDays(value string) ([7]bool, error) {
+	var days [7]bool
+	for _, entry := range strings.Split(strings.ToLower(value), ",") {
+		from, to, isRange := strings.Cut(strings.TrimSpace(entry), "-")
+		if !isRange {
+			to = from
+		}
+		first, okFirst := weekdayNames[strings.TrimSpace(from)]
+		last, okLast := weekdayNames[strings.TrimSpace(to)]
+		if !okFirst || !okLast {
+			return days, fmt.Errorf("invalid business days %q", value)
+		}
+		for day := first; ; day = (day + 1) % 7 {
+			days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseClock parses a time of day such as "09:30" into minutes after midnight
+func parseClock(value string) (int, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		if strings.TrimSpace(value) == "24:00" {
+			return 24 * 60, nil
+		}
+		return 0, err
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// parseHolidays adds the dates of a comma- or newline-separated list to holidays; text
+// after # is a comment
+func parseHolidays(value string, holidays map[string]bool) error {
+	for _, line := range strings.Split(value, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		for _, date := range strings.Split(line, ",") {
+			date = strings.TrimSpace(date)
+			if date == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", date); err != nil {
+				return fmt.Errorf("invalid holiday %q (expected YYYY-MM-DD)", date)
+			}
+			holidays[date] = true
+		}
+	}
+	return nil
+}
+
+// Name names the provider in logs
+func (bc *businessCalendar) Name() string {
+	return "calendar"
+}
+
+// EnvironmentAttributes returns whether the check falls on a holiday, a business day and
+// within business hours, in the calendar's time zone
+func (bc *businessCalendar) EnvironmentAttributes(_ context.Context, request EnvironmentRequest) (map[string]string, error) {
+	local := request.Time.In(bc.location)
+	holiday := bc.holidays[local.Format("2006-01-02")]
+	businessDay := bc.days[local.Weekday()] && !holiday
+	minute := local.Hour()*60 + local.Minute()
+	return map[string]string{
+		"holiday":        strconv.FormatBool(holiday),
+		"business_day":   strconv.FormatBool(businessDay),
+		"business_hours": strconv.FormatBool(businessDay && minute >= bc.open && minute < bc.close),
+		"local_time":     local.Format("15:04"),
+	}, nil
+}
+
+// tlsClientCertProvider describes the client certificate of the end user's TLS connection,
+// which the caller's TLS terminator forwards as the tls.client_cert request attribute
+type tlsClientCertProvider struct{}
+
+// Name names the provider in logs
+func (tlsClientCertProvider) Name() string {
+	return "tls"
+}
+
+// EnvironmentAttributes returns the subject, issuer, DNS names, expiry and fingerprint of
+// the forwarded client certificate, and whether it is valid at the time of the check
+func (tlsClientCertProvider) EnvironmentAttributes(_ context.Context, request EnvironmentRequest) (map[string]string, error) {
+	encoded := strings.TrimSpace(request.Attributes[tlsClientCertAttribute])
+	if encoded == "" {
+		return nil, nil
+	}
+	// Envoy's %DOWNSTREAM_PEER_CERT% and nginx's $ssl_client_escaped_cert are URL-encoded
+	if strings.Contains(encoded, "%") {
+		unescaped, err := url.QueryUnescape(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL-encoded client certificate: %v", err)
+		}
+		encoded = unescaped
+	}
+	block, _ := pem.Decode([]byte(encoded))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("client certificate is not a PEM certificate")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client certificate: %v", err)
+	}
+
+	fingerprint := sha256.Sum256(certificate.Raw)
+	valid := !request.Time.Before(certificate.NotBefore) && !request.Time.After(certificate.NotAfter)
+	attributes := map[string]string{
+		"tls.client.subject":     certificate.Subject.CommonName,
+		"tls.client.issuer":      certificate.Issuer.CommonName,
+		"tls.client.serial":      certificate.SerialNumber.String(),
+		"tls.client.not_after":   certificate.NotAfter.UTC().Format(time.RFC3339),
+		"tls.client.valid":       strconv.FormatBool(valid),
+		"tls.client.fingerprint": hex.EncodeToString(fingerprint[:]),
+	}
+	if len(certificate.DNSNames) > 0 {
+		attributes["tls.client.dns_names"] = strings.Join(certificate.DNSNames, ",")
+	}
+	return attributes, nil
+}
+
+// environmentProvidersFromEnv configures the environment providers: IP geolocation from
+// ABAC_ENV_GEOIP_FILE, the business calendar from ABAC_ENV_BUSINESS_HOURS and
+// ABAC_ENV_HOLIDAYS(_FILE), and client certificates with ABAC_ENV_TLS=true
+func environmentProvidersFromEnv() ([]EnvironmentProvider, error) {
+	var providers []EnvironmentProvider
+
+	if path := os.Getenv("ABAC_ENV_GEOIP_FILE"); path != "" {
+		attribute := os.Getenv("ABAC_ENV_IP_ATTRIBUTE")
+		if attribute == "" {
+			attribute = defaultEnvironmentIPAttribute
+		}
+		provider, err := loadGeoIPProvider(path, attribute)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ABAC_ENV_GEOIP_FILE value: %v", err)
+		}
+		providers = append(providers, provider)
+	}
+
+	hours, days := os.Getenv("ABAC_ENV_BUSINESS_HOURS"), os.Getenv("ABAC_ENV_BUSINESS_DAYS")
+	holidays, holidaysFile := os.Getenv("ABAC_ENV_HOLIDAYS"), os.Getenv("ABAC_ENV_HOLIDAYS_FILE")
+	if hours != "" || days != "" || holidays != "" || holidaysFile != "" {
+		calendar, err := businessCalendarFromEnv(hours, days, holidays, holidaysFile)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, calendar)
+	}
+
+	if tlsStr := os.Getenv("ABAC_ENV_TLS"); tlsStr != "" {
+		enabled, err := strconv.ParseBool(tlsStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ABAC_ENV_TLS value: %s", tlsStr)
+		}
+		if enabled {
+			providers = append(providers, tlsClientCertProvider{})
+		}
+	}
+	return providers, nil
+}
+
+// businessCalendarFromEnv builds the business calendar, in ABAC_ENV_TIMEZONE (UTC by
+// default)
+func businessCalendarFromEnv(hours, days, holidays, holidaysFile string) (*businessCalendar, error) {
+	if hours == "" {
+		hours = defaultBusinessHours
+	}
+	if days == "" {
+		days = defaultBusinessDays
+	}
+	calendar := &businessCalendar{location: time.UTC, holidays: make(map[string]bool)}
+
+	from, until, ok := strings.Cut(hours, "-")
+	opening, errOpening := parseClock(from)
+	closing, errClosing := parseClock(until)
+	if !ok || errOpening != nil || errClosing != nil || closing <= opening {
+		return nil, fmt.Errorf("invalid ABAC_ENV_BUSINESS_HOURS value: %s", hours)
+	}
+	calendar.open, calendar.close = opening, closing
+
+	var err error
+	if calendar.days, err = parseBusinessDays(days); err != nil {
+		return nil, fmt.Errorf("invalid ABAC_ENV_BUSINESS_DAYS value: %s", days)
+	}
+	if zone := os.Getenv("ABAC_ENV_TIMEZONE"); zone != "" {
+		if calendar.location, err = time.LoadLocation(zone); err != nil {
+			return nil, fmt.Errorf("invalid ABAC_ENV_TIMEZONE value: %s", zone)
+		}
+	}
+	if err := parseHolidays(holidays, calendar.holidays); err != nil {
+		return nil, fmt.Errorf("invalid ABAC_ENV_HOLIDAYS value: %v", err)
+	}
+	if holidaysFile != "" {
+		content, err := os.ReadFile(holidaysFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ABAC_ENV_HOLIDAYS_FILE: %v", err)
+		}
+		if err := parseHolidays(string(content), calendar.holidays); err != nil {
+			return nil, fmt.Errorf("invalid ABAC_ENV_HOLIDAYS_FILE: %v", err)
+		}
+	}
+	return calendar, nil
+}
+
+// EnvironmentPreviewRequest asks for the environment a check with these attributes sees
+type EnvironmentPreviewRequest struct {
+	Attributes map[string]string `json:"attributes"`
+}
+
+// previewEnvironmentHandler returns the environment attributes ABAC conditions would see
+// for a check with the given request attributes, to debug the environment providers
+func (s *AuthService) previewEnvironmentHandler(w http.ResponseWriter, r *http.Request) {
+	var req EnvironmentPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	var v validator
+	v.attributes("attributes", req.Attributes)
+	if err := v.err(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+	if req.Attributes == nil {
+		req.Attributes = map[string]string{}
+	}
+
+	providers := make([]string, 0, len(s.environmentProviders))
+	for _, provider := range s.environmentProviders {
+		providers = append(providers, provider.Name())
+	}
+	response := map[string]interface{}{
+		"environment": s.environmentFor(req.Attributes),
+		"providers":   providers,
+		"model":       "abac",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,138 @@
+// Multi-Model Authorization Microservice - ABAC Environment Provider Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeGeoIPFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "ip2asn-combined.tsv")
+	content := "1.0.0.0\t1.0.0.255\t13335\tUS\tCLOUDFLARENET\n" +
+		"5.1.0.0\t5.1.63.255\t3320\tDE\tDTAG Internet service provider operations\n" +
+		"5.1.64.0\t5.1.127.255\t0\tNone\tNot routed\n" +
+		"2a01:4f8::\t2a01:4f8:ffff:ffff:ffff:ffff:ffff:ffff\t24940\tDE\tHETZNER-AS\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestEnvironmentProviders_GeoIP(t *testing.T) {
+	provider, err := loadGeoIPProvider(writeGeoIPFile(t), defaultEnvironmentIPAttribute)
+	if err != nil {
+		t.Fatalf("Failed to load IP database: %v", err)
+	}
+
+	for ip, country := range map[string]string{
+		"5.1.20.7":          "DE",
+		"1.0.0.255":         "US",
+		"2a01:4f8:c0c:1::2": "DE",
+		"::ffff:5.1.0.1":    "DE",
+		"5.1.70.1":          "", // Not routed
+		"9.9.9.9":           "",
+		"not-an-ip":         "",
+	} {
+		attributes, err := provider.EnvironmentAttributes(context.Background(), EnvironmentRequest{Attributes: map[string]string{"ip": ip}})
+		if err != nil || attributes["geo.country"] != country {
+			t.Errorf("%s: expected country %q, got %v (%v)", ip, country, attributes, err)
+		}
+	}
+}
+
+func TestEnvironmentProviders_BusinessCalendar(t *testing.T) {
+	t.Setenv("ABAC_ENV_TIMEZONE", "Europe/Berlin")
+	calendar, err := businessCalendarFromEnv("08:30-18:00", "mon-fri", "2026-12-25", "")
+	if err != nil {
+		t.Fatalf("Failed to configure calendar: %v", err)
+	}
+
+	for at, want := range map[string][3]string{
+		"2026-10-19T07:00:00Z": {"false", "true", "true"},   // Monday, 09:00 in Berlin
+		"2026-10-19T06:00:00Z": {"false", "true", "false"},  // 08:00 in Berlin
+		"2026-10-18T10:00:00Z": {"false", "false", "false"}, // Sunday
+		"2026-12-25T10:00:00Z": {"true", "false", "false"},  // Friday, but a holiday
+	} {
+		now, _ := time.Parse(time.RFC3339, at)
+		attributes, _ := calendar.EnvironmentAttributes(context.Background(), EnvironmentRequest{Time: now})
+		if got := [3]string{attributes["holiday"], attributes["business_day"], attributes["business_hours"]}; got != want {
+			t.Errorf("%s: expected holiday, business day and hours %v, got %v", at, want, got)
+		}
+	}
+
+	for _, hours := range []string{"18:00-08:00", "9-17", "09:00"} {
+		if _, err := businessCalendarFromEnv(hours, "", "", ""); err == nil {
+			t.Errorf("Expected business hours %q to be rejected", hours)
+		}
+	}
+	if _, err := businessCalendarFromEnv("", "mon-xyz", "", ""); err == nil {
+		t.Error("Expected unknown business days to be rejected")
+	}
+}
+
+func TestEnvironmentProviders_TLSClientCertificate(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "device-17"},
+		DNSNames:     []string{"device-17.corp.example.com"},
+		NotBefore:    time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := url.QueryEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	attributes, err := tlsClientCertProvider{}.EnvironmentAttributes(context.Background(), EnvironmentRequest{Time: now, Attributes: map[string]string{tlsClientCertAttribute: encoded}})
+	if err != nil || attributes["tls.client.subject"] != "device-17" || attributes["tls.client.valid"] != "true" ||
+		attributes["tls.client.serial"] != "42" || attributes["tls.client.dns_names"] != "device-17.corp.example.com" {
+		t.Errorf("Unexpected certificate attributes %v: %v", attributes, err)
+	}
+
+	if _, err := (tlsClientCertProvider{}).EnvironmentAttributes(context.Background(), EnvironmentRequest{Attributes: map[string]string{tlsClientCertAttribute: "garbage"}}); err == nil {
+		t.Error("Expected an invalid certificate to be reported")
+	}
+}
+
+func TestEnvironmentProviders_ABACDecisions(t *testing.T) {
+	service := setupTestService(t)
+	t.Setenv("ABAC_ENV_GEOIP_FILE", writeGeoIPFile(t))
+	providers, err := environmentProvidersFromEnv()
+	if err != nil || len(providers) != 1 {
+		t.Fatalf("Expected the GeoIP provider, got %v: %v", providers, err)
+	}
+	service.environmentProviders = providers
+
+	service.policyEngine.AddPolicy(&ABACPolicy{
+		ID: "eu-only", Name: "EU only", Effect: "allow",
+		Conditions: []PolicyCondition{{Type: "environment", Field: "geo.country", Operator: "in", Value: "DE,FR"}},
+	})
+
+	if allowed, _ := service.Enforce(ModelABAC, "alice", "report", "read", map[string]string{"ip": "5.1.20.7"}); !allowed {
+		t.Error("Expected a German client to be allowed")
+	}
+	if allowed, _ := service.Enforce(ModelABAC, "alice", "report", "read", map[string]string{"ip": "1.0.0.1"}); allowed {
+		t.Error("Expected a US client to be denied")
+	}
+	// Attributes sent by the caller win over provided ones
+	if allowed, _ := service.Enforce(ModelABAC, "alice", "report", "read", map[string]string{"ip": "1.0.0.1", "geo.country": "FR"}); !allowed {
+		t.Error("Expected the caller's country to be used")
+	}
+}
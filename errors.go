@@ -0,0 +1,82 @@
+// Multi-Model Authorization Microservice - Error Responses
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// ErrorResponse is the JSON body of every error returned by the API
+type ErrorResponse struct {
+	Code    string      `json:"code"`              // Machine-readable error, e.g. "not_found" or "validation_failed"
+	Message string      `json:"message"`           // Human-readable description
+	Details interface{} `json:"details,omitempty"` // Further information, e.g. the fields failing validation
+}
+
+// errorCode derives the error code of a status from its text, e.g. 404 becomes "not_found"
+func errorCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
+
+// writeErrorResponse writes an error envelope with the given status code
+func writeErrorResponse(w http.ResponseWriter, status int, response ErrorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// writeJSONError writes an error payload with the given status code
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeErrorResponse(w, status, ErrorResponse{Code: errorCode(status), Message: message})
+}
+
+// notFoundHandler answers requests matching no route with an error envelope
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSONError(w, http.StatusNotFound, "No endpoint for "+r.Method+" "+r.URL.Path)
+}
+
+// methodNotAllowedHandler answers requests matching a route but not its method with an
+// error envelope
+func methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSONError(w, http.StatusMethodNotAllowed, "Method "+r.Method+" is not allowed for "+r.URL.Path)
+}
+
+// routeMethods are the methods routeNotFoundHandler probes a path with
+var routeMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+// routeNotFoundHandler answers requests no route of router handles with an error envelope:
+// 405 and the allowed methods when the path has routes for other methods, 404 otherwise.
+// Routes of a subrouter share its path prefix, which keeps mux from telling the two apart.
+func routeNotFoundHandler(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var allowed []string
+		for _, method := range routeMethods {
+			if method == r.Method {
+				continue
+			}
+			probe := r.Clone(r.Context())
+			probe.Method = method
+			var match mux.RouteMatch
+			if router.Match(probe, &match) && match.MatchErr == nil {
+				allowed = append(allowed, method)
+			}
+		}
+		if len(allowed) == 0 {
+			notFoundHandler(w, r)
+			return
+		}
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		methodNotAllowedHandler(w, r)
+	})
+}
@@ -0,0 +1,241 @@
+// Multi-Model Authorization Microservice - ReBAC Expand
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// Kinds of userset tree nodes
+const (
+	expandUnion          = "union"            // Children hold the relation together
+	expandThis           = "this"             // Subjects of tuples with the relation itself
+	expandGroup          = "group"            // Members of a group holding the relation
+	expandTupleToUserset = "tuple_to_userset" // The relation on objects linked to the object, e.g. parents
+)
+
+// UsersetTree is a node of the tree of subjects holding a relation on an object, like the
+// result of Zanzibar's Expand. Leaves list subjects; inner nodes name the object and
+// relation through which their children hold it.
+type UsersetTree struct {
+	Kind        string         `json:"kind"`
+	Object      string         `json:"object"`
+	Relation    string         `json:"relation"`
+	Subjects    []string       `json:"subjects,omitempty"`    // Subjects of the node's own tuples
+	Conditional []string       `json:"conditional,omitempty"` // Subjects of caveated tuples, which hold the relation only in a matching request context
+	Children    []*UsersetTree `json:"children,omitempty"`
+	Truncated   bool           `json:"truncated,omitempty"` // Not expanded further: the depth limit was reached or the node is expanded above
+}
+
+// empty reports whether a node grants the relation to nobody
+func (t *UsersetTree) empty() bool {
+	return len(t.Subjects) == 0 && len(t.Conditional) == 0 && len(t.Children) == 0 && !t.Truncated
+}
+
+// addChild adds a child unless it grants the relation to nobody
+func (t *UsersetTree) addChild(child *UsersetTree) {
+	if child != nil && !child.empty() {
+		t.Children = append(t.Children, child)
+	}
+}
+
+// localize rewrites the names in the tree for a tenant
+func (t *UsersetTree) localize(scope tenantScope) {
+	t.Object = scope.local(t.Object)
+	for i := range t.Subjects {
+		t.Subjects[i] = scope.local(t.Subjects[i])
+	}
+	for i := range t.Conditional {
+		t.Conditional[i] = scope.local(t.Conditional[i])
+	}
+	for _, child := range t.Children {
+		child.localize(scope)
+	}
+}
+
+// leafSubjects collects the subjects holding the relation unconditionally, leaving out
+// groups and usersets that are expanded in the tree, sorted
+func (t *UsersetTree) leafSubjects() []string {
+	expanded := make(map[string]bool)
+	subjects := make(map[string]bool)
+	var walk func(node *UsersetTree)
+	walk = func(node *UsersetTree) {
+		for _, child := range node.Children {
+			expanded[child.Object] = true
+			expanded[child.Object+"#"+child.Relation] = true
+			walk(child)
+		}
+		for _, subject := range node.Subjects {
+			subjects[subject] = true
+		}
+	}
+	walk(t)
+
+	leaves := []string{}
+	for _, subject := range sortedSet(subjects) {
+		if !expanded[subject] {
+			leaves = append(leaves, subject)
+		}
+	}
+	return leaves
+}
+
+// Expand returns the tree of subjects holding relation on object: through their own tuples,
+// through groups and usersets, and through linked objects such as parents. Nesting is
+// followed up to maxDepth levels.
+func (rg *RelationshipGraph) Expand(object, relation string, maxDepth int) *UsersetTree {
+	unlock := rg.readLock()
+	defer unlock()
+	// Like checks, objects of a defined namespace are expanded by its rewrite rules all the
+	// way down, and others by the built-in group and parent traversal
+	namespaced := rg.definitionFor(object) != nil
+	return rg.expand(object, relation, 0, maxDepth, make(map[string]bool), namespaced)
+}
+
+// expand implements Expand for callers holding the lock. visiting holds the object#relation
+// pairs being expanded so cycles terminate.
+func (rg *RelationshipGraph) expand(object, relation string, depth, maxDepth int, visiting map[string]bool, namespaced bool) *UsersetTree {
+	tree := &UsersetTree{Kind: expandUnion, Object: object, Relation: relation}
+	key := object + "#" + relation
+	if depth >= maxDepth || visiting[key] {
+		tree.Truncated = true
+		return tree
+	}
+	visiting[key] = true
+	defer delete(visiting, key)
+
+	rg.ensureObjectLoaded(object)
+	incoming := rg.reverse.incomingTo(object)
+
+	if !namespaced {
+		tree.addChild(rg.expandThis(object, relation, incoming, depth, maxDepth, visiting, false))
+		tree.addChild(rg.expandTupleToUserset(object, "parent", relation, incoming, depth, maxDepth, visiting, false))
+		return tree
+	}
+
+	rewrite := RelationRewrite{This: true}
+	if def := rg.definitionFor(object); def != nil {
+		if defined, exists := def.Relations[relation]; exists {
+			rewrite = defined
+		}
+	}
+	if rewrite.This {
+		tree.addChild(rg.expandThis(object, relation, incoming, depth, maxDepth, visiting, true))
+	}
+	for _, computed := range rewrite.ComputedUsersets {
+		tree.addChild(rg.expand(object, computed, depth+1, maxDepth, visiting, true))
+	}
+	for _, ttu := range rewrite.TupleToUsersets {
+		tree.addChild(rg.expandTupleToUserset(object, ttu.Tupleset, ttu.ComputedUserset, incoming, depth, maxDepth, visiting, true))
+	}
+	return tree
+}
+
+// expandThis lists the subjects of the tuples with relation on object. Userset subjects
+// are expanded in namespaced objects and groups in the others, as checks follow them.
+func (rg *RelationshipGraph) expandThis(object, relation string, incoming []Relationship, depth, maxDepth int, visiting map[string]bool, namespaced bool) *UsersetTree {
+	node := &UsersetTree{Kind: expandThis, Object: object, Relation: relation}
+	for _, rel := range incoming {
+		if rel.Relationship != relation {
+			continue
+		}
+		if _, caveated := rg.caveats[rel]; caveated {
+			node.Conditional = append(node.Conditional, rel.Subject)
+			continue
+		}
+		node.Subjects = append(node.Subjects, rel.Subject)
+
+		if namespaced {
+			if setObject, setRelation, ok := splitUserset(rel.Subject); ok {
+				node.addChild(rg.expand(setObject, setRelation, depth+1, maxDepth, visiting, true))
+			}
+		} else if rg.isGroup(rel.Subject) {
+			node.addChild(rg.expandGroup(rel.Subject, depth+1, min(maxDepth, depth+rg.maxGroupDepth()), visiting))
+		}
+	}
+	return node
+}
+
+// expandGroup lists the members of a group in the built-in model, expanding nested groups
+func (rg *RelationshipGraph) expandGroup(group string, depth, maxDepth int, visiting map[string]bool) *UsersetTree {
+	node := &UsersetTree{Kind: expandGroup, Object: group, Relation: "member"}
+	key := group + "#member"
+	if depth > maxDepth || visiting[key] {
+		node.Truncated = true
+		return node
+	}
+	visiting[key] = true
+	defer delete(visiting, key)
+
+	rg.ensureObjectLoaded(group)
+	for _, rel := range rg.reverse.incomingTo(group) {
+		if rel.Relationship != "member" {
+			continue
+		}
+		if _, caveated := rg.caveats[rel]; caveated {
+			node.Conditional = append(node.Conditional, rel.Subject)
+			continue
+		}
+		node.Subjects = append(node.Subjects, rel.Subject)
+		if rg.isGroup(rel.Subject) {
+			node.addChild(rg.expandGroup(rel.Subject, depth+1, maxDepth, visiting))
+		}
+	}
+	return node
+}
+
+// expandTupleToUserset expands relation on each object linked to object through tupleset
+func (rg *RelationshipGraph) expandTupleToUserset(object, tupleset, relation string, incoming []Relationship, depth, maxDepth int, visiting map[string]bool, namespaced bool) *UsersetTree {
+	node := &UsersetTree{Kind: expandTupleToUserset, Object: object, Relation: tupleset}
+	for _, rel := range incoming {
+		if rel.Relationship != tupleset {
+			continue
+		}
+		if _, caveated := rg.caveats[rel]; caveated {
+			node.Conditional = append(node.Conditional, rel.Subject)
+			continue
+		}
+		node.addChild(rg.expand(rel.Subject, relation, depth+1, maxDepth, visiting, namespaced))
+	}
+	return node
+}
+
+// expandRelationHandler returns the tree of subjects holding a relation on an object
+func (s *AuthService) expandRelationHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	objectID, relation := vars["objectId"], vars["relation"]
+
+	maxDepth, err := s.parseMaxDepth(r.URL.Query().Get("max_depth"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	tree := s.relationshipGraph.Expand(scope.qualify(objectID), relation, maxDepth)
+	tree.localize(scope)
+	subjects := tree.leafSubjects()
+
+	response := map[string]interface{}{
+		"object":    objectID,
+		"relation":  relation,
+		"tree":      tree,
+		"subjects":  subjects,
+		"count":     len(subjects),
+		"max_depth": maxDepth,
+		"model":     "rebac",
+	}
+	s.relationshipGraph.addConsistencyToken(response)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
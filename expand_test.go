@@ -0,0 +1,114 @@
+// Multi-Model Authorization Microservice - ReBAC Expand Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// findNode returns the first node of a tree with the given kind, object and relation
+func findNode(tree *UsersetTree, kind, object, relation string) *UsersetTree {
+	if tree.Kind == kind && tree.Object == object && tree.Relation == relation {
+		return tree
+	}
+	for _, child := range tree.Children {
+		if node := findNode(child, kind, object, relation); node != nil {
+			return node
+		}
+	}
+	return nil
+}
+
+func TestExpand_GroupsAndParents(t *testing.T) {
+	service := setupTestService(t)
+	rg := service.relationshipGraph
+
+	rg.AddRelationship("alice", "viewer", "report")
+	rg.AddRelationship("engineering", "viewer", "report")
+	rg.AddRelationship("bob", "member", "engineering")
+	rg.AddRelationship("backend", "member", "engineering")
+	rg.AddRelationship("carol", "member", "backend")
+	rg.AddRelationship("reports", "parent", "report")
+	rg.AddRelationship("dave", "viewer", "reports")
+	rg.AddRelationship("erin", "editor", "report") // Another relation
+	rg.AddConditionalRelationship("frank", "viewer", "report", nil, &RelationshipCaveat{Conditions: []CaveatCondition{{Attribute: "ip", Operator: "cidr", Value: "10.0.0.0/8"}}})
+
+	tree := rg.Expand("report", "viewer", 10)
+	this := findNode(tree, expandThis, "report", "viewer")
+	if this == nil || strings.Join(this.Subjects, ",") != "alice,engineering" || strings.Join(this.Conditional, ",") != "frank" {
+		t.Fatalf("Expected the direct viewers, got %+v", this)
+	}
+	if group := findNode(tree, expandGroup, "backend", "member"); group == nil || strings.Join(group.Subjects, ",") != "carol" {
+		t.Errorf("Expected the nested group to be expanded, got %+v", group)
+	}
+	parent := findNode(tree, expandTupleToUserset, "report", "parent")
+	if parent == nil || len(parent.Children) != 1 || parent.Children[0].Object != "reports" {
+		t.Fatalf("Expected the parent to be expanded, got %+v", parent)
+	}
+	if subjects := strings.Join(tree.leafSubjects(), ","); subjects != "alice,bob,carol,dave" {
+		t.Errorf("Expected the unconditional viewers, got %s", subjects)
+	}
+
+	// Depth limits and cycles cut the tree
+	if group := findNode(rg.Expand("report", "viewer", 1), expandUnion, "reports", "viewer"); group == nil || !group.Truncated {
+		t.Errorf("Expected the parent to be truncated at depth 1, got %+v", group)
+	}
+	rg.AddRelationship("report", "parent", "reports")
+	if subjects := strings.Join(rg.Expand("report", "viewer", 10).leafSubjects(), ","); subjects != "alice,bob,carol,dave" {
+		t.Errorf("Expected the cyclic hierarchy to terminate, got %s", subjects)
+	}
+}
+
+func TestExpand_Namespaces(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/objects/{objectId}/relations/{relation}/expand", service.expandRelationHandler).Methods("GET")
+
+	rg := service.relationshipGraph
+	if err := rg.SetNamespace(&NamespaceDefinition{Name: "document", Relations: map[string]RelationRewrite{
+		"editor": {This: true},
+		"viewer": {This: true, ComputedUsersets: []string{"editor"}, TupleToUsersets: []TupleToUserset{{Tupleset: "parent", ComputedUserset: "viewer"}}},
+	}}); err != nil {
+		t.Fatalf("Failed to define the document namespace: %v", err)
+	}
+	rg.AddRelationship("alice", "editor", "document:plan")
+	rg.AddRelationship("folder:shared", "parent", "document:plan")
+	rg.AddRelationship("group:eng#member", "viewer", "folder:shared")
+	rg.AddRelationship("bob", "member", "group:eng")
+	rg.AddRelationship("carol", "owner", "document:plan") // Not part of viewer
+
+	req, _ := http.NewRequest("GET", "/api/v1/objects/document:plan/relations/viewer/expand", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+	var response struct {
+		Tree     *UsersetTree `json:"tree"`
+		Subjects []string     `json:"subjects"`
+		Count    int          `json:"count"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if strings.Join(response.Subjects, ",") != "alice,bob" || response.Count != 2 {
+		t.Errorf("Expected alice and bob, got %s", rr.Body.String())
+	}
+	if node := findNode(response.Tree, expandUnion, "document:plan", "editor"); node == nil {
+		t.Errorf("Expected the computed userset in the tree, got %s", rr.Body.String())
+	}
+	if node := findNode(response.Tree, expandUnion, "group:eng", "member"); node == nil || strings.Join(node.Children[0].Subjects, ",") != "bob" {
+		t.Errorf("Expected the userset to be expanded, got %s", rr.Body.String())
+	}
+
+	// Every check the tree grants agrees with the regular check
+	for _, subject := range response.Subjects {
+		if allowed, _ := rg.CheckReBACAccess(subject, "document:plan", "read"); !allowed {
+			t.Errorf("Expected %s to be able to read", subject)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+// Multi-Model Authorization Microservice - Time-Bound Relationships
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// defaultRelationshipSweepInterval is how often expired tuples are purged from the database
+const defaultRelationshipSweepInterval = time.Minute
+
+// relationshipExpired reports whether a stored tuple no longer grants access at now
+func relationshipExpired(record RelationshipRecord, now time.Time) bool {
+	return record.ExpiresAt != nil && !now.Before(*record.ExpiresAt)
+}
+
+// trackExpiry remembers when an in-memory tuple stops granting access. It must be called
+// before the tuple is added to memory: a permanent copy of the tuple keeps it permanent,
+// and of several expiring copies the latest expiry wins.
+func (rg *RelationshipGraph) trackExpiry(rel Relationship, expiresAt *time.Time) {
+	current, expiring := rg.expiries[rel]
+	existed := rg.reverse.incoming[rel.Object][rel] > 0
+
+	switch {
+	case expiresAt == nil:
+		delete(rg.expiries, rel)
+	case existed && !expiring:
+		// A permanent copy is already resident
+	case !existed || expiresAt.After(current):
+		if rg.expiries == nil {
+			rg.expiries = make(map[Relationship]time.Time)
+		}
+		rg.expiries[rel] = *expiresAt
+		if rg.nextExpiry.IsZero() || expiresAt.Before(rg.nextExpiry) {
+			rg.nextExpiry = *expiresAt
+		}
+	}
+}
+
+// dropExpired removes tuples whose expiry has passed from memory, so they stop granting
+// access and cached checks relying on them are invalidated. The janitor deletes them from
+// the database later.
+func (rg *RelationshipGraph) dropExpired(now time.Time) {
+	if !rg.expiryDue(now) {
+		return
+	}
+
+	rg.nextExpiry = time.Time{}
+	for rel, expiresAt := range rg.expiries {
+		if !now.Before(expiresAt) {
+			rg.forget(rel.Subject, rel.Relationship, rel.Object)
+			continue
+		}
+		if rg.nextExpiry.IsZero() || expiresAt.Before(rg.nextExpiry) {
+			rg.nextExpiry = expiresAt
+		}
+	}
+}
+
+// expiryDue reports whether an in-memory tuple has expired by now
+func (rg *RelationshipGraph) expiryDue(now time.Time) bool {
+	return !rg.nextExpiry.IsZero() && !now.Before(rg.nextExpiry)
+}
+
+// RelationshipExpirations returns when the time-bound tuples of subject (or of everyone when
+// subject is empty) expire, keyed by "subject:relationship:object"
+func (rg *RelationshipGraph) RelationshipExpirations(subject string) (map[string]time.Time, error) {
+	var records []RelationshipRecord
+	query := rg.db.Where("expires_at IS NOT NULL AND expires_at > ?", time.Now())
+	if subject != "" {
+		query = query.Where("subject = ?", subject)
+	}
+	if err := query.Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	// Of several expiring copies of a tuple the latest expiry wins
+	latest := make(map[Relationship]time.Time)
+	for _, record := range records {
+		rel := Relationship{Subject: record.Subject, Relationship: record.Relationship, Object: record.Object}
+		if current, exists := latest[rel]; !exists || record.ExpiresAt.After(current) {
+			latest[rel] = *record.ExpiresAt
+		}
+	}
+
+	// A permanent copy of a tuple never expires
+	expirations := make(map[string]time.Time, len(latest))
+	for rel, expiresAt := range latest {
+		var permanent int64
+		if err := rg.db.Model(&RelationshipRecord{}).
+			Where("subject = ? AND relationship = ? AND object = ? AND expires_at IS NULL", rel.Subject, rel.Relationship, rel.Object).
+			Count(&permanent).Error; err != nil {
+			return nil, err
+		}
+		if permanent == 0 {
+			expirations[labelKey(rel.Subject, rel.Relationship, rel.Object)] = expiresAt
+		}
+	}
+	return expirations, nil
+}
+
+// PurgeExpiredRelationships deletes expired tuples from the database and returns the tuples
+// that no longer exist at all. Memory is not touched: expired tuples are dropped from it
+// when the graph is next read.
+func (rg *RelationshipGraph) PurgeExpiredRelationships(now time.Time) ([]Relationship, error) {
+	var records []RelationshipRecord
+	if err := rg.db.Where("expires_at IS NOT NULL AND expires_at <= ?", now).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load expired relationships: %v", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+	if err := rg.db.Delete(&RelationshipRecord{}, ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete expired relationships: %v", err)
+	}
+
+	var gone []Relationship
+	seen := make(map[Relationship]bool)
+	for _, record := range records {
+		rel := Relationship{Subject: record.Subject, Relationship: record.Relationship, Object: record.Object}
+		if seen[rel] {
+			continue
+		}
+		seen[rel] = true
+
+		var remaining int64
+		if err := rg.db.Model(&RelationshipRecord{}).
+			Where("subject = ? AND relationship = ? AND object = ?", rel.Subject, rel.Relationship, rel.Object).
+			Count(&remaining).Error; err != nil {
+			return nil, fmt.Errorf("failed to count remaining relationships: %v", err)
+		}
+		if remaining == 0 {
+			gone = append(gone, rel)
+		}
+	}
+	return gone, nil
+}
+
+// purgeExpiredRelationships purges expired tuples and the labels of tuples that are gone
+func (s *AuthService) purgeExpiredRelationships(now time.Time) (int, error) {
+	gone, err := s.relationshipGraph.PurgeExpiredRelationships(now)
+	if err != nil {
+		return 0, err
+	}
+	for _, rel := range gone {
+		s.removeLabels(labelKindRelationship, labelKey(rel.Subject, rel.Relationship, rel.Object))
+		s.logRelationshipChange(changeRemoved, relationshipChange(rel.Subject, rel.Relationship, rel.Object, nil), "")
+	}
+	return len(gone), nil
+}
+
+// relationshipSweepIntervalFromEnv reads REBAC_EXPIRY_SWEEP_INTERVAL
+func relationshipSweepIntervalFromEnv() (time.Duration, error) {
+	intervalStr := os.Getenv("REBAC_EXPIRY_SWEEP_INTERVAL")
+	if intervalStr == "" {
+		return defaultRelationshipSweepInterval, nil
+	}
+	interval, err := time.ParseDuration(intervalStr)
+	if err != nil || interval <= 0 {
+		return 0, fmt.Errorf("invalid REBAC_EXPIRY_SWEEP_INTERVAL value: %s", intervalStr)
+	}
+	return interval, nil
+}
+
+// startRelationshipJanitor purges expired tuples from the database in the background
+func (s *AuthService) startRelationshipJanitor() {
+	go func() {
+		ticker := time.NewTicker(s.relationshipSweepInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purged, err := s.purgeExpiredRelationships(time.Now())
+			if err != nil {
+				log.Printf("Relationship expiry sweep failed: %v", err)
+			} else if purged > 0 {
+				log.Printf("Relationship expiry sweep purged %d expired relationships", purged)
+			}
+			if _, err := s.trimRelationshipChanges(time.Now()); err != nil {
+				log.Printf("Relationship change log trim failed: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,147 @@
+// Multi-Model Authorization Microservice - Time-Bound Relationship Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExpiry_ExpiredTuplesStopGrantingAccess(t *testing.T) {
+	service := setupTestService(t)
+	rg := service.relationshipGraph
+	rg.SetCheckCacheSize(100)
+
+	expiresAt := time.Now().Add(time.Hour)
+	if err := rg.AddExpiringRelationship("guest", "viewer", "doc1", &expiresAt); err != nil {
+		t.Fatalf("Failed to add expiring relationship: %v", err)
+	}
+	if allowed, _ := service.Enforce(ModelReBAC, "guest", "doc1", "read", nil); !allowed {
+		t.Fatal("Expected the guest to read before the tuple expires")
+	}
+
+	// Pretend the hour has passed; the cached decision must not outlive the tuple
+	rg.expiries[Relationship{Subject: "guest", Relationship: "viewer", Object: "doc1"}] = time.Now().Add(-time.Second)
+	rg.nextExpiry = time.Now().Add(-time.Second)
+	if allowed, _ := service.Enforce(ModelReBAC, "guest", "doc1", "read", nil); allowed {
+		t.Error("Expected the expired tuple to stop granting access")
+	}
+	if relationships, _ := rg.ListRelationships("guest"); len(relationships) != 0 {
+		t.Errorf("Expected expired tuples not to be listed, got %+v", relationships)
+	}
+
+	// Tuples that expired while the server was down are not loaded
+	past := time.Now().Add(-time.Minute)
+	service.db.Create(&RelationshipRecord{Subject: "guest", Relationship: "viewer", Object: "doc2", ExpiresAt: &past})
+	if err := rg.loadFromDatabase(); err != nil {
+		t.Fatalf("Failed to reload relationships: %v", err)
+	}
+	if rg.HasDirectRelationship("guest", "viewer", "doc2") {
+		t.Error("Expected the expired stored tuple to be skipped on load")
+	}
+}
+
+func TestExpiry_PermanentCopyWins(t *testing.T) {
+	service := setupTestService(t)
+	rg := service.relationshipGraph
+
+	soon := time.Now().Add(time.Minute)
+	later := time.Now().Add(time.Hour)
+	rg.AddRelationship("alice", "editor", "doc1")
+	rg.AddExpiringRelationship("alice", "editor", "doc1", &soon)
+	rg.AddExpiringRelationship("bob", "viewer", "doc1", &soon)
+	rg.AddExpiringRelationship("bob", "viewer", "doc1", &later)
+
+	expirations, err := rg.RelationshipExpirations("")
+	if err != nil {
+		t.Fatalf("Failed to list expirations: %v", err)
+	}
+	if _, ok := expirations["alice:editor:doc1"]; ok {
+		t.Error("Expected a tuple with a permanent copy not to expire")
+	}
+	if !expirations["bob:viewer:doc1"].Equal(later) {
+		t.Errorf("Expected the latest expiry to win, got %v", expirations["bob:viewer:doc1"])
+	}
+
+	rg.dropExpired(soon.Add(time.Second))
+	if !rg.HasDirectRelationship("alice", "editor", "doc1") || !rg.HasDirectRelationship("bob", "viewer", "doc1") {
+		t.Error("Expected both tuples to outlive the earlier expiry")
+	}
+}
+
+func TestExpiry_PurgeRemovesExpiredRowsAndLabels(t *testing.T) {
+	service := setupTestService(t)
+	rg := service.relationshipGraph
+
+	expiresAt := time.Now().Add(time.Hour)
+	rg.AddExpiringRelationship("guest", "viewer", "doc1", &expiresAt)
+	rg.AddRelationship("alice", "viewer", "doc1")
+	rg.AddExpiringRelationship("alice", "viewer", "doc1", &expiresAt)
+	service.setLabels(labelKindRelationship, labelKey("guest", "viewer", "doc1"), []string{"temporary"})
+	service.setLabels(labelKindRelationship, labelKey("alice", "viewer", "doc1"), []string{"staff"})
+
+	// Nothing has expired yet
+	if purged, err := service.purgeExpiredRelationships(time.Now()); err != nil || purged != 0 {
+		t.Fatalf("Expected nothing to purge, got %d: %v", purged, err)
+	}
+
+	purged, err := service.purgeExpiredRelationships(expiresAt.Add(time.Second))
+	if err != nil || purged != 1 {
+		t.Fatalf("Expected one tuple to be purged, got %d: %v", purged, err)
+	}
+
+	var remaining int64
+	service.db.Model(&RelationshipRecord{}).Count(&remaining)
+	if remaining != 1 {
+		t.Errorf("Expected only alice's permanent row to remain, got %d rows", remaining)
+	}
+	labels, _ := service.labelsByKey(labelKindRelationship)
+	if _, ok := labels["guest:viewer:doc1"]; ok {
+		t.Errorf("Expected the purged tuple's labels to be removed, got %v", labels)
+	}
+	if len(labels["alice:viewer:doc1"]) != 1 {
+		t.Errorf("Expected the surviving tuple to keep its labels, got %v", labels)
+	}
+}
+
+func TestExpiry_AddRelationshipHandler(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/v1/relationships", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	past := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	if rr := post(`{"subject": "guest", "relationship": "viewer", "object": "doc1", "expires_at": "` + past + `"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an expiry in the past, got %d", rr.Code)
+	}
+
+	future := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	rr := post(`{"subject": "guest", "relationship": "viewer", "object": "doc1", "expires_at": "` + future.Format(time.RFC3339) + `"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req, _ := http.NewRequest("GET", "/api/v1/relationships?subject=guest", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var listing struct {
+		Relationships []Relationship       `json:"relationships"`
+		Expirations   map[string]time.Time `json:"expirations"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &listing)
+	if len(listing.Relationships) != 1 || !listing.Expirations["guest:viewer:doc1"].Equal(future) {
+		t.Errorf("Expected the tuple to be listed with its expiry, got %s", rr.Body.String())
+	}
+}
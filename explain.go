@@ -0,0 +1,357 @@
+// Multi-Model Authorization Microservice - Decision Explanations
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConditionResult is the outcome of one ABAC policy condition
+type ConditionResult struct {
+	Type     string `json:"type"`
+	Field    string `json:"field"`
+	Operator string `json:"operator"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+	LogicOp  string `json:"logic_op,omitempty"`
+	Result   bool   `json:"result"`
+}
+
+// PolicyResult is the outcome of one ABAC policy, in evaluation order
+type PolicyResult struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Effect     string            `json:"effect"`
+	Priority   int               `json:"priority"`
+	Applies    bool              `json:"applies"` // False when the policy is scoped to other actions
+	Matched    bool              `json:"matched"`
+	Conditions []ConditionResult `json:"conditions,omitempty"`
+	Expression string            `json:"expression,omitempty"`
+	// ExpressionError is why the expression could not be evaluated, e.g. a missing attribute
+	ExpressionError string `json:"expression_error,omitempty"`
+}
+
+// GroupBindingResult is an RBAC rule reached through a ReBAC group the subject belongs to
+type GroupBindingResult struct {
+	Group   string   `json:"group"`
+	Rule    []string `json:"rule"`
+	Allowed bool     `json:"allowed"`
+}
+
+// TraversalStep is one check made while walking the relationship graph
+type TraversalStep struct {
+	Check   string `json:"check"` // "direct", "group", "hierarchy" or "social"
+	Detail  string `json:"detail"`
+	Granted bool   `json:"granted"`
+}
+
+// DecisionExplanation describes why a decision was allowed or denied
+type DecisionExplanation struct {
+	Model   AccessControlModel `json:"model"`
+	Allowed bool               `json:"allowed"`
+	Reason  string             `json:"reason"`
+
+	// ACL and RBAC
+	Roles          []string             `json:"roles,omitempty"`           // Roles including inherited ones (RBAC)
+	ResourceGroups []string             `json:"resource_groups,omitempty"` // Resource groups of the object, nearest first (RBAC)
+	MatchedRule    []string             `json:"matched_rule,omitempty"`    // Rule that decided the check
+	Groups         []string             `json:"groups,omitempty"`          // ReBAC groups checked for role bindings
+	GroupBindings  []GroupBindingResult `json:"group_bindings,omitempty"`
+
+	// ABAC
+	UserAttributes        map[string]string `json:"user_attributes,omitempty"`
+	ObjectAttributes      map[string]string `json:"object_attributes,omitempty"`
+	EnvironmentAttributes map[string]string `json:"environment_attributes,omitempty"`
+	Policies              []PolicyResult    `json:"policies,omitempty"`
+
+	// ReBAC
+	Permission string          `json:"permission,omitempty"`
+	Trace      []TraversalStep `json:"trace,omitempty"`
+	Path       []PathHop       `json:"path,omitempty"`
+}
+
+// explain re-evaluates a decision and records how each model reached it. The outcome
+// comes from the regular enforcement path, so it always matches the decision itself.
+func (s *AuthService) explain(model AccessControlModel, subject, object, action string, attributes map[string]string, freshness string) (*DecisionExplanation, error) {
+	return s.explainInTenant(tenantScope{}, model, subject, object, action, attributes, freshness, nil)
+}
+
+// explainInTenant explains a decision made within a tenant, assuming the contextual tuples
+// exist. Names in the explanation are tenant-qualified, and only the tenant's ABAC policies
+// are listed.
+func (s *AuthService) explainInTenant(scope tenantScope, model AccessControlModel, subject, object, action string, attributes map[string]string, freshness string, contextual []Relationship) (*DecisionExplanation, error) {
+	if model == "" {
+		model = ModelRBAC
+	}
+	subject, object = scope.qualify(subject), scope.qualify(object)
+	contextual = scope.qualifyTuples(contextual)
+	allowed, err := s.EnforceWithTuples(scope, model, subject, object, action, attributes, freshness, contextual)
+	if err != nil {
+		return nil, err
+	}
+	strong := freshness == freshnessStrong
+	explanation := &DecisionExplanation{Model: model, Allowed: allowed}
+
+	switch model {
+	case ModelACL, ModelRBAC:
+		if model == ModelRBAC {
+			roles, err := s.rbacEnforcer.GetImplicitRolesForUser(subject)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve roles: %v", err)
+			}
+			explanation.Roles = roles
+
+			resourceGroups, err := s.ResourceGroupAncestors(object)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve resource groups: %v", err)
+			}
+			for _, ancestor := range resourceGroups {
+				explanation.ResourceGroups = append(explanation.ResourceGroups, ancestor.Group)
+			}
+		}
+
+		_, rule, err := s.getEnforcer(model).EnforceEx(subject, object, action)
+		if err != nil {
+			return nil, err
+		}
+		explanation.MatchedRule = rule
+		if len(rule) > 0 {
+			explanation.Reason = fmt.Sprintf("decided by rule [%s]", strings.Join(rule, ", "))
+			break
+		}
+		explanation.Reason = "no rule matched"
+
+		if model == ModelRBAC && s.rbacGroupBindings {
+			graph := s.relationshipGraph
+			if len(contextual) > 0 {
+				graph = graph.withContextualTuples(contextual)
+			} else if strong {
+				graph = graph.freshSnapshot()
+			}
+			_, explanation.Groups = graph.GroupsForSubject(subject, s.currentSettings().maxDepthLimit)
+			if explanation.Groups == nil {
+				explanation.Groups = []string{}
+			}
+			for _, group := range explanation.Groups {
+				groupAllowed, groupRule, err := s.rbacEnforcer.EnforceEx(group, object, action)
+				if err == nil && len(groupRule) > 0 {
+					explanation.GroupBindings = append(explanation.GroupBindings, GroupBindingResult{Group: group, Rule: groupRule, Allowed: groupAllowed})
+				}
+			}
+			if len(explanation.GroupBindings) > 0 {
+				explanation.Reason = "decided by roles bound to the subject's groups (a deny wins)"
+			}
+		}
+
+	case ModelABAC:
+		ctx := s.abacEvaluationContext(subject, object, action, attributes, strong)
+		ctx.Tenant = scope.tenant
+		explanation.UserAttributes = ctx.UserAttributes
+		explanation.ObjectAttributes = ctx.ObjectAttributes
+		explanation.EnvironmentAttributes = ctx.EnvironmentAttributes
+
+		engine := s.policyEngine
+		explanation.Policies = []PolicyResult{}
+		for _, policy := range engine.sortedPolicies() {
+			// Only the policies Decide considers, so global checks skip tenant policies
+			if policy.Tenant != scope.tenant {
+				continue
+			}
+			result := PolicyResult{
+				ID:       policy.ID,
+				Name:     policy.Name,
+				Effect:   policy.Effect,
+				Priority: policy.Priority,
+				Applies:  engine.appliesToAction(policy, action),
+			}
+			if result.Applies && policy.Expression != "" {
+				result.Expression = policy.Expression
+				matched, err := engine.evaluateExpression(policy.Expression, ctx)
+				result.Matched = matched
+				if err != nil {
+					result.ExpressionError = err.Error()
+				}
+			} else if result.Applies {
+				conditions := policy.allConditions()
+				for i := range conditions {
+					condition := &conditions[i]
+					actual, _ := engine.conditionValue(condition, ctx)
+					result.Conditions = append(result.Conditions, ConditionResult{
+						Type:     condition.Type,
+						Field:    condition.Field,
+						Operator: condition.Operator,
+						Expected: condition.Value,
+						Actual:   actual,
+						LogicOp:  condition.LogicOp,
+						Result:   engine.evaluateCondition(condition, ctx),
+					})
+				}
+				result.Matched = engine.evaluatePolicy(policy, ctx)
+			}
+			explanation.Policies = append(explanation.Policies, result)
+		}
+		_, explanation.Reason = engine.Evaluate(ctx)
+
+	case ModelReBAC:
+		graph := s.relationshipGraph
+		if len(contextual) > 0 {
+			graph = graph.withContextualTuples(contextual)
+		} else if strong {
+			graph = graph.freshSnapshot()
+		}
+		graph = graph.withCaveatContext(attributes)
+		explanation.Permission = graph.ActionPermission(action)
+		explanation.Trace = graph.traceReBACAccess(subject, object, explanation.Permission)
+		if granted, hops := graph.CheckReBACAccessHops(subject, object, action); granted {
+			explanation.Path = hops
+			explanation.Reason = "granted through path: " + formatPath(subject, hops)
+		} else {
+			explanation.Reason = fmt.Sprintf("no relationship path grants %s", explanation.Permission)
+		}
+	}
+
+	return explanation, nil
+}
+
+// traceReBACAccess repeats the checks of evaluateReBACAccess, recording each one until
+// access is granted
+func (rg *RelationshipGraph) traceReBACAccess(subject, object, permission string) []TraversalStep {
+	unlock := rg.readLock()
+	defer unlock()
+
+	var trace []TraversalStep
+	step := func(check string, granted bool, format string, args ...interface{}) bool {
+		trace = append(trace, TraversalStep{Check: check, Detail: fmt.Sprintf(format, args...), Granted: granted})
+		return granted
+	}
+
+	// 1. Direct relationships
+	direct := rg.directRelationships(subject, object)
+	if len(direct) == 0 {
+		step("direct", false, "%s has no direct relationship to %s", subject, object)
+	}
+	for _, rel := range direct {
+		granted := rg.HasPermissionThroughRelationship(rel.Relationship, permission)
+		if step("direct", granted, "%s -[%s]-> %s grants [%s]", subject, rel.Relationship, object, strings.Join(rg.GetPermissionsForRelationship(rel.Relationship), ", ")) {
+			return trace
+		}
+	}
+
+	// 2. Group membership, including nested groups
+	_, groups := rg.groupsForSubject(subject, rg.maxGroupDepth())
+	if len(groups) == 0 {
+		step("group", false, "%s is not a member of any group", subject)
+	}
+	for _, group := range groups {
+		groupRelationships := rg.directRelationships(group, object)
+		if len(groupRelationships) == 0 {
+			step("group", false, "group %s has no relationship to %s", group, object)
+		}
+		for _, rel := range groupRelationships {
+			granted := rg.HasPermissionThroughRelationship(rel.Relationship, permission)
+			if step("group", granted, "group %s -[%s]-> %s grants [%s]", group, rel.Relationship, object, strings.Join(rg.GetPermissionsForRelationship(rel.Relationship), ", ")) {
+				return trace
+			}
+		}
+	}
+
+	// 3. Parent objects
+	rg.ensureObjectLoaded(object)
+	var parents []string
+	for _, rel := range rg.reverse.incomingTo(object) {
+		if rel.Relationship == "parent" {
+			parents = append(parents, rel.Subject)
+		}
+	}
+	if len(parents) == 0 {
+		step("hierarchy", false, "%s has no parent", object)
+	}
+	for _, parent := range parents {
+		if granted, hops := rg.checkReBACAccessHops(subject, parent, permission); granted {
+			step("hierarchy", true, "%s inherits from parent %s, reached through %s", object, parent, formatPath(subject, hops))
+			return trace
+		}
+		step("hierarchy", false, "no access to parent %s", parent)
+	}
+
+	// 4. Social connections, for reads only
+	if permission != "read" && permission != "read_limited" {
+		step("social", false, "social connections only grant reads")
+		return trace
+	}
+	found, hops := rg.findRelationshipHops(subject, object, socialAccessDepth)
+	switch {
+	case !found:
+		step("social", false, "no path from %s to %s within %d hops", subject, object, socialAccessDepth)
+	case !pathHasRelation(hops, "friend"):
+		step("social", false, "shortest path %s has no friend relationship", formatPath(subject, hops))
+	default:
+		step("social", rg.HasPermissionThroughRelationship("friend", "read_limited"), "friend path %s", formatPath(subject, hops))
+	}
+	return trace
+}
+
+// Steps renders the explanation as human-readable lines
+func (e *DecisionExplanation) Steps() []string {
+	var steps []string
+	step := func(format string, args ...interface{}) {
+		steps = append(steps, fmt.Sprintf(format, args...))
+	}
+
+	switch e.Model {
+	case ModelACL, ModelRBAC:
+		if e.Model == ModelRBAC {
+			step("roles (including inherited): [%s]", strings.Join(e.Roles, ", "))
+			if len(e.ResourceGroups) > 0 {
+				step("resource groups of the object: [%s]", strings.Join(e.ResourceGroups, ", "))
+			}
+		}
+		if len(e.MatchedRule) > 0 {
+			step("decided by rule [%s]", strings.Join(e.MatchedRule, ", "))
+		} else {
+			step("no rule matched")
+		}
+		if e.Groups != nil {
+			step("ReBAC groups checked for role bindings: [%s]", strings.Join(e.Groups, ", "))
+			for _, binding := range e.GroupBindings {
+				step("group %s decided by rule [%s] (allowed=%v)", binding.Group, strings.Join(binding.Rule, ", "), binding.Allowed)
+			}
+		}
+
+	case ModelABAC:
+		step("user attributes: %v", e.UserAttributes)
+		step("object attributes: %v", e.ObjectAttributes)
+		step("environment attributes: %v", e.EnvironmentAttributes)
+		for _, policy := range e.Policies {
+			switch {
+			case !policy.Applies:
+				step("policy %s (priority %d, %s): skipped, scoped to other actions", policy.ID, policy.Priority, policy.Effect)
+			case policy.Matched:
+				step("policy %s (priority %d, %s): matched", policy.ID, policy.Priority, policy.Effect)
+			default:
+				step("policy %s (priority %d, %s): conditions not met", policy.ID, policy.Priority, policy.Effect)
+			}
+			for _, condition := range policy.Conditions {
+				step("  %s.%s %s %q (actual %q): %v", condition.Type, condition.Field, condition.Operator, condition.Expected, condition.Actual, condition.Result)
+			}
+			if policy.ExpressionError != "" {
+				step("  %s: %s", policy.Expression, policy.ExpressionError)
+			} else if policy.Expression != "" {
+				step("  %s: %v", policy.Expression, policy.Matched)
+			}
+		}
+		step("result: %s", e.Reason)
+
+	case ModelReBAC:
+		step("permission required: %s", e.Permission)
+		for _, traversal := range e.Trace {
+			step("%s check: %s (granted=%v)", traversal.Check, traversal.Detail, traversal.Granted)
+		}
+		step("%s", e.Reason)
+	}
+
+	return steps
+}
@@ -0,0 +1,127 @@
+// Multi-Model Authorization Microservice - Decision Explanation Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExplain_AuthorizationsReturnExplanation(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+
+	service.rbacEnforcer.AddRoleForUser("bob", "contractor")
+	service.rbacEnforcer.AddRoleForUser("contractor", "staff")
+	service.rbacEnforcer.AddPolicy("staff", "finance_docs", "read", "allow")
+	service.rbacEnforcer.AddPolicy("contractor", "finance_docs", "delete", "deny")
+
+	authorize := func(url, body string) (int, map[string]json.RawMessage) {
+		req, _ := http.NewRequest("POST", url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var response map[string]json.RawMessage
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr.Code, response
+	}
+
+	code, response := authorize("/api/v1/authorizations", `{"model": "rbac", "subject": "bob", "object": "finance_docs", "action": "delete", "explain": true}`)
+	if code != http.StatusForbidden {
+		t.Fatalf("Expected 403, got %d", code)
+	}
+	var explanation DecisionExplanation
+	json.Unmarshal(response["explanation"], &explanation)
+	if explanation.Allowed || strings.Join(explanation.MatchedRule, ",") != "contractor,finance_docs,delete,deny" {
+		t.Errorf("Expected the deny rule to be reported, got %+v", explanation)
+	}
+	if strings.Join(explanation.Roles, ",") != "contractor,staff" {
+		t.Errorf("Expected inherited roles, got %v", explanation.Roles)
+	}
+
+	// The query parameter works too, and explanations are omitted unless requested
+	_, response = authorize("/api/v1/authorizations?explain=true", `{"model": "rbac", "subject": "bob", "object": "finance_docs", "action": "read"}`)
+	json.Unmarshal(response["explanation"], &explanation)
+	if !explanation.Allowed || !strings.Contains(explanation.Reason, "staff, finance_docs, read, allow") {
+		t.Errorf("Expected the granting rule, got %+v", explanation)
+	}
+	if _, response = authorize("/api/v1/authorizations", `{"model": "rbac", "subject": "bob", "object": "finance_docs", "action": "read"}`); response["explanation"] != nil {
+		t.Error("Expected no explanation unless requested")
+	}
+}
+
+func TestExplain_ABACConditionResults(t *testing.T) {
+	service := setupTestService(t)
+	service.saveUserAttribute("carol", "department", "finance")
+	service.saveUserAttribute("carol", "level", "3")
+	service.policyEngine.AddPolicy(&ABACPolicy{
+		ID:       "senior-finance",
+		Name:     "Senior finance staff",
+		Effect:   "allow",
+		Priority: 10,
+		Conditions: []PolicyCondition{
+			{Type: "user", Field: "department", Operator: "eq", Value: "finance", LogicOp: "and"},
+			{Type: "user", Field: "level", Operator: "gte", Value: "5"},
+		},
+	})
+	service.policyEngine.AddPolicy(&ABACPolicy{
+		ID:      "writes-only",
+		Name:    "Writers",
+		Effect:  "allow",
+		Actions: []string{"write"},
+		Conditions: []PolicyCondition{
+			{Type: "user", Field: "department", Operator: "eq", Value: "finance"},
+		},
+	})
+
+	explanation, err := service.explain(ModelABAC, "carol", "ledger", "read", nil, freshnessDefault)
+	if err != nil {
+		t.Fatalf("Failed to explain: %v", err)
+	}
+	if explanation.Allowed || explanation.Reason != "No policy grants access" || len(explanation.Policies) != 2 {
+		t.Fatalf("Unexpected explanation: %+v", explanation)
+	}
+	senior := explanation.Policies[0]
+	if !senior.Applies || senior.Matched || len(senior.Conditions) != 2 {
+		t.Fatalf("Unexpected policy result: %+v", senior)
+	}
+	if !senior.Conditions[0].Result || senior.Conditions[1].Result || senior.Conditions[1].Actual != "3" {
+		t.Errorf("Expected the level condition to fail on the actual value 3: %+v", senior.Conditions)
+	}
+	if writes := explanation.Policies[1]; writes.Applies || len(writes.Conditions) != 0 {
+		t.Errorf("Expected the write-only policy to be skipped: %+v", writes)
+	}
+}
+
+func TestExplain_ReBACTraversalTrace(t *testing.T) {
+	service := setupTestService(t)
+	rg := service.relationshipGraph
+	rg.AddRelationship("alice", "member", "team")
+	rg.AddRelationship("team", "viewer", "doc1")
+	rg.AddRelationship("bob", "editor", "folder")
+	rg.AddRelationship("folder", "parent", "doc2")
+
+	explanation, _ := service.explain(ModelReBAC, "alice", "doc1", "edit", nil, freshnessDefault)
+	if explanation.Allowed || explanation.Permission != "write" {
+		t.Fatalf("Expected edit to be denied as write, got %+v", explanation)
+	}
+	checks := map[string]bool{}
+	for _, step := range explanation.Trace {
+		checks[step.Check] = true
+	}
+	if !checks["direct"] || !checks["group"] || !checks["hierarchy"] || !checks["social"] {
+		t.Errorf("Expected every stage to be traced for a denial, got %+v", explanation.Trace)
+	}
+
+	explanation, _ = service.explain(ModelReBAC, "bob", "doc2", "write", nil, freshnessDefault)
+	last := explanation.Trace[len(explanation.Trace)-1]
+	if !explanation.Allowed || last.Check != "hierarchy" || !last.Granted || len(explanation.Path) != 2 {
+		t.Errorf("Expected access inherited from the folder, got %+v", explanation)
+	}
+}
@@ -0,0 +1,182 @@
+// Multi-Model Authorization Microservice - ABAC Policy Expressions
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/ext"
+)
+
+const (
+	// maxExpressionCost bounds the work of evaluating one policy expression, so expressions
+	// looping over long lists cannot stall decisions
+	maxExpressionCost = 10000
+	// maxCachedExpressions bounds the compiled expressions kept in memory
+	maxCachedExpressions = 10000
+)
+
+// compiledExpression is a policy expression ready to evaluate
+type compiledExpression struct {
+	program    cel.Program
+	references []PolicyCondition // User and object attributes the expression reads
+}
+
+// expressionCache holds compiled expressions by source, shared by every copy of a policy
+type expressionCache struct {
+	mu       sync.Mutex
+	compiled map[string]*compiledExpression
+}
+
+// policyExpressions caches the expressions of all policies
+var policyExpressions = &expressionCache{compiled: make(map[string]*compiledExpression)}
+
+// expressionEnvironment declares what a policy expression can read: the user, object,
+// environment and request attributes as maps of strings, and the subject, resource and
+// action of the check. The CEL string extensions add split, lowerAscii and the like.
+var expressionEnvironment = sync.OnceValues(func() (*cel.Env, error) {
+	attributes := cel.MapType(cel.StringType, cel.StringType)
+	return cel.NewEnv(
+		cel.Variable("user", attributes),
+		cel.Variable("object", attributes),
+		cel.Variable("env", attributes),
+		cel.Variable("request", attributes),
+		cel.Variable("subject", cel.StringType),
+		cel.Variable("resource", cel.StringType),
+		cel.Variable("action", cel.StringType),
+		ext.Strings(),
+	)
+})
+
+// compile returns the compiled form of an expression, compiling it on first use. Only
+// boolean expressions compile.
+func (c *expressionCache) compile(expression string) (*compiledExpression, error) {
+	c.mu.Lock()
+	compiled, ok := c.compiled[expression]
+	c.mu.Unlock()
+	if ok {
+		return compiled, nil
+	}
+
+	env, err := expressionEnvironment()
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up expressions: %v", err)
+	}
+	checked, issues := env.Compile(expression)
+	if issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression: %v", issues.Err())
+	}
+	if checked.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("expression must be a boolean, not %s", checked.OutputType())
+	}
+	program, err := env.Program(checked, cel.CostLimit(maxExpressionCost))
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %v", err)
+	}
+	compiled = &compiledExpression{program: program, references: expressionReferences(checked.NativeRep())}
+
+	c.mu.Lock()
+	if len(c.compiled) >= maxCachedExpressions {
+		c.compiled = make(map[string]*compiledExpression)
+	}
+	c.compiled[expression] = compiled
+	c.mu.Unlock()
+	return compiled, nil
+}
+
+// expressionReferences lists the user and object attributes an expression reads as
+// user.name or user["name"], as conditions without an operator
+func expressionReferences(checked *celast.AST) []PolicyCondition {
+	var references []PolicyCondition
+	seen := make(map[string]bool)
+	add := func(scope celast.Expr, field string) {
+		if scope.Kind() != celast.IdentKind {
+			return
+		}
+		name := scope.AsIdent()
+		if (name == "user" || name == "object") && !seen[name+"."+field] {
+			seen[name+"."+field] = true
+			references = append(references, PolicyCondition{Type: name, Field: field})
+		}
+	}
+	celast.PreOrderVisit(checked.Expr(), celast.NewExprVisitor(func(e celast.Expr) {
+		switch e.Kind() {
+		case celast.SelectKind:
+			add(e.AsSelect().Operand(), e.AsSelect().FieldName())
+		case celast.CallKind:
+			call := e.AsCall()
+			if call.FunctionName() != operators.Index || len(call.Args()) != 2 || call.Args()[1].Kind() != celast.LiteralKind {
+				return
+			}
+			if field, ok := call.Args()[1].AsLiteral().Value().(string); ok {
+				add(call.Args()[0], field)
+			}
+		}
+	}))
+	return references
+}
+
+// validatePolicyExpression rejects policies combining an expression with conditions or a
+// condition group, and expressions that do not compile to a boolean
+func validatePolicyExpression(policy *ABACPolicy) error {
+	if policy.Expression == "" {
+		return nil
+	}
+	if len(policy.Conditions) > 0 || policy.Group != nil {
+		return fmt.Errorf("a policy has either an expression or conditions, not both")
+	}
+	_, err := policyExpressions.compile(policy.Expression)
+	return err
+}
+
+// evaluateExpression evaluates the expression of a policy. Reading a missing attribute
+// is an error, so the policy does not match; has(user.name) tests for an attribute.
+func (pe *PolicyEngine) evaluateExpression(expression string, ctx *PolicyEvaluationContext) (bool, error) {
+	compiled, err := policyExpressions.compile(expression)
+	if err != nil {
+		return false, err
+	}
+
+	env := make(map[string]string, len(ctx.EnvironmentAttributes)+1)
+	for name, value := range ctx.EnvironmentAttributes {
+		env[name] = value
+	}
+	// Conditions call the hour of the check "time"; expressions can also call it "hour"
+	if _, set := env["hour"]; !set && env["time"] != "" {
+		env["hour"] = env["time"]
+	}
+	activation := map[string]interface{}{
+		"user":     nonNilAttributes(ctx.UserAttributes),
+		"object":   nonNilAttributes(ctx.ObjectAttributes),
+		"env":      env,
+		"request":  nonNilAttributes(ctx.RequestAttributes),
+		"subject":  ctx.Subject,
+		"resource": ctx.Object,
+		"action":   ctx.Action,
+	}
+
+	result, _, err := compiled.program.Eval(activation)
+	if err != nil {
+		serviceMetrics.Inc("abac_expression_errors_total")
+		return false, err
+	}
+	matched, ok := result.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression returned %v, not a boolean", result.Value())
+	}
+	return matched, nil
+}
+
+// nonNilAttributes returns attributes, or an empty map for nil
+func nonNilAttributes(attributes map[string]string) map[string]string {
+	if attributes == nil {
+		return map[string]string{}
+	}
+	return attributes
+}
@@ -0,0 +1,77 @@
+// Multi-Model Authorization Microservice - ABAC Policy Expression Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExpressions_PolicyEvaluation(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/abac/policies", service.addABACPolicyHandler).Methods("POST")
+
+	post := func(body string) int {
+		req, _ := http.NewRequest("POST", "/api/v1/abac/policies", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	code := post(`{
+		"id": "same-department", "name": "Same department", "effect": "allow",
+		"expression": "user.department == object.department && int(env.hour) < 18 || (has(user.clearance) && int(user.clearance) >= 5 && action == 'read')"
+	}`)
+	if code != http.StatusOK {
+		t.Fatalf("Failed to add expression policy: %d", code)
+	}
+
+	service.saveUserAttribute("alice", "department", "engineering")
+	service.saveUserAttribute("carol", "clearance", "7")
+	service.saveObjectAttribute("design", "department", "engineering")
+
+	// Expressions are stored with the policy and survive a reload
+	if err := service.policyEngine.LoadPolicies(); err != nil {
+		t.Fatalf("Failed to reload policies: %v", err)
+	}
+	for _, check := range []struct {
+		user, action, hour string
+		want               bool
+	}{
+		{"alice", "write", "9", true},
+		{"alice", "write", "20", false},
+		{"bob", "read", "9", false}, // No department, so the comparison fails
+		{"carol", "read", "22", true},
+		{"carol", "write", "9", false},
+	} {
+		if allowed, _ := service.Enforce(ModelABAC, check.user, "design", check.action, map[string]string{"hour": check.hour}); allowed != check.want {
+			t.Errorf("%s %s at %s: expected %v", check.user, check.action, check.hour, check.want)
+		}
+	}
+
+	for _, body := range []string{
+		`{"id": "syntax", "name": "Syntax", "effect": "allow", "expression": "user.department =="}`,
+		`{"id": "string", "name": "String", "effect": "allow", "expression": "user.department"}`,
+		`{"id": "unknown", "name": "Unknown", "effect": "allow", "expression": "device.os == 'ios'"}`,
+		`{"id": "mixed", "name": "Mixed", "effect": "allow", "expression": "true",
+		  "conditions": [{"type": "user", "field": "a", "operator": "eq", "value": "1"}]}`,
+	} {
+		if code := post(body); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, code)
+		}
+	}
+}
+
+func TestExpressions_ReferencedAttributes(t *testing.T) {
+	policy := ABACPolicy{Expression: `user.department == object["department"] && request["device.os"] == "ios" && user.department != ""`}
+	conditions := policy.allConditions()
+	if len(conditions) != 2 || conditions[0] != (PolicyCondition{Type: "user", Field: "department"}) || conditions[1] != (PolicyCondition{Type: "object", Field: "department"}) {
+		t.Errorf("Unexpected references: %+v", conditions)
+	}
+}
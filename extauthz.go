@@ -0,0 +1,493 @@
+// Multi-Model Authorization Microservice - Envoy External Authorization
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Envoy's ext_authz filter asks an external service whether to let each request through,
+// either over gRPC (envoy.service.auth.v3.Authorization/Check) or by forwarding the request
+// to an HTTP endpoint. Both protocols are served here: rules map the method and path of the
+// forwarded request to the object and action of an authorization check.
+
+// extAuthzPlaceholder matches the {name} and {name...} placeholders of rule paths and templates
+var extAuthzPlaceholder = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)(\.\.\.)?\}`)
+
+// extAuthzPathPrefix is where Envoy's HTTP ext_authz service forwards requests to
+const extAuthzPathPrefix = "/api/v1/ext_authz/"
+
+// extAuthzMethodActions derive the action of a rule that does not name one
+var extAuthzMethodActions = map[string]string{
+	"GET": "read", "HEAD": "read", "OPTIONS": "read",
+	"POST": "create", "PUT": "update", "PATCH": "update", "DELETE": "delete",
+}
+
+// ExtAuthzRule maps requests to an authorization check. Path segments written as {name}
+// match any one segment, and a final {name...} matches the rest of the path. The captured
+// values, and {method}, can be used in the object and action templates.
+type ExtAuthzRule struct {
+	Methods []string           `json:"methods,omitempty"` // Empty matches every method
+	Path    string             `json:"path"`
+	Public  bool               `json:"public,omitempty"` // Let matching requests through without a check
+	Model   AccessControlModel `json:"model,omitempty"`
+	Object  string             `json:"object,omitempty"` // The request path when empty
+	Action  string             `json:"action,omitempty"` // Derived from the method when empty
+}
+
+// ExtAuthzConfig configures how forwarded requests are authorized. The first matching rule
+// decides; requests no rule matches are denied unless allow_unmatched is set.
+type ExtAuthzConfig struct {
+	Rules          []ExtAuthzRule `json:"rules"`
+	SubjectHeader  string         `json:"subject_header,omitempty"` // Header naming the subject when no end-user JWT is verified
+	Tenant         string         `json:"tenant,omitempty"`
+	AllowUnmatched bool           `json:"allow_unmatched,omitempty"`
+}
+
+// extAuthzCheck is a request forwarded by Envoy
+type extAuthzCheck struct {
+	method  string
+	path    string
+	host    string
+	headers map[string]string // Lowercase header names
+}
+
+// extAuthzDecision is the answer to Envoy: the status and body sent to the client on
+// denial, or the headers added to the upstream request when allowed
+type extAuthzDecision struct {
+	allowed bool
+	status  int
+	headers map[string]string
+	body    string
+}
+
+// validate checks the rules and their templates
+func (c *ExtAuthzConfig) validate() error {
+	if len(c.Rules) == 0 {
+		return fmt.Errorf("no rules configured")
+	}
+	for i, rule := range c.Rules {
+		if !strings.HasPrefix(rule.Path, "/") {
+			return fmt.Errorf("rule %d: path must start with /", i)
+		}
+		switch rule.Model {
+		case "", ModelACL, ModelRBAC, ModelABAC, ModelReBAC:
+		default:
+			return fmt.Errorf("rule %d: invalid model %s", i, rule.Model)
+		}
+
+		captured := map[string]bool{"method": true}
+		segments := strings.Split(rule.Path, "/")
+		for j, segment := range segments {
+			if !strings.Contains(segment, "{") {
+				continue
+			}
+			match := extAuthzPlaceholder.FindStringSubmatch(segment)
+			if match == nil || match[0] != segment {
+				return fmt.Errorf("rule %d: placeholder %s must span a whole path segment", i, segment)
+			}
+			if match[2] != "" && j != len(segments)-1 {
+				return fmt.Errorf("rule %d: %s must be the last path segment", i, segment)
+			}
+			captured[match[1]] = true
+		}
+		for _, template := range []string{rule.Object, rule.Action} {
+			for _, match := range extAuthzPlaceholder.FindAllStringSubmatch(template, -1) {
+				if !captured[match[1]] {
+					return fmt.Errorf("rule %d: %s is not captured by the path", i, match[0])
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// match reports whether a rule matches a request and returns the captured path values
+func (rule *ExtAuthzRule) match(method, path string) (map[string]string, bool) {
+	if len(rule.Methods) > 0 {
+		found := false
+		for _, allowed := range rule.Methods {
+			found = found || strings.EqualFold(allowed, method)
+		}
+		if !found {
+			return nil, false
+		}
+	}
+
+	values := map[string]string{"method": strings.ToLower(method)}
+	patternSegments := strings.Split(rule.Path, "/")
+	pathSegments := strings.Split(path, "/")
+	for i, pattern := range patternSegments {
+		match := extAuthzPlaceholder.FindStringSubmatch(pattern)
+		if match != nil && match[2] != "" {
+			values[match[1]] = strings.Join(pathSegments[min(i, len(pathSegments)):], "/")
+			return values, true
+		}
+		if i >= len(pathSegments) {
+			return nil, false
+		}
+		if match == nil {
+			if pattern != pathSegments[i] {
+				return nil, false
+			}
+		} else if pathSegments[i] == "" {
+			return nil, false
+		} else {
+			values[match[1]] = pathSegments[i]
+		}
+	}
+	return values, len(patternSegments) == len(pathSegments)
+}
+
+// expandExtAuthzTemplate fills the placeholders of a template with captured values
+func expandExtAuthzTemplate(template string, values map[string]string) string {
+	return extAuthzPlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		return values[extAuthzPlaceholder.FindStringSubmatch(placeholder)[1]]
+	})
+}
+
+// extAuthzConfigFromEnv reads the rules named by EXT_AUTHZ_CONFIG_FILE. It returns nil when
+// none are configured.
+func extAuthzConfigFromEnv() (*ExtAuthzConfig, error) {
+	path := os.Getenv("EXT_AUTHZ_CONFIG_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read EXT_AUTHZ_CONFIG_FILE: %v", err)
+	}
+	var config ExtAuthzConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid EXT_AUTHZ_CONFIG_FILE: %v", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid EXT_AUTHZ_CONFIG_FILE: %v", err)
+	}
+	if config.SubjectHeader == "" {
+		config.SubjectHeader = "x-user-id"
+	}
+	config.SubjectHeader = strings.ToLower(config.SubjectHeader)
+	return &config, nil
+}
+
+// deniedExtAuthz is a denial returned to the client
+func deniedExtAuthz(status int, message string) extAuthzDecision {
+	body, _ := json.Marshal(map[string]interface{}{"allowed": false, "message": message})
+	return extAuthzDecision{status: status, headers: map[string]string{"content-type": "application/json"}, body: string(body)}
+}
+
+// CheckExtAuthz decides a request forwarded by Envoy. The subject is read from a verified
+// end-user JWT when token authorization is configured, and from the subject header
+// otherwise. The captured path values, the method, path and host, and the token's claims
+// are the request attributes of the check.
+func (s *AuthService) CheckExtAuthz(ctx context.Context, check extAuthzCheck) (extAuthzDecision, error) {
+	config := s.extAuthz
+	path, _, _ := strings.Cut(check.path, "?")
+
+	var rule *ExtAuthzRule
+	var values map[string]string
+	for i := range config.Rules {
+		if captured, ok := config.Rules[i].match(check.method, path); ok {
+			rule, values = &config.Rules[i], captured
+			break
+		}
+	}
+	switch {
+	case rule == nil && config.AllowUnmatched:
+		return extAuthzDecision{allowed: true, status: http.StatusOK}, nil
+	case rule == nil:
+		serviceMetrics.Inc("ext_authz_denied_total")
+		return deniedExtAuthz(http.StatusForbidden, "No authorization rule matches the request"), nil
+	case rule.Public:
+		return extAuthzDecision{allowed: true, status: http.StatusOK}, nil
+	}
+
+	token := &verifiedToken{subject: check.headers[config.SubjectHeader]}
+	if bearer := check.headers["authorization"]; s.tokenAuthorization != nil && strings.HasPrefix(bearer, "Bearer ") {
+		verified, err := s.tokenAuthorization.verify(strings.TrimSpace(strings.TrimPrefix(bearer, "Bearer ")), time.Now())
+		if err != nil {
+			serviceMetrics.Inc("token_authorization_rejections_total")
+			return deniedExtAuthz(http.StatusUnauthorized, fmt.Sprintf("Invalid token: %v", err)), nil
+		}
+		token = verified
+	}
+	if token.subject == "" {
+		return deniedExtAuthz(http.StatusUnauthorized, "The request does not name a subject"), nil
+	}
+
+	scope, err := s.tenantScope(config.Tenant)
+	if err != nil {
+		return extAuthzDecision{}, err
+	}
+	object := path
+	if rule.Object != "" {
+		object = expandExtAuthzTemplate(rule.Object, values)
+	}
+	action := extAuthzMethodActions[strings.ToUpper(check.method)]
+	if rule.Action != "" {
+		action = expandExtAuthzTemplate(rule.Action, values)
+	} else if action == "" {
+		action = strings.ToLower(check.method)
+	}
+	model := rule.Model
+	if model == "" {
+		model = ModelRBAC
+	}
+
+	attributes := map[string]string{"method": strings.ToUpper(check.method), "path": path, "host": check.host}
+	for name, value := range values {
+		if name != "method" {
+			attributes[name] = value
+		}
+	}
+	for name, value := range token.attributes {
+		attributes[name] = value
+	}
+
+	allowed, grantedBy, err := s.EnforceToken(scope, model, token, object, action, attributes)
+	if err != nil {
+		return extAuthzDecision{}, err
+	}
+	subject, qualifiedObject := scope.qualify(token.subject), scope.qualify(object)
+	s.recordDecision(requestIDFromContext(ctx), model, subject, qualifiedObject, action, attributes, allowed)
+	s.traceDecision(requestIDFromContext(ctx), model, subject, qualifiedObject, action, attributes, allowed)
+
+	if !allowed {
+		serviceMetrics.Inc("ext_authz_denied_total")
+		return deniedExtAuthz(http.StatusForbidden, "Access denied"), nil
+	}
+	serviceMetrics.Inc("ext_authz_allowed_total")
+	decision := extAuthzDecision{allowed: true, status: http.StatusOK, headers: map[string]string{"x-authz-subject": token.subject}}
+	if grantedBy != token.subject {
+		decision.headers["x-authz-granted-by"] = grantedBy
+	}
+	return decision, nil
+}
+
+// extAuthzHandler serves Envoy's HTTP ext_authz protocol: Envoy forwards the method, path
+// and headers of each request below /api/v1/ext_authz and lets the request through on 200.
+// The x-authz-* headers of an allowed response can be passed upstream with
+// allowed_upstream_headers.
+func (s *AuthService) extAuthzHandler(w http.ResponseWriter, r *http.Request) {
+	check := extAuthzCheck{method: r.Method, path: mux.Vars(r)["path"], host: r.Host, headers: make(map[string]string, len(r.Header))}
+	for name, values := range r.Header {
+		check.headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+
+	decision, err := s.CheckExtAuthz(r.Context(), check)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Authorization error: %v", err))
+		return
+	}
+	for name, value := range decision.headers {
+		w.Header().Set(name, value)
+	}
+	w.WriteHeader(decision.status)
+	w.Write([]byte(decision.body))
+}
+
+// extAuthzProto describes the parts of Envoy's external authorization API the service
+// reads and writes. Field numbers follow envoy/service/auth/v3/external_auth.proto and
+// attribute_context.proto; everything else a CheckRequest carries is skipped when decoding.
+const extAuthzProto = `
+name: "envoy/service/auth/v3/external_auth.proto"
+package: "envoy.service.auth.v3"
+syntax: "proto3"
+message_type {
+  name: "CheckRequest"
+  field { name: "attributes" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".envoy.service.auth.v3.AttributeContext" }
+}
+message_type {
+  name: "AttributeContext"
+  field { name: "request" number: 4 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".envoy.service.auth.v3.AttributeContext.Request" }
+  nested_type {
+    name: "Request"
+    field { name: "http" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".envoy.service.auth.v3.AttributeContext.HttpRequest" }
+  }
+  nested_type {
+    name: "HttpRequest"
+    field { name: "method" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING }
+    field { name: "headers" number: 3 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".envoy.service.auth.v3.AttributeContext.HttpRequest.HeadersEntry" }
+    field { name: "path" number: 4 label: LABEL_OPTIONAL type: TYPE_STRING }
+    field { name: "host" number: 5 label: LABEL_OPTIONAL type: TYPE_STRING }
+    nested_type {
+      name: "HeadersEntry"
+      field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING }
+      field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING }
+      options { map_entry: true }
+    }
+  }
+}
+message_type {
+  name: "CheckResponse"
+  field { name: "status" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".envoy.service.auth.v3.Status" }
+  field { name: "denied_response" number: 2 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".envoy.service.auth.v3.DeniedHttpResponse" oneof_index: 0 }
+  field { name: "ok_response" number: 3 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".envoy.service.auth.v3.OkHttpResponse" oneof_index: 0 }
+  oneof_decl { name: "http_response" }
+}
+message_type {
+  name: "Status"
+  field { name: "code" number: 1 label: LABEL_OPTIONAL type: TYPE_INT32 }
+  field { name: "message" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING }
+}
+message_type {
+  name: "DeniedHttpResponse"
+  field { name: "status" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".envoy.service.auth.v3.HttpStatus" }
+  field { name: "headers" number: 2 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".envoy.service.auth.v3.HeaderValueOption" }
+  field { name: "body" number: 3 label: LABEL_OPTIONAL type: TYPE_STRING }
+}
+message_type {
+  name: "OkHttpResponse"
+  field { name: "headers" number: 2 label: LABEL_REPEATED type: TYPE_MESSAGE type_name: ".envoy.service.auth.v3.HeaderValueOption" }
+}
+message_type {
+  name: "HttpStatus"
+  field { name: "code" number: 1 label: LABEL_OPTIONAL type: TYPE_INT32 }
+}
+message_type {
+  name: "HeaderValueOption"
+  field { name: "header" number: 1 label: LABEL_OPTIONAL type: TYPE_MESSAGE type_name: ".envoy.service.auth.v3.HeaderValue" }
+}
+message_type {
+  name: "HeaderValue"
+  field { name: "key" number: 1 label: LABEL_OPTIONAL type: TYPE_STRING }
+  field { name: "value" number: 2 label: LABEL_OPTIONAL type: TYPE_STRING }
+}
+service {
+  name: "Authorization"
+  method { name: "Check" input_type: ".envoy.service.auth.v3.CheckRequest" output_type: ".envoy.service.auth.v3.CheckResponse" }
+}
+`
+
+// extAuthzDescriptor is the file described by extAuthzProto. Its messages are used through
+// dynamicpb, since the service does not depend on Envoy's generated bindings.
+var extAuthzDescriptor = func() protoreflect.FileDescriptor {
+	var file descriptorpb.FileDescriptorProto
+	if err := prototext.Unmarshal([]byte(extAuthzProto), &file); err != nil {
+		panic(fmt.Sprintf("invalid ext_authz descriptor: %v", err))
+	}
+	descriptor, err := protodesc.NewFile(&file, new(protoregistry.Files))
+	if err != nil {
+		panic(fmt.Sprintf("invalid ext_authz descriptor: %v", err))
+	}
+	return descriptor
+}()
+
+// extAuthzCheckMethod is the full name of Envoy's Check RPC
+const extAuthzCheckMethod = "/envoy.service.auth.v3.Authorization/Check"
+
+// extAuthzGRPC implements envoy.service.auth.v3.Authorization
+type extAuthzGRPC struct {
+	s *AuthService
+}
+
+// extAuthzServer is the handler type of the Authorization service
+type extAuthzServer interface {
+	Check(ctx context.Context, req *dynamicpb.Message) (*dynamicpb.Message, error)
+}
+
+// extAuthzServiceDesc registers the Authorization service like generated bindings would
+var extAuthzServiceDesc = grpc.ServiceDesc{
+	ServiceName: "envoy.service.auth.v3.Authorization",
+	HandlerType: (*extAuthzServer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Check",
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := dynamicpb.NewMessage(extAuthzDescriptor.Messages().ByName("CheckRequest"))
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if interceptor == nil {
+				return srv.(extAuthzServer).Check(ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: extAuthzCheckMethod}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.(extAuthzServer).Check(ctx, req.(*dynamicpb.Message))
+			}
+			return interceptor(ctx, in, info, handler)
+		},
+	}},
+	Metadata: "envoy/service/auth/v3/external_auth.proto",
+}
+
+// protoField returns the value of a field of a dynamic message
+func protoField(message protoreflect.Message, name protoreflect.Name) protoreflect.Value {
+	return message.Get(message.Descriptor().Fields().ByName(name))
+}
+
+// Check decides a request described by a CheckRequest
+func (g *extAuthzGRPC) Check(ctx context.Context, req *dynamicpb.Message) (*dynamicpb.Message, error) {
+	httpRequest := protoField(protoField(protoField(req, "attributes").Message(), "request").Message(), "http").Message()
+	check := extAuthzCheck{
+		method:  protoField(httpRequest, "method").String(),
+		path:    protoField(httpRequest, "path").String(),
+		host:    protoField(httpRequest, "host").String(),
+		headers: make(map[string]string),
+	}
+	protoField(httpRequest, "headers").Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+		check.headers[strings.ToLower(key.String())] = value.String()
+		return true
+	})
+
+	decision, err := g.s.CheckExtAuthz(ctx, check)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "authorization check error: %v", err)
+	}
+	return extAuthzResponse(decision), nil
+}
+
+// extAuthzResponse encodes a decision as a CheckResponse
+func extAuthzResponse(decision extAuthzDecision) *dynamicpb.Message {
+	messages := extAuthzDescriptor.Messages()
+	response := dynamicpb.NewMessage(messages.ByName("CheckResponse"))
+	rpcStatus := response.Mutable(response.Descriptor().Fields().ByName("status")).Message()
+
+	var httpResponse protoreflect.Message
+	if decision.allowed {
+		httpResponse = response.Mutable(response.Descriptor().Fields().ByName("ok_response")).Message()
+	} else {
+		rpcStatus.Set(rpcStatus.Descriptor().Fields().ByName("code"), protoreflect.ValueOfInt32(int32(codes.PermissionDenied)))
+		rpcStatus.Set(rpcStatus.Descriptor().Fields().ByName("message"), protoreflect.ValueOfString(http.StatusText(decision.status)))
+		httpResponse = response.Mutable(response.Descriptor().Fields().ByName("denied_response")).Message()
+		httpStatus := httpResponse.Mutable(httpResponse.Descriptor().Fields().ByName("status")).Message()
+		httpStatus.Set(httpStatus.Descriptor().Fields().ByName("code"), protoreflect.ValueOfInt32(int32(decision.status)))
+		httpResponse.Set(httpResponse.Descriptor().Fields().ByName("body"), protoreflect.ValueOfString(decision.body))
+	}
+
+	names := make([]string, 0, len(decision.headers))
+	for name := range decision.headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headers := httpResponse.Mutable(httpResponse.Descriptor().Fields().ByName("headers")).List()
+	for _, name := range names {
+		option := dynamicpb.NewMessage(messages.ByName("HeaderValueOption"))
+		header := option.Mutable(option.Descriptor().Fields().ByName("header")).Message()
+		header.Set(header.Descriptor().Fields().ByName("key"), protoreflect.ValueOfString(name))
+		header.Set(header.Descriptor().Fields().ByName("value"), protoreflect.ValueOfString(decision.headers[name]))
+		headers.Append(protoreflect.ValueOfMessage(option))
+	}
+	return response
+}
@@ -0,0 +1,136 @@
+// Multi-Model Authorization Microservice - Envoy External Authorization Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// setupTestExtAuthz configures rules for a document API
+func setupTestExtAuthz(t *testing.T) *AuthService {
+	service := setupTestService(t)
+	service.extAuthz = &ExtAuthzConfig{
+		SubjectHeader: "x-user-id",
+		Rules: []ExtAuthzRule{
+			{Path: "/healthz", Public: true},
+			{Methods: []string{"GET", "DELETE"}, Path: "/documents/{id}", Object: "doc:{id}"},
+			{Methods: []string{"POST"}, Path: "/documents/{id}/{verb}", Object: "doc:{id}", Action: "{verb}"},
+			{Path: "/files/{rest...}", Object: "files", Action: "{method}"},
+		},
+	}
+	if err := service.extAuthz.validate(); err != nil {
+		t.Fatalf("Invalid rules: %v", err)
+	}
+	service.rbacEnforcer.AddPolicy("alice", "doc:1", "read", "allow")
+	service.rbacEnforcer.AddPolicy("alice", "doc:1", "share", "allow")
+	service.rbacEnforcer.AddPolicy("alice", "files", "put", "allow")
+	return service
+}
+
+func TestExtAuthz_HTTP(t *testing.T) {
+	service := setupTestExtAuthz(t)
+	router := mux.NewRouter()
+	service.registerEnforcementRoutes(router.PathPrefix("/api/v1").Subrouter())
+
+	check := func(method, path, subject string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/api/v1/ext_authz"+path, nil)
+		if subject != "" {
+			req.Header.Set("X-User-Id", subject)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := check("GET", "/documents/1?version=2", "alice"); rr.Code != http.StatusOK || rr.Header().Get("x-authz-subject") != "alice" {
+		t.Errorf("Expected alice to read doc:1 with the subject header injected, got %d %v", rr.Code, rr.Header())
+	}
+	if rr := check("DELETE", "/documents/1", "alice"); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected alice not to delete doc:1, got %d", rr.Code)
+	}
+	if rr := check("POST", "/documents/1/share", "alice"); rr.Code != http.StatusOK {
+		t.Errorf("Expected the action to be taken from the path, got %d", rr.Code)
+	}
+	if rr := check("PUT", "/files/a/b.txt", "alice"); rr.Code != http.StatusOK {
+		t.Errorf("Expected the rest of the path to match, got %d", rr.Code)
+	}
+	if rr := check("GET", "/documents/1", ""); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a request without a subject to be rejected, got %d", rr.Code)
+	}
+	if rr := check("GET", "/healthz", ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected a public path to be let through, got %d", rr.Code)
+	}
+	if rr := check("GET", "/admin", "alice"); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected an unmatched path to be denied, got %d", rr.Code)
+	}
+	service.extAuthz.AllowUnmatched = true
+	if rr := check("GET", "/admin", "alice"); rr.Code != http.StatusOK {
+		t.Errorf("Expected an unmatched path to be allowed with allow_unmatched, got %d", rr.Code)
+	}
+}
+
+func TestExtAuthz_ValidatesRules(t *testing.T) {
+	for name, rule := range map[string]ExtAuthzRule{
+		"relative path":       {Path: "documents"},
+		"partial placeholder": {Path: "/documents/doc-{id}"},
+		"rest not last":       {Path: "/files/{rest...}/raw"},
+		"unknown placeholder": {Path: "/documents/{id}", Object: "doc:{name}"},
+		"invalid model":       {Path: "/documents", Model: "mac"},
+	} {
+		config := ExtAuthzConfig{Rules: []ExtAuthzRule{rule}}
+		if err := config.validate(); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestExtAuthz_GRPC(t *testing.T) {
+	service := setupTestExtAuthz(t)
+	conn := setupTestGRPC(t, service)
+
+	check := func(method, path string, headers map[string]string) protoreflect.Message {
+		request := dynamicpb.NewMessage(extAuthzDescriptor.Messages().ByName("CheckRequest"))
+		attributes := request.Mutable(request.Descriptor().Fields().ByName("attributes")).Message()
+		requestContext := attributes.Mutable(attributes.Descriptor().Fields().ByName("request")).Message()
+		httpRequest := requestContext.Mutable(requestContext.Descriptor().Fields().ByName("http")).Message()
+		httpRequest.Set(httpRequest.Descriptor().Fields().ByName("method"), protoreflect.ValueOfString(method))
+		httpRequest.Set(httpRequest.Descriptor().Fields().ByName("path"), protoreflect.ValueOfString(path))
+		headerMap := httpRequest.Mutable(httpRequest.Descriptor().Fields().ByName("headers")).Map()
+		for name, value := range headers {
+			headerMap.Set(protoreflect.ValueOfString(name).MapKey(), protoreflect.ValueOfString(value))
+		}
+
+		response := dynamicpb.NewMessage(extAuthzDescriptor.Messages().ByName("CheckResponse"))
+		if err := conn.Invoke(context.Background(), extAuthzCheckMethod, request, response); err != nil {
+			t.Fatalf("Check failed: %v", err)
+		}
+		return response
+	}
+
+	allowed := check("GET", "/documents/1", map[string]string{"x-user-id": "alice"})
+	if code := protoField(protoField(allowed, "status").Message(), "code").Int(); code != 0 {
+		t.Errorf("Expected an OK status, got %d", code)
+	}
+	headers := protoField(protoField(allowed, "ok_response").Message(), "headers").List()
+	if headers.Len() != 1 || protoField(protoField(headers.Get(0).Message(), "header").Message(), "value").String() != "alice" {
+		t.Errorf("Expected the subject header to be injected, got %v", allowed)
+	}
+
+	denied := check("DELETE", "/documents/1", map[string]string{"x-user-id": "alice"})
+	deniedResponse := protoField(denied, "denied_response").Message()
+	if code := protoField(protoField(denied, "status").Message(), "code").Int(); code != 7 {
+		t.Errorf("Expected PERMISSION_DENIED, got %d", code)
+	}
+	if code := protoField(protoField(deniedResponse, "status").Message(), "code").Int(); code != http.StatusForbidden {
+		t.Errorf("Expected a 403 for the client, got %d", code)
+	}
+}
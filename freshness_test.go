@@ -0,0 +1,60 @@
+// Multi-Model Authorization Microservice - Read Freshness Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFreshness_StrongBypassesCaches(t *testing.T) {
+	service := setupTestService(t)
+
+	// Another instance writes a tuple and revokes an attribute directly in the database
+	service.relationshipGraph.CheckReBACAccess("alice", "account1", "write")
+	service.db.Create(&RelationshipRecord{Subject: "alice", Relationship: "owner", Object: "account1"})
+
+	if allowed, _ := service.Enforce(ModelReBAC, "alice", "account1", "write", nil); allowed {
+		t.Error("Expected default freshness to use the in-memory graph")
+	}
+	if allowed, _ := service.EnforceWithFreshness(ModelReBAC, "alice", "account1", "write", nil, freshnessStrong); !allowed {
+		t.Error("Expected strong freshness to read the tuple from the database")
+	}
+
+	service.policyEngine.AddPolicy(&ABACPolicy{
+		ID:     "transfer",
+		Name:   "Unfrozen accounts allow transfers",
+		Effect: "allow",
+		Conditions: []PolicyCondition{
+			{Type: "object", Field: "frozen", Operator: "eq", Value: "false"},
+		},
+	})
+	service.saveObjectAttribute("account1", "frozen", "false")
+	service.Enforce(ModelABAC, "alice", "account1", "transfer", nil) // Warms the attribute cache
+	service.db.Model(&ObjectAttribute{}).Where("object_id = ?", "account1").Update("value", "true")
+
+	if allowed, _ := service.Enforce(ModelABAC, "alice", "account1", "transfer", nil); !allowed {
+		t.Error("Expected default freshness to use cached object attributes")
+	}
+	if allowed, _ := service.EnforceWithFreshness(ModelABAC, "alice", "account1", "transfer", nil, freshnessStrong); allowed {
+		t.Error("Expected strong freshness to see the frozen account")
+	}
+}
+
+func TestFreshness_InvalidLevelRejected(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+
+	body, _ := json.Marshal(EnforceRequest{Model: ModelACL, Subject: "alice", Object: "doc", Action: "read", Freshness: "eventual"})
+	req, _ := http.NewRequest("POST", "/api/v1/authorizations", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,246 @@
+// Multi-Model Authorization Microservice - Relationship Graph Export
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Formats the relationship graph can be exported in
+const (
+	graphFormatDOT     = "dot"     // Graphviz
+	graphFormatGraphML = "graphml" // Gephi, yEd and other graph tools
+)
+
+// graphEdge is a relationship tuple drawn as an edge from its subject to its object
+type graphEdge struct {
+	Subject      string
+	Relationship string
+	Object       string
+	ExpiresAt    *time.Time // When the tuple lapses, if it does
+	Conditional  bool       // Whether the tuple carries a caveat
+}
+
+// nodeType returns the type of a graph node: the namespace of "type:id" objects, "userset"
+// for subjects such as "group:eng#member", and "" for plain names
+func nodeType(node string) string {
+	if strings.Contains(node, "#") {
+		return "userset"
+	}
+	if i := strings.Index(node, ":"); i > 0 {
+		return node[:i]
+	}
+	return ""
+}
+
+// graphNodes returns the sorted subjects and objects of the edges
+func graphNodes(edges []graphEdge) []string {
+	nodes := make(map[string]bool)
+	for _, edge := range edges {
+		nodes[edge.Subject] = true
+		nodes[edge.Object] = true
+	}
+	return sortedSet(nodes)
+}
+
+// dotQuote quotes a Graphviz ID
+func dotQuote(id string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(id) + `"`
+}
+
+// renderDOT writes the edges as a Graphviz digraph. Usersets are drawn as boxes, and
+// conditional and expiring tuples as dashed edges.
+func renderDOT(edges []graphEdge) string {
+	var b strings.Builder
+	b.WriteString("digraph relationships {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=ellipse];\n")
+	for _, node := range graphNodes(edges) {
+		if nodeType(node) == "userset" {
+			fmt.Fprintf(&b, "  %s [shape=box];\n", dotQuote(node))
+		} else {
+			fmt.Fprintf(&b, "  %s;\n", dotQuote(node))
+		}
+	}
+	for _, edge := range edges {
+		attributes := []string{"label=" + dotQuote(edge.Relationship)}
+		if edge.Conditional || edge.ExpiresAt != nil {
+			attributes = append(attributes, "style=dashed")
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(edge.Subject), dotQuote(edge.Object), strings.Join(attributes, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// GraphML documents; see http://graphml.graphdrawing.org
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID      string `xml:"id,attr"`
+	For     string `xml:"for,attr"`
+	Name    string `xml:"attr.name,attr"`
+	Type    string `xml:"attr.type,attr"`
+	Default string `xml:"default,omitempty"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// renderGraphML writes the edges as a GraphML document. Nodes carry their label and type,
+// edges their relationship, whether they are conditional and when they expire.
+func renderGraphML(edges []graphEdge) ([]byte, error) {
+	doc := graphMLDocument{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "type", For: "node", Name: "type", Type: "string"},
+			{ID: "relationship", For: "edge", Name: "relationship", Type: "string"},
+			{ID: "conditional", For: "edge", Name: "conditional", Type: "boolean", Default: "false"},
+			{ID: "expires_at", For: "edge", Name: "expires_at", Type: "string"},
+		},
+		Graph: graphMLGraph{ID: "relationships", EdgeDefault: "directed"},
+	}
+
+	for _, node := range graphNodes(edges) {
+		data := []graphMLData{{Key: "label", Value: node}}
+		if kind := nodeType(node); kind != "" {
+			data = append(data, graphMLData{Key: "type", Value: kind})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: node, Data: data})
+	}
+	for i, edge := range edges {
+		data := []graphMLData{{Key: "relationship", Value: edge.Relationship}}
+		if edge.Conditional {
+			data = append(data, graphMLData{Key: "conditional", Value: "true"})
+		}
+		if edge.ExpiresAt != nil {
+			data = append(data, graphMLData{Key: "expires_at", Value: edge.ExpiresAt.UTC().Format(time.RFC3339)})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: edge.Subject,
+			Target: edge.Object,
+			Data:   data,
+		})
+	}
+
+	output, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(output, '\n')...), nil
+}
+
+// exportRelationshipsHandler dumps the relationship graph, or the tuples whose subject and
+// object start with the given prefixes, for visualization tools
+func (s *AuthService) exportRelationshipsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = graphFormatDOT
+	}
+	if format != graphFormatDOT && format != graphFormatGraphML {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("format must be %s or %s", graphFormatDOT, graphFormatGraphML))
+		return
+	}
+	subjectPrefix, objectPrefix := query.Get("subject_prefix"), query.Get("object_prefix")
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	relationships, err := s.relationshipGraph.ListRelationships("")
+	if err == nil {
+		relationships, err = s.filterRelationshipsByLabel(relationships, query.Get("label"))
+	}
+	var expirations map[string]time.Time
+	if err == nil {
+		expirations, err = s.relationshipGraph.RelationshipExpirations("")
+	}
+	var caveats map[string]*RelationshipCaveat
+	if err == nil {
+		caveats, err = s.relationshipGraph.RelationshipCaveats("")
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve relationships: %v", err))
+		return
+	}
+
+	var edges []graphEdge
+	for _, rel := range relationships {
+		if !scope.ownsRelationship(rel) {
+			continue
+		}
+		local := scope.localRelationship(rel)
+		if !strings.HasPrefix(local.Subject, subjectPrefix) || !strings.HasPrefix(local.Object, objectPrefix) {
+			continue
+		}
+		edge := graphEdge{Subject: local.Subject, Relationship: local.Relationship, Object: local.Object}
+		key := labelKey(rel.Subject, rel.Relationship, rel.Object)
+		if expiresAt, ok := expirations[key]; ok {
+			edge.ExpiresAt = &expiresAt
+		}
+		_, edge.Conditional = caveats[key]
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Subject != edges[j].Subject {
+			return edges[i].Subject < edges[j].Subject
+		}
+		if edges[i].Relationship != edges[j].Relationship {
+			return edges[i].Relationship < edges[j].Relationship
+		}
+		return edges[i].Object < edges[j].Object
+	})
+
+	if format == graphFormatGraphML {
+		output, err := renderGraphML(edges)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to render GraphML: %v", err))
+			return
+		}
+		w.Header().Set("Content-Type", "application/graphml+xml; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="relationships.graphml"`)
+		w.Write(output)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="relationships.dot"`)
+	fmt.Fprint(w, renderDOT(edges))
+}
@@ -0,0 +1,68 @@
+// Multi-Model Authorization Microservice - Relationship Graph Export Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGraphExport_Formats(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/relationships/export", service.exportRelationshipsHandler).Methods("GET")
+
+	rg := service.relationshipGraph
+	rg.AddRelationship("alice", "owner", "document:plan")
+	rg.AddRelationship("group:eng#member", "viewer", "document:plan")
+	rg.AddRelationship("bob", "member", "group:eng")
+	rg.AddRelationship(`say "hi"`, "viewer", "folder:shared")
+	expiresAt := time.Now().Add(time.Hour)
+	rg.AddConditionalRelationship("carol", "viewer", "document:plan", &expiresAt, nil)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/v1/relationships/export"+query, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("")
+	dot := rr.Body.String()
+	if rr.Code != http.StatusOK || !strings.HasPrefix(rr.Header().Get("Content-Type"), "text/vnd.graphviz") || !strings.HasPrefix(dot, "digraph relationships {") {
+		t.Fatalf("Expected a DOT graph, got %d %s", rr.Code, dot)
+	}
+	for _, line := range []string{
+		`"alice" -> "document:plan" [label="owner"];`,
+		`"group:eng#member" [shape=box];`,
+		`"carol" -> "document:plan" [label="viewer", style=dashed];`,
+		`"say \"hi\"" -> "folder:shared" [label="viewer"];`,
+	} {
+		if !strings.Contains(dot, line) {
+			t.Errorf("Expected %s in the DOT graph:\n%s", line, dot)
+		}
+	}
+
+	// Prefixes limit the exported tuples
+	rr = get("?format=graphml&object_prefix=document:&subject_prefix=group:")
+	var doc graphMLDocument
+	if err := xml.Unmarshal(rr.Body.Bytes(), &doc); err != nil || rr.Code != http.StatusOK {
+		t.Fatalf("Expected a GraphML document, got %d %v %s", rr.Code, err, rr.Body.String())
+	}
+	if len(doc.Graph.Edges) != 1 || doc.Graph.Edges[0].Source != "group:eng#member" || doc.Graph.Edges[0].Target != "document:plan" {
+		t.Errorf("Expected only the userset tuple, got %+v", doc.Graph.Edges)
+	}
+	if len(doc.Graph.Nodes) != 2 || doc.Graph.Nodes[1].Data[1] != (graphMLData{Key: "type", Value: "userset"}) {
+		t.Errorf("Expected typed nodes, got %+v", doc.Graph.Nodes)
+	}
+
+	if rr := get("?format=png"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown format to be rejected, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,41 @@
+// Multi-Model Authorization Microservice - ReBAC Graph Locking
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import "time"
+
+// The in-memory tuples and their indexes are guarded by the graph's RWMutex. Exported
+// methods take the lock and unexported ones expect their caller to hold it, so exported
+// methods never call each other.
+
+// readLock takes the graph for reading and returns the function releasing it. Tuples
+// that expired are dropped first under the write lock. Reads of a partitioned graph load
+// partitions as they go, so they hold the write lock throughout.
+func (rg *RelationshipGraph) readLock() (unlock func()) {
+	if rg.partitions != nil {
+		return rg.writeLock()
+	}
+
+	rg.mu.RLock()
+	if rg.caveatContext == nil && rg.expiryDue(time.Now()) {
+		rg.mu.RUnlock()
+		rg.mu.Lock()
+		rg.dropExpired(time.Now())
+		rg.mu.Unlock()
+		rg.mu.RLock()
+	}
+	return rg.mu.RUnlock
+}
+
+// writeLock takes the graph for writing and returns the function releasing it. Tuples
+// that expired are dropped first.
+func (rg *RelationshipGraph) writeLock() (unlock func()) {
+	rg.mu.Lock()
+	// Request views share the base graph's tuples but not its revision; the base drops them
+	if rg.caveatContext == nil {
+		rg.dropExpired(time.Now())
+	}
+	return rg.mu.Unlock
+}
@@ -0,0 +1,67 @@
+// Multi-Model Authorization Microservice - ReBAC Graph Locking Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGraphLock_ConcurrentWritesAndChecks(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	rg := service.relationshipGraph
+	rg.AddRelationship("team", "viewer", "doc")
+
+	// Every connection to ":memory:" opens a separate empty database
+	sqlDB, _ := service.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 25; i++ {
+				user := fmt.Sprintf("user%d-%d", w, i)
+				body := fmt.Sprintf(`{"subject": %q, "relationship": "member", "object": "team"}`, user)
+				req, _ := http.NewRequest("POST", "/api/v1/relationships", bytes.NewBufferString(body))
+				req.Header.Set("Content-Type", "application/json")
+				router.ServeHTTP(httptest.NewRecorder(), req)
+
+				expiresAt := time.Now().Add(time.Millisecond)
+				rg.AddExpiringRelationship(user, "editor", "doc", &expiresAt)
+				if err := rg.RemoveRelationship(user, "member", "team"); err != nil {
+					t.Errorf("Failed to remove membership: %v", err)
+				}
+			}
+		}(w)
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				service.Enforce(ModelReBAC, fmt.Sprintf("user%d-%d", w, i%25), "doc", "read", nil)
+				rg.ListRelationships("")
+				rg.GroupsForSubject(fmt.Sprintf("user%d-%d", w, i%25), 0)
+				rg.ListAccessibleObjects(fmt.Sprintf("user%d-%d", w, i%25), "read")
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	time.Sleep(2 * time.Millisecond)
+	if allowed, _ := rg.CheckReBACAccess("user0-0", "doc", "write"); allowed {
+		t.Error("Expected the expired editor tuple to stop granting access")
+	}
+	assertIndexConsistent(t, rg)
+	if relationships, _ := rg.ListRelationships(""); len(relationships) != 1 {
+		t.Errorf("Expected only the team tuple to remain, got %+v", relationships)
+	}
+}
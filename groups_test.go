@@ -0,0 +1,85 @@
+// Multi-Model Authorization Microservice - Group Membership Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGroups_MembershipAndRoleBindings(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/users/{userId}/groups", service.getUserGroupsHandler).Methods("GET")
+
+	service.relationshipGraph.AddRelationship("alice", "member", "backend")
+	service.relationshipGraph.AddRelationship("backend", "member", "engineering")
+	service.relationshipGraph.AddRelationship("engineering", "member", "backend") // cycle
+
+	req, _ := http.NewRequest("GET", "/api/v1/users/alice/groups", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var response struct {
+		Groups    []string `json:"groups"`
+		AllGroups []string `json:"all_groups"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if len(response.Groups) != 1 || response.Groups[0] != "backend" {
+		t.Errorf("Expected direct group backend, got %v", response.Groups)
+	}
+	if len(response.AllGroups) != 2 || response.AllGroups[1] != "engineering" {
+		t.Errorf("Expected nested group engineering, got %v", response.AllGroups)
+	}
+
+	// Role bound to a group rather than a user
+	service.rbacEnforcer.AddGroupingPolicy("engineering", "developer")
+	service.rbacEnforcer.AddPolicy("developer", "repository", "write", "allow")
+
+	if allowed, _ := service.Enforce(ModelRBAC, "alice", "repository", "write", nil); allowed {
+		t.Error("Group role bindings should not apply unless enabled")
+	}
+
+	service.rbacGroupBindings = true
+	if allowed, _ := service.Enforce(ModelRBAC, "alice", "repository", "write", nil); !allowed {
+		t.Error("Expected role bound to alice's group to apply")
+	}
+	if allowed, _ := service.Enforce(ModelRBAC, "bob", "repository", "write", nil); allowed {
+		t.Error("Non-members should not inherit group role bindings")
+	}
+}
+
+func TestGroups_NestedGroupAccess(t *testing.T) {
+	service := setupTestService(t)
+	rg := service.relationshipGraph
+
+	rg.AddRelationship("alice", "member", "team")
+	rg.AddRelationship("team", "member", "department")
+	rg.AddRelationship("department", "member", "org")
+	rg.AddRelationship("org", "member", "team") // Cycle
+	rg.AddRelationship("org", "viewer", "handbook")
+
+	allowed, hops := rg.CheckReBACAccessHops("alice", "handbook", "read")
+	if !allowed {
+		t.Fatal("Expected access through nested groups")
+	}
+	if path := formatPath("alice", hops); path != "alice -[member]-> team -[member]-> department -[member]-> org -[viewer]-> handbook" {
+		t.Errorf("Unexpected path: %s", path)
+	}
+	if allowed, _ := rg.CheckReBACAccessHops("alice", "handbook", "write"); allowed {
+		t.Error("Expected the viewer grant not to allow writes")
+	}
+
+	rg.groupDepth = 2
+	rg.bumpRevision()
+	if allowed, _ := rg.CheckReBACAccessHops("alice", "handbook", "read"); allowed {
+		t.Error("Expected groups beyond the depth limit not to grant access")
+	}
+}
@@ -0,0 +1,568 @@
+// Multi-Model Authorization Microservice - gRPC API
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"casbin-authorization-server/authzpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newGRPCServer creates a gRPC server exposing enforcement and policy management.
+// It shares the AuthService with the HTTP API, so both see the same data.
+func newGRPCServer(s *AuthService) *grpc.Server {
+	server := grpc.NewServer(grpc.UnaryInterceptor(s.grpcRequestInterceptor))
+	authzpb.RegisterAuthorizationServiceServer(server, &authorizationGRPC{s: s})
+	authzpb.RegisterRBACServiceServer(server, &rbacGRPC{s: s})
+	authzpb.RegisterABACServiceServer(server, &abacGRPC{s: s})
+	authzpb.RegisterReBACServiceServer(server, &rebacGRPC{s: s})
+	if s.extAuthz != nil {
+		server.RegisterService(&extAuthzServiceDesc, &extAuthzGRPC{s: s})
+	}
+	healthpb.RegisterHealthServer(server, newHealthGRPC(s, server))
+	return server
+}
+
+// grpcRequestInterceptor assigns request IDs and logs calls like the HTTP middleware,
+// and turns handler panics into Internal errors instead of crashing the server
+func (s *AuthService) grpcRequestInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	requestID := grpcMetadataValue(ctx, "x-request-id")
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+	ctx = context.WithValue(ctx, requestIDKey{}, requestID)
+	grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			log.Printf("[%s] panic in %s: %v", requestID, info.FullMethod, recovered)
+			err = status.Error(codes.Internal, "internal server error")
+		}
+	}()
+
+	start := time.Now()
+	ctx, err = s.authenticator.authenticateGRPC(ctx, info.FullMethod)
+	if err != nil {
+		log.Printf("[%s] gRPC %s %s (%v)", requestID, info.FullMethod, status.Code(err), time.Since(start))
+		return nil, err
+	}
+	if s.changeApproval && approvalGRPCMethods[info.FullMethod] {
+		err = status.Error(codes.FailedPrecondition, "changes need approval; submit them through the HTTP API")
+		log.Printf("[%s] gRPC %s %s (%v)", requestID, info.FullMethod, status.Code(err), time.Since(start))
+		return nil, err
+	}
+	resp, err = handler(ctx, req)
+	log.Printf("[%s] gRPC %s %s (%v)", requestID, info.FullMethod, status.Code(err), time.Since(start))
+	return resp, err
+}
+
+// grpcMetadataValue returns the first value of an incoming metadata key
+func grpcMetadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// actorFromGRPC identifies the caller like actorFromRequest does over HTTP
+func actorFromGRPC(ctx context.Context) string {
+	if principal := principalFromContext(ctx); principal != nil {
+		return principal.Name
+	}
+	if actor := grpcMetadataValue(ctx, "x-actor"); actor != "" {
+		return actor
+	}
+	return anonymousActor
+}
+
+// authorizationGRPC implements authzpb.AuthorizationServiceServer
+type authorizationGRPC struct {
+	authzpb.UnimplementedAuthorizationServiceServer
+	s *AuthService
+}
+
+// Enforce performs an authorization check against any model
+func (g *authorizationGRPC) Enforce(ctx context.Context, req *authzpb.EnforceRequest) (*authzpb.EnforceResponse, error) {
+	model := AccessControlModel(req.Model)
+	if model == "" {
+		model = ModelRBAC
+	}
+
+	// The tenant comes from the request or the x-tenant metadata key
+	tenant := req.Tenant
+	if tenant == "" {
+		tenant = grpcMetadataValue(ctx, "x-tenant")
+	}
+	scope, err := g.s.tenantScope(tenant)
+	if errors.Is(err, errTenantNotFound) {
+		return nil, status.Errorf(codes.NotFound, "tenant not found: %s", tenant)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	switch model {
+	case ModelACL, ModelRBAC, ModelABAC, ModelReBAC:
+	default:
+		return nil, status.Error(codes.InvalidArgument, "invalid model specified")
+	}
+	if !validFreshness(req.Freshness) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid freshness: %s", req.Freshness)
+	}
+	if !g.s.currentSettings().modelEnabled(model) {
+		return nil, status.Errorf(codes.FailedPrecondition, "model %s is disabled", model)
+	}
+
+	// Every model goes through the decision cache and honors the requested freshness
+	decision, err := g.s.DecideWithTuples(scope, model, req.Subject, req.Object, req.Action, req.Attributes, req.Freshness, nil)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "authorization check error: %v", err)
+	}
+	allowed := decision.Allowed
+	var path string
+	if allowed && decision.Hops != nil {
+		path = formatPath(req.Subject, localHops(scope, decision.Hops))
+	}
+
+	// Decisions are audited with tenant-qualified names
+	subject, object := scope.qualify(req.Subject), scope.qualify(req.Object)
+	g.s.recordDecision(requestIDFromContext(ctx), model, subject, object, req.Action, req.Attributes, allowed)
+	g.s.traceDecision(requestIDFromContext(ctx), model, subject, object, req.Action, req.Attributes, allowed)
+
+	response := &authzpb.EnforceResponse{Allowed: allowed, Model: string(model), Path: path, Message: "Access denied"}
+	if allowed {
+		response.Message = "Access granted"
+		if path != "" {
+			response.Message += fmt.Sprintf(" (relationship path: %s)", path)
+		}
+	}
+	return response, nil
+}
+
+// rbacGRPC implements authzpb.RBACServiceServer
+type rbacGRPC struct {
+	authzpb.UnimplementedRBACServiceServer
+	s *AuthService
+}
+
+// policyTarget resolves the enforcer and label kind of a policy request's model
+func (g *rbacGRPC) policyTarget(model string) (AccessControlModel, string, error) {
+	switch AccessControlModel(model) {
+	case ModelACL:
+		return ModelACL, labelKindACL, nil
+	case ModelRBAC, "":
+		return ModelRBAC, labelKindRBAC, nil
+	default:
+		return "", "", status.Error(codes.InvalidArgument, "model must be 'acl' or 'rbac'")
+	}
+}
+
+// AddPolicy adds an ACL or RBAC policy
+func (g *rbacGRPC) AddPolicy(ctx context.Context, req *authzpb.PolicyRequest) (*authzpb.PolicyResponse, error) {
+	model, kind, err := g.policyTarget(req.Model)
+	if err != nil {
+		return nil, err
+	}
+	if req.Subject == "" || req.Object == "" || req.Action == "" {
+		return nil, status.Error(codes.InvalidArgument, "subject, object, and action are required")
+	}
+
+	effect, err := normalizeEffect(req.Effect)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if model == ModelACL {
+		if err := validateACLRule(req.Object, req.Action); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	enforcer := g.s.getEnforcer(model)
+	added, err := addRule(enforcer, req.Subject, req.Object, req.Action, effect)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to add policy: %v", err)
+	}
+	if !added {
+		return nil, status.Error(codes.AlreadyExists, "policy already exists")
+	}
+
+	key := labelKey(req.Subject, req.Object, req.Action)
+	g.s.recordPolicyMetadata(kind, key, actorFromGRPC(ctx))
+	g.s.publishChange(ruleChangeKind(kind), changeAdded, key, ruleChange(req.Subject, req.Object, req.Action, effect), actorFromGRPC(ctx))
+	if len(req.Labels) > 0 {
+		if err := g.s.setLabels(kind, key, req.Labels); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to label policy: %v", err)
+		}
+	}
+
+	return &authzpb.PolicyResponse{Model: string(model), Subject: req.Subject, Object: req.Object, Action: req.Action, Effect: effect}, nil
+}
+
+// RemovePolicy removes an ACL or RBAC policy
+func (g *rbacGRPC) RemovePolicy(ctx context.Context, req *authzpb.PolicyRequest) (*authzpb.PolicyResponse, error) {
+	model, kind, err := g.policyTarget(req.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	enforcer := g.s.getEnforcer(model)
+	removed, err := removeRule(enforcer, req.Subject, req.Object, req.Action)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to remove policy: %v", err)
+	}
+	if !removed {
+		return nil, status.Error(codes.NotFound, "policy not found")
+	}
+
+	key := labelKey(req.Subject, req.Object, req.Action)
+	g.s.removeLabels(kind, key)
+	g.s.removePolicyMetadata(kind, key)
+	g.s.publishChange(ruleChangeKind(kind), changeRemoved, key, ruleChange(req.Subject, req.Object, req.Action, ""), actorFromGRPC(ctx))
+
+	return &authzpb.PolicyResponse{Model: string(model), Subject: req.Subject, Object: req.Object, Action: req.Action}, nil
+}
+
+// ListPolicies lists ACL or RBAC policies, optionally filtered by label
+func (g *rbacGRPC) ListPolicies(ctx context.Context, req *authzpb.ListPoliciesRequest) (*authzpb.ListPoliciesResponse, error) {
+	model, kind, err := g.policyTarget(req.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := g.s.getEnforcer(model).GetPolicy()
+	if err == nil {
+		rules, err = g.s.filterRulesByLabel(kind, rules, req.Label)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "policy retrieval error: %v", err)
+	}
+
+	response := &authzpb.ListPoliciesResponse{Model: string(model)}
+	for _, rule := range rules {
+		if len(rule) < 3 {
+			continue
+		}
+		response.Policies = append(response.Policies, &authzpb.Policy{Subject: rule[0], Object: rule[1], Action: rule[2], Effect: ruleEffect(rule)})
+	}
+	return response, nil
+}
+
+// AddRoleForUser assigns an RBAC role to a user
+func (g *rbacGRPC) AddRoleForUser(ctx context.Context, req *authzpb.RoleRequest) (*authzpb.RoleResponse, error) {
+	if req.User == "" || req.Role == "" {
+		return nil, status.Error(codes.InvalidArgument, "user and role are required")
+	}
+
+	added, err := g.s.rbacEnforcer.AddRoleForUser(req.User, req.Role)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to add role: %v", err)
+	}
+	if !added {
+		return nil, status.Error(codes.AlreadyExists, "user already has this role")
+	}
+
+	key := labelKey(req.User, req.Role)
+	g.s.recordPolicyMetadata(labelKindRole, key, actorFromGRPC(ctx))
+	g.s.publishChange(changeKindRBACRole, changeAdded, key, roleChange(req.User, req.Role), actorFromGRPC(ctx))
+	if len(req.Labels) > 0 {
+		if err := g.s.setLabels(labelKindRole, key, req.Labels); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to label role assignment: %v", err)
+		}
+	}
+
+	return &authzpb.RoleResponse{User: req.User, Role: req.Role}, nil
+}
+
+// RemoveRoleForUser removes an RBAC role from a user
+func (g *rbacGRPC) RemoveRoleForUser(ctx context.Context, req *authzpb.RoleRequest) (*authzpb.RoleResponse, error) {
+	removed, err := g.s.rbacEnforcer.DeleteRoleForUser(req.User, req.Role)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to remove role: %v", err)
+	}
+	if !removed {
+		return nil, status.Error(codes.NotFound, "user does not have this role")
+	}
+
+	key := labelKey(req.User, req.Role)
+	g.s.removeLabels(labelKindRole, key)
+	g.s.removePolicyMetadata(labelKindRole, key)
+	g.s.publishChange(changeKindRBACRole, changeRemoved, key, roleChange(req.User, req.Role), actorFromGRPC(ctx))
+
+	return &authzpb.RoleResponse{User: req.User, Role: req.Role}, nil
+}
+
+// GetRolesForUser lists the RBAC roles assigned to a user
+func (g *rbacGRPC) GetRolesForUser(ctx context.Context, req *authzpb.GetRolesForUserRequest) (*authzpb.GetRolesForUserResponse, error) {
+	roles, err := g.s.rbacEnforcer.GetRolesForUser(req.User)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "role retrieval error: %v", err)
+	}
+	return &authzpb.GetRolesForUserResponse{User: req.User, Roles: roles}, nil
+}
+
+// abacGRPC implements authzpb.ABACServiceServer
+type abacGRPC struct {
+	authzpb.UnimplementedABACServiceServer
+	s *AuthService
+}
+
+// schemaProblems rejects schema violations in enforce mode and logs them in warn mode
+func (g *abacGRPC) schemaProblems(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	if g.s.schemaEnforce {
+		return status.Error(codes.InvalidArgument, "attribute schema violation: "+strings.Join(problems, "; "))
+	}
+	log.Printf("Attribute schema warnings: %s", strings.Join(problems, "; "))
+	return nil
+}
+
+// setAttributes validates and saves the attributes of a user or object
+func (g *abacGRPC) setAttributes(ctx context.Context, scope string, req *authzpb.SetAttributesRequest, save func(id, attribute, value string) error) (*authzpb.SetAttributesResponse, error) {
+	if req.Id == "" || len(req.Attributes) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "id and attributes are required")
+	}
+
+	warnings, err := g.s.validateAttributes(scope, req.Attributes)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if err := g.schemaProblems(warnings); err != nil {
+		return nil, err
+	}
+	if err := g.s.checkAttributeValues(scope, req.Attributes, nil); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	for k, v := range req.Attributes {
+		if err := save(req.Id, k, v); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to save %s attribute: %v", scope, err)
+		}
+		g.s.publishChange(scope+"_attribute", changeUpdated, req.Id+"."+k, attributeChange(scope, req.Id, k, v), actorFromGRPC(ctx))
+	}
+
+	return &authzpb.SetAttributesResponse{Id: req.Id, Attributes: req.Attributes, Warnings: warnings}, nil
+}
+
+// SetUserAttributes sets attributes of a user
+func (g *abacGRPC) SetUserAttributes(ctx context.Context, req *authzpb.SetAttributesRequest) (*authzpb.SetAttributesResponse, error) {
+	return g.setAttributes(ctx, "user", req, g.s.saveUserAttribute)
+}
+
+// SetObjectAttributes sets attributes of an object
+func (g *abacGRPC) SetObjectAttributes(ctx context.Context, req *authzpb.SetAttributesRequest) (*authzpb.SetAttributesResponse, error) {
+	return g.setAttributes(ctx, "object", req, g.s.saveObjectAttribute)
+}
+
+// AddPolicy creates or replaces an ABAC policy
+func (g *abacGRPC) AddPolicy(ctx context.Context, req *authzpb.ABACPolicy) (*authzpb.ABACPolicy, error) {
+	if req.Id == "" || req.Name == "" || req.Effect == "" {
+		return nil, status.Error(codes.InvalidArgument, "ID, Name, and Effect are required")
+	}
+	if req.Effect != "allow" && req.Effect != "deny" {
+		return nil, status.Error(codes.InvalidArgument, "Effect must be 'allow' or 'deny'")
+	}
+	if err := validatePolicyActions(req.Actions); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	policy := abacPolicyFromProto(req)
+	warnings, err := g.s.validatePolicyConditions(policy.Conditions)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if err := g.schemaProblems(warnings); err != nil {
+		return nil, err
+	}
+
+	policy.CreatedAt = time.Now()
+	policy.UpdatedAt = time.Now()
+	if err := g.s.policyEngine.AddPolicy(policy); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to add policy: %v", err)
+	}
+	g.s.publishChange(changeKindABACPolicy, changeAdded, policy.ID, policy, actorFromGRPC(ctx))
+	return abacPolicyToProto(policy), nil
+}
+
+// RemovePolicy removes an ABAC policy
+func (g *abacGRPC) RemovePolicy(ctx context.Context, req *authzpb.RemoveABACPolicyRequest) (*authzpb.RemoveABACPolicyResponse, error) {
+	if _, exists := g.s.policyEngine.getPolicy(req.Id); !exists {
+		return nil, status.Error(codes.NotFound, "policy not found")
+	}
+
+	err := g.s.policyEngine.RemovePolicy(req.Id)
+	if err == nil {
+		err = g.s.removeLabels(labelKindABAC, req.Id)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to remove policy: %v", err)
+	}
+	g.s.publishChange(changeKindABACPolicy, changeRemoved, req.Id, map[string]string{"id": req.Id}, actorFromGRPC(ctx))
+	return &authzpb.RemoveABACPolicyResponse{Id: req.Id}, nil
+}
+
+// ListPolicies lists all ABAC policies ordered by ID
+func (g *abacGRPC) ListPolicies(ctx context.Context, req *authzpb.ListABACPoliciesRequest) (*authzpb.ListABACPoliciesResponse, error) {
+	response := &authzpb.ListABACPoliciesResponse{}
+	for _, policy := range g.s.policyEngine.policyList() {
+		response.Policies = append(response.Policies, abacPolicyToProto(policy))
+	}
+	sort.Slice(response.Policies, func(i, j int) bool {
+		return response.Policies[i].Id < response.Policies[j].Id
+	})
+	return response, nil
+}
+
+// abacPolicyFromProto converts a protobuf ABAC policy into the engine's representation
+func abacPolicyFromProto(p *authzpb.ABACPolicy) *ABACPolicy {
+	policy := &ABACPolicy{
+		ID:          p.Id,
+		Name:        p.Name,
+		Description: p.Description,
+		Effect:      p.Effect,
+		Priority:    int(p.Priority),
+		Actions:     p.Actions,
+	}
+	for _, c := range p.Conditions {
+		policy.Conditions = append(policy.Conditions, PolicyCondition{
+			Type:     c.Type,
+			Field:    c.Field,
+			Operator: c.Operator,
+			Value:    c.Value,
+			LogicOp:  c.LogicOp,
+		})
+	}
+	return policy
+}
+
+// abacPolicyToProto converts an ABAC policy into its protobuf representation. The protobuf
+// schema has flat conditions only, so condition groups are not included.
+func abacPolicyToProto(policy *ABACPolicy) *authzpb.ABACPolicy {
+	p := &authzpb.ABACPolicy{
+		Id:          policy.ID,
+		Name:        policy.Name,
+		Description: policy.Description,
+		Effect:      policy.Effect,
+		Priority:    int32(policy.Priority),
+		Actions:     policy.Actions,
+	}
+	for _, c := range policy.Conditions {
+		p.Conditions = append(p.Conditions, &authzpb.PolicyCondition{
+			Type:     c.Type,
+			Field:    c.Field,
+			Operator: c.Operator,
+			Value:    c.Value,
+			LogicOp:  c.LogicOp,
+		})
+	}
+	return p
+}
+
+// rebacGRPC implements authzpb.ReBACServiceServer
+type rebacGRPC struct {
+	authzpb.UnimplementedReBACServiceServer
+	s *AuthService
+}
+
+// AddRelationship adds a relationship tuple
+func (g *rebacGRPC) AddRelationship(ctx context.Context, req *authzpb.Relationship) (*authzpb.RelationshipResponse, error) {
+	if req.Subject == "" || req.Relationship == "" || req.Object == "" {
+		return nil, status.Error(codes.InvalidArgument, "subject, relationship, and object are required")
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != "" {
+		parsed, err := time.Parse(time.RFC3339, req.ExpiresAt)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid expires_at: %v", err)
+		}
+		if !parsed.After(time.Now()) {
+			return nil, status.Error(codes.InvalidArgument, "expires_at must be in the future")
+		}
+		expiresAt = &parsed
+	}
+
+	actor := actorFromGRPC(ctx)
+	err := g.s.relationshipGraph.AddAnnotatedRelationship(req.Subject, req.Relationship, req.Object, expiresAt, nil, TupleMetadata{CreatedBy: actor})
+	var cardinalityErr *CardinalityError
+	if errors.As(err, &cardinalityErr) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	var typeErr *TypeError
+	if errors.As(err, &typeErr) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to add relationship: %v", err)
+	}
+
+	if len(req.Labels) > 0 {
+		if err := g.s.setLabels(labelKindRelationship, labelKey(req.Subject, req.Relationship, req.Object), req.Labels); err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to label relationship: %v", err)
+		}
+	}
+	g.s.publishChange(changeKindRelationship, changeAdded, labelKey(req.Subject, req.Relationship, req.Object),
+		relationshipChange(req.Subject, req.Relationship, req.Object, expiresAt), actor)
+
+	return &authzpb.RelationshipResponse{Subject: req.Subject, Relationship: req.Relationship, Object: req.Object, ExpiresAt: req.ExpiresAt}, nil
+}
+
+// RemoveRelationship removes a relationship tuple
+func (g *rebacGRPC) RemoveRelationship(ctx context.Context, req *authzpb.Relationship) (*authzpb.RelationshipResponse, error) {
+	if err := g.s.relationshipGraph.RemoveRelationship(req.Subject, req.Relationship, req.Object); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to remove relationship: %v", err)
+	}
+	g.s.removeLabels(labelKindRelationship, labelKey(req.Subject, req.Relationship, req.Object))
+	g.s.publishChange(changeKindRelationship, changeRemoved, labelKey(req.Subject, req.Relationship, req.Object),
+		relationshipChange(req.Subject, req.Relationship, req.Object, nil), actorFromGRPC(ctx))
+
+	return &authzpb.RelationshipResponse{Subject: req.Subject, Relationship: req.Relationship, Object: req.Object}, nil
+}
+
+// ListRelationships lists relationship tuples, optionally for one subject and label
+func (g *rebacGRPC) ListRelationships(ctx context.Context, req *authzpb.ListRelationshipsRequest) (*authzpb.ListRelationshipsResponse, error) {
+	relationships, err := g.s.relationshipGraph.ListRelationships(req.Subject)
+	if err == nil {
+		relationships, err = g.s.filterRelationshipsByLabel(relationships, req.Label)
+	}
+	var expirations map[string]time.Time
+	if err == nil {
+		expirations, err = g.s.relationshipGraph.RelationshipExpirations(req.Subject)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to retrieve relationships: %v", err)
+	}
+
+	response := &authzpb.ListRelationshipsResponse{}
+	for _, rel := range relationships {
+		tuple := &authzpb.Relationship{
+			Subject:      rel.Subject,
+			Relationship: rel.Relationship,
+			Object:       rel.Object,
+		}
+		if expiresAt, ok := expirations[labelKey(rel.Subject, rel.Relationship, rel.Object)]; ok {
+			tuple.ExpiresAt = expiresAt.Format(time.RFC3339)
+		}
+		response.Relationships = append(response.Relationships, tuple)
+	}
+	return response, nil
+}
@@ -0,0 +1,187 @@
+// Multi-Model Authorization Microservice - gRPC API Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"casbin-authorization-server/authzpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// setupTestGRPC serves the gRPC API over an in-memory connection
+func setupTestGRPC(t *testing.T, service *AuthService) *grpc.ClientConn {
+	listener := bufconn.Listen(1 << 20)
+	server := newGRPCServer(service)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to dial gRPC server: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestGRPC_PolicyManagementAndEnforce(t *testing.T) {
+	service := setupTestService(t)
+	conn := setupTestGRPC(t, service)
+	ctx := context.Background()
+
+	authz := authzpb.NewAuthorizationServiceClient(conn)
+	rbac := authzpb.NewRBACServiceClient(conn)
+
+	if _, err := rbac.AddPolicy(ctx, &authzpb.PolicyRequest{Model: "rbac", Subject: "editor", Object: "doc1", Action: "write"}); err != nil {
+		t.Fatalf("AddPolicy failed: %v", err)
+	}
+	if _, err := rbac.AddPolicy(ctx, &authzpb.PolicyRequest{Model: "rbac", Subject: "editor", Object: "doc1", Action: "write"}); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Expected AlreadyExists for a duplicate policy, got %v", err)
+	}
+	if _, err := rbac.AddRoleForUser(ctx, &authzpb.RoleRequest{User: "alice", Role: "editor"}); err != nil {
+		t.Fatalf("AddRoleForUser failed: %v", err)
+	}
+
+	roles, err := rbac.GetRolesForUser(ctx, &authzpb.GetRolesForUserRequest{User: "alice"})
+	if err != nil || len(roles.Roles) != 1 || roles.Roles[0] != "editor" {
+		t.Errorf("Unexpected roles for alice: %v %v", roles, err)
+	}
+	policies, err := rbac.ListPolicies(ctx, &authzpb.ListPoliciesRequest{Model: "rbac"})
+	if err != nil || len(policies.Policies) != 1 || policies.Policies[0].Object != "doc1" {
+		t.Errorf("Unexpected RBAC policies: %v %v", policies, err)
+	}
+
+	resp, err := authz.Enforce(ctx, &authzpb.EnforceRequest{Model: "rbac", Subject: "alice", Object: "doc1", Action: "write"})
+	if err != nil || !resp.Allowed {
+		t.Errorf("Expected alice to write doc1: %v %v", resp, err)
+	}
+
+	if _, err := rbac.RemoveRoleForUser(ctx, &authzpb.RoleRequest{User: "alice", Role: "editor"}); err != nil {
+		t.Fatalf("RemoveRoleForUser failed: %v", err)
+	}
+	resp, err = authz.Enforce(ctx, &authzpb.EnforceRequest{Subject: "alice", Object: "doc1", Action: "write"})
+	if err != nil || resp.Allowed {
+		t.Errorf("Expected alice to be denied after role removal: %v %v", resp, err)
+	}
+
+	if _, err := authz.Enforce(ctx, &authzpb.EnforceRequest{Model: "unknown"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an unknown model, got %v", err)
+	}
+	if _, err := rbac.RemovePolicy(ctx, &authzpb.PolicyRequest{Model: "acl", Subject: "x", Object: "y", Action: "z"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for a missing policy, got %v", err)
+	}
+}
+
+func TestGRPC_ABACAndReBAC(t *testing.T) {
+	service := setupTestService(t)
+	conn := setupTestGRPC(t, service)
+	ctx := context.Background()
+
+	authz := authzpb.NewAuthorizationServiceClient(conn)
+	abac := authzpb.NewABACServiceClient(conn)
+	rebac := authzpb.NewReBACServiceClient(conn)
+
+	_, err := abac.AddPolicy(ctx, &authzpb.ABACPolicy{
+		Id:       "engineering_read",
+		Name:     "Engineering Read",
+		Effect:   "allow",
+		Priority: 10,
+		Actions:  []string{"read"},
+		Conditions: []*authzpb.PolicyCondition{
+			{Type: "user", Field: "department", Operator: "eq", Value: "engineering"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("ABAC AddPolicy failed: %v", err)
+	}
+	if _, err := abac.SetUserAttributes(ctx, &authzpb.SetAttributesRequest{Id: "bob", Attributes: map[string]string{"department": "engineering"}}); err != nil {
+		t.Fatalf("SetUserAttributes failed: %v", err)
+	}
+
+	for action, expected := range map[string]bool{"read": true, "write": false} {
+		resp, err := authz.Enforce(ctx, &authzpb.EnforceRequest{Model: "abac", Subject: "bob", Object: "doc1", Action: action})
+		if err != nil || resp.Allowed != expected {
+			t.Errorf("ABAC %s: expected %v, got %v %v", action, expected, resp, err)
+		}
+	}
+
+	list, err := abac.ListPolicies(ctx, &authzpb.ListABACPoliciesRequest{})
+	if err != nil || len(list.Policies) != 1 || len(list.Policies[0].Actions) != 1 {
+		t.Errorf("Unexpected ABAC policies: %v %v", list, err)
+	}
+	if _, err := abac.RemovePolicy(ctx, &authzpb.RemoveABACPolicyRequest{Id: "missing"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for a missing ABAC policy, got %v", err)
+	}
+
+	if _, err := rebac.AddRelationship(ctx, &authzpb.Relationship{Subject: "carol", Relationship: "owner", Object: "doc2"}); err != nil {
+		t.Fatalf("AddRelationship failed: %v", err)
+	}
+	resp, err := authz.Enforce(ctx, &authzpb.EnforceRequest{Model: "rebac", Subject: "carol", Object: "doc2", Action: "read"})
+	if err != nil || !resp.Allowed || resp.Path == "" {
+		t.Errorf("Expected carol to read doc2 with a path: %v %v", resp, err)
+	}
+
+	rels, err := rebac.ListRelationships(ctx, &authzpb.ListRelationshipsRequest{Subject: "carol"})
+	if err != nil || len(rels.Relationships) != 1 {
+		t.Errorf("Unexpected relationships: %v %v", rels, err)
+	}
+	if _, err := rebac.RemoveRelationship(ctx, &authzpb.Relationship{Subject: "carol", Relationship: "owner", Object: "doc2"}); err != nil {
+		t.Fatalf("RemoveRelationship failed: %v", err)
+	}
+	resp, err = authz.Enforce(ctx, &authzpb.EnforceRequest{Model: "rebac", Subject: "carol", Object: "doc2", Action: "read"})
+	if err != nil || resp.Allowed {
+		t.Errorf("Expected carol to be denied after removal: %v %v", resp, err)
+	}
+}
+
+func TestGRPC_EnforceHonorsFreshnessAndEnabledModels(t *testing.T) {
+	service := setupTestService(t)
+	if err := service.enableDecisionCache(100, time.Minute); err != nil {
+		t.Fatalf("Failed to enable decision cache: %v", err)
+	}
+	conn := setupTestGRPC(t, service)
+	ctx := context.Background()
+	authz := authzpb.NewAuthorizationServiceClient(conn)
+
+	// Another instance writes a tuple directly in the database
+	check := &authzpb.EnforceRequest{Model: "rebac", Subject: "alice", Object: "account1", Action: "write"}
+	if resp, err := authz.Enforce(ctx, check); err != nil || resp.Allowed {
+		t.Fatalf("Expected alice to be denied before the write: %v %v", resp, err)
+	}
+	service.db.Create(&RelationshipRecord{Subject: "alice", Relationship: "owner", Object: "account1"})
+
+	if resp, err := authz.Enforce(ctx, check); err != nil || resp.Allowed {
+		t.Errorf("Expected default freshness to serve the cached decision: %v %v", resp, err)
+	}
+	check.Freshness = freshnessStrong
+	if resp, err := authz.Enforce(ctx, check); err != nil || !resp.Allowed || resp.Path != "alice -[owner]-> account1" {
+		t.Errorf("Expected strong freshness to read the tuple from the database: %v %v", resp, err)
+	}
+	check.Freshness = "eventual"
+	if _, err := authz.Enforce(ctx, check); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an unknown freshness, got %v", err)
+	}
+
+	// Models disabled in the configuration refuse checks over gRPC too
+	service.settings.Store(&runtimeSettings{
+		defaultMaxDepth: defaultMaxDepth,
+		maxDepthLimit:   defaultMaxDepthLimit,
+		enabledModels:   map[AccessControlModel]bool{ModelRBAC: true},
+	})
+	check.Freshness = ""
+	if _, err := authz.Enforce(ctx, check); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for a disabled model, got %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+// Multi-Model Authorization Microservice - Health Checking
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// readinessTimeout bounds the database pings of a readiness check
+const readinessTimeout = 2 * time.Second
+
+// healthWatchInterval is how often gRPC health watches re-check readiness
+const healthWatchInterval = 5 * time.Second
+
+// grpcHealthMethodPrefix prefixes the methods of the gRPC health service
+const grpcHealthMethodPrefix = "/grpc.health.v1.Health/"
+
+// ComponentHealth is the state of one dependency the service needs to serve requests
+type ComponentHealth struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// readiness checks that the database, and the read replica when one is configured, answer
+// and that the enforcers and the relationship graph are loaded. The service is ready when
+// every component is healthy.
+func (s *AuthService) readiness(ctx context.Context) ([]ComponentHealth, bool) {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	components := []ComponentHealth{{Name: "database", Healthy: true}}
+	if sqlDB, err := s.db.DB(); err != nil {
+		components[0] = ComponentHealth{Name: "database", Error: err.Error()}
+	} else if err := sqlDB.PingContext(ctx); err != nil {
+		components[0] = ComponentHealth{Name: "database", Error: err.Error()}
+	}
+
+	if s.relationshipGraph != nil && s.relationshipGraph.replica != nil {
+		replica := ComponentHealth{Name: "read_replica", Healthy: true}
+		if sqlDB, err := s.relationshipGraph.replica.db.DB(); err != nil {
+			replica = ComponentHealth{Name: "read_replica", Error: err.Error()}
+		} else if err := sqlDB.PingContext(ctx); err != nil {
+			replica = ComponentHealth{Name: "read_replica", Error: err.Error()}
+		}
+		components = append(components, replica)
+	}
+
+	enforcers := ComponentHealth{Name: "enforcers", Healthy: true}
+	if s.aclEnforcer == nil || s.rbacEnforcer == nil || s.policyEngine == nil || s.relationshipGraph == nil {
+		enforcers = ComponentHealth{Name: "enforcers", Error: "policies are not loaded"}
+	}
+	components = append(components, enforcers)
+
+	ready := true
+	for _, component := range components {
+		ready = ready && component.Healthy
+	}
+	return components, ready
+}
+
+// readinessHandler reports whether the service can serve requests, with 503 when it
+// cannot, for load balancers to take the instance out of rotation
+func (s *AuthService) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	components, ready := s.readiness(r.Context())
+
+	response := map[string]interface{}{
+		"status":     "ready",
+		"components": components,
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		serviceMetrics.Inc("readiness_failures_total")
+		response["status"] = "unavailable"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// healthGRPC implements the standard grpc.health.v1.Health service. Every service of the
+// server shares the readiness of the service as a whole, checked on each call.
+type healthGRPC struct {
+	healthpb.UnimplementedHealthServer
+	s        *AuthService
+	services map[string]bool // Names of the registered services; "" is the server as a whole
+}
+
+// newHealthGRPC covers the services already registered on server
+func newHealthGRPC(s *AuthService, server *grpc.Server) *healthGRPC {
+	services := map[string]bool{"": true, healthpb.Health_ServiceDesc.ServiceName: true}
+	for name := range server.GetServiceInfo() {
+		services[name] = true
+	}
+	return &healthGRPC{s: s, services: services}
+}
+
+// servingStatus returns the status of the server
+func (g *healthGRPC) servingStatus(ctx context.Context) healthpb.HealthCheckResponse_ServingStatus {
+	if _, ready := g.s.readiness(ctx); !ready {
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
+
+// Check returns the status of a service, or of the server for the empty name
+func (g *healthGRPC) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if !g.services[req.Service] {
+		return nil, status.Errorf(codes.NotFound, "unknown service: %s", req.Service)
+	}
+	return &healthpb.HealthCheckResponse{Status: g.servingStatus(ctx)}, nil
+}
+
+// List returns the status of every service
+func (g *healthGRPC) List(ctx context.Context, _ *healthpb.HealthListRequest) (*healthpb.HealthListResponse, error) {
+	serving := g.servingStatus(ctx)
+	statuses := make(map[string]*healthpb.HealthCheckResponse, len(g.services))
+	for name := range g.services {
+		statuses[name] = &healthpb.HealthCheckResponse{Status: serving}
+	}
+	return &healthpb.HealthListResponse{Statuses: statuses}, nil
+}
+
+// Watch sends the status of a service, and again whenever it changes, until the client
+// goes away. Unknown services are reported as SERVICE_UNKNOWN.
+func (g *healthGRPC) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	ticker := time.NewTicker(healthWatchInterval)
+	defer ticker.Stop()
+
+	last := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		current := healthpb.HealthCheckResponse_SERVICE_UNKNOWN
+		if g.services[req.Service] {
+			current = g.servingStatus(stream.Context())
+		}
+		if current != last {
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
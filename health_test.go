@@ -0,0 +1,80 @@
+// Multi-Model Authorization Microservice - Health Checking Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestHealth_ReadinessFollowsTheDatabase(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/health/ready", service.readinessHandler).Methods("GET")
+
+	ready := func() int {
+		req, _ := http.NewRequest("GET", "/api/v1/health/ready", nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	if code := ready(); code != http.StatusOK {
+		t.Fatalf("Expected the service to be ready, got %d", code)
+	}
+
+	// Policies that failed to load leave the service unready
+	policyEngine := service.policyEngine
+	service.policyEngine = nil
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without policies, got %d", code)
+	}
+	service.policyEngine = policyEngine
+
+	sqlDB, _ := service.db.DB()
+	sqlDB.Close()
+	if code := ready(); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 once the database is gone, got %d", code)
+	}
+}
+
+func TestHealth_GRPCHealthService(t *testing.T) {
+	service := setupTestService(t)
+	// Load balancers check health without credentials
+	service.authenticator, _ = newAuthenticator(AuthConfig{APIKeys: []APIKeyConfig{{Name: "ops", Key: "ops-key", Role: roleAdmin}}})
+	health := healthpb.NewHealthClient(setupTestGRPC(t, service))
+	ctx := context.Background()
+
+	for _, name := range []string{"", "authorization.v1.RBACService"} {
+		response, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: name})
+		if err != nil || response.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("Expected %q to be serving, got %v: %v", name, response, err)
+		}
+	}
+	if _, err := health.Check(ctx, &healthpb.HealthCheckRequest{Service: "unknown.Service"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an unknown service, got %v", err)
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := health.Watch(watchCtx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+	if response, err := stream.Recv(); err != nil || response.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("Expected the watch to start serving, got %v: %v", response, err)
+	}
+
+	sqlDB, _ := service.db.DB()
+	sqlDB.Close()
+	if response, err := health.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil || response.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("Expected NOT_SERVING once the database is gone, got %v: %v", response, err)
+	}
+}
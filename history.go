@@ -0,0 +1,310 @@
+// Multi-Model Authorization Microservice - Policy Change History
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+var errRevisionNotFound = errors.New("revision not found")
+
+// PolicyRevision is one version of an ACL rule, RBAC rule, role assignment, resource group
+// membership or ABAC policy. Every change appends a revision, so removed and overwritten
+// policies can be inspected and restored.
+type PolicyRevision struct {
+	ID        uint            `json:"-" gorm:"primaryKey"`
+	Kind      string          `json:"kind" gorm:"index:idx_policy_revisions_policy"` // Change kind of the policy
+	PolicyKey string          `json:"policy" gorm:"index:idx_policy_revisions_policy"`
+	Version   int             `json:"version"`
+	Action    string          `json:"action"` // "added", "updated" or "removed"
+	State     json.RawMessage `json:"state"`  // The policy after the change, or before it was removed
+	Actor     string          `json:"actor"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// RestoreRequest selects the revision to restore; the revision before the latest one
+// when version is 0
+type RestoreRequest struct {
+	Version int `json:"version,omitempty"`
+}
+
+// versionedKind reports whether changes of a kind are kept as policy revisions. Role
+// assignments and resource group memberships are versioned for point-in-time checks.
+func versionedKind(kind string) bool {
+	switch kind {
+	case changeKindACL, changeKindRBACPolicy, changeKindRBACRole, changeKindRBACResourceGroup, changeKindABACPolicy:
+		return true
+	}
+	return false
+}
+
+// recordRevision appends a revision for a policy change. Removals keep the state of the
+// latest revision, as removal events only identify the policy.
+func (s *AuthService) recordRevision(kind, action, key string, data interface{}, actor string) {
+	latest, err := s.latestRevision(kind, key)
+	if err != nil && !errors.Is(err, errRevisionNotFound) {
+		log.Printf("Failed to record %s revision for %s: %v", kind, key, err)
+		return
+	}
+
+	revision := PolicyRevision{Kind: kind, PolicyKey: key, Version: 1, Action: action, Actor: actor}
+	if latest != nil {
+		revision.Version = latest.Version + 1
+	}
+	if action == changeRemoved && latest != nil {
+		revision.State = latest.State
+	} else if revision.State, err = json.Marshal(data); err != nil {
+		log.Printf("Failed to encode %s revision for %s: %v", kind, key, err)
+		return
+	}
+
+	if err := s.db.Create(&revision).Error; err != nil {
+		log.Printf("Failed to record %s revision for %s: %v", kind, key, err)
+	}
+}
+
+// latestRevision returns the newest revision of a policy
+func (s *AuthService) latestRevision(kind, key string) (*PolicyRevision, error) {
+	var revision PolicyRevision
+	err := s.db.Where("kind = ? AND policy_key = ?", kind, key).Order("version desc").First(&revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy history: %v", err)
+	}
+	return &revision, nil
+}
+
+// PolicyHistory returns the revisions of a policy, newest first
+func (s *AuthService) PolicyHistory(kind, key string) ([]PolicyRevision, error) {
+	revisions := make([]PolicyRevision, 0)
+	if err := s.db.Where("kind = ? AND policy_key = ?", kind, key).Order("version desc").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to read policy history: %v", err)
+	}
+	return revisions, nil
+}
+
+// revisionToRestore returns the requested revision of a policy, or the one before the
+// latest revision when version is 0
+func (s *AuthService) revisionToRestore(kind, key string, version int) (*PolicyRevision, error) {
+	if version == 0 {
+		latest, err := s.latestRevision(kind, key)
+		if err != nil {
+			return nil, err
+		}
+		version = latest.Version - 1
+	}
+
+	var revision PolicyRevision
+	err := s.db.Where("kind = ? AND policy_key = ? AND version = ?", kind, key, version).First(&revision).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errRevisionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy history: %v", err)
+	}
+	return &revision, nil
+}
+
+// restoreRule brings back the ACL/RBAC rule of a revision, replacing the rule with the same
+// subject, object and action, and returns the restored rule
+func (s *AuthService) restoreRule(enforcer casbin.IEnforcer, labelKind string, revision *PolicyRevision, actor string) (map[string]string, error) {
+	var rule map[string]string
+	if err := json.Unmarshal(revision.State, &rule); err != nil {
+		return nil, fmt.Errorf("invalid revision state: %v", err)
+	}
+	effect, err := normalizeEffect(rule["effect"])
+	if err != nil {
+		return nil, err
+	}
+	rule["effect"] = effect
+
+	existed, err := removeRule(enforcer, rule["subject"], rule["object"], rule["action"])
+	if err != nil {
+		return nil, err
+	}
+	if _, err := addRule(enforcer, rule["subject"], rule["object"], rule["action"], effect); err != nil {
+		return nil, err
+	}
+
+	s.recordPolicyMetadata(labelKind, revision.PolicyKey, actor)
+	s.publishChange(revision.Kind, map[bool]string{true: changeUpdated, false: changeAdded}[existed], revision.PolicyKey, rule, actor)
+	return rule, nil
+}
+
+// restoreABACPolicy brings back the ABAC policy of a revision, replacing the current one
+func (s *AuthService) restoreABACPolicy(revision *PolicyRevision, actor string) (*ABACPolicy, error) {
+	var policy ABACPolicy
+	if err := json.Unmarshal(revision.State, &policy); err != nil {
+		return nil, fmt.Errorf("invalid revision state: %v", err)
+	}
+	for i := range policy.Conditions {
+		policy.Conditions[i].ID = 0
+		policy.Conditions[i].PolicyID = policy.ID
+	}
+	policy.UpdatedAt = time.Now()
+	policy.Version = s.policyEngine.nextVersion(policy.ID)
+
+	_, exists := s.policyEngine.getPolicy(policy.ID)
+	if exists {
+		if err := s.policyEngine.RemovePolicy(policy.ID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.policyEngine.AddPolicy(&policy); err != nil {
+		return nil, err
+	}
+	if policy.Labels != nil {
+		if err := s.setLabels(labelKindABAC, policy.ID, policy.Labels); err != nil {
+			return nil, err
+		}
+	}
+
+	s.publishChange(changeKindABACPolicy, map[bool]string{true: changeUpdated, false: changeAdded}[exists], policy.ID, &policy, actor)
+	return &policy, nil
+}
+
+// historyKey returns the stored key of the policy a history or restore request names:
+// "subject:object:action" for ACL/RBAC rules, the policy ID for ABAC policies
+func historyKey(scope tenantScope, kind, id string) (string, error) {
+	if kind == changeKindABACPolicy {
+		return scope.qualify(id), nil
+	}
+	parts := strings.Split(id, ":")
+	if len(parts) != 3 {
+		return "", errors.New("Policy ID must be in format 'subject:object:action'")
+	}
+	return labelKey(scope.qualify(parts[0]), scope.qualify(parts[1]), parts[2]), nil
+}
+
+// aclPolicyHistoryHandler lists the revisions of an ACL rule
+func (s *AuthService) aclPolicyHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	s.policyHistory(w, r, changeKindACL, ModelACL)
+}
+
+// rbacPolicyHistoryHandler lists the revisions of an RBAC rule
+func (s *AuthService) rbacPolicyHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	s.policyHistory(w, r, changeKindRBACPolicy, ModelRBAC)
+}
+
+// abacPolicyHistoryHandler lists the revisions of an ABAC policy
+func (s *AuthService) abacPolicyHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	s.policyHistory(w, r, changeKindABACPolicy, ModelABAC)
+}
+
+// restoreACLPolicyHandler restores a revision of an ACL rule
+func (s *AuthService) restoreACLPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	s.restorePolicy(w, r, changeKindACL, ModelACL)
+}
+
+// restoreRBACPolicyHandler restores a revision of an RBAC rule
+func (s *AuthService) restoreRBACPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	s.restorePolicy(w, r, changeKindRBACPolicy, ModelRBAC)
+}
+
+// restoreABACPolicyHandler restores a revision of an ABAC policy
+func (s *AuthService) restoreABACPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	s.restorePolicy(w, r, changeKindABACPolicy, ModelABAC)
+}
+
+// policyHistory responds with the revisions of the policy a request names
+func (s *AuthService) policyHistory(w http.ResponseWriter, r *http.Request, kind string, model AccessControlModel) {
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+	key, err := historyKey(scope, kind, mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	revisions, err := s.PolicyHistory(kind, key)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(revisions) == 0 {
+		writeJSONError(w, http.StatusNotFound, "No history for this policy")
+		return
+	}
+
+	response := map[string]interface{}{
+		"policy":    key,
+		"revisions": revisions,
+		"count":     len(revisions),
+		"model":     string(model),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// restorePolicy restores a revision of the policy a request names
+func (s *AuthService) restorePolicy(w http.ResponseWriter, r *http.Request, kind string, model AccessControlModel) {
+	var request RestoreRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
+	}
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+	key, err := historyKey(scope, kind, mux.Vars(r)["id"])
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	revision, err := s.revisionToRestore(kind, key, request.Version)
+	if errors.Is(err, errRevisionNotFound) {
+		writeJSONError(w, http.StatusNotFound, "No such revision of this policy")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var restored interface{}
+	actor := actorFromRequest(r)
+	switch kind {
+	case changeKindACL:
+		restored, err = s.restoreRule(s.aclEnforcer, labelKindACL, revision, actor)
+	case changeKindRBACPolicy:
+		restored, err = s.restoreRule(s.rbacEnforcer, labelKindRBAC, revision, actor)
+	default:
+		restored, err = s.restoreABACPolicy(revision, actor)
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to restore policy: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"message":          "Policy restored successfully",
+		"restored_version": revision.Version,
+		"policy":           restored,
+		"model":            string(model),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
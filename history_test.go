@@ -0,0 +1,102 @@
+// Multi-Model Authorization Microservice - Policy Change History Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHistory_RestoreRemovedACLRule(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/acl/policies", service.addACLPolicyHandler).Methods("POST")
+	router.HandleFunc("/api/v1/acl/policies/{id}", service.deleteACLPolicyHandler).Methods("DELETE")
+	router.HandleFunc("/api/v1/acl/policies/{id}/history", service.aclPolicyHistoryHandler).Methods("GET")
+	router.HandleFunc("/api/v1/acl/policies/{id}/restore", service.restoreACLPolicyHandler).Methods("POST")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Actor", "deployer")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	send("POST", "/api/v1/acl/policies", `{"subject": "alice", "object": "doc1", "action": "read", "effect": "deny"}`)
+	send("DELETE", "/api/v1/acl/policies/alice:doc1:read", "")
+	if allowed, denied, _ := enforceRule(service.aclEnforcer, "alice", "doc1", "read"); allowed || denied {
+		t.Fatal("Expected the rule to be removed")
+	}
+
+	rr := send("GET", "/api/v1/acl/policies/alice:doc1:read/history", "")
+	var history struct {
+		Revisions []PolicyRevision `json:"revisions"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &history)
+	if rr.Code != http.StatusOK || len(history.Revisions) != 2 {
+		t.Fatalf("Expected 2 revisions, got %d %s", rr.Code, rr.Body.String())
+	}
+	removal := history.Revisions[0]
+	if removal.Version != 2 || removal.Action != changeRemoved || removal.Actor != "deployer" {
+		t.Errorf("Unexpected removal revision: %+v", removal)
+	}
+	var state map[string]string
+	if json.Unmarshal(removal.State, &state); state["effect"] != effectDeny {
+		t.Errorf("Expected the removal to keep the rule's last state, got %s", removal.State)
+	}
+
+	if rr := send("POST", "/api/v1/acl/policies/alice:doc1:read/restore", ""); rr.Code != http.StatusOK {
+		t.Fatalf("Restore failed: %d %s", rr.Code, rr.Body.String())
+	}
+	if _, denied, _ := enforceRule(service.aclEnforcer, "alice", "doc1", "read"); !denied {
+		t.Error("Expected the deny rule to be restored")
+	}
+	if revisions, _ := service.PolicyHistory(changeKindACL, "alice:doc1:read"); len(revisions) != 3 || revisions[0].Action != changeAdded {
+		t.Errorf("Expected the restore to be recorded, got %+v", revisions)
+	}
+
+	if rr := send("POST", "/api/v1/acl/policies/bob:doc1:read/restore", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a rule without history, got %d", rr.Code)
+	}
+}
+
+func TestHistory_RollBackABACUpdate(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/abac/policies/{id}", service.updateABACPolicyHandler).Methods("PUT")
+	router.HandleFunc("/api/v1/abac/policies/{id}/restore", service.restoreABACPolicyHandler).Methods("POST")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s %s failed: %d %s", method, path, rr.Code, rr.Body.String())
+		}
+		return rr
+	}
+
+	send("POST", "/api/v1/abac/policies", `{"id": "eng", "name": "Engineering", "effect": "allow", "priority": 10,
+		"conditions": [{"type": "user", "field": "department", "operator": "eq", "value": "engineering"}]}`)
+	send("PUT", "/api/v1/abac/policies/eng", `{"name": "Engineering", "effect": "deny", "priority": 10,
+		"conditions": [{"type": "user", "field": "department", "operator": "eq", "value": "sales"}]}`)
+
+	send("POST", "/api/v1/abac/policies/eng/restore", `{"version": 1}`)
+	policy := service.policyEngine.policies["eng"]
+	if policy == nil || policy.Effect != "allow" || len(policy.Conditions) != 1 || policy.Conditions[0].Value != "engineering" {
+		t.Fatalf("Expected the first version to be restored, got %+v", policy)
+	}
+	var count int64
+	service.db.Model(&PolicyCondition{}).Where("policy_id = ?", "eng").Count(&count)
+	if count != 1 {
+		t.Errorf("Expected the restored policy to keep 1 condition, got %d", count)
+	}
+}
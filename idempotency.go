@@ -0,0 +1,245 @@
+// Multi-Model Authorization Microservice - Idempotency Keys
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// idempotencyKeyHeader names the header clients send to make a write safe to retry
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the keys clients may send
+const maxIdempotencyKeyLength = 255
+
+// defaultIdempotencyKeyTTL is how long responses are kept for replay
+const defaultIdempotencyKeyTTL = 24 * time.Hour
+
+// IdempotencyRecord is the response to a write sent with an Idempotency-Key. The record
+// is created before the write runs, so a concurrent retry finds it in progress.
+type IdempotencyRecord struct {
+	ID             uint   `gorm:"primaryKey"`
+	Principal      string `gorm:"uniqueIndex:idx_idempotency_key"` // Client the key belongs to; empty without authentication
+	IdempotencyKey string `gorm:"uniqueIndex:idx_idempotency_key"`
+	Fingerprint    string // Hash of the method, URI, tenant and body of the request
+	Status         int    // Zero while the request is in progress
+	ContentType    string
+	Body           []byte
+	CreatedAt      time.Time `gorm:"index"`
+}
+
+// idempotencyStore keeps the responses of keyed writes in the database, so retries
+// reaching any instance get the original response instead of applying the write again
+type idempotencyStore struct {
+	db  *gorm.DB
+	ttl time.Duration
+}
+
+// idempotencyKeyTTLFromEnv reads IDEMPOTENCY_KEY_TTL, e.g. "24h" or "7d"
+func idempotencyKeyTTLFromEnv() (time.Duration, error) {
+	ttlStr := os.Getenv("IDEMPOTENCY_KEY_TTL")
+	if ttlStr == "" {
+		return defaultIdempotencyKeyTTL, nil
+	}
+	ttl, err := parseRetentionPeriod(ttlStr)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("invalid IDEMPOTENCY_KEY_TTL value: %s", ttlStr)
+	}
+	return ttl, nil
+}
+
+// newIdempotencyStore migrates the idempotency table
+func newIdempotencyStore(db *gorm.DB, ttl time.Duration) (*idempotencyStore, error) {
+	if err := db.AutoMigrate(&IdempotencyRecord{}); err != nil {
+		return nil, fmt.Errorf("failed to migrate idempotency table: %v", err)
+	}
+	return &idempotencyStore{db: db, ttl: ttl}, nil
+}
+
+// reserve records that a keyed request is in progress. It returns nil when the key is new,
+// or has expired, and the existing record otherwise.
+func (store *idempotencyStore) reserve(principal, key, fingerprint string, now time.Time) (*IdempotencyRecord, error) {
+	if err := store.db.Where("principal = ? AND idempotency_key = ? AND created_at < ?", principal, key, now.Add(-store.ttl)).
+		Delete(&IdempotencyRecord{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to expire idempotency key: %v", err)
+	}
+
+	record := IdempotencyRecord{Principal: principal, IdempotencyKey: key, Fingerprint: fingerprint, CreatedAt: now}
+	createErr := store.db.Create(&record).Error
+	if createErr == nil {
+		return nil, nil
+	}
+
+	// The key is taken, by an earlier request or a concurrent one
+	var existing IdempotencyRecord
+	if err := store.db.Where("principal = ? AND idempotency_key = ?", principal, key).First(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %v", createErr)
+	}
+	return &existing, nil
+}
+
+// complete stores the response of a keyed request for replay
+func (store *idempotencyStore) complete(principal, key string, status int, contentType string, body []byte) error {
+	return store.db.Model(&IdempotencyRecord{}).
+		Where("principal = ? AND idempotency_key = ?", principal, key).
+		Updates(map[string]interface{}{"status": status, "content_type": contentType, "body": body}).Error
+}
+
+// release forgets a keyed request whose response is not kept, so a retry runs it again
+func (store *idempotencyStore) release(principal, key string) {
+	if err := store.db.Where("principal = ? AND idempotency_key = ?", principal, key).Delete(&IdempotencyRecord{}).Error; err != nil {
+		log.Printf("Failed to release idempotency key: %v", err)
+	}
+}
+
+// purge deletes the records of expired keys
+func (store *idempotencyStore) purge(now time.Time) (int64, error) {
+	result := store.db.Where("created_at < ?", now.Add(-store.ttl)).Delete(&IdempotencyRecord{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// start purges expired keys in the background
+func (store *idempotencyStore) start() {
+	if store == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if _, err := store.purge(time.Now()); err != nil {
+				log.Printf("Idempotency key purge failed: %v", err)
+			}
+		}
+	}()
+}
+
+// idempotencyFingerprint hashes what identifies a request, so a key reused for another
+// request is detected
+func idempotencyFingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\n%s\n%s\n", r.Method, r.URL.RequestURI(), r.Header.Get(tenantHeader))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// idempotencyRecorder passes a response through while keeping a copy
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *idempotencyRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rec *idempotencyRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// middleware makes POST, PUT, PATCH and DELETE requests carrying an Idempotency-Key safe to
+// retry: the first request runs and its response is kept, retries with the same key and
+// request get that response back with Idempotent-Replayed set. Keys belong to the
+// authenticated client. Server errors are not kept, so their retries run again. A nil
+// store lets every request through.
+func (store *idempotencyStore) middleware(next http.Handler) http.Handler {
+	if store == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("%s must be at most %d bytes", idempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		principal := ""
+		if p := principalFromContext(r.Context()); p != nil {
+			principal = p.Name
+		}
+		fingerprint := idempotencyFingerprint(r, body)
+		existing, err := store.reserve(principal, key, fingerprint, time.Now())
+		switch {
+		case err != nil:
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		case existing == nil:
+		case existing.Fingerprint != fingerprint:
+			writeJSONError(w, http.StatusUnprocessableEntity, fmt.Sprintf("%s was already used for a different request", idempotencyKeyHeader))
+			return
+		case existing.Status == 0:
+			writeJSONError(w, http.StatusConflict, fmt.Sprintf("A request with this %s is still in progress", idempotencyKeyHeader))
+			return
+		default:
+			serviceMetrics.Inc("idempotent_replays_total")
+			if existing.ContentType != "" {
+				w.Header().Set("Content-Type", existing.ContentType)
+			}
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(existing.Status)
+			w.Write(existing.Body)
+			return
+		}
+
+		// A panicking handler leaves no response to keep
+		recorder := &idempotencyRecorder{ResponseWriter: w}
+		kept := false
+		defer func() {
+			if !kept {
+				store.release(principal, key)
+			}
+		}()
+		next.ServeHTTP(recorder, r)
+
+		if recorder.status == 0 {
+			recorder.status = http.StatusOK
+		}
+		if recorder.status >= http.StatusInternalServerError {
+			return
+		}
+		if err := store.complete(principal, key, recorder.status, recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			log.Printf("Failed to store response for idempotency key: %v", err)
+			return
+		}
+		kept = true
+	})
+}
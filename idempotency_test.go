@@ -0,0 +1,70 @@
+// Multi-Model Authorization Microservice - Idempotency Key Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIdempotency_ReplaysRetriedWrites(t *testing.T) {
+	service := setupTestService(t)
+	store, err := newIdempotencyStore(service.db, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to create idempotency store: %v", err)
+	}
+	service.idempotency = store
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/acl/policies", service.addACLPolicyHandler).Methods("POST")
+	router.Use(service.idempotency.middleware)
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/v1/acl/policies", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	policy := `{"subject": "alice", "object": "data1", "action": "read"}`
+
+	first := send("retry-1", policy)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", first.Code, first.Body.String())
+	}
+
+	// A retry gets the original response rather than a duplicate conflict
+	retry := send("retry-1", policy)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() || retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("Expected the original response to be replayed, got %d: %s", retry.Code, retry.Body.String())
+	}
+	if policies, _ := service.aclEnforcer.GetPolicy(); len(policies) != 1 {
+		t.Errorf("Expected the policy to be added once, got %v", policies)
+	}
+
+	if rr := send("", policy); rr.Code != http.StatusConflict {
+		t.Errorf("Expected a write without a key to conflict, got %d", rr.Code)
+	}
+	if rr := send("retry-1", `{"subject": "bob", "object": "data1", "action": "read"}`); rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected a key reused for another request to be rejected, got %d", rr.Code)
+	}
+
+	// A request still in progress makes concurrent retries wait
+	pending, _ := http.NewRequest("POST", "/api/v1/acl/policies", nil)
+	store.reserve("", "retry-2", idempotencyFingerprint(pending, []byte(policy)), time.Now())
+	if rr := send("retry-2", policy); rr.Code != http.StatusConflict {
+		t.Errorf("Expected a retry of a request in progress to conflict, got %d", rr.Code)
+	}
+
+	// Expired keys are forgotten
+	if purged, err := store.purge(time.Now().Add(2 * time.Hour)); err != nil || purged != 2 {
+		t.Errorf("Expected 2 keys to be purged, got %d: %v", purged, err)
+	}
+}
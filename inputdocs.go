@@ -0,0 +1,99 @@
+// Multi-Model Authorization Microservice - ABAC Input Documents
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxInputDepth bounds how deeply the attributes of a request may be nested
+const maxInputDepth = 16
+
+// RequestAttributes are the attributes of an authorization request. Besides flat strings
+// they accept an OPA-style JSON input document: nested objects are flattened into dot
+// paths ({"device": {"os": "ios"}} becomes "device.os"), numbers and booleans become text,
+// and arrays become comma-separated lists like list attributes. Nulls are left out.
+type RequestAttributes map[string]string
+
+// UnmarshalJSON flattens a JSON input document
+func (ra *RequestAttributes) UnmarshalJSON(data []byte) error {
+	var document map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		return err
+	}
+	if document == nil {
+		*ra = nil
+		return nil
+	}
+
+	flattened := make(RequestAttributes, len(document))
+	if err := flattened.add("", document, 0); err != nil {
+		return err
+	}
+	*ra = flattened
+	return nil
+}
+
+// add stores the fields of a JSON object below prefix
+func (ra RequestAttributes) add(prefix string, document map[string]interface{}, depth int) error {
+	if depth >= maxInputDepth {
+		return fmt.Errorf("attributes are nested more than %d levels deep", maxInputDepth)
+	}
+
+	for name, value := range document {
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := ra.add(path, v, depth+1); err != nil {
+				return err
+			}
+			continue
+		case nil:
+			continue
+		case []interface{}:
+			elements := make([]string, 0, len(v))
+			for _, element := range v {
+				formatted, ok := inputScalar(element)
+				if !ok {
+					return fmt.Errorf("attribute %s: arrays may only hold strings, numbers and booleans", path)
+				}
+				elements = append(elements, formatted)
+			}
+			value = strings.Join(elements, ",")
+		}
+
+		formatted, _ := inputScalar(value)
+		// A flat "device.os" and a nested {"device": {"os": ...}} name the same attribute
+		if _, exists := ra[path]; exists {
+			return fmt.Errorf("attribute %s is given twice", path)
+		}
+		ra[path] = formatted
+	}
+	return nil
+}
+
+// inputScalar renders a string, number or boolean of an input document as text
+func inputScalar(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,89 @@
+// Multi-Model Authorization Microservice - ABAC Input Document Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInputDocuments_Flattening(t *testing.T) {
+	var request EnforceRequest
+	body := `{"subject": "alice", "object": "doc1", "action": "read", "attributes": {
+		"ip": "10.0.0.1",
+		"device": {"os": "ios", "version": 17.4, "managed": true, "owner": null},
+		"roles": ["admin", "dev"],
+		"geo": {"country": {"code": "DE"}}
+	}}`
+	if err := json.Unmarshal([]byte(body), &request); err != nil {
+		t.Fatalf("Failed to decode request: %v", err)
+	}
+	expected := map[string]string{
+		"ip":               "10.0.0.1",
+		"device.os":        "ios",
+		"device.version":   "17.4",
+		"device.managed":   "true",
+		"roles":            "admin,dev",
+		"geo.country.code": "DE",
+	}
+	if len(request.Attributes) != len(expected) {
+		t.Errorf("Unexpected attributes %v", request.Attributes)
+	}
+	for name, value := range expected {
+		if request.Attributes[name] != value {
+			t.Errorf("Expected %s=%q, got %q", name, value, request.Attributes[name])
+		}
+	}
+
+	invalid := []string{
+		`{"device.os": "ios", "device": {"os": "android"}}`,
+		`{"devices": [{"os": "ios"}]}`,
+		`{"a":` + strings.Repeat(`{"a":`, maxInputDepth) + `"x"` + strings.Repeat("}", maxInputDepth+1),
+	}
+	for _, document := range invalid {
+		var attributes RequestAttributes
+		if err := json.Unmarshal([]byte(document), &attributes); err == nil {
+			t.Errorf("Expected %s to be rejected", document)
+		}
+	}
+}
+
+func TestInputDocuments_RequestConditions(t *testing.T) {
+	service := setupTestService(t)
+	service.policyEngine.AddPolicy(&ABACPolicy{
+		ID:     "managed-ios",
+		Effect: "allow",
+		Conditions: []PolicyCondition{
+			{Type: "request", Field: "device.os", Operator: "eq", Value: "ios"},
+			{Type: "request", Field: "device.version", Operator: "gte", Value: "17"},
+		},
+	})
+	router := setupTestRouter(service)
+
+	check := func(attributes string) int {
+		body := `{"model": "abac", "subject": "alice", "object": "doc1", "action": "read", "attributes": ` + attributes + `}`
+		req, _ := http.NewRequest("POST", "/api/v1/authorizations", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	if code := check(`{"device": {"os": "ios", "version": 17.4}}`); code != http.StatusOK {
+		t.Errorf("Expected a nested input document to be allowed, got %d", code)
+	}
+	if code := check(`{"device.os": "ios", "device.version": "17"}`); code != http.StatusOK {
+		t.Errorf("Expected flat dot paths to be allowed, got %d", code)
+	}
+	if code := check(`{"device": {"os": "ios", "version": 16}}`); code != http.StatusForbidden {
+		t.Errorf("Expected an old version to be denied, got %d", code)
+	}
+	if code := check(`{"devices": [{"os": "ios"}]}`); code != http.StatusBadRequest {
+		t.Errorf("Expected an array of objects to be rejected, got %d", code)
+	}
+}
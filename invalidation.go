@@ -0,0 +1,300 @@
+// Multi-Model Authorization Microservice - Cross-Instance Invalidation
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// defaultInvalidationChannel is the Redis channel instances exchange changes on
+	defaultInvalidationChannel = "authz-invalidations"
+
+	// invalidationQueueSize bounds the changes waiting to be broadcast; further changes are
+	// dropped and peers catch up through cache TTLs and consistency tokens
+	invalidationQueueSize = 1000
+
+	// changeKindCasbinPolicy is the invalidation kind of policy changes made through a
+	// Casbin enforcer; the key names the enforcer
+	changeKindCasbinPolicy = "casbin_policy"
+)
+
+// invalidationBus carries invalidation messages between the instances sharing a database
+type invalidationBus interface {
+	Publish(message []byte) error
+	Subscribe(handle func(message []byte)) error // Blocks until the bus is closed
+	Close() error
+}
+
+// invalidationMessage announces a change made by one instance to the others. Kinds,
+// actions and keys are those of change events.
+type invalidationMessage struct {
+	Instance string          `json:"instance"`
+	Kind     string          `json:"kind"`
+	Action   string          `json:"action"`
+	Key      string          `json:"key"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// invalidator broadcasts this instance's changes and applies those of its peers. Messages
+// are queued and published by a single worker so broadcasting never blocks a write.
+type invalidator struct {
+	bus       invalidationBus
+	instance  string // Identifies this instance so it ignores its own messages
+	queue     chan *invalidationMessage
+	enforcers map[string]*revisionWatcher // Watchers of the Casbin enforcers by model
+}
+
+// redisInvalidationBus is an invalidation bus on Redis pub/sub
+type redisInvalidationBus struct {
+	client  *redis.Client
+	channel string
+}
+
+// Publish implements invalidationBus
+func (b *redisInvalidationBus) Publish(message []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return b.client.Publish(ctx, b.channel, message).Err()
+}
+
+// Subscribe implements invalidationBus. The client reconnects on its own, so messages
+// are only lost while Redis is unreachable.
+func (b *redisInvalidationBus) Subscribe(handle func(message []byte)) error {
+	subscription := b.client.Subscribe(context.Background(), b.channel)
+	defer subscription.Close()
+	for message := range subscription.Channel() {
+		handle([]byte(message.Payload))
+	}
+	return nil
+}
+
+// Close implements invalidationBus
+func (b *redisInvalidationBus) Close() error {
+	return b.client.Close()
+}
+
+// invalidationBusFromEnv connects to the Redis server in INVALIDATION_REDIS_URL (e.g.
+// "redis://localhost:6379/0") on the channel in INVALIDATION_CHANNEL. It returns nil when
+// no URL is configured, i.e. for single-instance deployments.
+func invalidationBusFromEnv() (invalidationBus, error) {
+	redisURL := os.Getenv("INVALIDATION_REDIS_URL")
+	if redisURL == "" {
+		return nil, nil
+	}
+	options, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid INVALIDATION_REDIS_URL value: %v", err)
+	}
+
+	channel := os.Getenv("INVALIDATION_CHANNEL")
+	if channel == "" {
+		channel = defaultInvalidationChannel
+	}
+	return &redisInvalidationBus{client: redis.NewClient(options), channel: channel}, nil
+}
+
+// startInvalidation broadcasts this instance's changes on bus and applies the changes
+// other instances broadcast
+func (s *AuthService) startInvalidation(bus invalidationBus) error {
+	inv := &invalidator{
+		bus:      bus,
+		instance: newRequestID(),
+		queue:    make(chan *invalidationMessage, invalidationQueueSize),
+	}
+	s.invalidator = inv
+	if err := s.watchEnforcers(); err != nil {
+		return err
+	}
+
+	go func() {
+		for message := range inv.queue {
+			payload, err := json.Marshal(message)
+			if err == nil {
+				err = inv.bus.Publish(payload)
+			}
+			if err != nil {
+				serviceMetrics.Inc("invalidations_failed_total")
+				log.Printf("Failed to broadcast %s.%s invalidation for %s: %v", message.Kind, message.Action, message.Key, err)
+				continue
+			}
+			serviceMetrics.Inc("invalidations_sent_total")
+		}
+	}()
+
+	go func() {
+		err := inv.bus.Subscribe(func(payload []byte) {
+			var message invalidationMessage
+			if err := json.Unmarshal(payload, &message); err != nil {
+				log.Printf("Ignoring malformed invalidation: %v", err)
+				return
+			}
+			if message.Instance == inv.instance {
+				return
+			}
+			if err := s.applyInvalidation(&message); err != nil {
+				serviceMetrics.Inc("invalidations_failed_total")
+				log.Printf("Failed to apply %s.%s invalidation for %s: %v", message.Kind, message.Action, message.Key, err)
+				return
+			}
+			serviceMetrics.Inc("invalidations_applied_total")
+		})
+		if err != nil {
+			log.Printf("Invalidation subscription ended: %v", err)
+		}
+	}()
+	return nil
+}
+
+// watchEnforcers sets Casbin watchers on the ACL, RBAC and ABAC enforcers that broadcast
+// their policy changes and reload the policies other instances changed. An enforcer has a
+// single watcher, so the decision cache's watchers are reused and keep counting revisions.
+func (s *AuthService) watchEnforcers() error {
+	enforcers := map[string]*casbin.SyncedEnforcer{
+		string(ModelACL):  s.aclEnforcer,
+		string(ModelRBAC): s.rbacEnforcer,
+		string(ModelABAC): s.abacEnforcer,
+	}
+
+	s.invalidator.enforcers = make(map[string]*revisionWatcher, len(enforcers))
+	for name, enforcer := range enforcers {
+		if enforcer == nil {
+			continue
+		}
+		watcher := &revisionWatcher{}
+		if s.decisions != nil && name == string(ModelACL) {
+			watcher = s.decisions.acl
+		} else if s.decisions != nil && name == string(ModelRBAC) {
+			watcher = s.decisions.rbac
+		}
+
+		if err := enforcer.SetWatcher(watcher); err != nil {
+			return fmt.Errorf("failed to watch %s policies: %v", name, err)
+		}
+		// Replace Casbin's default callback, which discards reload errors
+		watcher.SetUpdateCallback(func(string) {
+			// Conditions are saved before the RBAC rules they belong to
+			if name == string(ModelRBAC) && s.ruleConditions != nil {
+				if err := s.ruleConditions.load(s.db); err != nil {
+					log.Printf("Failed to reload RBAC rule conditions: %v", err)
+				}
+			}
+			if err := enforcer.LoadPolicy(); err != nil {
+				log.Printf("Failed to reload %s policies: %v", name, err)
+			}
+		})
+		watcher.mu.Lock()
+		watcher.notify = func() { s.broadcastInvalidation(changeKindCasbinPolicy, changeUpdated, name, nil) }
+		watcher.mu.Unlock()
+		s.invalidator.enforcers[name] = watcher
+	}
+	return nil
+}
+
+// broadcastInvalidation queues a change for the other instances. It is a no-op without an
+// invalidation bus.
+func (s *AuthService) broadcastInvalidation(kind, action, key string, data interface{}) {
+	if s.invalidator == nil {
+		return
+	}
+
+	message := &invalidationMessage{Instance: s.invalidator.instance, Kind: kind, Action: action, Key: key}
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("Failed to encode %s invalidation for %s: %v", kind, key, err)
+			return
+		}
+		message.Data = encoded
+	}
+
+	select {
+	case s.invalidator.queue <- message:
+	default:
+		serviceMetrics.Inc("invalidations_dropped_total")
+		log.Printf("Invalidation queue full, dropping %s.%s for %s", kind, action, key)
+	}
+}
+
+// applyInvalidation brings the in-memory state touched by a peer's change up to date with
+// the database. Changes are re-read rather than replayed, so the order messages arrive in
+// does not matter. Casbin enforcers reload all their policies.
+func (s *AuthService) applyInvalidation(message *invalidationMessage) error {
+	switch message.Kind {
+	case changeKindCasbinPolicy:
+		if watcher := s.invalidator.enforcers[message.Key]; watcher != nil {
+			watcher.reload()
+		}
+		return nil
+
+	case changeKindRelationship:
+		var change LabeledRelationship
+		if err := json.Unmarshal(message.Data, &change); err != nil {
+			return fmt.Errorf("invalid relationship data: %v", err)
+		}
+		if err := s.relationshipGraph.refreshTuple(change.Subject, change.Relationship.Relationship, change.Object); err != nil {
+			return err
+		}
+		// The peer logged the change in the shared change log
+		s.watchers.notify()
+		return nil
+
+	case changeKindNamespace:
+		return s.relationshipGraph.reloadNamespaces()
+
+	case changeKindAction:
+		return s.relationshipGraph.reloadActions()
+
+	case changeKindActionImplication:
+		return s.actionHierarchy.load(s.db)
+
+	case changeKindABACPolicy:
+		return s.policyEngine.LoadPolicies()
+
+	case changeKindUserAttribute, changeKindObjectAttribute:
+		var change map[string]string
+		if err := json.Unmarshal(message.Data, &change); err != nil {
+			return fmt.Errorf("invalid attribute data: %v", err)
+		}
+		if message.Kind == changeKindUserAttribute {
+			s.invalidateUserAttributes(change["user"])
+		} else {
+			s.invalidateObjectAttributes(change["object"])
+		}
+		return s.attributeTypes.load(s.db)
+	}
+	return nil
+}
+
+// refreshTuple makes the in-memory graph agree with the database about one tuple after
+// another instance added or removed it
+func (rg *RelationshipGraph) refreshTuple(subject, relationship, object string) error {
+	unlock := rg.writeLock()
+	defer unlock()
+
+	var records []RelationshipRecord
+	if err := rg.db.Where("subject = ? AND relationship = ? AND object = ?", subject, relationship, object).
+		Find(&records).Error; err != nil {
+		return fmt.Errorf("failed to read relationship: %v", err)
+	}
+
+	// Drop every in-memory copy first, so expiries and caveats are tracked afresh
+	rg.forget(subject, relationship, object)
+	now := time.Now()
+	for _, record := range records {
+		if !relationshipExpired(record, now) {
+			rg.applyAdded(subject, relationship, object, record.ExpiresAt, record.Caveat)
+		}
+	}
+	return nil
+}
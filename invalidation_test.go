@@ -0,0 +1,240 @@
+// Multi-Model Authorization Microservice - Cross-Instance Invalidation Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// memoryInvalidationBus delivers every message to all subscribers in the process
+type memoryInvalidationBus struct {
+	mu       sync.Mutex
+	handlers []func([]byte)
+	closed   chan struct{}
+}
+
+func newMemoryInvalidationBus() *memoryInvalidationBus {
+	return &memoryInvalidationBus{closed: make(chan struct{})}
+}
+
+func (b *memoryInvalidationBus) Publish(message []byte) error {
+	b.mu.Lock()
+	handlers := append([]func([]byte){}, b.handlers...)
+	b.mu.Unlock()
+	for _, handle := range handlers {
+		handle(message)
+	}
+	return nil
+}
+
+func (b *memoryInvalidationBus) Subscribe(handle func([]byte)) error {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, handle)
+	b.mu.Unlock()
+	<-b.closed
+	return nil
+}
+
+func (b *memoryInvalidationBus) Close() error {
+	close(b.closed)
+	return nil
+}
+
+// waitUntil polls condition until it holds or a second has passed
+func waitUntil(t *testing.T, description string, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Fatalf("Timed out waiting until %s", description)
+}
+
+func TestInvalidation_PeersFollowWrites(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/abac/policies", service.addABACPolicyHandler).Methods("POST")
+
+	// A second instance sharing the database
+	graph, err := NewRelationshipGraph(service.db)
+	if err != nil {
+		t.Fatalf("Failed to create peer graph: %v", err)
+	}
+	peer := &AuthService{
+		db:                service.db,
+		relationshipGraph: graph,
+		policyEngine:      NewPolicyEngine(service.db),
+		userAttrs:         newAttributeCache("user", defaultAttributeCacheSize, defaultAttributeCacheTTL),
+		objectAttrs:       newAttributeCache("object", defaultAttributeCacheSize, defaultAttributeCacheTTL),
+	}
+
+	bus := newMemoryInvalidationBus()
+	defer bus.Close()
+	service.startInvalidation(bus)
+	peer.startInvalidation(bus)
+	waitUntil(t, "both instances subscribed", func() bool {
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+		return len(bus.handlers) == 2
+	})
+
+	send := func(method, path, body string) {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%s %s failed: %d %s", method, path, rr.Code, rr.Body.String())
+		}
+	}
+	peerAllows := func() bool {
+		allowed, _ := peer.relationshipGraph.CheckReBACAccess("alice", "doc1", "read")
+		return allowed
+	}
+
+	send("POST", "/api/v1/relationships", `{"subject": "alice", "relationship": "owner", "object": "doc1"}`)
+	waitUntil(t, "the peer sees the new tuple", peerAllows)
+
+	send("DELETE", "/api/v1/relationships/alice:owner:doc1", "")
+	waitUntil(t, "the peer drops the removed tuple", func() bool { return !peerAllows() })
+
+	// The peer keeps checking while reloads replace its policies
+	stop := make(chan struct{})
+	checking := make(chan struct{})
+	go func() {
+		defer close(checking)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				peer.policyEngine.Decide(&PolicyEvaluationContext{Action: "read"})
+			}
+		}
+	}()
+	send("POST", "/api/v1/abac/policies", `{"id": "p1", "name": "P1", "effect": "allow",
+		"conditions": [{"type": "user", "field": "department", "operator": "eq", "value": "eng"}]}`)
+	waitUntil(t, "the peer loads the new policy", func() bool {
+		_, exists := peer.policyEngine.getPolicy("p1")
+		return exists
+	})
+	close(stop)
+	<-checking
+
+	// The peer's cached attributes are dropped when another instance changes them
+	peer.userAttrs.get("bob", peer.getUserAttributesFromDB)
+	service.saveUserAttribute("bob", "department", "eng")
+	service.publishChange(changeKindUserAttribute, changeUpdated, "bob.department", attributeChange("user", "bob", "department", "eng"), "")
+	waitUntil(t, "the peer drops cached attributes", func() bool {
+		return peer.userAttrs.Stats().Entries == 0
+	})
+	if attributes, _ := peer.userAttrs.get("bob", peer.getUserAttributesFromDB); attributes["department"] != "eng" {
+		t.Errorf("Expected the peer to read the new attributes, got %v", attributes)
+	}
+}
+
+func TestInvalidation_CasbinWatcherReloadsPeers(t *testing.T) {
+	service := setupTestService(t)
+	if err := service.enableDecisionCache(100, time.Minute); err != nil {
+		t.Fatalf("Failed to enable decision cache: %v", err)
+	}
+
+	// A second instance with its own ACL enforcer on the shared database
+	peer := newACLInstance(t, service.db)
+
+	bus := newMemoryInvalidationBus()
+	defer bus.Close()
+	if err := service.startInvalidation(bus); err != nil {
+		t.Fatalf("Failed to start invalidation: %v", err)
+	}
+	if err := peer.startInvalidation(bus); err != nil {
+		t.Fatalf("Failed to start peer invalidation: %v", err)
+	}
+	waitUntil(t, "both instances subscribed", func() bool {
+		bus.mu.Lock()
+		defer bus.mu.Unlock()
+		return len(bus.handlers) == 2
+	})
+
+	// The decision cache keeps counting changes through the shared watcher
+	before := service.decisions.acl.Revision()
+	if _, err := addRule(service.aclEnforcer, "alice", "doc1", "read", effectAllow); err != nil {
+		t.Fatalf("Failed to add rule: %v", err)
+	}
+	if service.decisions.acl.Revision() != before+1 {
+		t.Error("Expected the decision cache to see the local change")
+	}
+
+	waitUntil(t, "the peer reloads its ACL policies", func() bool {
+		allowed, _, _ := enforceRule(peer.aclEnforcer, "alice", "doc1", "read")
+		return allowed
+	})
+	if peer.invalidator.enforcers["acl"].Revision() == 0 {
+		t.Error("Expected the peer's revision to advance on reload")
+	}
+}
+
+// newACLInstance returns an instance with its own ACL enforcer on a shared database
+func newACLInstance(t *testing.T, db *gorm.DB) *AuthService {
+	adapter, err := gormadapter.NewAdapterByDBUseTableName(db, "", "acl_rules")
+	if err != nil {
+		t.Fatalf("Failed to create adapter: %v", err)
+	}
+	aclModelObj, _ := model.NewModelFromString(aclModel)
+	enforcer, err := casbin.NewSyncedEnforcer(aclModelObj, adapter)
+	if err != nil {
+		t.Fatalf("Failed to create enforcer: %v", err)
+	}
+	registerACLFunctions(enforcer)
+	instance := &AuthService{db: db, aclEnforcer: enforcer}
+	instance.addImpliedActionFunction(enforcer)
+	return instance
+}
+
+func TestInvalidation_WritesKeepPeersRules(t *testing.T) {
+	// A database file, as every connection to an in-memory database sees a database of its own
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "authz.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	if err := db.AutoMigrate(&PolicyMetadata{}, &PolicyRevision{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	// The second instance has not reloaded since the first one's write
+	first, second := newACLInstance(t, db), newACLInstance(t, db)
+	add := func(instance *AuthService, body string) {
+		req, _ := http.NewRequest("POST", "/api/v1/acl/policies", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		instance.addACLPolicyHandler(rr, req)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("Failed to add rule: %d %s", rr.Code, rr.Body.String())
+		}
+	}
+	add(first, `{"subject": "alice", "object": "doc1", "action": "read"}`)
+	add(second, `{"subject": "bob", "object": "doc2", "action": "read"}`)
+
+	// Both rules are stored, so the second write did not wipe the first
+	reloaded := newACLInstance(t, db)
+	for _, rule := range [][]string{{"alice", "doc1", "read"}, {"bob", "doc2", "read"}} {
+		if allowed, _, _ := enforceRule(reloaded.aclEnforcer, rule[0], rule[1], rule[2]); !allowed {
+			t.Errorf("Expected %v to survive the other instance's write", rule)
+		}
+	}
+}
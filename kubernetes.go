@@ -0,0 +1,207 @@
+// Multi-Model Authorization Microservice - Kubernetes Authorization Webhook
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+)
+
+// The Kubernetes API server can delegate authorization to a webhook: it posts a
+// SubjectAccessReview for every request and reads the decision from its status. Resource
+// requests are checked as "[namespaces/<namespace>/]<resource>[.<group>][/<subresource>]"
+// with the verb as action, so a role may be granted "namespaces/dev/deployments.apps" or
+// "nodes"; non-resource requests are checked on their path, e.g. "/healthz". The user's
+// groups act like RBAC roles.
+
+// SubjectAccessReview is the request and response body of the Kubernetes authorization
+// webhook (authorization.k8s.io v1 and v1beta1)
+type SubjectAccessReview struct {
+	APIVersion string                    `json:"apiVersion"`
+	Kind       string                    `json:"kind"`
+	Spec       SubjectAccessReviewSpec   `json:"spec"`
+	Status     SubjectAccessReviewStatus `json:"status"`
+}
+
+// SubjectAccessReviewSpec describes the request being authorized
+type SubjectAccessReviewSpec struct {
+	ResourceAttributes    *KubernetesResourceAttributes    `json:"resourceAttributes,omitempty"`
+	NonResourceAttributes *KubernetesNonResourceAttributes `json:"nonResourceAttributes,omitempty"`
+	User                  string                           `json:"user,omitempty"`
+	Groups                []string                         `json:"groups,omitempty"`
+	LegacyGroups          []string                         `json:"group,omitempty"` // v1beta1 name of groups
+	UID                   string                           `json:"uid,omitempty"`
+	Extra                 map[string][]string              `json:"extra,omitempty"`
+}
+
+// KubernetesResourceAttributes describe a request on an API resource
+type KubernetesResourceAttributes struct {
+	Namespace   string `json:"namespace,omitempty"`
+	Verb        string `json:"verb,omitempty"`
+	Group       string `json:"group,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Resource    string `json:"resource,omitempty"`
+	Subresource string `json:"subresource,omitempty"`
+	Name        string `json:"name,omitempty"`
+}
+
+// KubernetesNonResourceAttributes describe a request on a non-resource path
+type KubernetesNonResourceAttributes struct {
+	Path string `json:"path,omitempty"`
+	Verb string `json:"verb,omitempty"`
+}
+
+// SubjectAccessReviewStatus is the decision. A request that is neither allowed nor denied
+// is left to the API server's other authorizers.
+type SubjectAccessReviewStatus struct {
+	Allowed         bool   `json:"allowed"`
+	Denied          bool   `json:"denied,omitempty"`
+	Reason          string `json:"reason,omitempty"`
+	EvaluationError string `json:"evaluationError,omitempty"`
+}
+
+// kubernetesWebhook configures the Kubernetes authorization webhook
+type kubernetesWebhook struct {
+	model         AccessControlModel // Model requests are checked with
+	authoritative bool               // Deny what is not allowed instead of leaving it to other authorizers
+}
+
+// kubernetesWebhookFromEnv reads K8S_AUTHZ_MODEL and K8S_AUTHZ_AUTHORITATIVE
+func kubernetesWebhookFromEnv() (kubernetesWebhook, error) {
+	config := kubernetesWebhook{model: ModelRBAC}
+	if model := os.Getenv("K8S_AUTHZ_MODEL"); model != "" {
+		config.model = AccessControlModel(model)
+		switch config.model {
+		case ModelACL, ModelRBAC, ModelABAC, ModelReBAC:
+		default:
+			return config, fmt.Errorf("invalid K8S_AUTHZ_MODEL value: %s", model)
+		}
+	}
+	if authoritative := os.Getenv("K8S_AUTHZ_AUTHORITATIVE"); authoritative != "" {
+		config.authoritative = authoritative == "true"
+	}
+	return config, nil
+}
+
+// object returns the object and action a review is checked as
+func (spec *SubjectAccessReviewSpec) object() (string, string, error) {
+	if attrs := spec.ResourceAttributes; attrs != nil {
+		if attrs.Resource == "" || attrs.Verb == "" {
+			return "", "", fmt.Errorf("resourceAttributes need a resource and a verb")
+		}
+		object := attrs.Resource
+		if attrs.Group != "" {
+			object += "." + attrs.Group
+		}
+		if attrs.Subresource != "" {
+			object += "/" + attrs.Subresource
+		}
+		if attrs.Namespace != "" {
+			object = "namespaces/" + attrs.Namespace + "/" + object
+		}
+		return object, attrs.Verb, nil
+	}
+	if attrs := spec.NonResourceAttributes; attrs != nil && attrs.Path != "" && attrs.Verb != "" {
+		return attrs.Path, attrs.Verb, nil
+	}
+	return "", "", fmt.Errorf("resourceAttributes or nonResourceAttributes are required")
+}
+
+// attributes returns the request attributes of a review for ABAC: the resource fields,
+// the user's groups and uid, and its extra values as extra.<key>
+func (spec *SubjectAccessReviewSpec) attributes() map[string]string {
+	attributes := make(map[string]string)
+	set := func(name, value string) {
+		if value != "" {
+			attributes[name] = value
+		}
+	}
+	if attrs := spec.ResourceAttributes; attrs != nil {
+		set("namespace", attrs.Namespace)
+		set("verb", attrs.Verb)
+		set("group", attrs.Group)
+		set("version", attrs.Version)
+		set("resource", attrs.Resource)
+		set("subresource", attrs.Subresource)
+		set("name", attrs.Name)
+	} else if attrs := spec.NonResourceAttributes; attrs != nil {
+		set("path", attrs.Path)
+		set("verb", attrs.Verb)
+	}
+	set("groups", strings.Join(spec.groups(), ","))
+	set("uid", spec.UID)
+	for key, values := range spec.Extra {
+		set("extra."+key, strings.Join(values, ","))
+	}
+	return attributes
+}
+
+// groups returns the user's groups under either API version's name, sorted
+func (spec *SubjectAccessReviewSpec) groups() []string {
+	groups := append(append([]string(nil), spec.Groups...), spec.LegacyGroups...)
+	sort.Strings(groups)
+	return groups
+}
+
+// subjectAccessReviewHandler answers the Kubernetes authorization webhook. The review is
+// returned with its status filled in; evaluation errors are reported in the status, so the
+// API server applies its failure policy.
+func (s *AuthService) subjectAccessReviewHandler(w http.ResponseWriter, r *http.Request) {
+	var review SubjectAccessReview
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if review.Kind != "SubjectAccessReview" || (review.APIVersion != "authorization.k8s.io/v1" && review.APIVersion != "authorization.k8s.io/v1beta1") {
+		writeJSONError(w, http.StatusBadRequest, "expected an authorization.k8s.io/v1 SubjectAccessReview")
+		return
+	}
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	review.Status = SubjectAccessReviewStatus{}
+	object, action, err := review.Spec.object()
+	switch {
+	case err != nil:
+		review.Status.EvaluationError = err.Error()
+	case review.Spec.User == "":
+		review.Status.EvaluationError = "user is required"
+	default:
+		config := s.kubernetesWebhook
+		token := &verifiedToken{subject: review.Spec.User, roles: review.Spec.groups()}
+		attributes := review.Spec.attributes()
+
+		allowed, grantedBy, err := s.EnforceToken(scope, config.model, token, object, action, attributes)
+		if err != nil {
+			review.Status.EvaluationError = err.Error()
+			break
+		}
+		subject, qualifiedObject := scope.qualify(token.subject), scope.qualify(object)
+		s.recordDecision(requestIDFromContext(r.Context()), config.model, subject, qualifiedObject, action, attributes, allowed)
+		s.traceDecision(requestIDFromContext(r.Context()), config.model, subject, qualifiedObject, action, attributes, allowed)
+
+		review.Status.Allowed = allowed
+		switch {
+		case allowed:
+			review.Status.Reason = fmt.Sprintf("%s may %s %s (granted to %s)", token.subject, action, object, grantedBy)
+		case config.authoritative:
+			review.Status.Denied = true
+			review.Status.Reason = fmt.Sprintf("%s may not %s %s", token.subject, action, object)
+		default:
+			review.Status.Reason = fmt.Sprintf("no rule allows %s to %s %s", token.subject, action, object)
+		}
+	}
+	serviceMetrics.Inc("kubernetes_reviews_total")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(review)
+}
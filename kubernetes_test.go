@@ -0,0 +1,68 @@
+// Multi-Model Authorization Microservice - Kubernetes Authorization Webhook Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestKubernetesWebhook_SubjectAccessReview(t *testing.T) {
+	service := setupTestService(t)
+	service.kubernetesWebhook = kubernetesWebhook{model: ModelRBAC}
+	service.rbacEnforcer.AddPolicy("system:masters", "nodes", "list", "allow")
+	service.rbacEnforcer.AddPolicy("dev-team", "namespaces/dev/deployments.apps", "update", "allow")
+	service.rbacEnforcer.AddPolicy("jane", "namespaces/dev/pods/log", "get", "allow")
+	service.rbacEnforcer.AddPolicy("ops", "/metrics", "get", "allow")
+	router := mux.NewRouter()
+	service.registerEnforcementRoutes(router.PathPrefix("/api/v1").Subrouter())
+
+	review := func(body string) (int, SubjectAccessReview) {
+		req, _ := http.NewRequest("POST", "/api/v1/kubernetes/subjectaccessreviews", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var response SubjectAccessReview
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr.Code, response
+	}
+	const prefix = `{"apiVersion": "authorization.k8s.io/v1", "kind": "SubjectAccessReview", "spec": `
+
+	// Groups act like roles, and API groups qualify the resource
+	code, response := review(prefix + `{"user": "jane", "groups": ["dev-team"], "resourceAttributes": {"namespace": "dev", "verb": "update", "group": "apps", "version": "v1", "resource": "deployments", "name": "web"}}}`)
+	if code != http.StatusOK || !response.Status.Allowed || response.Kind != "SubjectAccessReview" {
+		t.Errorf("Expected jane to update deployments through dev-team, got %d %+v", code, response)
+	}
+	if _, response := review(prefix + `{"user": "jane", "groups": ["dev-team"], "resourceAttributes": {"namespace": "prod", "verb": "update", "group": "apps", "resource": "deployments"}}}`); response.Status.Allowed || response.Status.Denied {
+		t.Errorf("Expected no opinion on another namespace, got %+v", response.Status)
+	}
+	if _, response := review(prefix + `{"user": "jane", "resourceAttributes": {"namespace": "dev", "verb": "get", "resource": "pods", "subresource": "log"}}}`); !response.Status.Allowed {
+		t.Errorf("Expected jane to read pod logs, got %+v", response.Status)
+	}
+	if _, response := review(prefix + `{"user": "admin", "groups": ["system:masters"], "resourceAttributes": {"verb": "list", "resource": "nodes"}}}`); !response.Status.Allowed {
+		t.Errorf("Expected cluster-scoped resources without a namespace, got %+v", response.Status)
+	}
+	if _, response := review(`{"apiVersion": "authorization.k8s.io/v1beta1", "kind": "SubjectAccessReview", "spec": {"user": "bob", "group": ["ops"], "nonResourceAttributes": {"path": "/metrics", "verb": "get"}}}`); !response.Status.Allowed {
+		t.Errorf("Expected v1beta1 groups and non-resource paths, got %+v", response.Status)
+	}
+
+	// Authoritative webhooks deny what they do not allow
+	service.kubernetesWebhook.authoritative = true
+	if _, response := review(prefix + `{"user": "jane", "resourceAttributes": {"namespace": "dev", "verb": "delete", "resource": "pods"}}}`); response.Status.Allowed || !response.Status.Denied {
+		t.Errorf("Expected an authoritative denial, got %+v", response.Status)
+	}
+
+	// Incomplete reviews are answered with an evaluation error
+	if _, response := review(prefix + `{"user": "jane"}}`); response.Status.EvaluationError == "" || response.Status.Allowed {
+		t.Errorf("Expected an evaluation error, got %+v", response.Status)
+	}
+	if code, _ := review(`{"apiVersion": "v1", "kind": "Pod"}`); code != http.StatusBadRequest {
+		t.Errorf("Expected other kinds to be rejected, got %d", code)
+	}
+}
@@ -0,0 +1,508 @@
+// Multi-Model Authorization Microservice - Policy and Tuple Labels
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Label kinds identify which type of authorization data a label is attached to
+const (
+	labelKindACL           = "acl"
+	labelKindRBAC          = "rbac"
+	labelKindRole          = "role"
+	labelKindResourceGroup = "resource_group"
+	labelKindABAC          = "abac"
+	labelKindRelationship  = "relationship"
+)
+
+// ResourceLabel represents a label attached to a policy, role assignment or relationship tuple
+type ResourceLabel struct {
+	ID          uint   `gorm:"primaryKey"`
+	Kind        string `gorm:"index"`
+	ResourceKey string `gorm:"index"`
+	Label       string `gorm:"index"`
+	CreatedAt   time.Time
+}
+
+// labelKeyEscaper escapes the separator and the escape character inside key parts
+var labelKeyEscaper = strings.NewReplacer(`\`, `\\`, ":", `\:`)
+
+// labelKey builds the resource key used to attach labels (e.g. "alice:document1:read").
+// Colons and backslashes inside parts are escaped with a backslash, so the keys of
+// ("a:b", "c", "d") and ("a", "b:c", "d") differ.
+func labelKey(parts ...string) string {
+	escaped := make([]string, len(parts))
+	for i, part := range parts {
+		escaped[i] = labelKeyEscaper.Replace(part)
+	}
+	return strings.Join(escaped, ":")
+}
+
+// splitLabelKey returns the parts of a key built by labelKey
+func splitLabelKey(key string) []string {
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(key); i++ {
+		switch {
+		case key[i] == '\\' && i+1 < len(key):
+			i++
+			part.WriteByte(key[i])
+		case key[i] == ':':
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(key[i])
+		}
+	}
+	return append(parts, part.String())
+}
+
+// normalizeLabels trims labels and drops empty and duplicate entries
+func normalizeLabels(labels []string) []string {
+	seen := make(map[string]bool)
+	var normalized []string
+	for _, label := range labels {
+		label = strings.TrimSpace(label)
+		if label == "" || seen[label] {
+			continue
+		}
+		seen[label] = true
+		normalized = append(normalized, label)
+	}
+	return normalized
+}
+
+// setLabels replaces the labels attached to a resource
+func (s *AuthService) setLabels(kind, key string, labels []string) error {
+	if err := s.db.Where("kind = ? AND resource_key = ?", kind, key).Delete(&ResourceLabel{}).Error; err != nil {
+		return fmt.Errorf("failed to clear labels: %v", err)
+	}
+
+	for _, label := range normalizeLabels(labels) {
+		record := ResourceLabel{Kind: kind, ResourceKey: key, Label: label}
+		if err := s.db.Create(&record).Error; err != nil {
+			return fmt.Errorf("failed to save label: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// removeLabels drops all labels attached to a resource
+func (s *AuthService) removeLabels(kind, key string) error {
+	if err := s.db.Where("kind = ? AND resource_key = ?", kind, key).Delete(&ResourceLabel{}).Error; err != nil {
+		return fmt.Errorf("failed to remove labels: %v", err)
+	}
+	return nil
+}
+
+// labelsByKey returns the labels of every resource of a kind, keyed by resource key
+func (s *AuthService) labelsByKey(kind string) (map[string][]string, error) {
+	var records []ResourceLabel
+	if err := s.db.Where("kind = ?", kind).Order("id").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load labels: %v", err)
+	}
+
+	labels := make(map[string][]string)
+	for _, record := range records {
+		labels[record.ResourceKey] = append(labels[record.ResourceKey], record.Label)
+	}
+	return labels, nil
+}
+
+// hasLabel reports whether a label is present in the list
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// filterRulesByLabel keeps the Casbin rules of a kind that carry the label
+func (s *AuthService) filterRulesByLabel(kind string, rules [][]string, label string) ([][]string, error) {
+	if label == "" {
+		return rules, nil
+	}
+
+	labels, err := s.labelsByKey(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([][]string, 0)
+	for _, rule := range rules {
+		if hasLabel(labels[ruleKey(rule)], label) {
+			filtered = append(filtered, rule)
+		}
+	}
+	return filtered, nil
+}
+
+// filterRelationshipsByLabel keeps the relationships that carry the label
+func (s *AuthService) filterRelationshipsByLabel(relationships []Relationship, label string) ([]Relationship, error) {
+	if label == "" {
+		return relationships, nil
+	}
+
+	labels, err := s.labelsByKey(labelKindRelationship)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]Relationship, 0)
+	for _, rel := range relationships {
+		if hasLabel(labels[labelKey(rel.Subject, rel.Relationship, rel.Object)], label) {
+			filtered = append(filtered, rel)
+		}
+	}
+	return filtered, nil
+}
+
+// LabeledRule is an exported ACL/RBAC rule or role assignment with its labels
+type LabeledRule struct {
+	Values   []string        `json:"values"`
+	Labels   []string        `json:"labels,omitempty"`
+	Metadata *PolicyMetadata `json:"metadata,omitempty"`
+}
+
+// LabeledRelationship is an exported relationship tuple with its labels
+type LabeledRelationship struct {
+	Relationship
+	Labels    []string            `json:"labels,omitempty"`
+	ExpiresAt *time.Time          `json:"expires_at,omitempty"`
+	Caveat    *RelationshipCaveat `json:"caveat,omitempty"`
+	Metadata  *TupleMetadata      `json:"metadata,omitempty"` // Who wrote the tuple, for writes and change events
+}
+
+// PolicyExport is a snapshot of authorization data across all models
+type PolicyExport struct {
+	Label              string                `json:"label,omitempty"`
+	ACL                []LabeledRule         `json:"acl"`
+	RBACPolicies       []LabeledRule         `json:"rbac_policies"`
+	RBACRoles          []LabeledRule         `json:"rbac_roles"`
+	RBACResourceGroups []LabeledRule         `json:"rbac_resource_groups"`
+	ABACPolicies       []*ABACPolicy         `json:"abac_policies"`
+	Relationships      []LabeledRelationship `json:"relationships"`
+
+	// Attributes carry no labels, so they are only part of unfiltered exports
+	UserAttributes   map[string]map[string]string `json:"user_attributes,omitempty"`
+	ObjectAttributes map[string]map[string]string `json:"object_attributes,omitempty"`
+}
+
+// exportRules converts Casbin rules into labeled rules with their provenance, keeping only those with the label if set
+func (s *AuthService) exportRules(kind string, rules [][]string, label string) ([]LabeledRule, error) {
+	labels, err := s.labelsByKey(kind)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := s.metadataByKey(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	exported := make([]LabeledRule, 0)
+	for _, rule := range rules {
+		key := ruleKey(rule)
+		ruleLabels := labels[key]
+		if label != "" && !hasLabel(ruleLabels, label) {
+			continue
+		}
+		exported = append(exported, LabeledRule{Values: rule, Labels: ruleLabels, Metadata: metadata[key]})
+	}
+	return exported, nil
+}
+
+// ExportPolicies builds a snapshot of all authorization data, optionally restricted to a label
+func (s *AuthService) ExportPolicies(label string) (*PolicyExport, error) {
+	export := &PolicyExport{Label: label}
+
+	aclRules, err := s.aclEnforcer.GetPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ACL policies: %v", err)
+	}
+	if export.ACL, err = s.exportRules(labelKindACL, aclRules, label); err != nil {
+		return nil, err
+	}
+
+	rbacRules, err := s.rbacEnforcer.GetPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RBAC policies: %v", err)
+	}
+	if export.RBACPolicies, err = s.exportRules(labelKindRBAC, rbacRules, label); err != nil {
+		return nil, err
+	}
+
+	roleRules, err := s.rbacEnforcer.GetGroupingPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RBAC roles: %v", err)
+	}
+	if export.RBACRoles, err = s.exportRules(labelKindRole, roleRules, label); err != nil {
+		return nil, err
+	}
+
+	resourceGroupRules, err := s.rbacEnforcer.GetNamedGroupingPolicy("g2")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RBAC resource groups: %v", err)
+	}
+	if export.RBACResourceGroups, err = s.exportRules(labelKindResourceGroup, resourceGroupRules, label); err != nil {
+		return nil, err
+	}
+
+	abacLabels, err := s.labelsByKey(labelKindABAC)
+	if err != nil {
+		return nil, err
+	}
+	export.ABACPolicies = make([]*ABACPolicy, 0)
+	for _, policy := range s.policyEngine.policyList() {
+		// Label a copy, the engine's policies are shared with concurrent checks
+		labeled := *policy
+		labeled.Labels = abacLabels[policy.ID]
+		if label != "" && !hasLabel(labeled.Labels, label) {
+			continue
+		}
+		export.ABACPolicies = append(export.ABACPolicies, &labeled)
+	}
+	sort.Slice(export.ABACPolicies, func(i, j int) bool {
+		return export.ABACPolicies[i].ID < export.ABACPolicies[j].ID
+	})
+
+	relationships, err := s.relationshipGraph.ListRelationships("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relationships: %v", err)
+	}
+	relLabels, err := s.labelsByKey(labelKindRelationship)
+	if err != nil {
+		return nil, err
+	}
+	expirations, err := s.relationshipGraph.RelationshipExpirations("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relationship expirations: %v", err)
+	}
+	caveats, err := s.relationshipGraph.RelationshipCaveats("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relationship caveats: %v", err)
+	}
+	export.Relationships = make([]LabeledRelationship, 0)
+	for _, rel := range relationships {
+		key := labelKey(rel.Subject, rel.Relationship, rel.Object)
+		tupleLabels := relLabels[key]
+		if label != "" && !hasLabel(tupleLabels, label) {
+			continue
+		}
+		exported := LabeledRelationship{Relationship: rel, Labels: tupleLabels}
+		if expiresAt, expiring := expirations[key]; expiring {
+			exported.ExpiresAt = &expiresAt
+		}
+		exported.Caveat = caveats[key]
+		export.Relationships = append(export.Relationships, exported)
+	}
+
+	if label == "" {
+		var userAttrs []UserAttribute
+		if err := s.db.Find(&userAttrs).Error; err != nil {
+			return nil, fmt.Errorf("failed to read user attributes: %v", err)
+		}
+		export.UserAttributes = make(map[string]map[string]string)
+		for _, attr := range userAttrs {
+			if export.UserAttributes[attr.UserID] == nil {
+				export.UserAttributes[attr.UserID] = make(map[string]string)
+			}
+			export.UserAttributes[attr.UserID][attr.Attribute] = attr.Value
+		}
+
+		var objectAttrs []ObjectAttribute
+		if err := s.db.Find(&objectAttrs).Error; err != nil {
+			return nil, fmt.Errorf("failed to read object attributes: %v", err)
+		}
+		export.ObjectAttributes = make(map[string]map[string]string)
+		for _, attr := range objectAttrs {
+			if export.ObjectAttributes[attr.ObjectID] == nil {
+				export.ObjectAttributes[attr.ObjectID] = make(map[string]string)
+			}
+			export.ObjectAttributes[attr.ObjectID][attr.Attribute] = attr.Value
+		}
+	}
+
+	return export, nil
+}
+
+// exportHandler exports authorization data across all models, optionally filtered by label.
+// format=csv with model=acl|rbac returns the rules of one model as a Casbin policy file.
+func (s *AuthService) exportHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	model := AccessControlModel(query.Get("model"))
+	if query.Get("format") == "csv" && model != ModelACL && model != ModelRBAC {
+		writeJSONError(w, http.StatusBadRequest, "CSV format supports the acl and rbac models")
+		return
+	}
+
+	export, err := s.ExportPolicies(query.Get("label"))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Export error: %v", err))
+		return
+	}
+
+	if query.Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		writeCasbinCSV(w, export, model)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(export)
+}
+
+// getLabelsHandler lists all labels in use and how many resources of each kind carry them
+func (s *AuthService) getLabelsHandler(w http.ResponseWriter, r *http.Request) {
+	var records []ResourceLabel
+	if err := s.db.Find(&records).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve labels: %v", err))
+		return
+	}
+
+	counts := make(map[string]map[string]int)
+	for _, record := range records {
+		if counts[record.Label] == nil {
+			counts[record.Label] = make(map[string]int)
+		}
+		counts[record.Label][record.Kind]++
+	}
+
+	response := map[string]interface{}{
+		"labels": counts,
+		"count":  len(counts),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// resourceKeyParts is how many parts the resource keys of each label kind have
+var resourceKeyParts = map[string]int{
+	labelKindACL:           3,
+	labelKindRBAC:          3,
+	labelKindRole:          2,
+	labelKindResourceGroup: 2,
+	labelKindRelationship:  3,
+}
+
+// resourceKeyTables are the tables storing resource keys, with the label kind of each
+// kind stored in them. Policy revisions are stored by change kind.
+var resourceKeyTables = []struct {
+	model  interface{}
+	column string
+	kinds  map[string]string
+}{
+	{&ResourceLabel{}, "resource_key", map[string]string{
+		labelKindACL: labelKindACL, labelKindRBAC: labelKindRBAC, labelKindRole: labelKindRole,
+		labelKindResourceGroup: labelKindResourceGroup, labelKindRelationship: labelKindRelationship,
+	}},
+	{&PolicyMetadata{}, "resource_key", map[string]string{
+		labelKindACL: labelKindACL, labelKindRBAC: labelKindRBAC, labelKindRole: labelKindRole,
+		labelKindResourceGroup: labelKindResourceGroup,
+	}},
+	{&RuleCondition{}, "resource_key", map[string]string{labelKindRBAC: labelKindRBAC}},
+	{&PolicyRevision{}, "policy_key", map[string]string{
+		changeKindACL: labelKindACL, changeKindRBACPolicy: labelKindRBAC, changeKindRBACRole: labelKindRole,
+		changeKindRBACResourceGroup: labelKindResourceGroup,
+	}},
+}
+
+// migrateResourceKeys rewrites the resource keys stored before labelKey escaped colons
+// inside parts, such as those of tuples on "document:readme". An old key with more colons
+// than its kind has parts is ambiguous, so it is matched against the current rules, role
+// assignments and tuples; keys of resources that no longer exist are left alone.
+func (s *AuthService) migrateResourceKeys() error {
+	stale := make(map[string]map[string]bool) // Old keys by label kind
+	for _, table := range resourceKeyTables {
+		for kind, labelKind := range table.kinds {
+			var keys []string
+			pattern := "%" + strings.Repeat(":%", resourceKeyParts[labelKind])
+			if err := s.db.Model(table.model).Where("kind = ? AND "+table.column+" LIKE ?", kind, pattern).
+				Distinct().Pluck(table.column, &keys).Error; err != nil {
+				return fmt.Errorf("failed to read resource keys: %v", err)
+			}
+			for _, key := range keys {
+				if strings.Contains(key, `\`) {
+					continue // Already escaped
+				}
+				if stale[labelKind] == nil {
+					stale[labelKind] = make(map[string]bool)
+				}
+				stale[labelKind][key] = true
+			}
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	rekeyed := make(map[string]map[string]string) // Escaped key by old key and label kind
+	rekey := func(labelKind string, parts []string) {
+		if old := strings.Join(parts, ":"); stale[labelKind][old] {
+			if rekeyed[labelKind] == nil {
+				rekeyed[labelKind] = make(map[string]string)
+			}
+			rekeyed[labelKind][old] = labelKey(parts...)
+		}
+	}
+	rules := []struct {
+		labelKind string
+		read      func() ([][]string, error)
+		parts     int
+	}{
+		{labelKindACL, s.aclEnforcer.GetPolicy, 3},
+		{labelKindRBAC, s.rbacEnforcer.GetPolicy, 3},
+		{labelKindRole, s.rbacEnforcer.GetGroupingPolicy, 2},
+		{labelKindResourceGroup, func() ([][]string, error) { return s.rbacEnforcer.GetNamedGroupingPolicy("g2") }, 2},
+	}
+	for _, kind := range rules {
+		if stale[kind.labelKind] == nil {
+			continue
+		}
+		current, err := kind.read()
+		if err != nil {
+			return fmt.Errorf("failed to read %s rules: %v", kind.labelKind, err)
+		}
+		for _, rule := range current {
+			if len(rule) >= kind.parts {
+				rekey(kind.labelKind, rule[:kind.parts])
+			}
+		}
+	}
+	if stale[labelKindRelationship] != nil {
+		var records []RelationshipRecord
+		if err := s.db.Select("subject", "relationship", "object").Find(&records).Error; err != nil {
+			return fmt.Errorf("failed to read relationships: %v", err)
+		}
+		for _, record := range records {
+			rekey(labelKindRelationship, []string{record.Subject, record.Relationship, record.Object})
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range resourceKeyTables {
+			for kind, labelKind := range table.kinds {
+				for old, key := range rekeyed[labelKind] {
+					if err := tx.Model(table.model).Where("kind = ? AND "+table.column+" = ?", kind, old).
+						Update(table.column, key).Error; err != nil {
+						return fmt.Errorf("failed to migrate resource key %s: %v", old, err)
+					}
+				}
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,143 @@
+// Multi-Model Authorization Microservice - Label Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupLabelRouter(service *AuthService) http.Handler {
+	router := setupTestRouter(service)
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/acl/policies", service.addACLPolicyHandler).Methods("POST")
+	api.HandleFunc("/acl/policies", service.getACLPoliciesHandler).Methods("GET")
+	api.HandleFunc("/acl/policies/{id}", service.deleteACLPolicyHandler).Methods("DELETE")
+	api.HandleFunc("/export", service.exportHandler).Methods("GET")
+	api.HandleFunc("/labels", service.getLabelsHandler).Methods("GET")
+	return router
+}
+
+func TestLabels_FilterAndExport(t *testing.T) {
+	service := setupTestService(t)
+	router := setupLabelRouter(service)
+
+	post := func(path string, body interface{}) int {
+		reqBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", path, bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := post("/api/v1/acl/policies", PolicyRequest{Subject: "alice", Object: "invoice1", Action: "read", Labels: []string{"app:billing"}}); code != http.StatusCreated {
+		t.Fatalf("Failed to add labeled ACL policy: %d", code)
+	}
+	if code := post("/api/v1/acl/policies", PolicyRequest{Subject: "bob", Object: "ticket1", Action: "read", Labels: []string{"app:support"}}); code != http.StatusCreated {
+		t.Fatalf("Failed to add labeled ACL policy: %d", code)
+	}
+	if code := post("/api/v1/relationships", map[string]interface{}{
+		"subject": "alice", "relationship": "owner", "object": "invoice2", "labels": []string{"app:billing", " app:billing "},
+	}); code != http.StatusOK {
+		t.Fatalf("Failed to add labeled relationship: %d", code)
+	}
+	service.relationshipGraph.AddRelationship("bob", "owner", "ticket2")
+
+	// List endpoints filter by label
+	req, _ := http.NewRequest("GET", "/api/v1/acl/policies?label=app:billing", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var aclResponse struct {
+		Policies [][]string `json:"policies"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &aclResponse)
+	if len(aclResponse.Policies) != 1 || aclResponse.Policies[0][0] != "alice" {
+		t.Errorf("Expected only alice's billing policy, got %v", aclResponse.Policies)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/relationships?label=app:billing", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var relResponse struct {
+		Relationships []Relationship `json:"relationships"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &relResponse)
+	if len(relResponse.Relationships) != 1 || relResponse.Relationships[0].Object != "invoice2" {
+		t.Errorf("Expected only the billing relationship, got %v", relResponse.Relationships)
+	}
+
+	// Export restricted to a label
+	req, _ = http.NewRequest("GET", "/api/v1/export?label=app:billing", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var export PolicyExport
+	if err := json.Unmarshal(rr.Body.Bytes(), &export); err != nil {
+		t.Fatalf("Failed to decode export: %v", err)
+	}
+	if len(export.ACL) != 1 || len(export.Relationships) != 1 || len(export.RBACPolicies) != 0 {
+		t.Errorf("Unexpected filtered export: %+v", export)
+	}
+	if len(export.Relationships) == 1 && len(export.Relationships[0].Labels) != 1 {
+		t.Errorf("Expected duplicate labels to be collapsed, got %v", export.Relationships[0].Labels)
+	}
+
+	// Unfiltered export includes everything
+	all, err := service.ExportPolicies("")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(all.ACL) != 2 || len(all.Relationships) != 2 {
+		t.Errorf("Expected full export, got %d ACL rules and %d relationships", len(all.ACL), len(all.Relationships))
+	}
+
+	// Deleting a policy drops its labels
+	req, _ = http.NewRequest("DELETE", "/api/v1/acl/policies/alice:invoice1:read", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Failed to delete policy: %d", rr.Code)
+	}
+	labels, _ := service.labelsByKey(labelKindACL)
+	if len(labels["alice:invoice1:read"]) != 0 {
+		t.Error("Expected labels to be removed with the policy")
+	}
+}
+
+func TestLabels_KeysDoNotCollide(t *testing.T) {
+	if labelKey("a:b", "c", "d") == labelKey("a", "b:c", "d") {
+		t.Error("Expected keys of different resources to differ")
+	}
+	for _, parts := range [][]string{{"alice", "viewer", "document:readme"}, {`a\`, ":b", ""}} {
+		key := labelKey(parts...)
+		if split := splitLabelKey(key); len(split) != len(parts) || split[0] != parts[0] || split[1] != parts[1] || split[2] != parts[2] {
+			t.Errorf("Expected %q to split into %q, got %q", key, parts, split)
+		}
+	}
+}
+
+func TestLabels_MigrateResourceKeys(t *testing.T) {
+	service := setupTestService(t)
+	service.relationshipGraph.AddRelationship("alice", "viewer", "document:readme")
+	service.db.Create(&ResourceLabel{Kind: labelKindRelationship, ResourceKey: "alice:viewer:document:readme", Label: "docs"})
+	service.db.Create(&ResourceLabel{Kind: labelKindRelationship, ResourceKey: "bob:viewer:document:gone", Label: "docs"})
+
+	if err := service.migrateResourceKeys(); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	labels, _ := service.labelsByKey(labelKindRelationship)
+	if !hasLabel(labels[labelKey("alice", "viewer", "document:readme")], "docs") {
+		t.Errorf("Expected the label to move to the escaped key, got %v", labels)
+	}
+	if !hasLabel(labels["bob:viewer:document:gone"], "docs") {
+		t.Errorf("Expected the key of a removed tuple to stay, got %v", labels)
+	}
+	if err := service.migrateResourceKeys(); err != nil {
+		t.Fatalf("Repeated migration failed: %v", err)
+	}
+}
@@ -0,0 +1,495 @@
+// Multi-Model Authorization Microservice - LDAP Group Sync
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	// defaultLDAPSyncInterval is how often groups are pulled from the directory
+	defaultLDAPSyncInterval = 15 * time.Minute
+
+	// ldapSyncTimeout bounds one pull from the directory
+	ldapSyncTimeout = 2 * time.Minute
+
+	// ldapSyncPageSize is the page size of group searches, below the 1000 entries Active
+	// Directory returns at most per page
+	ldapSyncPageSize = 500
+
+	// ldapSyncActor is recorded as the actor of changes made by the sync
+	ldapSyncActor = "ldap-sync"
+
+	// Kinds of assignments the sync manages
+	ldapSyncKindRole         = "role"
+	ldapSyncKindRelationship = "relationship"
+)
+
+// GroupDirectory lists the groups of a directory with the user IDs of their members
+type GroupDirectory interface {
+	Groups(ctx context.Context) (map[string][]string, error)
+}
+
+// ldapGroupDirectory reads groups and their members from LDAP or Active Directory. Members
+// are resolved to the user attribute of their entry; nested groups are not expanded.
+type ldapGroupDirectory struct {
+	url             string
+	bindDN          string
+	bindPassword    string
+	baseDN          string
+	groupFilter     string // Search filter selecting the groups to sync
+	groupAttribute  string // Attribute naming a group, e.g. cn
+	memberAttribute string // Attribute listing the DNs of group members, e.g. member
+	userAttribute   string // Attribute holding the user ID of a member, e.g. uid or sAMAccountName
+}
+
+// Groups searches the directory for the configured groups
+func (ld *ldapGroupDirectory) Groups(ctx context.Context) (map[string][]string, error) {
+	timeout := ldapSyncTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	conn, err := ldap.DialURL(ld.url, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP: %v", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(timeout)
+
+	if ld.bindDN != "" {
+		if err := conn.Bind(ld.bindDN, ld.bindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP bind failed: %v", err)
+		}
+	}
+
+	search := ldap.NewSearchRequest(ld.baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(timeout.Seconds()), false,
+		ld.groupFilter, []string{ld.groupAttribute, ld.memberAttribute}, nil)
+	result, err := conn.SearchWithPaging(search, ldapSyncPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP group search failed: %v", err)
+	}
+
+	// Members usually belong to several groups, so each DN is resolved once
+	users := make(map[string]string)
+	groups := make(map[string][]string, len(result.Entries))
+	for _, entry := range result.Entries {
+		name := entry.GetAttributeValue(ld.groupAttribute)
+		if name == "" {
+			continue
+		}
+		members := []string{}
+		for _, dn := range entry.GetAttributeValues(ld.memberAttribute) {
+			user, ok := users[dn]
+			if !ok {
+				if user, err = ld.memberID(conn, dn, timeout); err != nil {
+					return nil, err
+				}
+				users[dn] = user
+			}
+			if user != "" {
+				members = append(members, user)
+			}
+		}
+		groups[name] = members
+	}
+	return groups, nil
+}
+
+// memberID returns the user ID of a member DN, or "" when the entry has none, e.g. because
+// it is a nested group. The ID is taken from the DN when its first component is the user
+// attribute and read from the entry otherwise.
+func (ld *ldapGroupDirectory) memberID(conn *ldap.Conn, dn string, timeout time.Duration) (string, error) {
+	if parsed, err := ldap.ParseDN(dn); err == nil && len(parsed.RDNs) > 0 {
+		for _, attribute := range parsed.RDNs[0].Attributes {
+			if strings.EqualFold(attribute.Type, ld.userAttribute) {
+				return attribute.Value, nil
+			}
+		}
+	}
+
+	search := ldap.NewSearchRequest(dn, ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, int(timeout.Seconds()), false,
+		"(objectClass=*)", []string{ld.userAttribute}, nil)
+	result, err := conn.Search(search)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("LDAP lookup of member %s failed: %v", dn, err)
+	}
+	if len(result.Entries) == 0 {
+		return "", nil
+	}
+	return result.Entries[0].GetAttributeValue(ld.userAttribute), nil
+}
+
+// LDAPSyncAssignment records a role assignment or member tuple made by the LDAP sync. Only
+// recorded assignments are removed when a user leaves a group, so assignments made through
+// the API are never touched by the sync.
+type LDAPSyncAssignment struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Kind      string    `json:"kind" gorm:"uniqueIndex:idx_ldap_sync_assignment"`
+	UserID    string    `json:"user" gorm:"uniqueIndex:idx_ldap_sync_assignment"`
+	GroupName string    `json:"group" gorm:"uniqueIndex:idx_ldap_sync_assignment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ldapSyncKey identifies an assignment of the sync
+type ldapSyncKey struct {
+	kind, user, group string
+}
+
+// LDAPSyncChange is an assignment added or removed by a sync run
+type LDAPSyncChange struct {
+	Kind  string `json:"kind"`
+	User  string `json:"user"`
+	Group string `json:"group"`
+}
+
+// LDAPSyncRun summarizes one reconciliation of directory groups
+type LDAPSyncRun struct {
+	StartedAt  time.Time        `json:"started_at"`
+	DryRun     bool             `json:"dry_run"`
+	Groups     int              `json:"groups"`
+	Added      []LDAPSyncChange `json:"added"`
+	Removed    []LDAPSyncChange `json:"removed"`
+	Skipped    []string         `json:"skipped,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	DurationMS int64            `json:"duration_ms"`
+}
+
+// ldapSync periodically reconciles directory groups into RBAC roles and member tuples
+type ldapSync struct {
+	directory GroupDirectory
+	interval  time.Duration // How often the sync runs in the background
+	dryRun    bool          // Report changes without applying them
+	tuples    bool          // Also maintain ReBAC member tuples
+	target    string        // Human-readable directory location
+
+	mu      sync.Mutex // Serializes runs and guards lastRun
+	lastRun *LDAPSyncRun
+}
+
+// ldapSyncFromEnv configures the group sync from the LDAP_SYNC_* settings. The sync is
+// disabled (nil) unless LDAP_SYNC_URL is set.
+func ldapSyncFromEnv() (*ldapSync, error) {
+	url := os.Getenv("LDAP_SYNC_URL")
+	if url == "" {
+		return nil, nil
+	}
+	baseDN := os.Getenv("LDAP_SYNC_BASE_DN")
+	if baseDN == "" {
+		return nil, fmt.Errorf("LDAP_SYNC_BASE_DN is required with LDAP_SYNC_URL")
+	}
+
+	setting := func(name, fallback string) string {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+		return fallback
+	}
+	directory := &ldapGroupDirectory{
+		url:             url,
+		bindDN:          os.Getenv("LDAP_SYNC_BIND_DN"),
+		bindPassword:    os.Getenv("LDAP_SYNC_BIND_PASSWORD"),
+		baseDN:          baseDN,
+		groupFilter:     setting("LDAP_SYNC_GROUP_FILTER", "(|(objectClass=groupOfNames)(objectClass=group))"),
+		groupAttribute:  setting("LDAP_SYNC_GROUP_ATTRIBUTE", "cn"),
+		memberAttribute: setting("LDAP_SYNC_MEMBER_ATTRIBUTE", "member"),
+		userAttribute:   setting("LDAP_SYNC_USER_ATTRIBUTE", "uid"),
+	}
+
+	job := &ldapSync{
+		directory: directory,
+		interval:  defaultLDAPSyncInterval,
+		dryRun:    os.Getenv("LDAP_SYNC_DRY_RUN") == "true",
+		tuples:    os.Getenv("LDAP_SYNC_TUPLES") == "true",
+		target:    url + "/" + baseDN,
+	}
+	if intervalStr := os.Getenv("LDAP_SYNC_INTERVAL"); intervalStr != "" {
+		interval, err := time.ParseDuration(intervalStr)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid LDAP_SYNC_INTERVAL value: %s", intervalStr)
+		}
+		job.interval = interval
+	}
+	return job, nil
+}
+
+// ldapSyncHeld reports whether an assignment currently exists
+func (s *AuthService) ldapSyncHeld(key ldapSyncKey) (bool, error) {
+	if key.kind == ldapSyncKindRelationship {
+		return s.relationshipGraph.HasDirectRelationship(key.user, groupMemberRelationship, key.group), nil
+	}
+	return s.rbacEnforcer.HasRoleForUser(key.user, key.group)
+}
+
+// SyncLDAPGroups reconciles the directory groups into role assignments, and member tuples
+// when enabled. Members gain the role named after each of their groups; assignments the
+// sync made earlier are removed once the user left the group. A dry run only reports the
+// changes. A directory listing no groups at all while synced assignments exist is treated
+// as a misconfiguration rather than removing every assignment.
+func (s *AuthService) SyncLDAPGroups(ctx context.Context, dryRun bool) (*LDAPSyncRun, error) {
+	job := s.ldapSync
+	if job == nil {
+		return nil, fmt.Errorf("LDAP group sync is not configured")
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	start := time.Now()
+	run := &LDAPSyncRun{StartedAt: start, DryRun: dryRun, Added: []LDAPSyncChange{}, Removed: []LDAPSyncChange{}}
+	defer func() {
+		run.DurationMS = time.Since(start).Milliseconds()
+		job.lastRun = run
+	}()
+	fail := func(format string, args ...interface{}) (*LDAPSyncRun, error) {
+		run.Error = fmt.Sprintf(format, args...)
+		return run, errors.New(run.Error)
+	}
+
+	groups, err := job.directory.Groups(ctx)
+	if err != nil {
+		return fail("%v", err)
+	}
+	run.Groups = len(groups)
+
+	kinds := []string{ldapSyncKindRole}
+	if job.tuples {
+		kinds = append(kinds, ldapSyncKindRelationship)
+	}
+	valid := func(name string) bool {
+		var v validator
+		v.identifier("name", name)
+		return v.err() == nil
+	}
+	desired := make(map[ldapSyncKey]bool)
+	for group, members := range groups {
+		if !valid(group) {
+			run.Skipped = append(run.Skipped, group)
+			continue
+		}
+		for _, user := range members {
+			if !valid(user) {
+				run.Skipped = append(run.Skipped, group+"/"+user)
+				continue
+			}
+			for _, kind := range kinds {
+				desired[ldapSyncKey{kind, user, group}] = true
+			}
+		}
+	}
+	sort.Strings(run.Skipped)
+
+	var records []LDAPSyncAssignment
+	if err := s.db.Find(&records).Error; err != nil {
+		return fail("failed to load synced assignments: %v", err)
+	}
+	managed := make(map[ldapSyncKey]LDAPSyncAssignment, len(records))
+	for _, record := range records {
+		managed[ldapSyncKey{record.Kind, record.UserID, record.GroupName}] = record
+	}
+	if len(groups) == 0 && len(managed) > 0 {
+		return fail("the directory returned no groups; refusing to remove %d synced assignments", len(managed))
+	}
+
+	for _, key := range sortedLDAPSyncKeys(desired) {
+		if dryRun {
+			held, err := s.ldapSyncHeld(key)
+			if err != nil {
+				return fail("%v", err)
+			}
+			if !held {
+				run.Added = append(run.Added, LDAPSyncChange{key.kind, key.user, key.group})
+			}
+			continue
+		}
+
+		var added bool
+		if key.kind == ldapSyncKindRelationship {
+			added, err = s.addGroupMemberTuple(key.user, key.group, ldapSyncActor)
+		} else {
+			added, err = s.assignGroupRole(key.user, key.group, ldapSyncActor)
+		}
+		if err != nil {
+			return fail("%v", err)
+		}
+		// Assignments that already existed stay owned by whoever made them
+		if !added {
+			continue
+		}
+		run.Added = append(run.Added, LDAPSyncChange{key.kind, key.user, key.group})
+		if _, ok := managed[key]; !ok {
+			record := LDAPSyncAssignment{Kind: key.kind, UserID: key.user, GroupName: key.group}
+			if err := s.db.Create(&record).Error; err != nil {
+				return fail("failed to record synced assignment: %v", err)
+			}
+		}
+	}
+
+	stale := make(map[ldapSyncKey]bool)
+	for key := range managed {
+		if !desired[key] {
+			stale[key] = true
+		}
+	}
+	for _, key := range sortedLDAPSyncKeys(stale) {
+		if dryRun {
+			held, err := s.ldapSyncHeld(key)
+			if err != nil {
+				return fail("%v", err)
+			}
+			if held {
+				run.Removed = append(run.Removed, LDAPSyncChange{key.kind, key.user, key.group})
+			}
+			continue
+		}
+
+		var removed bool
+		if key.kind == ldapSyncKindRelationship {
+			removed, err = s.removeGroupMemberTuple(key.user, key.group, ldapSyncActor)
+		} else {
+			removed, err = s.unassignGroupRole(key.user, key.group, ldapSyncActor)
+		}
+		if err != nil {
+			return fail("%v", err)
+		}
+		if removed {
+			run.Removed = append(run.Removed, LDAPSyncChange{key.kind, key.user, key.group})
+		}
+		if err := s.db.Delete(&LDAPSyncAssignment{}, managed[key].ID).Error; err != nil {
+			return fail("failed to delete synced assignment: %v", err)
+		}
+	}
+	return run, nil
+}
+
+// sortedLDAPSyncKeys orders assignments by group, user and kind for stable reports
+func sortedLDAPSyncKeys(set map[ldapSyncKey]bool) []ldapSyncKey {
+	keys := make([]ldapSyncKey, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].group != keys[j].group {
+			return keys[i].group < keys[j].group
+		}
+		if keys[i].user != keys[j].user {
+			return keys[i].user < keys[j].user
+		}
+		return keys[i].kind < keys[j].kind
+	})
+	return keys
+}
+
+// startLDAPSync syncs directory groups in the background at the configured interval
+func (s *AuthService) startLDAPSync() {
+	if s.ldapSync == nil {
+		return
+	}
+
+	// Background runs cannot be held, so they only report what an approved run would apply
+	dryRun := s.ldapSync.dryRun
+	if s.changeApproval && !dryRun {
+		log.Printf("LDAP group sync runs as a dry run while changes need approval; apply it with POST /api/v1/ldap/sync/run")
+		dryRun = true
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.ldapSync.interval)
+		defer ticker.Stop()
+		for {
+			ctx, cancel := context.WithTimeout(context.Background(), ldapSyncTimeout)
+			run, err := s.SyncLDAPGroups(ctx, dryRun)
+			cancel()
+			if err != nil {
+				log.Printf("LDAP group sync failed: %v", err)
+			} else if len(run.Added) > 0 || len(run.Removed) > 0 {
+				verb := "applied"
+				if run.DryRun {
+					verb = "would apply (dry run)"
+				}
+				log.Printf("LDAP group sync %s %d additions and %d removals across %d groups", verb, len(run.Added), len(run.Removed), run.Groups)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// getLDAPSyncStatusHandler reports the sync configuration, how many assignments the sync
+// manages and the outcome of the last run
+func (s *AuthService) getLDAPSyncStatusHandler(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{
+		"enabled": s.ldapSync != nil,
+	}
+
+	if job := s.ldapSync; job != nil {
+		var managed int64
+		if err := s.db.Model(&LDAPSyncAssignment{}).Count(&managed).Error; err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to count synced assignments: %v", err))
+			return
+		}
+
+		job.mu.Lock()
+		lastRun := job.lastRun
+		job.mu.Unlock()
+
+		response["directory"] = job.target
+		response["interval"] = job.interval.String()
+		response["dry_run"] = job.dryRun
+		response["tuples"] = job.tuples
+		response["managed_assignments"] = managed
+		response["last_run"] = lastRun
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// runLDAPSyncHandler syncs immediately instead of waiting for the next interval.
+// ?dry_run=true or false overrides the configured mode for this run.
+func (s *AuthService) runLDAPSyncHandler(w http.ResponseWriter, r *http.Request) {
+	if s.ldapSync == nil {
+		writeJSONError(w, http.StatusConflict, "LDAP group sync is not configured (set LDAP_SYNC_URL)")
+		return
+	}
+
+	dryRun := s.ldapSync.dryRun
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "dry_run must be true or false")
+			return
+		}
+		dryRun = parsed
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), ldapSyncTimeout)
+	defer cancel()
+	run, err := s.SyncLDAPGroups(ctx, dryRun)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(run)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
@@ -0,0 +1,113 @@
+// Multi-Model Authorization Microservice - LDAP Group Sync Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// staticDirectory is a GroupDirectory returning fixed groups
+type staticDirectory struct {
+	groups map[string][]string
+}
+
+func (sd *staticDirectory) Groups(ctx context.Context) (map[string][]string, error) {
+	return sd.groups, nil
+}
+
+func TestLDAPSync_ReconcilesGroups(t *testing.T) {
+	service := setupTestService(t)
+	directory := &staticDirectory{groups: map[string][]string{
+		"engineering": {"alice", "bob"},
+		"ops":         {"bob"},
+	}}
+	service.ldapSync = &ldapSync{directory: directory, tuples: true}
+	hasRole := func(user, role string) bool {
+		held, _ := service.rbacEnforcer.HasRoleForUser(user, role)
+		return held
+	}
+
+	// A role granted through the API is not owned by the sync
+	service.rbacEnforcer.AddRoleForUser("bob", "ops")
+
+	run, err := service.SyncLDAPGroups(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if len(run.Added) != 5 || hasRole("alice", "engineering") {
+		t.Fatalf("Expected the dry run to report 5 additions without applying them, got %+v", run.Added)
+	}
+
+	if _, err := service.SyncLDAPGroups(context.Background(), false); err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if !hasRole("alice", "engineering") || !hasRole("bob", "engineering") ||
+		!service.relationshipGraph.HasDirectRelationship("bob", groupMemberRelationship, "ops") {
+		t.Fatal("Expected the sync to assign the group roles and member tuples")
+	}
+
+	// Leaving groups removes the synced assignments but keeps the one made through the API
+	directory.groups = map[string][]string{"engineering": {"bob"}, "ops": {}}
+	run, err = service.SyncLDAPGroups(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if hasRole("alice", "engineering") || service.relationshipGraph.HasDirectRelationship("bob", groupMemberRelationship, "ops") {
+		t.Errorf("Expected the departed members to lose the synced assignments, removed %+v", run.Removed)
+	}
+	if !hasRole("bob", "ops") {
+		t.Error("Expected the role granted through the API to remain")
+	}
+
+	// An empty directory does not wipe every synced assignment
+	directory.groups = map[string][]string{}
+	if _, err := service.SyncLDAPGroups(context.Background(), false); err == nil {
+		t.Error("Expected an empty directory to be refused")
+	}
+	if !hasRole("bob", "engineering") {
+		t.Error("Expected the refused sync to keep the assignments")
+	}
+}
+
+func TestLDAPSync_Handlers(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/ldap/sync", service.getLDAPSyncStatusHandler).Methods("GET")
+	router.HandleFunc("/api/v1/ldap/sync/run", service.runLDAPSyncHandler).Methods("POST")
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send("POST", "/api/v1/ldap/sync/run"); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 without a configured sync, got %d", rr.Code)
+	}
+
+	service.ldapSync = &ldapSync{directory: &staticDirectory{groups: map[string][]string{"engineering": {"alice"}}}}
+	if rr := send("POST", "/api/v1/ldap/sync/run?dry_run=maybe"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid dry_run, got %d", rr.Code)
+	}
+	rr := send("POST", "/api/v1/ldap/sync/run")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	var status struct {
+		Enabled bool         `json:"enabled"`
+		Managed int          `json:"managed_assignments"`
+		LastRun *LDAPSyncRun `json:"last_run"`
+	}
+	json.Unmarshal(send("GET", "/api/v1/ldap/sync").Body.Bytes(), &status)
+	if !status.Enabled || status.Managed != 1 || status.LastRun == nil || len(status.LastRun.Added) != 1 {
+		t.Errorf("Expected the status to report the synced assignment, got %+v", status)
+	}
+}
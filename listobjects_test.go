@@ -0,0 +1,107 @@
+// Multi-Model Authorization Microservice - ReBAC List-Objects Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestListObjects_AgreesWithEnforcement(t *testing.T) {
+	service := setupTestService(t)
+	rg := service.relationshipGraph
+
+	tuples := [][3]string{
+		{"alice", "owner", "doc1"},
+		{"alice", "member", "engineering"},
+		{"engineering", "viewer", "doc2"},
+		{"alice", "editor", "folder1"},
+		{"folder1", "parent", "doc3"},
+		{"doc3", "parent", "doc4"},
+		{"alice", "friend", "bob"},
+		{"bob", "owner", "photo1"},
+		{"carol", "owner", "doc5"},
+		{"engineering", "member", "company"},
+		{"company", "viewer", "handbook"},
+	}
+	nodes := map[string]bool{}
+	for _, tuple := range tuples {
+		rg.AddRelationship(tuple[0], tuple[1], tuple[2])
+		nodes[tuple[0]] = true
+		nodes[tuple[2]] = true
+	}
+
+	var objects []string
+	for node := range nodes {
+		objects = append(objects, node)
+	}
+	sort.Strings(objects)
+
+	for _, action := range []string{"read", "write", "delete", "view", "read_limited"} {
+		var expected []string
+		for _, object := range objects {
+			if object == "alice" {
+				continue
+			}
+			if allowed, _ := rg.CheckReBACAccess("alice", object, action); allowed {
+				expected = append(expected, object)
+			}
+		}
+
+		var listed []string
+		for _, accessible := range rg.ListAccessibleObjects("alice", action) {
+			listed = append(listed, accessible.Object)
+			if len(accessible.Hops) == 0 || accessible.Path == "" {
+				t.Errorf("Expected a granting path for %s", accessible.Object)
+			}
+		}
+		if !reflect.DeepEqual(listed, expected) {
+			t.Errorf("%s: listed %v, enforcement allows %v", action, listed, expected)
+		}
+	}
+
+	var written []string
+	for _, accessible := range rg.ListAccessibleObjects("alice", "write") {
+		written = append(written, accessible.Object)
+	}
+	if !reflect.DeepEqual(written, []string{"doc1", "doc3", "doc4", "folder1"}) {
+		t.Errorf("Expected alice to write her own and inherited documents, got %v", written)
+	}
+}
+
+func TestListObjects_Handler(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/subjects/{subject}/objects", service.listSubjectObjectsHandler).Methods("GET")
+
+	service.relationshipGraph.AddRelationship("alice", "viewer", "doc1")
+	service.relationshipGraph.AddRelationship("alice", "owner", "doc2")
+
+	req, _ := http.NewRequest("GET", "/api/v1/subjects/alice/objects?permission=delete", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var response struct {
+		Objects []AccessibleObject `json:"objects"`
+		Count   int                `json:"count"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if response.Count != 1 || response.Objects[0].Object != "doc2" || response.Objects[0].Path != "alice -[owner]-> doc2" {
+		t.Errorf("Expected only doc2 to be deletable, got %s", rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/subjects/alice/objects", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a permission, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,298 @@
+// Multi-Model Authorization Microservice - Who-Has-Access Queries
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/gorilla/mux"
+)
+
+// SubjectGrant is a subject allowed to act on an object, with what grants it
+type SubjectGrant struct {
+	Subject string             `json:"subject"`
+	Model   AccessControlModel `json:"model"`
+	Grant   string             `json:"grant"` // Rule, policy or relationship path granting access
+}
+
+// explainRule describes the ACL/RBAC rule that decided a check, or "" when none matched
+func explainRule(enforcer casbin.IEnforcer, subject, object, action string) string {
+	_, explain, err := enforcer.EnforceEx(subject, object, action)
+	if err != nil || len(explain) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("rule [%s]", strings.Join(explain, ", "))
+}
+
+// ruleSubjects returns the subjects of allow rules on object and action
+func ruleSubjects(enforcer casbin.IEnforcer, object, action string) ([]string, error) {
+	rules, err := enforcer.GetFilteredPolicy(1, object, action)
+	if err != nil {
+		return nil, err
+	}
+	var subjects []string
+	for _, rule := range rules {
+		if ruleEffect(rule) == effectAllow {
+			subjects = append(subjects, rule[0])
+		}
+	}
+	return subjects, nil
+}
+
+// aclRuleSubjects returns the subjects of ACL allow rules whose object and action match,
+// including pattern rules
+func aclRuleSubjects(enforcer casbin.IEnforcer, object, action string) ([]string, error) {
+	rules, err := enforcer.GetPolicy()
+	if err != nil {
+		return nil, err
+	}
+	var subjects []string
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if len(rule) < 3 || ruleEffect(rule) != effectAllow || seen[rule[0]] {
+			continue
+		}
+		if aclPatternMatch(object, rule[1]) && aclPatternMatch(action, rule[2]) {
+			seen[rule[0]] = true
+			subjects = append(subjects, rule[0])
+		}
+	}
+	return subjects, nil
+}
+
+// roleMembers returns the users holding an RBAC role directly or through other roles.
+// The synced enforcer does not lock this lookup itself.
+func (s *AuthService) roleMembers(role string) ([]string, error) {
+	lock := s.rbacEnforcer.GetLock()
+	lock.RLock()
+	defer lock.RUnlock()
+	return s.rbacEnforcer.GetImplicitUsersForRole(role)
+}
+
+// GroupMembers returns the direct and nested members of group in the relationship graph
+func (rg *RelationshipGraph) GroupMembers(group string) []string {
+	unlock := rg.readLock()
+	defer unlock()
+
+	var members []string
+	seen := map[string]bool{group: true}
+	queue := []string{group}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		rg.ensureObjectLoaded(node)
+		for _, rel := range rg.reverse.incomingTo(node) {
+			if rel.Relationship == "member" && !seen[rel.Subject] {
+				seen[rel.Subject] = true
+				members = append(members, rel.Subject)
+				queue = append(queue, rel.Subject)
+			}
+		}
+	}
+	return members
+}
+
+// Ancestors returns every node with a relationship path to object, the superset of
+// subjects any ReBAC check on object can grant
+func (rg *RelationshipGraph) Ancestors(object string) []string {
+	unlock := rg.readLock()
+	defer unlock()
+
+	var nodes []string
+	seen := map[string]bool{object: true}
+	queue := []string{object}
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		rg.ensureObjectLoaded(node)
+		for _, rel := range rg.reverse.incomingTo(node) {
+			if !seen[rel.Subject] {
+				seen[rel.Subject] = true
+				nodes = append(nodes, rel.Subject)
+				queue = append(queue, rel.Subject)
+			}
+		}
+	}
+	return nodes
+}
+
+// ListSubjects returns the subjects that can perform action on object in each of the given
+// models, sorted by subject. Candidates are confirmed with the regular checks. Roles and
+// groups are not listed themselves; their members are, with the grant naming them.
+func (s *AuthService) ListSubjects(object, action string, models []AccessControlModel) ([]SubjectGrant, error) {
+	grants := []SubjectGrant{}
+	add := func(model AccessControlModel, subject, grant string) {
+		grants = append(grants, SubjectGrant{Subject: subject, Model: model, Grant: grant})
+	}
+
+	rg := s.relationshipGraph
+
+	for _, model := range models {
+		switch model {
+		case ModelACL:
+			subjects, err := aclRuleSubjects(s.aclEnforcer, object, action)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read ACL policies: %v", err)
+			}
+			for _, subject := range subjects {
+				if allowed, _, err := enforceRule(s.aclEnforcer, subject, object, action); err == nil && allowed {
+					add(model, subject, explainRule(s.aclEnforcer, subject, object, action))
+				}
+			}
+
+		case ModelRBAC:
+			roles, err := s.rbacEnforcer.GetAllRoles()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read RBAC roles: %v", err)
+			}
+			isRole := make(map[string]bool, len(roles))
+			for _, role := range roles {
+				isRole[role] = true
+			}
+
+			granted, err := ruleSubjects(s.rbacEnforcer, object, action)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read RBAC policies: %v", err)
+			}
+			// Rules on the object's resource groups apply to it as well
+			resourceGroups, err := s.ResourceGroupAncestors(object)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve resource groups of %s: %v", object, err)
+			}
+			for _, ancestor := range resourceGroups {
+				groupGranted, err := ruleSubjects(s.rbacEnforcer, ancestor.Group, action)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read RBAC policies: %v", err)
+				}
+				granted = append(granted, groupGranted...)
+			}
+			candidates := make(map[string]bool)
+			for _, subject := range granted {
+				candidates[subject] = true
+				users, err := s.roleMembers(subject)
+				if err != nil {
+					return nil, fmt.Errorf("failed to resolve members of role %s: %v", subject, err)
+				}
+				for _, user := range users {
+					candidates[user] = true
+				}
+			}
+			if s.rbacGroupBindings {
+				// Roles bound to groups apply to the group's members
+				for _, candidate := range sortedSet(candidates) {
+					for _, member := range rg.GroupMembers(candidate) {
+						candidates[member] = true
+					}
+				}
+			}
+
+			for _, subject := range sortedSet(candidates) {
+				if isRole[subject] || (s.rbacGroupBindings && rg.IsGroup(subject)) {
+					continue
+				}
+				allowed, err := s.Enforce(model, subject, object, action, nil)
+				if err != nil || !allowed {
+					continue
+				}
+				grant := explainRule(s.rbacEnforcer, subject, object, action)
+				if grant == "" {
+					_, groups := rg.GroupsForSubject(subject, s.currentSettings().maxDepthLimit)
+					for _, group := range groups {
+						if groupGrant := explainRule(s.rbacEnforcer, group, object, action); groupGrant != "" {
+							grant = fmt.Sprintf("group %s: %s", group, groupGrant)
+							break
+						}
+					}
+				}
+				add(model, subject, grant)
+			}
+
+		case ModelABAC:
+			// Any user with attributes may match a policy
+			var users []string
+			if err := s.db.Model(&UserAttribute{}).Distinct().Pluck("user_id", &users).Error; err != nil {
+				return nil, fmt.Errorf("failed to read user attributes: %v", err)
+			}
+			sort.Strings(users)
+			for _, user := range users {
+				allowed, reason := s.policyEngine.Evaluate(s.abacEvaluationContext(user, object, action, nil, false))
+				if allowed {
+					add(model, user, reason)
+				}
+			}
+
+		case ModelReBAC:
+			for _, subject := range rg.Ancestors(object) {
+				if rg.IsGroup(subject) {
+					continue
+				}
+				if allowed, hops := rg.CheckReBACAccessHops(subject, object, action); allowed {
+					add(model, subject, formatPath(subject, hops))
+				}
+			}
+
+		default:
+			return nil, fmt.Errorf("invalid model specified: %s", model)
+		}
+	}
+
+	sort.SliceStable(grants, func(i, j int) bool {
+		return grants[i].Subject < grants[j].Subject
+	})
+	return grants, nil
+}
+
+// sortedSet returns the members of a set in order
+func sortedSet(set map[string]bool) []string {
+	members := make([]string, 0, len(set))
+	for member := range set {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	return members
+}
+
+// listObjectSubjectsHandler lists who can perform an action on an object, across models
+func (s *AuthService) listObjectSubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	object := mux.Vars(r)["objectId"]
+	permission := r.URL.Query().Get("permission")
+	if permission == "" {
+		writeJSONError(w, http.StatusBadRequest, "permission query parameter is required")
+		return
+	}
+
+	models := []AccessControlModel{ModelACL, ModelRBAC, ModelABAC, ModelReBAC}
+	model := AccessControlModel(r.URL.Query().Get("model"))
+	switch model {
+	case "":
+	case ModelACL, ModelRBAC, ModelABAC, ModelReBAC:
+		models = []AccessControlModel{model}
+	default:
+		writeJSONError(w, http.StatusBadRequest, "Invalid model specified")
+		return
+	}
+
+	subjects, err := s.ListSubjects(object, permission, models)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list subjects: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"object":     object,
+		"permission": permission,
+		"models":     models,
+		"subjects":   subjects,
+		"count":      len(subjects),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,122 @@
+// Multi-Model Authorization Microservice - Who-Has-Access Query Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListSubjects_AcrossModels(t *testing.T) {
+	service := setupTestService(t)
+	service.rbacGroupBindings = true
+
+	service.aclEnforcer.AddPolicy("alice", "report", "write", "allow")
+	service.aclEnforcer.AddPolicy("mallory", "report", "write", "allow")
+	service.aclEnforcer.AddPolicy("mallory", "report", "read", "deny")
+
+	// bob inherits editor from senior; contractors are denied despite being editors
+	service.rbacEnforcer.AddPolicy("editor", "report", "write", "allow")
+	service.rbacEnforcer.AddRoleForUser("senior", "editor")
+	service.rbacEnforcer.AddRoleForUser("bob", "senior")
+	service.rbacEnforcer.AddRoleForUser("carl", "editor")
+	service.rbacEnforcer.AddPolicy("carl", "report", "write", "deny")
+	service.rbacEnforcer.AddRoleForUser("writers", "editor")
+	service.relationshipGraph.AddRelationship("dana", "member", "writers")
+
+	service.saveUserAttribute("erin", "department", "finance")
+	service.saveUserAttribute("frank", "department", "sales")
+	service.policyEngine.AddPolicy(&ABACPolicy{
+		ID:       "finance-write",
+		Name:     "Finance writes reports",
+		Effect:   "allow",
+		Priority: 10,
+		Conditions: []PolicyCondition{
+			{Type: "user", Field: "department", Operator: "eq", Value: "finance"},
+		},
+	})
+
+	service.relationshipGraph.AddRelationship("gina", "owner", "report")
+	service.relationshipGraph.AddRelationship("hank", "member", "team")
+	service.relationshipGraph.AddRelationship("team", "editor", "reports")
+	service.relationshipGraph.AddRelationship("reports", "parent", "report")
+	service.relationshipGraph.AddRelationship("ivan", "viewer", "report")
+
+	grants, err := service.ListSubjects("report", "write", []AccessControlModel{ModelACL, ModelRBAC, ModelABAC, ModelReBAC})
+	if err != nil {
+		t.Fatalf("Failed to list subjects: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, grant := range grants {
+		got[grant.Subject+"/"+string(grant.Model)] = grant.Grant
+	}
+	expected := map[string]string{
+		"alice/acl":   "rule [alice, report, write, allow]",
+		"mallory/acl": "rule [mallory, report, write, allow]",
+		"bob/rbac":    "rule [editor, report, write, allow]",
+		"dana/rbac":   "group writers: rule [editor, report, write, allow]",
+		"erin/abac":   "Access granted by policy: Finance writes reports",
+		"gina/rebac":  "gina -[owner]-> report",
+		"hank/rebac":  "hank -[member]-> team -[editor]-> reports -[parent]-> report",
+	}
+	if len(got) != len(expected) {
+		t.Errorf("Expected %d grants, got %v", len(expected), got)
+	}
+	for key, grant := range expected {
+		if got[key] != grant {
+			t.Errorf("Expected %s to be granted by %q, got %q", key, grant, got[key])
+		}
+	}
+	for i := 1; i < len(grants); i++ {
+		if grants[i-1].Subject > grants[i].Subject {
+			t.Errorf("Expected grants sorted by subject, got %+v", grants)
+			break
+		}
+	}
+}
+
+func TestListSubjects_Handler(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/objects/{objectId}/subjects", service.listObjectSubjectsHandler).Methods("GET")
+
+	service.aclEnforcer.AddPolicy("alice", "doc1", "read", "allow")
+	service.relationshipGraph.AddRelationship("bob", "viewer", "doc1")
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", url, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := get("/api/v1/objects/doc1/subjects?permission=read&model=rebac")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var response struct {
+		Subjects []SubjectGrant `json:"subjects"`
+		Count    int            `json:"count"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if response.Count != 1 || response.Subjects[0].Subject != "bob" || response.Subjects[0].Model != ModelReBAC {
+		t.Errorf("Expected only bob through ReBAC, got %s", rr.Body.String())
+	}
+
+	rr = get("/api/v1/objects/doc1/subjects?permission=read")
+	if !strings.Contains(rr.Body.String(), `"subject":"alice"`) || !strings.Contains(rr.Body.String(), `"subject":"bob"`) {
+		t.Errorf("Expected subjects from all models, got %s", rr.Body.String())
+	}
+
+	for _, url := range []string{"/api/v1/objects/doc1/subjects", "/api/v1/objects/doc1/subjects?permission=read&model=dac"} {
+		if rr := get(url); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", url, rr.Code)
+		}
+	}
+}
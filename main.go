@@ -6,20 +6,23 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/casbin/casbin/v2/model"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
 	"github.com/gorilla/mux"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
@@ -39,7 +42,14 @@ type EnforceRequest struct {
 	Subject    string             `json:"subject"`
 	Object     string             `json:"object"`
 	Action     string             `json:"action"`
-	Attributes map[string]string  `json:"attributes,omitempty"` // Attributes for ABAC
+	Attributes RequestAttributes  `json:"attributes,omitempty"` // Attributes for ABAC, nested objects as dot paths
+	Freshness  string             `json:"freshness,omitempty"`  // "strong" reads attributes and tuples from the database
+	Explain    bool               `json:"explain,omitempty"`    // Return why the decision was made
+	Tenant     string             `json:"tenant,omitempty"`     // Tenant whose data decides the check (global when empty)
+
+	ConsistencyToken string         `json:"consistency_token,omitempty"` // ReBAC: decide at least as fresh as the write that returned it
+	ContextualTuples []Relationship `json:"contextual_tuples,omitempty"` // ReBAC: tuples assumed to exist for this check only
+	AsOf             string         `json:"as_of,omitempty"`             // Decide against the rules and tuples at this time (RFC 3339)
 }
 
 // PolicyRequest represents a policy management request
@@ -48,6 +58,10 @@ type PolicyRequest struct {
 	Subject string             `json:"subject"`
 	Object  string             `json:"object"`
 	Action  string             `json:"action"`
+	Effect  string             `json:"effect,omitempty"` // "allow" (default) or "deny" for ACL/RBAC
+	Labels  []string           `json:"labels,omitempty"` // Labels for organizing policies (e.g. "app:billing")
+	// Condition must also hold for an RBAC rule to match, e.g. object.ward eq user.ward
+	Condition *ConditionGroup `json:"condition,omitempty"`
 }
 
 // RoleRequest represents a role assignment request
@@ -69,6 +83,41 @@ type RelationshipRequest struct {
 	Object       string `json:"object"`
 }
 
+// AddRelationshipRequest represents a request to add a relationship tuple
+type AddRelationshipRequest struct {
+	RelationshipRequest
+	Labels    []string            `json:"labels,omitempty"`
+	ExpiresAt *time.Time          `json:"expires_at,omitempty"` // Time after which the tuple stops granting access
+	Caveat    *RelationshipCaveat `json:"caveat,omitempty"`     // Conditions the request must meet for the tuple to grant access
+	Source    string              `json:"source,omitempty"`     // Originating system, stored as tuple metadata
+	Note      string              `json:"note,omitempty"`       // Why the tuple was written, stored as tuple metadata
+}
+
+// UserRoleRequest represents a request to assign a role to a user
+type UserRoleRequest struct {
+	Role   string   `json:"role"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// UserAttributesRequest represents a request to set attributes of a user
+type UserAttributesRequest struct {
+	Attributes map[string]string `json:"attributes"`
+	Types      map[string]string `json:"types,omitempty"` // Value type of attributes, e.g. "number" or "date"
+}
+
+// ObjectAttributesRequest represents a request to set attributes of an object
+type ObjectAttributesRequest struct {
+	Object     string            `json:"object"`
+	Attributes map[string]string `json:"attributes"`
+	Types      map[string]string `json:"types,omitempty"` // Value type of attributes, e.g. "number" or "date"
+}
+
+// PermissionCheckRequest represents a check whether a relationship grants a permission
+type PermissionCheckRequest struct {
+	Relationship string `json:"relationship"`
+	Permission   string `json:"permission"`
+}
+
 // ResBACQueryRequest represents a ReBAC query request
 type ResBACQueryRequest struct {
 	Subject string `json:"subject"`
@@ -83,7 +132,15 @@ type ABACPolicy struct {
 	Description string            `json:"description"`
 	Effect      string            `json:"effect"` // "allow" or "deny"
 	Priority    int               `json:"priority"`
+	Actions     []string          `json:"actions,omitempty" gorm:"serializer:json"` // Actions the policy applies to (all when empty)
 	Conditions  []PolicyCondition `json:"conditions" gorm:"foreignKey:PolicyID"`
+	Group       *ConditionGroup   `json:"condition_group,omitempty" gorm:"column:condition_group;serializer:json"`
+	Expression  string            `json:"expression,omitempty" gorm:"type:text"`        // CEL expression replacing conditions
+	Obligations []Obligation      `json:"obligations,omitempty" gorm:"serializer:json"` // Returned with its decisions; callers must fulfill them
+	Advice      []Obligation      `json:"advice,omitempty" gorm:"serializer:json"`      // Returned with its decisions; callers may ignore them
+	Labels      []string          `json:"labels,omitempty" gorm:"-"`                    // Stored in the resource label table
+	Tenant      string            `json:"tenant,omitempty" gorm:"index"`                // Tenant owning the policy (global when empty)
+	Version     int               `json:"version"`                                      // Incremented by every change; its ETag guards updates
 	CreatedAt   time.Time         `json:"created_at"`
 	UpdatedAt   time.Time         `json:"updated_at"`
 }
@@ -92,11 +149,14 @@ type ABACPolicy struct {
 type PolicyCondition struct {
 	ID       uint   `json:"id" gorm:"primaryKey"`
 	PolicyID string `json:"policy_id" gorm:"index"`
-	Type     string `json:"type"`     // "user", "object", "environment", "action"
+	Type     string `json:"type"`     // "user", "object", "environment", "request", "action"
 	Field    string `json:"field"`    // attribute name
-	Operator string `json:"operator"` // "eq", "ne", "gt", "gte", "lt", "lte", "in", "contains", "startswith", "endswith"
+	Operator string `json:"operator"` // "eq", "ne", "gt", "gte", "lt", "lte", "before", "after", "in", "contains", "startswith", "endswith", "regex", "cidr"
 	Value    string `json:"value"`    // comparison value
 	LogicOp  string `json:"logic_op"` // "and", "or" (for combining with next condition)
+	// ValueFrom compares with another attribute instead of Value, as "<type>.<field>",
+	// e.g. "user.ward" for object.ward eq user.ward
+	ValueFrom string `json:"value_from,omitempty"`
 }
 
 // PolicyEvaluationContext holds all data needed for policy evaluation
@@ -105,23 +165,38 @@ type PolicyEvaluationContext struct {
 	ObjectAttributes      map[string]string
 	EnvironmentAttributes map[string]string
 	ActionAttributes      map[string]string
+	RequestAttributes     map[string]string // Attributes of the authorization request, nested fields as dot paths
+	UserAttributeTypes    map[string]string // Value types of typed user attributes; others are strings
+	ObjectAttributeTypes  map[string]string // Value types of typed object attributes; others are strings
 	Subject               string
 	Object                string
 	Action                string
+	Tenant                string // Only this tenant's policies apply (all policies when empty)
 }
 
 // PolicyEngine handles ABAC policy evaluation
 type PolicyEngine struct {
+	mu       sync.RWMutex // Guards policies, which reloads replace while checks read them
 	policies map[string]*ABACPolicy
 	db       *gorm.DB
+	revision uint64           // Incremented on every policy change to invalidate cached decisions
+	actions  *actionHierarchy // Actions implied by the actions policies are scoped to (nil when none)
 }
 
 // EnforceResponse represents the response for an enforcement request
 type EnforceResponse struct {
-	Allowed bool   `json:"allowed"`
-	Message string `json:"message,omitempty"`
-	Model   string `json:"model"`
-	Path    string `json:"path,omitempty"` // ReBAC: relationship path for access permission
+	Allowed bool      `json:"allowed"`
+	Message string    `json:"message,omitempty"`
+	Model   string    `json:"model"`
+	Path    string    `json:"path,omitempty"` // ReBAC: relationship path for access permission
+	Hops    []PathHop `json:"hops,omitempty"` // ReBAC: the same path as structured hops
+
+	Matched     *DecisionMatch       `json:"matched,omitempty"`     // Rule, role chain or policy that produced the decision
+	Tenant      string               `json:"tenant,omitempty"`      // Tenant whose data decided the check
+	Explanation *DecisionExplanation `json:"explanation,omitempty"` // Why the decision was made, on request
+	AsOf        string               `json:"as_of,omitempty"`       // Point in time the check was decided at
+	Obligations []Obligation         `json:"obligations,omitempty"` // ABAC: what the caller must do to enforce the decision
+	Advice      []Obligation         `json:"advice,omitempty"`      // ABAC: what the caller may do along with the decision
 }
 
 // Relationship represents a relationship in the ReBAC graph
@@ -134,19 +209,41 @@ type Relationship struct {
 // RelationshipGraph manages relationships for ReBAC
 type RelationshipGraph struct {
 	relationships map[string][]Relationship
-	objectTypes   map[string]string   // Object type mappings
-	db            *gorm.DB            // Database connection for persistence
-	permissions   map[string][]string // Relationship to permissions mapping
+	mu            *sync.RWMutex                   // Guards the in-memory tuples and indexes, shared with request views
+	db            *gorm.DB                        // Database connection for persistence
+	replica       *replicaDatabase                // Read-only database tuple loads prefer (nil reads the primary)
+	permissions   map[string][]string             // Relationship to permissions mapping
+	partitions    *partitionCache                 // Resident object namespaces (nil when the whole graph is in memory)
+	checkCache    *checkCache                     // Cached check results (nil when caching is disabled)
+	revision      uint64                          // Incremented on every tuple write to invalidate cached checks
+	constraints   []RelationshipConstraint        // Cardinality limits enforced on write
+	enforceTypes  bool                            // Reject tuples violating the declared types instead of reporting them
+	reverse       *reverseIndex                   // Incoming tuples per object, kept in step with relationships
+	adjacency     *adjacencyIndex                 // Outgoing relations per subject, kept in step with relationships
+	expiries      map[Relationship]time.Time      // Expiry of time-bound tuples in memory
+	nextExpiry    time.Time                       // Earliest expiry in expiries (zero when none)
+	namespaces    map[string]*NamespaceDefinition // Relation rewrite rules by namespace, replaced on every change
+	actions       map[string]*ActionMapping       // Custom action to permission mappings, replaced on every change
+	sequence      uint64                          // Shared write sequence number the in-memory graph reflects
+
+	caveats       map[Relationship]*RelationshipCaveat // Caveats of conditional tuples in memory
+	caveatContext map[string]string                    // Request attributes caveats are evaluated against (nil outside request views)
+	hasCaveats    bool                                 // Whether any tuple, resident or not, has carried a caveat
+	groupDepth    int                                  // Levels of nested groups group access follows (defaultGroupDepth when zero)
 }
 
 // RelationshipRecord represents a relationship record in the database
 type RelationshipRecord struct {
-	ID           uint   `gorm:"primaryKey"`
-	Subject      string `gorm:"index"`
-	Relationship string `gorm:"index"`
-	Object       string `gorm:"index"`
+	ID           uint                `gorm:"primaryKey"`
+	Subject      string              `gorm:"index"`
+	Relationship string              `gorm:"index"`
+	Object       string              `gorm:"index"`
+	ExpiresAt    *time.Time          `gorm:"index"`           // Nil for tuples that never expire
+	Caveat       *RelationshipCaveat `gorm:"serializer:json"` // Nil for unconditional tuples
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
+
+	TupleMetadata `gorm:"embedded"` // Who wrote the tuple, from which system and why
 }
 
 // UserAttribute represents a user attribute record in the database
@@ -155,6 +252,7 @@ type UserAttribute struct {
 	UserID    string `gorm:"index"`
 	Attribute string `gorm:"index"`
 	Value     string
+	ValueType string `gorm:"default:string"` // "string", "number", "boolean", "date" or "list"
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -165,6 +263,7 @@ type ObjectAttribute struct {
 	ObjectID  string `gorm:"index"`
 	Attribute string `gorm:"index"`
 	Value     string
+	ValueType string `gorm:"default:string"` // "string", "number", "boolean", "date" or "list"
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
@@ -178,26 +277,54 @@ func NewRelationshipGraph(db *gorm.DB) (*RelationshipGraph, error) {
 	}
 
 	rg := &RelationshipGraph{
+		mu:            &sync.RWMutex{},
 		relationships: make(map[string][]Relationship),
-		objectTypes:   make(map[string]string),
+		reverse:       newReverseIndex(),
+		adjacency:     newAdjacencyIndex(),
 		db:            db,
 		permissions:   make(map[string][]string),
+		checkCache:    newCheckCache(defaultCheckCacheSize),
 	}
 
 	// Initialize default permission mappings following ReBAC best practices
 	rg.initializeDefaultPermissions()
 
+	// Record the write sequence before loading, so consistency tokens are never overstated
+	if err := migrateRelationshipSequence(db); err != nil {
+		return nil, err
+	}
+	if err := rg.syncSequence(); err != nil {
+		return nil, err
+	}
+
 	// Load existing relationships from database
 	err = rg.loadFromDatabase()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load relationships from database: %v", err)
 	}
 
+	if err := migrateNamespaces(db); err != nil {
+		return nil, err
+	}
+	if err := rg.loadNamespaces(); err != nil {
+		return nil, err
+	}
+
+	if err := migrateActions(db); err != nil {
+		return nil, err
+	}
+	if err := rg.loadActions(); err != nil {
+		return nil, err
+	}
+
 	return rg, nil
 }
 
 // loadFromDatabase loads all relationships from the database into memory
 func (rg *RelationshipGraph) loadFromDatabase() error {
+	unlock := rg.writeLock()
+	defer unlock()
+
 	var records []RelationshipRecord
 	result := rg.db.Find(&records)
 	if result.Error != nil {
@@ -206,28 +333,65 @@ func (rg *RelationshipGraph) loadFromDatabase() error {
 
 	// Clear existing relationships
 	rg.relationships = make(map[string][]Relationship)
-
-	// Load relationships into memory
+	rg.reverse = newReverseIndex()
+	rg.adjacency = newAdjacencyIndex()
+	rg.expiries = make(map[Relationship]time.Time)
+	rg.nextExpiry = time.Time{}
+	rg.caveats = make(map[Relationship]*RelationshipCaveat)
+
+	// Load relationships into memory, skipping expired ones the janitor has not purged yet
+	now := time.Now()
 	for _, record := range records {
-		rel := Relationship{
-			Subject:      record.Subject,
-			Relationship: record.Relationship,
-			Object:       record.Object,
+		if relationshipExpired(record, now) {
+			continue
 		}
+		rel := Relationship{Subject: record.Subject, Relationship: record.Relationship, Object: record.Object}
+		rg.trackExpiry(rel, record.ExpiresAt)
+		rg.trackCaveat(rel, record.Caveat)
+		rg.addToMemory(record.Subject, record.Relationship, record.Object)
+	}
 
-		key := fmt.Sprintf("%s:%s", record.Subject, record.Relationship)
-		rg.relationships[key] = append(rg.relationships[key], rel)
+	return nil
+}
 
-		// Store reverse relationship for graph traversal
-		reverseKey := fmt.Sprintf("%s:reverse_%s", record.Object, record.Relationship)
-		rg.relationships[reverseKey] = append(rg.relationships[reverseKey], Relationship{
-			Subject:      record.Object,
-			Relationship: "reverse_" + record.Relationship,
-			Object:       record.Subject,
-		})
+// addToMemory stores a relationship in the in-memory graph and indexes it by subject and object
+func (rg *RelationshipGraph) addToMemory(subject, relationship, object string) {
+	rel := Relationship{
+		Subject:      subject,
+		Relationship: relationship,
+		Object:       object,
 	}
 
-	return nil
+	key := fmt.Sprintf("%s:%s", subject, relationship)
+	rg.relationships[key] = append(rg.relationships[key], rel)
+	rg.reverse.add(rel)
+	rg.adjacency.add(rel)
+}
+
+// removeFromMemory removes every copy of a relationship from the in-memory graph and its indexes,
+// matching the database delete which removes all identical rows
+func (rg *RelationshipGraph) removeFromMemory(subject, relationship, object string) {
+	key := fmt.Sprintf("%s:%s", subject, relationship)
+	relationships := rg.relationships[key]
+
+	kept := relationships[:0]
+	for _, rel := range relationships {
+		if rel.Object != object {
+			kept = append(kept, rel)
+		}
+	}
+	removed := len(relationships) - len(kept)
+	if len(kept) == 0 {
+		delete(rg.relationships, key)
+	} else {
+		rg.relationships[key] = kept
+	}
+
+	rel := Relationship{Subject: subject, Relationship: relationship, Object: object}
+	rg.reverse.remove(rel)
+	rg.adjacency.remove(subject, relationship, removed)
+	delete(rg.expiries, rel)
+	delete(rg.caveats, rel)
 }
 
 // initializeDefaultPermissions sets up the default relationship-to-permission mappings
@@ -278,11 +442,14 @@ func (rg *RelationshipGraph) HasPermissionThroughRelationship(relationship, perm
 }
 
 // saveToDatabase saves a relationship to the database
-func (rg *RelationshipGraph) saveToDatabase(subject, relationship, object string) error {
+func (rg *RelationshipGraph) saveToDatabase(subject, relationship, object string, expiresAt *time.Time, caveat *RelationshipCaveat, metadata TupleMetadata) error {
 	record := RelationshipRecord{
-		Subject:      subject,
-		Relationship: relationship,
-		Object:       object,
+		Subject:       subject,
+		Relationship:  relationship,
+		Object:        object,
+		ExpiresAt:     expiresAt,
+		Caveat:        caveat,
+		TupleMetadata: metadata,
 	}
 
 	result := rg.db.Create(&record)
@@ -297,71 +464,124 @@ func (rg *RelationshipGraph) deleteFromDatabase(subject, relationship, object st
 
 // AddRelationship adds a new relationship to the graph and persists it to database
 func (rg *RelationshipGraph) AddRelationship(subject, relationship, object string) error {
+	return rg.AddExpiringRelationship(subject, relationship, object, nil)
+}
+
+// AddExpiringRelationship adds a relationship that stops granting access at expiresAt
+// (never when nil)
+func (rg *RelationshipGraph) AddExpiringRelationship(subject, relationship, object string, expiresAt *time.Time) error {
+	return rg.AddConditionalRelationship(subject, relationship, object, expiresAt, nil)
+}
+
+// AddConditionalRelationship adds a relationship that only grants access to requests
+// meeting caveat (always when nil) until expiresAt (forever when nil)
+func (rg *RelationshipGraph) AddConditionalRelationship(subject, relationship, object string, expiresAt *time.Time, caveat *RelationshipCaveat) error {
+	return rg.AddAnnotatedRelationship(subject, relationship, object, expiresAt, caveat, TupleMetadata{})
+}
+
+// AddAnnotatedRelationship adds a relationship like AddConditionalRelationship and stores
+// who wrote it, from which system and why
+func (rg *RelationshipGraph) AddAnnotatedRelationship(subject, relationship, object string, expiresAt *time.Time, caveat *RelationshipCaveat, metadata TupleMetadata) error {
+	// Writes are serialized, so memory applies them in the order the database did
+	unlock := rg.writeLock()
+	defer unlock()
+
+	if rg.enforceTypes {
+		if problems := rg.typeProblems(subject, relationship, object); len(problems) > 0 {
+			return &TypeError{Problems: problems}
+		}
+	}
+	if err := rg.checkConstraints(subject, relationship, object); err != nil {
+		return err
+	}
+
 	// Save to database first
-	err := rg.saveToDatabase(subject, relationship, object)
+	err := rg.saveToDatabase(subject, relationship, object, expiresAt, caveat, metadata)
 	if err != nil {
 		return fmt.Errorf("failed to save relationship to database: %v", err)
 	}
+	rg.bumpRevision()
+	rg.applyAdded(subject, relationship, object, expiresAt, caveat)
 
-	rel := Relationship{
-		Subject:      subject,
-		Relationship: relationship,
-		Object:       object,
-	}
+	// Count the write only once memory reflects it
+	return rg.recordWrite()
+}
 
-	key := fmt.Sprintf("%s:%s", subject, relationship)
-	rg.relationships[key] = append(rg.relationships[key], rel)
+// applyAdded adds a tuple that has been saved to the database to memory
+func (rg *RelationshipGraph) applyAdded(subject, relationship, object string, expiresAt *time.Time, caveat *RelationshipCaveat) {
+	if caveat != nil {
+		rg.hasCaveats = true
+	}
 
-	// Store reverse relationship for graph traversal
-	reverseKey := fmt.Sprintf("%s:reverse_%s", object, relationship)
-	rg.relationships[reverseKey] = append(rg.relationships[reverseKey], Relationship{
-		Subject:      object,
-		Relationship: "reverse_" + relationship,
-		Object:       subject,
-	})
+	// Partitions that are not resident pick up the new tuple when they are loaded
+	if rg.partitions != nil {
+		if !rg.partitions.isResident(rg.partitions.namespaceOf(object)) {
+			return
+		}
+		rg.partitions.track(Relationship{Subject: subject, Relationship: relationship, Object: object})
+	}
 
-	return nil
+	rel := Relationship{Subject: subject, Relationship: relationship, Object: object}
+	rg.trackExpiry(rel, expiresAt)
+	rg.trackCaveat(rel, caveat)
+	rg.addToMemory(subject, relationship, object)
 }
 
 // RemoveRelationship removes a relationship from the graph and database
 func (rg *RelationshipGraph) RemoveRelationship(subject, relationship, object string) error {
+	unlock := rg.writeLock()
+	defer unlock()
+
 	// Remove from database first
 	err := rg.deleteFromDatabase(subject, relationship, object)
 	if err != nil {
 		return fmt.Errorf("failed to delete relationship from database: %v", err)
 	}
 
-	key := fmt.Sprintf("%s:%s", subject, relationship)
-	relationships := rg.relationships[key]
+	rg.forget(subject, relationship, object)
 
-	for i, rel := range relationships {
-		if rel.Object == object {
-			rg.relationships[key] = append(relationships[:i], relationships[i+1:]...)
-			break
-		}
-	}
+	return rg.recordWrite()
+}
 
-	// Remove reverse relationship as well
-	reverseKey := fmt.Sprintf("%s:reverse_%s", object, relationship)
-	reverseRelationships := rg.relationships[reverseKey]
+// removeStored removes a relationship like RemoveRelationship, reporting whether the
+// database held it. Nothing is recorded as written when it did not.
+func (rg *RelationshipGraph) removeStored(subject, relationship, object string) (bool, error) {
+	unlock := rg.writeLock()
+	defer unlock()
 
-	for i, rel := range reverseRelationships {
-		if rel.Object == subject {
-			rg.relationships[reverseKey] = append(reverseRelationships[:i], reverseRelationships[i+1:]...)
-			break
-		}
+	result := rg.db.Where("subject = ? AND relationship = ? AND object = ?", subject, relationship, object).Delete(&RelationshipRecord{})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to delete relationship: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
 	}
 
-	return nil
+	rg.forget(subject, relationship, object)
+	return true, rg.recordWrite()
+}
+
+// forget drops a relationship that has already been removed from the database from memory
+func (rg *RelationshipGraph) forget(subject, relationship, object string) {
+	rg.bumpRevision()
+	if rg.partitions != nil {
+		rg.partitions.untrack(Relationship{Subject: subject, Relationship: relationship, Object: object})
+	}
+	rg.removeFromMemory(subject, relationship, object)
 }
 
 // HasDirectRelationship checks if a direct relationship exists between subject and object
 func (rg *RelationshipGraph) HasDirectRelationship(subject, relationship, object string) bool {
+	unlock := rg.readLock()
+	defer unlock()
+
+	rg.ensureObjectLoaded(object)
+
 	key := fmt.Sprintf("%s:%s", subject, relationship)
 	relationships := rg.relationships[key]
 
 	for _, rel := range relationships {
-		if rel.Object == object {
+		if rel.Object == object && rg.caveatHolds(rel) {
 			return true
 		}
 	}
@@ -370,16 +590,33 @@ func (rg *RelationshipGraph) HasDirectRelationship(subject, relationship, object
 
 // FindRelationshipPath searches for a relationship path using breadth-first search
 func (rg *RelationshipGraph) FindRelationshipPath(subject, targetObject string, maxDepth int) (bool, string) {
+	found, hops := rg.FindRelationshipHops(subject, targetObject, maxDepth)
+	if !found {
+		return false, ""
+	}
+	return true, formatPath(subject, hops)
+}
+
+// FindRelationshipHops searches for a relationship path using breadth-first search and
+// returns it as structured hops
+func (rg *RelationshipGraph) FindRelationshipHops(subject, targetObject string, maxDepth int) (bool, []PathHop) {
+	unlock := rg.readLock()
+	defer unlock()
+	return rg.findRelationshipHops(subject, targetObject, maxDepth)
+}
+
+// findRelationshipHops implements FindRelationshipHops for callers holding the lock
+func (rg *RelationshipGraph) findRelationshipHops(subject, targetObject string, maxDepth int) (bool, []PathHop) {
 	if maxDepth <= 0 {
-		maxDepth = 5 // Default maximum depth
+		maxDepth = defaultMaxDepth
 	}
 
 	visited := make(map[string]bool)
 	queue := []struct {
 		node  string
-		path  string
+		path  []PathHop
 		depth int
-	}{{subject, subject, 0}}
+	}{{subject, nil, 0}}
 
 	for len(queue) > 0 {
 		current := queue[0]
@@ -398,46 +635,80 @@ func (rg *RelationshipGraph) FindRelationshipPath(subject, targetObject string,
 		}
 		visited[current.node] = true
 
-		// Check all relationships
-		for key, relationships := range rg.relationships {
-			parts := strings.Split(key, ":")
-			if len(parts) != 2 || parts[0] != current.node {
-				continue
-			}
-
-			relationshipType := parts[1]
-			if strings.HasPrefix(relationshipType, "reverse_") {
-				continue // Exclude reverse relationships
-			}
-
-			for _, rel := range relationships {
-				if !visited[rel.Object] {
-					newPath := fmt.Sprintf("%s -[%s]-> %s", current.path, relationshipType, rel.Object)
-					queue = append(queue, struct {
-						node  string
-						path  string
-						depth int
-					}{rel.Object, newPath, current.depth + 1})
-				}
+		// Outgoing edges may live in any partition the node points into
+		rg.ensureSubjectLoaded(current.node)
+
+		// Follow the relationships leaving the node
+		for _, rel := range rg.outgoingFrom(current.node) {
+			if !visited[rel.Object] && rg.caveatHolds(rel) {
+				newPath := appendHop(current.path, PathHop{Subject: current.node, Relation: rel.Relationship, Object: rel.Object})
+				queue = append(queue, struct {
+					node  string
+					path  []PathHop
+					depth int
+				}{rel.Object, newPath, current.depth + 1})
 			}
 		}
 	}
 
-	return false, ""
+	return false, nil
 }
 
 // CheckReBACAccess checks access permissions using ReBAC rules
 // This method properly separates authorization logic from relationship queries
 // following ReBAC best practices (like Google Zanzibar)
 func (rg *RelationshipGraph) CheckReBACAccess(subject, object, action string) (bool, string) {
+	allowed, hops := rg.CheckReBACAccessHops(subject, object, action)
+	if !allowed {
+		return false, ""
+	}
+	return true, formatPath(subject, hops)
+}
+
+// CheckReBACAccessHops checks access permissions using ReBAC rules and returns the
+// granting path as structured hops
+func (rg *RelationshipGraph) CheckReBACAccessHops(subject, object, action string) (bool, []PathHop) {
+	// Expired tuples stop granting access even before the janitor purges them
+	unlock := rg.readLock()
+	defer unlock()
+	return rg.checkReBACAccessHops(subject, object, action)
+}
+
+// checkReBACAccessHops implements CheckReBACAccessHops for callers holding the lock
+func (rg *RelationshipGraph) checkReBACAccessHops(subject, object, action string) (bool, []PathHop) {
 	// Map common actions to standardized permissions
 	permission := rg.mapActionToPermission(action)
 
+	if rg.checkCache == nil {
+		return rg.evaluateReBACAccess(subject, object, permission)
+	}
+
+	// Read the revision before evaluating so a concurrent write cannot stamp a stale result as current
+	key := checkCacheKey{subject: subject, object: object, permission: permission}
+	revision := rg.Revision()
+	if entry, found := rg.checkCache.get(key, revision); found {
+		serviceMetrics.Inc("rebac_check_cache_hits_total")
+		return entry.allowed, entry.hops
+	}
+	serviceMetrics.Inc("rebac_check_cache_misses_total")
+
+	allowed, hops := rg.evaluateReBACAccess(subject, object, permission)
+	rg.checkCache.put(key, checkCacheEntry{revision: revision, allowed: allowed, hops: hops})
+	return allowed, hops
+}
+
+// evaluateReBACAccess walks the relationship graph to decide a permission check
+func (rg *RelationshipGraph) evaluateReBACAccess(subject, object, permission string) (bool, []PathHop) {
+	// Objects of a defined namespace are decided by its rewrite rules alone
+	if def := rg.definitionFor(object); def != nil {
+		return rg.checkNamespacedAccess(subject, object, permission, def)
+	}
+
 	// 1. Check all direct relationships and their associated permissions
-	directRelationships := rg.GetDirectRelationships(subject, object)
+	directRelationships := rg.directRelationships(subject, object)
 	for _, rel := range directRelationships {
 		if rg.HasPermissionThroughRelationship(rel.Relationship, permission) {
-			return true, fmt.Sprintf("%s -[%s]-> %s", subject, rel.Relationship, object)
+			return true, []PathHop{{Subject: subject, Relation: rel.Relationship, Object: object}}
 		}
 	}
 
@@ -461,144 +732,259 @@ func (rg *RelationshipGraph) CheckReBACAccess(subject, object, action string) (b
 		}
 	}
 
-	return false, ""
+	return false, nil
 }
 
-// mapActionToPermission maps action strings to standardized permissions
+// mapActionToPermission maps action strings to standardized permissions, preferring the
+// registered custom actions over the built-in verbs
 func (rg *RelationshipGraph) mapActionToPermission(action string) string {
-	// Normalize common action names to permissions
-	switch action {
-	case "view":
-		return "read"
-	case "edit", "update", "modify":
-		return "write"
-	case "remove":
-		return "delete"
-	case "manage", "administer":
-		return "admin"
-	default:
-		return action
+	if mapping, exists := rg.actions[action]; exists {
+		return mapping.Permission
+	}
+	if permission, exists := builtinActionPermissions[action]; exists {
+		return permission
 	}
+	return action
 }
 
-// GetDirectRelationships returns all direct relationships between subject and object
+// GetDirectRelationships returns the direct relationships between subject and object that
+// grant access in the graph's request context
 func (rg *RelationshipGraph) GetDirectRelationships(subject, object string) []Relationship {
+	unlock := rg.readLock()
+	defer unlock()
+	return rg.directRelationships(subject, object)
+}
+
+// directRelationships implements GetDirectRelationships for callers holding the lock
+func (rg *RelationshipGraph) directRelationships(subject, object string) []Relationship {
+	rg.ensureObjectLoaded(object)
+
 	var relationships []Relationship
 
-	for key, rels := range rg.relationships {
-		parts := strings.Split(key, ":")
-		if len(parts) == 2 && parts[0] == subject && !strings.HasPrefix(parts[1], "reverse_") {
-			for _, rel := range rels {
-				if rel.Object == object {
-					relationships = append(relationships, rel)
-				}
-			}
+	for _, rel := range rg.outgoingFrom(subject) {
+		if rel.Object == object && rg.caveatHolds(rel) {
+			relationships = append(relationships, rel)
 		}
 	}
 
 	return relationships
 }
 
-// checkGroupAccess checks if subject has access through group membership
-func (rg *RelationshipGraph) checkGroupAccess(subject, object, permission string) (bool, string) {
-	// Find all groups the subject is a member of
-	memberKey := fmt.Sprintf("%s:member", subject)
-	if groups, exists := rg.relationships[memberKey]; exists {
-		for _, groupRel := range groups {
-			groupName := groupRel.Object
-
-			// Check if the group has the required permission on the object
-			groupRelationships := rg.GetDirectRelationships(groupName, object)
-			for _, rel := range groupRelationships {
-				if rg.HasPermissionThroughRelationship(rel.Relationship, permission) {
-					path := fmt.Sprintf("%s -[member]-> %s -[%s]-> %s",
-						subject, groupName, rel.Relationship, object)
-					return true, path
+// ListRelationships returns all relationships, optionally restricted to a single subject
+func (rg *RelationshipGraph) ListRelationships(subject string) ([]Relationship, error) {
+	unlock := rg.readLock()
+	defer unlock()
+
+	var relationships []Relationship
+	now := time.Now()
+
+	// Only part of a partitioned graph is resident, so list from the database
+	if rg.partitions != nil {
+		var records []RelationshipRecord
+		query := rg.db.Order("id").Where("expires_at IS NULL OR expires_at > ?", now)
+		if subject != "" {
+			query = query.Where("subject = ?", subject)
+		}
+		if err := query.Find(&records).Error; err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			relationships = append(relationships, Relationship{
+				Subject:      record.Subject,
+				Relationship: record.Relationship,
+				Object:       record.Object,
+			})
+		}
+		return relationships, nil
+	}
+
+	if subject != "" {
+		return append(relationships, rg.outgoingFrom(subject)...), nil
+	}
+	for _, rels := range rg.relationships {
+		relationships = append(relationships, rels...)
+	}
+
+	return relationships, nil
+}
+
+// checkGroupAccess checks if subject has access through group membership. Groups may be
+// members of other groups (team -> department -> org); nearer groups are checked first, and
+// each group is visited once so membership cycles terminate.
+func (rg *RelationshipGraph) checkGroupAccess(subject, object, permission string) (bool, []PathHop) {
+	type membership struct {
+		node string
+		path []PathHop // Member hops from subject to node
+	}
+
+	visited := map[string]bool{subject: true}
+	frontier := []membership{{node: subject}}
+	for depth := 0; depth < rg.maxGroupDepth() && len(frontier) > 0; depth++ {
+		var next []membership
+		for _, current := range frontier {
+			// Find all groups the node is a member of
+			rg.ensureSubjectLoaded(current.node)
+			// Copy memberships since loading other partitions may rewrite the slice
+			groups := append([]Relationship(nil), rg.relationships[fmt.Sprintf("%s:member", current.node)]...)
+			for _, groupRel := range groups {
+				if visited[groupRel.Object] || !rg.caveatHolds(groupRel) {
+					continue
+				}
+				visited[groupRel.Object] = true
+				groupName := groupRel.Object
+				path := appendHop(current.path, PathHop{Subject: current.node, Relation: "member", Object: groupName})
+
+				// Check if the group has the required permission on the object
+				groupRelationships := rg.directRelationships(groupName, object)
+				for _, rel := range groupRelationships {
+					if rg.HasPermissionThroughRelationship(rel.Relationship, permission) {
+						return true, appendHop(path, PathHop{Subject: groupName, Relation: rel.Relationship, Object: object})
+					}
 				}
+				next = append(next, membership{node: groupName, path: path})
 			}
 		}
+		frontier = next
 	}
 
-	return false, ""
+	return false, nil
+}
+
+// maxGroupDepth returns how many levels of nested groups group access follows
+func (rg *RelationshipGraph) maxGroupDepth() int {
+	if rg.groupDepth <= 0 {
+		return defaultGroupDepth
+	}
+	return rg.groupDepth
 }
 
 // checkHierarchicalAccess checks access through parent-child relationships
-func (rg *RelationshipGraph) checkHierarchicalAccess(subject, object, permission string) (bool, string) {
+func (rg *RelationshipGraph) checkHierarchicalAccess(subject, object, permission string) (bool, []PathHop) {
 	// Find parent objects
-	for key, relationships := range rg.relationships {
-		parts := strings.Split(key, ":")
-		if len(parts) != 2 || parts[1] != "parent" {
-			continue
+	rg.ensureObjectLoaded(object)
+	var parentObjects []string
+	for _, rel := range rg.reverse.incomingTo(object) {
+		if rel.Relationship == "parent" && rg.caveatHolds(rel) {
+			parentObjects = append(parentObjects, rel.Subject)
 		}
+	}
 
-		parentObject := parts[0]
-		for _, rel := range relationships {
-			if rel.Object == object {
-				// Recursively check if subject has access to parent
-				hasAccess, parentPath := rg.CheckReBACAccess(subject, parentObject, permission)
-				if hasAccess {
-					path := fmt.Sprintf("%s -> %s -[parent]-> %s", parentPath, parentObject, object)
-					return true, path
-				}
-			}
+	for _, parentObject := range parentObjects {
+		// Recursively check if subject has access to parent
+		hasAccess, parentPath := rg.checkReBACAccessHops(subject, parentObject, permission)
+		if hasAccess {
+			return true, appendHop(parentPath, PathHop{Subject: parentObject, Relation: "parent", Object: object})
 		}
 	}
 
-	return false, ""
+	return false, nil
 }
 
 // checkSocialAccess checks access through social relationships (e.g., friend connections)
-func (rg *RelationshipGraph) checkSocialAccess(subject, object string, maxDepth int) (bool, string) {
-	found, path := rg.FindRelationshipPath(subject, object, maxDepth)
-	if found && strings.Contains(path, "friend") {
+func (rg *RelationshipGraph) checkSocialAccess(subject, object string, maxDepth int) (bool, []PathHop) {
+	found, path := rg.findRelationshipHops(subject, object, maxDepth)
+	if found && pathHasRelation(path, "friend") {
 		// Verify that the friend relationship grants the required permission
 		if rg.HasPermissionThroughRelationship("friend", "read_limited") {
 			return true, path
 		}
 	}
-	return false, ""
+	return false, nil
 }
 
 // AuthService manages multiple authorization models
 type AuthService struct {
-	aclEnforcer       *casbin.Enforcer
-	rbacEnforcer      *casbin.Enforcer
-	abacEnforcer      *casbin.Enforcer
-	userAttrs         map[string]map[string]string // User attributes cache for ABAC
-	objectAttrs       map[string]map[string]string // Object attributes cache for ABAC
-	relationshipGraph *RelationshipGraph           // Relationship graph for ReBAC
-	policyEngine      *PolicyEngine                // ABAC policy engine
-	db                *gorm.DB                     // Database connection for ABAC persistence
+	aclEnforcer       *casbin.SyncedEnforcer // Synced, as peers' changes are reloaded while checks run
+	rbacEnforcer      *casbin.SyncedEnforcer
+	abacEnforcer      *casbin.SyncedEnforcer
+	userAttrs         *attributeCache     // User attributes cache for ABAC (nil when disabled)
+	objectAttrs       *attributeCache     // Object attributes cache for ABAC (nil when disabled)
+	relationshipGraph *RelationshipGraph  // Relationship graph for ReBAC
+	policyEngine      *PolicyEngine       // ABAC policy engine
+	db                *gorm.DB            // Database connection for ABAC persistence
+	errorReporter     ErrorReporter       // Receives recovered panics (optional)
+	auditDecisions    bool                // Record authorization decisions in the audit log (opt-in, a database write per check)
+	rbacGroupBindings bool                // Let RBAC roles bound to ReBAC groups apply to their members
+	sloTracker        *sloTracker         // Decision latency SLO tracking (optional)
+	enforceLimiter    *concurrencyLimiter // Bounds in-flight enforce requests (nil when unlimited)
+	schemaEnforce     bool                // Reject attribute schema violations instead of warning
+	demoMode          bool                // Load the sample organization and expose demo scenarios
+	changeApproval    bool                // Hold policy and relationship changes until a second admin approves them
+	decisionRetention *decisionRetention  // Archives and deletes old audited decisions (nil keeps them)
+	decisionTracer    *decisionTracer     // Verbose tracing of decisions about selected subjects and objects
+	authenticator     *authenticator      // API key and JWT authentication (nil leaves the API open)
+	idempotency       *idempotencyStore   // Responses of writes sent with an Idempotency-Key
+	knownTenants      sync.Map            // Names of tenants known to exist
+	tenantQuotas      tenantQuotas        // Default tenant quotas and the soft limit warned about
+	webhooks          *webhookDispatcher  // Delivers change events to registered webhooks (nil disables them)
+	invalidator       *invalidator        // Broadcasts changes to and applies changes from other instances (nil when running alone)
+	decisions         *decisionCache      // Cached enforce results (nil when caching is disabled)
+	attributeRevision uint64              // Incremented on every attribute write to invalidate cached decisions
+	attributeTypes    attributeTypeIndex  // Value types of typed ABAC attribute names
+	ruleConditions    *ruleConditions     // ABAC conditions of conditional RBAC rules
+	actionHierarchy   *actionHierarchy    // Actions granting others, e.g. write implies read
+	scimMu            sync.Mutex          // Serializes SCIM provisioning, which derives membership changes from stored groups
+	ldapSync          *ldapSync           // Reconciles LDAP groups into roles and member tuples (nil when disabled)
+
+	relationshipSweepInterval time.Duration         // How often expired relationship tuples are purged
+	watchRetention            time.Duration         // How long relationship changes stay in the change log
+	watchers                  relationshipWatchers  // Open relationship watch streams of this instance
+	attributeResolution       *attributeResolution  // External attribute lookups for ABAC (nil when disabled)
+	environmentProviders      []EnvironmentProvider // Compute environment attributes of ABAC checks
+	tokenAuthorization        *tokenAuthorization   // Verifies end-user JWTs for token authorization (nil when disabled)
+	extAuthz                  *ExtAuthzConfig       // Rules for requests checked by Envoy's ext_authz filter (nil when disabled)
+	kubernetesWebhook         kubernetesWebhook     // How Kubernetes SubjectAccessReviews are checked
+
+	settings atomic.Pointer[runtimeSettings] // Values configuration reloads change (defaults when unset)
 }
 
-// ACL model definition
+const (
+	// defaultMaxDepth is the traversal depth used when callers do not specify max_depth and
+	// REBAC_DEFAULT_MAX_DEPTH is not set
+	defaultMaxDepth = 5
+
+	// defaultMaxDepthLimit is the absolute maximum traversal depth when MAX_DEPTH_LIMIT is not set
+	defaultMaxDepthLimit = 10
+
+	// defaultGroupDepth is how many levels of nested groups group access follows when
+	// REBAC_GROUP_MAX_DEPTH is not set
+	defaultGroupDepth = 5
+)
+
+// ACL model definition (deny rules override allow rules). Objects and actions of rules may
+// be patterns, see aclPatternMatch. Allow rules also grant the actions their action
+// implies, see actionHierarchy.
 const aclModel = `[request_definition]
 r = sub, obj, act
 
 [policy_definition]
-p = sub, obj, act
+p = sub, obj, act, eft
 
 [policy_effect]
-e = some(where (p.eft == allow))
+e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
 
 [matchers]
-m = r.sub == p.sub && r.obj == p.obj && r.act == p.act`
+m = r.sub == p.sub && aclMatch(r.obj, p.obj) && (aclMatch(r.act, p.act) || impliedAction(r.act, p.act, p.eft))`
 
-// RBAC model definition
+// RBAC model definition (deny rules override allow rules, including inherited ones). g2
+// puts objects in resource groups; HasLink also matches an object to itself. ruleCondition
+// checks the ABAC condition a rule may carry, and impliedAction the actions an allow rule's
+// action implies.
 const rbacModel = `[request_definition]
 r = sub, obj, act
 
 [policy_definition]
-p = sub, obj, act
+p = sub, obj, act, eft
 
 [role_definition]
 g = _, _
+g2 = _, _
 
 [policy_effect]
-e = some(where (p.eft == allow))
+e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
 
 [matchers]
-m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act`
+m = g(r.sub, p.sub) && g2(r.obj, p.obj) && (r.act == p.act || impliedAction(r.act, p.act, p.eft)) && ruleCondition(r.sub, r.obj, r.act, p.sub, p.obj, p.act)`
 
 // ABAC model definition (simplified version)
 const abacModel = `[request_definition]
@@ -615,10 +1001,14 @@ m = keyMatch(r.sub, p.sub) && keyMatch(r.obj, p.obj) && keyMatch(r.act, p.act)`
 
 // NewAuthService creates a new authorization service with multiple models
 func NewAuthService() (*AuthService, error) {
-	// Connect to SQLite database
-	db, err := gorm.Open(sqlite.Open("casbin.db"), &gorm.Config{})
+	// Connect to the database, a SQLite file unless DB_DRIVER and DB_DSN say otherwise
+	dialector, err := databaseFromEnv()
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to SQLite database: %v", err)
+		return nil, err
+	}
+	db, err := openDatabase(dialector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s database: %v", dialector.Name(), err)
 	}
 
 	// Create adapters for each model
@@ -642,16 +1032,17 @@ func NewAuthService() (*AuthService, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ACL model: %v", err)
 	}
-	aclEnforcer, err := casbin.NewEnforcer(aclModelObj, aclAdapter)
+	aclEnforcer, err := casbin.NewSyncedEnforcer(aclModelObj, aclAdapter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ACL enforcer: %v", err)
 	}
+	registerACLFunctions(aclEnforcer)
 
 	rbacModelObj, err := model.NewModelFromString(rbacModel)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create RBAC model: %v", err)
 	}
-	rbacEnforcer, err := casbin.NewEnforcer(rbacModelObj, rbacAdapter)
+	rbacEnforcer, err := casbin.NewSyncedEnforcer(rbacModelObj, rbacAdapter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create RBAC enforcer: %v", err)
 	}
@@ -660,11 +1051,19 @@ func NewAuthService() (*AuthService, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ABAC model: %v", err)
 	}
-	abacEnforcer, err := casbin.NewEnforcer(abacModelObj, abacAdapter)
+	abacEnforcer, err := casbin.NewSyncedEnforcer(abacModelObj, abacAdapter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ABAC enforcer: %v", err)
 	}
 
+	// Rules stored before deny rules existed have no effect and are allow rules
+	if err := migrateRuleEffects(db, "acl_rules"); err != nil {
+		return nil, fmt.Errorf("failed to migrate ACL rule effects: %v", err)
+	}
+	if err := migrateRuleEffects(db, "rbac_rules"); err != nil {
+		return nil, fmt.Errorf("failed to migrate RBAC rule effects: %v", err)
+	}
+
 	// Load policies
 	aclEnforcer.LoadPolicy()
 	rbacEnforcer.LoadPolicy()
@@ -681,12 +1080,150 @@ func NewAuthService() (*AuthService, error) {
 		return nil, fmt.Errorf("failed to migrate ABAC tables: %v", err)
 	}
 
-	// Create relationship graph with database persistence
-	relationshipGraph, err := NewRelationshipGraph(db)
+	// Auto-migrate the decision audit log
+	err = db.AutoMigrate(&DecisionRecord{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate audit tables: %v", err)
+	}
+
+	// Auto-migrate labels attached to policies and relationships
+	err = db.AutoMigrate(&ResourceLabel{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate label table: %v", err)
+	}
+
+	// Auto-migrate provenance metadata of ACL/RBAC rules
+	err = db.AutoMigrate(&PolicyMetadata{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate policy metadata table: %v", err)
+	}
+
+	// Auto-migrate the ABAC conditions of RBAC rules
+	err = db.AutoMigrate(&RuleCondition{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate rule condition table: %v", err)
+	}
+
+	// Auto-migrate the action hierarchy
+	err = db.AutoMigrate(&ActionImplication{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate action hierarchy table: %v", err)
+	}
+
+	// Auto-migrate the revision history of ACL/RBAC rules and ABAC policies
+	err = db.AutoMigrate(&PolicyRevision{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate policy revision table: %v", err)
+	}
+
+	// Auto-migrate change requests awaiting or decided by a second admin
+	err = db.AutoMigrate(&ChangeRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate change request table: %v", err)
+	}
+
+	// Auto-migrate tenants and their bootstrap templates
+	err = db.AutoMigrate(&Tenant{}, &TenantTemplateRecord{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate tenant tables: %v", err)
+	}
+	if err := migratePolicyTenants(db); err != nil {
+		return nil, fmt.Errorf("failed to migrate ABAC policy tenants: %v", err)
+	}
+
+	// Auto-migrate the ABAC attribute schema registry
+	err = db.AutoMigrate(&AttributeDefinition{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate attribute schema table: %v", err)
+	}
+
+	// Auto-migrate webhooks notified of policy and relationship changes
+	err = db.AutoMigrate(&Webhook{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate webhook table: %v", err)
+	}
+
+	// Auto-migrate the relationship change log streamed to watchers
+	err = db.AutoMigrate(&RelationshipChange{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate relationship change table: %v", err)
+	}
+
+	// Auto-migrate the users and groups provisioned through SCIM
+	err = db.AutoMigrate(&SCIMUser{}, &SCIMGroup{}, &SCIMMember{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate SCIM tables: %v", err)
+	}
+
+	// Auto-migrate the assignments made by the LDAP group sync
+	err = db.AutoMigrate(&LDAPSyncAssignment{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate LDAP sync table: %v", err)
+	}
+
+	// Create relationship graph with database persistence. Large deployments can keep
+	// only the most recently used object namespaces in memory, or load objects lazily.
+	var relationshipGraph *RelationshipGraph
+	if os.Getenv("REBAC_LAZY_LOADING") == "true" {
+		size := defaultLazyCacheSize
+		if sizeStr := os.Getenv("REBAC_LAZY_CACHE_SIZE"); sizeStr != "" {
+			var convErr error
+			size, convErr = strconv.Atoi(sizeStr)
+			if convErr != nil || size <= 0 {
+				return nil, fmt.Errorf("invalid REBAC_LAZY_CACHE_SIZE value: %s", sizeStr)
+			}
+		}
+		relationshipGraph, err = NewLazyRelationshipGraph(db, size)
+	} else if sizeStr := os.Getenv("REBAC_PARTITION_CACHE_SIZE"); sizeStr != "" {
+		size, convErr := strconv.Atoi(sizeStr)
+		if convErr != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid REBAC_PARTITION_CACHE_SIZE value: %s", sizeStr)
+		}
+		relationshipGraph, err = NewPartitionedRelationshipGraph(db, size, os.Getenv("REBAC_PARTITION_DELIMITER"))
+	} else {
+		relationshipGraph, err = NewRelationshipGraph(db)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create relationship graph: %v", err)
 	}
 
+	// Checks load tuples from a read replica, when configured, once it has caught up
+	relationshipGraph.replica, err = replicaFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if relationshipGraph.replica != nil {
+		relationshipGraph.replica.start()
+	}
+
+	// Size the ReBAC check result cache (0 disables it)
+	checkCacheSize, err := rebacCheckCacheSizeFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	relationshipGraph.SetCheckCacheSize(checkCacheSize)
+
+	// Limit how deeply nested groups grant their members access
+	if depthStr := os.Getenv("REBAC_GROUP_MAX_DEPTH"); depthStr != "" {
+		depth, convErr := strconv.Atoi(depthStr)
+		if convErr != nil || depth <= 0 {
+			return nil, fmt.Errorf("invalid REBAC_GROUP_MAX_DEPTH value: %s", depthStr)
+		}
+		relationshipGraph.groupDepth = depth
+	}
+
+	// Enforce cardinality limits such as "at most one owner per object"
+	relationshipGraph.constraints, err = parseRelationshipConstraints(os.Getenv("REBAC_CONSTRAINTS"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Decide whether tuples violating the declared types are rejected or only reported
+	relationshipGraph.enforceTypes, err = parseSchemaMode("REBAC_SCHEMA_MODE", os.Getenv("REBAC_SCHEMA_MODE"))
+	if err != nil {
+		return nil, err
+	}
+
 	// Create and initialize policy engine
 	policyEngine := NewPolicyEngine(db)
 	err = policyEngine.LoadPolicies()
@@ -698,120 +1235,282 @@ func NewAuthService() (*AuthService, error) {
 		aclEnforcer:       aclEnforcer,
 		rbacEnforcer:      rbacEnforcer,
 		abacEnforcer:      abacEnforcer,
-		userAttrs:         make(map[string]map[string]string),
-		objectAttrs:       make(map[string]map[string]string),
 		relationshipGraph: relationshipGraph,
 		policyEngine:      policyEngine,
 		db:                db,
+		auditDecisions:    os.Getenv("AUDIT_DECISIONS") == "true",
+		rbacGroupBindings: os.Getenv("RBAC_REBAC_GROUPS") == "true",
+		demoMode:          os.Getenv("DEMO_MODE") == "true",
+		changeApproval:    os.Getenv("CHANGE_APPROVAL") == "true",
+		decisionTracer:    newDecisionTracer(),
 	}
 
-	// Load ABAC attributes from database
-	err = service.loadABACAttributes()
+	// Traversal depths, CORS origins and enabled models can change on configuration reloads
+	settings, err := runtimeSettingsFromEnv()
 	if err != nil {
-		return nil, fmt.Errorf("failed to load ABAC attributes: %v", err)
+		return nil, err
 	}
+	service.settings.Store(settings)
 
-	return service, nil
-}
+	// Decide whether attribute schema violations are rejected or only reported
+	service.schemaEnforce, err = parseSchemaMode("ABAC_SCHEMA_MODE", os.Getenv("ABAC_SCHEMA_MODE"))
+	if err != nil {
+		return nil, err
+	}
 
-// loadABACAttributes loads user and object attributes from database into memory cache
-func (s *AuthService) loadABACAttributes() error {
-	// Load user attributes
-	var userAttrs []UserAttribute
-	result := s.db.Find(&userAttrs)
-	if result.Error != nil {
-		return fmt.Errorf("failed to load user attributes: %v", result.Error)
+	// Remember the value types of typed attributes so policies compare them by type
+	if err := service.attributeTypes.load(db); err != nil {
+		return nil, err
 	}
 
-	// Group user attributes by user ID
-	for _, attr := range userAttrs {
-		if s.userAttrs[attr.UserID] == nil {
-			s.userAttrs[attr.UserID] = make(map[string]string)
-		}
-		s.userAttrs[attr.UserID][attr.Attribute] = attr.Value
+	// Escape colons inside the parts of resource keys stored by earlier versions
+	if err := service.migrateResourceKeys(); err != nil {
+		return nil, err
 	}
 
-	// Load object attributes
-	var objectAttrs []ObjectAttribute
-	result = s.db.Find(&objectAttrs)
-	if result.Error != nil {
-		return fmt.Errorf("failed to load object attributes: %v", result.Error)
+	// RBAC rules may carry ABAC conditions, checked with the user and object attributes
+	if err := service.registerRBACConditions(rbacEnforcer); err != nil {
+		return nil, err
+	}
+
+	// Actions may imply others, e.g. write implies read, in ACL, RBAC and ABAC checks
+	if err := service.registerActionHierarchy(aclEnforcer, rbacEnforcer); err != nil {
+		return nil, err
+	}
+
+	// Shed enforce requests with 503 once in-flight and queue limits are reached
+	service.enforceLimiter, err = enforceLimiterFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Track decision latency against the configured SLO
+	service.sloTracker, err = sloTrackerFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Forward recovered panics to an external error tracker when configured
+	if reportURL := os.Getenv("PANIC_REPORT_URL"); reportURL != "" {
+		service.errorReporter = newWebhookErrorReporter(reportURL)
+	}
+
+	// Cache ABAC attributes of recently used users and objects
+	service.userAttrs, service.objectAttrs, err = attributeCachesFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch attributes the service does not store from external information points
+	service.attributeResolution, err = attributeResolutionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Compute environment attributes, such as the country of the client IP, for ABAC
+	service.environmentProviders, err = environmentProvidersFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Authorize the subjects of end-user JWTs signed by keys of a JWKS
+	service.tokenAuthorization, err = tokenAuthorizationFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Map requests checked by Envoy's ext_authz filter to authorization checks
+	service.extAuthz, err = extAuthzConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Check Kubernetes SubjectAccessReviews with the configured model
+	service.kubernetesWebhook, err = kubernetesWebhookFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Bound the audit log by archiving and deleting decisions past the hot period
+	service.decisionRetention, err = decisionRetentionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Reconcile LDAP or Active Directory groups into role assignments
+	service.ldapSync, err = ldapSyncFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Purge expired relationship tuples from the database periodically
+	service.relationshipSweepInterval, err = relationshipSweepIntervalFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	service.watchRetention, err = watchRetentionFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Require API keys or bearer tokens, separating read clients from admins
+	service.authenticator, err = authenticatorFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	// Without authentication the actor is a client supplied header, so anyone could approve a change
+	if service.changeApproval && service.authenticator == nil {
+		return nil, fmt.Errorf("CHANGE_APPROVAL requires authentication; set AUTH_API_KEYS, AUTH_JWT_SECRET or AUTH_CONFIG_FILE")
+	}
+
+	// Bound what each tenant may store
+	service.tenantQuotas, err = tenantQuotasFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Replay the responses of retried writes sent with an Idempotency-Key
+	idempotencyTTL, err := idempotencyKeyTTLFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	service.idempotency, err = newIdempotencyStore(db, idempotencyTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cache enforce results until the data they depend on changes
+	cacheSize, cacheTTL, err := decisionCacheConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err := service.enableDecisionCache(cacheSize, cacheTTL); err != nil {
+		return nil, err
+	}
+
+	// Notify registered webhooks of changes to authorization data
+	service.webhooks, err = newWebhookDispatcher(db)
+	if err != nil {
+		return nil, err
 	}
 
-	// Group object attributes by object ID
-	for _, attr := range objectAttrs {
-		if s.objectAttrs[attr.ObjectID] == nil {
-			s.objectAttrs[attr.ObjectID] = make(map[string]string)
+	// Keep the caches of other instances sharing the database in step with our changes
+	bus, err := invalidationBusFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if bus != nil {
+		if err := service.startInvalidation(bus); err != nil {
+			return nil, err
 		}
-		s.objectAttrs[attr.ObjectID][attr.Attribute] = attr.Value
 	}
 
-	return nil
+	return service, nil
 }
 
-// saveUserAttribute saves a user attribute to database and updates cache
+// saveUserAttribute saves a user attribute with the value type its name already has
 func (s *AuthService) saveUserAttribute(userID, attribute, value string) error {
+	return s.saveTypedUserAttribute(userID, attribute, value, "")
+}
+
+// saveTypedUserAttribute saves a user attribute to database and invalidates its cache entry.
+// The value is checked against its value type and stored in the type's canonical form.
+func (s *AuthService) saveTypedUserAttribute(userID, attribute, value, valueType string) error {
+	valueType, value, err := s.resolveAttributeValue("user", attribute, value, valueType)
+	if err != nil {
+		return err
+	}
+
+	if err := saveUserAttributeIn(s.db, userID, attribute, value, valueType); err != nil {
+		return err
+	}
+	s.attributeTypes.set("user", attribute, valueType)
+
+	// Reload the user's attributes on next use
+	s.invalidateUserAttributes(userID)
+
+	return nil
+}
+
+// saveUserAttributeIn creates or updates a resolved user attribute in db, which can be a
+// transaction
+func saveUserAttributeIn(db *gorm.DB, userID, attribute, value, valueType string) error {
 	// Check if attribute already exists
 	var existingAttr UserAttribute
-	result := s.db.Where("user_id = ? AND attribute = ?", userID, attribute).First(&existingAttr)
+	result := db.Where("user_id = ? AND attribute = ?", userID, attribute).First(&existingAttr)
 
 	if result.Error == nil {
 		// Update existing attribute
 		existingAttr.Value = value
-		result = s.db.Save(&existingAttr)
+		existingAttr.ValueType = valueType
+		result = db.Save(&existingAttr)
 	} else {
 		// Create new attribute
 		newAttr := UserAttribute{
 			UserID:    userID,
 			Attribute: attribute,
 			Value:     value,
+			ValueType: valueType,
 		}
-		result = s.db.Create(&newAttr)
+		result = db.Create(&newAttr)
 	}
 
 	if result.Error != nil {
 		return fmt.Errorf("failed to save user attribute: %v", result.Error)
 	}
+	return nil
+}
+
+// saveObjectAttribute saves an object attribute with the value type its name already has
+func (s *AuthService) saveObjectAttribute(objectID, attribute, value string) error {
+	return s.saveTypedObjectAttribute(objectID, attribute, value, "")
+}
+
+// saveTypedObjectAttribute saves an object attribute to database and invalidates its cache entry.
+// The value is checked against its value type and stored in the type's canonical form.
+func (s *AuthService) saveTypedObjectAttribute(objectID, attribute, value, valueType string) error {
+	valueType, value, err := s.resolveAttributeValue("object", attribute, value, valueType)
+	if err != nil {
+		return err
+	}
 
-	// Update cache
-	if s.userAttrs[userID] == nil {
-		s.userAttrs[userID] = make(map[string]string)
+	if err := saveObjectAttributeIn(s.db, objectID, attribute, value, valueType); err != nil {
+		return err
 	}
-	s.userAttrs[userID][attribute] = value
+	s.attributeTypes.set("object", attribute, valueType)
+
+	// Reload the object's attributes on next use
+	s.invalidateObjectAttributes(objectID)
 
 	return nil
 }
 
-// saveObjectAttribute saves an object attribute to database and updates cache
-func (s *AuthService) saveObjectAttribute(objectID, attribute, value string) error {
+// saveObjectAttributeIn creates or updates a resolved object attribute in db, which can be
+// a transaction
+func saveObjectAttributeIn(db *gorm.DB, objectID, attribute, value, valueType string) error {
 	// Check if attribute already exists
 	var existingAttr ObjectAttribute
-	result := s.db.Where("object_id = ? AND attribute = ?", objectID, attribute).First(&existingAttr)
+	result := db.Where("object_id = ? AND attribute = ?", objectID, attribute).First(&existingAttr)
 
 	if result.Error == nil {
 		// Update existing attribute
 		existingAttr.Value = value
-		result = s.db.Save(&existingAttr)
+		existingAttr.ValueType = valueType
+		result = db.Save(&existingAttr)
 	} else {
 		// Create new attribute
 		newAttr := ObjectAttribute{
 			ObjectID:  objectID,
 			Attribute: attribute,
 			Value:     value,
+			ValueType: valueType,
 		}
-		result = s.db.Create(&newAttr)
+		result = db.Create(&newAttr)
 	}
 
 	if result.Error != nil {
 		return fmt.Errorf("failed to save object attribute: %v", result.Error)
 	}
-
-	// Update cache
-	if s.objectAttrs[objectID] == nil {
-		s.objectAttrs[objectID] = make(map[string]string)
-	}
-	s.objectAttrs[objectID][attribute] = value
-
 	return nil
 }
 
@@ -846,23 +1545,39 @@ func (pe *PolicyEngine) LoadPolicies() error {
 		return fmt.Errorf("failed to load policies: %v", err)
 	}
 
-	pe.policies = make(map[string]*ABACPolicy)
+	loaded := make(map[string]*ABACPolicy, len(policies))
 	for _, policy := range policies {
-		pe.policies[policy.ID] = &policy
+		loaded[policy.ID] = &policy
 	}
+	pe.mu.Lock()
+	pe.policies = loaded
+	pe.mu.Unlock()
+	atomic.AddUint64(&pe.revision, 1)
 
 	return nil
 }
 
-// AddPolicy adds a new policy to the engine
+// Revision returns the current policy revision, which increments on every policy change
+func (pe *PolicyEngine) Revision() uint64 {
+	return atomic.LoadUint64(&pe.revision)
+}
+
+// AddPolicy adds a new policy to the engine. A policy without a version starts at 1.
 func (pe *PolicyEngine) AddPolicy(policy *ABACPolicy) error {
+	if policy.Version == 0 {
+		policy.Version = 1
+	}
+
 	// Save to database
 	if err := pe.db.Create(policy).Error; err != nil {
 		return fmt.Errorf("failed to save policy: %v", err)
 	}
 
 	// Add to memory cache
+	pe.mu.Lock()
 	pe.policies[policy.ID] = policy
+	pe.mu.Unlock()
+	atomic.AddUint64(&pe.revision, 1)
 	return nil
 }
 
@@ -879,17 +1594,36 @@ func (pe *PolicyEngine) RemovePolicy(policyID string) error {
 	}
 
 	// Remove from memory cache
+	pe.mu.Lock()
 	delete(pe.policies, policyID)
+	pe.mu.Unlock()
+	atomic.AddUint64(&pe.revision, 1)
 	return nil
 }
 
-// Evaluate evaluates all policies against the given context
-func (pe *PolicyEngine) Evaluate(ctx *PolicyEvaluationContext) (bool, string) {
-	// Sort policies by priority (higher priority first)
-	var sortedPolicies []*ABACPolicy
+// getPolicy returns the policy with the given ID
+func (pe *PolicyEngine) getPolicy(policyID string) (*ABACPolicy, bool) {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+	policy, exists := pe.policies[policyID]
+	return policy, exists
+}
+
+// policyList returns the policies in no particular order. The slice is a snapshot, so
+// callers may iterate it while policies change.
+func (pe *PolicyEngine) policyList() []*ABACPolicy {
+	pe.mu.RLock()
+	defer pe.mu.RUnlock()
+	policies := make([]*ABACPolicy, 0, len(pe.policies))
 	for _, policy := range pe.policies {
-		sortedPolicies = append(sortedPolicies, policy)
+		policies = append(policies, policy)
 	}
+	return policies
+}
+
+// sortedPolicies returns the policies in evaluation order (higher priority first)
+func (pe *PolicyEngine) sortedPolicies() []*ABACPolicy {
+	sortedPolicies := pe.policyList()
 
 	// Simple sort by priority (descending)
 	for i := 0; i < len(sortedPolicies); i++ {
@@ -899,24 +1633,70 @@ func (pe *PolicyEngine) Evaluate(ctx *PolicyEvaluationContext) (bool, string) {
 			}
 		}
 	}
+	return sortedPolicies
+}
 
-	// Evaluate policies in priority order
-	for _, policy := range sortedPolicies {
+// Evaluate evaluates all policies against the given context
+func (pe *PolicyEngine) Evaluate(ctx *PolicyEvaluationContext) (bool, string) {
+	decision := pe.Decide(ctx)
+	return decision.Allowed, decision.Reason
+}
+
+// Decide evaluates all policies against the given context and returns the policy that
+// decided, if any
+func (pe *PolicyEngine) Decide(ctx *PolicyEvaluationContext) PolicyDecision {
+	// Evaluate policies in priority order, skipping those scoped to other actions or tenants;
+	// global checks only see untenanted policies
+	for _, policy := range pe.sortedPolicies() {
+		if !pe.appliesToAction(policy, ctx.Action) || policy.Tenant != ctx.Tenant {
+			continue
+		}
 		if pe.evaluatePolicy(policy, ctx) {
 			if policy.Effect == "allow" {
-				return true, fmt.Sprintf("Access granted by policy: %s", policy.Name)
+				return PolicyDecision{Allowed: true, Reason: fmt.Sprintf("Access granted by policy: %s", policy.Name), Policy: policy}
 			} else if policy.Effect == "deny" {
-				return false, fmt.Sprintf("Access denied by policy: %s", policy.Name)
+				return PolicyDecision{Allowed: false, Reason: fmt.Sprintf("Access denied by policy: %s", policy.Name), Policy: policy}
 			}
 		}
 	}
 
 	// Default deny if no policy matches
-	return false, "No policy grants access"
+	return PolicyDecision{Allowed: false, Reason: "No policy grants access"}
+}
+
+// AppliesToAction reports whether the policy is scoped to action. Policies without an
+// actions list apply to every action.
+func (policy *ABACPolicy) AppliesToAction(action string) bool {
+	if len(policy.Actions) == 0 {
+		return true
+	}
+	for _, candidate := range policy.Actions {
+		if candidate == action {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePolicyActions rejects empty entries in a policy's actions list
+func validatePolicyActions(actions []string) error {
+	for _, action := range actions {
+		if strings.TrimSpace(action) == "" {
+			return fmt.Errorf("actions must not contain empty values")
+		}
+	}
+	return nil
 }
 
 // evaluatePolicy evaluates a single policy against the context
 func (pe *PolicyEngine) evaluatePolicy(policy *ABACPolicy, ctx *PolicyEvaluationContext) bool {
+	if policy.Expression != "" {
+		matched, _ := pe.evaluateExpression(policy.Expression, ctx)
+		return matched
+	}
+	if policy.Group != nil {
+		return pe.evaluateGroup(policy.Group, ctx)
+	}
 	if len(policy.Conditions) == 0 {
 		return false
 	}
@@ -948,56 +1728,109 @@ func (pe *PolicyEngine) evaluatePolicy(policy *ABACPolicy, ctx *PolicyEvaluation
 
 // evaluateCondition evaluates a single condition
 func (pe *PolicyEngine) evaluateCondition(condition *PolicyCondition, ctx *PolicyEvaluationContext) bool {
-	var actualValue string
+	actualValue, known := pe.conditionValue(condition, ctx)
+	if !known {
+		return false
+	}
+
+	expected := condition.Value
+	if condition.ValueFrom != "" {
+		// A missing attribute on either side never matches, so two unset attributes are not equal
+		reference, ok := attributeReference(condition.ValueFrom)
+		if !ok {
+			return false
+		}
+		expected, known = pe.conditionValue(&reference, ctx)
+		if !known || expected == "" || actualValue == "" {
+			return false
+		}
+	}
 
+	// Evaluate based on operator, comparing typed attributes by their type
+	return pe.evaluateOperator(actualValue, condition.Operator, expected, pe.conditionValueType(condition, ctx))
+}
+
+// conditionValue looks up the value a condition compares against; known is false for
+// unsupported condition types
+func (pe *PolicyEngine) conditionValue(condition *PolicyCondition, ctx *PolicyEvaluationContext) (value string, known bool) {
 	// Get the actual value based on condition type
 	switch condition.Type {
 	case "user":
-		actualValue = ctx.UserAttributes[condition.Field]
+		return ctx.UserAttributes[condition.Field], true
 	case "object":
-		actualValue = ctx.ObjectAttributes[condition.Field]
+		return ctx.ObjectAttributes[condition.Field], true
 	case "environment":
-		actualValue = ctx.EnvironmentAttributes[condition.Field]
+		return ctx.EnvironmentAttributes[condition.Field], true
+	case "request":
+		return ctx.RequestAttributes[condition.Field], true
 	case "action":
 		if condition.Field == "action" {
-			actualValue = ctx.Action
-		} else {
-			actualValue = ctx.ActionAttributes[condition.Field]
+			return ctx.Action, true
 		}
+		return ctx.ActionAttributes[condition.Field], true
 	case "subject":
 		if condition.Field == "subject" {
-			actualValue = ctx.Subject
+			return ctx.Subject, true
 		}
+		return "", true
 	case "resource":
 		if condition.Field == "object" {
-			actualValue = ctx.Object
+			return ctx.Object, true
 		}
+		return "", true
 	default:
-		return false
+		return "", false
 	}
+}
 
-	// Evaluate based on operator
-	return pe.evaluateOperator(actualValue, condition.Operator, condition.Value)
+// attributeReference parses the "<type>.<field>" reference of a condition's value_from into
+// a condition reading that attribute; ok is false for malformed references
+func attributeReference(reference string) (condition PolicyCondition, ok bool) {
+	conditionType, field, found := strings.Cut(reference, ".")
+	if !found || conditionType == "" || field == "" {
+		return PolicyCondition{}, false
+	}
+	return PolicyCondition{Type: conditionType, Field: field}, true
 }
 
-// evaluateOperator performs the actual comparison
-func (pe *PolicyEngine) evaluateOperator(actual, operator, expected string) bool {
+// evaluateOperator performs the actual comparison. Values of a known type compare by that
+// type: numbers, booleans and dates by value, dates chronologically, and lists by element.
+func (pe *PolicyEngine) evaluateOperator(actual, operator, expected, valueType string) bool {
 	switch operator {
 	case "eq":
-		return actual == expected
+		return typedEqual(actual, expected, valueType)
 	case "ne":
-		return actual != expected
+		return !typedEqual(actual, expected, valueType)
 	case "gt":
-		return pe.compareNumeric(actual, expected) > 0
+		return pe.compareTyped(actual, expected, valueType) > 0
 	case "gte":
-		return pe.compareNumeric(actual, expected) >= 0
+		return pe.compareTyped(actual, expected, valueType) >= 0
 	case "lt":
-		return pe.compareNumeric(actual, expected) < 0
+		return pe.compareTyped(actual, expected, valueType) < 0
 	case "lte":
-		return pe.compareNumeric(actual, expected) <= 0
+		return pe.compareTyped(actual, expected, valueType) <= 0
+	case "before":
+		order, ok := compareDates(actual, expected)
+		return ok && order < 0
+	case "after":
+		order, ok := compareDates(actual, expected)
+		return ok && order > 0
 	case "in":
+		if valueType == valueTypeList {
+			// Every element of the list must be one of the expected values
+			elements := splitListValue(actual)
+			for _, element := range elements {
+				if !pe.evaluateIn(element, expected) {
+					return false
+				}
+			}
+			return len(elements) > 0
+		}
 		return pe.evaluateIn(actual, expected)
 	case "contains":
+		if valueType == valueTypeList {
+			return listContains(actual, expected)
+		}
 		return strings.Contains(actual, expected)
 	case "startswith":
 		return strings.HasPrefix(actual, expected)
@@ -1006,11 +1839,23 @@ func (pe *PolicyEngine) evaluateOperator(actual, operator, expected string) bool
 	case "regex":
 		matched, _ := regexp.MatchString(expected, actual)
 		return matched
+	case "cidr":
+		return pe.evaluateCIDR(actual, expected)
 	default:
 		return false
 	}
 }
 
+// compareTyped orders two values, chronologically for dates and numerically otherwise
+func (pe *PolicyEngine) compareTyped(actual, expected, valueType string) int {
+	if valueType == valueTypeDate {
+		if order, ok := compareDates(actual, expected); ok {
+			return order
+		}
+	}
+	return pe.compareNumeric(actual, expected)
+}
+
 // compareNumeric compares two string values as numbers
 func (pe *PolicyEngine) compareNumeric(actual, expected string) int {
 	actualNum, err1 := strconv.ParseFloat(actual, 64)
@@ -1040,50 +1885,151 @@ func (pe *PolicyEngine) evaluateIn(actual, expectedList string) bool {
 	return false
 }
 
-// getObjectAttributes retrieves object attributes from cache
-func (s *AuthService) getObjectAttributes(objectID string) map[string]string {
-	// Return a copy of the attributes map to avoid concurrent modification issues
-	if attrs, exists := s.objectAttrs[objectID]; exists {
-		result := make(map[string]string)
-		for k, v := range attrs {
-			result[k] = v
+// evaluateCIDR checks if actual is an IP address in one of the comma-separated CIDR ranges
+func (pe *PolicyEngine) evaluateCIDR(actual, expectedRanges string) bool {
+	ip := net.ParseIP(strings.TrimSpace(actual))
+	if ip == nil {
+		return false
+	}
+	for _, cidr := range strings.Split(expectedRanges, ",") {
+		if _, network, err := net.ParseCIDR(strings.TrimSpace(cidr)); err == nil && network.Contains(ip) {
+			return true
 		}
-		return result
 	}
+	return false
+}
 
-	// Return nil if object attributes don't exist
-	return nil
+// getObjectAttributes retrieves object attributes through the cache, or nil if the object has none
+func (s *AuthService) getObjectAttributes(objectID string) map[string]string {
+	var attrs map[string]string
+	var err error
+	if s.objectAttrs == nil {
+		attrs, err = s.getObjectAttributesFromDB(objectID)
+	} else {
+		attrs, err = s.objectAttrs.get(objectID, s.getObjectAttributesFromDB)
+	}
+	if err != nil || len(attrs) == 0 {
+		return nil
+	}
+	return attrs
 }
 
 // Enforce performs authorization check for the given model
 func (s *AuthService) Enforce(model AccessControlModel, subject, object, action string, attributes map[string]string) (bool, error) {
+	return s.EnforceWithFreshness(model, subject, object, action, attributes, freshnessDefault)
+}
+
+// EnforceWithFreshness performs an authorization check, bypassing in-memory attribute and
+// tuple caches when strong freshness is requested
+func (s *AuthService) EnforceWithFreshness(model AccessControlModel, subject, object, action string, attributes map[string]string, freshness string) (bool, error) {
+	return s.EnforceInTenant(tenantScope{}, model, subject, object, action, attributes, freshness)
+}
+
+// EnforceInTenant performs an authorization check within a tenant. The subject and object
+// are tenant-local names, and ABAC checks only consider the tenant's policies. Results are
+// served from the decision cache unless strong freshness is requested.
+func (s *AuthService) EnforceInTenant(scope tenantScope, model AccessControlModel, subject, object, action string, attributes map[string]string, freshness string) (bool, error) {
+	return s.EnforceWithTuples(scope, model, subject, object, action, attributes, freshness, nil)
+}
+
+// EnforceWithTuples performs an authorization check within a tenant that assumes the
+// contextual ReBAC tuples exist besides the stored ones. Tuples use tenant-local names.
+// Checks with contextual tuples bypass the decision cache.
+func (s *AuthService) EnforceWithTuples(scope tenantScope, model AccessControlModel, subject, object, action string, attributes map[string]string, freshness string, contextual []Relationship) (bool, error) {
+	decision, err := s.DecideWithTuples(scope, model, subject, object, action, attributes, freshness, contextual)
+	return decision.Allowed, err
+}
+
+// DecideWithTuples is EnforceWithTuples that also returns what produced the decision. Names
+// in the decision are tenant-qualified.
+func (s *AuthService) DecideWithTuples(scope tenantScope, model AccessControlModel, subject, object, action string, attributes map[string]string, freshness string, contextual []Relationship) (Decision, error) {
+	subject, object = scope.qualify(subject), scope.qualify(object)
+	contextual = scope.qualifyTuples(contextual)
+
 	// Set default model
 	if model == "" {
 		model = ModelRBAC
 	}
+	if !s.currentSettings().modelEnabled(model) {
+		return Decision{}, fmt.Errorf("model %s is disabled", model)
+	}
+	strong := freshness == freshnessStrong
+	if s.decisions == nil || strong || len(contextual) > 0 {
+		return s.evaluate(scope, model, subject, object, action, attributes, strong, contextual)
+	}
 
-	var allowed bool
-	var err error
+	// Read the revision first, so a result computed during a write is never served after it
+	key := decisionCacheKey{model: model, tenant: scope.tenant, subject: subject, object: object, action: action, attributes: hashAttributes(attributes)}
+	revision := s.decisionRevision(model)
+	if decision, found := s.decisions.get(key, revision, time.Now()); found {
+		return decision, nil
+	}
+
+	decision, err := s.evaluate(scope, model, subject, object, action, attributes, strong, nil)
+	if err == nil {
+		s.decisions.put(key, revision, decision, time.Now())
+	}
+	return decision, err
+}
+
+// evaluate performs an uncached authorization check of qualified names, assuming the
+// contextual tuples exist
+func (s *AuthService) evaluate(scope tenantScope, model AccessControlModel, subject, object, action string, attributes map[string]string, strong bool, contextual []Relationship) (Decision, error) {
+	graph := s.relationshipGraph
+	if model == ModelReBAC || (model == ModelRBAC && s.rbacGroupBindings) {
+		if len(contextual) > 0 {
+			graph = graph.withContextualTuples(contextual)
+		} else if strong {
+			graph = graph.freshSnapshot()
+		} else {
+			graph = graph.forRequest()
+		}
+	}
+	// Caveated tuples grant access depending on the request attributes
+	graph = graph.withCaveatContext(attributes)
+
+	var decision Decision
 
 	switch model {
 	case ModelACL, ModelRBAC:
 		enforcer := s.getEnforcer(model)
-		allowed, err = enforcer.Enforce(subject, object, action)
+		allowed, denied, rule, err := matchRule(enforcer, subject, object, action)
+		if err != nil {
+			return Decision{}, err
+		}
+		decision = Decision{Allowed: allowed, Match: ruleMatch(enforcer, model, subject, rule)}
+		if !allowed && !denied && model == ModelRBAC && s.rbacGroupBindings {
+			// Roles may be bound to groups whose membership is managed in the graph
+			allowed, group, rule, err := s.matchGroupRoleBindings(graph, subject, object, action)
+			if err != nil {
+				return Decision{}, err
+			}
+			if match := ruleMatch(enforcer, model, group, rule); match != nil {
+				match.Group = group
+				decision = Decision{Allowed: allowed, Match: match}
+			}
+		}
 	case ModelABAC:
-		// ABAC uses custom policy engine
-		allowed = s.matchABACAttributes(subject, object, action, attributes)
+		// ABAC uses custom policy engine, limited to the tenant's policies
+		ctx := s.abacEvaluationContext(subject, object, action, attributes, strong)
+		ctx.Tenant = scope.tenant
+		result := s.policyEngine.Decide(ctx)
+		decision.Allowed = result.Allowed
+		if result.Policy != nil {
+			decision.Match = &DecisionMatch{PolicyID: result.Policy.ID}
+		}
 	case ModelReBAC:
 		// ReBAC uses relationship graph
-		allowed, _ = s.relationshipGraph.CheckReBACAccess(subject, object, action)
+		decision.Allowed, decision.Hops = graph.CheckReBACAccessHops(subject, object, action)
 	default:
-		return false, fmt.Errorf("invalid model specified: %s", model)
+		return Decision{}, fmt.Errorf("invalid model specified: %s", model)
 	}
 
-	return allowed, err
+	return decision, nil
 }
 
 // getEnforcer returns the appropriate enforcer for the given model
-func (s *AuthService) getEnforcer(model AccessControlModel) *casbin.Enforcer {
+func (s *AuthService) getEnforcer(model AccessControlModel) *casbin.SyncedEnforcer {
 	switch model {
 	case ModelACL:
 		return s.aclEnforcer
@@ -1107,7 +2053,7 @@ func (s *AuthService) initializeData() error {
 	}
 
 	for _, policy := range aclPolicies {
-		s.aclEnforcer.AddPolicy(policy)
+		addRule(s.aclEnforcer, policy[0], policy[1], policy[2], effectAllow)
 	}
 
 	// Initial data for RBAC
@@ -1131,7 +2077,7 @@ func (s *AuthService) initializeData() error {
 	}
 
 	for _, policy := range rbacPolicies {
-		s.rbacEnforcer.AddPolicy(policy)
+		addRule(s.rbacEnforcer, policy[0], policy[1], policy[2], effectAllow)
 	}
 
 	// No hardcoded initial data for ABAC
@@ -1192,19 +2138,58 @@ func (s *AuthService) initializeABACPolicies() error {
 }
 
 // matchABACAttributes uses the policy engine to evaluate ABAC authorization
-func (s *AuthService) matchABACAttributes(subject, object, action string, reqAttrs map[string]string) bool {
-	// Get user attributes from persistent storage
-	userAttrs, _ := s.getUserAttributesFromDB(subject)
+func (s *AuthService) matchABACAttributes(subject, object, action string, reqAttrs map[string]string, strong bool) *PolicyDecision {
+	decision := s.policyEngine.Decide(s.abacEvaluationContext(subject, object, action, reqAttrs, strong))
+	return &decision
+}
+
+// abacEvaluationContext gathers the user, object, environment and request attributes of an
+// ABAC decision
+func (s *AuthService) abacEvaluationContext(subject, object, action string, reqAttrs map[string]string, strong bool) *PolicyEvaluationContext {
+	// Get user attributes, from the database for strong freshness
+	var userAttrs map[string]string
+	if strong {
+		userAttrs, _ = s.getUserAttributesFromDB(subject)
+	} else {
+		userAttrs, _ = s.getUserAttributes(subject)
+	}
 	if userAttrs == nil {
 		userAttrs = make(map[string]string)
 	}
 
-	// Get object attributes
-	objectAttrs := s.getObjectAttributes(object)
+	// Get object attributes, from the database for strong freshness
+	var objectAttrs map[string]string
+	if strong {
+		objectAttrs, _ = s.getObjectAttributesFromDB(object)
+	} else {
+		objectAttrs = s.getObjectAttributes(object)
+	}
 	if objectAttrs == nil {
 		objectAttrs = make(map[string]string)
 	}
 
+	// Fill in what is not stored from external information points
+	userAttrs = s.withResolvedAttributes("user", subject, userAttrs, strong)
+	objectAttrs = s.withResolvedAttributes("object", object, objectAttrs, strong)
+
+	// Create evaluation context
+	return &PolicyEvaluationContext{
+		UserAttributes:        userAttrs,
+		ObjectAttributes:      objectAttrs,
+		EnvironmentAttributes: s.environmentFor(reqAttrs),
+		ActionAttributes:      make(map[string]string),
+		RequestAttributes:     reqAttrs,
+		UserAttributeTypes:    s.attributeTypes.snapshot("user"),
+		ObjectAttributeTypes:  s.attributeTypes.snapshot("object"),
+		Subject:               subject,
+		Object:                object,
+		Action:                action,
+	}
+}
+
+// environmentAttributes returns the environment of a request: the current time, overridden
+// by the request attributes
+func environmentAttributes(reqAttrs map[string]string) map[string]string {
 	// Create environment attributes
 	envAttrs := map[string]string{
 		"time": strconv.Itoa(time.Now().Hour()),
@@ -1221,28 +2206,19 @@ func (s *AuthService) matchABACAttributes(subject, object, action string, reqAtt
 	if hourStr, exists := reqAttrs["hour"]; exists {
 		envAttrs["time"] = hourStr
 	}
-
-	// Create evaluation context
-	ctx := &PolicyEvaluationContext{
-		UserAttributes:        userAttrs,
-		ObjectAttributes:      objectAttrs,
-		EnvironmentAttributes: envAttrs,
-		ActionAttributes:      make(map[string]string),
-		Subject:               subject,
-		Object:                object,
-		Action:                action,
-	}
-
-	// Use policy engine to evaluate
-	allowed, _ := s.policyEngine.Evaluate(ctx)
-	return allowed
+	return envAttrs
 }
 
 // enforceHandler handles authorization enforcement requests for all models
 func (s *AuthService) enforceHandler(w http.ResponseWriter, r *http.Request) {
 	var req EnforceRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
@@ -1254,32 +2230,50 @@ func (s *AuthService) enforceHandler(w http.ResponseWriter, r *http.Request) {
 	var allowed bool
 	var err error
 	var path string
+	var hops []PathHop
+	var decision *PolicyDecision
+	var matched *DecisionMatch
 
 	switch req.Model {
 	case ModelACL, ModelRBAC:
 		enforcer := s.getEnforcer(req.Model)
-		allowed, err = enforcer.Enforce(req.Subject, req.Object, req.Action)
+		var rule []string
+		allowed, _, rule, err = matchRule(enforcer, req.Subject, req.Object, req.Action)
+		matched = ruleMatch(enforcer, req.Model, req.Subject, rule)
 	case ModelABAC:
 		// ABAC uses custom logic
-		allowed = s.matchABACAttributes(req.Subject, req.Object, req.Action, req.Attributes)
+		decision = s.matchABACAttributes(req.Subject, req.Object, req.Action, req.Attributes, req.Freshness == freshnessStrong)
+		allowed = decision.Allowed
+		if decision.Policy != nil {
+			matched = &DecisionMatch{PolicyID: decision.Policy.ID}
+		}
 	case ModelReBAC:
 		// ReBAC uses relationship graph
-		allowed, path = s.relationshipGraph.CheckReBACAccess(req.Subject, req.Object, req.Action)
+		allowed, hops = s.relationshipGraph.forRequest().withCaveatContext(req.Attributes).CheckReBACAccessHops(req.Subject, req.Object, req.Action)
+		if allowed {
+			path = formatPath(req.Subject, hops)
+		}
 	default:
-		http.Error(w, "Invalid model specified", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid model specified")
 		return
 	}
 
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Authorization check error: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Authorization check error: %v", err))
 		return
 	}
 
+	s.recordDecision(requestIDFromContext(r.Context()), req.Model, req.Subject, req.Object, req.Action, req.Attributes, allowed)
+	s.traceDecision(requestIDFromContext(r.Context()), req.Model, req.Subject, req.Object, req.Action, req.Attributes, allowed)
+
 	response := EnforceResponse{
 		Allowed: allowed,
 		Model:   string(req.Model),
 		Path:    path,
+		Hops:    hops,
+		Matched: matched,
 	}
+	decision.attachTo(&response)
 
 	if !allowed {
 		response.Message = "Access denied"
@@ -1296,25 +2290,79 @@ func (s *AuthService) enforceHandler(w http.ResponseWriter, r *http.Request) {
 
 // addRelationshipHandler handles adding new relationships for ReBAC
 func (s *AuthService) addRelationshipHandler(w http.ResponseWriter, r *http.Request) {
-	var req RelationshipRequest
+	var req AddRelationshipRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+	subject, object := scope.qualify(req.Subject), scope.qualify(req.Object)
+	if !s.checkQuota(w, scope, quotaRelationships, 1) {
 		return
 	}
+	warnings := s.relationshipTypeWarnings(subject, req.Relationship, object)
 
-	err := s.relationshipGraph.AddRelationship(req.Subject, req.Relationship, req.Object)
+	metadata := TupleMetadata{CreatedBy: actorFromRequest(r), Source: req.Source, Note: req.Note}
+	err := s.relationshipGraph.AddAnnotatedRelationship(subject, req.Relationship, object, req.ExpiresAt, req.Caveat, metadata)
+	var cardinalityErr *CardinalityError
+	if errors.As(err, &cardinalityErr) {
+		writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	}
+	var typeErr *TypeError
+	if errors.As(err, &typeErr) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to add relationship: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to add relationship: %v", err))
 		return
 	}
 
+	if len(req.Labels) > 0 {
+		if err := s.setLabels(labelKindRelationship, labelKey(subject, req.Relationship, object), req.Labels); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to label relationship: %v", err))
+			return
+		}
+	}
+	change := relationshipChange(subject, req.Relationship, object, req.ExpiresAt)
+	change.Caveat = req.Caveat
+	change.Metadata = &metadata
+	s.publishChange(changeKindRelationship, changeAdded, labelKey(subject, req.Relationship, object), change, metadata.CreatedBy)
+
 	response := map[string]interface{}{
 		"message":      "Relationship added successfully",
 		"subject":      req.Subject,
 		"relationship": req.Relationship,
 		"object":       req.Object,
+		"labels":       normalizeLabels(req.Labels),
 		"model":        "rebac",
 	}
+	if req.ExpiresAt != nil {
+		response["expires_at"] = req.ExpiresAt
+	}
+	if req.Caveat != nil {
+		response["caveat"] = req.Caveat
+	}
+	if req.Source != "" {
+		response["source"] = req.Source
+	}
+	if req.Note != "" {
+		response["note"] = req.Note
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	s.relationshipGraph.addConsistencyToken(response)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -1324,15 +2372,21 @@ func (s *AuthService) addRelationshipHandler(w http.ResponseWriter, r *http.Requ
 func (s *AuthService) removeRelationshipHandler(w http.ResponseWriter, r *http.Request) {
 	var req RelationshipRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
 	err := s.relationshipGraph.RemoveRelationship(req.Subject, req.Relationship, req.Object)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to remove relationship: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to remove relationship: %v", err))
 		return
 	}
+	s.removeLabels(labelKindRelationship, labelKey(req.Subject, req.Relationship, req.Object))
 
 	response := map[string]interface{}{
 		"message":      "Relationship removed successfully",
@@ -1341,6 +2395,7 @@ func (s *AuthService) removeRelationshipHandler(w http.ResponseWriter, r *http.R
 		"object":       req.Object,
 		"model":        "rebac",
 	}
+	s.relationshipGraph.addConsistencyToken(response)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -1350,36 +2405,128 @@ func (s *AuthService) removeRelationshipHandler(w http.ResponseWriter, r *http.R
 func (s *AuthService) getRelationshipsHandler(w http.ResponseWriter, r *http.Request) {
 	subject := r.URL.Query().Get("subject")
 
-	var relationships []Relationship
+	options, err := parseListOptions(r.URL.Query(), "subject", "relationship", "object")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Tuples are listed from a map, so give pages a stable order
+	options.sortByDefault("subject")
 
-	if subject != "" {
-		// Get relationships for specific subject only
-		for key, rels := range s.relationshipGraph.relationships {
-			parts := strings.Split(key, ":")
-			if len(parts) == 2 && parts[0] == subject && !strings.HasPrefix(parts[1], "reverse_") {
-				relationships = append(relationships, rels...)
-			}
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	asOf, ok := parseAsOfQuery(w, r)
+	if !ok {
+		return
+	}
+
+	var relationships []Relationship
+	var expirations map[string]time.Time
+	var caveats map[string]*RelationshipCaveat
+	if asOf.IsZero() {
+		relationships, err = s.relationshipGraph.ListRelationships(scope.qualify(subject))
+		if err == nil {
+			expirations, err = s.relationshipGraph.RelationshipExpirations(scope.qualify(subject))
+		}
+		if err == nil {
+			caveats, err = s.relationshipGraph.RelationshipCaveats(scope.qualify(subject))
 		}
 	} else {
-		// Get all relationships
-		for key, rels := range s.relationshipGraph.relationships {
-			parts := strings.Split(key, ":")
-			if len(parts) == 2 && !strings.HasPrefix(parts[1], "reverse_") {
-				relationships = append(relationships, rels...)
-			}
+		relationships, expirations, caveats, err = s.relationshipListingAsOf(scope.qualify(subject), asOf)
+	}
+	if err == nil {
+		relationships, err = s.filterRelationshipsByLabel(relationships, r.URL.Query().Get("label"))
+	}
+	if errors.Is(err, errHistoryNotRetained) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve relationships: %v", err))
+		return
+	}
+
+	scoped := make([]Relationship, 0, len(relationships))
+	for _, rel := range relationships {
+		if scope.ownsRelationship(rel) {
+			scoped = append(scoped, scope.localRelationship(rel))
+		}
+	}
+	scoped, total := pageEntries(scoped, options, relationshipField)
+
+	// Report expiries, caveats and metadata of the listed tuples only, keyed by tenant-local IDs
+	qualified := make([]Relationship, len(scoped))
+	for i, local := range scoped {
+		qualified[i] = Relationship{Subject: scope.qualify(local.Subject), Relationship: local.Relationship, Object: scope.qualify(local.Object)}
+	}
+	metadata, err := s.relationshipGraph.RelationshipMetadata(qualified)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve relationship metadata: %v", err))
+		return
+	}
+	listed := make(map[string]time.Time)
+	listedCaveats := make(map[string]*RelationshipCaveat)
+	listedMetadata := make(map[string]ListedTupleMetadata)
+	for i, local := range scoped {
+		key := labelKey(qualified[i].Subject, qualified[i].Relationship, qualified[i].Object)
+		localKey := labelKey(local.Subject, local.Relationship, local.Object)
+		if expiresAt, ok := expirations[key]; ok {
+			listed[localKey] = expiresAt
+		}
+		if caveat, ok := caveats[key]; ok {
+			listedCaveats[localKey] = caveat
+		}
+		if meta, ok := metadata[key]; ok {
+			listedMetadata[localKey] = meta
 		}
 	}
 
 	response := map[string]interface{}{
-		"relationships": relationships,
+		"relationships": scoped,
+		"expirations":   listed,
+		"caveats":       listedCaveats,
+		"metadata":      listedMetadata,
 		"subject":       subject,
+		"count":         len(scoped),
 		"model":         "rebac",
 	}
+	options.annotate(response, len(scoped), total)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// parseMaxDepth validates the max_depth query parameter and caps it at the configured limit
+func (s *AuthService) parseMaxDepth(maxDepthStr string) (int, error) {
+	settings := s.currentSettings()
+	limit := settings.maxDepthLimit
+
+	if maxDepthStr == "" {
+		if settings.defaultMaxDepth > limit {
+			return limit, nil
+		}
+		return settings.defaultMaxDepth, nil
+	}
+
+	maxDepth, err := strconv.Atoi(maxDepthStr)
+	if err != nil {
+		return 0, fmt.Errorf("max_depth must be an integer")
+	}
+	if maxDepth <= 0 {
+		return 0, fmt.Errorf("max_depth must be a positive integer")
+	}
+
+	// Cap externally supplied depths to prevent expensive traversals
+	if maxDepth > limit {
+		maxDepth = limit
+	}
+
+	return maxDepth, nil
+}
+
 // findPathHandler searches for relationship paths in ReBAC
 func (s *AuthService) findPathHandler(w http.ResponseWriter, r *http.Request) {
 	subject := r.URL.Query().Get("subject")
@@ -1387,22 +2534,28 @@ func (s *AuthService) findPathHandler(w http.ResponseWriter, r *http.Request) {
 	maxDepthStr := r.URL.Query().Get("max_depth")
 
 	if subject == "" || object == "" {
-		http.Error(w, "subject and object parameters are required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "subject and object parameters are required")
 		return
 	}
 
-	maxDepth := 5
-	if maxDepthStr != "" {
-		if d, err := strconv.Atoi(maxDepthStr); err == nil {
-			maxDepth = d
-		}
+	maxDepth, err := s.parseMaxDepth(maxDepthStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	found, path := s.relationshipGraph.FindRelationshipPath(subject, object, maxDepth)
+	found, hops := s.relationshipGraph.FindRelationshipHops(subject, object, maxDepth)
+	path := ""
+	if found {
+		path = formatPath(subject, hops)
+	} else {
+		hops = []PathHop{}
+	}
 
 	response := map[string]interface{}{
 		"found":     found,
 		"path":      path,
+		"hops":      hops,
 		"subject":   subject,
 		"object":    object,
 		"max_depth": maxDepth,
@@ -1417,7 +2570,12 @@ func (s *AuthService) findPathHandler(w http.ResponseWriter, r *http.Request) {
 func (s *AuthService) addPolicyHandler(w http.ResponseWriter, r *http.Request) {
 	var req PolicyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
@@ -1426,14 +2584,32 @@ func (s *AuthService) addPolicyHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if req.Model == ModelReBAC {
-		http.Error(w, "For ReBAC, please use the addRelationship endpoint", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "For ReBAC, please use the addRelationship endpoint")
 		return
 	}
 
 	enforcer := s.getEnforcer(req.Model)
-	added, err := enforcer.AddPolicy(req.Subject, req.Object, req.Action)
+	var added bool
+	var err error
+	if req.Model == ModelABAC {
+		if req.Effect != "" {
+			writeJSONError(w, http.StatusBadRequest, "effect is only supported for ACL and RBAC policies")
+			return
+		}
+		added, err = enforcer.AddPolicy(req.Subject, req.Object, req.Action)
+	} else {
+		if req.Effect, err = normalizeEffect(req.Effect); err != nil {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if req.Model == ModelRBAC {
+			added, err = s.addRBACRule(req.Subject, req.Object, req.Action, req.Effect, req.Condition)
+		} else {
+			added, err = addRule(enforcer, req.Subject, req.Object, req.Action, req.Effect)
+		}
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Policy addition error: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Policy addition error: %v", err))
 		return
 	}
 
@@ -1442,6 +2618,9 @@ func (s *AuthService) addPolicyHandler(w http.ResponseWriter, r *http.Request) {
 		"message": fmt.Sprintf("Policy added successfully for %s model", req.Model),
 		"model":   req.Model,
 	}
+	if req.Effect != "" {
+		response["effect"] = req.Effect
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -1451,7 +2630,12 @@ func (s *AuthService) addPolicyHandler(w http.ResponseWriter, r *http.Request) {
 func (s *AuthService) removePolicyHandler(w http.ResponseWriter, r *http.Request) {
 	var req PolicyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
@@ -1460,14 +2644,20 @@ func (s *AuthService) removePolicyHandler(w http.ResponseWriter, r *http.Request
 	}
 
 	if req.Model == ModelReBAC {
-		http.Error(w, "For ReBAC, please use the removeRelationship endpoint", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "For ReBAC, please use the removeRelationship endpoint")
 		return
 	}
 
 	enforcer := s.getEnforcer(req.Model)
-	removed, err := enforcer.RemovePolicy(req.Subject, req.Object, req.Action)
+	var removed bool
+	var err error
+	if req.Model == ModelABAC {
+		removed, err = enforcer.RemovePolicy(req.Subject, req.Object, req.Action)
+	} else {
+		removed, err = removeRule(enforcer, req.Subject, req.Object, req.Action)
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Policy removal error: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Policy removal error: %v", err))
 		return
 	}
 
@@ -1485,13 +2675,18 @@ func (s *AuthService) removePolicyHandler(w http.ResponseWriter, r *http.Request
 func (s *AuthService) addRoleHandler(w http.ResponseWriter, r *http.Request) {
 	var req RoleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
 	added, err := s.rbacEnforcer.AddRoleForUser(req.User, req.Role)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Role addition error: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Role addition error: %v", err))
 		return
 	}
 
@@ -1510,35 +2705,66 @@ func (s *AuthService) setUserAttributesHandler(w http.ResponseWriter, r *http.Re
 	vars := mux.Vars(r)
 	userId := vars["userId"]
 
-	var req struct {
-		Attributes map[string]string `json:"attributes"`
-	}
+	var req UserAttributesRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
 		return
 	}
+	user := scope.qualify(userId)
 
-	if len(req.Attributes) == 0 {
-		http.Error(w, "attributes are required", http.StatusBadRequest)
+	warnings, err := s.validateAttributes("user", req.Attributes)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !s.acceptSchemaProblems(w, warnings) {
+		return
+	}
+	if err := s.checkAttributeValues("user", req.Attributes, req.Types); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !s.checkAttributeQuota(w, scope, "user", user, attributeNames(req.Attributes)) {
 		return
 	}
 
-	// Save each attribute to database and update cache
+	// Save each attribute to database and invalidate the cache
 	for k, v := range req.Attributes {
-		err := s.saveUserAttribute(userId, k, v)
+		err := s.saveTypedUserAttribute(user, k, v, req.Types[k])
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to save user attribute: %v", err), http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save user attribute: %v", err))
 			return
 		}
+		s.publishChange(changeKindUserAttribute, changeUpdated, user+"."+k, attributeChange("user", user, k, v), actorFromRequest(r))
+	}
+
+	attributes, err := s.getUserAttributes(user)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve user attributes: %v", err))
+		return
 	}
 
 	response := map[string]interface{}{
 		"message":    "User attributes set successfully",
 		"user":       userId,
-		"attributes": s.userAttrs[userId],
+		"attributes": attributes,
+		"types":      s.attributeTypes.typesOf("user", attributes),
 		"count":      len(req.Attributes),
 		"model":      "abac",
 	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -1554,14 +2780,14 @@ func (s *AuthService) getPoliciesHandler(w http.ResponseWriter, r *http.Request)
 	model := AccessControlModel(modelParam)
 
 	if model == ModelReBAC {
-		http.Error(w, "For ReBAC, please use the getRelationships endpoint", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "For ReBAC, please use the getRelationships endpoint")
 		return
 	}
 
 	enforcer := s.getEnforcer(model)
 	policies, err := enforcer.GetPolicy()
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Policy retrieval error: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Policy retrieval error: %v", err))
 		return
 	}
 
@@ -1578,17 +2804,39 @@ func (s *AuthService) getUserRolesHandler(w http.ResponseWriter, r *http.Request
 	vars := mux.Vars(r)
 	userId := vars["userId"]
 
-	roles, err := s.rbacEnforcer.GetRolesForUser(userId)
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+	user := scope.qualify(userId)
+
+	roles, err := s.rbacEnforcer.GetRolesForUser(user)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Role retrieval error: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Role retrieval error: %v", err))
+		return
+	}
+
+	assignments := make([][]string, 0, len(roles))
+	for _, role := range roles {
+		assignments = append(assignments, []string{user, role})
+	}
+	assignments = scope.ownedRules(assignments, 2)
+	roles = make([]string, 0, len(assignments))
+	for _, assignment := range assignments {
+		roles = append(roles, scope.local(assignment[1]))
+	}
+	metadata, err := s.rulesMetadata(labelKindRole, assignments)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Role retrieval error: %v", err))
 		return
 	}
 
 	response := map[string]interface{}{
-		"user":  userId,
-		"roles": roles,
-		"count": len(roles),
-		"model": "rbac",
+		"user":     userId,
+		"roles":    roles,
+		"metadata": scope.localMetadata(metadata),
+		"count":    len(roles),
+		"model":    "rbac",
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1599,16 +2847,22 @@ func (s *AuthService) getUserAttributesHandler(w http.ResponseWriter, r *http.Re
 	vars := mux.Vars(r)
 	userId := vars["userId"]
 
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
 	// Get attributes from database (ensures consistency)
-	attributes, err := s.getUserAttributesFromDB(userId)
+	attributes, err := s.getUserAttributesFromDB(scope.qualify(userId))
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to retrieve user attributes: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve user attributes: %v", err))
 		return
 	}
 
 	response := map[string]interface{}{
 		"user":       userId,
 		"attributes": attributes,
+		"types":      s.attributeTypes.typesOf("user", attributes),
 		"count":      len(attributes),
 		"model":      "abac",
 	}
@@ -1617,31 +2871,16 @@ func (s *AuthService) getUserAttributesHandler(w http.ResponseWriter, r *http.Re
 	json.NewEncoder(w).Encode(response)
 }
 
-// getModelsHandler returns information about supported authorization models
+// getModelsHandler reports the supported authorization models and their current state
 func (s *AuthService) getModelsHandler(w http.ResponseWriter, r *http.Request) {
+	models, err := s.describeModels()
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to describe models: %v", err))
+		return
+	}
+
 	response := map[string]interface{}{
-		"models": []map[string]string{
-			{
-				"name":        "acl",
-				"description": "Access Control List - Direct user-resource mapping",
-				"usage":       "Small-scale systems, simple permission management",
-			},
-			{
-				"name":        "rbac",
-				"description": "Role-Based Access Control - Role-based authorization",
-				"usage":       "Enterprise systems, organizational permission management",
-			},
-			{
-				"name":        "abac",
-				"description": "Attribute-Based Access Control - Attribute-based authorization",
-				"usage":       "Advanced security, dynamic permission control",
-			},
-			{
-				"name":        "rebac",
-				"description": "Relationship-Based Access Control - Graph-based authorization",
-				"usage":       "Social media, collaboration platforms, hierarchical organizations",
-			},
-		},
+		"models":  models,
 		"default": "rbac",
 	}
 
@@ -1651,41 +2890,60 @@ func (s *AuthService) getModelsHandler(w http.ResponseWriter, r *http.Request) {
 
 // setObjectAttributesHandler sets attributes for an object (ABAC)
 func (s *AuthService) setObjectAttributesHandler(w http.ResponseWriter, r *http.Request) {
-	var request struct {
-		Object     string            `json:"object"`
-		Attributes map[string]string `json:"attributes"`
-	}
+	var request ObjectAttributesRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	if err := request.validate(); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
-	if request.Object == "" {
-		http.Error(w, "Object is required", http.StatusBadRequest)
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
 		return
 	}
 
-	if len(request.Attributes) == 0 {
-		http.Error(w, "At least one attribute is required", http.StatusBadRequest)
+	warnings, err := s.validateAttributes("object", request.Attributes)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !s.acceptSchemaProblems(w, warnings) {
+		return
+	}
+	if err := s.checkAttributeValues("object", request.Attributes, request.Types); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !s.checkAttributeQuota(w, scope, "object", scope.qualify(request.Object), attributeNames(request.Attributes)) {
 		return
 	}
 
 	// Save each attribute to database
 	for key, value := range request.Attributes {
-		err := s.saveObjectAttribute(request.Object, key, value)
+		object := scope.qualify(request.Object)
+		err := s.saveTypedObjectAttribute(object, key, value, request.Types[key])
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to save object attribute: %v", err), http.StatusInternalServerError)
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save object attribute: %v", err))
 			return
 		}
+		s.publishChange(changeKindObjectAttribute, changeUpdated, object+"."+key, attributeChange("object", object, key, value), actorFromRequest(r))
 	}
 
 	response := map[string]interface{}{
 		"message":    "Object attributes set successfully",
 		"object":     request.Object,
 		"attributes": request.Attributes,
+		"types":      s.attributeTypes.typesOf("object", request.Attributes),
 		"model":      "abac",
 	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -1696,8 +2954,13 @@ func (s *AuthService) getObjectAttributesHandler(w http.ResponseWriter, r *http.
 	vars := mux.Vars(r)
 	objectId := vars["objectId"]
 
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
 	// Get attributes from database
-	attributes := s.getObjectAttributes(objectId)
+	attributes := s.getObjectAttributes(scope.qualify(objectId))
 	if attributes == nil {
 		attributes = make(map[string]string)
 	}
@@ -1705,6 +2968,7 @@ func (s *AuthService) getObjectAttributesHandler(w http.ResponseWriter, r *http.
 	response := map[string]interface{}{
 		"object":     objectId,
 		"attributes": attributes,
+		"types":      s.attributeTypes.typesOf("object", attributes),
 		"count":      len(attributes),
 		"model":      "abac",
 	}
@@ -1717,39 +2981,75 @@ func (s *AuthService) getObjectAttributesHandler(w http.ResponseWriter, r *http.
 func (s *AuthService) addABACPolicyHandler(w http.ResponseWriter, r *http.Request) {
 	var policy ABACPolicy
 	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	if err := policy.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := validateConditionGroup(&policy); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validatePolicyExpression(&policy); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Validate required fields
-	if policy.ID == "" || policy.Name == "" || policy.Effect == "" {
-		http.Error(w, "ID, Name, and Effect are required", http.StatusBadRequest)
+	warnings, err := s.validatePolicyConditions(policy.allConditions())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !s.acceptSchemaProblems(w, warnings) {
 		return
 	}
 
-	// Validate effect
-	if policy.Effect != "allow" && policy.Effect != "deny" {
-		http.Error(w, "Effect must be 'allow' or 'deny'", http.StatusBadRequest)
+	// Tenant policies are stored as "<tenant>/<id>"
+	scope, ok := s.requestTenant(w, r, policy.Tenant)
+	if !ok {
+		return
+	}
+	policy.ID = scope.qualify(policy.ID)
+	policy.Tenant = scope.tenant
+	if !s.checkQuota(w, scope, quotaPolicies, 1) {
 		return
 	}
 
-	// Set timestamps
+	// Set timestamps; versions are assigned by the engine
 	policy.CreatedAt = time.Now()
 	policy.UpdatedAt = time.Now()
+	policy.Version = 0
 
 	// Add policy to engine
-	err := s.policyEngine.AddPolicy(&policy)
+	err = s.policyEngine.AddPolicy(&policy)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to add policy: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to add policy: %v", err))
 		return
 	}
 
+	if len(policy.Labels) > 0 {
+		policy.Labels = normalizeLabels(policy.Labels)
+		if err := s.setLabels(labelKindABAC, policy.ID, policy.Labels); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to label policy: %v", err))
+			return
+		}
+	}
+	s.publishChange(changeKindABACPolicy, changeAdded, policy.ID, &policy, actorFromRequest(r))
+
 	response := map[string]interface{}{
 		"message": "ABAC policy added successfully",
 		"policy":  policy,
 	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", policyETag(policy.Version))
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -1758,21 +3058,45 @@ func (s *AuthService) deleteABACPolicyHandler(w http.ResponseWriter, r *http.Req
 	vars := mux.Vars(r)
 	policyId := vars["id"]
 
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+	policyId = scope.qualify(policyId)
+	if policy, exists := s.policyEngine.getPolicy(policyId); !scope.global() && (!exists || !scope.ownsPolicy(policy)) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    errorCode(http.StatusNotFound),
+			"removed": false,
+			"message": "Policy not found",
+			"id":      policyId,
+		})
+		return
+	}
+
 	err := s.policyEngine.RemovePolicy(policyId)
+	if err == nil {
+		err = s.removeLabels(labelKindABAC, policyId)
+	}
 	if err != nil {
 		if err.Error() == "policy not found" {
+			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(map[string]interface{}{
+				"code":    errorCode(http.StatusNotFound),
 				"removed": false,
 				"message": "Policy not found",
 				"id":      policyId,
 			})
 			return
 		}
-		http.Error(w, fmt.Sprintf("Failed to remove policy: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to remove policy: %v", err))
 		return
 	}
 
+	s.publishChange(changeKindABACPolicy, changeRemoved, policyId, map[string]string{"id": policyId}, actorFromRequest(r))
+
 	response := map[string]interface{}{
 		"removed": true,
 		"message": "ABAC policy removed successfully",
@@ -1791,18 +3115,18 @@ func (s *AuthService) removeABACPolicyHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid JSON format", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
 		return
 	}
 
 	if request.ID == "" {
-		http.Error(w, "Policy ID is required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Policy ID is required")
 		return
 	}
 
 	err := s.policyEngine.RemovePolicy(request.ID)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to remove policy: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to remove policy: %v", err))
 		return
 	}
 
@@ -1817,15 +3141,43 @@ func (s *AuthService) removeABACPolicyHandler(w http.ResponseWriter, r *http.Req
 
 // getABACPoliciesHandler returns all ABAC policies
 func (s *AuthService) getABACPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	options, err := parseListOptions(r.URL.Query(), "id", "name", "effect")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Policies are kept in a map, so give pages a stable order
+	options.sortByDefault("id")
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	labels, err := s.labelsByKey(labelKindABAC)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve labels: %v", err))
+		return
+	}
+
+	label := r.URL.Query().Get("label")
 	policies := make([]*ABACPolicy, 0)
-	for _, policy := range s.policyEngine.policies {
-		policies = append(policies, policy)
+	for _, policy := range s.policyEngine.policyList() {
+		// Label a copy, the engine's policies are shared with concurrent checks
+		labeled := *policy
+		labeled.Labels = labels[policy.ID]
+		if !scope.ownsPolicy(&labeled) || (label != "" && !hasLabel(labeled.Labels, label)) {
+			continue
+		}
+		policies = append(policies, &labeled)
 	}
+	policies, total := pageEntries(policies, options, abacPolicyField)
 
 	response := map[string]interface{}{
 		"policies": policies,
 		"count":    len(policies),
 	}
+	options.annotate(response, len(policies), total)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -1837,43 +3189,112 @@ func (s *AuthService) getABACPolicyHandler(w http.ResponseWriter, r *http.Reques
 	policyID := vars["id"]
 
 	if policyID == "" {
-		http.Error(w, "Policy ID is required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Policy ID is required")
 		return
 	}
 
-	policy, exists := s.policyEngine.policies[policyID]
-	if !exists {
-		http.Error(w, "Policy not found", http.StatusNotFound)
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
 		return
 	}
+	policyID = scope.qualify(policyID)
+
+	policy, exists := s.policyEngine.getPolicy(policyID)
+	if !exists || !scope.ownsPolicy(policy) {
+		writeJSONError(w, http.StatusNotFound, "Policy not found")
+		return
+	}
+
+	labels, err := s.labelsByKey(labelKindABAC)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve labels: %v", err))
+		return
+	}
+	labeled := *policy
+	labeled.Labels = labels[policyID]
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(policy)
+	w.Header().Set("ETag", policyETag(labeled.Version))
+	json.NewEncoder(w).Encode(&labeled)
 }
 
 // authorizationHandler handles authorization checks for all models
 func (s *AuthService) authorizationHandler(w http.ResponseWriter, r *http.Request) {
 	var request EnforceRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
 
-	if request.Subject == "" || request.Object == "" || request.Action == "" {
-		http.Error(w, "subject, object, and action are required", http.StatusBadRequest)
+	if err := request.validate(); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
-	allowed, err := s.Enforce(request.Model, request.Subject, request.Object, request.Action, request.Attributes)
+	// A consistency token newer than the in-memory graph upgrades the check to strong freshness
+	freshness, err := s.freshnessForToken(request.Model, request.Freshness, request.ConsistencyToken)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Authorization error: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
+	request.Freshness = freshness
 
-	response := map[string]interface{}{
-		"allowed": allowed,
-		"message": map[bool]string{true: "Access granted", false: "Access denied"}[allowed],
-		"model":   request.Model,
+	scope, ok := s.requestTenant(w, r, request.Tenant)
+	if !ok {
+		return
+	}
+
+	// Without group bindings, RBAC checks never read the tuples
+	if len(request.ContextualTuples) > 0 && request.Model != ModelReBAC && !s.rbacGroupBindings {
+		writeJSONError(w, http.StatusBadRequest, "contextual_tuples need a ReBAC check, or an RBAC check with RBAC_REBAC_GROUPS=true")
+		return
+	}
+
+	// Point-in-time checks replay the recorded history instead of reading the current data
+	if request.AsOf != "" {
+		s.historicalAuthorization(w, scope, &request)
+		return
+	}
+
+	start := time.Now()
+	decision, err := s.DecideWithTuples(scope, request.Model, request.Subject, request.Object, request.Action, request.Attributes, request.Freshness, request.ContextualTuples)
+	s.sloTracker.Observe(time.Since(start))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Authorization error: %v", err))
+		return
+	}
+	allowed := decision.Allowed
+
+	// Decisions are audited with tenant-qualified names
+	subject, object := scope.qualify(request.Subject), scope.qualify(request.Object)
+	s.recordDecision(requestIDFromContext(r.Context()), request.Model, subject, object, request.Action, request.Attributes, allowed)
+	s.traceDecision(requestIDFromContext(r.Context()), request.Model, subject, object, request.Action, request.Attributes, allowed)
+
+	response := EnforceResponse{
+		Allowed: allowed,
+		Message: map[bool]string{true: "Access granted", false: "Access denied"}[allowed],
+		Model:   string(request.Model),
+		Matched: decision.Match.local(scope),
+		Hops:    localHops(scope, decision.Hops),
+	}
+	if decision.Hops != nil {
+		response.Path = formatPath(request.Subject, response.Hops)
+	}
+	if !scope.global() {
+		response.Tenant = scope.tenant
+	}
+	if decision.Match != nil && decision.Match.PolicyID != "" {
+		s.policyEngine.attachObligations(decision.Match.PolicyID, &response)
+	}
+
+	// Explain the decision on request, e.g. to debug a denial
+	if request.Explain || r.URL.Query().Get("explain") == "true" {
+		explanation, err := s.explainInTenant(scope, request.Model, request.Subject, request.Object, request.Action, request.Attributes, request.Freshness, request.ContextualTuples)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to explain decision: %v", err))
+			return
+		}
+		response.Explanation = explanation
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1885,29 +3306,56 @@ func (s *AuthService) authorizationHandler(w http.ResponseWriter, r *http.Reques
 func (s *AuthService) addACLPolicyHandler(w http.ResponseWriter, r *http.Request) {
 	var request PolicyRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if err := request.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	effect, err := normalizeEffect(request.Effect)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := validateACLRule(request.Object, request.Action); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if request.Condition != nil {
+		writeJSONError(w, http.StatusBadRequest, "condition is only supported for RBAC policies")
+		return
+	}
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
 		return
 	}
-
-	if request.Subject == "" || request.Object == "" || request.Action == "" {
-		http.Error(w, "subject, object, and action are required", http.StatusBadRequest)
+	subject, object := scope.qualify(request.Subject), scope.qualify(request.Object)
+	if !s.checkQuota(w, scope, quotaPolicies, 1) {
 		return
 	}
 
-	added, err := s.aclEnforcer.AddPolicy(request.Subject, request.Object, request.Action)
+	added, err := addRule(s.aclEnforcer, subject, object, request.Action, effect)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to add policy: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to add policy: %v", err))
 		return
 	}
 
 	if !added {
 		response := map[string]interface{}{
+			"code":    errorCode(http.StatusConflict),
 			"added":   false,
 			"message": "Policy already exists",
 			"policy": map[string]string{
 				"subject": request.Subject,
 				"object":  request.Object,
 				"action":  request.Action,
+				"effect":  effect,
 			},
 			"model": "acl",
 		}
@@ -1917,7 +3365,15 @@ func (s *AuthService) addACLPolicyHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	s.aclEnforcer.SavePolicy()
+	s.recordPolicyMetadata(labelKindACL, labelKey(subject, object, request.Action), actorFromRequest(r))
+	s.publishChange(changeKindACL, changeAdded, labelKey(subject, object, request.Action), ruleChange(subject, object, request.Action, effect), actorFromRequest(r))
+
+	if len(request.Labels) > 0 {
+		if err := s.setLabels(labelKindACL, labelKey(subject, object, request.Action), request.Labels); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to label policy: %v", err))
+			return
+		}
+	}
 
 	response := map[string]interface{}{
 		"added":   true,
@@ -1926,8 +3382,10 @@ func (s *AuthService) addACLPolicyHandler(w http.ResponseWriter, r *http.Request
 			"subject": request.Subject,
 			"object":  request.Object,
 			"action":  request.Action,
+			"effect":  effect,
 		},
-		"model": "acl",
+		"labels": normalizeLabels(request.Labels),
+		"model":  "acl",
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -1937,17 +3395,50 @@ func (s *AuthService) addACLPolicyHandler(w http.ResponseWriter, r *http.Request
 
 // getACLPoliciesHandler retrieves all ACL policies
 func (s *AuthService) getACLPoliciesHandler(w http.ResponseWriter, r *http.Request) {
-	policies, err := s.aclEnforcer.GetPolicy()
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	options, err := parseListOptions(r.URL.Query(), "subject", "object", "action", "effect")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	asOf, ok := parseAsOfQuery(w, r)
+	if !ok {
+		return
+	}
+
+	var policies [][]string
+	if asOf.IsZero() {
+		policies, err = s.aclEnforcer.GetPolicy()
+	} else {
+		policies, err = s.rulesAsOf(changeKindACL, asOf)
+	}
+	if err == nil {
+		policies = scope.ownedRules(policies, 2)
+		policies, err = s.filterRulesByLabel(labelKindACL, policies, r.URL.Query().Get("label"))
+	}
+	total := 0
+	var metadata map[string]*PolicyMetadata
+	if err == nil {
+		policies, total = pageEntries(policies, options, ruleField(scope))
+		metadata, err = s.rulesMetadata(labelKindACL, policies)
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Policy retrieval error: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Policy retrieval error: %v", err))
 		return
 	}
 
 	response := map[string]interface{}{
-		"policies": policies,
+		"policies": scope.localRules(policies, 2),
+		"metadata": scope.localMetadata(metadata),
 		"count":    len(policies),
 		"model":    "acl",
 	}
+	options.annotate(response, len(policies), total)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -1956,24 +3447,42 @@ func (s *AuthService) getACLPoliciesHandler(w http.ResponseWriter, r *http.Reque
 // deleteACLPolicyHandler removes an ACL policy
 func (s *AuthService) deleteACLPolicyHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
-	policyId := vars["id"]
 
-	// Parse policy ID format: "subject:object:action"
-	parts := strings.Split(policyId, ":")
-	if len(parts) != 3 {
-		http.Error(w, "Policy ID must be in format 'subject:object:action'", http.StatusBadRequest)
+	// Parse policy ID format: "subject:object:action". Rules on objects containing ':' or
+	// '/', such as path patterns, are identified by query parameters instead.
+	var parts []string
+	if policyId, ok := vars["id"]; ok {
+		parts = strings.Split(policyId, ":")
+		if len(parts) != 3 {
+			writeJSONError(w, http.StatusBadRequest, "Policy ID must be in format 'subject:object:action'")
+			return
+		}
+	} else {
+		query := r.URL.Query()
+		parts = []string{query.Get("subject"), query.Get("object"), query.Get("action")}
+		if parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			writeJSONError(w, http.StatusBadRequest, "subject, object, and action query parameters are required")
+			return
+		}
+	}
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
 		return
 	}
+	parts[0], parts[1] = scope.qualify(parts[0]), scope.qualify(parts[1])
 
-	removed, err := s.aclEnforcer.RemovePolicy(parts[0], parts[1], parts[2])
+	removed, err := removeRule(s.aclEnforcer, parts[0], parts[1], parts[2])
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to remove policy: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to remove policy: %v", err))
 		return
 	}
 
 	if !removed {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    errorCode(http.StatusNotFound),
 			"removed": false,
 			"message": "Policy not found",
 			"model":   "acl",
@@ -1981,7 +3490,9 @@ func (s *AuthService) deleteACLPolicyHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	s.aclEnforcer.SavePolicy()
+	s.removeLabels(labelKindACL, labelKey(parts[0], parts[1], parts[2]))
+	s.removePolicyMetadata(labelKindACL, labelKey(parts[0], parts[1], parts[2]))
+	s.publishChange(changeKindACL, changeRemoved, labelKey(parts[0], parts[1], parts[2]), ruleChange(parts[0], parts[1], parts[2], ""), actorFromRequest(r))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -1996,29 +3507,55 @@ func (s *AuthService) deleteACLPolicyHandler(w http.ResponseWriter, r *http.Requ
 func (s *AuthService) addRBACPolicyHandler(w http.ResponseWriter, r *http.Request) {
 	var request PolicyRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
 
-	if request.Subject == "" || request.Object == "" || request.Action == "" {
-		http.Error(w, "subject, object, and action are required", http.StatusBadRequest)
+	if err := request.validate(); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
-	added, err := s.rbacEnforcer.AddPolicy(request.Subject, request.Object, request.Action)
+	effect, err := normalizeEffect(request.Effect)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to add policy: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	warnings, err := s.validateRuleCondition(request.Condition)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !s.acceptSchemaProblems(w, warnings) {
+		return
+	}
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+	subject, object := scope.qualify(request.Subject), scope.qualify(request.Object)
+	if !s.checkQuota(w, scope, quotaPolicies, 1) {
+		return
+	}
+
+	added, err := s.addRBACRule(subject, object, request.Action, effect, request.Condition)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to add policy: %v", err))
 		return
 	}
 
 	if !added {
 		response := map[string]interface{}{
+			"code":    errorCode(http.StatusConflict),
 			"added":   false,
 			"message": "Policy already exists",
 			"policy": map[string]string{
 				"subject": request.Subject,
 				"object":  request.Object,
 				"action":  request.Action,
+				"effect":  effect,
 			},
 			"model": "rbac",
 		}
@@ -2028,7 +3565,15 @@ func (s *AuthService) addRBACPolicyHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	s.rbacEnforcer.SavePolicy()
+	s.recordPolicyMetadata(labelKindRBAC, labelKey(subject, object, request.Action), actorFromRequest(r))
+	s.publishChange(changeKindRBACPolicy, changeAdded, labelKey(subject, object, request.Action), ruleChange(subject, object, request.Action, effect), actorFromRequest(r))
+
+	if len(request.Labels) > 0 {
+		if err := s.setLabels(labelKindRBAC, labelKey(subject, object, request.Action), request.Labels); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to label policy: %v", err))
+			return
+		}
+	}
 
 	response := map[string]interface{}{
 		"added":   true,
@@ -2037,8 +3582,16 @@ func (s *AuthService) addRBACPolicyHandler(w http.ResponseWriter, r *http.Reques
 			"subject": request.Subject,
 			"object":  request.Object,
 			"action":  request.Action,
+			"effect":  effect,
 		},
-		"model": "rbac",
+		"labels": normalizeLabels(request.Labels),
+		"model":  "rbac",
+	}
+	if request.Condition != nil {
+		response["condition"] = request.Condition
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -2048,17 +3601,56 @@ func (s *AuthService) addRBACPolicyHandler(w http.ResponseWriter, r *http.Reques
 
 // getRBACPoliciesHandler retrieves all RBAC policies
 func (s *AuthService) getRBACPoliciesHandler(w http.ResponseWriter, r *http.Request) {
-	policies, err := s.rbacEnforcer.GetPolicy()
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	options, err := parseListOptions(r.URL.Query(), "subject", "object", "action", "effect")
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	asOf, ok := parseAsOfQuery(w, r)
+	if !ok {
+		return
+	}
+
+	var policies [][]string
+	if asOf.IsZero() {
+		policies, err = s.rbacEnforcer.GetPolicy()
+	} else {
+		policies, err = s.rulesAsOf(changeKindRBACPolicy, asOf)
+	}
+	if err == nil {
+		policies = scope.ownedRules(policies, 2)
+		policies, err = s.filterRulesByLabel(labelKindRBAC, policies, r.URL.Query().Get("label"))
+	}
+	total := 0
+	var metadata map[string]*PolicyMetadata
+	if err == nil {
+		policies, total = pageEntries(policies, options, ruleField(scope))
+		metadata, err = s.rulesMetadata(labelKindRBAC, policies)
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Policy retrieval error: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Policy retrieval error: %v", err))
 		return
 	}
 
+	conditions := make(map[string]*ConditionGroup)
+	for key, group := range s.rulesConditions(policies) {
+		conditions[scope.localRuleKey(key)] = group
+	}
+
 	response := map[string]interface{}{
-		"policies": policies,
-		"count":    len(policies),
-		"model":    "rbac",
+		"policies":   scope.localRules(policies, 2),
+		"metadata":   scope.localMetadata(metadata),
+		"conditions": conditions,
+		"count":      len(policies),
+		"model":      "rbac",
 	}
+	options.annotate(response, len(policies), total)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -2072,19 +3664,27 @@ func (s *AuthService) deleteRBACPolicyHandler(w http.ResponseWriter, r *http.Req
 	// Parse policy ID format: "subject:object:action"
 	parts := strings.Split(policyId, ":")
 	if len(parts) != 3 {
-		http.Error(w, "Policy ID must be in format 'subject:object:action'", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Policy ID must be in format 'subject:object:action'")
 		return
 	}
 
-	removed, err := s.rbacEnforcer.RemovePolicy(parts[0], parts[1], parts[2])
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+	parts[0], parts[1] = scope.qualify(parts[0]), scope.qualify(parts[1])
+
+	removed, err := removeRule(s.rbacEnforcer, parts[0], parts[1], parts[2])
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to remove policy: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to remove policy: %v", err))
 		return
 	}
 
 	if !removed {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    errorCode(http.StatusNotFound),
 			"removed": false,
 			"message": "Policy not found",
 			"model":   "rbac",
@@ -2092,7 +3692,12 @@ func (s *AuthService) deleteRBACPolicyHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	s.rbacEnforcer.SavePolicy()
+	s.removeLabels(labelKindRBAC, labelKey(parts[0], parts[1], parts[2]))
+	s.removePolicyMetadata(labelKindRBAC, labelKey(parts[0], parts[1], parts[2]))
+	if err := s.setRuleCondition(labelKey(parts[0], parts[1], parts[2]), nil); err != nil {
+		log.Printf("Failed to remove condition of RBAC rule %s: %v", policyId, err)
+	}
+	s.publishChange(changeKindRBACPolicy, changeRemoved, labelKey(parts[0], parts[1], parts[2]), ruleChange(parts[0], parts[1], parts[2], ""), actorFromRequest(r))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -2108,27 +3713,35 @@ func (s *AuthService) addUserRoleHandler(w http.ResponseWriter, r *http.Request)
 	vars := mux.Vars(r)
 	userId := vars["userId"]
 
-	var request struct {
-		Role string `json:"role"`
-	}
+	var request UserRoleRequest
 	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if err := request.validate(); err != nil {
+		writeValidationError(w, err)
 		return
 	}
 
-	if request.Role == "" {
-		http.Error(w, "role is required", http.StatusBadRequest)
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+	user, role := scope.qualify(userId), scope.qualify(request.Role)
+	if !s.checkQuota(w, scope, quotaPolicies, 1) {
 		return
 	}
 
-	added, err := s.rbacEnforcer.AddRoleForUser(userId, request.Role)
+	added, err := s.rbacEnforcer.AddRoleForUser(user, role)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to add role: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to add role: %v", err))
 		return
 	}
 
 	if !added {
 		response := map[string]interface{}{
+			"code":    errorCode(http.StatusConflict),
 			"added":   false,
 			"message": "User already has this role",
 			"user":    userId,
@@ -2141,13 +3754,22 @@ func (s *AuthService) addUserRoleHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	s.rbacEnforcer.SavePolicy()
+	s.recordPolicyMetadata(labelKindRole, labelKey(user, role), actorFromRequest(r))
+	s.publishChange(changeKindRBACRole, changeAdded, labelKey(user, role), roleChange(user, role), actorFromRequest(r))
+
+	if len(request.Labels) > 0 {
+		if err := s.setLabels(labelKindRole, labelKey(user, role), request.Labels); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to label role assignment: %v", err))
+			return
+		}
+	}
 
 	response := map[string]interface{}{
 		"added":   true,
 		"message": "Role added successfully",
 		"user":    userId,
 		"role":    request.Role,
+		"labels":  normalizeLabels(request.Labels),
 		"model":   "rbac",
 	}
 
@@ -2162,15 +3784,23 @@ func (s *AuthService) deleteUserRoleHandler(w http.ResponseWriter, r *http.Reque
 	userId := vars["userId"]
 	roleId := vars["roleId"]
 
-	removed, err := s.rbacEnforcer.DeleteRoleForUser(userId, roleId)
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+	user, role := scope.qualify(userId), scope.qualify(roleId)
+
+	removed, err := s.rbacEnforcer.DeleteRoleForUser(user, role)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to remove role: %v", err), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to remove role: %v", err))
 		return
 	}
 
 	if !removed {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    errorCode(http.StatusNotFound),
 			"removed": false,
 			"message": "User does not have this role",
 			"user":    userId,
@@ -2180,7 +3810,9 @@ func (s *AuthService) deleteUserRoleHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	s.rbacEnforcer.SavePolicy()
+	s.removeLabels(labelKindRole, labelKey(user, role))
+	s.removePolicyMetadata(labelKindRole, labelKey(user, role))
+	s.publishChange(changeKindRBACRole, changeRemoved, labelKey(user, role), roleChange(user, role), actorFromRequest(r))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -2199,16 +3831,24 @@ func (s *AuthService) deleteUserAttributeHandler(w http.ResponseWriter, r *http.
 	userId := vars["userId"]
 	key := vars["key"]
 
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+	user := scope.qualify(userId)
+
 	// Remove from database
-	result := s.db.Where("user_id = ? AND attribute = ?", userId, key).Delete(&UserAttribute{})
+	result := s.db.Where("user_id = ? AND attribute = ?", user, key).Delete(&UserAttribute{})
 	if result.Error != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete user attribute: %v", result.Error), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete user attribute: %v", result.Error))
 		return
 	}
 
 	if result.RowsAffected == 0 {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    errorCode(http.StatusNotFound),
 			"removed": false,
 			"message": "Attribute not found",
 			"user":    userId,
@@ -2219,12 +3859,8 @@ func (s *AuthService) deleteUserAttributeHandler(w http.ResponseWriter, r *http.
 	}
 
 	// Remove from cache
-	if s.userAttrs[userId] != nil {
-		delete(s.userAttrs[userId], key)
-		if len(s.userAttrs[userId]) == 0 {
-			delete(s.userAttrs, userId)
-		}
-	}
+	s.invalidateUserAttributes(user)
+	s.publishChange(changeKindUserAttribute, changeRemoved, user+"."+key, attributeChange("user", user, key, ""), actorFromRequest(r))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -2243,16 +3879,24 @@ func (s *AuthService) deleteObjectAttributeHandler(w http.ResponseWriter, r *htt
 	objectId := vars["objectId"]
 	key := vars["key"]
 
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+	object := scope.qualify(objectId)
+
 	// Remove from database
-	result := s.db.Where("object_id = ? AND attribute = ?", objectId, key).Delete(&ObjectAttribute{})
+	result := s.db.Where("object_id = ? AND attribute = ?", object, key).Delete(&ObjectAttribute{})
 	if result.Error != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete object attribute: %v", result.Error), http.StatusInternalServerError)
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete object attribute: %v", result.Error))
 		return
 	}
 
 	if result.RowsAffected == 0 {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    errorCode(http.StatusNotFound),
 			"removed": false,
 			"message": "Attribute not found",
 			"object":  objectId,
@@ -2263,12 +3907,8 @@ func (s *AuthService) deleteObjectAttributeHandler(w http.ResponseWriter, r *htt
 	}
 
 	// Remove from cache
-	if s.objectAttrs[objectId] != nil {
-		delete(s.objectAttrs[objectId], key)
-		if len(s.objectAttrs[objectId]) == 0 {
-			delete(s.objectAttrs, objectId)
-		}
-	}
+	s.invalidateObjectAttributes(object)
+	s.publishChange(changeKindObjectAttribute, changeRemoved, object+"."+key, attributeChange("object", object, key, ""), actorFromRequest(r))
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -2288,17 +3928,74 @@ func (s *AuthService) updateABACPolicyHandler(w http.ResponseWriter, r *http.Req
 
 	var policy ABACPolicy
 	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
-		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	// The ID comes from the path; one in the body is ignored
+	policy.ID = policyId
+	if err := policy.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := validateConditionGroup(&policy); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := validatePolicyExpression(&policy); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	warnings, err := s.validatePolicyConditions(policy.allConditions())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if !s.acceptSchemaProblems(w, warnings) {
+		return
+	}
+
+	scope, ok := s.requestTenant(w, r, policy.Tenant)
+	if !ok {
+		return
+	}
+	policyId = scope.qualify(policyId)
+	existing, exists := s.policyEngine.getPolicy(policyId)
+	if !scope.global() && (!exists || !scope.ownsPolicy(existing)) {
+		writeJSONError(w, http.StatusNotFound, "Policy not found")
+		return
+	}
+
+	// Edits based on an older version, named by If-Match or the body's version, are rejected
+	current := 0
+	if exists {
+		current = existing.Version
+	}
+	if !matchesETag(r.Header.Get("If-Match"), policyETag(current), exists) || (policy.Version != 0 && policy.Version != current) {
+		writeVersionConflict(w, policyId, current, exists)
 		return
 	}
 
+	// Updates keep the tenant of the policy unless one is given
+	policy.Tenant = scope.tenant
+	if scope.global() && exists {
+		policy.Tenant = existing.Tenant
+	}
+
 	policy.ID = policyId
 	policy.UpdatedAt = time.Now()
 
 	// Update policy in database
-	result := s.db.Save(&policy)
-	if result.Error != nil {
-		http.Error(w, fmt.Sprintf("Failed to update policy: %v", result.Error), http.StatusInternalServerError)
+	updated, err := s.policyEngine.savePolicyVersion(&policy, existing)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to update policy: %v", err))
+		return
+	}
+	if !updated {
+		// Another update was saved since the policy was read
+		writeVersionConflict(w, policyId, current, exists)
 		return
 	}
 
@@ -2309,15 +4006,29 @@ func (s *AuthService) updateABACPolicyHandler(w http.ResponseWriter, r *http.Req
 		s.db.Create(&condition)
 	}
 
+	// Replace labels when the update provides them
+	if policy.Labels != nil {
+		policy.Labels = normalizeLabels(policy.Labels)
+		if err := s.setLabels(labelKindABAC, policyId, policy.Labels); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to label policy: %v", err))
+			return
+		}
+	}
+
 	// Reload policy engine cache
 	s.policyEngine.LoadPolicies()
+	s.publishChange(changeKindABACPolicy, changeUpdated, policyId, &policy, actorFromRequest(r))
 
 	response := map[string]interface{}{
 		"message": "ABAC policy updated successfully",
 		"policy":  policy,
 	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", policyETag(policy.Version))
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -2329,50 +4040,48 @@ func (s *AuthService) deleteRelationshipHandler(w http.ResponseWriter, r *http.R
 	// Parse relationship ID format: "subject:relationship:object"
 	parts := strings.Split(relationshipId, ":")
 	if len(parts) != 3 {
-		http.Error(w, "Relationship ID must be in format 'subject:relationship:object'", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Relationship ID must be in format 'subject:relationship:object'")
 		return
 	}
 
-	subject, relationship, object := parts[0], parts[1], parts[2]
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+	subject, relationship, object := scope.qualify(parts[0]), parts[1], scope.qualify(parts[2])
 
-	// Remove from database
-	result := s.db.Where("subject = ? AND relationship = ? AND object = ?", subject, relationship, object).Delete(&RelationshipRecord{})
-	if result.Error != nil {
-		http.Error(w, fmt.Sprintf("Failed to delete relationship: %v", result.Error), http.StatusInternalServerError)
+	// Remove from database and memory
+	removed, err := s.relationshipGraph.removeStored(subject, relationship, object)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	if result.RowsAffected == 0 {
+	if !removed {
+		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusNotFound)
 		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    errorCode(http.StatusNotFound),
 			"removed": false,
 			"message": "Relationship not found",
 			"model":   "rebac",
 		})
 		return
 	}
+	s.removeLabels(labelKindRelationship, labelKey(subject, relationship, object))
+	s.publishChange(changeKindRelationship, changeRemoved, labelKey(subject, relationship, object),
+		relationshipChange(subject, relationship, object, nil), actorFromRequest(r))
 
-	// Remove from memory
-	key := fmt.Sprintf("%s:%s", subject, relationship)
-	if objects, exists := s.relationshipGraph.relationships[key]; exists {
-		for i, obj := range objects {
-			if obj.Object == object {
-				s.relationshipGraph.relationships[key] = append(objects[:i], objects[i+1:]...)
-				if len(s.relationshipGraph.relationships[key]) == 0 {
-					delete(s.relationshipGraph.relationships, key)
-				}
-				break
-			}
-		}
+	response := map[string]interface{}{
+		"removed": true,
+		"message": "Relationship removed successfully",
+		"model":   "rebac",
 	}
+	s.relationshipGraph.addConsistencyToken(response)
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"removed": true,
-		"message": "Relationship removed successfully",
-		"model":   "rebac",
-	})
+	json.NewEncoder(w).Encode(response)
 }
 
 // findRelationshipPathHandler finds relationship paths
@@ -2382,28 +4091,92 @@ func (s *AuthService) findRelationshipPathHandler(w http.ResponseWriter, r *http
 	maxDepthStr := r.URL.Query().Get("max_depth")
 
 	if subject == "" || object == "" {
-		http.Error(w, "subject and object parameters are required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "subject and object parameters are required")
 		return
 	}
 
-	maxDepth := 5
-	if maxDepthStr != "" {
-		if depth, err := strconv.Atoi(maxDepthStr); err == nil && depth > 0 {
-			maxDepth = depth
-		}
+	maxDepth, err := s.parseMaxDepth(maxDepthStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	found, path := s.relationshipGraph.FindRelationshipPath(subject, object, maxDepth)
+	// permission restricts the paths to those that grant it, like an authorization check
+	permission := r.URL.Query().Get("permission")
+	if permission != "" && r.URL.Query().Get("action") != "" {
+		writeJSONError(w, http.StatusBadRequest, "permission and action cannot be combined")
+		return
+	}
+
+	var found bool
+	var hops []PathHop
+	if permission != "" {
+		if granting := s.relationshipGraph.FindPermissionPaths(subject, object, permission, maxDepth, 1); len(granting) > 0 {
+			found, hops = true, granting[0]
+		}
+	} else {
+		found, hops = s.relationshipGraph.FindRelationshipHops(subject, object, maxDepth)
+	}
+	path := ""
+	if found {
+		path = formatPath(subject, hops)
+	} else {
+		hops = []PathHop{}
+	}
 
 	response := map[string]interface{}{
 		"found":     found,
 		"path":      path,
+		"hops":      hops,
 		"subject":   subject,
 		"object":    object,
 		"max_depth": maxDepth,
 		"model":     "rebac",
 		"note":      "This endpoint shows relationship connectivity, not authorization. Use /api/v1/authorizations for permission checks.",
 	}
+	if permission != "" {
+		response["permission"] = permission
+		response["note"] = "Only relationships that can grant the permission are traversed. Use /api/v1/authorizations for permission checks."
+	}
+
+	// all=true enumerates every distinct path up to a limit, optionally only those granting an action
+	if r.URL.Query().Get("all") == "true" {
+		limit := defaultPathLimit
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			parsed, err := strconv.Atoi(limitStr)
+			if err != nil || parsed <= 0 {
+				writeJSONError(w, http.StatusBadRequest, "limit must be a positive integer")
+				return
+			}
+			if parsed < maxPathLimit {
+				limit = parsed
+			} else {
+				limit = maxPathLimit
+			}
+		}
+
+		var all [][]PathHop
+		if permission != "" {
+			all = s.relationshipGraph.FindPermissionPaths(subject, object, permission, maxDepth, limit)
+		} else if action := r.URL.Query().Get("action"); action != "" {
+			all = s.relationshipGraph.FindGrantPaths(subject, object, action, maxDepth, limit)
+			response["action"] = action
+		} else {
+			all = s.relationshipGraph.FindAllRelationshipHops(subject, object, maxDepth, limit, nil)
+		}
+
+		paths := make([]map[string]interface{}, 0, len(all))
+		for _, hops := range all {
+			paths = append(paths, map[string]interface{}{
+				"path":   formatPath(subject, hops),
+				"hops":   hops,
+				"length": len(hops),
+			})
+		}
+		response["paths"] = paths
+		response["count"] = len(paths)
+		response["limit"] = limit
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
@@ -2440,18 +4213,15 @@ func (s *AuthService) getRelationshipPermissionsHandler(w http.ResponseWriter, r
 
 // checkRelationshipPermissionHandler checks if a relationship grants a specific permission
 func (s *AuthService) checkRelationshipPermissionHandler(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Relationship string `json:"relationship"`
-		Permission   string `json:"permission"`
-	}
+	var req PermissionCheckRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
 		return
 	}
 
 	if req.Relationship == "" || req.Permission == "" {
-		http.Error(w, "relationship and permission fields are required", http.StatusBadRequest)
+		writeJSONError(w, http.StatusBadRequest, "relationship and permission fields are required")
 		return
 	}
 
@@ -2477,8 +4247,8 @@ func (s *AuthService) healthHandler(w http.ResponseWriter, r *http.Request) {
 		"service":          "multi-model-casbin-auth-service",
 		"supported_models": []string{"acl", "rbac", "abac", "rebac"},
 		"default_model":    "rbac",
-		"database":         "sqlite",
-		"version":          "2.0.0",
+		"database":         s.db.Dialector.Name(),
+		"version":          serviceVersion,
 		"rebac_features":   []string{"ownership", "hierarchy", "groups", "social"},
 	}
 
@@ -2486,100 +4256,305 @@ func (s *AuthService) healthHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// corsMiddleware adds CORS headers to responses
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+// registerEnforcementRoutes registers the endpoints application networks need to
+// authorize requests
+func (s *AuthService) registerEnforcementRoutes(api *mux.Router) {
+	api.HandleFunc("/health", s.healthHandler).Methods("GET")
+	api.HandleFunc("/health/ready", s.readinessHandler).Methods("GET")
+	api.HandleFunc("/models", s.getModelsHandler).Methods("GET")
+	api.HandleFunc("/openapi.json", s.openAPIHandler).Methods("GET")
 
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
+	// Authorization endpoint
+	api.Handle("/authorizations", s.enforceLimiter.wrap(http.HandlerFunc(s.authorizationHandler))).Methods("POST")
 
-		next.ServeHTTP(w, r)
-	})
+	// Kubernetes authorization webhook
+	api.Handle("/kubernetes/subjectaccessreviews", s.enforceLimiter.wrap(http.HandlerFunc(s.subjectAccessReviewHandler))).Methods("POST")
+
+	// Token authorization endpoint, only present when a JWKS is configured
+	if s.tokenAuthorization != nil {
+		api.Handle("/authorizations/token", s.enforceLimiter.wrap(http.HandlerFunc(s.tokenAuthorizationHandler))).Methods("POST")
+	}
+
+	// Envoy HTTP ext_authz endpoint, receiving requests of any method below /ext_authz
+	if s.extAuthz != nil {
+		api.Handle("/ext_authz{path:/.*}", s.enforceLimiter.wrap(http.HandlerFunc(s.extAuthzHandler)))
+	}
 }
 
-// loggingMiddleware logs incoming HTTP requests
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("%s %s %s", r.Method, r.RequestURI, r.RemoteAddr)
-		next.ServeHTTP(w, r)
-	})
+// registerAdminRoutes registers the policy administration and operational endpoints
+func (s *AuthService) registerAdminRoutes(api *mux.Router) {
+	// Policy and relationship writes wait for a second admin in change approval mode
+	if s.changeApproval {
+		api.Use(s.approvalMiddleware(api))
+	}
+
+	api.HandleFunc("/metrics", s.metricsHandler).Methods("GET")
+	api.HandleFunc("/slo", s.getSLOHandler).Methods("GET")
+
+	// Label and export endpoints
+	api.HandleFunc("/labels", s.getLabelsHandler).Methods("GET")
+	api.HandleFunc("/export", s.exportHandler).Methods("GET")
+	api.HandleFunc("/import", s.importHandler).Methods("POST")
+	api.HandleFunc("/export/diff", s.diffExportsHandler).Methods("POST")
+
+	// Transactions across models
+	api.HandleFunc("/transactions", s.transactionHandler).Methods("POST")
+
+	// SCIM 2.0 provisioning of users and groups by identity providers
+	api.HandleFunc("/scim/v2/ServiceProviderConfig", s.scimServiceProviderConfigHandler).Methods("GET")
+	api.HandleFunc("/scim/v2/Users", s.scimCreateUserHandler).Methods("POST")
+	api.HandleFunc("/scim/v2/Users", s.scimListUsersHandler).Methods("GET")
+	api.HandleFunc("/scim/v2/Users/{id}", s.scimGetUserHandler).Methods("GET")
+	api.HandleFunc("/scim/v2/Users/{id}", s.scimReplaceUserHandler).Methods("PUT")
+	api.HandleFunc("/scim/v2/Users/{id}", s.scimPatchUserHandler).Methods("PATCH")
+	api.HandleFunc("/scim/v2/Users/{id}", s.scimDeleteUserHandler).Methods("DELETE")
+	api.HandleFunc("/scim/v2/Groups", s.scimCreateGroupHandler).Methods("POST")
+	api.HandleFunc("/scim/v2/Groups", s.scimListGroupsHandler).Methods("GET")
+	api.HandleFunc("/scim/v2/Groups/{id}", s.scimGetGroupHandler).Methods("GET")
+	api.HandleFunc("/scim/v2/Groups/{id}", s.scimReplaceGroupHandler).Methods("PUT")
+	api.HandleFunc("/scim/v2/Groups/{id}", s.scimPatchGroupHandler).Methods("PATCH")
+	api.HandleFunc("/scim/v2/Groups/{id}", s.scimDeleteGroupHandler).Methods("DELETE")
+
+	// LDAP group sync
+	api.HandleFunc("/ldap/sync", s.getLDAPSyncStatusHandler).Methods("GET")
+	api.HandleFunc("/ldap/sync/run", s.runLDAPSyncHandler).Methods("POST")
+
+	// Change notification webhooks
+	api.HandleFunc("/webhooks", s.createWebhookHandler).Methods("POST")
+	api.HandleFunc("/webhooks", s.getWebhooksHandler).Methods("GET")
+	api.HandleFunc("/webhooks/{id}", s.deleteWebhookHandler).Methods("DELETE")
+
+	// Change approval endpoints
+	api.HandleFunc("/changes", s.listChangesHandler).Methods("GET")
+	api.HandleFunc("/changes/{id}", s.getChangeHandler).Methods("GET")
+	api.HandleFunc("/changes/{id}/approve", s.approveChangeHandler).Methods("POST")
+	api.HandleFunc("/changes/{id}/reject", s.rejectChangeHandler).Methods("POST")
+
+	// Decision audit endpoints
+	api.HandleFunc("/audit/decisions", s.getDecisionsHandler).Methods("GET")
+	api.HandleFunc("/audit/decisions/replay", s.replayDecisionsHandler).Methods("POST")
+	api.HandleFunc("/audit/retention", s.getRetentionStatusHandler).Methods("GET")
+	api.HandleFunc("/audit/retention/run", s.runRetentionHandler).Methods("POST")
+	api.HandleFunc("/audit/recommendations", s.getRecommendationsHandler).Methods("GET")
+
+	// Policy test runner
+	api.HandleFunc("/policies/test", s.testPoliciesHandler).Methods("POST")
+
+	// Temporary verbose tracing of selected subjects and objects
+	api.HandleFunc("/traces", s.startTraceHandler).Methods("POST")
+	api.HandleFunc("/traces", s.getTracesHandler).Methods("GET")
+	api.HandleFunc("/traces/{id}", s.getTraceHandler).Methods("GET")
+	api.HandleFunc("/traces/{id}", s.stopTraceHandler).Methods("DELETE")
+
+	// Demo walkthrough endpoints, only present in demo mode
+	if s.demoMode {
+		api.HandleFunc("/demo/scenarios", s.getDemoScenariosHandler).Methods("GET")
+		api.HandleFunc("/demo/scenarios/{name}", s.runDemoScenarioHandler).Methods("POST")
+	}
+
+	// Tenant endpoints
+	api.HandleFunc("/tenants", s.createTenantHandler).Methods("POST")
+	api.HandleFunc("/tenants", s.getTenantsHandler).Methods("GET")
+	api.HandleFunc("/tenants/templates", s.saveTenantTemplateHandler).Methods("POST")
+	api.HandleFunc("/tenants/templates", s.getTenantTemplatesHandler).Methods("GET")
+	api.HandleFunc("/tenants/{name}", s.getTenantHandler).Methods("GET")
+	api.HandleFunc("/tenants/{name}/quota", s.setTenantQuotaHandler).Methods("PUT")
+	api.HandleFunc("/tenants/{name}/quota", s.deleteTenantQuotaHandler).Methods("DELETE")
+	api.HandleFunc("/tenants/{name}/usage", s.getTenantUsageHandler).Methods("GET")
+
+	// ACL Policy endpoints
+	api.HandleFunc("/acl/policies", s.addACLPolicyHandler).Methods("POST")
+	api.HandleFunc("/acl/policies", s.getACLPoliciesHandler).Methods("GET")
+	api.HandleFunc("/acl/policies", s.deleteACLPolicyHandler).Methods("DELETE")
+	api.HandleFunc("/acl/policies/bulk", s.bulkAddACLPoliciesHandler).Methods("POST")
+	api.HandleFunc("/acl/policies/bulk", s.bulkRemoveACLPoliciesHandler).Methods("DELETE")
+	api.HandleFunc("/acl/policies/{id}", s.deleteACLPolicyHandler).Methods("DELETE")
+	api.HandleFunc("/acl/policies/{id}/history", s.aclPolicyHistoryHandler).Methods("GET")
+	api.HandleFunc("/acl/policies/{id}/restore", s.restoreACLPolicyHandler).Methods("POST")
+
+	// RBAC Policy endpoints
+	api.HandleFunc("/rbac/policies", s.addRBACPolicyHandler).Methods("POST")
+	api.HandleFunc("/rbac/policies", s.getRBACPoliciesHandler).Methods("GET")
+	api.HandleFunc("/rbac/policies/bulk", s.bulkAddRBACPoliciesHandler).Methods("POST")
+	api.HandleFunc("/rbac/policies/bulk", s.bulkRemoveRBACPoliciesHandler).Methods("DELETE")
+	api.HandleFunc("/rbac/policies/{id}", s.deleteRBACPolicyHandler).Methods("DELETE")
+	api.HandleFunc("/rbac/policies/{id}/history", s.rbacPolicyHistoryHandler).Methods("GET")
+	api.HandleFunc("/rbac/policies/{id}/restore", s.restoreRBACPolicyHandler).Methods("POST")
+
+	// RBAC role hierarchy endpoints
+	api.HandleFunc("/rbac/roles/{roleId}/parents", s.addRoleParentHandler).Methods("POST")
+	api.HandleFunc("/rbac/roles/{roleId}/parents/{parentId}", s.deleteRoleParentHandler).Methods("DELETE")
+	api.HandleFunc("/rbac/roles/{roleId}/hierarchy", s.getRoleHierarchyHandler).Methods("GET")
+
+	// RBAC resource group endpoints
+	api.HandleFunc("/rbac/resources/{objectId}/groups", s.addResourceGroupHandler).Methods("POST")
+	api.HandleFunc("/rbac/resources/{objectId}/groups", s.getResourceGroupsHandler).Methods("GET")
+	api.HandleFunc("/rbac/resources/{objectId}/groups/{groupId}", s.deleteResourceGroupHandler).Methods("DELETE")
+
+	// User role endpoints
+	api.HandleFunc("/users/{userId}", s.offboardUserHandler).Methods("DELETE")
+	api.HandleFunc("/users/{userId}/roles", s.addUserRoleHandler).Methods("POST")
+	api.HandleFunc("/users/{userId}/roles", s.getUserRolesHandler).Methods("GET")
+	api.HandleFunc("/users/{userId}/roles/{roleId}", s.deleteUserRoleHandler).Methods("DELETE")
+	api.HandleFunc("/users/{userId}/groups", s.getUserGroupsHandler).Methods("GET")
+	api.HandleFunc("/users/{userId}/permissions", s.getUserPermissionsHandler).Methods("GET")
+	api.HandleFunc("/subjects/{subject}/objects", s.listSubjectObjectsHandler).Methods("GET")
+
+	// User attributes endpoints
+	api.HandleFunc("/users/attributes/import", s.importUserAttributesHandler).Methods("POST")
+	api.HandleFunc("/users/{userId}/attributes", s.setUserAttributesHandler).Methods("PUT")
+	api.HandleFunc("/users/{userId}/attributes", s.getUserAttributesHandler).Methods("GET")
+	api.HandleFunc("/users/{userId}/attributes/{key}", s.deleteUserAttributeHandler).Methods("DELETE")
+
+	// Object attributes endpoints
+	api.HandleFunc("/objects/attributes/import", s.importObjectAttributesHandler).Methods("POST")
+	api.HandleFunc("/objects/{objectId}/attributes", s.setObjectAttributesHandler).Methods("PUT")
+	api.HandleFunc("/objects/{objectId}/attributes", s.getObjectAttributesHandler).Methods("GET")
+	api.HandleFunc("/objects/{objectId}/attributes/visible", s.getVisibleObjectAttributesHandler).Methods("GET")
+	api.HandleFunc("/objects/{objectId}/subjects", s.listObjectSubjectsHandler).Methods("GET")
+	api.HandleFunc("/objects/{objectId}/relations/{relation}/expand", s.expandRelationHandler).Methods("GET")
+	api.HandleFunc("/objects/{objectId}/attributes/{key}", s.deleteObjectAttributeHandler).Methods("DELETE")
+
+	// ABAC attribute schema endpoints
+	api.HandleFunc("/abac/schema", s.defineAttributeHandler).Methods("POST")
+	api.HandleFunc("/abac/schema", s.getAttributeSchemaHandler).Methods("GET")
+	api.HandleFunc("/abac/schema/{scope}/{name}", s.deleteAttributeDefinitionHandler).Methods("DELETE")
+	api.HandleFunc("/abac/environment", s.previewEnvironmentHandler).Methods("POST")
+
+	// ABAC Policy Management endpoints
+	api.HandleFunc("/abac/policies", s.addABACPolicyHandler).Methods("POST")
+	api.HandleFunc("/abac/policies", s.getABACPoliciesHandler).Methods("GET")
+	api.HandleFunc("/abac/policies/{id}", s.getABACPolicyHandler).Methods("GET")
+	api.HandleFunc("/abac/policies/{id}", s.updateABACPolicyHandler).Methods("PUT")
+	api.HandleFunc("/abac/policies/{id}", s.deleteABACPolicyHandler).Methods("DELETE")
+	api.HandleFunc("/abac/policies/{id}/history", s.abacPolicyHistoryHandler).Methods("GET")
+	api.HandleFunc("/abac/policies/{id}/restore", s.restoreABACPolicyHandler).Methods("POST")
+	api.HandleFunc("/abac/analysis", s.analyzePoliciesHandler).Methods("GET")
+
+	// ReBAC relationship endpoints
+	api.HandleFunc("/relationships", s.addRelationshipHandler).Methods("POST")
+	api.HandleFunc("/relationships", s.getRelationshipsHandler).Methods("GET")
+	api.HandleFunc("/relationships/bulk", s.bulkAddRelationshipsHandler).Methods("POST")
+	api.HandleFunc("/relationships/watch", s.watchRelationshipsHandler).Methods("GET")
+	api.HandleFunc("/relationships/export", s.exportRelationshipsHandler).Methods("GET")
+	api.HandleFunc("/relationships/{id}", s.deleteRelationshipHandler).Methods("DELETE")
+	api.HandleFunc("/relationships/paths", s.findRelationshipPathHandler).Methods("GET")
+	api.HandleFunc("/relationships/partitions", s.getRelationshipPartitionsHandler).Methods("GET")
+	api.HandleFunc("/relationships/constraints", s.getRelationshipConstraintsHandler).Methods("GET")
+	api.HandleFunc("/relationships/suggestions", s.getAccessSuggestionsHandler).Methods("GET")
+
+	// ReBAC namespace definition (relation rewrite rule) endpoints
+	api.HandleFunc("/relationships/namespaces", s.getNamespacesHandler).Methods("GET")
+	api.HandleFunc("/relationships/namespaces/{name}", s.getNamespaceHandler).Methods("GET")
+	api.HandleFunc("/relationships/namespaces/{name}", s.putNamespaceHandler).Methods("PUT")
+	api.HandleFunc("/relationships/namespaces/{name}", s.deleteNamespaceHandler).Methods("DELETE")
+
+	// ReBAC custom actions
+	api.HandleFunc("/relationships/actions", s.getActionsHandler).Methods("GET")
+	api.HandleFunc("/relationships/actions/{action}", s.getActionHandler).Methods("GET")
+	api.HandleFunc("/relationships/actions/{action}", s.putActionHandler).Methods("PUT")
+	api.HandleFunc("/relationships/actions/{action}", s.deleteActionHandler).Methods("DELETE")
+
+	// Action hierarchy endpoints
+	api.HandleFunc("/actions", s.getActionImplicationsHandler).Methods("GET")
+	api.HandleFunc("/actions/{action}", s.getActionImplicationHandler).Methods("GET")
+	api.HandleFunc("/actions/{action}", s.putActionImplicationHandler).Methods("PUT")
+	api.HandleFunc("/actions/{action}", s.deleteActionImplicationHandler).Methods("DELETE")
+
+	// ReBAC permission mapping endpoints (following best practices)
+	api.HandleFunc("/relationships/permissions", s.getRelationshipPermissionsHandler).Methods("GET")
+	api.HandleFunc("/relationships/permissions/check", s.checkRelationshipPermissionHandler).Methods("POST")
 }
 
 // main initializes and starts the authorization microservice
 func main() {
+	// CONFIG_FILE provides defaults for the environment, including to subcommands
+	configFile, err := loadConfigFile()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Subcommands such as "diff" run and exit without starting the server
+	if handled, err := runCommand(os.Args[1:], os.Stdout); handled {
+		if err != nil {
+			log.Fatalf("Command failed: %v", err)
+		}
+		return
+	}
+
 	// Initialize authorization service
 	authService, err := NewAuthService()
 	if err != nil {
 		log.Fatalf("Failed to initialize authorization service: %v", err)
 	}
 
+	// Apply configuration file changes on SIGHUP or when the file is modified
+	authService.watchConfigFile(configFile)
+
 	// Set up initial data
 	err = authService.initializeData()
 	if err != nil {
 		log.Printf("Failed to set up initial data: %v", err)
 	}
 
-	// Set up router
-	router := mux.NewRouter()
-
-	// Define API endpoints
-	api := router.PathPrefix("/api/v1").Subrouter()
-	api.HandleFunc("/health", authService.healthHandler).Methods("GET")
-	api.HandleFunc("/models", authService.getModelsHandler).Methods("GET")
-
-	// Authorization endpoint
-	api.HandleFunc("/authorizations", authService.authorizationHandler).Methods("POST")
-
-	// ACL Policy endpoints
-	api.HandleFunc("/acl/policies", authService.addACLPolicyHandler).Methods("POST")
-	api.HandleFunc("/acl/policies", authService.getACLPoliciesHandler).Methods("GET")
-	api.HandleFunc("/acl/policies/{id}", authService.deleteACLPolicyHandler).Methods("DELETE")
-
-	// RBAC Policy endpoints
-	api.HandleFunc("/rbac/policies", authService.addRBACPolicyHandler).Methods("POST")
-	api.HandleFunc("/rbac/policies", authService.getRBACPoliciesHandler).Methods("GET")
-	api.HandleFunc("/rbac/policies/{id}", authService.deleteRBACPolicyHandler).Methods("DELETE")
-
-	// User role endpoints
-	api.HandleFunc("/users/{userId}/roles", authService.addUserRoleHandler).Methods("POST")
-	api.HandleFunc("/users/{userId}/roles", authService.getUserRolesHandler).Methods("GET")
-	api.HandleFunc("/users/{userId}/roles/{roleId}", authService.deleteUserRoleHandler).Methods("DELETE")
+	// Demo mode loads a sample organization for evaluation and onboarding
+	if authService.demoMode {
+		if err := authService.LoadDemoData(); err != nil {
+			log.Fatalf("Failed to load demo data: %v", err)
+		}
+		log.Printf("Demo mode enabled: see GET /api/v1/demo/scenarios")
+	}
 
-	// User attributes endpoints
-	api.HandleFunc("/users/{userId}/attributes", authService.setUserAttributesHandler).Methods("PUT")
-	api.HandleFunc("/users/{userId}/attributes", authService.getUserAttributesHandler).Methods("GET")
-	api.HandleFunc("/users/{userId}/attributes/{key}", authService.deleteUserAttributeHandler).Methods("DELETE")
+	// Archive and delete audited decisions past the retention period
+	authService.startDecisionRetention()
 
-	// Object attributes endpoints
-	api.HandleFunc("/objects/{objectId}/attributes", authService.setObjectAttributesHandler).Methods("PUT")
-	api.HandleFunc("/objects/{objectId}/attributes", authService.getObjectAttributesHandler).Methods("GET")
-	api.HandleFunc("/objects/{objectId}/attributes/{key}", authService.deleteObjectAttributeHandler).Methods("DELETE")
+	// Pull groups from LDAP and reconcile role assignments
+	authService.startLDAPSync()
 
-	// ABAC Policy Management endpoints
-	api.HandleFunc("/abac/policies", authService.addABACPolicyHandler).Methods("POST")
-	api.HandleFunc("/abac/policies", authService.getABACPoliciesHandler).Methods("GET")
-	api.HandleFunc("/abac/policies/{id}", authService.getABACPolicyHandler).Methods("GET")
-	api.HandleFunc("/abac/policies/{id}", authService.updateABACPolicyHandler).Methods("PUT")
-	api.HandleFunc("/abac/policies/{id}", authService.deleteABACPolicyHandler).Methods("DELETE")
+	// Purge expired relationship tuples and idempotency keys
+	authService.startRelationshipJanitor()
+	authService.idempotency.start()
 
-	// ReBAC relationship endpoints
-	api.HandleFunc("/relationships", authService.addRelationshipHandler).Methods("POST")
-	api.HandleFunc("/relationships", authService.getRelationshipsHandler).Methods("GET")
-	api.HandleFunc("/relationships/{id}", authService.deleteRelationshipHandler).Methods("DELETE")
-	api.HandleFunc("/relationships/paths", authService.findRelationshipPathHandler).Methods("GET")
+	// Set up routers. Administration endpoints share the main listener unless
+	// ADMIN_LISTEN moves them to a separate port or unix socket.
+	router := mux.NewRouter()
+	authService.registerEnforcementRoutes(router.PathPrefix("/api/v1").Subrouter())
 
-	// ReBAC permission mapping endpoints (following best practices)
-	api.HandleFunc("/relationships/permissions", authService.getRelationshipPermissionsHandler).Methods("GET")
-	api.HandleFunc("/relationships/permissions/check", authService.checkRelationshipPermissionHandler).Methods("POST")
+	adminListen := os.Getenv("ADMIN_LISTEN")
+	adminRouter := router
+	if adminListen != "" {
+		adminRouter = mux.NewRouter()
+		adminRouter.HandleFunc("/api/v1/health", authService.healthHandler).Methods("GET")
+		adminRouter.HandleFunc("/api/v1/health/ready", authService.readinessHandler).Methods("GET")
+	}
+	authService.registerAdminRoutes(adminRouter.PathPrefix("/api/v1").Subrouter())
 
-	// Apply middleware
-	router.Use(corsMiddleware)
-	router.Use(loggingMiddleware)
+	// Apply middleware in the configured order
+	middlewares, err := authService.buildMiddlewareChain(middlewareChainFromEnv())
+	if err != nil {
+		log.Fatalf("Failed to configure middleware: %v", err)
+	}
+	router.Use(middlewares...)
+	router.Use(authService.authenticator.middleware)
+	router.Use(authService.idempotency.middleware)
+	router.Use(validatePathMiddleware)
+	router.NotFoundHandler = routeNotFoundHandler(router)
+	router.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+	if adminListen != "" {
+		adminMiddlewares, err := authService.buildMiddlewareChain(middlewareChainFromEnv())
+		if err != nil {
+			log.Fatalf("Failed to configure middleware: %v", err)
+		}
+		adminRouter.Use(adminMiddlewares...)
+		adminRouter.Use(authService.authenticator.middleware)
+		adminRouter.Use(authService.idempotency.middleware)
+		adminRouter.Use(validatePathMiddleware)
+		adminRouter.NotFoundHandler = routeNotFoundHandler(adminRouter)
+		adminRouter.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+	}
+	if authService.authenticator == nil {
+		log.Printf("Warning: authentication is disabled; set AUTH_API_KEYS, AUTH_JWT_SECRET or AUTH_CONFIG_FILE to protect the API")
+	}
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -2611,7 +4586,41 @@ func main() {
 	log.Printf("  GET  /api/v1/relationships?subject=alice - Get relationships (ReBAC only)")
 	log.Printf("  GET  /api/v1/relationships/path?subject=alice&object=document1 - Find relationship path (ReBAC only)")
 
-	if err := http.ListenAndServe(addr, router); err != nil {
+	serverCfg, err := serverConfigFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure HTTP server: %v", err)
+	}
+	server := newHTTPServer(addr, router, serverCfg)
+	log.Printf("HTTP/2 cleartext (h2c) enabled: %v, idle timeout: %v", serverCfg.EnableH2C, serverCfg.IdleTimeout)
+
+	if adminListen != "" {
+		adminListener, err := listen(adminListen)
+		if err != nil {
+			log.Fatalf("Failed to open admin listener: %v", err)
+		}
+		log.Printf("Serving administration endpoints on %s", adminListen)
+		go func() {
+			if err := newHTTPServer(adminListen, adminRouter, serverCfg).Serve(adminListener); err != nil {
+				log.Fatalf("Admin server failed: %v", err)
+			}
+		}()
+	}
+
+	// GRPC_LISTEN serves the gRPC API alongside HTTP (e.g. ":9090" or "unix:/run/authz-grpc.sock")
+	if grpcListen := os.Getenv("GRPC_LISTEN"); grpcListen != "" {
+		grpcListener, err := listen(grpcListen)
+		if err != nil {
+			log.Fatalf("Failed to open gRPC listener: %v", err)
+		}
+		log.Printf("Serving gRPC API on %s", grpcListen)
+		go func() {
+			if err := newGRPCServer(authService).Serve(grpcListener); err != nil {
+				log.Fatalf("gRPC server failed: %v", err)
+			}
+		}()
+	}
+
+	if err := server.ListenAndServe(); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
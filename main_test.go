@@ -37,6 +37,22 @@ func setupTestDB() (*gorm.DB, error) {
 		&ObjectAttribute{},
 		&ABACPolicy{},
 		&PolicyCondition{},
+		&DecisionRecord{},
+		&ResourceLabel{},
+		&PolicyMetadata{},
+		&Tenant{},
+		&TenantTemplateRecord{},
+		&AttributeDefinition{},
+		&Webhook{},
+		&PolicyRevision{},
+		&ChangeRequest{},
+		&RelationshipChange{},
+		&RuleCondition{},
+		&ActionImplication{},
+		&SCIMUser{},
+		&SCIMGroup{},
+		&SCIMMember{},
+		&LDAPSyncAssignment{},
 	)
 	if err != nil {
 		return nil, err
@@ -54,8 +70,8 @@ func setupTestService(t *testing.T) *AuthService {
 
 	service := &AuthService{
 		db:                    db,
-		userAttrs:            make(map[string]map[string]string),
-		objectAttrs:          make(map[string]map[string]string),
+		userAttrs:            newAttributeCache("user", defaultAttributeCacheSize, defaultAttributeCacheTTL),
+		objectAttrs:          newAttributeCache("object", defaultAttributeCacheSize, defaultAttributeCacheTTL),
 		aclEnforcer:          nil,
 		rbacEnforcer:         nil,
 		abacEnforcer:         nil,
@@ -63,47 +79,6 @@ func setupTestService(t *testing.T) *AuthService {
 		policyEngine:         nil,
 	}
 
-	// Initialize enforcers using embedded logic from NewAuthService
-	// Create model strings for test enforcers
-	aclModel := `[request_definition]
-r = sub, obj, act
-
-[policy_definition]
-p = sub, obj, act
-
-[policy_effect]
-e = some(where (p.eft == allow))
-
-[matchers]
-m = r.sub == p.sub && r.obj == p.obj && r.act == p.act`
-
-	rbacModel := `[request_definition]
-r = sub, obj, act
-
-[policy_definition]
-p = sub, obj, act
-
-[role_definition]
-g = _, _
-
-[policy_effect]
-e = some(where (p.eft == allow))
-
-[matchers]
-m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act`
-
-	abacModel := `[request_definition]
-r = sub, obj, act
-
-[policy_definition]
-p = sub, obj, act
-
-[policy_effect]
-e = some(where (p.eft == allow))
-
-[matchers]
-m = keyMatch(r.sub, p.sub) && keyMatch(r.obj, p.obj) && keyMatch(r.act, p.act)`
-
 	// Create adapters for each model
 	aclAdapter, err := gormadapter.NewAdapterByDBUseTableName(db, "", "acl_rules")
 	if err != nil {
@@ -120,21 +95,22 @@ m = keyMatch(r.sub, p.sub) && keyMatch(r.obj, p.obj) && keyMatch(r.act, p.act)`
 		t.Fatalf("Failed to create ABAC adapter: %v", err)
 	}
 
-	// Create enforcers
+	// Create enforcers from the production model definitions
 	aclModelObj, err := model.NewModelFromString(aclModel)
 	if err != nil {
 		t.Fatalf("Failed to create ACL model: %v", err)
 	}
-	service.aclEnforcer, err = casbin.NewEnforcer(aclModelObj, aclAdapter)
+	service.aclEnforcer, err = casbin.NewSyncedEnforcer(aclModelObj, aclAdapter)
 	if err != nil {
 		t.Fatalf("Failed to create ACL enforcer: %v", err)
 	}
+	registerACLFunctions(service.aclEnforcer)
 
 	rbacModelObj, err := model.NewModelFromString(rbacModel)
 	if err != nil {
 		t.Fatalf("Failed to create RBAC model: %v", err)
 	}
-	service.rbacEnforcer, err = casbin.NewEnforcer(rbacModelObj, rbacAdapter)
+	service.rbacEnforcer, err = casbin.NewSyncedEnforcer(rbacModelObj, rbacAdapter)
 	if err != nil {
 		t.Fatalf("Failed to create RBAC enforcer: %v", err)
 	}
@@ -143,7 +119,7 @@ m = keyMatch(r.sub, p.sub) && keyMatch(r.obj, p.obj) && keyMatch(r.act, p.act)`
 	if err != nil {
 		t.Fatalf("Failed to create ABAC model: %v", err)
 	}
-	service.abacEnforcer, err = casbin.NewEnforcer(abacModelObj, abacAdapter)
+	service.abacEnforcer, err = casbin.NewSyncedEnforcer(abacModelObj, abacAdapter)
 	if err != nil {
 		t.Fatalf("Failed to create ABAC enforcer: %v", err)
 	}
@@ -157,10 +133,12 @@ m = keyMatch(r.sub, p.sub) && keyMatch(r.obj, p.obj) && keyMatch(r.act, p.act)`
 	policyEngine := NewPolicyEngine(db)
 	service.policyEngine = policyEngine
 
-	// Load attributes from database using the correct method name
-	err = service.loadABACAttributes()
-	if err != nil {
-		t.Fatalf("Failed to load attributes: %v", err)
+	if err := service.registerRBACConditions(service.rbacEnforcer); err != nil {
+		t.Fatalf("Failed to load RBAC rule conditions: %v", err)
+	}
+
+	if err := service.registerActionHierarchy(service.aclEnforcer, service.rbacEnforcer); err != nil {
+		t.Fatalf("Failed to load action hierarchy: %v", err)
 	}
 
 	return service
@@ -457,6 +435,54 @@ func TestPolicyEngine_RemovePolicy(t *testing.T) {
 	}
 }
 
+func TestPolicyEngine_ActionScopedPolicy(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	pe := NewPolicyEngine(db)
+
+	// Read-only policy without an action condition row
+	policy := &ABACPolicy{
+		ID:       "read_only",
+		Name:     "Read Only",
+		Effect:   "allow",
+		Priority: 100,
+		Actions:  []string{"read", "list"},
+		Conditions: []PolicyCondition{
+			{Type: "user", Field: "department", Operator: "eq", Value: "engineering"},
+		},
+	}
+	if err := pe.AddPolicy(policy); err != nil {
+		t.Fatalf("Failed to add policy: %v", err)
+	}
+
+	ctx := &PolicyEvaluationContext{
+		UserAttributes:        map[string]string{"department": "engineering"},
+		ObjectAttributes:      make(map[string]string),
+		EnvironmentAttributes: make(map[string]string),
+		ActionAttributes:      make(map[string]string),
+		Subject:               "alice",
+		Object:                "document1",
+	}
+
+	for action, expected := range map[string]bool{"read": true, "list": true, "write": false, "delete": false} {
+		ctx.Action = action
+		if allowed, _ := pe.Evaluate(ctx); allowed != expected {
+			t.Errorf("Evaluate(%s) = %v, expected %v", action, allowed, expected)
+		}
+	}
+
+	// Actions survive a reload from the database
+	if err := pe.LoadPolicies(); err != nil {
+		t.Fatalf("Failed to reload policies: %v", err)
+	}
+	if actions := pe.policies["read_only"].Actions; len(actions) != 2 || actions[0] != "read" || actions[1] != "list" {
+		t.Errorf("Expected actions [read list] after reload, got %v", actions)
+	}
+}
+
 // Integration Tests
 func TestAuthService_Integration(t *testing.T) {
 	service := setupTestService(t)
@@ -464,7 +490,7 @@ func TestAuthService_Integration(t *testing.T) {
 	// Test ACL
 	t.Run("ACL Integration", func(t *testing.T) {
 		// Add ACL policy
-		added, err := service.aclEnforcer.AddPolicy("alice", "document1", "read")
+		added, err := service.aclEnforcer.AddPolicy("alice", "document1", "read", "allow")
 		if err != nil || !added {
 			t.Errorf("Failed to add ACL policy: %v", err)
 		}
@@ -490,7 +516,7 @@ func TestAuthService_Integration(t *testing.T) {
 			t.Fatalf("Failed to add role: %v", err)
 		}
 
-		_, err = service.rbacEnforcer.AddPolicy("admin", "document1", "read")
+		_, err = service.rbacEnforcer.AddPolicy("admin", "document1", "read", "allow")
 		if err != nil {
 			t.Fatalf("Failed to add RBAC policy: %v", err)
 		}
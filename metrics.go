@@ -0,0 +1,90 @@
+// Multi-Model Authorization Microservice - Metrics
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// metricsRegistry holds in-process counters exposed through the metrics endpoint
+type metricsRegistry struct {
+	mu       sync.RWMutex
+	counters map[string]*atomic.Int64
+}
+
+// serviceMetrics is the process-wide metrics registry
+var serviceMetrics = newMetricsRegistry()
+
+// newMetricsRegistry creates an empty metrics registry
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{
+		counters: make(map[string]*atomic.Int64),
+	}
+}
+
+// counter returns the named counter, creating it on first use
+func (m *metricsRegistry) counter(name string) *atomic.Int64 {
+	m.mu.RLock()
+	c, exists := m.counters[name]
+	m.mu.RUnlock()
+	if exists {
+		return c
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, exists = m.counters[name]; !exists {
+		c = &atomic.Int64{}
+		m.counters[name] = c
+	}
+	return c
+}
+
+// Inc increments the named counter by one
+func (m *metricsRegistry) Inc(name string) {
+	m.counter(name).Add(1)
+}
+
+// Get returns the current value of the named counter
+func (m *metricsRegistry) Get(name string) int64 {
+	return m.counter(name).Load()
+}
+
+// Snapshot returns the current value of every counter
+func (m *metricsRegistry) Snapshot() map[string]int64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := make(map[string]int64, len(m.counters))
+	for name, c := range m.counters {
+		snapshot[name] = c.Load()
+	}
+	return snapshot
+}
+
+// metricsHandler returns the in-process counters
+func (s *AuthService) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{
+		"counters": serviceMetrics.Snapshot(),
+	}
+	if s.userAttrs != nil && s.objectAttrs != nil {
+		response["attribute_caches"] = map[string]AttributeCacheStats{
+			"user":   s.userAttrs.Stats(),
+			"object": s.objectAttrs.Stats(),
+		}
+	}
+	if s.decisions != nil {
+		response["decision_cache"] = s.decisions.Stats()
+	}
+	if stats := s.relationshipGraph.ReplicaStats(); stats != nil {
+		response["replica"] = stats
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
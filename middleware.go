@@ -0,0 +1,425 @@
+// Multi-Model Authorization Microservice - HTTP Middleware Chain
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultMiddlewareChain is the middleware order used when MIDDLEWARE_CHAIN is not set.
+// The first entry is the outermost middleware.
+var defaultMiddlewareChain = []string{"requestid", "recovery", "cors", "logging"}
+
+// middlewareFactories builds each named middleware; entries are referenced from MIDDLEWARE_CHAIN
+var middlewareFactories = map[string]func(s *AuthService) (mux.MiddlewareFunc, error){
+	"requestid":   func(s *AuthService) (mux.MiddlewareFunc, error) { return requestIDMiddleware, nil },
+	"recovery":    func(s *AuthService) (mux.MiddlewareFunc, error) { return newRecoveryMiddleware(s.errorReporter), nil },
+	"cors":        func(s *AuthService) (mux.MiddlewareFunc, error) { return s.corsMiddleware, nil },
+	"logging":     func(s *AuthService) (mux.MiddlewareFunc, error) { return loggingMiddleware, nil },
+	"compression": func(s *AuthService) (mux.MiddlewareFunc, error) { return compressionMiddleware, nil },
+	"ratelimit":   newRateLimitMiddlewareFromEnv,
+}
+
+// middlewareChainFromEnv returns the configured middleware names in order
+func middlewareChainFromEnv() []string {
+	chainStr, exists := os.LookupEnv("MIDDLEWARE_CHAIN")
+	if !exists {
+		return defaultMiddlewareChain
+	}
+
+	var names []string
+	for _, name := range strings.Split(chainStr, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// buildMiddlewareChain resolves middleware names into middleware functions, preserving order
+func (s *AuthService) buildMiddlewareChain(names []string) ([]mux.MiddlewareFunc, error) {
+	seen := make(map[string]bool)
+	chain := make([]mux.MiddlewareFunc, 0, len(names))
+
+	for _, name := range names {
+		factory, exists := middlewareFactories[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown middleware: %s", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("middleware listed more than once: %s", name)
+		}
+		seen[name] = true
+
+		mw, err := factory(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure %s middleware: %v", name, err)
+		}
+		chain = append(chain, mw)
+	}
+
+	return chain, nil
+}
+
+// corsMiddleware adds CORS headers allowing any origin to responses
+func corsMiddleware(next http.Handler) http.Handler {
+	return corsHandler(next, func() *runtimeSettings { return defaultRuntimeSettings })
+}
+
+// corsMiddleware adds CORS headers for the origins allowed by the current settings
+func (s *AuthService) corsMiddleware(next http.Handler) http.Handler {
+	return corsHandler(next, s.currentSettings)
+}
+
+// corsHandler adds CORS headers to responses. Listed origins are echoed back, so responses
+// vary by Origin unless any origin is allowed. Browsers may send the credential, tenant,
+// idempotency and conditional request headers and read the response headers clients act
+// on, such as ETag for optimistic concurrency.
+func corsHandler(next http.Handler, settings func() *runtimeSettings) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := settings().allowedOrigin(r.Header.Get("Origin"))
+		if origin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if origin != "*" {
+			w.Header().Add("Vary", "Origin")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-API-Key, "+tenantHeader+", "+idempotencyKeyHeader+", If-Match, If-None-Match, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Idempotent-Replayed, Retry-After, X-Request-ID, "+quotaWarningHeader)
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// loggingMiddleware logs incoming HTTP requests
+func loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestID := requestIDFromContext(r.Context()); requestID != "" {
+			log.Printf("[%s] %s %s %s", requestID, r.Method, r.RequestURI, r.RemoteAddr)
+		} else {
+			log.Printf("%s %s %s", r.Method, r.RequestURI, r.RemoteAddr)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestIDKey is the context key holding the request ID
+type requestIDKey struct{}
+
+// requestIDFromContext returns the request ID assigned by requestIDMiddleware, if any
+func requestIDFromContext(ctx context.Context) string {
+	if requestID, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return requestID
+	}
+	return ""
+}
+
+// newRequestID generates a random request identifier
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// requestIDMiddleware propagates the caller's X-Request-ID or assigns a new one
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" || len(requestID) > 128 {
+			requestID = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		ctx := context.WithValue(r.Context(), requestIDKey{}, requestID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// PanicReport describes a panic recovered while serving a request
+type PanicReport struct {
+	RequestID string    `json:"request_id"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Error     string    `json:"error"`
+	Stack     string    `json:"stack"`
+	Time      time.Time `json:"time"`
+}
+
+// ErrorReporter receives recovered panics, e.g. to forward them to Sentry or another error tracker
+type ErrorReporter interface {
+	ReportPanic(report PanicReport)
+}
+
+// webhookErrorReporter posts panic reports as JSON to an HTTP collector
+type webhookErrorReporter struct {
+	url    string
+	client *http.Client
+}
+
+// newWebhookErrorReporter creates a reporter that posts to the given URL
+func newWebhookErrorReporter(url string) *webhookErrorReporter {
+	return &webhookErrorReporter{
+		url:    url,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// ReportPanic sends the report without blocking the request
+func (wr *webhookErrorReporter) ReportPanic(report PanicReport) {
+	body, err := json.Marshal(report)
+	if err != nil {
+		log.Printf("Failed to encode panic report: %v", err)
+		return
+	}
+
+	go func() {
+		resp, err := wr.client.Post(wr.url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("Failed to deliver panic report: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
+
+// newRecoveryMiddleware converts handler panics into JSON 500 responses, logs the stack trace
+// and forwards the panic to the reporter when one is configured
+func newRecoveryMiddleware(reporter ErrorReporter) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				report := PanicReport{
+					RequestID: requestIDFromContext(r.Context()),
+					Method:    r.Method,
+					Path:      r.URL.Path,
+					Error:     fmt.Sprint(rec),
+					Stack:     string(debug.Stack()),
+					Time:      time.Now(),
+				}
+
+				serviceMetrics.Inc("http_panics_recovered_total")
+				log.Printf("[%s] panic serving %s %s: %s\n%s", report.RequestID, report.Method, report.Path, report.Error, report.Stack)
+				if reporter != nil {
+					reporter.ReportPanic(report)
+				}
+
+				writeErrorResponse(w, http.StatusInternalServerError, ErrorResponse{
+					Code:    errorCode(http.StatusInternalServerError),
+					Message: "Internal server error",
+					Details: map[string]string{"request_id": report.RequestID},
+				})
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// gzipResponseWriter compresses the response body. Whether to compress is decided when
+// the header is written, once the handler has set the content type: event streams are
+// passed through, since clients read their events as they arrive.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	writer      *gzip.Writer // nil until a compressed body starts
+	wroteHeader bool
+}
+
+func (g *gzipResponseWriter) WriteHeader(status int) {
+	if g.wroteHeader {
+		return
+	}
+	g.wroteHeader = true
+	if !strings.HasPrefix(g.Header().Get("Content-Type"), "text/event-stream") {
+		g.Header().Set("Content-Encoding", "gzip")
+		g.Header().Del("Content-Length")
+		g.writer = gzip.NewWriter(g.ResponseWriter)
+	}
+	g.ResponseWriter.WriteHeader(status)
+}
+
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.writer == nil {
+		return g.ResponseWriter.Write(b)
+	}
+	return g.writer.Write(b)
+}
+
+// Flush sends the compressed data written so far, so streamed responses are not held back
+func (g *gzipResponseWriter) Flush() {
+	if !g.wroteHeader {
+		g.WriteHeader(http.StatusOK)
+	}
+	if g.writer != nil {
+		g.writer.Flush()
+	}
+	if flusher, ok := g.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// close ends the compressed body, if one was started
+func (g *gzipResponseWriter) close() {
+	if g.writer != nil {
+		g.writer.Close()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (g *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return g.ResponseWriter
+}
+
+// compressionMiddleware gzips responses for clients that accept it
+func compressionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		gz := &gzipResponseWriter{ResponseWriter: w}
+		defer gz.close()
+
+		next.ServeHTTP(gz, r)
+	})
+}
+
+// rateLimiter is a per-client token bucket limiter
+type rateLimiter struct {
+	mu      sync.Mutex
+	rate    float64 // Tokens added per second
+	burst   float64 // Maximum tokens per client
+	buckets map[string]*tokenBucket
+}
+
+// tokenBucket tracks the remaining tokens of a single client
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// newRateLimiter creates a limiter allowing rate requests per second with the given burst
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow reports whether the client may make another request
+func (rl *rateLimiter) allow(client string, now time.Time) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	bucket, exists := rl.buckets[client]
+	if !exists {
+		bucket = &tokenBucket{tokens: rl.burst, lastSeen: now}
+		rl.buckets[client] = bucket
+	}
+
+	bucket.tokens += now.Sub(bucket.lastSeen).Seconds() * rl.rate
+	if bucket.tokens > rl.burst {
+		bucket.tokens = rl.burst
+	}
+	bucket.lastSeen = now
+
+	// Drop idle clients so the bucket map does not grow without bound
+	if len(rl.buckets) > 10000 {
+		for key, b := range rl.buckets {
+			if now.Sub(b.lastSeen) > time.Minute {
+				delete(rl.buckets, key)
+			}
+		}
+	}
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// middleware rejects requests from clients that exceeded their rate
+func (rl *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			client = r.RemoteAddr
+		}
+
+		if !rl.allow(client, time.Now()) {
+			w.Header().Set("Retry-After", "1")
+			writeJSONError(w, http.StatusTooManyRequests, "Rate limit exceeded")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newRateLimitMiddlewareFromEnv configures rate limiting from RATE_LIMIT_RPS and RATE_LIMIT_BURST
+func newRateLimitMiddlewareFromEnv(s *AuthService) (mux.MiddlewareFunc, error) {
+	rate := 100.0
+	if rateStr := os.Getenv("RATE_LIMIT_RPS"); rateStr != "" {
+		parsed, err := strconv.ParseFloat(rateStr, 64)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_RPS value: %s", rateStr)
+		}
+		rate = parsed
+	}
+
+	burst := int(rate)
+	if burstStr := os.Getenv("RATE_LIMIT_BURST"); burstStr != "" {
+		parsed, err := strconv.Atoi(burstStr)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid RATE_LIMIT_BURST value: %s", burstStr)
+		}
+		burst = parsed
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return newRateLimiter(rate, burst).middleware, nil
+}
@@ -0,0 +1,208 @@
+// Multi-Model Authorization Microservice - Middleware Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMiddleware_BuildChain(t *testing.T) {
+	service := &AuthService{}
+
+	chain, err := service.buildMiddlewareChain([]string{"recovery", "compression", "cors"})
+	if err != nil {
+		t.Fatalf("Failed to build middleware chain: %v", err)
+	}
+	if len(chain) != 3 {
+		t.Errorf("Expected 3 middlewares, got %d", len(chain))
+	}
+
+	if _, err := service.buildMiddlewareChain([]string{"cors", "unknown"}); err == nil {
+		t.Error("Expected error for unknown middleware")
+	}
+
+	if _, err := service.buildMiddlewareChain([]string{"cors", "cors"}); err == nil {
+		t.Error("Expected error for duplicate middleware")
+	}
+
+	t.Setenv("MIDDLEWARE_CHAIN", " Logging, cors ,")
+	names := middlewareChainFromEnv()
+	if len(names) != 2 || names[0] != "logging" || names[1] != "cors" {
+		t.Errorf("Unexpected middleware chain from environment: %v", names)
+	}
+}
+
+// recordingReporter captures panic reports for assertions
+type recordingReporter struct {
+	reports []PanicReport
+}
+
+func (rr *recordingReporter) ReportPanic(report PanicReport) {
+	rr.reports = append(rr.reports, report)
+}
+
+func TestMiddleware_Recovery(t *testing.T) {
+	reporter := &recordingReporter{}
+	router := mux.NewRouter()
+	router.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	router.Use(requestIDMiddleware, newRecoveryMiddleware(reporter))
+
+	before := serviceMetrics.Get("http_panics_recovered_total")
+
+	req, _ := http.NewRequest("GET", "/panic", nil)
+	req.Header.Set("X-Request-ID", "req-123")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", rr.Code)
+	}
+
+	var response struct {
+		Code    string            `json:"code"`
+		Message string            `json:"message"`
+		Details map[string]string `json:"details"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Expected JSON error body: %v", err)
+	}
+	if response.Code != "internal_server_error" || response.Message == "" {
+		t.Errorf("Expected an error envelope, got %+v", response)
+	}
+	if response.Details["request_id"] != "req-123" {
+		t.Errorf("Expected request ID to be echoed, got %v", response.Details["request_id"])
+	}
+
+	if len(reporter.reports) != 1 {
+		t.Fatalf("Expected 1 panic report, got %d", len(reporter.reports))
+	}
+	report := reporter.reports[0]
+	if report.Error != "boom" || report.RequestID != "req-123" || report.Stack == "" {
+		t.Errorf("Unexpected panic report: %+v", report)
+	}
+
+	if serviceMetrics.Get("http_panics_recovered_total") != before+1 {
+		t.Error("Expected panic counter to be incremented")
+	}
+}
+
+func TestMiddleware_RequestID(t *testing.T) {
+	var seen string
+	handler := requestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFromContext(r.Context())
+	}))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if seen == "" {
+		t.Fatal("Expected a generated request ID in the context")
+	}
+	if rr.Header().Get("X-Request-ID") != seen {
+		t.Errorf("Expected X-Request-ID header %q, got %q", seen, rr.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestMiddleware_Compression(t *testing.T) {
+	handler := compressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"healthy"}`))
+	}))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("Expected gzip content encoding")
+	}
+
+	reader, err := gzip.NewReader(rr.Body)
+	if err != nil {
+		t.Fatalf("Failed to open gzip body: %v", err)
+	}
+	body, _ := io.ReadAll(reader)
+	if string(body) != `{"status":"healthy"}` {
+		t.Errorf("Unexpected decompressed body: %s", body)
+	}
+}
+
+func TestMiddleware_CompressionPassesEventStreams(t *testing.T) {
+	handler := compressionMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+	}))
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Content-Encoding") != "" || rr.Body.String() != "data: first\n\n" || !rr.Flushed {
+		t.Errorf("Expected the event stream to be flushed uncompressed, got %q (%v)", rr.Body.String(), rr.Header())
+	}
+}
+
+func TestMiddleware_RateLimit(t *testing.T) {
+	limiter := newRateLimiter(1, 2)
+	now := time.Now()
+
+	if !limiter.allow("client", now) || !limiter.allow("client", now) {
+		t.Fatal("Expected burst requests to be allowed")
+	}
+	if limiter.allow("client", now) {
+		t.Error("Expected request beyond burst to be rejected")
+	}
+	if !limiter.allow("other", now) {
+		t.Error("Expected other clients to have their own bucket")
+	}
+	if !limiter.allow("client", now.Add(time.Second)) {
+		t.Error("Expected tokens to refill over time")
+	}
+
+	handler := limiter.middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:1234"
+	codes := []int{}
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		codes = append(codes, rr.Code)
+	}
+	if codes[2] != http.StatusTooManyRequests {
+		t.Errorf("Expected third request to be rate limited, got %v", codes)
+	}
+}
+
+func TestMiddleware_CORSAllowsConditionalAndTenantHeaders(t *testing.T) {
+	handler := corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	req := httptest.NewRequest("OPTIONS", "/api/v1/abac/policies/p1", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	allowed := rr.Header().Get("Access-Control-Allow-Headers")
+	for _, header := range []string{"If-Match", "If-None-Match", "X-API-Key", "X-Tenant", "Idempotency-Key"} {
+		if !strings.Contains(allowed, header) {
+			t.Errorf("Expected %s to be allowed, got %q", header, allowed)
+		}
+	}
+	if exposed := rr.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(exposed, "ETag") {
+		t.Errorf("Expected ETag to be exposed, got %q", exposed)
+	}
+}
@@ -0,0 +1,247 @@
+// Multi-Model Authorization Microservice - Model Capability Discovery
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// abacOperators lists the condition operators understood by PolicyEngine.evaluateOperator
+var abacOperators = []string{"eq", "ne", "gt", "gte", "lt", "lte", "before", "after", "in", "contains", "startswith", "endswith", "regex", "cidr"}
+
+// abacConditionTypes lists the attribute sources a condition can refer to
+var abacConditionTypes = []string{"user", "object", "environment", "request", "action"}
+
+// ModelStorage describes where a model keeps its data
+type ModelStorage struct {
+	Backend string   `json:"backend"` // Database dialect, e.g. "sqlite"
+	Tables  []string `json:"tables"`
+}
+
+// ModelCapability describes an authorization model as currently configured
+type ModelCapability struct {
+	Name        AccessControlModel  `json:"name"`
+	Description string              `json:"description"`
+	Usage       string              `json:"usage"`
+	Enabled     bool                `json:"enabled"`
+	Storage     ModelStorage        `json:"storage"`
+	Counts      map[string]int64    `json:"counts"`
+	Features    map[string]bool     `json:"features,omitempty"`
+	Effects     []string            `json:"effects,omitempty"`
+	Operators   []string            `json:"operators,omitempty"`
+	Conditions  []string            `json:"condition_types,omitempty"`
+	Relations   map[string][]string `json:"relations,omitempty"` // Relationship to the permissions it grants
+	Links       map[string]string   `json:"links"`
+}
+
+// tableName resolves the table gorm uses for model
+func tableName(db *gorm.DB, model interface{}) string {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return ""
+	}
+	return stmt.Schema.Table
+}
+
+// countRows counts the rows of a table, optionally narrowed by a condition
+func countRows(db *gorm.DB, model interface{}, query string, args ...interface{}) (int64, error) {
+	var count int64
+	tx := db.Model(model)
+	if query != "" {
+		tx = tx.Where(query, args...)
+	}
+	if err := tx.Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// countRuleEffects counts the allow and deny rules of an ACL or RBAC enforcer
+func countRuleEffects(rules [][]string) (allow, deny int64) {
+	for _, rule := range rules {
+		if ruleEffect(rule) == effectDeny {
+			deny++
+		} else {
+			allow++
+		}
+	}
+	return allow, deny
+}
+
+// describeModels reports each model's configuration and data from runtime state
+func (s *AuthService) describeModels() ([]ModelCapability, error) {
+	backend := s.db.Dialector.Name()
+	settings := s.currentSettings()
+
+	acl := ModelCapability{
+		Name:        ModelACL,
+		Description: "Access Control List - Direct user-resource mapping",
+		Usage:       "Small-scale systems, simple permission management",
+		Enabled:     s.aclEnforcer != nil && settings.modelEnabled(ModelACL),
+		Storage:     ModelStorage{Backend: backend, Tables: []string{"acl_rules"}},
+		Counts:      map[string]int64{},
+		Effects:     []string{effectAllow, effectDeny},
+		Links: map[string]string{
+			"policies":       "/api/v1/acl/policies",
+			"authorizations": "/api/v1/authorizations",
+		},
+	}
+	if acl.Enabled {
+		rules, err := s.aclEnforcer.GetPolicy()
+		if err != nil {
+			return nil, fmt.Errorf("failed to count ACL policies: %v", err)
+		}
+		allow, deny := countRuleEffects(rules)
+		acl.Counts["policies"] = allow + deny
+		acl.Counts["deny_policies"] = deny
+	}
+
+	rbac := ModelCapability{
+		Name:        ModelRBAC,
+		Description: "Role-Based Access Control - Role-based authorization",
+		Usage:       "Enterprise systems, organizational permission management",
+		Enabled:     s.rbacEnforcer != nil && settings.modelEnabled(ModelRBAC),
+		Storage:     ModelStorage{Backend: backend, Tables: []string{"rbac_rules"}},
+		Counts:      map[string]int64{},
+		Features: map[string]bool{
+			"role_hierarchy":      true,
+			"resource_roles":      true,
+			"rebac_group_binding": s.rbacGroupBindings,
+		},
+		Effects: []string{effectAllow, effectDeny},
+		Links: map[string]string{
+			"policies":        "/api/v1/rbac/policies",
+			"user_roles":      "/api/v1/users/{userId}/roles",
+			"role_parents":    "/api/v1/rbac/roles/{roleId}/parents",
+			"role_hierarchy":  "/api/v1/rbac/roles/{roleId}/hierarchy",
+			"resource_groups": "/api/v1/rbac/resources/{objectId}/groups",
+			"authorizations":  "/api/v1/authorizations",
+		},
+	}
+	if rbac.Enabled {
+		rules, err := s.rbacEnforcer.GetPolicy()
+		if err != nil {
+			return nil, fmt.Errorf("failed to count RBAC policies: %v", err)
+		}
+		assignments, err := s.rbacEnforcer.GetGroupingPolicy()
+		if err != nil {
+			return nil, fmt.Errorf("failed to count RBAC role assignments: %v", err)
+		}
+		roles, err := s.rbacEnforcer.GetAllRoles()
+		if err != nil {
+			return nil, fmt.Errorf("failed to count RBAC roles: %v", err)
+		}
+		resourceGroups, err := s.rbacEnforcer.GetNamedGroupingPolicy("g2")
+		if err != nil {
+			return nil, fmt.Errorf("failed to count RBAC resource groups: %v", err)
+		}
+		allow, deny := countRuleEffects(rules)
+		rbac.Counts["policies"] = allow + deny
+		rbac.Counts["deny_policies"] = deny
+		rbac.Counts["role_assignments"] = int64(len(assignments))
+		rbac.Counts["roles"] = int64(len(roles))
+		rbac.Counts["resource_group_memberships"] = int64(len(resourceGroups))
+	}
+
+	abac := ModelCapability{
+		Name:        ModelABAC,
+		Description: "Attribute-Based Access Control - Attribute-based authorization",
+		Usage:       "Advanced security, dynamic permission control",
+		Enabled:     s.policyEngine != nil && settings.modelEnabled(ModelABAC),
+		Storage: ModelStorage{Backend: backend, Tables: []string{
+			tableName(s.db, &ABACPolicy{}),
+			tableName(s.db, &PolicyCondition{}),
+			tableName(s.db, &UserAttribute{}),
+			tableName(s.db, &ObjectAttribute{}),
+			tableName(s.db, &AttributeDefinition{}),
+		}},
+		Counts:     map[string]int64{},
+		Operators:  abacOperators,
+		Conditions: abacConditionTypes,
+		Features: map[string]bool{
+			"attribute_cache":       s.userAttrs != nil,
+			"attribute_resolver":    s.attributeResolution != nil,
+			"environment_providers": len(s.environmentProviders) > 0,
+			"schema_enforced":       s.schemaEnforce,
+		},
+		Effects: []string{effectAllow, effectDeny},
+		Links: map[string]string{
+			"policies":          "/api/v1/abac/policies",
+			"schema":            "/api/v1/abac/schema",
+			"user_attributes":   "/api/v1/users/{userId}/attributes",
+			"object_attributes": "/api/v1/objects/{objectId}/attributes",
+			"authorizations":    "/api/v1/authorizations",
+		},
+	}
+	if abac.Enabled {
+		abac.Counts["policies"] = int64(len(s.policyEngine.policyList()))
+		for name, model := range map[string]interface{}{
+			"user_attributes":       &UserAttribute{},
+			"object_attributes":     &ObjectAttribute{},
+			"attribute_definitions": &AttributeDefinition{},
+		} {
+			count, err := countRows(s.db, model, "")
+			if err != nil {
+				return nil, fmt.Errorf("failed to count %s: %v", name, err)
+			}
+			abac.Counts[name] = count
+		}
+	}
+
+	rebac := ModelCapability{
+		Name:        ModelReBAC,
+		Description: "Relationship-Based Access Control - Graph-based authorization",
+		Usage:       "Social media, collaboration platforms, hierarchical organizations",
+		Enabled:     s.relationshipGraph != nil && settings.modelEnabled(ModelReBAC),
+		Storage:     ModelStorage{Backend: backend, Tables: []string{tableName(s.db, &RelationshipRecord{}), tableName(s.db, &NamespaceDefinition{}), tableName(s.db, &RelationshipSequence{})}},
+		Counts:      map[string]int64{},
+		Links: map[string]string{
+			"relationships":  "/api/v1/relationships",
+			"namespaces":     "/api/v1/relationships/namespaces",
+			"paths":          "/api/v1/relationships/paths",
+			"permissions":    "/api/v1/relationships/permissions",
+			"constraints":    "/api/v1/relationships/constraints",
+			"suggestions":    "/api/v1/relationships/suggestions",
+			"partitions":     "/api/v1/relationships/partitions",
+			"authorizations": "/api/v1/authorizations",
+		},
+	}
+	if rg := s.relationshipGraph; rg != nil {
+		unlock := rg.readLock()
+		namespaces := len(rg.namespaces)
+		rebac.Features = map[string]bool{
+			"partitioned":     rg.partitions != nil,
+			"check_cache":     rg.checkCache != nil,
+			"constraints":     len(rg.constraints) > 0,
+			"expiring_tuples": true,
+			"rewrite_rules":   namespaces > 0,
+			"types_enforced":  rg.enforceTypes && namespaces > 0,
+		}
+		unlock()
+		rebac.Relations = make(map[string][]string, len(rg.permissions))
+		for relation, permissions := range rg.permissions {
+			sorted := append([]string(nil), permissions...)
+			sort.Strings(sorted)
+			rebac.Relations[relation] = sorted
+		}
+
+		tuples, err := countRows(s.db, &RelationshipRecord{}, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to count relationships: %v", err)
+		}
+		expiring, err := countRows(s.db, &RelationshipRecord{}, "expires_at IS NOT NULL")
+		if err != nil {
+			return nil, fmt.Errorf("failed to count expiring relationships: %v", err)
+		}
+		rebac.Counts["tuples"] = tuples
+		rebac.Counts["expiring_tuples"] = expiring
+		rebac.Counts["namespaces"] = int64(namespaces)
+	}
+
+	return []ModelCapability{acl, rbac, abac, rebac}, nil
+}
@@ -0,0 +1,69 @@
+// Multi-Model Authorization Microservice - Model Capability Discovery Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestModels_ReportsRuntimeState(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+
+	service.aclEnforcer.AddPolicy("alice", "data1", "read", "allow")
+	service.aclEnforcer.AddPolicy("bob", "data1", "read", "deny")
+	service.rbacEnforcer.AddRoleForUser("alice", "admin")
+	service.rbacEnforcer.AddPolicy("admin", "data1", "write", "allow")
+	service.saveUserAttribute("alice", "department", "engineering")
+	expiresAt := time.Now().Add(time.Hour)
+	service.relationshipGraph.AddRelationship("alice", "owner", "doc1")
+	service.relationshipGraph.AddExpiringRelationship("bob", "viewer", "doc1", &expiresAt)
+
+	req, _ := http.NewRequest("GET", "/api/v1/models", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var response struct {
+		Models  []ModelCapability `json:"models"`
+		Default string            `json:"default"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if len(response.Models) != 4 || response.Default != "rbac" {
+		t.Fatalf("Expected four models with rbac as default, got %s", rr.Body.String())
+	}
+
+	models := map[AccessControlModel]ModelCapability{}
+	for _, model := range response.Models {
+		if !model.Enabled || model.Storage.Backend != "sqlite" || len(model.Links) == 0 {
+			t.Errorf("Expected %s to be enabled with storage and links: %+v", model.Name, model)
+		}
+		models[model.Name] = model
+	}
+
+	if acl := models[ModelACL]; acl.Counts["policies"] != 2 || acl.Counts["deny_policies"] != 1 {
+		t.Errorf("Unexpected ACL counts: %v", acl.Counts)
+	}
+	if rbac := models[ModelRBAC]; rbac.Counts["policies"] != 1 || rbac.Counts["role_assignments"] != 1 {
+		t.Errorf("Unexpected RBAC counts: %v", rbac.Counts)
+	}
+	abac := models[ModelABAC]
+	if abac.Counts["user_attributes"] != 1 || len(abac.Operators) != len(abacOperators) {
+		t.Errorf("Unexpected ABAC description: %+v", abac)
+	}
+	rebac := models[ModelReBAC]
+	if rebac.Counts["tuples"] != 2 || rebac.Counts["expiring_tuples"] != 1 {
+		t.Errorf("Unexpected ReBAC counts: %v", rebac.Counts)
+	}
+	if len(rebac.Relations["owner"]) == 0 || rebac.Links["relationships"] != "/api/v1/relationships" {
+		t.Errorf("Expected ReBAC relations and links: %+v", rebac)
+	}
+}
@@ -0,0 +1,444 @@
+// Multi-Model Authorization Microservice - ReBAC Namespace Definitions
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// changeKindNamespace is the change kind of namespace definitions in webhook events
+const changeKindNamespace = "rebac_namespace"
+
+// maxRewriteDepth bounds how deeply rewrite rules are followed in one check
+const maxRewriteDepth = 25
+
+// namespaceNamePattern restricts namespace and relation names
+var namespaceNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// errNamespaceNotFound is returned for operations on a namespace that is not defined
+var errNamespaceNotFound = errors.New("namespace not found")
+
+// NamespaceDefinition configures how the relations of an object namespace are computed,
+// following Zanzibar's userset rewrites. An object belongs to the namespace named before
+// the ":" of its name, e.g. "document" for "document:readme" or "acme/document:readme".
+// Objects of a defined namespace are decided by its rewrites instead of the built-in
+// group, parent and friend traversal.
+type NamespaceDefinition struct {
+	Name      string                     `json:"name" gorm:"primaryKey"`
+	Relations map[string]RelationRewrite `json:"relations" gorm:"serializer:json"`
+	CreatedAt time.Time                  `json:"created_at"`
+	UpdatedAt time.Time                  `json:"updated_at"`
+}
+
+// RelationRewrite defines a relation as the union of its own tuples, other relations of the
+// same object and relations of objects linked to it. Relations a namespace does not define
+// are granted by their own tuples only.
+type RelationRewrite struct {
+	This             bool             `json:"this"`                        // Tuples with this relation grant it
+	ComputedUsersets []string         `json:"computed_usersets,omitempty"` // Relations of the same object that imply it
+	TupleToUsersets  []TupleToUserset `json:"tuple_to_usersets,omitempty"` // Relations of linked objects that imply it
+	SubjectTypes     []string         `json:"subject_types,omitempty"`     // Types of subjects its tuples may have, e.g. "user" or "group#member" (any when empty)
+}
+
+// TupleToUserset grants a relation to whoever has computed_userset on the objects linked
+// through tupleset, e.g. the viewers of a document's parent folder
+type TupleToUserset struct {
+	Tupleset        string `json:"tupleset"`         // Relation whose subjects are the linked objects, e.g. "parent"
+	ComputedUserset string `json:"computed_userset"` // Relation checked on each linked object, e.g. "viewer"
+}
+
+// validate checks the names and references of a definition
+func (def *NamespaceDefinition) validate() error {
+	if !namespaceNamePattern.MatchString(def.Name) {
+		return fmt.Errorf("invalid namespace name %q: use lowercase letters, digits and underscores", def.Name)
+	}
+	if len(def.Relations) == 0 {
+		return fmt.Errorf("namespace %s defines no relations", def.Name)
+	}
+	for name, rewrite := range def.Relations {
+		if !namespaceNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid relation name %q", name)
+		}
+		if !rewrite.This && len(rewrite.ComputedUsersets) == 0 && len(rewrite.TupleToUsersets) == 0 {
+			return fmt.Errorf("relation %s grants nothing: set this, computed_usersets or tuple_to_usersets", name)
+		}
+		for _, computed := range rewrite.ComputedUsersets {
+			if _, defined := def.Relations[computed]; !defined {
+				return fmt.Errorf("relation %s refers to undefined relation %s", name, computed)
+			}
+		}
+		for _, ttu := range rewrite.TupleToUsersets {
+			if !namespaceNamePattern.MatchString(ttu.Tupleset) || !namespaceNamePattern.MatchString(ttu.ComputedUserset) {
+				return fmt.Errorf("relation %s: tuple_to_usersets need a tupleset and a computed_userset", name)
+			}
+		}
+		if len(rewrite.SubjectTypes) > 0 && !rewrite.This {
+			return fmt.Errorf("relation %s: subject_types need this, as only its tuples have subjects", name)
+		}
+		for _, subjectType := range rewrite.SubjectTypes {
+			if !subjectTypePattern.MatchString(subjectType) {
+				return fmt.Errorf("relation %s: invalid subject type %q, use a type or type#relation", name, subjectType)
+			}
+		}
+	}
+	return nil
+}
+
+// objectNamespace returns the namespace of an object, or "" when its name has none
+func objectNamespace(object string) string {
+	idx := strings.Index(object, ":")
+	if idx <= 0 {
+		return ""
+	}
+	prefix := object[:idx]
+	return prefix[strings.LastIndex(prefix, "/")+1:]
+}
+
+// splitUserset splits a userset subject such as "group:eng#member" into its object and
+// relation
+func splitUserset(subject string) (object, relation string, ok bool) {
+	idx := strings.LastIndex(subject, "#")
+	if idx <= 0 || idx == len(subject)-1 {
+		return "", "", false
+	}
+	return subject[:idx], subject[idx+1:], true
+}
+
+// loadNamespaces loads the namespace definitions from the database
+func (rg *RelationshipGraph) loadNamespaces() error {
+	var definitions []NamespaceDefinition
+	if err := rg.db.Find(&definitions).Error; err != nil {
+		return fmt.Errorf("failed to load namespace definitions: %v", err)
+	}
+
+	namespaces := make(map[string]*NamespaceDefinition, len(definitions))
+	for i := range definitions {
+		namespaces[definitions[i].Name] = &definitions[i]
+	}
+	rg.namespaces = namespaces
+	return nil
+}
+
+// reloadNamespaces replaces the namespace definitions with the stored ones after another
+// instance changed them
+func (rg *RelationshipGraph) reloadNamespaces() error {
+	unlock := rg.writeLock()
+	defer unlock()
+
+	if err := rg.loadNamespaces(); err != nil {
+		return err
+	}
+	rg.bumpRevision()
+	return nil
+}
+
+// definitionFor returns the definition of an object's namespace, or nil
+func (rg *RelationshipGraph) definitionFor(object string) *NamespaceDefinition {
+	if len(rg.namespaces) == 0 {
+		return nil
+	}
+	return rg.namespaces[objectNamespace(object)]
+}
+
+// Namespaces returns the namespace definitions sorted by name
+func (rg *RelationshipGraph) Namespaces() []NamespaceDefinition {
+	unlock := rg.readLock()
+	defer unlock()
+
+	definitions := make([]NamespaceDefinition, 0, len(rg.namespaces))
+	for _, def := range rg.namespaces {
+		definitions = append(definitions, *def)
+	}
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].Name < definitions[j].Name })
+	return definitions
+}
+
+// Namespace returns the definition of a namespace
+func (rg *RelationshipGraph) Namespace(name string) (*NamespaceDefinition, bool) {
+	unlock := rg.readLock()
+	defer unlock()
+
+	def, exists := rg.namespaces[name]
+	return def, exists
+}
+
+// SetNamespace creates or replaces a namespace definition
+func (rg *RelationshipGraph) SetNamespace(def *NamespaceDefinition) error {
+	if err := def.validate(); err != nil {
+		return err
+	}
+
+	unlock := rg.writeLock()
+	defer unlock()
+
+	def.UpdatedAt = time.Now()
+	if existing, exists := rg.namespaces[def.Name]; exists {
+		def.CreatedAt = existing.CreatedAt
+	} else {
+		def.CreatedAt = def.UpdatedAt
+	}
+	if err := rg.db.Save(def).Error; err != nil {
+		return fmt.Errorf("failed to save namespace definition: %v", err)
+	}
+
+	// Replace the map so traversals holding the previous one are unaffected
+	namespaces := make(map[string]*NamespaceDefinition, len(rg.namespaces)+1)
+	for name, existing := range rg.namespaces {
+		namespaces[name] = existing
+	}
+	namespaces[def.Name] = def
+	rg.namespaces = namespaces
+	rg.bumpRevision()
+	return rg.recordWrite()
+}
+
+// RemoveNamespace deletes a namespace definition, returning its objects to the built-in
+// traversal
+func (rg *RelationshipGraph) RemoveNamespace(name string) error {
+	unlock := rg.writeLock()
+	defer unlock()
+
+	if _, exists := rg.namespaces[name]; !exists {
+		return errNamespaceNotFound
+	}
+	if err := rg.db.Delete(&NamespaceDefinition{}, "name = ?", name).Error; err != nil {
+		return fmt.Errorf("failed to delete namespace definition: %v", err)
+	}
+
+	namespaces := make(map[string]*NamespaceDefinition, len(rg.namespaces))
+	for existing, def := range rg.namespaces {
+		if existing != name {
+			namespaces[existing] = def
+		}
+	}
+	rg.namespaces = namespaces
+	rg.bumpRevision()
+	return rg.recordWrite()
+}
+
+// checkNamespacedAccess decides a permission on an object of a defined namespace. A
+// permission the namespace defines as a relation is checked directly; otherwise it is
+// granted by any defined relation mapped to it, e.g. "read" by "viewer".
+func (rg *RelationshipGraph) checkNamespacedAccess(subject, object, permission string, def *NamespaceDefinition) (bool, []PathHop) {
+	if _, defined := def.Relations[permission]; defined {
+		return rg.checkRelation(subject, object, permission, 0, make(map[string]bool))
+	}
+
+	relations := make([]string, 0, len(def.Relations))
+	for relation := range def.Relations {
+		if rg.HasPermissionThroughRelationship(relation, permission) {
+			relations = append(relations, relation)
+		}
+	}
+	sort.Strings(relations)
+	for _, relation := range relations {
+		if allowed, path := rg.checkRelation(subject, object, relation, 0, make(map[string]bool)); allowed {
+			return true, path
+		}
+	}
+	return false, nil
+}
+
+// checkRelation decides whether subject has relation on object by applying the rewrite of
+// the object's namespace, returning the tuples that grant it. visiting holds the
+// object#relation pairs being evaluated so cyclic definitions terminate.
+func (rg *RelationshipGraph) checkRelation(subject, object, relation string, depth int, visiting map[string]bool) (bool, []PathHop) {
+	key := object + "#" + relation
+	if depth > maxRewriteDepth || visiting[key] {
+		return false, nil
+	}
+	visiting[key] = true
+	defer delete(visiting, key)
+
+	rewrite := RelationRewrite{This: true}
+	if def := rg.definitionFor(object); def != nil {
+		if defined, exists := def.Relations[relation]; exists {
+			rewrite = defined
+		}
+	}
+
+	rg.ensureObjectLoaded(object)
+	incoming := rg.reverse.incomingTo(object)
+
+	// Tuples with the relation, whose subject is either the subject or a userset containing it
+	if rewrite.This {
+		for _, rel := range incoming {
+			if rel.Relationship != relation || !rg.caveatHolds(rel) {
+				continue
+			}
+			if rel.Subject == subject {
+				return true, []PathHop{{Subject: subject, Relation: relation, Object: object}}
+			}
+			if setObject, setRelation, ok := splitUserset(rel.Subject); ok {
+				if allowed, path := rg.checkRelation(subject, setObject, setRelation, depth+1, visiting); allowed {
+					return true, appendHop(path, PathHop{Subject: rel.Subject, Relation: relation, Object: object})
+				}
+			}
+		}
+	}
+
+	// Other relations of the same object
+	for _, computed := range rewrite.ComputedUsersets {
+		if allowed, path := rg.checkRelation(subject, object, computed, depth+1, visiting); allowed {
+			return true, path
+		}
+	}
+
+	// Relations of the objects linked through the tupleset
+	for _, ttu := range rewrite.TupleToUsersets {
+		for _, rel := range incoming {
+			if rel.Relationship != ttu.Tupleset || !rg.caveatHolds(rel) {
+				continue
+			}
+			if allowed, path := rg.checkRelation(subject, rel.Subject, ttu.ComputedUserset, depth+1, visiting); allowed {
+				return true, appendHop(path, PathHop{Subject: rel.Subject, Relation: ttu.Tupleset, Object: object})
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// rewriteCandidates collects the namespaced objects reachable from subject through any
+// tuples, including tuples whose subject is a userset of a reached object, as candidates
+// for rewrite-based access
+func (rg *RelationshipGraph) rewriteCandidates(subject string) []string {
+	var candidates []string
+	reached := map[string]bool{subject: true}
+	frontier := []string{subject}
+	for depth := 0; depth < maxRewriteDepth && len(frontier) > 0; depth++ {
+		var next []string
+		visit := func(node string) bool {
+			if reached[node] {
+				return false
+			}
+			reached[node] = true
+			next = append(next, node)
+			return true
+		}
+		for _, node := range frontier {
+			for _, hop := range rg.outgoingHops(node) {
+				if visit(hop.Object) && rg.definitionFor(hop.Object) != nil {
+					candidates = append(candidates, hop.Object)
+				}
+				visit(hop.Object + "#" + hop.Relation)
+			}
+		}
+		frontier = next
+	}
+	return candidates
+}
+
+// putNamespaceHandler creates or replaces the definition of a namespace
+func (s *AuthService) putNamespaceHandler(w http.ResponseWriter, r *http.Request) {
+	var def NamespaceDefinition
+	if err := json.NewDecoder(r.Body).Decode(&def); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+	def.Name = mux.Vars(r)["name"]
+
+	_, existed := s.relationshipGraph.Namespace(def.Name)
+	if err := def.validate(); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.relationshipGraph.SetNamespace(&def); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.publishChange(changeKindNamespace, map[bool]string{true: changeUpdated, false: changeAdded}[existed], def.Name, &def, actorFromRequest(r))
+
+	response := map[string]interface{}{
+		"message":   "Namespace definition saved successfully",
+		"namespace": def,
+		"model":     "rebac",
+	}
+	s.relationshipGraph.addConsistencyToken(response)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getNamespacesHandler lists the namespace definitions
+func (s *AuthService) getNamespacesHandler(w http.ResponseWriter, r *http.Request) {
+	namespaces := s.relationshipGraph.Namespaces()
+
+	mode := "warn"
+	if s.relationshipGraph.enforceTypes {
+		mode = "enforce"
+	}
+
+	response := map[string]interface{}{
+		"namespaces": namespaces,
+		"count":      len(namespaces),
+		"mode":       mode,
+		"model":      "rebac",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getNamespaceHandler returns the definition of a namespace
+func (s *AuthService) getNamespaceHandler(w http.ResponseWriter, r *http.Request) {
+	def, exists := s.relationshipGraph.Namespace(mux.Vars(r)["name"])
+	if !exists {
+		writeJSONError(w, http.StatusNotFound, "Namespace not found")
+		return
+	}
+
+	response := map[string]interface{}{
+		"namespace": def,
+		"model":     "rebac",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// deleteNamespaceHandler removes the definition of a namespace
+func (s *AuthService) deleteNamespaceHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	err := s.relationshipGraph.RemoveNamespace(name)
+	if errors.Is(err, errNamespaceNotFound) {
+		writeJSONError(w, http.StatusNotFound, "Namespace not found")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.publishChange(changeKindNamespace, changeRemoved, name, nil, actorFromRequest(r))
+
+	response := map[string]interface{}{
+		"message": "Namespace definition deleted successfully",
+		"removed": true,
+		"model":   "rebac",
+	}
+	s.relationshipGraph.addConsistencyToken(response)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// migrateNamespaces creates the namespace definition table
+func migrateNamespaces(db *gorm.DB) error {
+	if err := db.AutoMigrate(&NamespaceDefinition{}); err != nil {
+		return fmt.Errorf("failed to migrate namespace table: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+// Multi-Model Authorization Microservice - ABAC Obligations and Advice
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+// Obligation is an instruction an ABAC policy attaches to its decisions for the calling
+// application to carry out, e.g. {"id": "mask_field", "attributes": {"field": "ssn"}} or
+// {"id": "require_mfa"}. The service does not interpret obligations.
+type Obligation struct {
+	ID         string            `json:"id"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// PolicyDecision is the outcome of an ABAC evaluation
+type PolicyDecision struct {
+	Allowed bool
+	Reason  string
+	Policy  *ABACPolicy // Policy that decided (nil when no policy matched)
+}
+
+// attachTo adds the obligations and advice of the deciding policy to a response
+func (decision *PolicyDecision) attachTo(response *EnforceResponse) {
+	if decision == nil || decision.Policy == nil {
+		return
+	}
+	response.Obligations = decision.Policy.Obligations
+	response.Advice = decision.Policy.Advice
+}
+
+// attachObligations adds the obligations and advice of a policy to a response. Cached
+// decisions only record the ID of the deciding policy, so they are looked up by it.
+func (pe *PolicyEngine) attachObligations(policyID string, response *EnforceResponse) {
+	if policy, exists := pe.getPolicy(policyID); exists {
+		response.Obligations = policy.Obligations
+		response.Advice = policy.Advice
+	}
+}
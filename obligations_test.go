@@ -0,0 +1,91 @@
+// Multi-Model Authorization Microservice - ABAC Obligation Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestObligations_ReturnedWithDecisions(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/abac/policies", service.addABACPolicyHandler).Methods("POST")
+	router.HandleFunc("/api/v1/authorizations", service.authorizationHandler).Methods("POST")
+	if err := service.enableDecisionCache(100, time.Minute); err != nil {
+		t.Fatalf("Failed to enable the decision cache: %v", err)
+	}
+
+	send := func(path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send("/api/v1/abac/policies", `{
+		"id": "hr-records", "name": "HR records", "effect": "allow", "priority": 10,
+		"conditions": [{"type": "user", "field": "department", "operator": "eq", "value": "hr"}],
+		"obligations": [{"id": "mask_field", "attributes": {"field": "ssn"}}],
+		"advice": [{"id": "log_elevated"}]
+	}`); rr.Code != http.StatusOK {
+		t.Fatalf("Failed to add the policy: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := send("/api/v1/abac/policies", `{
+		"id": "contractors", "name": "Contractors", "effect": "deny", "priority": 20,
+		"conditions": [{"type": "user", "field": "contractor", "operator": "eq", "value": "true"}],
+		"obligations": [{"id": "require_mfa"}]
+	}`); rr.Code != http.StatusOK {
+		t.Fatalf("Failed to add the policy: %d %s", rr.Code, rr.Body.String())
+	}
+	service.saveUserAttribute("alice", "department", "hr")
+	service.saveUserAttribute("bob", "department", "hr")
+	service.saveUserAttribute("bob", "contractor", "true")
+
+	check := func(subject string) (int, EnforceResponse) {
+		rr := send("/api/v1/authorizations", `{"model": "abac", "subject": "`+subject+`", "object": "record", "action": "read"}`)
+		var response EnforceResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr.Code, response
+	}
+
+	// Repeated checks return the obligations even though the decision could be cached
+	for i := 0; i < 2; i++ {
+		code, response := check("alice")
+		if code != http.StatusOK || len(response.Obligations) != 1 || response.Obligations[0].Attributes["field"] != "ssn" ||
+			len(response.Advice) != 1 || response.Advice[0].ID != "log_elevated" {
+			t.Fatalf("Expected the allow decision with its obligations, got %d %+v", code, response)
+		}
+	}
+
+	// Only the deciding policy contributes
+	code, response := check("bob")
+	if code != http.StatusForbidden || len(response.Obligations) != 1 || response.Obligations[0].ID != "require_mfa" || len(response.Advice) != 0 {
+		t.Errorf("Expected the deny decision with its obligations, got %d %+v", code, response)
+	}
+	code, response = check("carol")
+	if code != http.StatusForbidden || response.Obligations != nil {
+		t.Errorf("Expected the default deny without obligations, got %d %+v", code, response)
+	}
+
+	// Obligations are stored with the policy
+	if err := service.policyEngine.LoadPolicies(); err != nil {
+		t.Fatalf("Failed to reload policies: %v", err)
+	}
+	if policy := service.policyEngine.policies["hr-records"]; policy == nil || len(policy.Obligations) != 1 {
+		t.Errorf("Expected the obligations to survive a reload, got %+v", policy)
+	}
+
+	if rr := send("/api/v1/abac/policies", `{"id": "bad", "name": "Bad", "effect": "allow",
+		"conditions": [{"type": "user", "field": "department", "operator": "eq", "value": "hr"}],
+		"obligations": [{"attributes": {"field": "ssn"}}]}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an obligation without an ID, got %d", rr.Code)
+	}
+}
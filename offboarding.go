@@ -0,0 +1,234 @@
+// Multi-Model Authorization Microservice - User Offboarding
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// changeKindUser is the change kind of offboarded users in webhook events
+const changeKindUser = "user"
+
+// OffboardingReport lists everything removed when a user was offboarded
+type OffboardingReport struct {
+	User          string         `json:"user"`
+	Roles         []string       `json:"roles"`         // RBAC roles the user held
+	ACLPolicies   [][]string     `json:"acl_policies"`  // ACL rules of the user, as [subject, object, action, effect]
+	RBACPolicies  [][]string     `json:"rbac_policies"` // RBAC rules granted to the user directly
+	Attributes    []string       `json:"attributes"`    // Names of the user's ABAC attributes
+	Relationships []Relationship `json:"relationships"` // ReBAC tuples with the user as subject or object
+}
+
+// empty reports whether the user had nothing to remove
+func (r *OffboardingReport) empty() bool {
+	return len(r.Roles) == 0 && len(r.ACLPolicies) == 0 && len(r.RBACPolicies) == 0 &&
+		len(r.Attributes) == 0 && len(r.Relationships) == 0
+}
+
+// RemoveNode deletes every tuple with node as subject or object, along with whatever also
+// deletes, in one database transaction, and returns the removed tuples. When also fails
+// nothing is removed.
+func (rg *RelationshipGraph) RemoveNode(node string, also func(tx *gorm.DB) error) ([]Relationship, error) {
+	unlock := rg.writeLock()
+	defer unlock()
+
+	var removed []Relationship
+	err := rg.db.Transaction(func(tx *gorm.DB) error {
+		var records []RelationshipRecord
+		if err := tx.Where("subject = ? OR object = ?", node, node).Order("id").Find(&records).Error; err != nil {
+			return err
+		}
+		// Copies of a tuple, e.g. with different caveats, are removed together
+		seen := make(map[Relationship]bool)
+		for _, record := range records {
+			rel := Relationship{Subject: record.Subject, Relationship: record.Relationship, Object: record.Object}
+			if !seen[rel] {
+				seen[rel] = true
+				removed = append(removed, rel)
+			}
+		}
+		if err := tx.Where("subject = ? OR object = ?", node, node).Delete(&RelationshipRecord{}).Error; err != nil {
+			return err
+		}
+		return also(tx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove relationships of %s: %v", node, err)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	for _, rel := range removed {
+		rg.forget(rel.Subject, rel.Relationship, rel.Object)
+	}
+	return removed, rg.recordWrite()
+}
+
+// OffboardUser removes a user's roles, ACL rules, directly granted RBAC rules, attributes and
+// relationships in one database transaction, so either all or none of them are removed.
+// Each removal is published like a single change, followed by a "user.removed" event
+// carrying the report.
+func (s *AuthService) OffboardUser(user, actor string) (*OffboardingReport, error) {
+	report := &OffboardingReport{User: user}
+
+	roles, err := s.rbacEnforcer.GetFilteredGroupingPolicy(0, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read roles: %v", err)
+	}
+	if report.ACLPolicies, err = s.aclEnforcer.GetFilteredPolicy(0, user); err != nil {
+		return nil, fmt.Errorf("failed to read ACL rules: %v", err)
+	}
+	if report.RBACPolicies, err = s.rbacEnforcer.GetFilteredPolicy(0, user); err != nil {
+		return nil, fmt.Errorf("failed to read RBAC rules: %v", err)
+	}
+	for _, assignment := range roles {
+		report.Roles = append(report.Roles, assignment[1])
+	}
+
+	// Metadata kept alongside the rules goes with them
+	type ruleKind struct{ kind, key string }
+	var ruleKeys []ruleKind
+	for _, role := range report.Roles {
+		ruleKeys = append(ruleKeys, ruleKind{labelKindRole, labelKey(user, role)})
+	}
+	for _, rule := range report.ACLPolicies {
+		ruleKeys = append(ruleKeys, ruleKind{labelKindACL, ruleKey(rule)})
+	}
+	for _, rule := range report.RBACPolicies {
+		ruleKeys = append(ruleKeys, ruleKind{labelKindRBAC, ruleKey(rule)})
+	}
+
+	var attributes []UserAttribute
+	report.Relationships, err = s.relationshipGraph.RemoveNode(user, func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user).Order("attribute").Find(&attributes).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user).Delete(&UserAttribute{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("acl_rules").Where("ptype = ? AND v0 = ?", "p", user).Delete(&gormadapter.CasbinRule{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("rbac_rules").Where("ptype IN ? AND v0 = ?", []string{"p", "g"}, user).Delete(&gormadapter.CasbinRule{}).Error; err != nil {
+			return err
+		}
+		for _, rule := range ruleKeys {
+			if err := tx.Where("kind = ? AND resource_key = ?", rule.kind, rule.key).Delete(&ResourceLabel{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("kind = ? AND resource_key = ?", rule.kind, rule.key).Delete(&PolicyMetadata{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The rules are gone from the database; removing them through the enforcers updates
+	// their models and tells watchers, such as the decision cache and other instances
+	if len(report.ACLPolicies) > 0 {
+		if _, err := s.aclEnforcer.RemovePolicies(report.ACLPolicies); err != nil {
+			return nil, fmt.Errorf("failed to remove ACL rules: %v", err)
+		}
+	}
+	if len(report.RBACPolicies) > 0 {
+		if _, err := s.rbacEnforcer.RemovePolicies(report.RBACPolicies); err != nil {
+			return nil, fmt.Errorf("failed to remove RBAC rules: %v", err)
+		}
+	}
+	if len(roles) > 0 {
+		if _, err := s.rbacEnforcer.RemoveGroupingPolicies(roles); err != nil {
+			return nil, fmt.Errorf("failed to remove roles: %v", err)
+		}
+	}
+	if len(attributes) > 0 {
+		s.invalidateUserAttributes(user)
+	}
+
+	for _, role := range report.Roles {
+		s.publishChange(changeKindRBACRole, changeRemoved, labelKey(user, role), roleChange(user, role), actor)
+	}
+	for _, rule := range report.ACLPolicies {
+		s.publishChange(changeKindACL, changeRemoved, ruleKey(rule), ruleChange(rule[0], rule[1], rule[2], ""), actor)
+	}
+	for _, rule := range report.RBACPolicies {
+		s.publishChange(changeKindRBACPolicy, changeRemoved, ruleKey(rule), ruleChange(rule[0], rule[1], rule[2], ""), actor)
+	}
+	for _, attribute := range attributes {
+		report.Attributes = append(report.Attributes, attribute.Attribute)
+		s.publishChange(changeKindUserAttribute, changeRemoved, user+"."+attribute.Attribute, attributeChange("user", user, attribute.Attribute, ""), actor)
+	}
+	for _, rel := range report.Relationships {
+		key := labelKey(rel.Subject, rel.Relationship, rel.Object)
+		s.removeLabels(labelKindRelationship, key)
+		s.publishChange(changeKindRelationship, changeRemoved, key, relationshipChange(rel.Subject, rel.Relationship, rel.Object, nil), actor)
+	}
+	if !report.empty() {
+		s.publishChange(changeKindUser, changeRemoved, user, report, actor)
+	}
+	return report, nil
+}
+
+// offboardUserHandler removes everything granting a user access
+func (s *AuthService) offboardUserHandler(w http.ResponseWriter, r *http.Request) {
+	userId := mux.Vars(r)["userId"]
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	report, err := s.OffboardUser(scope.qualify(userId), actorFromRequest(r))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to offboard user: %v", err))
+		return
+	}
+
+	if report.empty() {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    errorCode(http.StatusNotFound),
+			"removed": false,
+			"message": "User has no roles, policies, attributes or relationships",
+			"user":    userId,
+		})
+		return
+	}
+
+	// Report tenant-local names; the report itself was published with qualified names
+	local := &OffboardingReport{
+		User:         userId,
+		ACLPolicies:  scope.localRules(report.ACLPolicies, 2),
+		RBACPolicies: scope.localRules(report.RBACPolicies, 2),
+		Attributes:   report.Attributes,
+	}
+	for _, role := range report.Roles {
+		local.Roles = append(local.Roles, scope.local(role))
+	}
+	for _, rel := range report.Relationships {
+		local.Relationships = append(local.Relationships, scope.localRelationship(rel))
+	}
+
+	response := map[string]interface{}{
+		"removed": true,
+		"message": "User offboarded successfully",
+		"report":  local,
+	}
+	s.relationshipGraph.addConsistencyToken(response)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,109 @@
+// Multi-Model Authorization Microservice - User Offboarding Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOffboarding_RemovesAllAccess(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/users/{userId}", service.offboardUserHandler).Methods("DELETE")
+
+	for _, user := range []string{"alice", "bob"} {
+		service.aclEnforcer.AddPolicy(user, "report", "read", "allow")
+		service.rbacEnforcer.AddPolicy(user, "payroll", "write", "allow")
+		service.rbacEnforcer.AddRoleForUser(user, "editor")
+		service.saveUserAttribute(user, "department", "finance")
+		service.relationshipGraph.AddRelationship(user, "member", "engineering")
+	}
+	service.rbacEnforcer.AddPolicy("editor", "docs", "write", "allow")
+	service.relationshipGraph.AddRelationship("carol", "manager", "alice")
+	service.setLabels(labelKindACL, "alice:report:read", []string{"finance"})
+
+	req, _ := http.NewRequest("DELETE", "/api/v1/users/alice", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+	var response struct {
+		Report OffboardingReport `json:"report"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	report := response.Report
+	if len(report.Roles) != 1 || len(report.ACLPolicies) != 1 || len(report.RBACPolicies) != 1 || len(report.Attributes) != 1 || len(report.Relationships) != 2 {
+		t.Errorf("Unexpected report: %s", rr.Body.String())
+	}
+
+	// Both the enforcers and the database lose alice's access, and bob keeps his
+	for _, enforcer := range []func() error{service.aclEnforcer.LoadPolicy, service.rbacEnforcer.LoadPolicy, nil} {
+		for user, expected := range map[string]bool{"alice": false, "bob": true} {
+			if allowed, _ := service.Enforce(ModelACL, user, "report", "read", nil); allowed != expected {
+				t.Errorf("Expected ACL access of %s to be %v", user, expected)
+			}
+			if allowed, _ := service.Enforce(ModelRBAC, user, "docs", "write", nil); allowed != expected {
+				t.Errorf("Expected role access of %s to be %v", user, expected)
+			}
+			if allowed, _ := service.Enforce(ModelRBAC, user, "payroll", "write", nil); allowed != expected {
+				t.Errorf("Expected direct RBAC access of %s to be %v", user, expected)
+			}
+			if related := service.relationshipGraph.HasDirectRelationship(user, "member", "engineering"); related != expected {
+				t.Errorf("Expected the membership of %s to be %v", user, expected)
+			}
+		}
+		if enforcer != nil {
+			if err := enforcer(); err != nil {
+				t.Fatalf("Failed to reload policies: %v", err)
+			}
+		}
+	}
+	if attributes, _ := service.getUserAttributes("alice"); len(attributes) != 0 {
+		t.Errorf("Expected alice's attributes to be removed, got %v", attributes)
+	}
+	if service.relationshipGraph.HasDirectRelationship("carol", "manager", "alice") {
+		t.Error("Expected relationships on alice to be removed")
+	}
+	var labels int64
+	service.db.Model(&ResourceLabel{}).Where("resource_key = ?", "alice:report:read").Count(&labels)
+	if labels != 0 {
+		t.Errorf("Expected the rule's labels to be removed, got %d", labels)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 once nothing is left, got %d", rr.Code)
+	}
+}
+
+func TestOffboarding_AllOrNothing(t *testing.T) {
+	service := setupTestService(t)
+	service.aclEnforcer.AddPolicy("alice", "report", "read", "allow")
+	service.rbacEnforcer.AddRoleForUser("alice", "editor")
+	service.relationshipGraph.AddRelationship("alice", "member", "engineering")
+
+	// A failing step rolls back the others
+	service.db.Migrator().DropTable(&UserAttribute{})
+	if _, err := service.OffboardUser("alice", "hr"); err == nil {
+		t.Fatal("Expected offboarding to fail")
+	}
+
+	if !service.relationshipGraph.HasDirectRelationship("alice", "member", "engineering") {
+		t.Error("Expected the relationship to be kept")
+	}
+	service.aclEnforcer.LoadPolicy()
+	service.rbacEnforcer.LoadPolicy()
+	if allowed, _ := service.Enforce(ModelACL, "alice", "report", "read", nil); !allowed {
+		t.Error("Expected the ACL rule to be kept")
+	}
+	if roles, _ := service.rbacEnforcer.GetRolesForUser("alice"); len(roles) != 1 {
+		t.Errorf("Expected the role to be kept, got %v", roles)
+	}
+}
@@ -0,0 +1,588 @@
+// Multi-Model Authorization Microservice - OpenAPI Specification
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// serviceVersion is reported by the health endpoint and the OpenAPI document
+const serviceVersion = "2.0.0"
+
+// apiOperation documents an endpoint. Request and response bodies are described by Go
+// values: structs are reflected through their JSON tags, and maps with interface values
+// describe response envelopes by example, so the document follows the types handlers use.
+type apiOperation struct {
+	summary  string
+	id       string      // Operation ID when the handler name can't be used
+	query    []apiParam  // Query parameters
+	request  interface{} // Request body, nil when the endpoint takes none
+	response interface{} // Response body, nil for a plain JSON object
+	status   int         // Success status (200 when zero)
+	also     []int       // Further statuses returning the response body
+}
+
+// apiParam is a documented query parameter
+type apiParam struct {
+	name        string
+	description string
+}
+
+// Response bodies shared by several endpoints
+var (
+	ruleAddedResponse = map[string]interface{}{
+		"added": true, "message": "", "policy": map[string]string{}, "labels": []string{}, "model": "",
+	}
+	ruleListResponse = map[string]interface{}{
+		"policies": [][]string{}, "metadata": map[string]*PolicyMetadata{}, "count": 0, "model": "",
+		"total": 0, "offset": 0, "limit": 0, "next_offset": 0,
+	}
+	removedResponse = map[string]interface{}{"removed": true, "message": "", "model": ""}
+	labelParam      = apiParam{"label", "Only return entries carrying this label"}
+	asOfParam       = apiParam{"as_of", "RFC 3339 time to list the entries as they were then"}
+
+	ruleListParams = pageParams(labelParam, apiParam{"subject", "Only rules of this subject"}, apiParam{"object", "Only rules on this object"},
+		apiParam{"action", "Only rules for this action"}, apiParam{"effect", "Only allow or deny rules"}, asOfParam)
+
+	historyResponse  = map[string]interface{}{"policy": "", "revisions": []PolicyRevision{}, "count": 0, "model": ""}
+	restoredResponse = map[string]interface{}{"message": "", "restored_version": 0, "policy": map[string]string{}, "model": ""}
+
+	bulkAddedResponse   = map[string]interface{}{"results": []BulkPolicyResult{}, "created": 0, "exists": 0, "failed": 0, "model": ""}
+	bulkRemovedResponse = map[string]interface{}{"results": []BulkPolicyResult{}, "removed": 0, "not_found": 0, "failed": 0, "model": ""}
+
+	attributeImportParams   = []apiParam{{"format", "\"csv\" or \"ndjson\"; CSV when the content type is text/csv, else NDJSON"}}
+	attributeImportResponse = map[string]interface{}{"imported": 0, "failed": 0, "errors": []AttributeImportError{}, "errors_truncated": true, "warnings": []string{}, "model": ""}
+)
+
+// apiOperations documents every route, keyed by method and path below /api/v1
+var apiOperations = map[string]apiOperation{
+	"GET /health":       {summary: "Health check", response: map[string]interface{}{"status": "", "service": "", "supported_models": []string{}, "default_model": "", "database": "", "version": "", "rebac_features": []string{}}},
+	"GET /health/ready": {summary: "Readiness check; 503 while the database or policies are unavailable", response: map[string]interface{}{"status": "", "components": []ComponentHealth{}}, also: []int{http.StatusServiceUnavailable}},
+	"GET /models":       {summary: "List the supported access control models", response: map[string]interface{}{"models": []ModelCapability{}, "default": ""}},
+	"GET /openapi.json": {summary: "This OpenAPI document", response: map[string]interface{}{}},
+	"POST /authorizations": {
+		summary:  "Check whether a subject may perform an action on an object; denials return 403",
+		id:       "authorize",
+		query:    []apiParam{{"explain", "\"true\" explains the decision"}},
+		request:  EnforceRequest{},
+		response: EnforceResponse{},
+		also:     []int{http.StatusForbidden},
+	},
+	"POST /authorizations/token": {
+		summary:  "Check access for the subject of an end-user JWT, with its claims as attributes and roles; denials return 403",
+		request:  TokenAuthorizationRequest{},
+		response: TokenAuthorizationResponse{},
+		also:     []int{http.StatusForbidden},
+	},
+	"POST /kubernetes/subjectaccessreviews": {
+		summary:  "Kubernetes authorization webhook: decide a SubjectAccessReview",
+		id:       "reviewSubjectAccess",
+		request:  SubjectAccessReview{},
+		response: SubjectAccessReview{},
+	},
+
+	"GET /metrics": {summary: "Service counters and cache statistics", response: map[string]interface{}{"counters": map[string]int64{}, "attribute_caches": map[string]AttributeCacheStats{}, "decision_cache": DecisionCacheStats{}}},
+	"GET /slo":     {summary: "Enforce latency SLO status", response: map[string]interface{}{"slos": []SLOStatus{}}},
+
+	"GET /labels": {summary: "Count labeled entries per label and kind", response: map[string]interface{}{"labels": map[string]map[string]int{}, "count": 0}},
+	"GET /export": {
+		summary:  "Export policies, roles, attributes and relationships (text/csv for format=csv)",
+		query:    []apiParam{labelParam, {"format", "\"csv\" exports Casbin CSV"}, {"model", "acl or rbac, for CSV exports"}},
+		response: PolicyExport{},
+	},
+	"POST /import": {
+		summary:  "Import an export bundle, or Casbin CSV with format=csv",
+		query:    []apiParam{{"mode", "merge (default) or replace"}, {"format", "\"csv\" imports Casbin CSV"}, {"model", "acl or rbac, for CSV imports"}},
+		request:  PolicyExport{},
+		response: map[string]interface{}{"message": "", "mode": "", "imported": map[string]int{}, "removed": map[string]int{}, "warnings": []string{}},
+	},
+	"POST /export/diff": {
+		summary:  "Compare two exports, or an export against the live state",
+		query:    []apiParam{{"format", "\"text\" returns a plain-text diff"}},
+		request:  ExportDiffRequest{},
+		response: map[string]interface{}{"against": "", "added": []PolicyChange{}, "removed": []PolicyChange{}, "modified": []PolicyChange{}, "summary": map[string]int{}, "text": ""},
+	},
+	"POST /transactions": {
+		summary:  "Apply up to 1000 role, relationship and attribute mutations in one transaction, all or nothing",
+		request:  TransactionRequest{},
+		response: map[string]interface{}{"committed": true, "results": []TransactionResult{}, "applied": 0, "unchanged": 0, "warnings": []string{}, "consistency_token": ""},
+	},
+
+	"GET /scim/v2/ServiceProviderConfig": {summary: "Describe the supported SCIM 2.0 features"},
+	"POST /scim/v2/Users":                {summary: "Provision a SCIM user", request: SCIMUserResource{}, response: SCIMUserResource{}, status: http.StatusCreated},
+	"GET /scim/v2/Users": {
+		summary:  "List provisioned SCIM users",
+		query:    []apiParam{{"filter", "<attribute> eq \"<value>\" on id, userName, externalId or displayName"}, {"startIndex", "1-based index of the first user"}, {"count", "Maximum number of users (at most 1000)"}},
+		response: SCIMListResponse{Resources: []SCIMUserResource{}},
+	},
+	"GET /scim/v2/Users/{id}":    {summary: "Get a provisioned SCIM user", response: SCIMUserResource{}},
+	"PUT /scim/v2/Users/{id}":    {summary: "Replace a provisioned SCIM user", request: SCIMUserResource{}, response: SCIMUserResource{}},
+	"PATCH /scim/v2/Users/{id}":  {summary: "Change a provisioned SCIM user, e.g. deactivate it", request: SCIMPatchRequest{}, response: SCIMUserResource{}},
+	"DELETE /scim/v2/Users/{id}": {summary: "Deprovision a SCIM user and offboard it", status: http.StatusNoContent},
+	"POST /scim/v2/Groups":       {summary: "Provision a SCIM group, mapping its members to an RBAC role and ReBAC member tuples", request: SCIMGroupResource{}, response: SCIMGroupResource{}, status: http.StatusCreated},
+	"GET /scim/v2/Groups": {
+		summary:  "List provisioned SCIM groups",
+		query:    []apiParam{{"filter", "<attribute> eq \"<value>\" on id, displayName or externalId"}, {"startIndex", "1-based index of the first group"}, {"count", "Maximum number of groups (at most 1000)"}},
+		response: SCIMListResponse{Resources: []SCIMGroupResource{}},
+	},
+	"GET /scim/v2/Groups/{id}":    {summary: "Get a provisioned SCIM group", response: SCIMGroupResource{}},
+	"PUT /scim/v2/Groups/{id}":    {summary: "Replace a provisioned SCIM group and its members", request: SCIMGroupResource{}, response: SCIMGroupResource{}},
+	"PATCH /scim/v2/Groups/{id}":  {summary: "Change a provisioned SCIM group, e.g. add or remove members", request: SCIMPatchRequest{}, response: SCIMGroupResource{}},
+	"DELETE /scim/v2/Groups/{id}": {summary: "Deprovision a SCIM group, removing its members' roles and tuples", status: http.StatusNoContent},
+
+	"GET /ldap/sync": {summary: "LDAP group sync status", response: map[string]interface{}{"enabled": true, "directory": "", "interval": "", "dry_run": false, "tuples": false, "managed_assignments": 0, "last_run": &LDAPSyncRun{}}},
+	"POST /ldap/sync/run": {
+		summary:  "Sync LDAP groups into role assignments now",
+		query:    []apiParam{{"dry_run", "true reports the changes without applying them (default from LDAP_SYNC_DRY_RUN)"}},
+		response: LDAPSyncRun{},
+	},
+
+	"POST /webhooks":        {summary: "Register a change webhook", request: Webhook{}, response: map[string]interface{}{"message": "", "webhook": Webhook{}}, status: http.StatusCreated},
+	"GET /webhooks":         {summary: "List webhooks", response: map[string]interface{}{"webhooks": []Webhook{}, "count": 0}},
+	"DELETE /webhooks/{id}": {summary: "Unregister a webhook", response: map[string]interface{}{"message": "", "id": ""}},
+
+	"GET /changes":               {summary: "List change requests, newest first", query: []apiParam{{"status", "pending, applied, failed or rejected"}}, response: map[string]interface{}{"changes": []ChangeRequest{}, "count": 0}},
+	"GET /changes/{id}":          {summary: "Get a change request", response: ChangeRequest{}},
+	"POST /changes/{id}/approve": {summary: "Approve and apply a pending change (the requester cannot approve their own change)", response: ChangeRequest{}},
+	"POST /changes/{id}/reject":  {summary: "Reject a pending change", request: RejectChangeRequest{}, response: ChangeRequest{}},
+
+	"GET /audit/decisions": {
+		summary:  "List audited decisions, newest first",
+		query:    []apiParam{{"subject", "Only decisions about this subject"}, {"model", "Only decisions of this model"}, {"limit", "Maximum number of decisions (default 100)"}},
+		response: map[string]interface{}{"decisions": []DecisionRecord{}, "count": 0},
+	},
+	"POST /audit/decisions/replay": {summary: "Replay audited decisions against the current policies", request: ReplayRequest{}, response: ReplayReport{}},
+	"GET /audit/retention":         {summary: "Decision retention status", response: map[string]interface{}{"enabled": true, "decisions": 0, "oldest_decision": time.Time{}, "hot_period": "", "interval": "", "archive": "", "cutoff": time.Time{}, "expired_pending": 0, "last_run": &RetentionRun{}}},
+	"POST /audit/retention/run":    {summary: "Archive and delete expired decisions now", response: RetentionRun{}},
+	"GET /audit/recommendations": {
+		summary:  "Suggest policy changes from audited decisions",
+		query:    []apiParam{{"since", "RFC 3339 start of the window (default 30 days ago)"}, {"until", "RFC 3339 end of the window (default now)"}, {"min_denies", "Denials needed before a grant is suggested"}},
+		response: RecommendationReport{},
+	},
+
+	"POST /policies/test": {summary: "Run authorization assertions against the current configuration", request: PolicyTestRequest{}, response: PolicyTestReport{}},
+
+	"POST /traces":        {summary: "Start tracing decisions about a subject and/or object", request: TraceRequest{}, response: TraceSession{}, status: http.StatusCreated},
+	"GET /traces":         {summary: "List active trace sessions", response: map[string]interface{}{"sessions": []TraceSession{}, "count": 0}},
+	"GET /traces/{id}":    {summary: "Get a trace session with its recent traces", response: TraceSession{}},
+	"DELETE /traces/{id}": {summary: "Stop a trace session", response: map[string]interface{}{"removed": true, "message": ""}},
+
+	"GET /demo/scenarios":         {summary: "List demo scenarios, or their results with run=true", query: []apiParam{{"run", "\"true\" runs every scenario"}}, response: map[string]interface{}{"scenarios": []DemoScenario{}, "label": "", "count": 0, "passed": 0}},
+	"POST /demo/scenarios/{name}": {summary: "Run a demo scenario", response: DemoScenarioResult{}},
+
+	"POST /tenants":           {summary: "Create a tenant from a template", request: CreateTenantRequest{}, response: map[string]interface{}{"message": "", "tenant": Tenant{}, "applied": TenantTemplate{}}, status: http.StatusCreated},
+	"GET /tenants":            {summary: "List tenants", response: map[string]interface{}{"tenants": []Tenant{}, "count": 0}},
+	"POST /tenants/templates": {summary: "Register or replace a tenant template", request: TenantTemplate{}, response: map[string]interface{}{"message": "", "template": TenantTemplate{}}},
+	"GET /tenants/templates":  {summary: "List tenant templates", response: map[string]interface{}{"templates": []TenantTemplate{}, "count": 0}},
+	"GET /tenants/{name}":     {summary: "Get a tenant", response: Tenant{}},
+
+	"PUT /tenants/{name}/quota":    {summary: "Give a tenant its own quota, replacing the defaults", request: TenantQuota{}, response: map[string]interface{}{"message": "", "tenant": "", "quota": TenantQuota{}}},
+	"DELETE /tenants/{name}/quota": {summary: "Return a tenant to the default quota", response: map[string]interface{}{"message": "", "tenant": "", "quota": TenantQuota{}}},
+	"GET /tenants/{name}/usage":    {summary: "Get what a tenant stores against its quota", response: map[string]interface{}{"tenant": "", "usage": TenantUsage{}, "quota": TenantQuota{}, "custom": false, "soft_limit": 0}},
+
+	"POST /acl/policies":        {summary: "Add an ACL rule", request: PolicyRequest{}, response: ruleAddedResponse, status: http.StatusCreated, also: []int{http.StatusConflict}},
+	"GET /acl/policies":         {summary: "List ACL rules as [subject, object, action, effect]", query: ruleListParams, response: ruleListResponse},
+	"DELETE /acl/policies/{id}": {summary: "Remove an ACL rule by subject:object:action", response: removedResponse},
+	"POST /acl/policies/bulk":   {summary: "Add up to 1000 ACL rules at once", request: BulkPolicyRequest{}, response: bulkAddedResponse},
+	"DELETE /acl/policies/bulk": {summary: "Remove up to 1000 ACL rules at once", request: BulkPolicyRequest{}, response: bulkRemovedResponse},
+	"DELETE /acl/policies": {
+		summary:  "Remove an ACL rule whose object contains ':' or '/'",
+		query:    []apiParam{{"subject", "Subject of the rule"}, {"object", "Object or object pattern of the rule"}, {"action", "Action or action pattern of the rule"}},
+		response: removedResponse,
+	},
+
+	"GET /acl/policies/{id}/history":  {summary: "List the revisions of an ACL rule, newest first", response: historyResponse},
+	"POST /acl/policies/{id}/restore": {summary: "Restore a revision of an ACL rule (the previous one by default)", request: RestoreRequest{}, response: restoredResponse},
+
+	"POST /rbac/policies":        {summary: "Add an RBAC rule, optionally matching only under an ABAC condition", request: PolicyRequest{}, response: ruleAddedResponse, status: http.StatusCreated, also: []int{http.StatusConflict}},
+	"GET /rbac/policies":         {summary: "List RBAC rules as [role, object, action, effect]", query: ruleListParams, response: ruleListResponse},
+	"POST /rbac/policies/bulk":   {summary: "Add up to 1000 RBAC rules at once", request: BulkPolicyRequest{}, response: bulkAddedResponse},
+	"DELETE /rbac/policies/bulk": {summary: "Remove up to 1000 RBAC rules at once", request: BulkPolicyRequest{}, response: bulkRemovedResponse},
+	"DELETE /rbac/policies/{id}": {summary: "Remove an RBAC rule by role:object:action", response: removedResponse},
+
+	"GET /rbac/policies/{id}/history":  {summary: "List the revisions of an RBAC rule, newest first", response: historyResponse},
+	"POST /rbac/policies/{id}/restore": {summary: "Restore a revision of an RBAC rule (the previous one by default)", request: RestoreRequest{}, response: restoredResponse},
+
+	"POST /rbac/roles/{roleId}/parents":                  {summary: "Make a role inherit from a parent role", request: RoleParentRequest{}, response: map[string]interface{}{"added": true, "message": "", "role": "", "parent": "", "labels": []string{}, "model": ""}, status: http.StatusCreated, also: []int{http.StatusConflict}},
+	"DELETE /rbac/roles/{roleId}/parents/{parentId}":     {summary: "Remove a parent role", response: map[string]interface{}{"removed": true, "message": "", "role": "", "parent": "", "model": ""}},
+	"GET /rbac/roles/{roleId}/hierarchy":                 {summary: "Show a role's parents, ancestors and members", response: map[string]interface{}{"role": "", "parents": []string{}, "ancestors": []RoleAncestor{}, "members": []string{}, "model": ""}},
+	"POST /rbac/resources/{objectId}/groups":             {summary: "Put an object in a resource group", request: ResourceGroupRequest{}, response: map[string]interface{}{"added": true, "message": "", "object": "", "group": "", "labels": []string{}, "model": ""}, status: http.StatusCreated, also: []int{http.StatusConflict}},
+	"DELETE /rbac/resources/{objectId}/groups/{groupId}": {summary: "Remove an object from a resource group", response: map[string]interface{}{"removed": true, "message": "", "object": "", "group": "", "model": ""}},
+	"GET /rbac/resources/{objectId}/groups":              {summary: "Show an object's resource groups, ancestors and members", response: map[string]interface{}{"object": "", "groups": []string{}, "ancestors": []ResourceGroupAncestor{}, "members": []string{}, "model": ""}},
+
+	"DELETE /users/{userId}":                {summary: "Offboard a user, removing their roles, ACL and RBAC rules, attributes and relationships in one transaction", response: map[string]interface{}{"removed": true, "message": "", "report": OffboardingReport{}, "consistency_token": ""}},
+	"POST /users/{userId}/roles":            {summary: "Assign a role to a user", request: UserRoleRequest{}, response: map[string]interface{}{"added": true, "message": "", "user": "", "role": "", "labels": []string{}, "model": ""}, status: http.StatusCreated, also: []int{http.StatusConflict}},
+	"GET /users/{userId}/roles":             {summary: "List a user's roles", response: map[string]interface{}{"user": "", "roles": []string{}, "metadata": map[string]*PolicyMetadata{}, "count": 0, "model": ""}},
+	"DELETE /users/{userId}/roles/{roleId}": {summary: "Remove a role from a user", response: map[string]interface{}{"removed": true, "message": "", "user": "", "role": "", "model": ""}},
+	"GET /users/{userId}/groups":            {summary: "List a user's direct and transitive groups", response: map[string]interface{}{"user": "", "groups": []string{}, "all_groups": []string{}, "model": ""}},
+	"GET /users/{userId}/permissions":       {summary: "List a user's effective RBAC permissions, including inherited and group-bound roles", query: []apiParam{{"prefix", "Only permissions on objects with this prefix"}}, response: map[string]interface{}{"user": "", "prefix": "", "permissions": []UserPermission{}, "count": 0, "model": ""}},
+	"GET /subjects/{subject}/objects":       {summary: "List objects a subject can access through relationships", query: []apiParam{{"permission", "Only objects granting this permission"}}, response: map[string]interface{}{"subject": "", "permission": "", "objects": []AccessibleObject{}, "count": 0, "model": ""}},
+
+	"PUT /users/{userId}/attributes":          {summary: "Set user attributes", request: UserAttributesRequest{}, response: map[string]interface{}{"message": "", "user": "", "attributes": map[string]string{}, "types": map[string]string{}, "count": 0, "warnings": []string{}, "model": ""}},
+	"GET /users/{userId}/attributes":          {summary: "Get user attributes", response: map[string]interface{}{"user": "", "attributes": map[string]string{}, "types": map[string]string{}, "count": 0, "model": ""}},
+	"DELETE /users/{userId}/attributes/{key}": {summary: "Delete a user attribute", response: map[string]interface{}{"removed": true, "message": "", "user": "", "key": "", "model": ""}},
+	"POST /users/attributes/import": {
+		summary:  "Import user attribute rows from NDJSON, or CSV with an id,attribute,value[,type] header",
+		query:    attributeImportParams,
+		request:  AttributeImportRow{},
+		response: attributeImportResponse,
+	},
+
+	"PUT /objects/{objectId}/attributes":          {summary: "Set object attributes", request: ObjectAttributesRequest{}, response: map[string]interface{}{"message": "", "object": "", "attributes": map[string]string{}, "types": map[string]string{}, "warnings": []string{}, "model": ""}},
+	"GET /objects/{objectId}/attributes":          {summary: "Get object attributes", response: map[string]interface{}{"object": "", "attributes": map[string]string{}, "types": map[string]string{}, "count": 0, "model": ""}},
+	"GET /objects/{objectId}/attributes/visible":  {summary: "Get the object attributes a subject may read", query: []apiParam{{"subject", "Subject reading the attributes"}, {"model", "Model deciding attribute reads"}}, response: map[string]interface{}{"object": "", "subject": "", "attributes": map[string]string{}, "redacted": []string{}, "count": 0, "enforcement_model": "", "model": ""}},
+	"GET /objects/{objectId}/subjects":            {summary: "List subjects with access to an object", query: []apiParam{{"permission", "Action or permission to check"}, {"model", "Only grants through this model"}}, response: map[string]interface{}{"object": "", "permission": "", "models": []AccessControlModel{}, "subjects": []SubjectGrant{}, "count": 0}},
+	"DELETE /objects/{objectId}/attributes/{key}": {summary: "Delete an object attribute", response: map[string]interface{}{"removed": true, "message": "", "object": "", "key": "", "model": ""}},
+	"POST /objects/attributes/import": {
+		summary:  "Import object attribute rows from NDJSON, or CSV with an id,attribute,value[,type] header",
+		query:    attributeImportParams,
+		request:  AttributeImportRow{},
+		response: attributeImportResponse,
+	},
+	"GET /objects/{objectId}/relations/{relation}/expand": {
+		summary:  "Expand the tree of subjects holding a relation on an object",
+		query:    []apiParam{{"max_depth", "Maximum nesting of groups, usersets and parents"}},
+		response: map[string]interface{}{"object": "", "relation": "", "tree": UsersetTree{}, "subjects": []string{}, "count": 0, "max_depth": 0, "model": "", "consistency_token": ""},
+	},
+
+	"POST /abac/schema":                  {summary: "Define an attribute in the ABAC schema", request: AttributeDefinition{}, response: map[string]interface{}{"message": "", "definition": AttributeDefinition{}, "model": ""}},
+	"GET /abac/schema":                   {summary: "List attribute definitions", query: []apiParam{{"scope", "user or object"}}, response: map[string]interface{}{"definitions": []AttributeDefinition{}, "count": 0, "mode": "", "model": ""}},
+	"DELETE /abac/schema/{scope}/{name}": {summary: "Delete an attribute definition", response: map[string]interface{}{"message": "", "model": ""}},
+
+	"POST /abac/policies":        {summary: "Add an ABAC policy", request: ABACPolicy{}, response: map[string]interface{}{"message": "", "policy": ABACPolicy{}, "warnings": []string{}}},
+	"GET /abac/policies/{id}":    {summary: "Get an ABAC policy, with its version as ETag", response: ABACPolicy{}},
+	"PUT /abac/policies/{id}":    {summary: "Replace an ABAC policy; an If-Match ETag or body version other than the current one returns 409", request: ABACPolicy{}, response: map[string]interface{}{"message": "", "policy": ABACPolicy{}, "warnings": []string{}}},
+	"DELETE /abac/policies/{id}": {summary: "Remove an ABAC policy", response: map[string]interface{}{"removed": true, "message": "", "id": ""}},
+	"GET /abac/policies": {
+		summary:  "List ABAC policies",
+		query:    pageParams(labelParam, apiParam{"id", "Only the policy with this ID"}, apiParam{"name", "Only policies with this name"}, apiParam{"effect", "Only allow or deny policies"}),
+		response: map[string]interface{}{"policies": []ABACPolicy{}, "count": 0, "total": 0, "offset": 0, "limit": 0, "next_offset": 0},
+	},
+
+	"GET /abac/policies/{id}/history":  {summary: "List the revisions of an ABAC policy, newest first", response: historyResponse},
+	"POST /abac/policies/{id}/restore": {summary: "Restore a revision of an ABAC policy (the previous one by default)", request: RestoreRequest{}, response: map[string]interface{}{"message": "", "restored_version": 0, "policy": ABACPolicy{}, "model": ""}},
+	"GET /abac/analysis":               {summary: "Find conflicting and shadowed ABAC policies and unused attributes", response: PolicyAnalysis{}},
+	"POST /abac/environment":           {summary: "Preview the environment attributes of an ABAC check", request: EnvironmentPreviewRequest{}, response: map[string]interface{}{"environment": map[string]string{}, "providers": []string{}, "model": ""}},
+
+	"POST /relationships": {summary: "Add a relationship tuple", request: AddRelationshipRequest{}, response: map[string]interface{}{"message": "", "subject": "", "relationship": "", "object": "", "labels": []string{}, "warnings": []string{}, "model": "", "consistency_token": ""}},
+	"GET /relationships": {
+		summary:  "List relationship tuples",
+		query:    pageParams(apiParam{"subject", "Only tuples of this subject"}, apiParam{"relationship", "Only tuples of this relationship"}, apiParam{"object", "Only tuples on this object"}, labelParam, asOfParam),
+		response: map[string]interface{}{"relationships": []Relationship{}, "expirations": map[string]time.Time{}, "subject": "", "count": 0, "total": 0, "offset": 0, "limit": 0, "next_offset": 0, "model": ""},
+	},
+	"POST /relationships/bulk": {
+		summary:  "Add up to 1000 relationship tuples in one transaction",
+		request:  BulkRelationshipRequest{},
+		response: map[string]interface{}{"results": []BulkRelationshipResult{}, "created": 0, "failed": 0, "model": "", "consistency_token": ""},
+	},
+	"GET /relationships/watch": {
+		summary:  "Stream relationship changes as server-sent events (text/event-stream), each with its sequence number as event ID",
+		query:    []apiParam{{"since", "Stream the changes after this sequence number (or the Last-Event-ID header); 410 when no longer retained"}, {"subject", "Only changes of this subject"}, {"object", "Only changes of this object"}},
+		response: RelationshipChange{},
+	},
+	"GET /relationships/export": {
+		summary:  "Export the relationship graph for Graphviz (text/vnd.graphviz) or GraphML tools (application/graphml+xml)",
+		query:    []apiParam{{"format", "dot (default) or graphml"}, {"subject_prefix", "Only tuples whose subject starts with this prefix"}, {"object_prefix", "Only tuples whose object starts with this prefix"}, labelParam},
+		response: "",
+	},
+	"DELETE /relationships/{id}": {summary: "Remove a relationship tuple by subject:relationship:object", response: map[string]interface{}{"removed": true, "message": "", "model": "", "consistency_token": ""}},
+	"GET /relationships/paths": {
+		summary:  "Find relationship paths between a subject and an object",
+		query:    []apiParam{{"subject", "Start of the path"}, {"object", "End of the path"}, {"max_depth", "Maximum path length"}, {"all", "\"true\" lists every path"}, {"limit", "Maximum number of paths"}, {"action", "Only paths granting this action"}, {"permission", "Only paths granting this permission"}},
+		response: map[string]interface{}{"found": true, "path": "", "hops": []PathHop{}, "paths": []map[string]interface{}{{"path": "", "hops": []PathHop{}, "length": 0}}, "subject": "", "object": "", "max_depth": 0, "model": "", "note": ""},
+	},
+	"GET /relationships/partitions":  {summary: "Resident relationship partitions", response: map[string]interface{}{"partitions": PartitionStats{}, "model": ""}},
+	"GET /relationships/constraints": {summary: "List relationship cardinality constraints", response: map[string]interface{}{"constraints": []RelationshipConstraint{}, "model": ""}},
+	"GET /relationships/suggestions": {
+		summary:  "Suggest the smallest changes that would grant access",
+		query:    []apiParam{{"subject", "Subject to grant access"}, {"object", "Object to access"}, {"action", "Action to allow"}, {"model", "rebac (default) or rbac"}, {"limit", "Maximum number of suggestions"}},
+		response: map[string]interface{}{"subject": "", "object": "", "action": "", "allowed": true, "suggestions": []AccessSuggestion{}, "count": 0, "model": ""},
+	},
+
+	"GET /relationships/namespaces":           {summary: "List ReBAC namespace definitions", response: map[string]interface{}{"namespaces": []NamespaceDefinition{}, "count": 0, "mode": "", "model": ""}},
+	"GET /relationships/namespaces/{name}":    {summary: "Get a ReBAC namespace definition", response: map[string]interface{}{"namespace": NamespaceDefinition{}, "model": ""}},
+	"PUT /relationships/namespaces/{name}":    {summary: "Create or replace the relation rewrite rules and types of a namespace", request: NamespaceDefinition{}, response: map[string]interface{}{"message": "", "namespace": NamespaceDefinition{}, "model": "", "consistency_token": ""}},
+	"DELETE /relationships/namespaces/{name}": {summary: "Remove a ReBAC namespace definition", response: map[string]interface{}{"message": "", "removed": true, "model": "", "consistency_token": ""}},
+	"GET /relationships/actions":              {summary: "List custom ReBAC actions and the built-in action mappings", response: map[string]interface{}{"actions": []ActionMapping{}, "count": 0, "builtin": map[string]string{}, "model": ""}},
+	"GET /relationships/actions/{action}":     {summary: "Get the permission a custom action is checked as", response: map[string]interface{}{"action": ActionMapping{}, "model": ""}},
+	"PUT /relationships/actions/{action}":     {summary: "Register an action and the permission it is checked as", request: ActionMapping{}, response: map[string]interface{}{"message": "", "action": ActionMapping{}, "model": "", "consistency_token": ""}},
+	"DELETE /relationships/actions/{action}":  {summary: "Remove a custom action mapping", response: map[string]interface{}{"message": "", "removed": true, "model": "", "consistency_token": ""}},
+
+	"GET /actions":             {summary: "List the action hierarchy", response: map[string]interface{}{"actions": []ActionImplication{}, "count": 0}},
+	"GET /actions/{action}":    {summary: "Get the actions an action implies, directly and transitively", response: map[string]interface{}{"action": ActionImplication{}, "implied": []string{}}},
+	"PUT /actions/{action}":    {summary: "Set the actions an action implies in ACL, RBAC and ABAC checks", request: ActionImplication{}, response: map[string]interface{}{"message": "", "action": ActionImplication{}, "implied": []string{}}},
+	"DELETE /actions/{action}": {summary: "Remove the implications of an action", response: map[string]interface{}{"message": "", "removed": true}},
+
+	"GET /relationships/permissions":        {summary: "Permissions granted by relationship types", query: []apiParam{{"type", "Only this relationship type"}}, response: map[string]interface{}{"relationship": "", "permissions": []string{}, "exists": true, "mappings": map[string][]string{}, "description": "", "model": "", "note": ""}},
+	"POST /relationships/permissions/check": {summary: "Check whether a relationship type grants a permission", request: PermissionCheckRequest{}, response: map[string]interface{}{"relationship": "", "permission": "", "granted": true, "all_permissions": []string{}, "model": ""}},
+}
+
+// pageParams documents the filter parameters of a list endpoint followed by its sort and
+// page parameters
+func pageParams(filters ...apiParam) []apiParam {
+	return append(filters,
+		apiParam{"sort", "Field to sort by, prefixed with '-' for descending order"},
+		apiParam{"limit", "Maximum number of entries (at most 1000)"},
+		apiParam{"offset", "Number of entries to skip"},
+	)
+}
+
+// openAPIHandler serves the OpenAPI document of the routes this instance registers
+func (s *AuthService) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/v1").Subrouter()
+	s.registerEnforcementRoutes(api)
+	s.registerAdminRoutes(api)
+
+	document, err := buildOpenAPIDocument(router)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(document)
+}
+
+// buildOpenAPIDocument describes every route of router with a method
+func buildOpenAPIDocument(router *mux.Router) (map[string]interface{}, error) {
+	schemas := &schemaGenerator{components: make(map[string]interface{})}
+	paths := make(map[string]map[string]interface{})
+
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // Subrouter prefixes
+		}
+
+		path := strings.TrimPrefix(template, "/api/v1")
+		if paths[path] == nil {
+			paths[path] = make(map[string]interface{})
+		}
+		for _, method := range methods {
+			op := apiOperations[method+" "+path]
+			paths[path][strings.ToLower(method)] = schemas.operation(op, path, route.GetHandler())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Multi-Model Authorization Microservice",
+			"version": serviceVersion,
+			"description": "ACL, RBAC, ABAC and ReBAC authorization. Most endpoints accept a tenant " +
+				"query parameter or " + tenantHeader + " header to scope names to a tenant.",
+		},
+		"servers": []map[string]string{{"url": "/api/v1"}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]string{"type": "http", "scheme": "bearer"},
+				"apiKey":     map[string]string{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+		"security": []map[string][]string{{"bearerAuth": {}}, {"apiKey": {}}},
+	}, nil
+}
+
+// pathParamPattern matches route variables such as {id} or {id:[0-9]+}
+var pathParamPattern = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// operation describes one method of a path
+func (g *schemaGenerator) operation(op apiOperation, path string, handler http.Handler) map[string]interface{} {
+	var parameters []map[string]interface{}
+	for _, match := range pathParamPattern.FindAllStringSubmatch(path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name": match[1], "in": "path", "required": true, "schema": map[string]string{"type": "string"},
+		})
+	}
+	for _, param := range op.query {
+		parameters = append(parameters, map[string]interface{}{
+			"name": param.name, "in": "query", "description": param.description, "schema": map[string]string{"type": "string"},
+		})
+	}
+
+	body := map[string]interface{}{"type": "object"}
+	if op.response != nil {
+		body = g.valueSchema(reflect.ValueOf(op.response))
+	}
+	success := map[string]interface{}{
+		"description": "Success",
+		"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": body}},
+	}
+	status := op.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	responses := map[string]interface{}{
+		strconv.Itoa(status): success,
+		"default": map[string]interface{}{
+			"description": "Error",
+			"content":     map[string]interface{}{"application/json": map[string]interface{}{"schema": g.schema(reflect.TypeOf(ErrorResponse{}))}},
+		},
+	}
+	for _, also := range op.also {
+		responses[strconv.Itoa(also)] = map[string]interface{}{"description": http.StatusText(also), "content": success["content"]}
+	}
+
+	id := op.id
+	if id == "" {
+		id = handlerOperationID(handler)
+	}
+	operation := map[string]interface{}{
+		"operationId": id,
+		"summary":     op.summary,
+		"tags":        []string{strings.Split(strings.TrimPrefix(path, "/"), "/")[0]},
+		"responses":   responses,
+	}
+	if len(parameters) > 0 {
+		operation["parameters"] = parameters
+	}
+	if op.request != nil {
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content":  map[string]interface{}{"application/json": map[string]interface{}{"schema": g.schema(reflect.TypeOf(op.request))}},
+		}
+	}
+	return operation
+}
+
+// handlerOperationID derives an operation ID from a handler method such as
+// addACLPolicyHandler, which gives "addACLPolicy"
+func handlerOperationID(handler http.Handler) string {
+	fn, ok := handler.(http.HandlerFunc)
+	if !ok {
+		return ""
+	}
+	name := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name()
+	name = strings.TrimSuffix(name, "-fm")
+	name = name[strings.LastIndex(name, ".")+1:]
+	return strings.TrimSuffix(name, "Handler")
+}
+
+// schemaGenerator converts Go types to OpenAPI schemas, collecting named structs as
+// reusable components
+type schemaGenerator struct {
+	components map[string]interface{}
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	modelType = reflect.TypeOf(ModelACL)
+)
+
+// schema describes a Go type
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == modelType:
+		return map[string]interface{}{"type": "string", "enum": []AccessControlModel{ModelACL, ModelRBAC, ModelABAC, ModelReBAC}}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, exists := g.components[t.Name()]; !exists {
+			g.components[t.Name()] = map[string]interface{}{} // Placeholder for recursive types
+			g.components[t.Name()] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]interface{}{} // Any value
+	}
+}
+
+// structSchema describes the JSON encoding of a struct, flattening embedded structs
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	g.addFields(t, properties)
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// addFields adds the JSON fields of a struct to properties
+func (g *schemaGenerator) addFields(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.addFields(field.Type, properties)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+	}
+}
+
+// valueSchema describes an example value. Maps with interface values are objects with a
+// property per key; other values are described by their type.
+func (g *schemaGenerator) valueSchema(v reflect.Value) map[string]interface{} {
+	switch {
+	case v.Kind() == reflect.Interface:
+		return g.valueSchema(v.Elem())
+	case v.Kind() == reflect.Map && v.Type().Elem().Kind() == reflect.Interface:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		properties := make(map[string]interface{}, len(keys))
+		for _, key := range keys {
+			properties[key.String()] = g.valueSchema(v.MapIndex(key))
+		}
+		return map[string]interface{}{"type": "object", "properties": properties}
+	case v.Kind() == reflect.Slice && v.Len() > 0 && v.Type().Elem().Kind() == reflect.Map:
+		return map[string]interface{}{"type": "array", "items": g.valueSchema(v.Index(0))}
+	default:
+		return g.schema(v.Type())
+	}
+}
@@ -0,0 +1,92 @@
+// Multi-Model Authorization Microservice - OpenAPI Specification Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	service := setupTestService(t)
+	service.demoMode = true
+	service.tokenAuthorization = &tokenAuthorization{}
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/v1").Subrouter()
+	service.registerEnforcementRoutes(api)
+	service.registerAdminRoutes(api)
+
+	// Every registered route needs an entry, so new endpoints can't be left out of the document
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, _ := route.GetPathTemplate()
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+		for _, method := range methods {
+			key := method + " " + strings.TrimPrefix(template, "/api/v1")
+			if _, documented := apiOperations[key]; !documented {
+				t.Errorf("Route %s is missing from apiOperations", key)
+			}
+		}
+		return nil
+	})
+
+	req, _ := http.NewRequest("GET", "/api/v1/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+
+	var document struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string                     `json:"operationId"`
+			Parameters  []map[string]interface{}   `json:"parameters"`
+			Responses   map[string]json.RawMessage `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &document); err != nil {
+		t.Fatalf("Invalid document: %v", err)
+	}
+	if document.OpenAPI != "3.0.3" || len(document.Paths) < 50 {
+		t.Fatalf("Unexpected document: version %s with %d paths", document.OpenAPI, len(document.Paths))
+	}
+
+	authorize := document.Paths["/authorizations"]["post"]
+	if authorize.OperationID != "authorize" || authorize.Responses["200"] == nil || authorize.Responses["403"] == nil {
+		t.Errorf("Unexpected authorizations operation: %+v", authorize)
+	}
+	if op := document.Paths["/acl/policies"]["post"]; op.OperationID != "addACLPolicy" || op.Responses["201"] == nil {
+		t.Errorf("Unexpected ACL operation: %+v", op)
+	}
+	if params := document.Paths["/users/{userId}/roles/{roleId}"]["delete"].Parameters; len(params) != 2 || params[0]["in"] != "path" {
+		t.Errorf("Expected two path parameters, got %v", params)
+	}
+
+	// Request and response types are reflected from their JSON tags
+	enforce := document.Components.Schemas["EnforceRequest"].Properties
+	if enforce["subject"]["type"] != "string" || len(enforce["model"]["enum"].([]interface{})) != 4 || enforce["attributes"]["type"] != "object" {
+		t.Errorf("Unexpected EnforceRequest schema: %v", enforce)
+	}
+	relationship := document.Components.Schemas["AddRelationshipRequest"].Properties
+	if relationship["subject"] == nil || relationship["expires_at"]["format"] != "date-time" {
+		t.Errorf("Expected embedded fields to be flattened, got %v", relationship)
+	}
+	if document.Components.Schemas["EnforceResponse"].Properties["explanation"]["$ref"] != "#/components/schemas/DecisionExplanation" {
+		t.Error("Expected the explanation to reference its schema")
+	}
+}
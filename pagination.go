@@ -0,0 +1,179 @@
+// Multi-Model Authorization Microservice - List Pagination
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxListLimit caps the page size of list endpoints
+const maxListLimit = 1000
+
+// listOptions are the filtering, sorting and pagination parameters of a list endpoint
+type listOptions struct {
+	fields     []string          // Fields entries can be filtered and sorted by
+	filters    map[string]string // Values entries must have, by field
+	sortBy     string            // Field to sort by, "" to keep the listing order
+	descending bool
+	offset     int
+	limit      int // 0 returns every entry after offset
+}
+
+// parseListOptions reads the list parameters of a request: a query parameter per field
+// keeps the entries with that exact value, sort names the field to order by ("-" prefix
+// for descending order), and limit and offset select a page
+func parseListOptions(query url.Values, fields ...string) (*listOptions, error) {
+	options := &listOptions{fields: fields, filters: make(map[string]string)}
+	for _, field := range fields {
+		if value := query.Get(field); value != "" {
+			options.filters[field] = value
+		}
+	}
+
+	if sortBy := query.Get("sort"); sortBy != "" {
+		options.sortBy = strings.TrimPrefix(sortBy, "-")
+		options.descending = strings.HasPrefix(sortBy, "-")
+		if !options.sortable(options.sortBy) {
+			return nil, fmt.Errorf("sort must be one of %s, optionally prefixed with '-'", strings.Join(fields, ", "))
+		}
+	}
+
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("offset must be a non-negative integer")
+		}
+		options.offset = offset
+	}
+	if limitStr := query.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("limit must be a positive integer")
+		}
+		if limit > maxListLimit {
+			limit = maxListLimit
+		}
+		options.limit = limit
+	}
+	return options, nil
+}
+
+// sortable reports whether entries can be sorted by a field
+func (o *listOptions) sortable(field string) bool {
+	for _, f := range o.fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// sortByDefault orders entries by a field when the request chose no order, for listings
+// whose natural order is not stable between requests
+func (o *listOptions) sortByDefault(field string) {
+	if o.sortBy == "" {
+		o.sortBy = field
+	}
+}
+
+// annotate adds the page position to a list response. next_offset is only set when more
+// entries follow.
+func (o *listOptions) annotate(response map[string]interface{}, returned, total int) {
+	response["total"] = total
+	response["offset"] = o.offset
+	if o.limit > 0 {
+		response["limit"] = o.limit
+	}
+	if next := o.offset + returned; next < total {
+		response["next_offset"] = next
+	}
+}
+
+// pageEntries filters, sorts and pages the entries of a list endpoint, reading fields
+// with field. Ties are broken by the remaining fields in order. It returns the page and
+// the number of entries matching the filters.
+func pageEntries[T any](entries []T, options *listOptions, field func(entry T, name string) string) ([]T, int) {
+	matching := make([]T, 0, len(entries))
+	for _, entry := range entries {
+		keep := true
+		for name, value := range options.filters {
+			keep = keep && field(entry, name) == value
+		}
+		if keep {
+			matching = append(matching, entry)
+		}
+	}
+
+	if options.sortBy != "" {
+		order := append([]string{options.sortBy}, options.fields...)
+		sort.SliceStable(matching, func(i, j int) bool {
+			for _, name := range order {
+				a, b := field(matching[i], name), field(matching[j], name)
+				if a != b {
+					return (a < b) != options.descending
+				}
+			}
+			return false
+		})
+	}
+
+	total := len(matching)
+	if options.offset >= total {
+		return matching[:0], total
+	}
+	page := matching[options.offset:]
+	if options.limit > 0 && len(page) > options.limit {
+		page = page[:options.limit]
+	}
+	return page, total
+}
+
+// ruleField reads a field of an ACL/RBAC rule (subject, object, action, effect), with
+// names in the form local to scope
+func ruleField(scope tenantScope) func(rule []string, name string) string {
+	return func(rule []string, name string) string {
+		switch name {
+		case "subject":
+			return scope.local(rule[0])
+		case "object":
+			return scope.local(rule[1])
+		case "action":
+			return rule[2]
+		case "effect":
+			return ruleEffect(rule)
+		}
+		return ""
+	}
+}
+
+// relationshipField reads a field of a tuple (subject, relationship, object)
+func relationshipField(rel Relationship, name string) string {
+	switch name {
+	case "subject":
+		return rel.Subject
+	case "relationship":
+		return rel.Relationship
+	case "object":
+		return rel.Object
+	}
+	return ""
+}
+
+// abacPolicyField reads a field of an ABAC policy (id, name, effect)
+func abacPolicyField(policy *ABACPolicy, name string) string {
+	switch name {
+	case "id":
+		return policy.ID
+	case "name":
+		return policy.Name
+	case "effect":
+		return policy.Effect
+	}
+	return ""
+}
@@ -0,0 +1,321 @@
+// Multi-Model Authorization Microservice - ReBAC Graph Partitioning
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultPartitionDelimiter separates an object's namespace from its name (e.g. "billing/invoice1")
+const defaultPartitionDelimiter = "/"
+
+// defaultLazyCacheSize is how many objects a lazily loaded graph keeps in memory between requests
+const defaultLazyCacheSize = 10000
+
+// partitionCache keeps track of which object namespaces are resident in memory.
+// Relationships are partitioned by the namespace of their object, and the least
+// recently used partition is evicted once the capacity is exceeded. Lazily loaded
+// graphs partition by object instead, so each partition holds the tuples of one node.
+type partitionCache struct {
+	capacity  int
+	delimiter string
+	perNode   bool                     // Partitions hold single objects rather than namespaces
+	stmts     *gorm.DB                 // Prepared-statement session for per-node loads (nil otherwise)
+	order     *list.List               // Front is the most recently used partition
+	entries   map[string]*list.Element // Namespace to LRU element
+}
+
+// partitionEntry holds the relationships loaded for a single namespace
+type partitionEntry struct {
+	namespace string
+	tuples    []Relationship
+}
+
+// PartitionStats describes the resident partitions of a partitioned graph
+type PartitionStats struct {
+	Enabled   bool     `json:"enabled"`
+	Capacity  int      `json:"capacity,omitempty"`
+	Delimiter string   `json:"delimiter,omitempty"`
+	Mode      string   `json:"mode,omitempty"` // "namespace", or "node" for lazily loaded graphs
+	Resident  []string `json:"resident"`
+	Tuples    int      `json:"tuples"`
+}
+
+// newPartitionCache creates an empty partition cache
+func newPartitionCache(capacity int, delimiter string) *partitionCache {
+	if delimiter == "" {
+		delimiter = defaultPartitionDelimiter
+	}
+	return &partitionCache{
+		capacity:  capacity,
+		delimiter: delimiter,
+		order:     list.New(),
+		entries:   make(map[string]*list.Element),
+	}
+}
+
+// newNodeCache creates an empty cache of per-node partitions loaded with prepared statements
+func newNodeCache(db *gorm.DB, capacity int) *partitionCache {
+	pc := newPartitionCache(capacity, "")
+	pc.perNode = true
+	pc.stmts = db.Session(&gorm.Session{PrepareStmt: true})
+	return pc
+}
+
+// namespaceOf returns the partition of an object: its namespace, "" for objects without
+// one, or the object itself when partitions are per node
+func (pc *partitionCache) namespaceOf(object string) string {
+	if pc.perNode {
+		return object
+	}
+	if idx := strings.Index(object, pc.delimiter); idx > 0 {
+		return object[:idx]
+	}
+	return ""
+}
+
+// isResident reports whether a namespace is currently loaded
+func (pc *partitionCache) isResident(namespace string) bool {
+	_, exists := pc.entries[namespace]
+	return exists
+}
+
+// track records a relationship as belonging to its (resident) partition
+func (pc *partitionCache) track(rel Relationship) {
+	if elem, exists := pc.entries[pc.namespaceOf(rel.Object)]; exists {
+		entry := elem.Value.(*partitionEntry)
+		entry.tuples = append(entry.tuples, rel)
+	}
+}
+
+// untrack removes every copy of a relationship from its partition bookkeeping
+func (pc *partitionCache) untrack(rel Relationship) {
+	elem, exists := pc.entries[pc.namespaceOf(rel.Object)]
+	if !exists {
+		return
+	}
+	entry := elem.Value.(*partitionEntry)
+	kept := entry.tuples[:0]
+	for _, tuple := range entry.tuples {
+		if tuple != rel {
+			kept = append(kept, tuple)
+		}
+	}
+	entry.tuples = kept
+}
+
+// NewPartitionedRelationshipGraph creates a relationship graph that loads object
+// namespaces on demand and keeps at most capacity of them in memory
+func NewPartitionedRelationshipGraph(db *gorm.DB, capacity int, delimiter string) (*RelationshipGraph, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("partition capacity must be positive")
+	}
+	return newPartialRelationshipGraph(db, newPartitionCache(capacity, delimiter))
+}
+
+// NewLazyRelationshipGraph creates a relationship graph that never loads the whole graph:
+// traversals query the tuples of each object they reach, and at most capacity objects stay
+// in memory. Checks read through request views, which memoize the objects of one request.
+func NewLazyRelationshipGraph(db *gorm.DB, capacity int) (*RelationshipGraph, error) {
+	if capacity <= 0 {
+		return nil, fmt.Errorf("lazy cache capacity must be positive")
+	}
+	return newPartialRelationshipGraph(db, newNodeCache(db, capacity))
+}
+
+// newPartialRelationshipGraph creates a relationship graph holding only the partitions of
+// partitions in memory
+func newPartialRelationshipGraph(db *gorm.DB, partitions *partitionCache) (*RelationshipGraph, error) {
+	err := db.AutoMigrate(&RelationshipRecord{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate relationship table: %v", err)
+	}
+
+	rg := &RelationshipGraph{
+		mu:            &sync.RWMutex{},
+		relationships: make(map[string][]Relationship),
+		reverse:       newReverseIndex(),
+		adjacency:     newAdjacencyIndex(),
+		db:            db,
+		permissions:   make(map[string][]string),
+		partitions:    partitions,
+		checkCache:    newCheckCache(defaultCheckCacheSize),
+	}
+
+	rg.initializeDefaultPermissions()
+
+	// Caveated tuples may be stored in partitions that are not resident
+	var caveated int64
+	if err := db.Model(&RelationshipRecord{}).Where("caveat IS NOT NULL").Count(&caveated).Error; err != nil {
+		return nil, fmt.Errorf("failed to count caveated relationships: %v", err)
+	}
+	rg.hasCaveats = caveated > 0
+
+	// Partitions are read later, so they are at least as fresh as the sequence recorded now
+	if err := migrateRelationshipSequence(db); err != nil {
+		return nil, err
+	}
+	if err := rg.syncSequence(); err != nil {
+		return nil, err
+	}
+
+	if err := migrateNamespaces(db); err != nil {
+		return nil, err
+	}
+	if err := rg.loadNamespaces(); err != nil {
+		return nil, err
+	}
+
+	if err := migrateActions(db); err != nil {
+		return nil, err
+	}
+	if err := rg.loadActions(); err != nil {
+		return nil, err
+	}
+
+	return rg, nil
+}
+
+// ensurePartition loads a namespace into memory if needed and marks it as recently used
+func (rg *RelationshipGraph) ensurePartition(namespace string) error {
+	pc := rg.partitions
+	if pc == nil {
+		return nil
+	}
+
+	if elem, exists := pc.entries[namespace]; exists {
+		pc.order.MoveToFront(elem)
+		return nil
+	}
+
+	var records []RelationshipRecord
+	query := rg.loadDB().Order("id")
+	if pc.perNode {
+		query = rg.loadDB().Where("object = ?", namespace).Order("id")
+	} else if namespace == "" {
+		query = query.Where("instr(object, ?) <= 1", pc.delimiter)
+	} else {
+		prefix := namespace + pc.delimiter
+		query = query.Where("substr(object, 1, ?) = ?", len(prefix), prefix)
+	}
+	if err := query.Find(&records).Error; err != nil {
+		return fmt.Errorf("failed to load partition %q: %v", namespace, err)
+	}
+
+	entry := &partitionEntry{namespace: namespace}
+	now := time.Now()
+	for _, record := range records {
+		if relationshipExpired(record, now) {
+			continue
+		}
+		rel := Relationship{
+			Subject:      record.Subject,
+			Relationship: record.Relationship,
+			Object:       record.Object,
+		}
+		entry.tuples = append(entry.tuples, rel)
+		rg.trackExpiry(rel, record.ExpiresAt)
+		rg.trackCaveat(rel, record.Caveat)
+		rg.addToMemory(rel.Subject, rel.Relationship, rel.Object)
+	}
+	pc.entries[namespace] = pc.order.PushFront(entry)
+
+	// Evict least recently used partitions beyond capacity
+	for pc.order.Len() > pc.capacity {
+		oldest := pc.order.Back()
+		evicted := oldest.Value.(*partitionEntry)
+		for _, rel := range evicted.tuples {
+			rg.removeFromMemory(rel.Subject, rel.Relationship, rel.Object)
+		}
+		pc.order.Remove(oldest)
+		delete(pc.entries, evicted.namespace)
+	}
+
+	return nil
+}
+
+// ensureObjectLoaded makes sure the partition holding relationships to object is resident
+func (rg *RelationshipGraph) ensureObjectLoaded(object string) {
+	if rg.partitions == nil {
+		return
+	}
+	if err := rg.ensurePartition(rg.partitions.namespaceOf(object)); err != nil {
+		log.Printf("ReBAC partition load error: %v", err)
+	}
+}
+
+// ensureSubjectLoaded makes sure every partition holding outgoing relationships of subject is resident
+func (rg *RelationshipGraph) ensureSubjectLoaded(subject string) {
+	if rg.partitions == nil {
+		return
+	}
+
+	var objects []string
+	if err := rg.loadDB().Model(&RelationshipRecord{}).Where("subject = ?", subject).Distinct().Pluck("object", &objects).Error; err != nil {
+		log.Printf("ReBAC partition lookup error for %s: %v", subject, err)
+		return
+	}
+
+	namespaces := make(map[string]bool)
+	for _, object := range objects {
+		namespace := rg.partitions.namespaceOf(object)
+		if namespaces[namespace] {
+			continue
+		}
+		namespaces[namespace] = true
+		if err := rg.ensurePartition(namespace); err != nil {
+			log.Printf("ReBAC partition load error: %v", err)
+		}
+	}
+}
+
+// PartitionStats returns information about resident partitions
+func (rg *RelationshipGraph) PartitionStats() PartitionStats {
+	pc := rg.partitions
+	if pc == nil {
+		return PartitionStats{Enabled: false, Resident: []string{}}
+	}
+
+	unlock := rg.readLock()
+	defer unlock()
+
+	stats := PartitionStats{
+		Enabled:   true,
+		Capacity:  pc.capacity,
+		Delimiter: pc.delimiter,
+		Mode:      "namespace",
+		Resident:  []string{},
+	}
+	if pc.perNode {
+		stats.Delimiter, stats.Mode = "", "node"
+	}
+	for elem := pc.order.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*partitionEntry)
+		stats.Resident = append(stats.Resident, entry.namespace)
+		stats.Tuples += len(entry.tuples)
+	}
+
+	return stats
+}
+
+// getRelationshipPartitionsHandler reports which relationship partitions are resident in memory
+func (s *AuthService) getRelationshipPartitionsHandler(w http.ResponseWriter, r *http.Request) {
+	response := map[string]interface{}{
+		"partitions": s.relationshipGraph.PartitionStats(),
+		"model":      "rebac",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
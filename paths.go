@@ -0,0 +1,212 @@
+// Multi-Model Authorization Microservice - ReBAC Relationship Paths
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// PathHop is one relationship tuple along a ReBAC path
+type PathHop struct {
+	Subject  string `json:"subject"`
+	Relation string `json:"relation"`
+	Object   string `json:"object"`
+}
+
+// appendHop returns a new path extending path with hop, leaving path itself untouched
+func appendHop(path []PathHop, hop PathHop) []PathHop {
+	extended := make([]PathHop, len(path), len(path)+1)
+	copy(extended, path)
+	return append(extended, hop)
+}
+
+// formatPath renders a path starting at subject as "alice -[member]-> team -[owner]-> doc"
+func formatPath(subject string, hops []PathHop) string {
+	var b strings.Builder
+	b.WriteString(subject)
+	for _, hop := range hops {
+		b.WriteString(" -[")
+		b.WriteString(hop.Relation)
+		b.WriteString("]-> ")
+		b.WriteString(hop.Object)
+	}
+	return b.String()
+}
+
+// pathHasRelation reports whether any hop of the path uses the relation
+func pathHasRelation(hops []PathHop, relation string) bool {
+	for _, hop := range hops {
+		if hop.Relation == relation {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultPathLimit and maxPathLimit bound how many paths the all-paths option returns
+const (
+	defaultPathLimit = 10
+	maxPathLimit     = 100
+)
+
+// maxPathExpansions caps the partial paths explored while enumerating, since the
+// number of simple paths can grow exponentially with depth
+const maxPathExpansions = 10000
+
+// outgoingHops returns the forward relationships leaving node as hops
+func (rg *RelationshipGraph) outgoingHops(node string) []PathHop {
+	// Outgoing edges may live in any partition the node points into
+	rg.ensureSubjectLoaded(node)
+
+	var hops []PathHop
+	for _, rel := range rg.outgoingFrom(node) {
+		if !rg.caveatHolds(rel) {
+			continue
+		}
+		hops = append(hops, PathHop{Subject: node, Relation: rel.Relationship, Object: rel.Object})
+	}
+
+	// Map iteration order is random; keep equal-length paths in a stable order
+	sort.Slice(hops, func(i, j int) bool {
+		if hops[i].Relation != hops[j].Relation {
+			return hops[i].Relation < hops[j].Relation
+		}
+		return hops[i].Object < hops[j].Object
+	})
+	return hops
+}
+
+// FindAllRelationshipHops returns up to limit distinct simple paths from subject to
+// targetObject, shortest first. When accept is non-nil only paths it approves are kept.
+func (rg *RelationshipGraph) FindAllRelationshipHops(subject, targetObject string, maxDepth, limit int, accept func([]PathHop) bool) [][]PathHop {
+	unlock := rg.readLock()
+	defer unlock()
+	return rg.findAllRelationshipHops(subject, targetObject, maxDepth, limit, accept, nil)
+}
+
+// findAllRelationshipHops implements FindAllRelationshipHops for callers holding the lock.
+// When viable is non-nil, paths it rejects are not extended any further.
+func (rg *RelationshipGraph) findAllRelationshipHops(subject, targetObject string, maxDepth, limit int, accept, viable func([]PathHop) bool) [][]PathHop {
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+	if limit <= 0 {
+		limit = defaultPathLimit
+	}
+
+	paths := [][]PathHop{}
+	queue := [][]PathHop{nil}
+	expansions := 0
+
+	for len(queue) > 0 && len(paths) < limit && expansions < maxPathExpansions {
+		current := queue[0]
+		queue = queue[1:]
+		expansions++
+
+		node := subject
+		if len(current) > 0 {
+			node = current[len(current)-1].Object
+		}
+
+		if node == targetObject && len(current) > 0 {
+			if accept == nil || accept(current) {
+				paths = append(paths, current)
+			}
+			continue
+		}
+
+		if len(current) >= maxDepth {
+			continue
+		}
+
+		// Keep paths simple so every result is a distinct reason rather than a cycle
+		onPath := map[string]bool{subject: true}
+		for _, hop := range current {
+			onPath[hop.Object] = true
+		}
+
+		for _, hop := range rg.outgoingHops(node) {
+			if hop.Object != targetObject && onPath[hop.Object] {
+				continue
+			}
+			extended := appendHop(current, hop)
+			if viable != nil && !viable(extended) {
+				continue
+			}
+			queue = append(queue, extended)
+		}
+	}
+
+	// Breadth-first order already yields non-decreasing lengths; keep it explicit
+	sort.SliceStable(paths, func(i, j int) bool {
+		return len(paths[i]) < len(paths[j])
+	})
+	return paths
+}
+
+// FindGrantPaths returns up to limit distinct paths through which subject holds the
+// permission mapped from action on object, shortest first
+func (rg *RelationshipGraph) FindGrantPaths(subject, object, action string, maxDepth, limit int) [][]PathHop {
+	unlock := rg.readLock()
+	defer unlock()
+	return rg.findPermissionPaths(subject, object, rg.mapActionToPermission(action), maxDepth, limit)
+}
+
+// FindPermissionPaths returns up to limit distinct paths through which subject holds
+// permission on object, shortest first. Only relationships that can lead to a grant of
+// the permission are traversed.
+func (rg *RelationshipGraph) FindPermissionPaths(subject, object, permission string, maxDepth, limit int) [][]PathHop {
+	unlock := rg.readLock()
+	defer unlock()
+	return rg.findPermissionPaths(subject, object, permission, maxDepth, limit)
+}
+
+// findPermissionPaths implements FindPermissionPaths for callers holding the lock
+func (rg *RelationshipGraph) findPermissionPaths(subject, object, permission string, maxDepth, limit int) [][]PathHop {
+	return rg.findAllRelationshipHops(subject, object, maxDepth, limit,
+		func(hops []PathHop) bool { return rg.pathGrants(hops, permission) },
+		func(hops []PathHop) bool { return rg.pathMayGrant(hops, permission) })
+}
+
+// pathGrants reports whether a path grants permission under the same rules the
+// ReBAC evaluator applies: a granting relation, optionally reached through one
+// group membership or a friend connection, followed by any number of parent links
+func (rg *RelationshipGraph) pathGrants(hops []PathHop, permission string) bool {
+	end := len(hops)
+	for end > 0 && hops[end-1].Relation == "parent" {
+		end--
+	}
+	core := hops[:end]
+
+	switch {
+	case len(core) == 1:
+		if rg.HasPermissionThroughRelationship(core[0].Relation, permission) {
+			return true
+		}
+	case len(core) == 2 && core[0].Relation == "member":
+		if rg.HasPermissionThroughRelationship(core[1].Relation, permission) {
+			return true
+		}
+	}
+
+	if (permission == "read" || permission == "read_limited") && len(core) > 0 && len(core) <= 3 {
+		return pathHasRelation(core, "friend") && rg.HasPermissionThroughRelationship("friend", "read_limited")
+	}
+	return false
+}
+
+// pathMayGrant reports whether a path grants permission or can still be extended into one
+// that does under the rules of pathGrants. A granting path only extends through parent
+// links, and otherwise only a group membership may precede the granting relation.
+func (rg *RelationshipGraph) pathMayGrant(hops []PathHop, permission string) bool {
+	if rg.pathGrants(hops, permission) {
+		return true
+	}
+	if (permission == "read" || permission == "read_limited") && len(hops) < 3 && rg.HasPermissionThroughRelationship("friend", "read_limited") {
+		return true
+	}
+	return len(hops) == 1 && hops[0].Relation == "member"
+}
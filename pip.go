@@ -0,0 +1,339 @@
+// Multi-Model Authorization Microservice - ABAC Attribute Resolvers
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	// defaultResolverTimeout bounds how long a decision waits for an external attribute lookup
+	defaultResolverTimeout = 500 * time.Millisecond
+
+	// defaultResolverCacheTTL is how long externally resolved attributes are reused
+	defaultResolverCacheTTL = time.Minute
+
+	// defaultLDAPUserFilter finds the LDAP entry of a user
+	defaultLDAPUserFilter = "(uid={id})"
+)
+
+// AttributeResolver fetches the attributes of a user or object from an external Policy
+// Information Point, e.g. an HR system or a directory, at enforcement time. scope is
+// "user" or "object". An unknown entity has no attributes rather than an error.
+type AttributeResolver interface {
+	ResolveAttributes(ctx context.Context, scope, id string) (map[string]string, error)
+}
+
+// attributeResolution resolves the attributes ABAC decisions need but the service does
+// not store, with a timeout and a cache per scope
+type attributeResolution struct {
+	resolver AttributeResolver
+	timeout  time.Duration
+	users    *attributeCache // nil when caching is disabled
+	objects  *attributeCache // nil when caching is disabled
+}
+
+// resolverChain asks each resolver in turn; attributes of earlier resolvers win
+type resolverChain []AttributeResolver
+
+// ResolveAttributes merges the attributes of every resolver
+func (rc resolverChain) ResolveAttributes(ctx context.Context, scope, id string) (map[string]string, error) {
+	merged := make(map[string]string)
+	for _, resolver := range rc {
+		attributes, err := resolver.ResolveAttributes(ctx, scope, id)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range attributes {
+			if _, exists := merged[name]; !exists {
+				merged[name] = value
+			}
+		}
+	}
+	return merged, nil
+}
+
+// httpAttributeResolver fetches attributes with GET from a URL template in which {scope}
+// and {id} are replaced, e.g. "https://pip.example.com/{scope}s/{id}". The endpoint answers
+// with a JSON object of attributes, or 404 for an unknown entity.
+type httpAttributeResolver struct {
+	urlTemplate string
+	token       string // Bearer token sent with every lookup (optional)
+	client      *http.Client
+}
+
+// ResolveAttributes fetches the attributes of an entity from the endpoint
+func (hr *httpAttributeResolver) ResolveAttributes(ctx context.Context, scope, id string) (map[string]string, error) {
+	target := strings.NewReplacer("{scope}", url.PathEscape(scope), "{id}", url.PathEscape(id)).Replace(hr.urlTemplate)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attribute lookup: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if hr.token != "" {
+		req.Header.Set("Authorization", "Bearer "+hr.token)
+	}
+
+	resp, err := hr.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("attribute lookup failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("attribute lookup rejected with status %d", resp.StatusCode)
+	}
+
+	var document map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid attribute lookup response: %v", err)
+	}
+	attributes := make(map[string]string, len(document))
+	for name, value := range document {
+		if formatted, ok := formatResolvedValue(value); ok {
+			attributes[name] = formatted
+		}
+	}
+	return attributes, nil
+}
+
+// formatResolvedValue renders a JSON value the way attributes are stored: numbers and
+// booleans as text and arrays as comma-separated lists. Objects and nulls are skipped.
+func formatResolvedValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case []interface{}:
+		elements := make([]string, 0, len(v))
+		for _, element := range v {
+			if formatted, ok := formatResolvedValue(element); ok {
+				elements = append(elements, formatted)
+			}
+		}
+		return strings.Join(elements, ","), true
+	default:
+		return "", false
+	}
+}
+
+// ldapAttributeResolver reads the attributes of users from their directory entry. Objects
+// are not looked up.
+type ldapAttributeResolver struct {
+	url          string
+	bindDN       string
+	bindPassword string
+	baseDN       string
+	filter       string            // Search filter in which {id} is replaced by the escaped user ID
+	attributes   map[string]string // LDAP attribute to ABAC attribute name
+	timeout      time.Duration
+}
+
+// ResolveAttributes searches the directory for the entry of a user
+func (lr *ldapAttributeResolver) ResolveAttributes(ctx context.Context, scope, id string) (map[string]string, error) {
+	if scope != "user" {
+		return nil, nil
+	}
+
+	// Connections are short-lived; resolved attributes are cached instead
+	timeout := lr.timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	conn, err := ldap.DialURL(lr.url, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP: %v", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(timeout)
+
+	if lr.bindDN != "" {
+		if err := conn.Bind(lr.bindDN, lr.bindPassword); err != nil {
+			return nil, fmt.Errorf("LDAP bind failed: %v", err)
+		}
+	}
+
+	names := make([]string, 0, len(lr.attributes))
+	for name := range lr.attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	filter := strings.ReplaceAll(lr.filter, "{id}", ldap.EscapeFilter(id))
+	search := ldap.NewSearchRequest(lr.baseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(timeout.Seconds()), false, filter, names, nil)
+	result, err := conn.Search(search)
+	if err != nil {
+		return nil, fmt.Errorf("LDAP search failed: %v", err)
+	}
+	switch len(result.Entries) {
+	case 0:
+		return nil, nil
+	case 1:
+	default:
+		return nil, fmt.Errorf("LDAP filter %s matches more than one entry", filter)
+	}
+
+	attributes := make(map[string]string, len(names))
+	for _, name := range names {
+		if values := result.Entries[0].GetAttributeValues(name); len(values) > 0 {
+			attributes[lr.attributes[name]] = strings.Join(values, ",")
+		}
+	}
+	return attributes, nil
+}
+
+// parseAttributeMapping parses a comma-separated list of external attributes, e.g. LDAP
+// attributes or token claims, each optionally renamed with ":", e.g.
+// "departmentNumber:department,title"
+func parseAttributeMapping(value string) (map[string]string, error) {
+	mapping := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		external, name, renamed := strings.Cut(entry, ":")
+		if !renamed {
+			name = external
+		}
+		if external == "" || name == "" {
+			return nil, fmt.Errorf("invalid attribute mapping %q", entry)
+		}
+		mapping[external] = name
+	}
+	if len(mapping) == 0 {
+		return nil, fmt.Errorf("no attributes configured")
+	}
+	return mapping, nil
+}
+
+// attributeResolutionFromEnv configures external attribute resolution from ABAC_PIP_URL
+// and the ABAC_PIP_LDAP_* settings. It returns nil when no resolver is configured.
+func attributeResolutionFromEnv() (*attributeResolution, error) {
+	timeout := defaultResolverTimeout
+	if timeoutStr := os.Getenv("ABAC_PIP_TIMEOUT"); timeoutStr != "" {
+		parsed, err := time.ParseDuration(timeoutStr)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid ABAC_PIP_TIMEOUT value: %s", timeoutStr)
+		}
+		timeout = parsed
+	}
+
+	resolution := &attributeResolution{timeout: timeout}
+	var chain resolverChain
+	if urlTemplate := os.Getenv("ABAC_PIP_URL"); urlTemplate != "" {
+		if !strings.Contains(urlTemplate, "{id}") {
+			return nil, fmt.Errorf("invalid ABAC_PIP_URL value: %s (it must contain {id})", urlTemplate)
+		}
+		chain = append(chain, &httpAttributeResolver{
+			urlTemplate: urlTemplate,
+			token:       os.Getenv("ABAC_PIP_TOKEN"),
+			client:      &http.Client{Timeout: timeout},
+		})
+	}
+	if ldapURL := os.Getenv("ABAC_PIP_LDAP_URL"); ldapURL != "" {
+		baseDN := os.Getenv("ABAC_PIP_LDAP_BASE_DN")
+		if baseDN == "" {
+			return nil, fmt.Errorf("ABAC_PIP_LDAP_BASE_DN is required with ABAC_PIP_LDAP_URL")
+		}
+		attributes, err := parseAttributeMapping(os.Getenv("ABAC_PIP_LDAP_ATTRIBUTES"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid ABAC_PIP_LDAP_ATTRIBUTES value: %v", err)
+		}
+		filter := os.Getenv("ABAC_PIP_LDAP_FILTER")
+		if filter == "" {
+			filter = defaultLDAPUserFilter
+		}
+		chain = append(chain, &ldapAttributeResolver{
+			url:          ldapURL,
+			bindDN:       os.Getenv("ABAC_PIP_LDAP_BIND_DN"),
+			bindPassword: os.Getenv("ABAC_PIP_LDAP_BIND_PASSWORD"),
+			baseDN:       baseDN,
+			filter:       filter,
+			attributes:   attributes,
+			timeout:      timeout,
+		})
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	resolution.resolver = chain
+	if len(chain) == 1 {
+		resolution.resolver = chain[0]
+	}
+
+	ttl := defaultResolverCacheTTL
+	if ttlStr := os.Getenv("ABAC_PIP_CACHE_TTL"); ttlStr != "" {
+		parsed, err := time.ParseDuration(ttlStr)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid ABAC_PIP_CACHE_TTL value: %s", ttlStr)
+		}
+		ttl = parsed
+	}
+	if ttl > 0 {
+		resolution.users = newAttributeCache("resolved_user", defaultAttributeCacheSize, ttl)
+		resolution.objects = newAttributeCache("resolved_object", defaultAttributeCacheSize, ttl)
+	}
+	return resolution, nil
+}
+
+// withResolvedAttributes adds the externally resolved attributes of a user or object that
+// are not stored in the service; stored attributes win. Strong freshness bypasses the
+// cache. When the lookup fails or times out, the decision uses the stored attributes only.
+func (s *AuthService) withResolvedAttributes(scope, id string, stored map[string]string, strong bool) map[string]string {
+	resolution := s.attributeResolution
+	if resolution == nil {
+		return stored
+	}
+
+	load := func(id string) (map[string]string, error) {
+		serviceMetrics.Inc("abac_pip_lookups_total")
+		ctx, cancel := context.WithTimeout(context.Background(), resolution.timeout)
+		defer cancel()
+		return resolution.resolver.ResolveAttributes(ctx, scope, id)
+	}
+	cache := resolution.users
+	if scope == "object" {
+		cache = resolution.objects
+	}
+
+	var resolved map[string]string
+	var err error
+	if strong || cache == nil {
+		resolved, err = load(id)
+	} else {
+		resolved, err = cache.get(id, load)
+	}
+	if err != nil {
+		// Failed lookups are not cached, so the next decision retries
+		serviceMetrics.Inc("abac_pip_errors_total")
+		log.Printf("Failed to resolve attributes of %s %s: %v", scope, id, err)
+		return stored
+	}
+
+	for name, value := range resolved {
+		if _, exists := stored[name]; !exists {
+			stored[name] = value
+		}
+	}
+	return stored
+}
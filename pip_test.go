@@ -0,0 +1,93 @@
+// Multi-Model Authorization Microservice - ABAC Attribute Resolver Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAttributeResolver_FillsMissingAttributes(t *testing.T) {
+	var lookups atomic.Int64
+	pip := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lookups.Add(1)
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/user/alice":
+			w.Write([]byte(`{"department": "eng", "level": 3, "tags": ["a", "b"], "manager": null}`))
+		case "/user/slow":
+			// Answer only after the client gave up
+			<-r.Context().Done()
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer pip.Close()
+
+	service := setupTestService(t)
+	service.attributeResolution = &attributeResolution{
+		resolver: &httpAttributeResolver{urlTemplate: pip.URL + "/{scope}/{id}", token: "s3cret", client: &http.Client{}},
+		timeout:  100 * time.Millisecond,
+		users:    newAttributeCache("resolved_user", 10, time.Minute),
+		objects:  newAttributeCache("resolved_object", 10, time.Minute),
+	}
+	service.db.Create(&UserAttribute{UserID: "alice", Attribute: "department", Value: "sales"})
+
+	// Stored attributes win over resolved ones
+	ctx := service.abacEvaluationContext("alice", "doc1", "read", nil, false)
+	if ctx.UserAttributes["department"] != "sales" || ctx.UserAttributes["level"] != "3" || ctx.UserAttributes["tags"] != "a,b" {
+		t.Errorf("Unexpected user attributes %v", ctx.UserAttributes)
+	}
+	if _, exists := ctx.UserAttributes["manager"]; exists {
+		t.Error("Null attributes should be skipped")
+	}
+	if len(ctx.ObjectAttributes) != 0 || lookups.Load() != 2 {
+		t.Errorf("Expected an empty lookup of the object, got %v after %d lookups", ctx.ObjectAttributes, lookups.Load())
+	}
+
+	// Resolved attributes are cached, except for strong freshness
+	service.abacEvaluationContext("alice", "doc1", "read", nil, false)
+	if lookups.Load() != 2 {
+		t.Errorf("Expected cached lookups, got %d", lookups.Load())
+	}
+	service.abacEvaluationContext("alice", "doc1", "read", nil, true)
+	if lookups.Load() != 4 {
+		t.Errorf("Expected strong freshness to bypass the cache, got %d lookups", lookups.Load())
+	}
+
+	// Lookups that time out are neither used nor cached
+	for i := 0; i < 2; i++ {
+		if ctx := service.abacEvaluationContext("slow", "doc1", "read", nil, false); len(ctx.UserAttributes) != 0 {
+			t.Errorf("Expected no attributes after a timeout, got %v", ctx.UserAttributes)
+		}
+	}
+	if errors := serviceMetrics.Get("abac_pip_errors_total"); errors < 2 {
+		t.Errorf("Expected the timeouts to be counted, got %d", errors)
+	}
+}
+
+func TestAttributeResolver_LDAPAttributeMap(t *testing.T) {
+	mapping, err := parseAttributeMapping("departmentNumber:department, title")
+	if err != nil || len(mapping) != 2 || mapping["departmentNumber"] != "department" || mapping["title"] != "title" {
+		t.Errorf("Unexpected mapping %v (%v)", mapping, err)
+	}
+	for _, invalid := range []string{"", ":department", "title:"} {
+		if _, err := parseAttributeMapping(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+
+	// Objects are not looked up in the directory
+	resolver := &ldapAttributeResolver{url: "ldap://127.0.0.1:1", timeout: time.Second}
+	if attributes, err := resolver.ResolveAttributes(t.Context(), "object", "doc1"); attributes != nil || err != nil {
+		t.Errorf("Expected no object lookup, got %v %v", attributes, err)
+	}
+}
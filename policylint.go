@@ -0,0 +1,296 @@
+// Multi-Model Authorization Microservice - ABAC Policy Analysis
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Kinds of problems found by the ABAC policy analysis
+const (
+	findingConflict        = "conflict"         // Same conditions, opposite effects
+	findingShadowed        = "shadowed"         // Never decides because a higher-priority policy always matches first
+	findingUnusedAttribute = "unused_attribute" // Stored or defined, but no policy reads it
+)
+
+// Severities of findings
+const (
+	severityError   = "error"   // Decisions depend on evaluation order or a policy has no effect
+	severityWarning = "warning" // Likely unintended, but decisions are well defined
+)
+
+// PolicyFinding is one problem found in the ABAC policy set
+type PolicyFinding struct {
+	Kind      string   `json:"kind"` // "conflict", "shadowed" or "unused_attribute"
+	Severity  string   `json:"severity"`
+	Policies  []string `json:"policies,omitempty"`  // Policies involved, the affected one first
+	Attribute string   `json:"attribute,omitempty"` // Unused attribute, as "user.<name>" or "object.<name>"
+	Message   string   `json:"message"`
+}
+
+// PolicyAnalysis is the report of the ABAC policy analysis
+type PolicyAnalysis struct {
+	Policies int             `json:"policies_analyzed"`
+	Findings []PolicyFinding `json:"findings"`
+	Summary  map[string]int  `json:"summary"` // Number of findings by kind
+}
+
+// conditionKey describes a condition, e.g. "user.department eq engineering", or
+// "object.ward eq $user.ward" for a comparison with another attribute
+func conditionKey(condition PolicyCondition) string {
+	value := condition.Value
+	if condition.ValueFrom != "" {
+		value = "$" + condition.ValueFrom
+	}
+	return fmt.Sprintf("%s.%s %s %s", condition.Type, condition.Field, strings.ToLower(condition.Operator), value)
+}
+
+// conjunction returns the conditions of a policy that only combines conditions with "and",
+// flattening nested "and" groups. ok is false for policies using "or" or an expression.
+func conjunction(policy *ABACPolicy) (conditions map[string]bool, ok bool) {
+	conditions = make(map[string]bool)
+	if policy.Expression != "" {
+		return nil, false
+	}
+	if policy.Group != nil {
+		return conditions, conjunctionOfGroup(policy.Group, conditions)
+	}
+	for i, condition := range policy.Conditions {
+		// The logic operator of the last condition joins nothing
+		if i < len(policy.Conditions)-1 && condition.LogicOp != "" && strings.ToLower(condition.LogicOp) != "and" {
+			return nil, false
+		}
+		conditions[conditionKey(condition)] = true
+	}
+	return conditions, true
+}
+
+// conjunctionOfGroup adds the conditions of an "and" group and its nested groups to conditions
+func conjunctionOfGroup(group *ConditionGroup, conditions map[string]bool) bool {
+	if group.Operator == "or" {
+		return false
+	}
+	for _, condition := range group.Conditions {
+		conditions[conditionKey(condition)] = true
+	}
+	for i := range group.Groups {
+		if !conjunctionOfGroup(&group.Groups[i], conditions) {
+			return false
+		}
+	}
+	return true
+}
+
+// conditionSignature describes when a policy matches, so that policies matching under the
+// same conditions have the same signature regardless of condition order where order does
+// not matter
+func conditionSignature(policy *ABACPolicy) string {
+	if policy.Expression != "" {
+		return "expression " + policy.Expression
+	}
+	if conditions, ok := conjunction(policy); ok {
+		return strings.Join(sortedSet(conditions), " and ")
+	}
+	if policy.Group != nil {
+		encoded, _ := json.Marshal(policy.Group.mapConditions(func(condition PolicyCondition) PolicyCondition {
+			return PolicyCondition{Type: condition.Type, Field: condition.Field, Operator: strings.ToLower(condition.Operator), Value: condition.Value, ValueFrom: condition.ValueFrom}
+		}))
+		return string(encoded)
+	}
+	var parts []string
+	for i, condition := range policy.Conditions {
+		parts = append(parts, conditionKey(condition))
+		if i < len(policy.Conditions)-1 {
+			parts = append(parts, strings.ToLower(condition.LogicOp))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// coversActions reports whether a policy applies to every action another one applies to
+func coversActions(policy, other *ABACPolicy) bool {
+	if len(policy.Actions) == 0 {
+		return true
+	}
+	if len(other.Actions) == 0 {
+		return false
+	}
+	for _, action := range other.Actions {
+		if !policy.AppliesToAction(action) {
+			return false
+		}
+	}
+	return true
+}
+
+// sharesActions reports whether two policies apply to at least one common action
+func sharesActions(policy, other *ABACPolicy) bool {
+	if len(policy.Actions) == 0 || len(other.Actions) == 0 {
+		return true
+	}
+	for _, action := range other.Actions {
+		if policy.AppliesToAction(action) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesWhenever reports whether policy matches every request other matches: its
+// conditions are a subset of other's when both only use "and", or the same otherwise
+func matchesWhenever(policy, other *ABACPolicy) bool {
+	conditions, ok := conjunction(policy)
+	otherConditions, otherOk := conjunction(other)
+	if !ok || !otherOk {
+		return conditionSignature(policy) == conditionSignature(other)
+	}
+	// Policies without conditions never match
+	if len(conditions) == 0 {
+		return false
+	}
+	for condition := range conditions {
+		if !otherConditions[condition] {
+			return false
+		}
+	}
+	return true
+}
+
+// AnalyzePolicies reports conflicting and shadowed policies among policies, and the user and
+// object attributes that are stored or defined but read by none of them. Policies only
+// interact with policies of the same tenant.
+func (s *AuthService) AnalyzePolicies(policies []*ABACPolicy) (*PolicyAnalysis, error) {
+	// Evaluation order, with ties broken by ID so the report is stable
+	sort.Slice(policies, func(i, j int) bool {
+		if policies[i].Priority != policies[j].Priority {
+			return policies[i].Priority > policies[j].Priority
+		}
+		return policies[i].ID < policies[j].ID
+	})
+
+	analysis := &PolicyAnalysis{Policies: len(policies), Findings: []PolicyFinding{}, Summary: map[string]int{}}
+	add := func(finding PolicyFinding) {
+		analysis.Findings = append(analysis.Findings, finding)
+		analysis.Summary[finding.Kind]++
+	}
+
+	shadowed := make(map[string]bool)
+	for i, higher := range policies {
+		for _, lower := range policies[i+1:] {
+			// A shadowed policy never decides, so nothing else about it matters
+			if higher.Tenant != lower.Tenant || shadowed[lower.ID] {
+				continue
+			}
+
+			if higher.Priority > lower.Priority && coversActions(higher, lower) && matchesWhenever(higher, lower) {
+				shadowed[lower.ID] = true
+				add(PolicyFinding{
+					Kind:     findingShadowed,
+					Severity: map[bool]string{true: severityError, false: severityWarning}[higher.Effect != lower.Effect],
+					Policies: []string{lower.ID, higher.ID},
+					Message: fmt.Sprintf("%s (priority %d, %s) never decides: %s (priority %d, %s) matches every request it matches",
+						lower.ID, lower.Priority, lower.Effect, higher.ID, higher.Priority, higher.Effect),
+				})
+				continue
+			}
+
+			if higher.Effect != lower.Effect && sharesActions(higher, lower) && len(higher.allConditions()) > 0 &&
+				conditionSignature(higher) == conditionSignature(lower) {
+				finding := PolicyFinding{Kind: findingConflict, Severity: severityWarning, Policies: []string{lower.ID, higher.ID}}
+				if higher.Priority == lower.Priority {
+					finding.Severity = severityError
+					finding.Message = fmt.Sprintf("%s (%s) and %s (%s) have the same conditions and priority %d, so which one decides is undefined",
+						lower.ID, lower.Effect, higher.ID, higher.Effect, lower.Priority)
+				} else {
+					finding.Message = fmt.Sprintf("%s (%s) has the same conditions as %s (%s), which decides first for the actions both apply to",
+						lower.ID, lower.Effect, higher.ID, higher.Effect)
+				}
+				add(finding)
+			}
+		}
+	}
+
+	unused, err := s.unusedAttributes(policies)
+	if err != nil {
+		return nil, err
+	}
+	for _, attribute := range unused {
+		add(PolicyFinding{
+			Kind:      findingUnusedAttribute,
+			Severity:  severityWarning,
+			Attribute: attribute,
+			Message:   fmt.Sprintf("%s is set or defined, but no policy condition reads it", attribute),
+		})
+	}
+	return analysis, nil
+}
+
+// unusedAttributes returns the user and object attributes that are stored or registered in
+// the schema but read by none of the policies, as sorted "<scope>.<name>" names
+func (s *AuthService) unusedAttributes(policies []*ABACPolicy) ([]string, error) {
+	known := make(map[string]bool)
+	var names []string
+	if err := s.db.Model(&UserAttribute{}).Distinct("attribute").Pluck("attribute", &names).Error; err != nil {
+		return nil, fmt.Errorf("failed to read user attributes: %v", err)
+	}
+	for _, name := range names {
+		known["user."+name] = true
+	}
+	names = nil
+	if err := s.db.Model(&ObjectAttribute{}).Distinct("attribute").Pluck("attribute", &names).Error; err != nil {
+		return nil, fmt.Errorf("failed to read object attributes: %v", err)
+	}
+	for _, name := range names {
+		known["object."+name] = true
+	}
+	var definitions []AttributeDefinition
+	if err := s.db.Find(&definitions).Error; err != nil {
+		return nil, fmt.Errorf("failed to read attribute definitions: %v", err)
+	}
+	for _, definition := range definitions {
+		known[definition.Scope+"."+definition.Name] = true
+	}
+
+	for _, policy := range policies {
+		for _, condition := range policy.allConditions() {
+			delete(known, condition.Type+"."+condition.Field)
+			delete(known, condition.ValueFrom)
+		}
+	}
+	return sortedSet(known), nil
+}
+
+// analyzePoliciesHandler reports conflicting and shadowed ABAC policies and unused attributes
+func (s *AuthService) analyzePoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	var policies []*ABACPolicy
+	for _, policy := range s.policyEngine.policyList() {
+		if scope.ownsPolicy(policy) {
+			policies = append(policies, policy)
+		}
+	}
+
+	analysis, err := s.AnalyzePolicies(policies)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to analyze policies: %v", err))
+		return
+	}
+	for i, finding := range analysis.Findings {
+		for j, id := range finding.Policies {
+			analysis.Findings[i].Policies[j] = scope.local(id)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(analysis)
+}
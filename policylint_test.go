@@ -0,0 +1,81 @@
+// Multi-Model Authorization Microservice - ABAC Policy Analysis Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPolicyAnalysis_Findings(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/abac/analysis", service.analyzePoliciesHandler).Methods("GET")
+
+	engineering := PolicyCondition{Type: "user", Field: "department", Operator: "eq", Value: "engineering"}
+	office := PolicyCondition{Type: "environment", Field: "location", Operator: "eq", Value: "office"}
+	for _, policy := range []*ABACPolicy{
+		// Same conditions and priority, opposite effects
+		{ID: "eng-allow", Name: "Engineering", Effect: "allow", Priority: 50, Conditions: []PolicyCondition{engineering, office}},
+		{ID: "eng-deny", Name: "No engineering", Effect: "deny", Priority: 50, Conditions: []PolicyCondition{office, engineering}},
+		// Matches a subset of what the broader policy above it matches
+		{ID: "eng-broad", Name: "All engineering", Effect: "deny", Priority: 90, Actions: []string{"read", "write"}, Conditions: []PolicyCondition{engineering}},
+		{ID: "eng-read", Name: "Engineering reads", Effect: "allow", Priority: 10, Actions: []string{"read"}, Conditions: []PolicyCondition{engineering, office}},
+		// Only overlaps for some requests
+		{ID: "eng-or", Name: "Engineering or office", Effect: "allow", Priority: 5, Conditions: []PolicyCondition{
+			{Type: "user", Field: "department", Operator: "eq", Value: "engineering", LogicOp: "or"}, office,
+		}},
+	} {
+		if err := service.policyEngine.AddPolicy(policy); err != nil {
+			t.Fatalf("Failed to add policy: %v", err)
+		}
+	}
+	service.saveUserAttribute("alice", "department", "engineering")
+	service.saveUserAttribute("alice", "clearance", "3")
+	service.db.Create(&AttributeDefinition{Scope: "object", Name: "classification", Type: "string"})
+
+	req, _ := http.NewRequest("GET", "/api/v1/abac/analysis", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+	var analysis PolicyAnalysis
+	json.Unmarshal(rr.Body.Bytes(), &analysis)
+
+	found := make(map[string]PolicyFinding)
+	for _, finding := range analysis.Findings {
+		key := finding.Kind + ":" + finding.Attribute
+		if len(finding.Policies) > 0 {
+			key += finding.Policies[0]
+		}
+		found[key] = finding
+	}
+	if conflict, ok := found["conflict:eng-deny"]; !ok || conflict.Severity != severityError || conflict.Policies[1] != "eng-allow" {
+		t.Errorf("Expected eng-allow and eng-deny to conflict, got %s", rr.Body.String())
+	}
+	if shadowed, ok := found["shadowed:eng-read"]; !ok || shadowed.Policies[1] != "eng-broad" || shadowed.Severity != severityError {
+		t.Errorf("Expected eng-read to be shadowed by eng-broad, got %s", rr.Body.String())
+	}
+	// eng-broad does not apply to every action, and eng-or also matches other requests
+	for _, id := range []string{"eng-allow", "eng-deny", "eng-or"} {
+		if _, ok := found["shadowed:"+id]; ok {
+			t.Errorf("Expected %s not to be shadowed, got %s", id, rr.Body.String())
+		}
+	}
+	for _, attribute := range []string{"user.clearance", "object.classification"} {
+		if _, ok := found["unused_attribute:"+attribute]; !ok {
+			t.Errorf("Expected %s to be unused, got %s", attribute, rr.Body.String())
+		}
+	}
+	if _, ok := found["unused_attribute:user.department"]; ok {
+		t.Error("Expected user.department to be used")
+	}
+	if analysis.Policies != 5 || analysis.Summary[findingConflict] != 1 || analysis.Summary[findingShadowed] != 1 || analysis.Summary[findingUnusedAttribute] != 2 {
+		t.Errorf("Unexpected summary: %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,68 @@
+// Multi-Model Authorization Microservice - Rule Provenance Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestPolicyMetadata_Provenance(t *testing.T) {
+	service := setupTestService(t)
+	router := mux.NewRouter()
+	service.registerAdminRoutes(router.PathPrefix("/api/v1").Subrouter())
+
+	send := func(method, path, body, actor string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		if actor != "" {
+			req.Header.Set("X-Actor", actor)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send("POST", "/api/v1/acl/policies", `{"subject": "alice", "object": "doc1", "action": "read"}`, "admin@example.com"); rr.Code != http.StatusCreated {
+		t.Fatalf("Failed to add ACL policy: %d", rr.Code)
+	}
+	send("POST", "/api/v1/users/bob/roles", `{"role": "editor"}`, "")
+
+	var listing struct {
+		Metadata map[string]PolicyMetadata `json:"metadata"`
+	}
+	rr := send("GET", "/api/v1/acl/policies", "", "")
+	json.Unmarshal(rr.Body.Bytes(), &listing)
+	meta, exists := listing.Metadata["alice:doc1:read"]
+	if !exists || meta.CreatedBy != "admin@example.com" || meta.CreatedAt.IsZero() || meta.UpdatedAt.IsZero() {
+		t.Errorf("Expected provenance for ACL policy, got %+v", listing.Metadata)
+	}
+
+	rr = send("GET", "/api/v1/users/bob/roles", "", "")
+	listing.Metadata = nil
+	json.Unmarshal(rr.Body.Bytes(), &listing)
+	if listing.Metadata["bob:editor"].CreatedBy != anonymousActor {
+		t.Errorf("Expected anonymous provenance for role assignment, got %+v", listing.Metadata)
+	}
+
+	export, err := service.ExportPolicies("")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(export.ACL) != 1 || export.ACL[0].Metadata == nil || export.ACL[0].Metadata.CreatedBy != "admin@example.com" {
+		t.Errorf("Expected provenance in export, got %+v", export.ACL)
+	}
+
+	send("DELETE", "/api/v1/acl/policies/alice:doc1:read", "", "")
+	var count int64
+	service.db.Model(&PolicyMetadata{}).Where("kind = ?", labelKindACL).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected metadata to be removed with the policy, got %d records", count)
+	}
+}
@@ -0,0 +1,131 @@
+// Multi-Model Authorization Microservice - Policy Test Runner
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// maxPolicyAssertions bounds the assertions evaluated by one test run
+const maxPolicyAssertions = 1000
+
+// PolicyAssertion is an authorization request together with its expected outcome
+type PolicyAssertion struct {
+	Name       string             `json:"name,omitempty"`
+	Model      AccessControlModel `json:"model"`
+	Subject    string             `json:"subject"`
+	Object     string             `json:"object"`
+	Action     string             `json:"action"`
+	Attributes RequestAttributes  `json:"attributes,omitempty"`
+	Expected   *bool              `json:"expected"` // true when access should be allowed
+}
+
+// PolicyTestRequest is a suite of assertions run against the current configuration
+type PolicyTestRequest struct {
+	Assertions []PolicyAssertion `json:"assertions"`
+	Tenant     string            `json:"tenant,omitempty"`
+}
+
+// PolicyAssertionResult is the outcome of a single assertion
+type PolicyAssertionResult struct {
+	PolicyAssertion
+	Allowed     bool                 `json:"allowed"`
+	Passed      bool                 `json:"passed"`
+	Error       string               `json:"error,omitempty"`
+	Explanation *DecisionExplanation `json:"explanation,omitempty"` // Why a failed assertion was decided as it was
+}
+
+// PolicyTestReport summarizes a test run
+type PolicyTestReport struct {
+	Success bool                    `json:"success"`
+	Total   int                     `json:"total"`
+	Passed  int                     `json:"passed"`
+	Failed  int                     `json:"failed"`
+	Results []PolicyAssertionResult `json:"results"`
+}
+
+// validate checks that an assertion is complete
+func (a PolicyAssertion) validate() error {
+	if a.Subject == "" || a.Object == "" || a.Action == "" {
+		return fmt.Errorf("subject, object, and action are required")
+	}
+	if a.Expected == nil {
+		return fmt.Errorf("expected is required")
+	}
+	return nil
+}
+
+// RunPolicyTests evaluates every assertion against the current policies, attributes and
+// relationships. Decisions are neither cached nor audited.
+func (s *AuthService) RunPolicyTests(scope tenantScope, assertions []PolicyAssertion) PolicyTestReport {
+	report := PolicyTestReport{Results: make([]PolicyAssertionResult, 0, len(assertions))}
+	for _, assertion := range assertions {
+		result := PolicyAssertionResult{PolicyAssertion: assertion}
+		model := assertion.Model
+		if model == "" {
+			model = ModelRBAC
+		}
+
+		// Read the database, so a change just deployed through another instance is seen
+		decision, err := s.evaluate(scope, model, scope.qualify(assertion.Subject), scope.qualify(assertion.Object), assertion.Action, assertion.Attributes, true, nil)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Allowed = decision.Allowed
+			result.Passed = decision.Allowed == *assertion.Expected
+		}
+
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+			if err == nil {
+				result.Explanation, _ = s.explainInTenant(scope, assertion.Model, assertion.Subject, assertion.Object, assertion.Action, assertion.Attributes, freshnessStrong, nil)
+			}
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.Total = len(assertions)
+	report.Success = report.Failed == 0
+	return report
+}
+
+// testPoliciesHandler runs a suite of assertions and reports which passed. The report is
+// returned with 200 even when assertions fail; success tells whether all of them passed.
+func (s *AuthService) testPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	var req PolicyTestRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if len(req.Assertions) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "assertions must not be empty")
+		return
+	}
+	if len(req.Assertions) > maxPolicyAssertions {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d assertions can be run at once", maxPolicyAssertions))
+		return
+	}
+	for i, assertion := range req.Assertions {
+		if err := assertion.validate(); err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("assertion %d: %v", i, err))
+			return
+		}
+	}
+
+	scope, ok := s.requestTenant(w, r, req.Tenant)
+	if !ok {
+		return
+	}
+
+	report := s.RunPolicyTests(scope, req.Assertions)
+	serviceMetrics.Inc("policy_test_runs_total")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
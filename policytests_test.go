@@ -0,0 +1,65 @@
+// Multi-Model Authorization Microservice - Policy Test Runner Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPolicyTests_ReportsPassAndFail(t *testing.T) {
+	service := setupTestService(t)
+	service.auditDecisions = true
+	addRule(service.rbacEnforcer, "editor", "/docs", "write", effectAllow)
+	service.rbacEnforcer.AddGroupingPolicy("alice", "editor")
+	service.relationshipGraph.AddRelationship("bob", "viewer", "doc1")
+
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/policies/test", service.testPoliciesHandler).Methods("POST")
+	run := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/v1/policies/test", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := run(`{"assertions": [
+		{"name": "editors write", "subject": "alice", "object": "/docs", "action": "write", "expected": true},
+		{"model": "rebac", "subject": "bob", "object": "doc1", "action": "read", "expected": true},
+		{"name": "viewers cannot delete", "model": "rebac", "subject": "bob", "object": "doc1", "action": "delete", "expected": true},
+		{"model": "unknown", "subject": "bob", "object": "doc1", "action": "read", "expected": false}
+	]}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var report PolicyTestReport
+	json.Unmarshal(rr.Body.Bytes(), &report)
+	if report.Success || report.Total != 4 || report.Passed != 2 || report.Failed != 2 {
+		t.Fatalf("Unexpected report %+v", report)
+	}
+	if failed := report.Results[2]; failed.Passed || failed.Allowed || failed.Name != "viewers cannot delete" || failed.Explanation == nil {
+		t.Errorf("Expected the delete assertion to fail with an explanation, got %+v", failed)
+	}
+	if errored := report.Results[3]; errored.Passed || errored.Error == "" {
+		t.Errorf("Expected the unknown model to be reported as an error, got %+v", errored)
+	}
+
+	// Test runs are not audited
+	var audited int64
+	service.db.Model(&DecisionRecord{}).Count(&audited)
+	if audited != 0 {
+		t.Errorf("Expected no audited decisions, got %d", audited)
+	}
+
+	for _, body := range []string{`{"assertions": []}`, `{"assertions": [{"subject": "alice", "object": "/docs", "action": "write"}]}`} {
+		if rr := run(body); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+}
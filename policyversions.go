@@ -0,0 +1,82 @@
+// Multi-Model Authorization Microservice - ABAC Policy Versions
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm/clause"
+)
+
+// policyETag returns the entity tag of a version of an ABAC policy
+func policyETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// matchesETag reports whether an If-Match header accepts the current entity tag of a
+// resource. An empty header accepts anything, "*" any existing resource. Weak tags compare
+// like strong ones, since versions change with every write.
+func matchesETag(header, etag string, exists bool) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return true
+	}
+	if header == "*" {
+		return exists
+	}
+	if !exists {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeVersionConflict answers an update based on an outdated version of a policy with 409,
+// naming the current version so the client can re-read the policy and retry
+func writeVersionConflict(w http.ResponseWriter, policyID string, current int, exists bool) {
+	message := fmt.Sprintf("Policy %s was changed by another update; version %d is current", policyID, current)
+	if !exists {
+		message = fmt.Sprintf("Policy %s no longer exists", policyID)
+	} else {
+		w.Header().Set("ETag", policyETag(current))
+	}
+	writeErrorResponse(w, http.StatusConflict, ErrorResponse{
+		Code:    errorCode(http.StatusConflict),
+		Message: message,
+		Details: map[string]int{"current_version": current},
+	})
+}
+
+// nextVersion returns the version a policy gets when it is written again: one past its
+// current version, or 1 when the engine does not hold it
+func (pe *PolicyEngine) nextVersion(policyID string) int {
+	if existing, exists := pe.getPolicy(policyID); exists {
+		return existing.Version + 1
+	}
+	return 1
+}
+
+// savePolicyVersion saves an updated policy as the version after existing, or as a new
+// policy when existing is nil. The update only applies while the database still holds the
+// version of existing, so of two admins editing the same version only the first can save;
+// false reports that the policy was changed in between.
+func (pe *PolicyEngine) savePolicyVersion(policy, existing *ABACPolicy) (bool, error) {
+	if existing == nil {
+		policy.Version = 1
+		return true, pe.db.Create(policy).Error
+	}
+
+	policy.Version = existing.Version + 1
+	policy.CreatedAt = existing.CreatedAt
+	result := pe.db.Model(policy).Select("*").Omit(clause.Associations).Where("version = ?", existing.Version).Updates(policy)
+	return result.RowsAffected > 0, result.Error
+}
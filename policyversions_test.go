@@ -0,0 +1,78 @@
+// Multi-Model Authorization Microservice - ABAC Policy Version Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPolicyVersions_IfMatch(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/abac/policies/{id}", service.getABACPolicyHandler).Methods("GET")
+	router.HandleFunc("/api/v1/abac/policies/{id}", service.updateABACPolicyHandler).Methods("PUT")
+
+	send := func(method, path, ifMatch, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	update := func(value string) string {
+		return `{"name": "Engineering", "effect": "allow", "conditions": [{"type": "user", "field": "department", "operator": "eq", "value": "` + value + `"}]}`
+	}
+
+	rr := send("POST", "/api/v1/abac/policies", "", `{"id": "eng", "name": "Engineering", "effect": "allow",
+		"conditions": [{"type": "user", "field": "department", "operator": "eq", "value": "engineering"}]}`)
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") != `"1"` {
+		t.Fatalf("Expected the new policy to be version 1, got %d %q", rr.Code, rr.Header().Get("ETag"))
+	}
+	if etag := send("GET", "/api/v1/abac/policies/eng", "", "").Header().Get("ETag"); etag != `"1"` {
+		t.Errorf("Expected ETag \"1\", got %q", etag)
+	}
+
+	// Two admins edit version 1; the second one is rejected
+	if rr := send("PUT", "/api/v1/abac/policies/eng", `"1"`, update("sales")); rr.Code != http.StatusOK || rr.Header().Get("ETag") != `"2"` {
+		t.Fatalf("Expected the first edit to save version 2, got %d %s", rr.Code, rr.Body.String())
+	}
+	rr = send("PUT", "/api/v1/abac/policies/eng", `"1"`, update("marketing"))
+	var conflict struct {
+		Details map[string]int `json:"details"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &conflict)
+	if rr.Code != http.StatusConflict || conflict.Details["current_version"] != 2 {
+		t.Fatalf("Expected a conflict naming version 2, got %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := send("PUT", "/api/v1/abac/policies/eng", "", `{"name": "Engineering", "effect": "allow", "version": 1}`); rr.Code != http.StatusConflict {
+		t.Errorf("Expected an outdated body version to conflict, got %d", rr.Code)
+	}
+	if policy := service.policyEngine.policies["eng"]; policy.Version != 2 || len(policy.Conditions) != 1 || policy.Conditions[0].Value != "sales" {
+		t.Errorf("Expected the first edit to be kept, got %+v", policy)
+	}
+
+	// Updates without a version still apply
+	if rr := send("PUT", "/api/v1/abac/policies/eng", "", update("support")); rr.Code != http.StatusOK || rr.Header().Get("ETag") != `"3"` {
+		t.Errorf("Expected an unconditional update to save version 3, got %d %q", rr.Code, rr.Header().Get("ETag"))
+	}
+	if rr := send("PUT", "/api/v1/abac/policies/missing", "*", update("sales")); rr.Code != http.StatusConflict {
+		t.Errorf("Expected If-Match: * to require an existing policy, got %d", rr.Code)
+	}
+
+	// A copy read before another instance's update cannot be saved
+	stale := *service.policyEngine.policies["eng"]
+	stale.Version = 2
+	policy := stale
+	if saved, err := service.policyEngine.savePolicyVersion(&policy, &stale); err != nil || saved {
+		t.Errorf("Expected saving over a stale version to fail, got %v %v", saved, err)
+	}
+}
@@ -0,0 +1,410 @@
+// Multi-Model Authorization Microservice - Tenant Quotas
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Dimensions of a tenant quota
+const (
+	quotaPolicies      = "policies"
+	quotaRelationships = "relationships"
+	quotaAttributes    = "attributes_per_subject"
+)
+
+// defaultQuotaSoftLimit is the percentage of a quota past which writes carry a warning
+const defaultQuotaSoftLimit = 80
+
+// quotaWarningHeader carries the warning of a write that took a tenant past its soft limit
+const quotaWarningHeader = "X-Quota-Warning"
+
+// TenantQuota bounds what one tenant may store. Zero leaves a dimension unlimited.
+type TenantQuota struct {
+	MaxPolicies             int `json:"max_policies"`               // ACL and RBAC rules, role assignments and ABAC policies
+	MaxRelationships        int `json:"max_relationships"`          // Unexpired relationship tuples
+	MaxAttributesPerSubject int `json:"max_attributes_per_subject"` // Attributes of one user or object
+}
+
+// TenantUsage is what a tenant stores, counted against its quota
+type TenantUsage struct {
+	Policies                int `json:"policies"`
+	Relationships           int `json:"relationships"`
+	MaxAttributesPerSubject int `json:"max_attributes_per_subject"` // Attributes of the tenant's user or object with the most
+}
+
+// tenantQuotas holds the quota of tenants without their own and the soft limit
+type tenantQuotas struct {
+	defaults  TenantQuota
+	softLimit int // Percentage of a quota past which writes carry a warning (0 disables warnings)
+}
+
+// validate checks that no limit is negative
+func (q TenantQuota) validate() error {
+	if q.MaxPolicies < 0 || q.MaxRelationships < 0 || q.MaxAttributesPerSubject < 0 {
+		return fmt.Errorf("quota limits must not be negative")
+	}
+	return nil
+}
+
+// limit returns the limit of a quota dimension
+func (q TenantQuota) limit(dimension string) int {
+	switch dimension {
+	case quotaPolicies:
+		return q.MaxPolicies
+	case quotaRelationships:
+		return q.MaxRelationships
+	case quotaAttributes:
+		return q.MaxAttributesPerSubject
+	}
+	return 0
+}
+
+// tenantQuotasFromEnv reads TENANT_MAX_POLICIES, TENANT_MAX_RELATIONSHIPS,
+// TENANT_MAX_ATTRIBUTES_PER_SUBJECT and TENANT_QUOTA_SOFT_LIMIT (a percentage)
+func tenantQuotasFromEnv() (tenantQuotas, error) {
+	quotas := tenantQuotas{softLimit: defaultQuotaSoftLimit}
+	limits := []struct {
+		env    string
+		target *int
+	}{
+		{"TENANT_MAX_POLICIES", &quotas.defaults.MaxPolicies},
+		{"TENANT_MAX_RELATIONSHIPS", &quotas.defaults.MaxRelationships},
+		{"TENANT_MAX_ATTRIBUTES_PER_SUBJECT", &quotas.defaults.MaxAttributesPerSubject},
+	}
+	for _, limit := range limits {
+		if value := os.Getenv(limit.env); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 0 {
+				return quotas, fmt.Errorf("invalid %s value: %s", limit.env, value)
+			}
+			*limit.target = parsed
+		}
+	}
+	if value := os.Getenv("TENANT_QUOTA_SOFT_LIMIT"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 0 || parsed > 100 {
+			return quotas, fmt.Errorf("invalid TENANT_QUOTA_SOFT_LIMIT value: %s", value)
+		}
+		quotas.softLimit = parsed
+	}
+	return quotas, nil
+}
+
+// effectiveQuota returns a tenant's own quota, which replaces the defaults as a whole, or
+// the defaults
+func (s *AuthService) effectiveQuota(tenant *Tenant) TenantQuota {
+	if tenant.Quota != nil {
+		return *tenant.Quota
+	}
+	return s.tenantQuotas.defaults
+}
+
+// scopeQuota returns the quota of a tenant scope. The global scope is unlimited.
+func (s *AuthService) scopeQuota(scope tenantScope) (TenantQuota, error) {
+	if scope.global() {
+		return TenantQuota{}, nil
+	}
+	var tenant Tenant
+	if err := s.db.First(&tenant, "name = ?", scope.tenant).Error; err != nil {
+		return TenantQuota{}, fmt.Errorf("failed to load tenant quota: %v", err)
+	}
+	return s.effectiveQuota(&tenant), nil
+}
+
+// tenantPolicyCount counts the ACL and RBAC rules, role assignments and ABAC policies of a tenant
+func (s *AuthService) tenantPolicyCount(scope tenantScope) (int, error) {
+	count := 0
+	for _, rules := range []func() ([][]string, error){s.aclEnforcer.GetPolicy, s.rbacEnforcer.GetPolicy, s.rbacEnforcer.GetGroupingPolicy} {
+		policies, err := rules()
+		if err != nil {
+			return 0, fmt.Errorf("failed to count policies: %v", err)
+		}
+		count += len(scope.ownedRules(policies, 2))
+	}
+
+	var abac int64
+	if err := s.db.Model(&ABACPolicy{}).Where("tenant = ?", scope.tenant).Count(&abac).Error; err != nil {
+		return 0, fmt.Errorf("failed to count ABAC policies: %v", err)
+	}
+	return count + int(abac), nil
+}
+
+// tenantRelationshipCount counts the unexpired tuples whose ends both belong to a tenant.
+// The database is counted, as a partitioned graph holds only part of the tuples in memory.
+func (s *AuthService) tenantRelationshipCount(scope tenantScope) (int, error) {
+	prefix := scope.tenant + "/"
+	var count int64
+	err := s.db.Model(&RelationshipRecord{}).
+		Where("substr(subject, 1, ?) = ? AND substr(object, 1, ?) = ?", len(prefix), prefix, len(prefix), prefix).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to count relationships: %v", err)
+	}
+	return int(count), nil
+}
+
+// attributeTable returns the model and ID column of user or object attributes
+func attributeTable(kind string) (interface{}, string) {
+	if kind == "object" {
+		return &ObjectAttribute{}, "object_id"
+	}
+	return &UserAttribute{}, "user_id"
+}
+
+// tenantMaxAttributeCount returns the attribute count of the tenant's user or object with
+// the most attributes
+func (s *AuthService) tenantMaxAttributeCount(scope tenantScope) (int, error) {
+	prefix := scope.tenant + "/"
+	most := 0
+	for _, kind := range []string{"user", "object"} {
+		table, column := attributeTable(kind)
+		var counts []int
+		err := s.db.Model(table).Select("count(*)").
+			Where(fmt.Sprintf("substr(%s, 1, ?) = ?", column), len(prefix), prefix).
+			Group(column).Order("count(*) DESC").Limit(1).Scan(&counts).Error
+		if err != nil {
+			return 0, fmt.Errorf("failed to count attributes: %v", err)
+		}
+		if len(counts) > 0 && counts[0] > most {
+			most = counts[0]
+		}
+	}
+	return most, nil
+}
+
+// attributeNamesOf returns the names of the attributes of a user or object
+func (s *AuthService) attributeNamesOf(kind, subject string) ([]string, error) {
+	table, column := attributeTable(kind)
+	var names []string
+	if err := s.db.Model(table).Where(column+" = ?", subject).Distinct().Pluck("attribute", &names).Error; err != nil {
+		return nil, fmt.Errorf("failed to count attributes: %v", err)
+	}
+	return names, nil
+}
+
+// newAttributeCount returns how many of the given attributes a user or object does not
+// have yet, along with how many it has
+func (s *AuthService) newAttributeCount(kind, subject string, attributes []string) (added, existing int, err error) {
+	names, err := s.attributeNamesOf(kind, subject)
+	if err != nil {
+		return 0, 0, err
+	}
+	held := make(map[string]bool, len(names))
+	for _, name := range names {
+		held[name] = true
+	}
+	for _, attribute := range attributes {
+		if !held[attribute] {
+			held[attribute] = true
+			added++
+		}
+	}
+	return added, len(names), nil
+}
+
+// allowQuota checks that adding requested items to usage stays within limit. A request
+// larger than the whole quota is rejected with 413, one the tenant has no room left for
+// with 429, and false is returned. Writes that take the tenant past the soft limit carry a
+// warning header.
+func (s *AuthService) allowQuota(w http.ResponseWriter, scope tenantScope, dimension string, limit, usage, requested int) bool {
+	if limit == 0 || requested == 0 {
+		return true
+	}
+	details := map[string]interface{}{"tenant": scope.tenant, "quota": dimension, "limit": limit, "usage": usage, "requested": requested}
+	if requested > limit {
+		serviceMetrics.Inc("quota_rejections_total")
+		writeErrorResponse(w, http.StatusRequestEntityTooLarge, ErrorResponse{
+			Code:    errorCode(http.StatusRequestEntityTooLarge),
+			Message: fmt.Sprintf("Request adds %d %s, more than the tenant quota of %d", requested, dimension, limit),
+			Details: details,
+		})
+		return false
+	}
+	if usage+requested > limit {
+		serviceMetrics.Inc("quota_rejections_total")
+		writeErrorResponse(w, http.StatusTooManyRequests, ErrorResponse{
+			Code:    errorCode(http.StatusTooManyRequests),
+			Message: fmt.Sprintf("Tenant %s quota exceeded: %d of %d %s used", scope.tenant, usage, limit, dimension),
+			Details: details,
+		})
+		return false
+	}
+	if s.tenantQuotas.softLimit > 0 && (usage+requested)*100 >= limit*s.tenantQuotas.softLimit {
+		serviceMetrics.Inc("quota_soft_limit_warnings_total")
+		w.Header().Set(quotaWarningHeader, fmt.Sprintf("%s: %d of %d used", dimension, usage+requested, limit))
+	}
+	return true
+}
+
+// checkQuota checks that a tenant has room for requested more policies or relationships,
+// writing the error response and returning false when it has not
+func (s *AuthService) checkQuota(w http.ResponseWriter, scope tenantScope, dimension string, requested int) bool {
+	if requested == 0 {
+		return true
+	}
+	quota, err := s.scopeQuota(scope)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	limit := quota.limit(dimension)
+	if limit == 0 {
+		return true
+	}
+
+	var usage int
+	switch dimension {
+	case quotaPolicies:
+		usage, err = s.tenantPolicyCount(scope)
+	case quotaRelationships:
+		usage, err = s.tenantRelationshipCount(scope)
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	return s.allowQuota(w, scope, dimension, limit, usage, requested)
+}
+
+// checkAttributeQuota checks that a user or object, given by its qualified name, has room
+// for the attributes it does not have yet
+func (s *AuthService) checkAttributeQuota(w http.ResponseWriter, scope tenantScope, kind, subject string, attributes []string) bool {
+	quota, err := s.scopeQuota(scope)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	if quota.MaxAttributesPerSubject == 0 {
+		return true
+	}
+	added, existing, err := s.newAttributeCount(kind, subject, attributes)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return false
+	}
+	return s.allowQuota(w, scope, quotaAttributes, quota.MaxAttributesPerSubject, existing, added)
+}
+
+// lookupTenant loads the tenant named in the path, writing 404 when it does not exist
+func (s *AuthService) lookupTenant(w http.ResponseWriter, r *http.Request) (*Tenant, bool) {
+	var tenant Tenant
+	if err := s.db.First(&tenant, "name = ?", mux.Vars(r)["name"]).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeJSONError(w, http.StatusNotFound, "Tenant not found")
+			return nil, false
+		}
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve tenant: %v", err))
+		return nil, false
+	}
+	return &tenant, true
+}
+
+// setTenantQuotaHandler gives a tenant its own quota, replacing the defaults
+func (s *AuthService) setTenantQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := s.lookupTenant(w, r)
+	if !ok {
+		return
+	}
+
+	var quota TenantQuota
+	if err := json.NewDecoder(r.Body).Decode(&quota); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+	if err := quota.validate(); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tenant.Quota = &quota
+	if err := s.db.Save(tenant).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save quota: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Tenant quota saved successfully",
+		"tenant":  tenant.Name,
+		"quota":   quota,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// deleteTenantQuotaHandler returns a tenant to the default quota
+func (s *AuthService) deleteTenantQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := s.lookupTenant(w, r)
+	if !ok {
+		return
+	}
+
+	tenant.Quota = nil
+	if err := s.db.Save(tenant).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to reset quota: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Tenant quota reset to the defaults",
+		"tenant":  tenant.Name,
+		"quota":   s.tenantQuotas.defaults,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getTenantUsageHandler reports what a tenant stores along with its quota
+func (s *AuthService) getTenantUsageHandler(w http.ResponseWriter, r *http.Request) {
+	tenant, ok := s.lookupTenant(w, r)
+	if !ok {
+		return
+	}
+	scope := tenantScope{tenant: tenant.Name}
+
+	var usage TenantUsage
+	var err error
+	if usage.Policies, err = s.tenantPolicyCount(scope); err == nil {
+		if usage.Relationships, err = s.tenantRelationshipCount(scope); err == nil {
+			usage.MaxAttributesPerSubject, err = s.tenantMaxAttributeCount(scope)
+		}
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	response := map[string]interface{}{
+		"tenant":     tenant.Name,
+		"usage":      usage,
+		"quota":      s.effectiveQuota(tenant),
+		"custom":     tenant.Quota != nil,
+		"soft_limit": s.tenantQuotas.softLimit,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// attributeNames returns the names of the attributes of a write
+func attributeNames(attributes map[string]string) []string {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,122 @@
+// Multi-Model Authorization Microservice - Tenant Quota Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestQuotas_EnforcedOnTenantWrites(t *testing.T) {
+	service := setupTenancyService(t)
+	// The default template gives each tenant 7 policies and 1 relationship
+	service.tenantQuotas = tenantQuotas{
+		defaults:  TenantQuota{MaxPolicies: 9, MaxRelationships: 3, MaxAttributesPerSubject: 2},
+		softLimit: 80,
+	}
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/acl/policies", service.addACLPolicyHandler).Methods("POST")
+	router.HandleFunc("/api/v1/users/{userId}/attributes", service.setUserAttributesHandler).Methods("PUT")
+	router.HandleFunc("/api/v1/relationships", service.addRelationshipHandler).Methods("POST")
+	router.HandleFunc("/api/v1/relationships/bulk", service.bulkAddRelationshipsHandler).Methods("POST")
+	router.HandleFunc("/api/v1/tenants/{name}/quota", service.setTenantQuotaHandler).Methods("PUT")
+	router.HandleFunc("/api/v1/tenants/{name}/usage", service.getTenantUsageHandler).Methods("GET")
+
+	send := func(method, url, tenant, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if tenant != "" {
+			req.Header.Set(tenantHeader, tenant)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send("POST", "/api/v1/acl/policies", "acme", `{"subject": "alice", "object": "report", "action": "read"}`)
+	if rr.Code != http.StatusCreated || rr.Header().Get(quotaWarningHeader) == "" {
+		t.Errorf("Expected the policy to be added with a soft limit warning, got %d %q", rr.Code, rr.Header().Get(quotaWarningHeader))
+	}
+	send("POST", "/api/v1/acl/policies", "acme", `{"subject": "alice", "object": "report", "action": "write"}`)
+	rr = send("POST", "/api/v1/acl/policies", "acme", `{"subject": "alice", "object": "report", "action": "delete"}`)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once the policy quota is used up, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var rejection ErrorResponse
+	json.Unmarshal(rr.Body.Bytes(), &rejection)
+	if details, _ := rejection.Details.(map[string]interface{}); details["quota"] != quotaPolicies || details["usage"] != float64(9) {
+		t.Errorf("Expected the rejection to detail the quota, got %+v", rejection)
+	}
+
+	// Other tenants and the global scope are not affected
+	if rr := send("POST", "/api/v1/acl/policies", "globex", `{"subject": "alice", "object": "report", "action": "delete"}`); rr.Code != http.StatusCreated {
+		t.Errorf("Expected globex to have room left, got %d", rr.Code)
+	}
+	if rr := send("POST", "/api/v1/acl/policies", "", `{"subject": "alice", "object": "report", "action": "delete"}`); rr.Code != http.StatusCreated {
+		t.Errorf("Expected the global scope to be unlimited, got %d", rr.Code)
+	}
+
+	// Batches larger than the whole quota can never succeed
+	bulk := `{"relationships": [{"subject": "a", "relationship": "viewer", "object": "d1"}, {"subject": "b", "relationship": "viewer", "object": "d1"}, {"subject": "c", "relationship": "viewer", "object": "d1"}, {"subject": "d", "relationship": "viewer", "object": "d1"}]}`
+	if rr := send("POST", "/api/v1/relationships/bulk", "acme", bulk); rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a batch over the relationship quota, got %d", rr.Code)
+	}
+	send("POST", "/api/v1/relationships", "acme", `{"subject": "alice", "relationship": "viewer", "object": "d1"}`)
+	send("POST", "/api/v1/relationships", "acme", `{"subject": "bob", "relationship": "viewer", "object": "d1"}`)
+	if rr := send("POST", "/api/v1/relationships", "acme", `{"subject": "carol", "relationship": "viewer", "object": "d1"}`); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 once the relationship quota is used up, got %d", rr.Code)
+	}
+
+	// Overwriting an attribute takes no room
+	if rr := send("PUT", "/api/v1/users/alice/attributes", "acme", `{"attributes": {"department": "finance", "level": "3"}}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected the attributes to be set, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := send("PUT", "/api/v1/users/alice/attributes", "acme", `{"attributes": {"level": "4"}}`); rr.Code != http.StatusOK {
+		t.Errorf("Expected an existing attribute to be overwritten, got %d", rr.Code)
+	}
+	if rr := send("PUT", "/api/v1/users/alice/attributes", "acme", `{"attributes": {"region": "emea"}}`); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 for an attribute over the quota, got %d", rr.Code)
+	}
+
+	// A tenant's own quota replaces the defaults
+	if rr := send("PUT", "/api/v1/tenants/acme/quota", "", `{"max_policies": 20}`); rr.Code != http.StatusOK {
+		t.Fatalf("Failed to set quota: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := send("POST", "/api/v1/acl/policies", "acme", `{"subject": "alice", "object": "report", "action": "delete"}`); rr.Code != http.StatusCreated {
+		t.Errorf("Expected the raised quota to apply, got %d", rr.Code)
+	}
+	if rr := send("PUT", "/api/v1/tenants/acme/quota", "", `{"max_policies": -1}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected a negative limit to be rejected, got %d", rr.Code)
+	}
+
+	var usage struct {
+		Usage  TenantUsage `json:"usage"`
+		Quota  TenantQuota `json:"quota"`
+		Custom bool        `json:"custom"`
+	}
+	rr = send("GET", "/api/v1/tenants/acme/usage", "", "")
+	json.Unmarshal(rr.Body.Bytes(), &usage)
+	expected := TenantUsage{Policies: 10, Relationships: 3, MaxAttributesPerSubject: 2}
+	if usage.Usage != expected || usage.Quota != (TenantQuota{MaxPolicies: 20}) || !usage.Custom {
+		t.Errorf("Unexpected usage: %s", rr.Body.String())
+	}
+}
+
+func TestQuotas_FromEnv(t *testing.T) {
+	t.Setenv("TENANT_MAX_POLICIES", "500")
+	t.Setenv("TENANT_MAX_RELATIONSHIPS", "10000")
+	quotas, err := tenantQuotasFromEnv()
+	if err != nil || quotas.defaults != (TenantQuota{MaxPolicies: 500, MaxRelationships: 10000}) || quotas.softLimit != defaultQuotaSoftLimit {
+		t.Errorf("Unexpected quotas %+v: %v", quotas, err)
+	}
+
+	t.Setenv("TENANT_QUOTA_SOFT_LIMIT", "120")
+	if _, err := tenantQuotasFromEnv(); err == nil {
+		t.Error("Expected a soft limit over 100 to be rejected")
+	}
+}
@@ -0,0 +1,183 @@
+// Multi-Model Authorization Microservice - Conditional RBAC Rules
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/casbin/casbin/v2"
+	"gorm.io/gorm"
+)
+
+// RuleCondition stores the ABAC condition an RBAC rule only matches under, e.g. doctors may
+// read patient records only when object.ward eq user.ward. Casbin rule tables only store the
+// tuple, so conditions are kept in a sidecar table keyed like labels.
+type RuleCondition struct {
+	ID          uint   `gorm:"primaryKey"`
+	Kind        string `gorm:"uniqueIndex:idx_rule_condition_rule"`
+	ResourceKey string `gorm:"uniqueIndex:idx_rule_condition_rule"`
+	Condition   string // JSON-encoded ConditionGroup
+}
+
+// ruleConditions holds the conditions of RBAC rules in memory, since the RBAC matcher looks
+// them up for every rule it matches
+type ruleConditions struct {
+	mu     sync.RWMutex
+	groups map[string]*ConditionGroup // By rule key
+}
+
+// get returns the condition of a rule, or nil for unconditional rules
+func (rc *ruleConditions) get(key string) *ConditionGroup {
+	if rc == nil {
+		return nil
+	}
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.groups[key]
+}
+
+// any reports whether some rule has a condition
+func (rc *ruleConditions) any() bool {
+	if rc == nil {
+		return false
+	}
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return len(rc.groups) > 0
+}
+
+// load replaces the conditions held in memory with those stored in the database
+func (rc *ruleConditions) load(db *gorm.DB) error {
+	var records []RuleCondition
+	if err := db.Where("kind = ?", labelKindRBAC).Find(&records).Error; err != nil {
+		return fmt.Errorf("failed to load rule conditions: %v", err)
+	}
+
+	groups := make(map[string]*ConditionGroup, len(records))
+	for _, record := range records {
+		var group ConditionGroup
+		if err := json.Unmarshal([]byte(record.Condition), &group); err != nil {
+			return fmt.Errorf("failed to decode condition of rule %s: %v", record.ResourceKey, err)
+		}
+		groups[record.ResourceKey] = &group
+	}
+
+	rc.mu.Lock()
+	rc.groups = groups
+	rc.mu.Unlock()
+	return nil
+}
+
+// registerRBACConditions loads the conditions of RBAC rules and registers the function the
+// RBAC matcher checks them with
+func (s *AuthService) registerRBACConditions(enforcer casbin.IEnforcer) error {
+	s.ruleConditions = &ruleConditions{groups: make(map[string]*ConditionGroup)}
+	if err := s.ruleConditions.load(s.db); err != nil {
+		return err
+	}
+	s.addRuleConditionFunction(enforcer)
+	return nil
+}
+
+// addRuleConditionFunction registers the function the RBAC matcher checks rule conditions with
+func (s *AuthService) addRuleConditionFunction(enforcer casbin.IEnforcer) {
+	enforcer.AddFunction("ruleCondition", func(args ...interface{}) (interface{}, error) {
+		if len(args) != 6 {
+			return false, fmt.Errorf("ruleCondition: expected 6 arguments, got %d", len(args))
+		}
+		values := make([]string, len(args))
+		for i, arg := range args {
+			values[i], _ = arg.(string)
+		}
+		return s.ruleConditionHolds(values[0], values[1], values[2], labelKey(values[3], values[4], values[5])), nil
+	})
+}
+
+// ruleConditionHolds reports whether the condition of the rule with the given key holds for
+// a request. Rules without a condition always match. Conditions see the stored and resolved
+// attributes of the requesting user and the requested object and the environment.
+func (s *AuthService) ruleConditionHolds(subject, object, action, key string) bool {
+	group := s.ruleConditions.get(key)
+	if group == nil {
+		return true
+	}
+	ctx := s.abacEvaluationContext(subject, object, action, nil, false)
+	return s.policyEngine.evaluateGroup(group, ctx)
+}
+
+// setRuleCondition stores the condition of an RBAC rule, or removes it when group is nil
+func (s *AuthService) setRuleCondition(key string, group *ConditionGroup) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("kind = ? AND resource_key = ?", labelKindRBAC, key).Delete(&RuleCondition{}).Error; err != nil {
+			return err
+		}
+		if group == nil {
+			return nil
+		}
+		encoded, err := json.Marshal(group)
+		if err != nil {
+			return err
+		}
+		return tx.Create(&RuleCondition{Kind: labelKindRBAC, ResourceKey: key, Condition: string(encoded)}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save rule condition: %v", err)
+	}
+
+	s.ruleConditions.mu.Lock()
+	if group == nil {
+		delete(s.ruleConditions.groups, key)
+	} else {
+		s.ruleConditions.groups[key] = group
+	}
+	s.ruleConditions.mu.Unlock()
+	return nil
+}
+
+// addRBACRule adds an RBAC rule like addRule, matching only while group holds when it is
+// not nil. The condition is saved first so the rule never matches without it; a rule that
+// already exists keeps its condition.
+func (s *AuthService) addRBACRule(subject, object, action, effect string, group *ConditionGroup) (bool, error) {
+	key := labelKey(subject, object, action)
+	previous := s.ruleConditions.get(key)
+	if err := s.setRuleCondition(key, group); err != nil {
+		return false, err
+	}
+
+	added, err := addRule(s.rbacEnforcer, subject, object, action, effect)
+	if err != nil || !added {
+		if restoreErr := s.setRuleCondition(key, previous); restoreErr != nil {
+			log.Printf("Failed to restore condition of RBAC rule %s: %v", key, restoreErr)
+		}
+	}
+	return added, err
+}
+
+// validateRuleCondition checks the condition of an RBAC rule against the attribute schema,
+// returning schema problems like validatePolicyConditions
+func (s *AuthService) validateRuleCondition(group *ConditionGroup) ([]string, error) {
+	if group == nil {
+		return nil, nil
+	}
+	var conditions []PolicyCondition
+	group.walk(func(condition *PolicyCondition) {
+		conditions = append(conditions, *condition)
+	})
+	return s.validatePolicyConditions(conditions)
+}
+
+// rulesConditions returns the conditions of the given rules that have one, keyed by rule key
+func (s *AuthService) rulesConditions(rules [][]string) map[string]*ConditionGroup {
+	conditions := make(map[string]*ConditionGroup)
+	for _, rule := range rules {
+		if group := s.ruleConditions.get(ruleKey(rule)); group != nil {
+			conditions[ruleKey(rule)] = group
+		}
+	}
+	return conditions
+}
@@ -0,0 +1,107 @@
+// Multi-Model Authorization Microservice - Conditional RBAC Rule Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRBACConditions_WardMatch(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/rbac/policies", service.addRBACPolicyHandler).Methods("POST")
+	router.HandleFunc("/api/v1/rbac/policies", service.getRBACPoliciesHandler).Methods("GET")
+	router.HandleFunc("/api/v1/rbac/policies/{id}", service.deleteRBACPolicyHandler).Methods("DELETE")
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Doctors read patient records of their own ward only
+	rr := send("POST", "/api/v1/rbac/policies", `{"subject": "doctor", "object": "record", "action": "read",
+		"condition": {"conditions": [{"type": "object", "field": "ward", "operator": "eq", "value_from": "user.ward"}]}}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", rr.Code, rr.Body.String())
+	}
+	addRule(service.rbacEnforcer, "doctor", "record", "write", effectAllow)
+	service.rbacEnforcer.AddRoleForUser("alice", "doctor")
+	service.rbacEnforcer.AddRoleForUser("bob", "doctor")
+	service.rbacEnforcer.AddNamedGroupingPolicy("g2", "patient-1", "record")
+	service.rbacEnforcer.AddNamedGroupingPolicy("g2", "patient-2", "record")
+	service.saveUserAttribute("alice", "ward", "cardiology")
+	service.saveObjectAttribute("patient-1", "ward", "cardiology")
+	service.saveObjectAttribute("patient-2", "ward", "oncology")
+
+	for _, tc := range []struct {
+		subject, object, action string
+		expected                bool
+	}{
+		{"alice", "patient-1", "read", true},
+		{"alice", "patient-2", "read", false},
+		// Missing attributes never match, even on both sides
+		{"bob", "patient-3", "read", false},
+		// Rules without a condition are unaffected
+		{"bob", "patient-2", "write", true},
+	} {
+		if allowed, err := service.Enforce(ModelRBAC, tc.subject, tc.object, tc.action, nil); err != nil || allowed != tc.expected {
+			t.Errorf("Expected %s %s %s to be %v, got %v %v", tc.subject, tc.action, tc.object, tc.expected, allowed, err)
+		}
+	}
+
+	// Re-adding the rule does not replace its condition
+	if rr := send("POST", "/api/v1/rbac/policies", `{"subject": "doctor", "object": "record", "action": "read"}`); rr.Code != http.StatusConflict {
+		t.Fatalf("Expected 409, got %d", rr.Code)
+	}
+	if allowed, _ := service.Enforce(ModelRBAC, "alice", "patient-2", "read", nil); allowed {
+		t.Error("Expected the existing rule to keep its condition")
+	}
+
+	var listing struct {
+		Conditions map[string]*ConditionGroup `json:"conditions"`
+	}
+	json.Unmarshal(send("GET", "/api/v1/rbac/policies", "").Body.Bytes(), &listing)
+	if group := listing.Conditions["doctor:record:read"]; group == nil || group.Conditions[0].ValueFrom != "user.ward" {
+		t.Errorf("Expected the condition to be listed, got %+v", listing.Conditions)
+	}
+
+	// Removing the rule drops its condition
+	send("DELETE", "/api/v1/rbac/policies/doctor:record:read", "")
+	if service.ruleConditions.any() {
+		t.Error("Expected the condition to be removed with its rule")
+	}
+}
+
+func TestRBACConditions_Validation(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/rbac/policies", service.addRBACPolicyHandler).Methods("POST")
+	router.HandleFunc("/api/v1/acl/policies", service.addACLPolicyHandler).Methods("POST")
+
+	for _, tc := range []struct{ path, body string }{
+		{"/api/v1/rbac/policies", `{"subject": "doctor", "object": "record", "action": "read",
+			"condition": {"conditions": [{"type": "object", "field": "ward", "operator": "eq", "value_from": "ward"}]}}`},
+		{"/api/v1/rbac/policies", `{"subject": "doctor", "object": "record", "action": "read", "condition": {"operator": "xor"}}`},
+		{"/api/v1/acl/policies", `{"subject": "alice", "object": "record", "action": "read",
+			"condition": {"conditions": [{"type": "user", "field": "ward", "operator": "eq", "value": "cardiology"}]}}`},
+	} {
+		req, _ := http.NewRequest("POST", tc.path, bytes.NewBufferString(tc.body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d %s", tc.body, rr.Code, rr.Body.String())
+		}
+	}
+	if service.ruleConditions.any() {
+		t.Error("Expected no condition to be stored")
+	}
+}
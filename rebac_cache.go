@@ -0,0 +1,120 @@
+// Multi-Model Authorization Microservice - ReBAC Check Cache
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultCheckCacheSize is the maximum number of cached ReBAC check results
+const defaultCheckCacheSize = 10000
+
+// checkCacheKey identifies a ReBAC permission check
+type checkCacheKey struct {
+	subject    string
+	object     string
+	permission string
+}
+
+// checkCacheEntry is a cached check result stamped with the graph revision it was computed at
+type checkCacheEntry struct {
+	revision uint64
+	allowed  bool
+	hops     []PathHop
+}
+
+// checkCache caches positive and negative ReBAC check results. Entries are only
+// served while the graph revision they were computed at is still current, so any
+// tuple write invalidates them without having to track which checks it affects.
+type checkCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[checkCacheKey]checkCacheEntry
+}
+
+// newCheckCache creates a check cache holding at most capacity results
+func newCheckCache(capacity int) *checkCache {
+	return &checkCache{
+		capacity: capacity,
+		entries:  make(map[checkCacheKey]checkCacheEntry),
+	}
+}
+
+// get returns the cached result for key if it was computed at the given revision
+func (cc *checkCache) get(key checkCacheKey, revision uint64) (checkCacheEntry, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	entry, exists := cc.entries[key]
+	if !exists || entry.revision != revision {
+		return checkCacheEntry{}, false
+	}
+	return entry, true
+}
+
+// put stores a result, dropping stale entries when the cache is full
+func (cc *checkCache) put(key checkCacheKey, entry checkCacheEntry) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if len(cc.entries) >= cc.capacity {
+		for k, e := range cc.entries {
+			if e.revision != entry.revision {
+				delete(cc.entries, k)
+			}
+		}
+		// Every entry is current; start over rather than tracking recency
+		if len(cc.entries) >= cc.capacity {
+			cc.entries = make(map[checkCacheKey]checkCacheEntry)
+		}
+	}
+	cc.entries[key] = entry
+}
+
+// size returns the number of cached results
+func (cc *checkCache) size() int {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return len(cc.entries)
+}
+
+// Revision returns the current graph revision, which increments on every tuple write
+func (rg *RelationshipGraph) Revision() uint64 {
+	return atomic.LoadUint64(&rg.revision)
+}
+
+// bumpRevision invalidates cached check results after the graph changed
+func (rg *RelationshipGraph) bumpRevision() {
+	atomic.AddUint64(&rg.revision, 1)
+}
+
+// rebacCheckCacheSizeFromEnv reads REBAC_CHECK_CACHE_SIZE
+func rebacCheckCacheSizeFromEnv() (int, error) {
+	sizeStr := os.Getenv("REBAC_CHECK_CACHE_SIZE")
+	if sizeStr == "" {
+		return defaultCheckCacheSize, nil
+	}
+	size, err := strconv.Atoi(sizeStr)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid REBAC_CHECK_CACHE_SIZE value: %s", sizeStr)
+	}
+	return size, nil
+}
+
+// SetCheckCacheSize configures the check result cache; a size of zero disables caching
+func (rg *RelationshipGraph) SetCheckCacheSize(size int) {
+	unlock := rg.writeLock()
+	defer unlock()
+
+	if size <= 0 {
+		rg.checkCache = nil
+		return
+	}
+	rg.checkCache = newCheckCache(size)
+}
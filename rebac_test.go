@@ -322,6 +322,139 @@ func TestReBAC_PathDiscovery(t *testing.T) {
 	}
 }
 
+func TestReBAC_StructuredPathHops(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	rg, err := NewRelationshipGraph(db)
+	if err != nil {
+		t.Fatalf("Failed to create relationship graph: %v", err)
+	}
+
+	rg.AddRelationship("alice", "owner", "root_folder")
+	rg.AddRelationship("root_folder", "parent", "document")
+
+	allowed, hops := rg.CheckReBACAccessHops("alice", "document", "read")
+	if !allowed {
+		t.Fatal("Alice should have access to document through hierarchical ownership")
+	}
+	expected := []PathHop{
+		{Subject: "alice", Relation: "owner", Object: "root_folder"},
+		{Subject: "root_folder", Relation: "parent", Object: "document"},
+	}
+	if len(hops) != len(expected) {
+		t.Fatalf("Expected %d hops, got %+v", len(expected), hops)
+	}
+	for i := range expected {
+		if hops[i] != expected[i] {
+			t.Errorf("Hop %d: expected %+v, got %+v", i, expected[i], hops[i])
+		}
+	}
+
+	_, path := rg.CheckReBACAccess("alice", "document", "read")
+	if path != formatPath("alice", hops) || path != "alice -[owner]-> root_folder -[parent]-> document" {
+		t.Errorf("Expected string path to match hops, got %q", path)
+	}
+
+	found, hops := rg.FindRelationshipHops("alice", "document", 5)
+	if !found || len(hops) != 2 || hops[1].Relation != "parent" {
+		t.Errorf("Unexpected path discovery hops: %+v", hops)
+	}
+}
+
+func TestReBAC_AllGrantPaths(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	rg, err := NewRelationshipGraph(db)
+	if err != nil {
+		t.Fatalf("Failed to create relationship graph: %v", err)
+	}
+
+	rg.AddRelationship("alice", "viewer", "doc")
+	rg.AddRelationship("alice", "member", "eng")
+	rg.AddRelationship("eng", "editor", "doc")
+	rg.AddRelationship("alice", "member", "sales")
+	rg.AddRelationship("sales", "viewer", "doc")
+	rg.AddRelationship("alice", "owner", "folder")
+	rg.AddRelationship("folder", "parent", "doc")
+	rg.AddRelationship("alice", "member", "guests")
+	rg.AddRelationship("guests", "member", "doc")
+
+	readPaths := rg.FindGrantPaths("alice", "doc", "read", 5, 10)
+	if len(readPaths) != 4 {
+		t.Fatalf("Expected 4 read grant paths, got %d: %+v", len(readPaths), readPaths)
+	}
+	if len(readPaths[0]) != 1 || readPaths[0][0].Relation != "viewer" {
+		t.Errorf("Expected the direct viewer grant first, got %+v", readPaths[0])
+	}
+	for i := 1; i < len(readPaths); i++ {
+		if len(readPaths[i]) < len(readPaths[i-1]) {
+			t.Errorf("Paths are not ranked by length: %+v", readPaths)
+		}
+	}
+
+	writePaths := rg.FindGrantPaths("alice", "doc", "edit", 5, 10)
+	if len(writePaths) != 2 {
+		t.Fatalf("Expected 2 write grant paths, got %+v", writePaths)
+	}
+	for _, hops := range writePaths {
+		if pathHasRelation(hops, "viewer") {
+			t.Errorf("Viewer path should not grant write: %+v", hops)
+		}
+	}
+
+	// Connectivity includes the non-granting guests path as well
+	allPaths := rg.FindAllRelationshipHops("alice", "doc", 5, 10, nil)
+	if len(allPaths) != 5 {
+		t.Errorf("Expected 5 connectivity paths, got %d", len(allPaths))
+	}
+
+	limited := rg.FindGrantPaths("alice", "doc", "read", 5, 2)
+	if len(limited) != 2 || len(limited[0]) != 1 {
+		t.Errorf("Expected the 2 shortest paths when limited, got %+v", limited)
+	}
+}
+
+func TestReBAC_PermissionPaths(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	rg, err := NewRelationshipGraph(db)
+	if err != nil {
+		t.Fatalf("Failed to create relationship graph: %v", err)
+	}
+
+	rg.AddRelationship("alice", "friend", "bob")
+	rg.AddRelationship("bob", "editor", "doc")
+	rg.AddRelationship("alice", "member", "eng")
+	rg.AddRelationship("eng", "editor", "doc")
+
+	// Connectivity happily goes through the friend
+	if found, hops := rg.FindRelationshipHops("alice", "doc", 5); !found || hops[0].Relation != "friend" {
+		t.Fatalf("Expected the friend path first, got %+v", hops)
+	}
+
+	writePaths := rg.FindPermissionPaths("alice", "doc", "write", 5, 10)
+	if len(writePaths) != 1 || formatPath("alice", writePaths[0]) != "alice -[member]-> eng -[editor]-> doc" {
+		t.Errorf("Expected only the team path to grant write, got %+v", writePaths)
+	}
+	if paths := rg.FindPermissionPaths("alice", "doc", "delete", 5, 10); len(paths) != 0 {
+		t.Errorf("Expected no path granting delete, got %+v", paths)
+	}
+
+	// Friends grant limited reads
+	if paths := rg.FindPermissionPaths("alice", "doc", "read_limited", 5, 10); len(paths) != 1 || paths[0][0].Relation != "friend" {
+		t.Errorf("Expected the friend path to grant read_limited, got %+v", paths)
+	}
+}
+
 func TestReBAC_DatabasePersistence(t *testing.T) {
 	db, err := setupTestDB()
 	if err != nil {
@@ -381,6 +514,189 @@ func TestReBAC_DatabasePersistence(t *testing.T) {
 	}
 }
 
+func TestReBAC_PartitionedGraph(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	// Seed tuples across several namespaces using a fully loaded graph
+	full, err := NewRelationshipGraph(db)
+	if err != nil {
+		t.Fatalf("Failed to create relationship graph: %v", err)
+	}
+	full.AddRelationship("alice", "member", "teams/engineering")
+	full.AddRelationship("teams/engineering", "group_access", "docs/design")
+	full.AddRelationship("bob", "owner", "wiki/home")
+	full.AddRelationship("wiki/home", "parent", "wiki/page")
+
+	// Only a single partition may be resident at a time
+	rg, err := NewPartitionedRelationshipGraph(db, 1, "")
+	if err != nil {
+		t.Fatalf("Failed to create partitioned graph: %v", err)
+	}
+
+	if stats := rg.PartitionStats(); !stats.Enabled || len(stats.Resident) != 0 {
+		t.Errorf("Expected no resident partitions before first check, got %+v", stats)
+	}
+
+	// Group access crosses the teams and docs partitions
+	allowed, path := rg.CheckReBACAccess("alice", "docs/design", "read")
+	if !allowed {
+		t.Error("Alice should have access to docs/design through her team")
+	}
+	t.Logf("Cross-partition path: %s", path)
+
+	allowed, _ = rg.CheckReBACAccess("bob", "wiki/page", "read")
+	if !allowed {
+		t.Error("Bob should have access to wiki/page through the parent wiki page")
+	}
+
+	stats := rg.PartitionStats()
+	if len(stats.Resident) > 1 {
+		t.Errorf("Expected at most 1 resident partition, got %v", stats.Resident)
+	}
+
+	// Writes to evicted partitions are visible once the partition is reloaded
+	if err := rg.AddRelationship("charlie", "viewer", "docs/design"); err != nil {
+		t.Fatalf("Failed to add relationship: %v", err)
+	}
+	allowed, _ = rg.CheckReBACAccess("charlie", "docs/design", "read")
+	if !allowed {
+		t.Error("Charlie should have viewer access to docs/design")
+	}
+
+	if err := rg.RemoveRelationship("charlie", "viewer", "docs/design"); err != nil {
+		t.Fatalf("Failed to remove relationship: %v", err)
+	}
+	allowed, _ = rg.CheckReBACAccess("charlie", "docs/design", "read")
+	if allowed {
+		t.Error("Charlie should not have access after the relationship was removed")
+	}
+
+	relationships, err := rg.ListRelationships("alice")
+	if err != nil {
+		t.Fatalf("Failed to list relationships: %v", err)
+	}
+	if len(relationships) != 1 {
+		t.Errorf("Expected 1 relationship for alice, got %d", len(relationships))
+	}
+}
+
+func TestReBAC_LazyGraph(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	full, err := NewRelationshipGraph(db)
+	if err != nil {
+		t.Fatalf("Failed to create relationship graph: %v", err)
+	}
+	full.AddRelationship("alice", "member", "engineering")
+	full.AddRelationship("engineering", "group_access", "design")
+	full.AddRelationship("bob", "owner", "wiki")
+	full.AddRelationship("wiki", "parent", "page")
+	full.AddRelationship("carol", "viewer", "unrelated")
+
+	// Only two objects may stay resident between requests
+	rg, err := NewLazyRelationshipGraph(db, 2)
+	if err != nil {
+		t.Fatalf("Failed to create lazy graph: %v", err)
+	}
+	if stats := rg.PartitionStats(); stats.Mode != "node" || len(stats.Resident) != 0 {
+		t.Errorf("Expected an empty node cache, got %+v", stats)
+	}
+
+	// Checks read through request views that load only the objects they reach
+	view := rg.forRequest()
+	if view == rg {
+		t.Fatal("Expected checks of a lazy graph to use a request view")
+	}
+	if allowed, _ := view.CheckReBACAccess("alice", "design", "read"); !allowed {
+		t.Error("Alice should have access to design through her team")
+	}
+	if allowed, _ := view.CheckReBACAccess("bob", "page", "read"); !allowed {
+		t.Error("Bob should have access to page through its parent")
+	}
+	for _, object := range view.PartitionStats().Resident {
+		if object == "unrelated" {
+			t.Error("Expected objects the checks did not reach to stay unloaded")
+		}
+	}
+	if stats := rg.PartitionStats(); len(stats.Resident) != 0 {
+		t.Errorf("Expected request views to leave the graph empty, got %v", stats.Resident)
+	}
+
+	// Writes are seen by the next request, and at most two objects stay resident
+	if err := rg.AddRelationship("dave", "viewer", "design"); err != nil {
+		t.Fatalf("Failed to add relationship: %v", err)
+	}
+	if allowed, _ := rg.forRequest().CheckReBACAccess("dave", "design", "read"); !allowed {
+		t.Error("Dave should have viewer access to design")
+	}
+	rg.CheckReBACAccess("alice", "design", "read")
+	rg.CheckReBACAccess("bob", "page", "read")
+	if stats := rg.PartitionStats(); len(stats.Resident) > 2 {
+		t.Errorf("Expected at most 2 resident objects, got %v", stats.Resident)
+	}
+}
+
+func TestReBAC_CheckCacheRevisionStamping(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	rg, err := NewRelationshipGraph(db)
+	if err != nil {
+		t.Fatalf("Failed to create relationship graph: %v", err)
+	}
+
+	rg.AddRelationship("alice", "member", "team")
+
+	// Negative result is cached
+	allowed, _ := rg.CheckReBACAccess("alice", "report", "read")
+	if allowed {
+		t.Fatal("Alice should not have access before the team is granted access")
+	}
+	if rg.checkCache.size() != 1 {
+		t.Errorf("Expected 1 cached result, got %d", rg.checkCache.size())
+	}
+
+	hits := serviceMetrics.Get("rebac_check_cache_hits_total")
+	rg.CheckReBACAccess("alice", "report", "view")
+	if serviceMetrics.Get("rebac_check_cache_hits_total") != hits+1 {
+		t.Error("Expected equivalent action to be served from cache")
+	}
+
+	// A tuple write bumps the revision and invalidates the cached negative result
+	revision := rg.Revision()
+	rg.AddRelationship("team", "group_access", "report")
+	if rg.Revision() == revision {
+		t.Error("Expected revision to change after a tuple write")
+	}
+
+	allowed, path := rg.CheckReBACAccess("alice", "report", "read")
+	if !allowed || path == "" {
+		t.Error("Alice should have access after the team was granted access")
+	}
+
+	// Removing the tuple invalidates the cached positive result
+	rg.RemoveRelationship("alice", "member", "team")
+	allowed, _ = rg.CheckReBACAccess("alice", "report", "read")
+	if allowed {
+		t.Error("Alice should lose access after leaving the team")
+	}
+
+	// Caching can be disabled
+	rg.SetCheckCacheSize(0)
+	allowed, _ = rg.CheckReBACAccess("team", "report", "read")
+	if !allowed {
+		t.Error("Team should have access with caching disabled")
+	}
+}
+
 func TestReBAC_PerformanceWithLargeDataset(t *testing.T) {
 	if testing.Short() {
 		t.Skip("Skipping performance test in short mode")
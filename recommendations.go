@@ -0,0 +1,269 @@
+// Multi-Model Authorization Microservice - Policy Recommendations
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// defaultRecommendationWindow is how far back the audit log is mined when no window is given
+const defaultRecommendationWindow = 30 * 24 * time.Hour
+
+// PolicySuggestion is a suggested policy change derived from audit history
+type PolicySuggestion struct {
+	Change     string    `json:"change"` // "add" or "remove"
+	Kind       string    `json:"kind"`   // "repeated_deny", "granted_after_denies", "unused_policy", "unused_role"
+	Model      string    `json:"model"`
+	Subject    string    `json:"subject"`
+	Object     string    `json:"object,omitempty"`
+	Action     string    `json:"action,omitempty"`
+	Reason     string    `json:"reason"`
+	Denies     int       `json:"denies,omitempty"`
+	Allows     int       `json:"allows,omitempty"`
+	LastSeenAt time.Time `json:"last_seen_at,omitempty"`
+}
+
+// RecommendationReport is a reviewable list of suggested policy additions and removals
+type RecommendationReport struct {
+	Since       time.Time          `json:"since"`
+	Until       time.Time          `json:"until"`
+	Decisions   int                `json:"decisions_analyzed"`
+	Suggestions []PolicySuggestion `json:"suggestions"`
+}
+
+// decisionStats aggregates audited outcomes for one (model, subject, object, action) request
+type decisionStats struct {
+	model, subject, object, action string
+	denies, allows                 int
+	denyStreak                     int // Denies seen before the first allow
+	grantedAfterDenies             bool
+	lastSeen                       time.Time
+}
+
+// patternExercised reports whether an ACL pattern rule's subject was allowed a request
+// matching its object and action patterns
+func patternExercised(stats map[string]*decisionStats, policy []string) bool {
+	for _, st := range stats {
+		if st.allows > 0 && st.model == string(ModelACL) && st.subject == policy[0] &&
+			aclPatternMatch(st.object, policy[1]) && aclPatternMatch(st.action, policy[2]) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecommendPolicies mines audited decisions between since and until for least-privilege suggestions.
+// Requests denied at least minDenies times are suggested for addition (or flagged when a manual
+// grant already followed), and ACL/RBAC policies and role assignments never exercised are
+// suggested for removal.
+func (s *AuthService) RecommendPolicies(since, until time.Time, minDenies int) (*RecommendationReport, error) {
+	if minDenies <= 0 {
+		minDenies = 3
+	}
+
+	var records []DecisionRecord
+	if err := s.db.Where("created_at >= ? AND created_at <= ?", since, until).Order("id").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load audited decisions: %v", err)
+	}
+
+	report := &RecommendationReport{
+		Since:       since,
+		Until:       until,
+		Decisions:   len(records),
+		Suggestions: []PolicySuggestion{},
+	}
+
+	// Aggregate outcomes per request in chronological order
+	stats := make(map[string]*decisionStats)
+	var order []string
+	allowedBySubject := make(map[string]bool)   // model:subject with any allowed decision
+	allowedRequests := make(map[string]bool)    // model:subject:object:action allowed at least once
+	allowedObjectAction := make(map[string]int) // model:object:action -> allowed decisions
+	for _, record := range records {
+		key := labelKey(record.Model, record.Subject, record.Object, record.Action)
+		st, exists := stats[key]
+		if !exists {
+			st = &decisionStats{model: record.Model, subject: record.Subject, object: record.Object, action: record.Action}
+			stats[key] = st
+			order = append(order, key)
+		}
+		st.lastSeen = record.CreatedAt
+
+		if record.Allowed {
+			st.allows++
+			if st.denies >= minDenies && st.allows == 1 {
+				st.grantedAfterDenies = true
+			}
+			allowedBySubject[labelKey(record.Model, record.Subject)] = true
+			allowedRequests[key] = true
+			allowedObjectAction[labelKey(record.Model, record.Object, record.Action)]++
+		} else {
+			st.denies++
+			if st.allows == 0 {
+				st.denyStreak++
+			}
+		}
+	}
+
+	for _, key := range order {
+		st := stats[key]
+		switch {
+		case st.grantedAfterDenies:
+			report.Suggestions = append(report.Suggestions, PolicySuggestion{
+				Change:     "add",
+				Kind:       "granted_after_denies",
+				Model:      st.model,
+				Subject:    st.subject,
+				Object:     st.object,
+				Action:     st.action,
+				Reason:     fmt.Sprintf("denied %d times before access was granted manually; consider formalizing the grant through a role or shared policy", st.denyStreak),
+				Denies:     st.denies,
+				Allows:     st.allows,
+				LastSeenAt: st.lastSeen,
+			})
+		case st.allows == 0 && st.denies >= minDenies:
+			report.Suggestions = append(report.Suggestions, PolicySuggestion{
+				Change:     "add",
+				Kind:       "repeated_deny",
+				Model:      st.model,
+				Subject:    st.subject,
+				Object:     st.object,
+				Action:     st.action,
+				Reason:     fmt.Sprintf("denied %d times and never allowed; grant access if the requests are legitimate", st.denies),
+				Denies:     st.denies,
+				LastSeenAt: st.lastSeen,
+			})
+		}
+	}
+
+	// ACL policies that never allowed a request. Deny rules never allow anything by design.
+	aclPolicies, err := s.aclEnforcer.GetPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ACL policies: %v", err)
+	}
+	for _, policy := range aclPolicies {
+		if len(policy) < 3 || ruleEffect(policy) == effectDeny || allowedRequests[labelKey(string(ModelACL), policy[0], policy[1], policy[2])] {
+			continue
+		}
+		if (isACLPattern(policy[1]) || isACLPattern(policy[2])) && patternExercised(stats, policy) {
+			continue
+		}
+		report.Suggestions = append(report.Suggestions, PolicySuggestion{
+			Change:  "remove",
+			Kind:    "unused_policy",
+			Model:   string(ModelACL),
+			Subject: policy[0],
+			Object:  policy[1],
+			Action:  policy[2],
+			Reason:  "policy did not allow any audited request in the analysis window",
+		})
+	}
+
+	// RBAC permissions no member of the role exercised
+	rbacPolicies, err := s.rbacEnforcer.GetPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RBAC policies: %v", err)
+	}
+	for _, policy := range rbacPolicies {
+		if len(policy) < 3 || ruleEffect(policy) == effectDeny {
+			continue
+		}
+		users, err := s.roleMembers(policy[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read role members: %v", err)
+		}
+		exercised := allowedRequests[labelKey(string(ModelRBAC), policy[0], policy[1], policy[2])]
+		for _, user := range users {
+			if allowedRequests[labelKey(string(ModelRBAC), user, policy[1], policy[2])] {
+				exercised = true
+				break
+			}
+		}
+		if exercised {
+			continue
+		}
+		report.Suggestions = append(report.Suggestions, PolicySuggestion{
+			Change:  "remove",
+			Kind:    "unused_policy",
+			Model:   string(ModelRBAC),
+			Subject: policy[0],
+			Object:  policy[1],
+			Action:  policy[2],
+			Reason:  "no member of the role was allowed this permission in the analysis window",
+		})
+	}
+
+	// Role assignments whose holder never had an allowed RBAC decision
+	assignments, err := s.rbacEnforcer.GetGroupingPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read role assignments: %v", err)
+	}
+	for _, assignment := range assignments {
+		if len(assignment) < 2 || allowedBySubject[labelKey(string(ModelRBAC), assignment[0])] {
+			continue
+		}
+		report.Suggestions = append(report.Suggestions, PolicySuggestion{
+			Change:  "remove",
+			Kind:    "unused_role",
+			Model:   string(ModelRBAC),
+			Subject: assignment[0],
+			Object:  assignment[1],
+			Reason:  fmt.Sprintf("user holds role %s but had no allowed RBAC request in the analysis window", assignment[1]),
+		})
+	}
+
+	sort.SliceStable(report.Suggestions, func(i, j int) bool {
+		return report.Suggestions[i].Change < report.Suggestions[j].Change
+	})
+
+	return report, nil
+}
+
+// getRecommendationsHandler produces policy suggestions from the audit log
+func (s *AuthService) getRecommendationsHandler(w http.ResponseWriter, r *http.Request) {
+	until := time.Now()
+	since := until.Add(-defaultRecommendationWindow)
+
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		parsed, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "since must be an RFC3339 timestamp")
+			return
+		}
+		since = parsed
+	}
+	if untilStr := r.URL.Query().Get("until"); untilStr != "" {
+		parsed, err := time.Parse(time.RFC3339, untilStr)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "until must be an RFC3339 timestamp")
+			return
+		}
+		until = parsed
+	}
+
+	minDenies := 3
+	if minStr := r.URL.Query().Get("min_denies"); minStr != "" {
+		parsed, err := strconv.Atoi(minStr)
+		if err != nil || parsed <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "min_denies must be a positive integer")
+			return
+		}
+		minDenies = parsed
+	}
+
+	report, err := s.RecommendPolicies(since, until, minDenies)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Recommendation error: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,73 @@
+// Multi-Model Authorization Microservice - Policy Recommendation Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecommendations_FromAuditHistory(t *testing.T) {
+	service := setupTestService(t)
+	service.auditDecisions = true
+
+	service.aclEnforcer.AddPolicy("alice", "report", "read", "allow")
+	service.aclEnforcer.AddPolicy("alice", "archive", "read", "allow") // never exercised
+	service.rbacEnforcer.AddPolicy("editor", "wiki", "write", "allow")
+	service.rbacEnforcer.AddGroupingPolicy("dave", "editor") // never exercised
+
+	check := func(model AccessControlModel, subject, object, action string) {
+		allowed, err := service.Enforce(model, subject, object, action, nil)
+		if err != nil {
+			t.Fatalf("Enforce failed: %v", err)
+		}
+		service.recordDecision("", model, subject, object, action, nil, allowed)
+	}
+
+	check(ModelACL, "alice", "report", "read")
+	for i := 0; i < 3; i++ {
+		check(ModelACL, "bob", "report", "read")
+		check(ModelACL, "carol", "report", "read")
+	}
+
+	// Carol is granted access manually after repeated denies
+	service.aclEnforcer.AddPolicy("carol", "report", "read", "allow")
+	check(ModelACL, "carol", "report", "read")
+
+	report, err := service.RecommendPolicies(time.Now().Add(-time.Hour), time.Now(), 3)
+	if err != nil {
+		t.Fatalf("RecommendPolicies failed: %v", err)
+	}
+	if report.Decisions != 8 {
+		t.Errorf("Expected 8 analyzed decisions, got %d", report.Decisions)
+	}
+
+	found := make(map[string]PolicySuggestion)
+	for _, suggestion := range report.Suggestions {
+		found[suggestion.Kind+":"+suggestion.Subject+":"+suggestion.Object] = suggestion
+	}
+
+	if s, ok := found["repeated_deny:bob:report"]; !ok || s.Change != "add" || s.Denies != 3 {
+		t.Errorf("Expected add suggestion for bob, got %+v", s)
+	}
+	if s, ok := found["granted_after_denies:carol:report"]; !ok || s.Change != "add" || s.Allows != 1 {
+		t.Errorf("Expected formalize suggestion for carol, got %+v", s)
+	}
+	if _, ok := found["unused_policy:alice:archive"]; !ok {
+		t.Error("Expected removal suggestion for unused ACL policy")
+	}
+	if _, ok := found["unused_policy:alice:report"]; ok {
+		t.Error("Exercised ACL policy should not be suggested for removal")
+	}
+	if _, ok := found["unused_policy:editor:wiki"]; !ok {
+		t.Error("Expected removal suggestion for unused RBAC permission")
+	}
+	if _, ok := found["unused_role:dave:editor"]; !ok {
+		t.Error("Expected removal suggestion for unused role assignment")
+	}
+	if report.Suggestions[0].Change != "add" {
+		t.Error("Expected additions to be listed before removals")
+	}
+}
@@ -0,0 +1,163 @@
+// Multi-Model Authorization Microservice - Read Replica
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultReplicaLagInterval is how often the replica's progress is polled
+const defaultReplicaLagInterval = time.Second
+
+// replicaDatabase is a read-only copy of the database serving the tuple loads of checks
+// and list queries, so they do not load the primary. Replication is asynchronous, so the
+// relationship write sequence, which is replicated along with the tuples, tells how far
+// the replica has caught up. Loads needing writes the replica has not applied yet go to
+// the primary.
+type replicaDatabase struct {
+	db       *gorm.DB
+	stmts    *gorm.DB      // Prepared-statement session for per-node loads
+	sequence atomic.Uint64 // Relationship write sequence the replica had applied when last polled
+	interval time.Duration
+}
+
+// ReplicaStats describes how far the replica lags behind the primary
+type ReplicaStats struct {
+	Sequence uint64 `json:"sequence"` // Relationship write sequence the replica has applied
+	Behind   uint64 `json:"behind"`   // Writes known to this instance the replica has not applied
+}
+
+// replicaFromEnv opens the replica named by DB_REPLICA_DSN, using DB_DRIVER like the
+// primary, and reads DB_REPLICA_LAG_INTERVAL. It returns nil when no replica is configured.
+func replicaFromEnv() (*replicaDatabase, error) {
+	dsn := os.Getenv("DB_REPLICA_DSN")
+	if dsn == "" {
+		return nil, nil
+	}
+
+	interval := defaultReplicaLagInterval
+	if intervalStr := os.Getenv("DB_REPLICA_LAG_INTERVAL"); intervalStr != "" {
+		parsed, err := time.ParseDuration(intervalStr)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid DB_REPLICA_LAG_INTERVAL value: %s", intervalStr)
+		}
+		interval = parsed
+	}
+
+	dialector, err := dialectorFor("DB_REPLICA_DSN", os.Getenv("DB_DRIVER"), dsn)
+	if err != nil {
+		return nil, err
+	}
+	db, err := openDatabase(dialector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s replica: %v", dialector.Name(), err)
+	}
+	return newReplicaDatabase(db, interval)
+}
+
+// newReplicaDatabase wraps a replica connection after reading how far it has caught up
+func newReplicaDatabase(db *gorm.DB, interval time.Duration) (*replicaDatabase, error) {
+	replica := &replicaDatabase{
+		db:       db,
+		stmts:    db.Session(&gorm.Session{PrepareStmt: true}),
+		interval: interval,
+	}
+	if err := replica.refresh(); err != nil {
+		return nil, err
+	}
+	return replica, nil
+}
+
+// refresh reads the write sequence the replica has applied
+func (replica *replicaDatabase) refresh() error {
+	sequence, err := currentSequence(replica.db)
+	if err != nil {
+		return fmt.Errorf("replica: %v", err)
+	}
+	replica.sequence.Store(sequence)
+	return nil
+}
+
+// start polls the replica's progress until the process exits. While polling fails the
+// last known sequence is kept, so reads fall back to the primary as writes go on.
+func (replica *replicaDatabase) start() {
+	go func() {
+		ticker := time.NewTicker(replica.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := replica.refresh(); err != nil {
+				serviceMetrics.Inc("db_replica_poll_failures_total")
+				log.Printf("Replica lag check failed: %v", err)
+			}
+		}
+	}()
+}
+
+// caughtUp reports whether the replica has applied every write up to sequence
+func (replica *replicaDatabase) caughtUp(sequence uint64) bool {
+	return replica.sequence.Load() >= sequence
+}
+
+// loadDB returns the database the graph loads tuples from: the replica once it has applied
+// every write the graph reflects, the primary otherwise
+func (rg *RelationshipGraph) loadDB() *gorm.DB {
+	prepared := rg.partitions != nil && rg.partitions.stmts != nil
+	if rg.replica != nil {
+		if rg.replica.caughtUp(atomic.LoadUint64(&rg.sequence)) {
+			serviceMetrics.Inc("db_replica_reads_total")
+			if prepared {
+				return rg.replica.stmts
+			}
+			return rg.replica.db
+		}
+		serviceMetrics.Inc("db_replica_lagging_reads_total")
+	}
+	if prepared {
+		return rg.partitions.stmts
+	}
+	return rg.db
+}
+
+// requireLatestWrite makes a view load tuples from the replica only once it has applied
+// the latest write committed to the primary, by any instance
+func (rg *RelationshipGraph) requireLatestWrite() {
+	if rg.replica == nil {
+		return
+	}
+	sequence, err := currentSequence(rg.db)
+	if err != nil {
+		log.Printf("Consistency check for replica reads failed, reading the primary: %v", err)
+		sequence = math.MaxUint64
+	}
+	atomic.StoreUint64(&rg.sequence, sequence)
+}
+
+// ReplicaStats reports the replica's progress, or nil without a replica
+func (rg *RelationshipGraph) ReplicaStats() *ReplicaStats {
+	if rg.replica == nil {
+		return nil
+	}
+	stats := &ReplicaStats{Sequence: rg.replica.sequence.Load()}
+	if known := atomic.LoadUint64(&rg.sequence); known > stats.Sequence {
+		stats.Behind = known - stats.Sequence
+	}
+	return stats
+}
+
+// readDB returns the database list queries that tolerate replication lag read, such as
+// the decision log
+func (s *AuthService) readDB() *gorm.DB {
+	if s.relationshipGraph != nil && s.relationshipGraph.replica != nil {
+		return s.relationshipGraph.replica.db
+	}
+	return s.db
+}
@@ -0,0 +1,79 @@
+// Multi-Model Authorization Microservice - Read Replica Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestReplica_LoadsTuplesOnceCaughtUp(t *testing.T) {
+	primary, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+	graph, err := NewLazyRelationshipGraph(primary, 100)
+	if err != nil {
+		t.Fatalf("Failed to create relationship graph: %v", err)
+	}
+
+	replicaDB, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup replica database: %v", err)
+	}
+	if err := migrateRelationshipSequence(replicaDB); err != nil {
+		t.Fatalf("Failed to migrate replica: %v", err)
+	}
+	// replicate copies a tuple and the write sequence to the replica, as replication would
+	replicate := func(subject, relationship, object string) {
+		replicaDB.Create(&RelationshipRecord{Subject: subject, Relationship: relationship, Object: object})
+		sequence, _ := currentSequence(primary)
+		replicaDB.Model(&RelationshipSequence{}).Where("id = ?", 1).UpdateColumn("value", sequence)
+	}
+
+	// Only the replica has alice's tuples, which tells which database a load read
+	replicate("alice", "viewer", "doc1")
+	replicate("alice", "viewer", "doc2")
+	replica, err := newReplicaDatabase(replicaDB, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to open replica: %v", err)
+	}
+	graph.replica = replica
+
+	if allowed, _ := graph.CheckReBACAccess("alice", "doc1", "read"); !allowed {
+		t.Fatal("Expected a caught-up replica to serve the load")
+	}
+
+	// A write the replica has not applied sends loads to the primary
+	graph.AddRelationship("bob", "viewer", "doc3")
+	if stats := graph.ReplicaStats(); stats.Behind != 1 {
+		t.Errorf("Expected the replica to be 1 write behind, got %+v", stats)
+	}
+	if allowed, _ := graph.CheckReBACAccess("bob", "doc3", "read"); !allowed {
+		t.Error("Expected the write to be visible while the replica lags")
+	}
+	if allowed, _ := graph.CheckReBACAccess("alice", "doc2", "read"); allowed {
+		t.Error("Expected the lagging replica not to be read")
+	}
+
+	replicate("bob", "viewer", "doc3")
+	if err := replica.refresh(); err != nil {
+		t.Fatalf("Failed to refresh replica: %v", err)
+	}
+	if stats := graph.ReplicaStats(); stats.Behind != 0 {
+		t.Errorf("Expected the replica to have caught up, got %+v", stats)
+	}
+
+	// Strong reads need the latest write on the primary, even by another instance
+	primary.Model(&RelationshipSequence{}).Where("id = ?", 1).UpdateColumn("value", gorm.Expr("value + 1"))
+	if allowed, _ := graph.freshSnapshot().CheckReBACAccess("alice", "doc2", "read"); allowed {
+		t.Error("Expected a strong read to skip a replica behind the primary")
+	}
+	if allowed, _ := graph.forRequest().CheckReBACAccess("alice", "doc2", "read"); !allowed {
+		t.Error("Expected a default read to use the replica caught up with this instance")
+	}
+}
@@ -0,0 +1,144 @@
+// Multi-Model Authorization Microservice - RBAC Resource Group Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestResourceGroups_PoliciesOnGroups(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/rbac/resources/{objectId}/groups", service.addResourceGroupHandler).Methods("POST")
+	router.HandleFunc("/api/v1/rbac/resources/{objectId}/groups", service.getResourceGroupsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/rbac/resources/{objectId}/groups/{groupId}", service.deleteResourceGroupHandler).Methods("DELETE")
+
+	service.rbacEnforcer.AddPolicy("editor", "project_docs", "write", "allow")
+	service.rbacEnforcer.AddPolicy("viewer", "all_docs", "read", "allow")
+	service.rbacEnforcer.AddPolicy("viewer", "secret", "read", "deny")
+	service.rbacEnforcer.AddRoleForUser("alice", "editor")
+	service.rbacEnforcer.AddRoleForUser("alice", "viewer")
+
+	addGroup := func(object, group string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"group": group})
+		req, _ := http.NewRequest("POST", "/api/v1/rbac/resources/"+object+"/groups", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// doc1 -> project_docs -> all_docs, and secret -> all_docs
+	for _, link := range [][2]string{{"doc1", "project_docs"}, {"project_docs", "all_docs"}, {"secret", "all_docs"}} {
+		if rr := addGroup(link[0], link[1]); rr.Code != http.StatusCreated {
+			t.Fatalf("Expected 201 for %s in %s, got %d: %s", link[0], link[1], rr.Code, rr.Body.String())
+		}
+	}
+	if rr := addGroup("doc1", "project_docs"); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for an existing group, got %d", rr.Code)
+	}
+	if rr := addGroup("all_docs", "doc1"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a cycle, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	// Rules on groups apply to their members; rules on the object itself still apply
+	for _, check := range []struct {
+		object, action string
+		expected       bool
+	}{
+		{"doc1", "write", true},
+		{"doc1", "read", true},
+		{"project_docs", "write", true},
+		{"doc2", "write", false},
+		{"secret", "read", false},
+	} {
+		allowed, err := service.Enforce(ModelRBAC, "alice", check.object, check.action, nil)
+		if err != nil || allowed != check.expected {
+			t.Errorf("alice %s %s: expected %v, got %v (%v)", check.action, check.object, check.expected, allowed, err)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/api/v1/rbac/resources/doc1/groups", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var groups struct {
+		Groups    []string                `json:"groups"`
+		Ancestors []ResourceGroupAncestor `json:"ancestors"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &groups)
+	if len(groups.Groups) != 1 || groups.Groups[0] != "project_docs" {
+		t.Errorf("Unexpected groups: %v", groups.Groups)
+	}
+	if len(groups.Ancestors) != 2 || groups.Ancestors[1] != (ResourceGroupAncestor{Group: "all_docs", Depth: 2}) {
+		t.Errorf("Unexpected ancestors: %v", groups.Ancestors)
+	}
+	members, _ := service.ResourceGroupMembers("all_docs")
+	if strings.Join(members, ",") != "doc1,project_docs,secret" {
+		t.Errorf("Unexpected members of all_docs: %v", members)
+	}
+
+	// Listing endpoints see through groups
+	permissions, err := service.UserPermissions(tenantScope{}, "alice", "doc")
+	if err != nil || len(permissions) != 2 || permissions[0].Object != "doc1" || permissions[1].Action != "write" {
+		t.Errorf("Expected alice to read and write doc1, got %+v (%v)", permissions, err)
+	}
+	subjects, err := service.ListSubjects("doc1", "write", []AccessControlModel{ModelRBAC})
+	if err != nil || len(subjects) != 1 || subjects[0].Subject != "alice" {
+		t.Errorf("Expected alice to be listed for doc1, got %+v (%v)", subjects, err)
+	}
+
+	export, err := service.ExportPolicies("")
+	if err != nil || len(export.RBACResourceGroups) != 3 {
+		t.Errorf("Expected the resource groups to be exported, got %+v (%v)", export.RBACResourceGroups, err)
+	}
+
+	req, _ = http.NewRequest("DELETE", "/api/v1/rbac/resources/doc1/groups/project_docs", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	if allowed, _ := service.Enforce(ModelRBAC, "alice", "doc1", "write", nil); allowed {
+		t.Error("Expected doc1 to lose the group's rules once removed")
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing group, got %d", rr.Code)
+	}
+}
+
+func TestResourceGroups_CasbinCSV(t *testing.T) {
+	bundle, err := parseCasbinCSV(strings.NewReader("p, editor, project_docs, write, allow\ng, alice, editor\ng2, doc1, project_docs\n"), ModelRBAC)
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(bundle.RBACResourceGroups) != 1 || strings.Join(bundle.RBACResourceGroups[0].Values, ",") != "doc1,project_docs" {
+		t.Fatalf("Expected a resource group, got %+v", bundle.RBACResourceGroups)
+	}
+
+	service := setupTestService(t)
+	if _, err := service.ImportPolicies(bundle, importModeMerge, "test"); err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if allowed, _ := service.Enforce(ModelRBAC, "alice", "doc1", "write", nil); !allowed {
+		t.Error("Expected the imported resource group to grant access")
+	}
+
+	var out bytes.Buffer
+	export, _ := service.ExportPolicies("")
+	if err := writeCasbinCSV(&out, export, ModelRBAC); err != nil || !strings.Contains(out.String(), "g2,doc1,project_docs") {
+		t.Errorf("Expected a g2 line, got %q (%v)", out.String(), err)
+	}
+
+	if err := (&PolicyExport{RBACResourceGroups: []LabeledRule{{Values: []string{"doc1", "doc1"}}}}).validate(); err == nil {
+		t.Error("Expected a self-membership to be rejected")
+	}
+}
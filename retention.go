@@ -0,0 +1,330 @@
+// Multi-Model Authorization Microservice - Decision Retention and Archival
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultRetentionInterval is how often expired decisions are archived and deleted
+	defaultRetentionInterval = time.Hour
+
+	// retentionBatchSize bounds how many decisions are archived per archive object
+	retentionBatchSize = 5000
+)
+
+// DecisionArchiver stores expired audit decisions before they are deleted, e.g. as files
+// on a mounted volume or objects in an object store. It returns where the batch was written.
+type DecisionArchiver interface {
+	Archive(name string, records []DecisionRecord) (string, error)
+}
+
+// encodeDecisionArchive renders records as gzip-compressed JSON lines
+func encodeDecisionArchive(records []DecisionRecord) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(writer)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fileArchiver writes each batch to a compressed file in a directory
+type fileArchiver struct {
+	dir string
+}
+
+// Archive writes the batch to <dir>/<name>
+func (fa *fileArchiver) Archive(name string, records []DecisionRecord) (string, error) {
+	data, err := encodeDecisionArchive(records)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode archive: %v", err)
+	}
+	if err := os.MkdirAll(fa.dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create archive directory: %v", err)
+	}
+
+	path := filepath.Join(fa.dir, name)
+	// Write to a temporary file first so a crash never leaves a truncated archive behind
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write archive: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", fmt.Errorf("failed to write archive: %v", err)
+	}
+	return path, nil
+}
+
+// httpArchiver uploads each batch with PUT <baseURL>/<name>, which works with object stores
+// and gateways that accept plain HTTP uploads
+type httpArchiver struct {
+	baseURL string
+	client  *http.Client
+}
+
+// newHTTPArchiver creates an archiver uploading below baseURL
+func newHTTPArchiver(baseURL string) *httpArchiver {
+	return &httpArchiver{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+// Archive uploads the batch and fails unless the store acknowledges it
+func (ha *httpArchiver) Archive(name string, records []DecisionRecord) (string, error) {
+	data, err := encodeDecisionArchive(records)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode archive: %v", err)
+	}
+
+	url := ha.baseURL + "/" + name
+	req, err := http.NewRequest(http.MethodPut, url, bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to create archive upload: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+
+	resp, err := ha.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload archive: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("archive upload rejected with status %d", resp.StatusCode)
+	}
+	return url, nil
+}
+
+// RetentionRun summarizes one pass over expired decisions
+type RetentionRun struct {
+	StartedAt  time.Time `json:"started_at"`
+	Cutoff     time.Time `json:"cutoff"`
+	Archived   int       `json:"archived"`
+	Deleted    int       `json:"deleted"`
+	Archives   []string  `json:"archives,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// decisionRetention keeps audited decisions for a hot period, then archives and deletes them
+type decisionRetention struct {
+	hot      time.Duration    // How long decisions stay in the database
+	interval time.Duration    // How often retention runs in the background
+	archiver DecisionArchiver // Where expired decisions go before deletion (nil deletes only)
+	target   string           // Human-readable archive destination
+
+	mu      sync.Mutex // Serializes runs and guards lastRun
+	lastRun *RetentionRun
+}
+
+// parseRetentionPeriod parses a Go duration or a number of days such as "90d"
+func parseRetentionPeriod(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// decisionRetentionFromEnv configures retention from AUDIT_RETENTION, AUDIT_RETENTION_INTERVAL
+// and AUDIT_ARCHIVE_DIR or AUDIT_ARCHIVE_URL. Retention is disabled (nil) unless
+// AUDIT_RETENTION is set.
+func decisionRetentionFromEnv() (*decisionRetention, error) {
+	hotStr := os.Getenv("AUDIT_RETENTION")
+	if hotStr == "" {
+		return nil, nil
+	}
+	hot, err := parseRetentionPeriod(hotStr)
+	if err != nil || hot <= 0 {
+		return nil, fmt.Errorf("invalid AUDIT_RETENTION value: %s", hotStr)
+	}
+
+	retention := &decisionRetention{hot: hot, interval: defaultRetentionInterval, target: "none (expired decisions are deleted)"}
+	if intervalStr := os.Getenv("AUDIT_RETENTION_INTERVAL"); intervalStr != "" {
+		interval, err := time.ParseDuration(intervalStr)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid AUDIT_RETENTION_INTERVAL value: %s", intervalStr)
+		}
+		retention.interval = interval
+	}
+
+	dir, url := os.Getenv("AUDIT_ARCHIVE_DIR"), os.Getenv("AUDIT_ARCHIVE_URL")
+	switch {
+	case dir != "" && url != "":
+		return nil, fmt.Errorf("AUDIT_ARCHIVE_DIR and AUDIT_ARCHIVE_URL are mutually exclusive")
+	case dir != "":
+		retention.archiver = &fileArchiver{dir: dir}
+		retention.target = dir
+	case url != "":
+		retention.archiver = newHTTPArchiver(url)
+		retention.target = url
+	}
+
+	return retention, nil
+}
+
+// ApplyDecisionRetention archives and deletes decisions older than the hot period as of now.
+// A batch is only deleted after it was archived, so a failing archiver never loses data.
+func (s *AuthService) ApplyDecisionRetention(now time.Time) (*RetentionRun, error) {
+	retention := s.decisionRetention
+	if retention == nil {
+		return nil, fmt.Errorf("decision retention is not configured")
+	}
+
+	retention.mu.Lock()
+	defer retention.mu.Unlock()
+
+	start := time.Now()
+	run := &RetentionRun{StartedAt: now, Cutoff: now.Add(-retention.hot)}
+	defer func() {
+		run.DurationMS = time.Since(start).Milliseconds()
+		retention.lastRun = run
+	}()
+
+	for {
+		var records []DecisionRecord
+		if err := s.db.Where("created_at < ?", run.Cutoff).Order("id").Limit(retentionBatchSize).Find(&records).Error; err != nil {
+			run.Error = fmt.Sprintf("failed to load expired decisions: %v", err)
+			return run, errors.New(run.Error)
+		}
+		if len(records) == 0 {
+			return run, nil
+		}
+
+		if retention.archiver != nil {
+			name := fmt.Sprintf("decisions-%d-%d.jsonl.gz", records[0].ID, records[len(records)-1].ID)
+			location, err := retention.archiver.Archive(name, records)
+			if err != nil {
+				run.Error = err.Error()
+				return run, err
+			}
+			run.Archived += len(records)
+			run.Archives = append(run.Archives, location)
+		}
+
+		ids := make([]uint, len(records))
+		for i, record := range records {
+			ids[i] = record.ID
+		}
+		result := s.db.Delete(&DecisionRecord{}, ids)
+		if result.Error != nil {
+			run.Error = fmt.Sprintf("failed to delete expired decisions: %v", result.Error)
+			return run, errors.New(run.Error)
+		}
+		run.Deleted += int(result.RowsAffected)
+	}
+}
+
+// startDecisionRetention applies retention in the background at the configured interval
+func (s *AuthService) startDecisionRetention() {
+	if s.decisionRetention == nil {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.decisionRetention.interval)
+		defer ticker.Stop()
+		for {
+			run, err := s.ApplyDecisionRetention(time.Now())
+			if err != nil {
+				log.Printf("Decision retention failed: %v", err)
+			} else if run.Deleted > 0 {
+				log.Printf("Decision retention archived %d and deleted %d decisions older than %s", run.Archived, run.Deleted, run.Cutoff.Format(time.RFC3339))
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// getRetentionStatusHandler reports the retention configuration, the size of the hot audit
+// log, how many decisions await archival and the outcome of the last run
+func (s *AuthService) getRetentionStatusHandler(w http.ResponseWriter, r *http.Request) {
+	var total int64
+	if err := s.db.Model(&DecisionRecord{}).Count(&total).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to count decisions: %v", err))
+		return
+	}
+
+	var oldest DecisionRecord
+	oldestResult := s.db.Order("created_at").Limit(1).Find(&oldest)
+	if oldestResult.Error != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read oldest decision: %v", oldestResult.Error))
+		return
+	}
+
+	response := map[string]interface{}{
+		"enabled":   s.decisionRetention != nil,
+		"decisions": total,
+	}
+	if oldestResult.RowsAffected > 0 {
+		response["oldest_decision"] = oldest.CreatedAt
+	}
+
+	if retention := s.decisionRetention; retention != nil {
+		cutoff := time.Now().Add(-retention.hot)
+		var expired int64
+		if err := s.db.Model(&DecisionRecord{}).Where("created_at < ?", cutoff).Count(&expired).Error; err != nil {
+			writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to count expired decisions: %v", err))
+			return
+		}
+
+		retention.mu.Lock()
+		lastRun := retention.lastRun
+		retention.mu.Unlock()
+
+		response["hot_period"] = retention.hot.String()
+		response["interval"] = retention.interval.String()
+		response["archive"] = retention.target
+		response["cutoff"] = cutoff
+		response["expired_pending"] = expired
+		response["last_run"] = lastRun
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// runRetentionHandler applies retention immediately instead of waiting for the next interval
+func (s *AuthService) runRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	if s.decisionRetention == nil {
+		writeJSONError(w, http.StatusConflict, "Decision retention is not configured (set AUDIT_RETENTION)")
+		return
+	}
+
+	run, err := s.ApplyDecisionRetention(time.Now())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(run)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(run)
+}
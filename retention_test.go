@@ -0,0 +1,167 @@
+// Multi-Model Authorization Microservice - Decision Retention Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+// failingArchiver rejects every batch
+type failingArchiver struct{}
+
+func (failingArchiver) Archive(name string, records []DecisionRecord) (string, error) {
+	return "", errors.New("object store unavailable")
+}
+
+// seedDecisions stores decisions created at the given ages
+func seedDecisions(t *testing.T, service *AuthService, now time.Time, ages ...time.Duration) {
+	for i, age := range ages {
+		record := DecisionRecord{Model: "rbac", Subject: "alice", Object: "doc", Action: "read", Allowed: i%2 == 0, CreatedAt: now.Add(-age)}
+		if err := service.db.Create(&record).Error; err != nil {
+			t.Fatalf("Failed to seed decision: %v", err)
+		}
+	}
+}
+
+func TestRetention_ArchivesThenDeletes(t *testing.T) {
+	service := setupTestService(t)
+	dir := t.TempDir()
+	service.decisionRetention = &decisionRetention{hot: 90 * 24 * time.Hour, archiver: &fileArchiver{dir: dir}, target: dir}
+
+	now := time.Now()
+	seedDecisions(t, service, now, 100*24*time.Hour, 95*24*time.Hour, 91*24*time.Hour, 10*24*time.Hour, time.Hour)
+
+	run, err := service.ApplyDecisionRetention(now)
+	if err != nil {
+		t.Fatalf("Retention failed: %v", err)
+	}
+	if run.Archived != 3 || run.Deleted != 3 || len(run.Archives) != 1 {
+		t.Fatalf("Unexpected run: %+v", run)
+	}
+
+	var remaining int64
+	service.db.Model(&DecisionRecord{}).Count(&remaining)
+	if remaining != 2 {
+		t.Errorf("Expected 2 hot decisions to remain, got %d", remaining)
+	}
+
+	// The archive holds the expired decisions as compressed JSON lines
+	file, err := os.Open(run.Archives[0])
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("Archive is not gzip-compressed: %v", err)
+	}
+	scanner := bufio.NewScanner(reader)
+	lines := 0
+	for scanner.Scan() {
+		var record DecisionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil || record.Subject != "alice" {
+			t.Errorf("Unexpected archived line %q: %v", scanner.Text(), err)
+		}
+		lines++
+	}
+	if lines != 3 {
+		t.Errorf("Expected 3 archived decisions, got %d", lines)
+	}
+
+	// A second run finds nothing left to expire
+	run, err = service.ApplyDecisionRetention(now)
+	if err != nil || run.Deleted != 0 {
+		t.Errorf("Expected an empty second run: %+v %v", run, err)
+	}
+}
+
+func TestRetention_FailingArchiverKeepsDecisions(t *testing.T) {
+	service := setupTestService(t)
+	service.decisionRetention = &decisionRetention{hot: time.Hour, archiver: failingArchiver{}}
+
+	now := time.Now()
+	seedDecisions(t, service, now, 2*time.Hour, 3*time.Hour)
+
+	run, err := service.ApplyDecisionRetention(now)
+	if err == nil || run.Deleted != 0 || run.Error == "" {
+		t.Fatalf("Expected the run to fail without deleting: %+v %v", run, err)
+	}
+
+	var remaining int64
+	service.db.Model(&DecisionRecord{}).Count(&remaining)
+	if remaining != 2 {
+		t.Errorf("Expected decisions to be kept when archiving fails, got %d", remaining)
+	}
+}
+
+func TestRetention_StatusAndRunHandlers(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/audit/retention", service.getRetentionStatusHandler).Methods("GET")
+	router.HandleFunc("/api/v1/audit/retention/run", service.runRetentionHandler).Methods("POST")
+
+	// Disabled retention still reports the audit log size
+	req, _ := http.NewRequest("POST", "/api/v1/audit/retention/run", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 when retention is disabled, got %d", rr.Code)
+	}
+
+	service.decisionRetention = &decisionRetention{hot: 24 * time.Hour, interval: time.Hour, target: "none (expired decisions are deleted)"}
+	seedDecisions(t, service, time.Now(), 48*time.Hour, time.Minute)
+
+	var status struct {
+		Enabled        bool          `json:"enabled"`
+		Decisions      int64         `json:"decisions"`
+		ExpiredPending int64         `json:"expired_pending"`
+		HotPeriod      string        `json:"hot_period"`
+		LastRun        *RetentionRun `json:"last_run"`
+	}
+	req, _ = http.NewRequest("GET", "/api/v1/audit/retention", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	json.Unmarshal(rr.Body.Bytes(), &status)
+	if !status.Enabled || status.Decisions != 2 || status.ExpiredPending != 1 || status.HotPeriod != "24h0m0s" || status.LastRun != nil {
+		t.Errorf("Unexpected status: %s", rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/api/v1/audit/retention/run", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var run RetentionRun
+	json.Unmarshal(rr.Body.Bytes(), &run)
+	if rr.Code != http.StatusOK || run.Deleted != 1 || run.Archived != 0 {
+		t.Errorf("Unexpected run response: %d %s", rr.Code, rr.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/audit/retention", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	status.LastRun = nil
+	json.Unmarshal(rr.Body.Bytes(), &status)
+	if status.Decisions != 1 || status.ExpiredPending != 0 || status.LastRun == nil || status.LastRun.Deleted != 1 {
+		t.Errorf("Unexpected status after run: %s", rr.Body.String())
+	}
+}
+
+func TestRetention_ParsePeriod(t *testing.T) {
+	for value, expected := range map[string]time.Duration{"90d": 90 * 24 * time.Hour, "36h": 36 * time.Hour} {
+		if parsed, err := parseRetentionPeriod(value); err != nil || parsed != expected {
+			t.Errorf("parseRetentionPeriod(%q) = %v, %v", value, parsed, err)
+		}
+	}
+	if _, err := parseRetentionPeriod("ninety"); err == nil {
+		t.Error("Expected an error for an invalid period")
+	}
+}
@@ -0,0 +1,105 @@
+// Multi-Model Authorization Microservice - ReBAC Reverse Index
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// reverseIndex is the secondary index from an object to the tuples pointing at it.
+// It is maintained together with the forward tuples by addToMemory/removeFromMemory,
+// so traversals that walk edges backwards no longer need stored reverse tuples.
+type reverseIndex struct {
+	incoming map[string]map[Relationship]int // Object to tuple multiplicity
+	size     int                             // Total tuples indexed, counting duplicates
+}
+
+// newReverseIndex creates an empty reverse index
+func newReverseIndex() *reverseIndex {
+	return &reverseIndex{incoming: make(map[string]map[Relationship]int)}
+}
+
+// add indexes a tuple under its object
+func (ri *reverseIndex) add(rel Relationship) {
+	tuples, exists := ri.incoming[rel.Object]
+	if !exists {
+		tuples = make(map[Relationship]int)
+		ri.incoming[rel.Object] = tuples
+	}
+	tuples[rel]++
+	ri.size++
+}
+
+// remove drops every copy of a tuple and frees the object's bucket once it is empty
+func (ri *reverseIndex) remove(rel Relationship) {
+	tuples, exists := ri.incoming[rel.Object]
+	if !exists {
+		return
+	}
+	ri.size -= tuples[rel]
+	delete(tuples, rel)
+	if len(tuples) == 0 {
+		delete(ri.incoming, rel.Object)
+	}
+}
+
+// incomingTo returns the distinct tuples whose object is object, in a stable order
+func (ri *reverseIndex) incomingTo(object string) []Relationship {
+	tuples := ri.incoming[object]
+	result := make([]Relationship, 0, len(tuples))
+	for rel := range tuples {
+		result = append(result, rel)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Subject != result[j].Subject {
+			return result[i].Subject < result[j].Subject
+		}
+		return result[i].Relationship < result[j].Relationship
+	})
+	return result
+}
+
+// verifyIndex checks that the reverse and adjacency indexes mirror the forward tuples exactly
+func (rg *RelationshipGraph) verifyIndex() error {
+	forward := make(map[Relationship]int)
+	total := 0
+	for key, rels := range rg.relationships {
+		if len(rels) == 0 {
+			return fmt.Errorf("empty forward bucket %q", key)
+		}
+		for _, rel := range rels {
+			if key != fmt.Sprintf("%s:%s", rel.Subject, rel.Relationship) {
+				return fmt.Errorf("tuple %+v stored under key %q", rel, key)
+			}
+			forward[rel]++
+			total++
+		}
+	}
+
+	if total != rg.reverse.size {
+		return fmt.Errorf("forward graph holds %d tuples but reverse index holds %d", total, rg.reverse.size)
+	}
+	for object, tuples := range rg.reverse.incoming {
+		if len(tuples) == 0 {
+			return fmt.Errorf("empty reverse bucket for %q", object)
+		}
+		for rel, count := range tuples {
+			if rel.Object != object {
+				return fmt.Errorf("tuple %+v indexed under object %q", rel, object)
+			}
+			if forward[rel] != count {
+				return fmt.Errorf("tuple %+v indexed %d times but stored %d times", rel, count, forward[rel])
+			}
+		}
+	}
+	for rel := range forward {
+		if _, exists := rg.reverse.incoming[rel.Object][rel]; !exists {
+			return fmt.Errorf("tuple %+v missing from reverse index", rel)
+		}
+	}
+
+	return rg.verifyAdjacency()
+}
@@ -0,0 +1,118 @@
+// Multi-Model Authorization Microservice - ReBAC Reverse Index Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func assertIndexConsistent(t *testing.T, rg *RelationshipGraph) {
+	t.Helper()
+	if err := rg.verifyIndex(); err != nil {
+		t.Fatalf("Reverse index invariant violated: %v", err)
+	}
+	for key := range rg.relationships {
+		if strings.Contains(key, ":reverse_") {
+			t.Fatalf("Reverse tuple stored in forward graph: %s", key)
+		}
+	}
+}
+
+func TestReverseIndex_MaintainedWithForwardTuples(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	rg, err := NewRelationshipGraph(db)
+	if err != nil {
+		t.Fatalf("Failed to create relationship graph: %v", err)
+	}
+
+	rg.AddRelationship("alice", "owner", "folder")
+	rg.AddRelationship("folder", "parent", "doc")
+	rg.AddRelationship("bob", "viewer", "doc")
+	assertIndexConsistent(t, rg)
+
+	incoming := rg.reverse.incomingTo("doc")
+	if len(incoming) != 2 || incoming[0].Subject != "bob" || incoming[1].Subject != "folder" {
+		t.Errorf("Unexpected incoming tuples for doc: %+v", incoming)
+	}
+
+	// Duplicate writes are removed together, as the database delete removes every row
+	rg.AddRelationship("bob", "viewer", "doc")
+	assertIndexConsistent(t, rg)
+	rg.RemoveRelationship("bob", "viewer", "doc")
+	assertIndexConsistent(t, rg)
+	if rg.HasDirectRelationship("bob", "viewer", "doc") {
+		t.Error("Duplicate tuple should be removed from memory")
+	}
+
+	rg.RemoveRelationship("folder", "parent", "doc")
+	assertIndexConsistent(t, rg)
+	if _, exists := rg.reverse.incoming["doc"]; exists {
+		t.Error("Empty reverse bucket should be freed")
+	}
+
+	if err := rg.loadFromDatabase(); err != nil {
+		t.Fatalf("Failed to reload graph: %v", err)
+	}
+	assertIndexConsistent(t, rg)
+	if rg.reverse.size != 1 {
+		t.Errorf("Expected 1 indexed tuple after reload, got %d", rg.reverse.size)
+	}
+}
+
+func TestReverseIndex_PartitionEviction(t *testing.T) {
+	db, err := setupTestDB()
+	if err != nil {
+		t.Fatalf("Failed to setup test database: %v", err)
+	}
+
+	rg, err := NewPartitionedRelationshipGraph(db, 1, "")
+	if err != nil {
+		t.Fatalf("Failed to create partitioned graph: %v", err)
+	}
+
+	rg.AddRelationship("alice", "owner", "billing/root")
+	rg.AddRelationship("billing/root", "parent", "billing/invoice")
+	rg.AddRelationship("bob", "viewer", "hr/payroll")
+
+	if allowed, _ := rg.CheckReBACAccess("alice", "billing/invoice", "read"); !allowed {
+		t.Error("Alice should read the invoice through the parent folder")
+	}
+	assertIndexConsistent(t, rg)
+
+	// Loading hr evicts billing, which must also leave the reverse index
+	rg.HasDirectRelationship("bob", "viewer", "hr/payroll")
+	assertIndexConsistent(t, rg)
+	if _, exists := rg.reverse.incoming["billing/invoice"]; exists {
+		t.Error("Evicted partition should be dropped from the reverse index")
+	}
+}
+
+func TestReverseIndex_DeleteHandler(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+
+	service.relationshipGraph.AddRelationship("alice", "owner", "doc")
+	service.relationshipGraph.AddRelationship("team", "parent", "doc")
+
+	req, _ := http.NewRequest("DELETE", "/api/v1/relationships/alice:owner:doc", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	assertIndexConsistent(t, service.relationshipGraph)
+	incoming := service.relationshipGraph.reverse.incomingTo("doc")
+	if len(incoming) != 1 || incoming[0].Subject != "team" {
+		t.Errorf("Expected only the parent tuple to remain indexed, got %+v", incoming)
+	}
+}
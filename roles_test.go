@@ -0,0 +1,105 @@
+// Multi-Model Authorization Microservice - RBAC Role Hierarchy Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoleHierarchy_InheritedPermissions(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/rbac/roles/{roleId}/parents", service.addRoleParentHandler).Methods("POST")
+	router.HandleFunc("/api/v1/rbac/roles/{roleId}/parents/{parentId}", service.deleteRoleParentHandler).Methods("DELETE")
+	router.HandleFunc("/api/v1/rbac/roles/{roleId}/hierarchy", service.getRoleHierarchyHandler).Methods("GET")
+
+	service.rbacEnforcer.AddPolicy("viewer", "reports", "read", "allow")
+	service.rbacEnforcer.AddPolicy("manager", "reports", "approve", "allow")
+	service.rbacEnforcer.AddPolicy("admin", "settings", "write", "allow")
+	service.rbacEnforcer.AddRoleForUser("alice", "admin")
+
+	addParent := func(role, parent string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"parent": parent})
+		req, _ := http.NewRequest("POST", "/api/v1/rbac/roles/"+role+"/parents", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// admin -> manager -> viewer
+	if rr := addParent("admin", "manager"); rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := addParent("manager", "viewer"); rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := addParent("admin", "manager"); rr.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for an existing parent, got %d", rr.Code)
+	}
+	if rr := addParent("viewer", "admin"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a cycle, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := addParent("admin", "admin"); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a self-parent, got %d", rr.Code)
+	}
+
+	// alice inherits through both levels
+	for _, check := range []struct {
+		object, action string
+		expected       bool
+	}{
+		{"settings", "write", true},
+		{"reports", "approve", true},
+		{"reports", "read", true},
+		{"reports", "delete", false},
+	} {
+		allowed, err := service.Enforce(ModelRBAC, "alice", check.object, check.action, nil)
+		if err != nil || allowed != check.expected {
+			t.Errorf("alice %s %s: expected %v, got %v (%v)", check.action, check.object, check.expected, allowed, err)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/api/v1/rbac/roles/admin/hierarchy", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var hierarchy struct {
+		Parents   []string       `json:"parents"`
+		Ancestors []RoleAncestor `json:"ancestors"`
+		Members   []string       `json:"members"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &hierarchy)
+	if len(hierarchy.Parents) != 1 || hierarchy.Parents[0] != "manager" {
+		t.Errorf("Unexpected parents: %v", hierarchy.Parents)
+	}
+	if len(hierarchy.Ancestors) != 2 || hierarchy.Ancestors[0] != (RoleAncestor{"manager", 1}) || hierarchy.Ancestors[1] != (RoleAncestor{"viewer", 2}) {
+		t.Errorf("Unexpected ancestors: %v", hierarchy.Ancestors)
+	}
+	if len(hierarchy.Members) != 1 || hierarchy.Members[0] != "alice" {
+		t.Errorf("Unexpected members: %v", hierarchy.Members)
+	}
+
+	// Removing a link stops inheritance
+	req, _ = http.NewRequest("DELETE", "/api/v1/rbac/roles/manager/parents/viewer", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if allowed, _ := service.Enforce(ModelRBAC, "alice", "reports", "read", nil); allowed {
+		t.Error("alice should no longer inherit viewer permissions")
+	}
+
+	req, _ = http.NewRequest("DELETE", "/api/v1/rbac/roles/manager/parents/viewer", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing link, got %d", rr.Code)
+	}
+}
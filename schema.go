@@ -0,0 +1,274 @@
+// Multi-Model Authorization Microservice - ABAC Attribute Schema
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// AttributeDefinition registers an ABAC attribute name with its type and allowed values.
+// Once a scope has at least one definition, writes and policy conditions in that scope
+// are checked against the registry.
+type AttributeDefinition struct {
+	Scope         string    `json:"scope" gorm:"primaryKey"` // "user" or "object"
+	Name          string    `json:"name" gorm:"primaryKey"`
+	Type          string    `json:"type"` // "string", "number", "boolean", "date" or "list"
+	AllowedValues []string  `json:"allowed_values,omitempty" gorm:"serializer:json"`
+	Description   string    `json:"description,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// validAttributeScopes and validAttributeTypes list what the registry accepts
+var (
+	validAttributeScopes = map[string]bool{"user": true, "object": true}
+	validAttributeTypes  = validValueTypes
+)
+
+// parseSchemaMode reads a schema mode setting such as ABAC_SCHEMA_MODE and reports whether
+// violations are rejected
+func parseSchemaMode(variable, mode string) (bool, error) {
+	switch mode {
+	case "", "warn":
+		return false, nil
+	case "enforce":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid %s value: %s", variable, mode)
+	}
+}
+
+// checkValue reports why value does not satisfy the definition, or "" when it does. Each
+// element of a list must be one of the allowed values.
+func (d AttributeDefinition) checkValue(value string) string {
+	if _, err := normalizeAttributeValue(d.Type, value); err != nil {
+		return fmt.Sprintf("%s attribute %q %v", d.Scope, d.Name, err)
+	}
+
+	if len(d.AllowedValues) > 0 {
+		values := []string{value}
+		if d.Type == valueTypeList {
+			values = splitListValue(value)
+		}
+		for _, v := range values {
+			if !slices.Contains(d.AllowedValues, v) {
+				return fmt.Sprintf("%s attribute %q does not allow %q (allowed: %s)", d.Scope, d.Name, v, strings.Join(d.AllowedValues, ", "))
+			}
+		}
+	}
+	return ""
+}
+
+// attributeDefinitions returns the registered definitions of a scope keyed by name
+func (s *AuthService) attributeDefinitions(scope string) (map[string]AttributeDefinition, error) {
+	var records []AttributeDefinition
+	if err := s.db.Where("scope = ?", scope).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load attribute schema: %v", err)
+	}
+
+	definitions := make(map[string]AttributeDefinition, len(records))
+	for _, record := range records {
+		definitions[record.Name] = record
+	}
+	return definitions, nil
+}
+
+// validateAttributes checks attribute writes of a scope against the registry
+func (s *AuthService) validateAttributes(scope string, attributes map[string]string) ([]string, error) {
+	definitions, err := s.attributeDefinitions(scope)
+	if err != nil || len(definitions) == 0 {
+		return nil, err
+	}
+
+	var problems []string
+	for name, value := range attributes {
+		definition, exists := definitions[name]
+		if !exists {
+			problems = append(problems, fmt.Sprintf("unknown %s attribute %q", scope, name))
+			continue
+		}
+		if problem := definition.checkValue(value); problem != "" {
+			problems = append(problems, problem)
+		}
+	}
+
+	sort.Strings(problems)
+	return problems, nil
+}
+
+// validatePolicyConditions checks that user and object conditions reference registered
+// attributes and compare them with values their definitions accept
+func (s *AuthService) validatePolicyConditions(conditions []PolicyCondition) ([]string, error) {
+	var problems []string
+	definitionsByScope := make(map[string]map[string]AttributeDefinition)
+
+	for _, condition := range conditions {
+		if !validAttributeScopes[condition.Type] {
+			continue
+		}
+
+		definitions, loaded := definitionsByScope[condition.Type]
+		if !loaded {
+			var err error
+			definitions, err = s.attributeDefinitions(condition.Type)
+			if err != nil {
+				return nil, err
+			}
+			definitionsByScope[condition.Type] = definitions
+		}
+		if len(definitions) == 0 {
+			continue
+		}
+
+		definition, exists := definitions[condition.Field]
+		if !exists {
+			problems = append(problems, fmt.Sprintf("condition references unknown %s attribute %q", condition.Type, condition.Field))
+			continue
+		}
+
+		// Comparisons with another attribute have no literal value to check
+		literal := condition.ValueFrom == ""
+		switch condition.Operator {
+		case "eq", "ne":
+			if problem := definition.checkValue(condition.Value); literal && problem != "" {
+				problems = append(problems, problem)
+			}
+		case "in":
+			for _, value := range strings.Split(condition.Value, ",") {
+				if problem := definition.checkValue(strings.TrimSpace(value)); literal && problem != "" {
+					problems = append(problems, problem)
+				}
+			}
+		case "gt", "gte", "lt", "lte":
+			if definition.Type != valueTypeNumber && definition.Type != valueTypeDate {
+				problems = append(problems, fmt.Sprintf("operator %q needs a number or date but %s attribute %q is a %s", condition.Operator, condition.Type, condition.Field, definition.Type))
+			}
+		case "before", "after":
+			if definition.Type != valueTypeDate {
+				problems = append(problems, fmt.Sprintf("operator %q needs a date but %s attribute %q is a %s", condition.Operator, condition.Type, condition.Field, definition.Type))
+			} else if _, err := parseDateValue(condition.Value); literal && err != nil {
+				problems = append(problems, fmt.Sprintf("operator %q compares %s attribute %q with %q, which is not a date", condition.Operator, condition.Type, condition.Field, condition.Value))
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// acceptSchemaProblems decides what to do with schema violations. In enforce mode it
+// rejects the request with 400 and returns false; otherwise the problems are logged and
+// returned to the caller as warnings.
+func (s *AuthService) acceptSchemaProblems(w http.ResponseWriter, problems []string) bool {
+	if len(problems) == 0 {
+		return true
+	}
+	if s.schemaEnforce {
+		writeJSONError(w, http.StatusBadRequest, "attribute schema violation: "+strings.Join(problems, "; "))
+		return false
+	}
+	log.Printf("Attribute schema warnings: %s", strings.Join(problems, "; "))
+	return true
+}
+
+// defineAttributeHandler registers or updates an attribute definition
+func (s *AuthService) defineAttributeHandler(w http.ResponseWriter, r *http.Request) {
+	var definition AttributeDefinition
+	if err := json.NewDecoder(r.Body).Decode(&definition); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	if !validAttributeScopes[definition.Scope] || definition.Name == "" {
+		writeJSONError(w, http.StatusBadRequest, "scope ('user' or 'object') and name are required")
+		return
+	}
+	if definition.Type == "" {
+		definition.Type = "string"
+	}
+	if !validAttributeTypes[definition.Type] {
+		writeJSONError(w, http.StatusBadRequest, "type must be 'string', 'number', 'boolean', 'date' or 'list'")
+		return
+	}
+	for _, value := range definition.AllowedValues {
+		check := AttributeDefinition{Scope: definition.Scope, Name: definition.Name, Type: definition.Type}
+		if problem := check.checkValue(value); problem != "" {
+			writeJSONError(w, http.StatusBadRequest, "invalid allowed value: "+problem)
+			return
+		}
+	}
+
+	if err := s.db.Save(&definition).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save attribute definition: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"message":    "Attribute definition saved successfully",
+		"definition": definition,
+		"model":      "abac",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getAttributeSchemaHandler lists registered attribute definitions, optionally for one scope
+func (s *AuthService) getAttributeSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	query := s.db.Order("scope, name")
+	if scope := r.URL.Query().Get("scope"); scope != "" {
+		query = query.Where("scope = ?", scope)
+	}
+
+	definitions := []AttributeDefinition{}
+	if err := query.Find(&definitions).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list attribute schema: %v", err))
+		return
+	}
+
+	mode := "warn"
+	if s.schemaEnforce {
+		mode = "enforce"
+	}
+
+	response := map[string]interface{}{
+		"definitions": definitions,
+		"count":       len(definitions),
+		"mode":        mode,
+		"model":       "abac",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// deleteAttributeDefinitionHandler removes an attribute definition
+func (s *AuthService) deleteAttributeDefinitionHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	result := s.db.Where("scope = ? AND name = ?", vars["scope"], vars["name"]).Delete(&AttributeDefinition{})
+	if result.Error != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete attribute definition: %v", result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		writeJSONError(w, http.StatusNotFound, "Attribute definition not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Attribute definition deleted successfully",
+		"model":   "abac",
+	})
+}
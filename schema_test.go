@@ -0,0 +1,148 @@
+// Multi-Model Authorization Microservice - ABAC Attribute Schema Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func setupSchemaRouter(service *AuthService) http.Handler {
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/abac/schema", service.defineAttributeHandler).Methods("POST")
+	router.HandleFunc("/api/v1/abac/schema", service.getAttributeSchemaHandler).Methods("GET")
+	router.HandleFunc("/api/v1/abac/schema/{scope}/{name}", service.deleteAttributeDefinitionHandler).Methods("DELETE")
+	return router
+}
+
+func schemaRequest(router http.Handler, method, url string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req, _ := http.NewRequest(method, url, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func defineDepartment(t *testing.T, router http.Handler) {
+	t.Helper()
+	rr := schemaRequest(router, "POST", "/api/v1/abac/schema", map[string]interface{}{
+		"scope":          "user",
+		"name":           "department",
+		"allowed_values": []string{"engineering", "sales"},
+		"description":    "Organizational department",
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Failed to define attribute: %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestSchema_DefinitionValidation(t *testing.T) {
+	service := setupTestService(t)
+	router := setupSchemaRouter(service)
+
+	rr := schemaRequest(router, "POST", "/api/v1/abac/schema", map[string]interface{}{"scope": "group", "name": "x"})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected unknown scope to be rejected, got %d", rr.Code)
+	}
+	rr = schemaRequest(router, "POST", "/api/v1/abac/schema", map[string]interface{}{
+		"scope": "user", "name": "level", "type": "number", "allowed_values": []string{"high"},
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected allowed value of the wrong type to be rejected, got %d", rr.Code)
+	}
+
+	defineDepartment(t, router)
+	rr = schemaRequest(router, "GET", "/api/v1/abac/schema?scope=user", nil)
+	var listed struct {
+		Definitions []AttributeDefinition `json:"definitions"`
+		Mode        string                `json:"mode"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &listed)
+	if len(listed.Definitions) != 1 || listed.Definitions[0].Type != "string" || len(listed.Definitions[0].AllowedValues) != 2 || listed.Mode != "warn" {
+		t.Errorf("Unexpected schema listing: %s", rr.Body.String())
+	}
+
+	rr = schemaRequest(router, "DELETE", "/api/v1/abac/schema/user/department", nil)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected definition to be deleted, got %d", rr.Code)
+	}
+	rr = schemaRequest(router, "DELETE", "/api/v1/abac/schema/user/department", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing definition, got %d", rr.Code)
+	}
+}
+
+func TestSchema_WarnModeAttributeWrites(t *testing.T) {
+	service := setupTestService(t)
+	router := setupSchemaRouter(service)
+
+	// Without definitions the registry stays out of the way
+	rr := schemaRequest(router, "PUT", "/api/v1/users/alice/attributes", map[string]interface{}{
+		"attributes": map[string]string{"departmnet": "engineering"},
+	})
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "warnings") {
+		t.Fatalf("Expected unchecked write without a schema, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	defineDepartment(t, router)
+	rr = schemaRequest(router, "PUT", "/api/v1/users/alice/attributes", map[string]interface{}{
+		"attributes": map[string]string{"departmnet": "engineering"},
+	})
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `unknown user attribute \"departmnet\"`) {
+		t.Errorf("Expected typo to be reported as a warning, got %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestSchema_EnforceMode(t *testing.T) {
+	service := setupTestService(t)
+	service.schemaEnforce = true
+	router := setupSchemaRouter(service)
+	defineDepartment(t, router)
+
+	rr := schemaRequest(router, "PUT", "/api/v1/users/alice/attributes", map[string]interface{}{
+		"attributes": map[string]string{"department": "marketing"},
+	})
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected disallowed value to be rejected, got %d", rr.Code)
+	}
+	if attrs, _ := service.getUserAttributesFromDB("alice"); len(attrs) != 0 {
+		t.Errorf("Rejected write should not be persisted, got %v", attrs)
+	}
+
+	rr = schemaRequest(router, "PUT", "/api/v1/users/alice/attributes", map[string]interface{}{
+		"attributes": map[string]string{"department": "sales"},
+	})
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected valid value to be accepted, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	policy := map[string]interface{}{
+		"id": "dept-read", "name": "Department read", "effect": "allow",
+		"conditions": []map[string]string{
+			{"type": "user", "field": "departmnet", "operator": "eq", "value": "sales"},
+		},
+	}
+	rr = schemaRequest(router, "POST", "/api/v1/abac/policies", policy)
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "departmnet") {
+		t.Errorf("Expected policy with unknown attribute to be rejected, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	policy["conditions"] = []map[string]string{
+		{"type": "user", "field": "department", "operator": "in", "value": "sales, engineering"},
+		{"type": "environment", "field": "hour", "operator": "gte", "value": "9"},
+	}
+	rr = schemaRequest(router, "POST", "/api/v1/abac/policies", policy)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected valid policy to be accepted, got %d %s", rr.Code, rr.Body.String())
+	}
+}
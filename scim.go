@@ -0,0 +1,1019 @@
+// Multi-Model Authorization Microservice - SCIM Provisioning
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// SCIM schema and message URNs (RFC 7643, RFC 7644)
+const (
+	scimUserSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimGroupSchema                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	scimListResponseSchema          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// scimMaxResults bounds the resources of one list response
+const scimMaxResults = 1000
+
+// scimFilterPattern matches the filters identity providers send to look resources up, e.g.
+// userName eq "alice"
+var scimFilterPattern = regexp.MustCompile(`(?i)^\s*(\w+)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// scimMemberFilterPattern matches the path removing one group member, e.g.
+// members[value eq "2819c223"]
+var scimMemberFilterPattern = regexp.MustCompile(`(?i)^\s*members\s*\[\s*value\s+eq\s+"([^"]*)"\s*\]\s*$`)
+
+// SCIMUser is a user provisioned by an identity provider. Its userName is the subject of
+// authorization checks.
+type SCIMUser struct {
+	ID          string `gorm:"primaryKey"`
+	UserName    string `gorm:"uniqueIndex"`
+	ExternalID  string `gorm:"index"`
+	DisplayName string
+	Active      bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// SCIMGroup is a group provisioned by an identity provider. Its displayName names the RBAC
+// role and the ReBAC group its active members are mapped to.
+type SCIMGroup struct {
+	ID          string `gorm:"primaryKey"`
+	DisplayName string `gorm:"uniqueIndex"`
+	ExternalID  string `gorm:"index"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// SCIMMember puts a provisioned user in a provisioned group
+type SCIMMember struct {
+	GroupID string `gorm:"primaryKey"`
+	UserID  string `gorm:"primaryKey;index"`
+}
+
+// SCIMMeta describes a SCIM resource
+type SCIMMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+// SCIMReference refers to a group of a user or a member of a group by SCIM ID
+type SCIMReference struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// SCIMUserResource is the SCIM representation of a user
+type SCIMUserResource struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	ExternalID  string          `json:"externalId,omitempty"`
+	UserName    string          `json:"userName"`
+	DisplayName string          `json:"displayName,omitempty"`
+	Active      *bool           `json:"active,omitempty"` // True when omitted
+	Groups      []SCIMReference `json:"groups,omitempty"` // Read-only, changed through the groups
+	Meta        *SCIMMeta       `json:"meta,omitempty"`
+}
+
+// SCIMGroupResource is the SCIM representation of a group
+type SCIMGroupResource struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	ExternalID  string          `json:"externalId,omitempty"`
+	DisplayName string          `json:"displayName"`
+	Members     []SCIMReference `json:"members"`
+	Meta        *SCIMMeta       `json:"meta,omitempty"`
+}
+
+// SCIMListResponse is a page of SCIM resources
+type SCIMListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// SCIMPatchRequest changes parts of a SCIM resource
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+// SCIMPatchOperation is one change of a SCIM PATCH request
+type SCIMPatchOperation struct {
+	Op    string          `json:"op"` // "add", "remove" or "replace"
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// SCIMError is the SCIM error response
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// scimRequestError is a SCIM request the service cannot apply, answered with its status
+type scimRequestError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e *scimRequestError) Error() string {
+	return e.detail
+}
+
+// scimInvalid reports an invalid value in a SCIM request
+func scimInvalid(format string, args ...interface{}) error {
+	return &scimRequestError{status: http.StatusBadRequest, scimType: "invalidValue", detail: fmt.Sprintf(format, args...)}
+}
+
+// writeSCIM writes a SCIM response body
+func writeSCIM(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/scim+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeSCIMError writes a SCIM error response, using the status of a *scimRequestError and
+// 500 for other errors
+func writeSCIMError(w http.ResponseWriter, err error) {
+	status, scimType := http.StatusInternalServerError, ""
+	var requestErr *scimRequestError
+	if errors.As(err, &requestErr) {
+		status, scimType = requestErr.status, requestErr.scimType
+	}
+	writeSCIM(w, status, SCIMError{Schemas: []string{scimErrorSchema}, Status: strconv.Itoa(status), ScimType: scimType, Detail: err.Error()})
+}
+
+// scimLocation returns the URL of a SCIM resource
+func scimLocation(r *http.Request, resourceType, id string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/api/v1/scim/v2/%s/%s", scheme, r.Host, resourceType, id)
+}
+
+// scimName checks a userName or displayName that names a subject, role or group
+func scimName(field, value string) error {
+	var v validator
+	v.identifier(field, value)
+	if err := v.err(); err != nil {
+		return scimInvalid("%v", err)
+	}
+	return nil
+}
+
+// grantSCIMMembership maps a group membership to the group's role and a member tuple
+func (s *AuthService) grantSCIMMembership(user, group, actor string) error {
+	if _, err := s.assignGroupRole(user, group, actor); err != nil {
+		return err
+	}
+	_, err := s.addGroupMemberTuple(user, group, actor)
+	return err
+}
+
+// revokeSCIMMembership removes the role and member tuple of a group membership
+func (s *AuthService) revokeSCIMMembership(user, group, actor string) error {
+	if _, err := s.unassignGroupRole(user, group, actor); err != nil {
+		return err
+	}
+	_, err := s.removeGroupMemberTuple(user, group, actor)
+	return err
+}
+
+// scimUser returns a provisioned user by SCIM ID
+func (s *AuthService) scimUser(id string) (*SCIMUser, error) {
+	var user SCIMUser
+	err := s.db.Where("id = ?", id).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &scimRequestError{status: http.StatusNotFound, detail: fmt.Sprintf("User %s not found", id)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user: %v", err)
+	}
+	return &user, nil
+}
+
+// scimGroup returns a provisioned group by SCIM ID
+func (s *AuthService) scimGroup(id string) (*SCIMGroup, error) {
+	var group SCIMGroup
+	err := s.db.Where("id = ?", id).First(&group).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, &scimRequestError{status: http.StatusNotFound, detail: fmt.Sprintf("Group %s not found", id)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read group: %v", err)
+	}
+	return &group, nil
+}
+
+// scimUsers returns provisioned users by SCIM ID, rejecting unknown IDs
+func (s *AuthService) scimUsers(ids []string) (map[string]SCIMUser, error) {
+	users := make(map[string]SCIMUser, len(ids))
+	if len(ids) == 0 {
+		return users, nil
+	}
+	var records []SCIMUser
+	if err := s.db.Where("id IN ?", ids).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to read users: %v", err)
+	}
+	for _, user := range records {
+		users[user.ID] = user
+	}
+	for _, id := range ids {
+		if _, found := users[id]; !found {
+			return nil, scimInvalid("member %s is not a provisioned user", id)
+		}
+	}
+	return users, nil
+}
+
+// scimMemberIDs returns the SCIM IDs of the users in a group, or of the groups of a user
+func (s *AuthService) scimMemberIDs(column, id string) ([]string, error) {
+	var members []SCIMMember
+	if err := s.db.Where(column+" = ?", id).Order("group_id, user_id").Find(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to read group members: %v", err)
+	}
+	ids := make([]string, len(members))
+	for i, member := range members {
+		if column == "group_id" {
+			ids[i] = member.UserID
+		} else {
+			ids[i] = member.GroupID
+		}
+	}
+	return ids, nil
+}
+
+// userResource returns the SCIM representation of a user with its groups
+func (s *AuthService) userResource(r *http.Request, user *SCIMUser) (*SCIMUserResource, error) {
+	groupIDs, err := s.scimMemberIDs("user_id", user.ID)
+	if err != nil {
+		return nil, err
+	}
+	var groups []SCIMGroup
+	if len(groupIDs) > 0 {
+		if err := s.db.Where("id IN ?", groupIDs).Order("display_name").Find(&groups).Error; err != nil {
+			return nil, fmt.Errorf("failed to read groups: %v", err)
+		}
+	}
+
+	active := user.Active
+	resource := &SCIMUserResource{
+		Schemas:     []string{scimUserSchema},
+		ID:          user.ID,
+		ExternalID:  user.ExternalID,
+		UserName:    user.UserName,
+		DisplayName: user.DisplayName,
+		Active:      &active,
+		Groups:      make([]SCIMReference, 0, len(groups)),
+		Meta:        &SCIMMeta{ResourceType: "User", Created: user.CreatedAt, LastModified: user.UpdatedAt, Location: scimLocation(r, "Users", user.ID)},
+	}
+	for _, group := range groups {
+		resource.Groups = append(resource.Groups, SCIMReference{Value: group.ID, Display: group.DisplayName})
+	}
+	return resource, nil
+}
+
+// groupResource returns the SCIM representation of a group with its members
+func (s *AuthService) groupResource(r *http.Request, group *SCIMGroup) (*SCIMGroupResource, error) {
+	memberIDs, err := s.scimMemberIDs("group_id", group.ID)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.scimUsers(memberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := &SCIMGroupResource{
+		Schemas:     []string{scimGroupSchema},
+		ID:          group.ID,
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Members:     make([]SCIMReference, 0, len(memberIDs)),
+		Meta:        &SCIMMeta{ResourceType: "Group", Created: group.CreatedAt, LastModified: group.UpdatedAt, Location: scimLocation(r, "Groups", group.ID)},
+	}
+	for _, id := range memberIDs {
+		resource.Members = append(resource.Members, SCIMReference{Value: id, Display: users[id].UserName})
+	}
+	return resource, nil
+}
+
+// saveSCIMUser stores a changed user and moves its group memberships along: deactivated
+// users lose them, reactivated users regain them and renamed users have them renamed
+func (s *AuthService) saveSCIMUser(before, after *SCIMUser, actor string) error {
+	if after.UserName != before.UserName {
+		var taken int64
+		if err := s.db.Model(&SCIMUser{}).Where("user_name = ? AND id <> ?", after.UserName, after.ID).Count(&taken).Error; err != nil {
+			return fmt.Errorf("failed to read users: %v", err)
+		}
+		if taken > 0 {
+			return &scimRequestError{status: http.StatusConflict, scimType: "uniqueness", detail: fmt.Sprintf("userName %s is already provisioned", after.UserName)}
+		}
+	}
+	if err := s.db.Save(after).Error; err != nil {
+		return fmt.Errorf("failed to save user: %v", err)
+	}
+
+	renamed := after.UserName != before.UserName
+	revoke := before.Active && (!after.Active || renamed)
+	grant := after.Active && (!before.Active || renamed)
+	if !revoke && !grant {
+		return nil
+	}
+
+	groupIDs, err := s.scimMemberIDs("user_id", after.ID)
+	if err != nil {
+		return err
+	}
+	for _, groupID := range groupIDs {
+		group, err := s.scimGroup(groupID)
+		if err != nil {
+			return err
+		}
+		if revoke {
+			if err := s.revokeSCIMMembership(before.UserName, group.DisplayName, actor); err != nil {
+				return err
+			}
+		}
+		if grant {
+			if err := s.grantSCIMMembership(after.UserName, group.DisplayName, actor); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// saveSCIMGroup stores a changed group with its members, given as user SCIM IDs, and maps
+// the membership changes of active users to roles and member tuples. A renamed group moves
+// its members to the new role and group.
+func (s *AuthService) saveSCIMGroup(before, after *SCIMGroup, memberIDs []string, actor string) error {
+	if after.DisplayName != before.DisplayName {
+		var taken int64
+		if err := s.db.Model(&SCIMGroup{}).Where("display_name = ? AND id <> ?", after.DisplayName, after.ID).Count(&taken).Error; err != nil {
+			return fmt.Errorf("failed to read groups: %v", err)
+		}
+		if taken > 0 {
+			return &scimRequestError{status: http.StatusConflict, scimType: "uniqueness", detail: fmt.Sprintf("displayName %s is already provisioned", after.DisplayName)}
+		}
+	}
+
+	previousIDs, err := s.scimMemberIDs("group_id", after.ID)
+	if err != nil {
+		return err
+	}
+	users, err := s.scimUsers(append(append([]string{}, previousIDs...), memberIDs...))
+	if err != nil {
+		return err
+	}
+	previous := make(map[string]bool, len(previousIDs))
+	for _, id := range previousIDs {
+		previous[id] = true
+	}
+	current := make(map[string]bool, len(memberIDs))
+	for _, id := range memberIDs {
+		current[id] = true
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(after).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", after.ID).Delete(&SCIMMember{}).Error; err != nil {
+			return err
+		}
+		for id := range current {
+			if err := tx.Create(&SCIMMember{GroupID: after.ID, UserID: id}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save group: %v", err)
+	}
+
+	renamed := after.DisplayName != before.DisplayName
+	for _, id := range previousIDs {
+		if user := users[id]; user.Active && (!current[id] || renamed) {
+			if err := s.revokeSCIMMembership(user.UserName, before.DisplayName, actor); err != nil {
+				return err
+			}
+		}
+	}
+	for id := range current {
+		if user := users[id]; user.Active && (!previous[id] || renamed) {
+			if err := s.grantSCIMMembership(user.UserName, after.DisplayName, actor); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// scimReferences returns the SCIM IDs of a list of member references
+func scimReferences(value json.RawMessage) ([]string, error) {
+	var references []SCIMReference
+	if err := json.Unmarshal(value, &references); err != nil {
+		return nil, scimInvalid("members must be a list of {\"value\": \"<user id>\"}")
+	}
+	ids := make([]string, 0, len(references))
+	for _, reference := range references {
+		ids = append(ids, reference.Value)
+	}
+	return ids, nil
+}
+
+// scimBool decodes a boolean PATCH value. Some identity providers send "True" and "False".
+func scimBool(value json.RawMessage) (bool, error) {
+	var flag bool
+	if err := json.Unmarshal(value, &flag); err == nil {
+		return flag, nil
+	}
+	var text string
+	if err := json.Unmarshal(value, &text); err == nil {
+		if flag, err := strconv.ParseBool(text); err == nil {
+			return flag, nil
+		}
+	}
+	return false, scimInvalid("active must be a boolean")
+}
+
+// scimString decodes a string PATCH value
+func scimString(field string, value json.RawMessage) (string, error) {
+	var text string
+	if err := json.Unmarshal(value, &text); err != nil {
+		return "", scimInvalid("%s must be a string", field)
+	}
+	return text, nil
+}
+
+// patchSCIMUser applies PATCH operations to a user. Operations without a path carry an
+// object of attributes to replace. Attributes the service does not keep, e.g. name or
+// emails, are ignored.
+func patchSCIMUser(user *SCIMUser, operations []SCIMPatchOperation) error {
+	for _, op := range operations {
+		values := map[string]json.RawMessage{}
+		if op.Path != "" {
+			values[op.Path] = op.Value
+		} else if err := json.Unmarshal(op.Value, &values); err != nil {
+			return scimInvalid("a PATCH operation without a path needs an object value")
+		}
+
+		for path, value := range values {
+			remove := strings.EqualFold(op.Op, "remove")
+			if !remove && !strings.EqualFold(op.Op, "add") && !strings.EqualFold(op.Op, "replace") {
+				return scimInvalid("unsupported PATCH operation %q", op.Op)
+			}
+			var err error
+			switch strings.ToLower(path) {
+			case "active":
+				if remove {
+					return scimInvalid("active cannot be removed")
+				}
+				user.Active, err = scimBool(value)
+			case "username":
+				if remove {
+					return scimInvalid("userName cannot be removed")
+				}
+				if user.UserName, err = scimString("userName", value); err == nil {
+					err = scimName("userName", user.UserName)
+				}
+			case "displayname":
+				user.DisplayName = ""
+				if !remove {
+					user.DisplayName, err = scimString("displayName", value)
+				}
+			case "externalid":
+				user.ExternalID = ""
+				if !remove {
+					user.ExternalID, err = scimString("externalId", value)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// patchSCIMGroup applies PATCH operations to a group and its members, given as user SCIM
+// IDs. Like patchSCIMUser it ignores attributes the service does not keep.
+func patchSCIMGroup(group *SCIMGroup, members []string, operations []SCIMPatchOperation) ([]string, error) {
+	for _, op := range operations {
+		values := map[string]json.RawMessage{}
+		if match := scimMemberFilterPattern.FindStringSubmatch(op.Path); match != nil && strings.EqualFold(op.Op, "remove") {
+			members = withoutMembers(members, []string{match[1]})
+			continue
+		}
+		if op.Path != "" {
+			values[op.Path] = op.Value
+		} else if err := json.Unmarshal(op.Value, &values); err != nil {
+			return nil, scimInvalid("a PATCH operation without a path needs an object value")
+		}
+
+		for path, value := range values {
+			var err error
+			switch strings.ToLower(path) {
+			case "members":
+				var ids []string
+				if len(value) > 0 {
+					if ids, err = scimReferences(value); err != nil {
+						return nil, err
+					}
+				}
+				switch strings.ToLower(op.Op) {
+				case "add":
+					members = append(withoutMembers(members, ids), ids...)
+				case "remove":
+					// Removing without a value removes every member
+					if len(value) == 0 {
+						ids = members
+					}
+					members = withoutMembers(members, ids)
+				case "replace":
+					members = ids
+				default:
+					return nil, scimInvalid("unsupported PATCH operation %q", op.Op)
+				}
+			case "displayname":
+				if strings.EqualFold(op.Op, "remove") {
+					return nil, scimInvalid("displayName cannot be removed")
+				}
+				if group.DisplayName, err = scimString("displayName", value); err == nil {
+					err = scimName("displayName", group.DisplayName)
+				}
+			case "externalid":
+				group.ExternalID = ""
+				if !strings.EqualFold(op.Op, "remove") {
+					group.ExternalID, err = scimString("externalId", value)
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return members, nil
+}
+
+// withoutMembers returns members without the given IDs
+func withoutMembers(members, ids []string) []string {
+	drop := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		drop[id] = true
+	}
+	kept := make([]string, 0, len(members))
+	for _, id := range members {
+		if !drop[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// scimList answers a SCIM list request from a query on a resource table. filterColumns
+// maps the attributes that can be filtered on to their columns.
+func (s *AuthService) scimList(r *http.Request, query *gorm.DB, filterColumns map[string]string, order string, page interface{}) (total int64, startIndex int, err error) {
+	if filter := r.URL.Query().Get("filter"); filter != "" {
+		match := scimFilterPattern.FindStringSubmatch(filter)
+		if match == nil {
+			return 0, 0, &scimRequestError{status: http.StatusBadRequest, scimType: "invalidFilter", detail: "only filters of the form <attribute> eq \"<value>\" are supported"}
+		}
+		column, supported := filterColumns[strings.ToLower(match[1])]
+		if !supported {
+			return 0, 0, &scimRequestError{status: http.StatusBadRequest, scimType: "invalidFilter", detail: fmt.Sprintf("filtering on %s is not supported", match[1])}
+		}
+		query = query.Where(column+" = ?", strings.ReplaceAll(match[2], `\"`, `"`))
+	}
+
+	startIndex, count := 1, scimMaxResults
+	if value := r.URL.Query().Get("startIndex"); value != "" {
+		if startIndex, err = strconv.Atoi(value); err != nil || startIndex < 1 {
+			startIndex = 1
+		}
+	}
+	if value := r.URL.Query().Get("count"); value != "" {
+		if count, err = strconv.Atoi(value); err != nil || count < 0 || count > scimMaxResults {
+			count = scimMaxResults
+		}
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to count resources: %v", err)
+	}
+	if err := query.Order(order).Offset(startIndex - 1).Limit(count).Find(page).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to list resources: %v", err)
+	}
+	return total, startIndex, nil
+}
+
+// scimServiceProviderConfigHandler describes the SCIM features the service supports
+func (s *AuthService) scimServiceProviderConfigHandler(w http.ResponseWriter, r *http.Request) {
+	writeSCIM(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{scimServiceProviderConfigSchema},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": scimMaxResults},
+		"changePassword": map[string]bool{"supported": false},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]string{
+			{"type": "oauthbearertoken", "name": "Bearer token", "description": "An admin API key or JWT sent as a bearer token"},
+		},
+	})
+}
+
+// scimCreateUserHandler provisions a user
+func (s *AuthService) scimCreateUserHandler(w http.ResponseWriter, r *http.Request) {
+	var request SCIMUserResource
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeSCIMError(w, &scimRequestError{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "Invalid JSON payload"})
+		return
+	}
+	if err := scimName("userName", request.UserName); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	s.scimMu.Lock()
+	defer s.scimMu.Unlock()
+
+	var taken int64
+	if err := s.db.Model(&SCIMUser{}).Where("user_name = ?", request.UserName).Count(&taken).Error; err != nil {
+		writeSCIMError(w, fmt.Errorf("failed to read users: %v", err))
+		return
+	}
+	if taken > 0 {
+		writeSCIMError(w, &scimRequestError{status: http.StatusConflict, scimType: "uniqueness", detail: fmt.Sprintf("userName %s is already provisioned", request.UserName)})
+		return
+	}
+
+	user := SCIMUser{
+		ID:          newRequestID(),
+		UserName:    request.UserName,
+		ExternalID:  request.ExternalID,
+		DisplayName: request.DisplayName,
+		Active:      request.Active == nil || *request.Active,
+	}
+	if err := s.db.Create(&user).Error; err != nil {
+		writeSCIMError(w, fmt.Errorf("failed to save user: %v", err))
+		return
+	}
+
+	resource, err := s.userResource(r, &user)
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusCreated, resource)
+}
+
+// scimListUsersHandler lists provisioned users, optionally filtered by userName or externalId
+func (s *AuthService) scimListUsersHandler(w http.ResponseWriter, r *http.Request) {
+	var users []SCIMUser
+	columns := map[string]string{"id": "id", "username": "user_name", "externalid": "external_id", "displayname": "display_name"}
+	total, startIndex, err := s.scimList(r, s.db.Model(&SCIMUser{}), columns, "user_name", &users)
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	resources := make([]*SCIMUserResource, 0, len(users))
+	for i := range users {
+		resource, err := s.userResource(r, &users[i])
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		resources = append(resources, resource)
+	}
+	writeSCIM(w, http.StatusOK, SCIMListResponse{
+		Schemas:      []string{scimListResponseSchema},
+		TotalResults: int(total),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// scimGetUserHandler returns a provisioned user
+func (s *AuthService) scimGetUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := s.scimUser(mux.Vars(r)["id"])
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	resource, err := s.userResource(r, user)
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, resource)
+}
+
+// scimReplaceUserHandler replaces a provisioned user
+func (s *AuthService) scimReplaceUserHandler(w http.ResponseWriter, r *http.Request) {
+	var request SCIMUserResource
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeSCIMError(w, &scimRequestError{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "Invalid JSON payload"})
+		return
+	}
+	if err := scimName("userName", request.UserName); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	s.scimMu.Lock()
+	defer s.scimMu.Unlock()
+
+	before, err := s.scimUser(mux.Vars(r)["id"])
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	after := *before
+	after.UserName, after.ExternalID, after.DisplayName = request.UserName, request.ExternalID, request.DisplayName
+	after.Active = request.Active == nil || *request.Active
+	s.finishSCIMUserChange(w, r, before, &after)
+}
+
+// scimPatchUserHandler changes parts of a provisioned user, e.g. deactivates it
+func (s *AuthService) scimPatchUserHandler(w http.ResponseWriter, r *http.Request) {
+	var request SCIMPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeSCIMError(w, &scimRequestError{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "Invalid JSON payload"})
+		return
+	}
+
+	s.scimMu.Lock()
+	defer s.scimMu.Unlock()
+
+	before, err := s.scimUser(mux.Vars(r)["id"])
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	after := *before
+	if err := patchSCIMUser(&after, request.Operations); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	s.finishSCIMUserChange(w, r, before, &after)
+}
+
+// finishSCIMUserChange saves a changed user and answers with it
+func (s *AuthService) finishSCIMUserChange(w http.ResponseWriter, r *http.Request, before, after *SCIMUser) {
+	if err := s.saveSCIMUser(before, after, actorFromRequest(r)); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	resource, err := s.userResource(r, after)
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, resource)
+}
+
+// scimDeleteUserHandler deprovisions a user. The user is offboarded: besides its group
+// memberships, its roles, rules, attributes and relationships are removed.
+func (s *AuthService) scimDeleteUserHandler(w http.ResponseWriter, r *http.Request) {
+	s.scimMu.Lock()
+	defer s.scimMu.Unlock()
+
+	user, err := s.scimUser(mux.Vars(r)["id"])
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", user.ID).Delete(&SCIMMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(user).Error
+	})
+	if err != nil {
+		writeSCIMError(w, fmt.Errorf("failed to delete user: %v", err))
+		return
+	}
+	if _, err := s.OffboardUser(user.UserName, actorFromRequest(r)); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// scimCreateGroupHandler provisions a group with its members
+func (s *AuthService) scimCreateGroupHandler(w http.ResponseWriter, r *http.Request) {
+	var request SCIMGroupResource
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeSCIMError(w, &scimRequestError{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "Invalid JSON payload"})
+		return
+	}
+	if err := scimName("displayName", request.DisplayName); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	s.scimMu.Lock()
+	defer s.scimMu.Unlock()
+
+	var taken int64
+	if err := s.db.Model(&SCIMGroup{}).Where("display_name = ?", request.DisplayName).Count(&taken).Error; err != nil {
+		writeSCIMError(w, fmt.Errorf("failed to read groups: %v", err))
+		return
+	}
+	if taken > 0 {
+		writeSCIMError(w, &scimRequestError{status: http.StatusConflict, scimType: "uniqueness", detail: fmt.Sprintf("displayName %s is already provisioned", request.DisplayName)})
+		return
+	}
+
+	group := SCIMGroup{ID: newRequestID(), DisplayName: request.DisplayName, ExternalID: request.ExternalID}
+	members := make([]string, 0, len(request.Members))
+	for _, member := range request.Members {
+		members = append(members, member.Value)
+	}
+	if _, err := s.scimUsers(members); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	if err := s.db.Create(&group).Error; err != nil {
+		writeSCIMError(w, fmt.Errorf("failed to save group: %v", err))
+		return
+	}
+	if err := s.saveSCIMGroup(&group, &group, members, actorFromRequest(r)); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	resource, err := s.groupResource(r, &group)
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusCreated, resource)
+}
+
+// scimListGroupsHandler lists provisioned groups, optionally filtered by displayName or
+// externalId
+func (s *AuthService) scimListGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	var groups []SCIMGroup
+	columns := map[string]string{"id": "id", "displayname": "display_name", "externalid": "external_id"}
+	total, startIndex, err := s.scimList(r, s.db.Model(&SCIMGroup{}), columns, "display_name", &groups)
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	resources := make([]*SCIMGroupResource, 0, len(groups))
+	for i := range groups {
+		resource, err := s.groupResource(r, &groups[i])
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		resources = append(resources, resource)
+	}
+	writeSCIM(w, http.StatusOK, SCIMListResponse{
+		Schemas:      []string{scimListResponseSchema},
+		TotalResults: int(total),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// scimGetGroupHandler returns a provisioned group
+func (s *AuthService) scimGetGroupHandler(w http.ResponseWriter, r *http.Request) {
+	group, err := s.scimGroup(mux.Vars(r)["id"])
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	resource, err := s.groupResource(r, group)
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, resource)
+}
+
+// scimReplaceGroupHandler replaces a provisioned group and its members
+func (s *AuthService) scimReplaceGroupHandler(w http.ResponseWriter, r *http.Request) {
+	var request SCIMGroupResource
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeSCIMError(w, &scimRequestError{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "Invalid JSON payload"})
+		return
+	}
+	if err := scimName("displayName", request.DisplayName); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+
+	s.scimMu.Lock()
+	defer s.scimMu.Unlock()
+
+	before, err := s.scimGroup(mux.Vars(r)["id"])
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	after := *before
+	after.DisplayName, after.ExternalID = request.DisplayName, request.ExternalID
+	members := make([]string, 0, len(request.Members))
+	for _, member := range request.Members {
+		members = append(members, member.Value)
+	}
+	s.finishSCIMGroupChange(w, r, before, &after, members)
+}
+
+// scimPatchGroupHandler changes parts of a provisioned group, e.g. adds or removes members
+func (s *AuthService) scimPatchGroupHandler(w http.ResponseWriter, r *http.Request) {
+	var request SCIMPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeSCIMError(w, &scimRequestError{status: http.StatusBadRequest, scimType: "invalidSyntax", detail: "Invalid JSON payload"})
+		return
+	}
+
+	s.scimMu.Lock()
+	defer s.scimMu.Unlock()
+
+	before, err := s.scimGroup(mux.Vars(r)["id"])
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	members, err := s.scimMemberIDs("group_id", before.ID)
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	after := *before
+	if members, err = patchSCIMGroup(&after, members, request.Operations); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	s.finishSCIMGroupChange(w, r, before, &after, members)
+}
+
+// finishSCIMGroupChange saves a changed group and answers with it
+func (s *AuthService) finishSCIMGroupChange(w http.ResponseWriter, r *http.Request, before, after *SCIMGroup, members []string) {
+	if err := s.saveSCIMGroup(before, after, members, actorFromRequest(r)); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	resource, err := s.groupResource(r, after)
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, resource)
+}
+
+// scimDeleteGroupHandler deprovisions a group, removing the roles and member tuples of its
+// active members
+func (s *AuthService) scimDeleteGroupHandler(w http.ResponseWriter, r *http.Request) {
+	s.scimMu.Lock()
+	defer s.scimMu.Unlock()
+
+	group, err := s.scimGroup(mux.Vars(r)["id"])
+	if err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	if err := s.saveSCIMGroup(group, group, nil, actorFromRequest(r)); err != nil {
+		writeSCIMError(w, err)
+		return
+	}
+	if err := s.db.Delete(group).Error; err != nil {
+		writeSCIMError(w, fmt.Errorf("failed to delete group: %v", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,164 @@
+// Multi-Model Authorization Microservice - SCIM Provisioning Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// setupSCIMRouter routes the SCIM endpoints of service
+func setupSCIMRouter(service *AuthService) *mux.Router {
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/scim/v2/Users", service.scimCreateUserHandler).Methods("POST")
+	router.HandleFunc("/api/v1/scim/v2/Users", service.scimListUsersHandler).Methods("GET")
+	router.HandleFunc("/api/v1/scim/v2/Users/{id}", service.scimPatchUserHandler).Methods("PATCH")
+	router.HandleFunc("/api/v1/scim/v2/Users/{id}", service.scimDeleteUserHandler).Methods("DELETE")
+	router.HandleFunc("/api/v1/scim/v2/Groups", service.scimCreateGroupHandler).Methods("POST")
+	router.HandleFunc("/api/v1/scim/v2/Groups/{id}", service.scimGetGroupHandler).Methods("GET")
+	router.HandleFunc("/api/v1/scim/v2/Groups/{id}", service.scimPatchGroupHandler).Methods("PATCH")
+	router.HandleFunc("/api/v1/scim/v2/Groups/{id}", service.scimDeleteGroupHandler).Methods("DELETE")
+	return router
+}
+
+func TestSCIM_GroupMembershipMapsToRolesAndTuples(t *testing.T) {
+	service := setupTestService(t)
+	router := setupSCIMRouter(service)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/scim+json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	member := func(user, group string) bool {
+		roles, _ := service.rbacEnforcer.GetRolesForUser(user)
+		hasRole := false
+		for _, role := range roles {
+			hasRole = hasRole || role == group
+		}
+		hasTuple := service.relationshipGraph.HasDirectRelationship(user, groupMemberRelationship, group)
+		if hasRole != hasTuple {
+			t.Fatalf("Expected the role and tuple of %s in %s to agree, got role %v tuple %v", user, group, hasRole, hasTuple)
+		}
+		return hasRole
+	}
+
+	rr := send("POST", "/api/v1/scim/v2/Users", `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "alice", "externalId": "00u1"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", rr.Code, rr.Body.String())
+	}
+	var user SCIMUserResource
+	json.Unmarshal(rr.Body.Bytes(), &user)
+	if user.ID == "" || user.Active == nil || !*user.Active {
+		t.Fatalf("Expected an active user with an ID, got %+v", user)
+	}
+
+	rr = send("POST", "/api/v1/scim/v2/Groups", `{"displayName": "engineering", "members": [{"value": "`+user.ID+`"}]}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", rr.Code, rr.Body.String())
+	}
+	var group SCIMGroupResource
+	json.Unmarshal(rr.Body.Bytes(), &group)
+	if !member("alice", "engineering") {
+		t.Fatal("Expected alice to get the engineering role and member tuple")
+	}
+
+	var list SCIMListResponse
+	json.Unmarshal(send("GET", "/api/v1/scim/v2/Users?filter="+url.QueryEscape(`userName eq "alice"`), "").Body.Bytes(), &list)
+	if list.TotalResults != 1 {
+		t.Errorf("Expected the filter to find alice, got %+v", list)
+	}
+
+	// Deactivation removes the memberships, reactivation restores them
+	send("PATCH", "/api/v1/scim/v2/Users/"+user.ID, `{"Operations": [{"op": "replace", "value": {"active": false}}]}`)
+	if member("alice", "engineering") {
+		t.Error("Expected the deactivated user to lose the membership")
+	}
+	send("PATCH", "/api/v1/scim/v2/Users/"+user.ID, `{"Operations": [{"op": "Replace", "path": "active", "value": "True"}]}`)
+	if !member("alice", "engineering") {
+		t.Error("Expected the reactivated user to regain the membership")
+	}
+
+	// Renaming the group moves its members
+	rr = send("PATCH", "/api/v1/scim/v2/Groups/"+group.ID, `{"Operations": [{"op": "replace", "path": "displayName", "value": "platform"}]}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+	if member("alice", "engineering") || !member("alice", "platform") {
+		t.Error("Expected the membership to follow the renamed group")
+	}
+
+	send("PATCH", "/api/v1/scim/v2/Groups/"+group.ID, `{"Operations": [{"op": "remove", "path": "members[value eq \"`+user.ID+`\"]"}]}`)
+	if member("alice", "platform") {
+		t.Error("Expected the removed member to lose the membership")
+	}
+	json.Unmarshal(send("GET", "/api/v1/scim/v2/Groups/"+group.ID, "").Body.Bytes(), &group)
+	if len(group.Members) != 0 {
+		t.Errorf("Expected no members, got %+v", group.Members)
+	}
+
+	send("PATCH", "/api/v1/scim/v2/Groups/"+group.ID, `{"Operations": [{"op": "add", "path": "members", "value": [{"value": "`+user.ID+`"}]}]}`)
+	if rr := send("DELETE", "/api/v1/scim/v2/Groups/"+group.ID, ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", rr.Code)
+	}
+	if member("alice", "platform") {
+		t.Error("Expected deleting the group to remove its memberships")
+	}
+
+	// Deleting a user offboards it
+	service.rbacEnforcer.AddRoleForUser("alice", "auditor")
+	if rr := send("DELETE", "/api/v1/scim/v2/Users/"+user.ID, ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", rr.Code)
+	}
+	if roles, _ := service.rbacEnforcer.GetRolesForUser("alice"); len(roles) != 0 {
+		t.Errorf("Expected the deleted user to be offboarded, got roles %v", roles)
+	}
+}
+
+func TestSCIM_Errors(t *testing.T) {
+	service := setupTestService(t)
+	router := setupSCIMRouter(service)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	send("POST", "/api/v1/scim/v2/Users", `{"userName": "alice"}`)
+
+	for _, tc := range []struct {
+		method, path, body string
+		status             int
+		scimType           string
+	}{
+		{"POST", "/api/v1/scim/v2/Users", `{"userName": "alice"}`, http.StatusConflict, "uniqueness"},
+		{"POST", "/api/v1/scim/v2/Users", `{"userName": ""}`, http.StatusBadRequest, "invalidValue"},
+		{"POST", "/api/v1/scim/v2/Groups", `{"displayName": "ops", "members": [{"value": "nobody"}]}`, http.StatusBadRequest, "invalidValue"},
+		{"GET", "/api/v1/scim/v2/Users?filter=" + url.QueryEscape(`userName sw "a"`), "", http.StatusBadRequest, "invalidFilter"},
+		{"PATCH", "/api/v1/scim/v2/Users/nobody", `{"Operations": []}`, http.StatusNotFound, ""},
+	} {
+		rr := send(tc.method, tc.path, tc.body)
+		var scimErr SCIMError
+		json.Unmarshal(rr.Body.Bytes(), &scimErr)
+		if rr.Code != tc.status || scimErr.ScimType != tc.scimType || scimErr.Schemas[0] != scimErrorSchema {
+			t.Errorf("Expected %d %q for %s %s, got %d %s", tc.status, tc.scimType, tc.method, tc.path, rr.Code, rr.Body.String())
+		}
+	}
+
+	var groups int64
+	service.db.Model(&SCIMGroup{}).Count(&groups)
+	if groups != 0 {
+		t.Error("Expected the group with an unknown member not to be stored")
+	}
+}
@@ -0,0 +1,267 @@
+// Multi-Model Authorization Microservice - Admin API Self-Authorization
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+)
+
+// Actions on admin resources
+const (
+	adminActionRead  = "read"  // GET and HEAD requests
+	adminActionWrite = "write" // Every other request
+	adminActionCheck = "check" // Authorization checks
+)
+
+// Built-in admin roles. The client roles read and admin are built in as well, so principals
+// without a binding keep the access of their client role.
+const (
+	adminRolePolicyAdmin    = "policy_admin"
+	adminRoleAuditor        = "auditor"
+	adminRoleEnforcerClient = "enforcer_client"
+)
+
+// Admin resource groups, which permissions may name instead of single resources
+const (
+	adminGroupPolicies       = "policy_data"
+	adminGroupAudit          = "audit_data"
+	adminGroupOperations     = "operations"
+	adminGroupAuthorizations = "authorizations"
+)
+
+// adminResourceGroups puts the admin resources, the first path segment below /api/v1, in
+// resource groups
+var adminResourceGroups = map[string]string{
+	"acl":            adminGroupPolicies,
+	"rbac":           adminGroupPolicies,
+	"abac":           adminGroupPolicies,
+	"relationships":  adminGroupPolicies,
+	"users":          adminGroupPolicies,
+	"objects":        adminGroupPolicies,
+	"subjects":       adminGroupPolicies,
+	"policies":       adminGroupPolicies,
+	"labels":         adminGroupPolicies,
+	"import":         adminGroupPolicies,
+	"export":         adminGroupPolicies,
+	"transactions":   adminGroupPolicies,
+	"actions":        adminGroupPolicies,
+	"changes":        adminGroupPolicies,
+	"tenants":        adminGroupPolicies,
+	"scim":           adminGroupPolicies,
+	"ldap":           adminGroupPolicies,
+	"demo":           adminGroupPolicies,
+	"audit":          adminGroupAudit,
+	"traces":         adminGroupAudit,
+	"health":         adminGroupOperations,
+	"models":         adminGroupOperations,
+	"openapi.json":   adminGroupOperations,
+	"metrics":        adminGroupOperations,
+	"slo":            adminGroupOperations,
+	"webhooks":       adminGroupOperations,
+	"authorizations": adminGroupAuthorizations,
+	"kubernetes":     adminGroupAuthorizations,
+	"ext_authz":      adminGroupAuthorizations,
+}
+
+// grpcAdminResources maps gRPC services to admin resources
+var grpcAdminResources = map[string]string{
+	"AuthorizationService": "authorizations",
+	"RBACService":          "rbac",
+	"ABACService":          "abac",
+	"ReBACService":         "relationships",
+	"Authorization":        "ext_authz", // Envoy ext_authz
+}
+
+// AdminPermission grants actions on an admin resource or resource group
+type AdminPermission struct {
+	Resource string   `json:"resource"`
+	Actions  []string `json:"actions"`
+}
+
+// AdminAuthorizationConfig binds principals to admin roles and defines custom roles
+type AdminAuthorizationConfig struct {
+	Bindings map[string][]string          `json:"bindings"`        // Admin roles by principal name
+	Roles    map[string][]AdminPermission `json:"roles,omitempty"` // Custom roles
+}
+
+// builtinAdminRoles lists the permissions of the built-in admin roles
+var builtinAdminRoles = map[string][]AdminPermission{
+	adminRolePolicyAdmin: {
+		{Resource: adminGroupPolicies, Actions: []string{adminActionRead, adminActionWrite}},
+		{Resource: adminGroupOperations, Actions: []string{adminActionRead}},
+	},
+	adminRoleAuditor: {
+		{Resource: adminGroupPolicies, Actions: []string{adminActionRead}},
+		{Resource: adminGroupAudit, Actions: []string{adminActionRead}},
+		{Resource: adminGroupOperations, Actions: []string{adminActionRead}},
+	},
+	adminRoleEnforcerClient: {
+		{Resource: adminGroupAuthorizations, Actions: []string{adminActionCheck}},
+		{Resource: adminGroupOperations, Actions: []string{adminActionRead}},
+	},
+	roleRead: {
+		{Resource: adminGroupPolicies, Actions: []string{adminActionRead}},
+		{Resource: adminGroupAudit, Actions: []string{adminActionRead}},
+		{Resource: adminGroupOperations, Actions: []string{adminActionRead}},
+		{Resource: adminGroupAuthorizations, Actions: []string{adminActionCheck}},
+	},
+	roleAdmin: {
+		{Resource: adminGroupPolicies, Actions: []string{adminActionRead, adminActionWrite}},
+		{Resource: adminGroupAudit, Actions: []string{adminActionRead, adminActionWrite}},
+		{Resource: adminGroupOperations, Actions: []string{adminActionRead, adminActionWrite}},
+		{Resource: adminGroupAuthorizations, Actions: []string{adminActionCheck}},
+	},
+}
+
+// adminAuthorizer decides which principals may call which admin endpoints with an RBAC
+// enforcer of its own, built on the service's RBAC model. Principals are subjects, admin
+// roles are roles ("role:<name>") and admin resources are objects in resource groups.
+type adminAuthorizer struct {
+	enforcer *casbin.Enforcer
+	bound    map[string]bool // Principals with role bindings
+}
+
+// adminBindingsFromEnv adds AUTH_ADMIN_ROLES, comma-separated "principal:role" entries, to
+// the bindings of a configuration
+func adminBindingsFromEnv(config *AuthConfig) error {
+	bindingsStr := os.Getenv("AUTH_ADMIN_ROLES")
+	if bindingsStr == "" {
+		return nil
+	}
+	if config.AdminAuthorization == nil {
+		config.AdminAuthorization = &AdminAuthorizationConfig{}
+	}
+	if config.AdminAuthorization.Bindings == nil {
+		config.AdminAuthorization.Bindings = make(map[string][]string)
+	}
+	for _, entry := range strings.Split(bindingsStr, ",") {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid AUTH_ADMIN_ROLES entry %q: expected principal:role", entry)
+		}
+		config.AdminAuthorization.Bindings[parts[0]] = append(config.AdminAuthorization.Bindings[parts[0]], parts[1])
+	}
+	return nil
+}
+
+// newAdminAuthorizer validates a configuration and loads it into an enforcer
+func newAdminAuthorizer(config *AdminAuthorizationConfig) (*adminAuthorizer, error) {
+	m, err := model.NewModelFromString(rbacModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin RBAC model: %v", err)
+	}
+	enforcer, err := casbin.NewEnforcer(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create admin enforcer: %v", err)
+	}
+	// Admin rules carry no conditions
+	enforcer.AddFunction("ruleCondition", func(args ...interface{}) (interface{}, error) {
+		return true, nil
+	})
+	// Admin actions imply no others
+	enforcer.AddFunction("impliedAction", func(args ...interface{}) (interface{}, error) {
+		return false, nil
+	})
+
+	roles := make(map[string][]AdminPermission, len(builtinAdminRoles)+len(config.Roles))
+	for name, permissions := range builtinAdminRoles {
+		roles[name] = permissions
+	}
+	for name, permissions := range config.Roles {
+		if _, ok := builtinAdminRoles[name]; ok {
+			return nil, fmt.Errorf("admin role %s is built in", name)
+		}
+		roles[name] = permissions
+	}
+
+	var rules [][]string
+	for name, permissions := range roles {
+		for _, permission := range permissions {
+			if permission.Resource == "" || len(permission.Actions) == 0 {
+				return nil, fmt.Errorf("permissions of admin role %s need a resource and actions", name)
+			}
+			for _, action := range permission.Actions {
+				if action != adminActionRead && action != adminActionWrite && action != adminActionCheck {
+					return nil, fmt.Errorf("invalid action %q in admin role %s: must be '%s', '%s' or '%s'", action, name, adminActionRead, adminActionWrite, adminActionCheck)
+				}
+				rules = append(rules, []string{"role:" + name, permission.Resource, action, "allow"})
+			}
+		}
+	}
+	if _, err := enforcer.AddPolicies(rules); err != nil {
+		return nil, fmt.Errorf("failed to load admin roles: %v", err)
+	}
+
+	var groups [][]string
+	for resource, group := range adminResourceGroups {
+		groups = append(groups, []string{resource, group})
+	}
+	if _, err := enforcer.AddNamedGroupingPolicies("g2", groups); err != nil {
+		return nil, fmt.Errorf("failed to load admin resource groups: %v", err)
+	}
+
+	authorizer := &adminAuthorizer{enforcer: enforcer, bound: make(map[string]bool)}
+	var bindings [][]string
+	for principal, names := range config.Bindings {
+		for _, name := range names {
+			if _, ok := roles[name]; !ok {
+				return nil, fmt.Errorf("unknown admin role %q bound to %s", name, principal)
+			}
+			bindings = append(bindings, []string{principal, "role:" + name})
+		}
+		authorizer.bound[principal] = true
+	}
+	if _, err := enforcer.AddGroupingPolicies(bindings); err != nil {
+		return nil, fmt.Errorf("failed to load admin role bindings: %v", err)
+	}
+	return authorizer, nil
+}
+
+// allows reports whether a principal may perform an action on an admin resource. Principals
+// with bindings have exactly their admin roles; the others have their client role.
+func (aa *adminAuthorizer) allows(principal *Principal, resource, action string) bool {
+	subject := principal.Name
+	if !aa.bound[principal.Name] {
+		subject = "role:" + principal.Role
+	}
+	allowed, err := aa.enforcer.Enforce(subject, resource, action)
+	return err == nil && allowed
+}
+
+// adminHTTPRequest returns the admin resource and action of an HTTP request. Authorization
+// checks are checks whatever their method.
+func adminHTTPRequest(r *http.Request) (string, string) {
+	resource := strings.TrimPrefix(r.URL.Path, "/api/v1/")
+	if i := strings.Index(resource, "/"); i >= 0 {
+		resource = resource[:i]
+	}
+	switch {
+	case adminResourceGroups[resource] == adminGroupAuthorizations:
+		return resource, adminActionCheck
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return resource, adminActionRead
+	}
+	return resource, adminActionWrite
+}
+
+// adminGRPCRequest returns the admin resource and action of a gRPC method
+func adminGRPCRequest(fullMethod string) (string, string) {
+	service, method := fullMethod, fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	service = strings.TrimPrefix(strings.TrimSuffix(service, "/"+method), "/")
+	resource := grpcAdminResources[service[strings.LastIndex(service, ".")+1:]]
+	switch {
+	case adminResourceGroups[resource] == adminGroupAuthorizations:
+		return resource, adminActionCheck
+	case grpcReadOnlyMethods[method]:
+		return resource, adminActionRead
+	}
+	return resource, adminActionWrite
+}
@@ -0,0 +1,172 @@
+// Multi-Model Authorization Microservice - Admin API Self-Authorization Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"casbin-authorization-server/authzpb"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestSelfAuthz_SeparatesAdminDuties(t *testing.T) {
+	service := setupTestService(t)
+	auth, err := newAuthenticator(AuthConfig{
+		APIKeys: []APIKeyConfig{
+			{Name: "deployer", Key: "deployer-key", Role: roleAdmin},
+			{Name: "siem", Key: "siem-key", Role: roleAdmin},
+			{Name: "checkout", Key: "checkout-key", Role: roleRead},
+			{Name: "legacy", Key: "legacy-key", Role: roleAdmin},
+		},
+		AdminAuthorization: &AdminAuthorizationConfig{
+			Bindings: map[string][]string{
+				"deployer": {adminRolePolicyAdmin},
+				"siem":     {adminRoleAuditor},
+				"checkout": {adminRoleEnforcerClient},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to configure authentication: %v", err)
+	}
+	service.authenticator = auth
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/acl/policies", service.addACLPolicyHandler).Methods("POST")
+	router.HandleFunc("/api/v1/acl/policies", service.getACLPoliciesHandler).Methods("GET")
+	router.HandleFunc("/api/v1/audit/decisions", service.getDecisionsHandler).Methods("GET")
+	router.Use(service.authenticator.middleware)
+
+	send := func(method, url, body, key string) int {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	policy := `{"subject": "alice", "object": "data1", "action": "read"}`
+	check := `{"model": "acl", "subject": "alice", "object": "data1", "action": "read"}`
+
+	expectations := []struct {
+		key, method, url, body string
+		code                   int
+	}{
+		{"deployer-key", "POST", "/api/v1/acl/policies", policy, http.StatusCreated},
+		{"deployer-key", "GET", "/api/v1/audit/decisions", "", http.StatusForbidden},
+		{"deployer-key", "POST", "/api/v1/authorizations", check, http.StatusForbidden},
+		{"siem-key", "GET", "/api/v1/audit/decisions", "", http.StatusOK},
+		{"siem-key", "GET", "/api/v1/acl/policies", "", http.StatusOK},
+		{"siem-key", "POST", "/api/v1/acl/policies", policy, http.StatusForbidden},
+		{"checkout-key", "POST", "/api/v1/authorizations", check, http.StatusOK},
+		{"checkout-key", "GET", "/api/v1/acl/policies", "", http.StatusForbidden},
+		// Principals without bindings keep the access of their client role
+		{"legacy-key", "POST", "/api/v1/acl/policies", `{"subject": "bob", "object": "data1", "action": "read"}`, http.StatusCreated},
+		{"legacy-key", "GET", "/api/v1/audit/decisions", "", http.StatusOK},
+	}
+	for _, e := range expectations {
+		if code := send(e.method, e.url, e.body, e.key); code != e.code {
+			t.Errorf("Expected %d for %s %s with %s, got %d", e.code, e.method, e.url, e.key, code)
+		}
+	}
+}
+
+func TestSelfAuthz_GRPC(t *testing.T) {
+	service := setupTestService(t)
+	service.authenticator, _ = newAuthenticator(AuthConfig{
+		APIKeys: []APIKeyConfig{
+			{Name: "deployer", Key: "deployer-key", Role: roleAdmin},
+			{Name: "checkout", Key: "checkout-key", Role: roleAdmin},
+		},
+		AdminAuthorization: &AdminAuthorizationConfig{Bindings: map[string][]string{
+			"deployer": {adminRolePolicyAdmin},
+			"checkout": {adminRoleEnforcerClient},
+		}},
+	})
+	conn := setupTestGRPC(t, service)
+	rbac := authzpb.NewRBACServiceClient(conn)
+	authz := authzpb.NewAuthorizationServiceClient(conn)
+	policy := &authzpb.PolicyRequest{Model: "rbac", Subject: "editor", Object: "doc1", Action: "write"}
+	check := &authzpb.EnforceRequest{Model: "rbac", Subject: "editor", Object: "doc1", Action: "write"}
+
+	checkoutCtx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "checkout-key")
+	if _, err := rbac.AddPolicy(checkoutCtx, policy); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for an enforcer client, got %v", err)
+	}
+	if _, err := authz.Enforce(checkoutCtx, check); err != nil {
+		t.Errorf("Expected enforcer clients to check authorizations, got %v", err)
+	}
+	deployerCtx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", "deployer-key")
+	if _, err := rbac.AddPolicy(deployerCtx, policy); err != nil {
+		t.Errorf("Expected policy admins to add policies, got %v", err)
+	}
+	if _, err := authz.Enforce(deployerCtx, check); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a policy admin checking authorizations, got %v", err)
+	}
+}
+
+func TestSelfAuthz_Configuration(t *testing.T) {
+	keys := []APIKeyConfig{{Name: "ops", Key: "ops-key", Role: roleRead}}
+
+	// Custom roles name resources or resource groups
+	auth, err := newAuthenticator(AuthConfig{APIKeys: keys, AdminAuthorization: &AdminAuthorizationConfig{
+		Roles:    map[string][]AdminPermission{"webhook_admin": {{Resource: "webhooks", Actions: []string{"read", "write"}}}},
+		Bindings: map[string][]string{"ops": {"webhook_admin", adminRoleAuditor}},
+	}})
+	if err != nil {
+		t.Fatalf("Failed to configure admin authorization: %v", err)
+	}
+	ops := &Principal{Name: "ops", Role: roleRead}
+	if !auth.admin.allows(ops, "webhooks", adminActionWrite) || auth.admin.allows(ops, "metrics", adminActionWrite) {
+		t.Error("Expected the custom role to grant webhook writes only")
+	}
+	if !auth.admin.allows(ops, "audit", adminActionRead) {
+		t.Error("Expected roles to combine")
+	}
+
+	t.Setenv("AUTH_API_KEYS", "ops:ops-key:read")
+	t.Setenv("AUTH_ADMIN_ROLES", "ops:policy_admin")
+	if auth, err := authenticatorFromEnv(); err != nil || !auth.admin.allows(ops, "rbac", adminActionWrite) {
+		t.Errorf("Expected AUTH_ADMIN_ROLES to bind ops, got %v", err)
+	}
+
+	invalid := map[string]AuthConfig{
+		"unknown role":   {APIKeys: keys, AdminAuthorization: &AdminAuthorizationConfig{Bindings: map[string][]string{"ops": {"superuser"}}}},
+		"built-in role":  {APIKeys: keys, AdminAuthorization: &AdminAuthorizationConfig{Roles: map[string][]AdminPermission{adminRoleAuditor: {{Resource: "audit", Actions: []string{"write"}}}}}},
+		"unknown action": {APIKeys: keys, AdminAuthorization: &AdminAuthorizationConfig{Roles: map[string][]AdminPermission{"deleter": {{Resource: "acl", Actions: []string{"delete"}}}}}},
+		"no credentials": {AdminAuthorization: &AdminAuthorizationConfig{Bindings: map[string][]string{"ops": {adminRoleAuditor}}}},
+	}
+	for name, config := range invalid {
+		if _, err := newAuthenticator(config); err == nil {
+			t.Errorf("Expected the %s configuration to be rejected", name)
+		}
+	}
+}
+
+func TestSelfAuthz_EveryRouteHasAResourceGroup(t *testing.T) {
+	service := setupTestService(t)
+	router := mux.NewRouter()
+	api := router.PathPrefix("/api/v1").Subrouter()
+	service.registerEnforcementRoutes(api)
+	service.registerAdminRoutes(api)
+
+	// New endpoints need a resource group, or only custom roles could reach them
+	router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, _ := route.GetPathTemplate()
+		resource := strings.SplitN(strings.TrimPrefix(template, "/api/v1/"), "/", 2)[0]
+		if _, ok := adminResourceGroups[resource]; !ok && template != "/api/v1" {
+			t.Errorf("Route %s has no admin resource group", template)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,81 @@
+// Multi-Model Authorization Microservice - Startup Self-Test Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelfTest_PassesAndCleansUp(t *testing.T) {
+	service := setupTestService(t)
+
+	report := service.RunSelfTest()
+	if !report.Passed {
+		t.Fatalf("Expected self-test to pass:\n%s", report.Text())
+	}
+	if len(report.Steps) != 20 {
+		t.Errorf("Expected 5 steps for each of 4 models, got %d", len(report.Steps))
+	}
+	if !strings.Contains(report.Text(), "All 20 steps passed") {
+		t.Errorf("Unexpected report text:\n%s", report.Text())
+	}
+
+	// No canary data is left behind
+	if policies, _ := service.aclEnforcer.GetPolicy(); len(policies) != 0 {
+		t.Errorf("ACL canary policies left behind: %v", policies)
+	}
+	if policies, _ := service.rbacEnforcer.GetPolicy(); len(policies) != 0 {
+		t.Errorf("RBAC canary policies left behind: %v", policies)
+	}
+	if roles, _ := service.rbacEnforcer.GetGroupingPolicy(); len(roles) != 0 {
+		t.Errorf("RBAC canary roles left behind: %v", roles)
+	}
+	if len(service.policyEngine.policies) != 0 {
+		t.Errorf("ABAC canary policy left behind")
+	}
+	var attributes int64
+	service.db.Model(&UserAttribute{}).Count(&attributes)
+	if attributes != 0 {
+		t.Errorf("Expected no user attributes after self-test, got %d", attributes)
+	}
+	if relationships, _ := service.relationshipGraph.ListRelationships(""); len(relationships) != 0 {
+		t.Errorf("ReBAC canary tuples left behind: %v", relationships)
+	}
+}
+
+func TestSelfTest_ReportsFailures(t *testing.T) {
+	service := setupTestService(t)
+
+	// A higher-priority deny policy overrides the canary's ABAC grant
+	service.policyEngine.AddPolicy(&ABACPolicy{
+		ID:         "deny_all",
+		Name:       "Deny All",
+		Effect:     "deny",
+		Priority:   1000,
+		Conditions: []PolicyCondition{{Type: "user", Field: "selftest", Operator: "ne", Value: ""}},
+	})
+
+	report := service.RunSelfTest()
+	if report.Passed {
+		t.Fatalf("Expected self-test to fail:\n%s", report.Text())
+	}
+
+	var failed []SelfTestStep
+	for _, step := range report.Steps {
+		if !step.Passed {
+			failed = append(failed, step)
+		}
+	}
+	if len(failed) != 1 || failed[0].Model != "abac" || failed[0].Step != "enforce" {
+		t.Errorf("Expected only the ABAC enforce step to fail, got %+v", failed)
+	}
+	if !strings.Contains(report.Text(), "1 of 20 steps failed") {
+		t.Errorf("Unexpected report text:\n%s", report.Text())
+	}
+	if _, exists := service.policyEngine.policies[report.Namespace+"/policy"]; exists {
+		t.Error("Canary ABAC policy was not cleaned up after a failure")
+	}
+}
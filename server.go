@@ -0,0 +1,132 @@
+// Multi-Model Authorization Microservice - HTTP Server Configuration
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serverConfig holds HTTP server tuning options
+type serverConfig struct {
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	MaxConcurrentH2   int  // Maximum concurrent streams per HTTP/2 connection
+	EnableH2C         bool // Accept HTTP/2 without TLS (prior knowledge), for internal traffic
+}
+
+// defaultServerConfig returns settings suited to PEPs that keep connections open and reuse them
+func defaultServerConfig() serverConfig {
+	return serverConfig{
+		ReadTimeout:       15 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		MaxHeaderBytes:    64 << 10,
+		MaxConcurrentH2:   250,
+		EnableH2C:         true,
+	}
+}
+
+// serverConfigFromEnv overrides the default server settings from environment variables
+func serverConfigFromEnv() (serverConfig, error) {
+	cfg := defaultServerConfig()
+
+	durations := []struct {
+		name   string
+		target *time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", &cfg.ReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", &cfg.ReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", &cfg.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", &cfg.IdleTimeout},
+	}
+	for _, d := range durations {
+		value := os.Getenv(d.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			return cfg, fmt.Errorf("invalid %s value: %s", d.name, value)
+		}
+		*d.target = parsed
+	}
+
+	integers := []struct {
+		name   string
+		target *int
+	}{
+		{"HTTP_MAX_HEADER_BYTES", &cfg.MaxHeaderBytes},
+		{"HTTP2_MAX_CONCURRENT_STREAMS", &cfg.MaxConcurrentH2},
+	}
+	for _, i := range integers {
+		value := os.Getenv(i.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return cfg, fmt.Errorf("invalid %s value: %s", i.name, value)
+		}
+		*i.target = parsed
+	}
+
+	if value := os.Getenv("HTTP_H2C"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid HTTP_H2C value: %s", value)
+		}
+		cfg.EnableH2C = enabled
+	}
+
+	return cfg, nil
+}
+
+// newHTTPServer creates an HTTP server using the given configuration
+func newHTTPServer(addr string, handler http.Handler, cfg serverConfig) *http.Server {
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetHTTP2(true)
+	protocols.SetUnencryptedHTTP2(cfg.EnableH2C)
+
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		Protocols:         protocols,
+		HTTP2: &http.HTTP2Config{
+			MaxConcurrentStreams: cfg.MaxConcurrentH2,
+		},
+	}
+}
+
+// listen opens a TCP listener, or a unix socket when addr is "unix:<path>".
+// A stale socket file left by a previous run is removed first.
+func listen(addr string) (net.Listener, error) {
+	path, isUnix := strings.CutPrefix(addr, "unix:")
+	if !isUnix {
+		return net.Listen("tcp", addr)
+	}
+
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale socket %s: %v", path, err)
+	}
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,194 @@
+// Multi-Model Authorization Microservice - HTTP Server Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// startConfiguredServer starts a test server using the production server configuration
+func startConfiguredServer(handler http.Handler, cfg serverConfig) *httptest.Server {
+	ts := httptest.NewUnstartedServer(handler)
+	ts.Config = newHTTPServer("", handler, cfg)
+	ts.Start()
+	return ts
+}
+
+// newClient creates a client speaking HTTP/1.1 or cleartext HTTP/2 with prior knowledge
+func newClient(h2c bool) *http.Client {
+	protocols := new(http.Protocols)
+	if h2c {
+		protocols.SetUnencryptedHTTP2(true)
+	} else {
+		protocols.SetHTTP1(true)
+	}
+	return &http.Client{Transport: &http.Transport{Protocols: protocols}}
+}
+
+func TestServer_H2C(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	})
+
+	ts := startConfiguredServer(handler, defaultServerConfig())
+	defer ts.Close()
+
+	for _, h2c := range []bool{true, false} {
+		resp, err := newClient(h2c).Get(ts.URL)
+		if err != nil {
+			t.Fatalf("Request failed (h2c=%v): %v", h2c, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		expected := "HTTP/1.1"
+		if h2c {
+			expected = "HTTP/2.0"
+		}
+		if string(body) != expected {
+			t.Errorf("Expected protocol %s, got %s", expected, body)
+		}
+	}
+
+	// h2c can be turned off
+	cfg := defaultServerConfig()
+	cfg.EnableH2C = false
+	ts2 := startConfiguredServer(handler, cfg)
+	defer ts2.Close()
+	if _, err := newClient(true).Get(ts2.URL); err == nil {
+		t.Error("Expected h2c request to fail when h2c is disabled")
+	}
+}
+
+func TestServer_ConfigFromEnv(t *testing.T) {
+	t.Setenv("HTTP_IDLE_TIMEOUT", "45s")
+	t.Setenv("HTTP_MAX_HEADER_BYTES", "8192")
+	t.Setenv("HTTP_H2C", "false")
+
+	cfg, err := serverConfigFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to read server config: %v", err)
+	}
+	if cfg.IdleTimeout != 45*time.Second || cfg.MaxHeaderBytes != 8192 || cfg.EnableH2C {
+		t.Errorf("Unexpected server config: %+v", cfg)
+	}
+
+	t.Setenv("HTTP_IDLE_TIMEOUT", "soon")
+	if _, err := serverConfigFromEnv(); err == nil {
+		t.Error("Expected error for invalid duration")
+	}
+}
+
+// benchmarkHealthChecks measures parallel requests from a single client
+func benchmarkHealthChecks(b *testing.B, client *http.Client) {
+	service := &AuthService{}
+	router := http.NewServeMux()
+	router.HandleFunc("/api/v1/health", service.healthHandler)
+
+	ts := startConfiguredServer(router, defaultServerConfig())
+	defer ts.Close()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			resp, err := client.Get(ts.URL + "/api/v1/health")
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+	})
+}
+
+// BenchmarkServer_HTTP1NoReuse opens a new connection per request, like short-lived PEP clients
+func BenchmarkServer_HTTP1NoReuse(b *testing.B) {
+	client := newClient(false)
+	client.Transport.(*http.Transport).DisableKeepAlives = true
+	benchmarkHealthChecks(b, client)
+}
+
+func BenchmarkServer_HTTP1KeepAlive(b *testing.B) {
+	benchmarkHealthChecks(b, newClient(false))
+}
+
+func BenchmarkServer_H2C(b *testing.B) {
+	benchmarkHealthChecks(b, newClient(true))
+}
+
+func TestServer_SeparateAdminRoutes(t *testing.T) {
+	service := setupTestService(t)
+
+	enforcement := mux.NewRouter()
+	service.registerEnforcementRoutes(enforcement.PathPrefix("/api/v1").Subrouter())
+	admin := mux.NewRouter()
+	service.registerAdminRoutes(admin.PathPrefix("/api/v1").Subrouter())
+
+	body := `{"model": "acl", "subject": "alice", "object": "doc", "action": "read"}`
+	req, _ := http.NewRequest("POST", "/api/v1/authorizations", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	enforcement.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("Expected enforcement router to serve authorizations, got %d", rr.Code)
+	}
+
+	req, _ = http.NewRequest("POST", "/api/v1/acl/policies", strings.NewReader(`{"subject": "alice", "object": "doc", "action": "read"}`))
+	rr = httptest.NewRecorder()
+	enforcement.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound && rr.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected enforcement router not to expose policy administration, got %d", rr.Code)
+	}
+
+	req, _ = http.NewRequest("POST", "/api/v1/acl/policies", strings.NewReader(`{"subject": "alice", "object": "doc", "action": "read"}`))
+	rr = httptest.NewRecorder()
+	admin.ServeHTTP(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Errorf("Expected admin router to serve policy administration, got %d", rr.Code)
+	}
+}
+
+func TestServer_UnixSocketListener(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin.sock")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatalf("Failed to create stale socket file: %v", err)
+	}
+
+	listener, err := listen("unix:" + path)
+	if err != nil {
+		t.Fatalf("Failed to listen on unix socket: %v", err)
+	}
+	server := newHTTPServer("", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "admin")
+	}), defaultServerConfig())
+	go server.Serve(listener)
+	defer server.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://admin/api/v1/health")
+	if err != nil {
+		t.Fatalf("Request over unix socket failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "admin" {
+		t.Errorf("Unexpected response over unix socket: %s", body)
+	}
+}
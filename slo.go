@@ -0,0 +1,251 @@
+// Multi-Model Authorization Microservice - Decision Latency SLOs
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultSLOThreshold is the decision latency each request should stay under
+	defaultSLOThreshold = 20 * time.Millisecond
+
+	// defaultSLOTarget is the fraction of decisions that must meet the threshold (p99)
+	defaultSLOTarget = 0.99
+
+	// sloBucketWidth is the resolution of the rolling windows
+	sloBucketWidth = time.Minute
+
+	// sloMinEvents is how many decisions a window needs before its budget can be exhausted
+	sloMinEvents = 20
+
+	// sloEvaluationInterval bounds how often observations trigger an alert evaluation
+	sloEvaluationInterval = 10 * time.Second
+)
+
+// sloWindows are the rolling windows burn rates are reported over; the longest
+// window decides whether the error budget is exhausted
+var sloWindows = []time.Duration{5 * time.Minute, time.Hour, 6 * time.Hour}
+
+// sloBucket counts decisions observed during one minute
+type sloBucket struct {
+	minute int64 // Unix minute the counts belong to
+	total  int64
+	slow   int64
+}
+
+// SLOWindowStatus reports compliance and burn rate over one rolling window
+type SLOWindowStatus struct {
+	Window    string  `json:"window"`
+	Total     int64   `json:"total"`
+	Slow      int64   `json:"slow"`
+	GoodRatio float64 `json:"good_ratio"`
+	BurnRate  float64 `json:"burn_rate"` // 1.0 consumes the error budget exactly at the allowed pace
+}
+
+// SLOStatus is the state of the decision latency SLO
+type SLOStatus struct {
+	Name      string            `json:"name"`
+	Threshold string            `json:"threshold"`
+	Target    float64           `json:"target"`
+	Exhausted bool              `json:"budget_exhausted"`
+	Windows   []SLOWindowStatus `json:"windows"`
+}
+
+// SLOAlert is posted to the alert webhook when the error budget is exhausted or recovers
+type SLOAlert struct {
+	Status    string          `json:"status"` // "firing" or "resolved"
+	SLO       string          `json:"slo"`
+	Threshold string          `json:"threshold"`
+	Target    float64         `json:"target"`
+	Window    SLOWindowStatus `json:"window"`
+	Time      time.Time       `json:"time"`
+}
+
+// sloTracker tracks decision latencies against a threshold and target in rolling windows
+type sloTracker struct {
+	mu            sync.Mutex
+	threshold     time.Duration
+	target        float64
+	buckets       []sloBucket
+	exhausted     bool
+	lastEvaluated time.Time
+	alertURL      string
+	client        *http.Client
+}
+
+// newSLOTracker creates a tracker whose buckets cover the longest rolling window
+func newSLOTracker(threshold time.Duration, target float64, alertURL string) *sloTracker {
+	longest := sloWindows[len(sloWindows)-1]
+	return &sloTracker{
+		threshold: threshold,
+		target:    target,
+		buckets:   make([]sloBucket, int(longest/sloBucketWidth)),
+		alertURL:  alertURL,
+		client:    &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// sloTrackerFromEnv builds the tracker from SLO_ENFORCE_LATENCY, SLO_TARGET and SLO_ALERT_URL
+func sloTrackerFromEnv() (*sloTracker, error) {
+	threshold := defaultSLOThreshold
+	if thresholdStr := os.Getenv("SLO_ENFORCE_LATENCY"); thresholdStr != "" {
+		parsed, err := time.ParseDuration(thresholdStr)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid SLO_ENFORCE_LATENCY value: %s", thresholdStr)
+		}
+		threshold = parsed
+	}
+
+	target := defaultSLOTarget
+	if targetStr := os.Getenv("SLO_TARGET"); targetStr != "" {
+		parsed, err := strconv.ParseFloat(targetStr, 64)
+		if err != nil || parsed <= 0 || parsed >= 1 {
+			return nil, fmt.Errorf("invalid SLO_TARGET value: %s", targetStr)
+		}
+		target = parsed
+	}
+
+	return newSLOTracker(threshold, target, os.Getenv("SLO_ALERT_URL")), nil
+}
+
+// Observe records the latency of one authorization decision
+func (st *sloTracker) Observe(latency time.Duration) {
+	if st == nil {
+		return
+	}
+	st.observe(time.Now(), latency)
+}
+
+// observe records a decision at the given time and periodically re-evaluates the budget
+func (st *sloTracker) observe(now time.Time, latency time.Duration) {
+	st.mu.Lock()
+	minute := now.Unix() / int64(sloBucketWidth/time.Second)
+	bucket := &st.buckets[minute%int64(len(st.buckets))]
+	if bucket.minute != minute {
+		*bucket = sloBucket{minute: minute}
+	}
+	bucket.total++
+	if latency > st.threshold {
+		bucket.slow++
+	}
+	evaluate := now.Sub(st.lastEvaluated) >= sloEvaluationInterval
+	st.mu.Unlock()
+
+	if evaluate {
+		st.evaluate(now)
+	}
+}
+
+// window sums the buckets within the window ending at now. Callers must hold the lock.
+func (st *sloTracker) window(now time.Time, window time.Duration) SLOWindowStatus {
+	current := now.Unix() / int64(sloBucketWidth/time.Second)
+	oldest := current - int64(window/sloBucketWidth) + 1
+
+	status := SLOWindowStatus{Window: window.String(), GoodRatio: 1}
+	for _, bucket := range st.buckets {
+		if bucket.minute >= oldest && bucket.minute <= current {
+			status.Total += bucket.total
+			status.Slow += bucket.slow
+		}
+	}
+	if status.Total > 0 {
+		badRatio := float64(status.Slow) / float64(status.Total)
+		status.GoodRatio = 1 - badRatio
+		status.BurnRate = badRatio / (1 - st.target)
+	}
+	return status
+}
+
+// Status reports the SLO over every rolling window
+func (st *sloTracker) Status(now time.Time) SLOStatus {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	status := SLOStatus{
+		Name:      "enforce_latency",
+		Threshold: st.threshold.String(),
+		Target:    st.target,
+		Exhausted: st.exhausted,
+		Windows:   []SLOWindowStatus{},
+	}
+	for _, window := range sloWindows {
+		status.Windows = append(status.Windows, st.window(now, window))
+	}
+	return status
+}
+
+// evaluate checks whether the error budget of the longest window is exhausted and
+// sends an alert when that state changes
+func (st *sloTracker) evaluate(now time.Time) {
+	st.mu.Lock()
+	st.lastEvaluated = now
+	longest := st.window(now, sloWindows[len(sloWindows)-1])
+	exhausted := longest.Total >= sloMinEvents && longest.BurnRate >= 1
+	changed := exhausted != st.exhausted
+	st.exhausted = exhausted
+	st.mu.Unlock()
+
+	if !changed {
+		return
+	}
+
+	alert := SLOAlert{
+		Status:    map[bool]string{true: "firing", false: "resolved"}[exhausted],
+		SLO:       "enforce_latency",
+		Threshold: st.threshold.String(),
+		Target:    st.target,
+		Window:    longest,
+		Time:      now,
+	}
+	log.Printf("SLO enforce_latency %s: burn rate %.2f over %s", alert.Status, longest.BurnRate, longest.Window)
+	serviceMetrics.Inc("slo_alerts_" + alert.Status + "_total")
+	st.sendAlert(alert)
+}
+
+// sendAlert posts the alert to the configured webhook without blocking
+func (st *sloTracker) sendAlert(alert SLOAlert) {
+	if st.alertURL == "" {
+		return
+	}
+
+	body, err := json.Marshal(alert)
+	if err != nil {
+		log.Printf("Failed to encode SLO alert: %v", err)
+		return
+	}
+
+	go func() {
+		resp, err := st.client.Post(st.alertURL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			log.Printf("Failed to deliver SLO alert: %v", err)
+			return
+		}
+		resp.Body.Close()
+	}()
+}
+
+// getSLOHandler reports decision latency compliance and burn rates
+func (s *AuthService) getSLOHandler(w http.ResponseWriter, r *http.Request) {
+	if s.sloTracker == nil {
+		writeJSONError(w, http.StatusNotFound, "SLO tracking is not enabled")
+		return
+	}
+
+	response := map[string]interface{}{
+		"slos": []SLOStatus{s.sloTracker.Status(time.Now())},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,119 @@
+// Multi-Model Authorization Microservice - SLO Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSLO_BurnRateAndAlerts(t *testing.T) {
+	alerts := make(chan SLOAlert, 2)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var alert SLOAlert
+		json.Unmarshal(body, &alert)
+		alerts <- alert
+	}))
+	defer collector.Close()
+
+	tracker := newSLOTracker(20*time.Millisecond, 0.9, collector.URL)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// 25% slow decisions burns a 10% budget 2.5x too fast
+	for i := 0; i < 40; i++ {
+		latency := time.Millisecond
+		if i%4 == 0 {
+			latency = 50 * time.Millisecond
+		}
+		tracker.observe(now, latency)
+	}
+	tracker.evaluate(now)
+
+	status := tracker.Status(now)
+	if !status.Exhausted {
+		t.Error("Expected error budget to be exhausted")
+	}
+	if status.Windows[0].Total != 40 || status.Windows[0].Slow != 10 {
+		t.Errorf("Unexpected window counts: %+v", status.Windows[0])
+	}
+	if burn := status.Windows[0].BurnRate; burn < 2.49 || burn > 2.51 {
+		t.Errorf("Expected burn rate 2.5, got %f", burn)
+	}
+
+	select {
+	case alert := <-alerts:
+		if alert.Status != "firing" {
+			t.Errorf("Expected firing alert, got %s", alert.Status)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected alert webhook to be called")
+	}
+
+	// Fast traffic later on only leaves the short window clean
+	later := now.Add(30 * time.Minute)
+	for i := 0; i < 400; i++ {
+		tracker.observe(later, time.Millisecond)
+	}
+	tracker.evaluate(later)
+
+	status = tracker.Status(later)
+	if status.Windows[0].Slow != 0 || status.Windows[2].Slow != 10 {
+		t.Errorf("Unexpected rolling windows: %+v", status.Windows)
+	}
+	if status.Exhausted {
+		t.Error("Expected budget to recover")
+	}
+
+	select {
+	case alert := <-alerts:
+		if alert.Status != "resolved" {
+			t.Errorf("Expected resolved alert, got %s", alert.Status)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected resolved alert")
+	}
+}
+
+func TestSLO_Handler(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/slo", service.getSLOHandler).Methods("GET")
+
+	req, _ := http.NewRequest("GET", "/api/v1/slo", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 when SLO tracking is disabled, got %d", rr.Code)
+	}
+
+	t.Setenv("SLO_ENFORCE_LATENCY", "5ms")
+	tracker, err := sloTrackerFromEnv()
+	if err != nil {
+		t.Fatalf("Failed to build tracker: %v", err)
+	}
+	service.sloTracker = tracker
+
+	req, _ = http.NewRequest("GET", "/api/v1/slo", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var response struct {
+		SLOs []SLOStatus `json:"slos"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if len(response.SLOs) != 1 || response.SLOs[0].Threshold != "5ms" || len(response.SLOs[0].Windows) != 3 {
+		t.Errorf("Unexpected SLO response: %s", rr.Body.String())
+	}
+
+	t.Setenv("SLO_TARGET", "1.5")
+	if _, err := sloTrackerFromEnv(); err == nil {
+		t.Error("Expected error for invalid SLO_TARGET")
+	}
+}
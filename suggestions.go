@@ -0,0 +1,322 @@
+// Multi-Model Authorization Microservice - Access Request Suggestions
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// defaultSuggestionLimit caps the number of suggestions returned when no limit is given
+const defaultSuggestionLimit = 10
+
+// suggestionExcludedRelations are never suggested as a direct grant: member and parent only
+// link nodes, and group_access is reserved for groups
+var suggestionExcludedRelations = map[string]bool{
+	"member":       true,
+	"parent":       true,
+	"group_access": true,
+}
+
+// AccessSuggestion is a single relationship or role change that would grant a permission
+type AccessSuggestion struct {
+	Kind         string    `json:"kind"` // "direct", "group_membership", "ancestor", "ancestor_group_membership" or "role"
+	Model        string    `json:"model"`
+	Subject      string    `json:"subject"`
+	Relationship string    `json:"relationship,omitempty"` // ReBAC tuple to add
+	Object       string    `json:"object,omitempty"`
+	Role         string    `json:"role,omitempty"` // RBAC role to assign
+	Description  string    `json:"description"`
+	Permissions  []string  `json:"permissions,omitempty"` // Permissions of the granting relationship
+	Impact       int       `json:"impact"`                // Objects (ReBAC) or policies (RBAC) the change grants access to
+	Path         []PathHop `json:"path,omitempty"`        // Grant path once the tuple is added
+}
+
+// leastPrivilegedRelation returns the relationship granting permission with the fewest
+// permissions, so suggestions do not grant more than needed
+func (rg *RelationshipGraph) leastPrivilegedRelation(permission string) string {
+	var names []string
+	for name := range rg.permissions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best := ""
+	for _, name := range names {
+		if suggestionExcludedRelations[name] || !rg.HasPermissionThroughRelationship(name, permission) {
+			continue
+		}
+		if best == "" || len(rg.permissions[name]) < len(rg.permissions[best]) {
+			best = name
+		}
+	}
+	return best
+}
+
+// IsGroup reports whether node has members
+func (rg *RelationshipGraph) IsGroup(node string) bool {
+	unlock := rg.readLock()
+	defer unlock()
+	return rg.isGroup(node)
+}
+
+// isGroup implements IsGroup for callers holding the lock
+func (rg *RelationshipGraph) isGroup(node string) bool {
+	rg.ensureObjectLoaded(node)
+	for _, rel := range rg.reverse.incomingTo(node) {
+		if rel.Relationship == "member" {
+			return true
+		}
+	}
+	return false
+}
+
+// reachableObjects counts the roots and every object inheriting from them through parent tuples
+func (rg *RelationshipGraph) reachableObjects(roots []string) int {
+	seen := make(map[string]bool)
+	queue := append([]string(nil), roots...)
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if seen[node] {
+			continue
+		}
+		seen[node] = true
+		for _, hop := range rg.outgoingHops(node) {
+			if hop.Relation == "parent" {
+				queue = append(queue, hop.Object)
+			}
+		}
+	}
+	return len(seen)
+}
+
+// groupImpact counts the objects a new member of group gains access to
+func (rg *RelationshipGraph) groupImpact(group string) int {
+	var targets []string
+	for _, hop := range rg.outgoingHops(group) {
+		if hop.Relation != "member" {
+			targets = append(targets, hop.Object)
+		}
+	}
+	return rg.reachableObjects(targets)
+}
+
+// SuggestRelationships lists single-tuple changes that would give subject the permission
+// behind action on object: a direct relationship on the object or one of its ancestors,
+// or membership in a group that already has access. Suggestions granting the least
+// additional access come first.
+func (rg *RelationshipGraph) SuggestRelationships(subject, object, action string, maxDepth int) []AccessSuggestion {
+	unlock := rg.readLock()
+	defer unlock()
+
+	permission := rg.mapActionToPermission(action)
+	relation := rg.leastPrivilegedRelation(permission)
+
+	suggestions := []AccessSuggestion{}
+	seen := make(map[string]bool)
+	add := func(suggestion AccessSuggestion) {
+		key := suggestion.Relationship + ":" + suggestion.Object
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		suggestion.Model = string(ModelReBAC)
+		suggestion.Subject = subject
+		suggestions = append(suggestions, suggestion)
+	}
+
+	// Walk from the object up through its ancestors; inheritedPath leads from a target to object
+	type target struct {
+		node          string
+		inheritedPath []PathHop
+	}
+	targets := []target{{node: object}}
+	visited := map[string]bool{object: true}
+	for i := 0; i < len(targets); i++ {
+		current := targets[i]
+		rg.ensureObjectLoaded(current.node)
+		incoming := rg.reverse.incomingTo(current.node)
+
+		directKind, groupKind := "direct", "group_membership"
+		if current.node != object {
+			directKind, groupKind = "ancestor", "ancestor_group_membership"
+		}
+
+		if relation != "" {
+			add(AccessSuggestion{
+				Kind:         directKind,
+				Relationship: relation,
+				Object:       current.node,
+				Description:  fmt.Sprintf("add %s as %s on %s", subject, relation, current.node),
+				Permissions:  rg.GetPermissionsForRelationship(relation),
+				Impact:       rg.reachableObjects([]string{current.node}),
+				Path:         append([]PathHop{{Subject: subject, Relation: relation, Object: current.node}}, current.inheritedPath...),
+			})
+		}
+
+		for _, rel := range incoming {
+			if rel.Subject == subject || !rg.HasPermissionThroughRelationship(rel.Relationship, permission) || !rg.isGroup(rel.Subject) {
+				continue
+			}
+			path := []PathHop{
+				{Subject: subject, Relation: "member", Object: rel.Subject},
+				{Subject: rel.Subject, Relation: rel.Relationship, Object: current.node},
+			}
+			add(AccessSuggestion{
+				Kind:         groupKind,
+				Relationship: "member",
+				Object:       rel.Subject,
+				Description:  fmt.Sprintf("add %s to group %s (%s on %s)", subject, rel.Subject, rel.Relationship, current.node),
+				Permissions:  rg.GetPermissionsForRelationship(rel.Relationship),
+				Impact:       rg.groupImpact(rel.Subject),
+				Path:         append(path, current.inheritedPath...),
+			})
+		}
+
+		for _, rel := range incoming {
+			if rel.Relationship != "parent" || visited[rel.Subject] || len(current.inheritedPath) >= maxDepth {
+				continue
+			}
+			visited[rel.Subject] = true
+			inherited := append([]PathHop{{Subject: rel.Subject, Relation: "parent", Object: current.node}}, current.inheritedPath...)
+			targets = append(targets, target{node: rel.Subject, inheritedPath: inherited})
+		}
+	}
+
+	sortSuggestions(suggestions)
+	return suggestions
+}
+
+// SuggestRoles lists RBAC roles whose assignment would let subject perform action on
+// object, including roles that inherit the permission from a parent role
+func (s *AuthService) SuggestRoles(subject, object, action string) ([]AccessSuggestion, error) {
+	subjects, err := s.rbacEnforcer.GetAllSubjects()
+	if err != nil {
+		return nil, err
+	}
+	roles, err := s.rbacEnforcer.GetAllRoles()
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := []AccessSuggestion{}
+	seen := map[string]bool{subject: true}
+	for _, role := range append(subjects, roles...) {
+		if seen[role] {
+			continue
+		}
+		seen[role] = true
+
+		allowed, err := s.rbacEnforcer.Enforce(role, object, action)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			continue
+		}
+		permissions, err := s.rbacEnforcer.GetImplicitPermissionsForUser(role)
+		if err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, AccessSuggestion{
+			Kind:        "role",
+			Model:       string(ModelRBAC),
+			Subject:     subject,
+			Role:        role,
+			Description: fmt.Sprintf("assign role %s to %s", role, subject),
+			Impact:      len(permissions),
+		})
+	}
+
+	sortSuggestions(suggestions)
+	return suggestions, nil
+}
+
+// sortSuggestions orders suggestions by the access they grant, narrowest first
+func sortSuggestions(suggestions []AccessSuggestion) {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Impact != b.Impact {
+			return a.Impact < b.Impact
+		}
+		if len(a.Permissions) != len(b.Permissions) {
+			return len(a.Permissions) < len(b.Permissions)
+		}
+		return a.Description < b.Description
+	})
+}
+
+// getAccessSuggestionsHandler suggests the smallest relationship or role changes that
+// would grant a subject an action on an object, e.g. to prefill "Request access" forms
+func (s *AuthService) getAccessSuggestionsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	subject, object, action := query.Get("subject"), query.Get("object"), query.Get("action")
+	if subject == "" || object == "" || action == "" {
+		writeJSONError(w, http.StatusBadRequest, "subject, object, and action are required")
+		return
+	}
+
+	limit := defaultSuggestionLimit
+	if limitStr := query.Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			writeJSONError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = parsed
+	}
+
+	model := AccessControlModel(query.Get("model"))
+	if model == "" {
+		model = ModelReBAC
+	}
+
+	var allowed bool
+	var suggestions []AccessSuggestion
+	var err error
+	switch model {
+	case ModelReBAC:
+		allowed, _ = s.relationshipGraph.CheckReBACAccessHops(subject, object, action)
+		if !allowed {
+			suggestions = s.relationshipGraph.SuggestRelationships(subject, object, action, s.currentSettings().maxDepthLimit)
+		}
+	case ModelRBAC:
+		allowed, err = s.Enforce(ModelRBAC, subject, object, action, nil)
+		if err == nil && !allowed {
+			suggestions, err = s.SuggestRoles(subject, object, action)
+		}
+	default:
+		writeJSONError(w, http.StatusBadRequest, "model must be 'rebac' or 'rbac'")
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Suggestion error: %v", err))
+		return
+	}
+
+	if suggestions == nil {
+		suggestions = []AccessSuggestion{}
+	}
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	response := map[string]interface{}{
+		"subject":     subject,
+		"object":      object,
+		"action":      action,
+		"allowed":     allowed,
+		"suggestions": suggestions,
+		"count":       len(suggestions),
+		"model":       string(model),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,115 @@
+// Multi-Model Authorization Microservice - Access Request Suggestion Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSuggestions_ReBACRankedByImpact(t *testing.T) {
+	service := setupTestService(t)
+	rg := service.relationshipGraph
+
+	rg.AddRelationship("folder1", "parent", "doc1")
+	rg.AddRelationship("engineering", "viewer", "folder1")
+	rg.AddRelationship("charlie", "member", "engineering")
+	rg.AddRelationship("readers", "viewer", "doc1")
+	rg.AddRelationship("dave", "member", "readers")
+	rg.AddRelationship("everyone", "editor", "doc1")
+	rg.AddRelationship("everyone", "editor", "doc2")
+	rg.AddRelationship("everyone", "editor", "doc3")
+	rg.AddRelationship("eve", "member", "everyone")
+	// alice is not a group, so her access is never suggested as a membership
+	rg.AddRelationship("alice", "owner", "doc1")
+
+	suggestions := rg.SuggestRelationships("bob", "doc1", "read", 10)
+	expected := []struct {
+		kind, relationship, object string
+		impact                     int
+	}{
+		{"direct", "viewer", "doc1", 1},
+		{"group_membership", "member", "readers", 1},
+		{"ancestor", "viewer", "folder1", 2},
+		{"ancestor_group_membership", "member", "engineering", 2},
+		{"group_membership", "member", "everyone", 3},
+	}
+	if len(suggestions) != len(expected) {
+		t.Fatalf("Expected %d suggestions, got %+v", len(expected), suggestions)
+	}
+	for i, e := range expected {
+		got := suggestions[i]
+		if got.Kind != e.kind || got.Relationship != e.relationship || got.Object != e.object || got.Impact != e.impact {
+			t.Errorf("Suggestion %d: expected %+v, got %+v", i, e, got)
+		}
+	}
+
+	// Every suggestion actually grants access once applied
+	for _, suggestion := range suggestions {
+		rg.AddRelationship("bob", suggestion.Relationship, suggestion.Object)
+		if allowed, _ := rg.CheckReBACAccess("bob", "doc1", "read"); !allowed {
+			t.Errorf("Suggestion %q does not grant access", suggestion.Description)
+		}
+		rg.RemoveRelationship("bob", suggestion.Relationship, suggestion.Object)
+	}
+
+	// Write access needs editor, which the viewer groups do not provide
+	suggestions = rg.SuggestRelationships("bob", "doc1", "write", 10)
+	if len(suggestions) != 3 || suggestions[0].Relationship != "editor" || suggestions[0].Object != "doc1" {
+		t.Errorf("Unexpected write suggestions: %+v", suggestions)
+	}
+}
+
+func TestSuggestions_Handler(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/relationships/suggestions", service.getAccessSuggestionsHandler).Methods("GET")
+
+	service.relationshipGraph.AddRelationship("bob", "viewer", "doc1")
+	service.rbacEnforcer.AddPolicy("reader", "report", "read", "allow")
+	service.rbacEnforcer.AddPolicy("auditor", "ledger", "read", "allow")
+	service.rbacEnforcer.AddRoleForUser("auditor", "reader")
+
+	var response struct {
+		Allowed     bool               `json:"allowed"`
+		Suggestions []AccessSuggestion `json:"suggestions"`
+		Count       int                `json:"count"`
+	}
+	get := func(url string) int {
+		req, _ := http.NewRequest("GET", url, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		response.Suggestions = nil
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr.Code
+	}
+
+	if code := get("/api/v1/relationships/suggestions?subject=bob&object=doc1&action=read"); code != http.StatusOK || !response.Allowed || response.Count != 0 {
+		t.Errorf("Expected no suggestions when already allowed: %d %+v", code, response)
+	}
+
+	if code := get("/api/v1/relationships/suggestions?subject=carol&object=report&action=read&model=rbac"); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if response.Allowed || response.Count != 2 || response.Suggestions[0].Role != "reader" || response.Suggestions[1].Role != "auditor" {
+		t.Errorf("Unexpected role suggestions: %+v", response)
+	}
+
+	if code := get("/api/v1/relationships/suggestions?subject=carol&object=report&action=read&model=rbac&limit=1"); code != http.StatusOK || response.Count != 1 {
+		t.Errorf("Expected the limit to apply: %d %+v", code, response)
+	}
+
+	for _, url := range []string{
+		"/api/v1/relationships/suggestions?subject=carol&object=report",
+		"/api/v1/relationships/suggestions?subject=carol&object=report&action=read&model=abac",
+		"/api/v1/relationships/suggestions?subject=carol&object=report&action=read&limit=0",
+	} {
+		if code := get(url); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", url, code)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Multi-Model Authorization Microservice - Tenant Scoping
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// tenantHeader selects the tenant of a request when no tenant field or parameter is given
+const tenantHeader = "X-Tenant"
+
+var errTenantNotFound = errors.New("tenant not found")
+
+// tenantScope namespaces the subjects, objects, roles and ABAC policy IDs of one tenant as
+// "<tenant>/<name>", the layout tenant templates already create. The zero value is the
+// global scope, in which names are used as given and the data of every tenant is visible.
+type tenantScope struct {
+	tenant string
+}
+
+// global reports whether the scope is the global scope
+func (t tenantScope) global() bool {
+	return t.tenant == ""
+}
+
+// qualify returns the stored form of a tenant-local name. Names that already carry the
+// tenant prefix are left alone, so names read back from the database can be passed again.
+func (t tenantScope) qualify(name string) string {
+	if t.global() || name == "" || strings.HasPrefix(name, t.tenant+"/") {
+		return name
+	}
+	return t.tenant + "/" + name
+}
+
+// local returns the tenant-local form of a stored name
+func (t tenantScope) local(name string) string {
+	if t.global() {
+		return name
+	}
+	return strings.TrimPrefix(name, t.tenant+"/")
+}
+
+// owns reports whether a stored name belongs to the scope
+func (t tenantScope) owns(name string) bool {
+	return t.global() || strings.HasPrefix(name, t.tenant+"/")
+}
+
+// ownedRules keeps the ACL/RBAC rules or role assignments whose first n fields (subject
+// and object, or user and role) belong to the scope
+func (t tenantScope) ownedRules(rules [][]string, n int) [][]string {
+	if t.global() {
+		return rules
+	}
+	owned := make([][]string, 0, len(rules))
+	for _, rule := range rules {
+		if len(rule) < n {
+			continue
+		}
+		inScope := true
+		for _, name := range rule[:n] {
+			inScope = inScope && t.owns(name)
+		}
+		if inScope {
+			owned = append(owned, rule)
+		}
+	}
+	return owned
+}
+
+// localRules returns copies of rules with their first n fields in tenant-local form
+func (t tenantScope) localRules(rules [][]string, n int) [][]string {
+	if t.global() {
+		return rules
+	}
+	local := make([][]string, 0, len(rules))
+	for _, rule := range rules {
+		rule = append([]string(nil), rule...)
+		for i := 0; i < n && i < len(rule); i++ {
+			rule[i] = t.local(rule[i])
+		}
+		local = append(local, rule)
+	}
+	return local
+}
+
+// localMetadata re-keys rule metadata by tenant-local rule IDs
+func (t tenantScope) localMetadata(metadata map[string]*PolicyMetadata) map[string]*PolicyMetadata {
+	if t.global() {
+		return metadata
+	}
+	local := make(map[string]*PolicyMetadata, len(metadata))
+	for key, m := range metadata {
+		local[t.localRuleKey(key)] = m
+	}
+	return local
+}
+
+// localRuleKey converts a "subject:object:action" rule key to tenant-local names
+func (t tenantScope) localRuleKey(key string) string {
+	parts := splitLabelKey(key)
+	for i := range parts {
+		parts[i] = t.local(parts[i])
+	}
+	return labelKey(parts...)
+}
+
+// ownsRelationship reports whether both ends of a tuple belong to the scope
+func (t tenantScope) ownsRelationship(rel Relationship) bool {
+	return t.owns(rel.Subject) && t.owns(rel.Object)
+}
+
+// localRelationship returns a tuple with its ends in tenant-local form
+func (t tenantScope) localRelationship(rel Relationship) Relationship {
+	return Relationship{Subject: t.local(rel.Subject), Relationship: rel.Relationship, Object: t.local(rel.Object)}
+}
+
+// ownsPolicy reports whether an ABAC policy belongs to the scope
+func (t tenantScope) ownsPolicy(policy *ABACPolicy) bool {
+	return t.global() || policy.Tenant == t.tenant
+}
+
+// tenantScope returns the scope of a tenant, or the global scope for "". Known tenants are
+// remembered, as tenants are never deleted.
+func (s *AuthService) tenantScope(tenant string) (tenantScope, error) {
+	if tenant == "" {
+		return tenantScope{}, nil
+	}
+	if _, known := s.knownTenants.Load(tenant); !known {
+		var count int64
+		if err := s.db.Model(&Tenant{}).Where("name = ?", tenant).Count(&count).Error; err != nil {
+			return tenantScope{}, fmt.Errorf("failed to look up tenant: %v", err)
+		}
+		if count == 0 {
+			return tenantScope{}, errTenantNotFound
+		}
+		s.knownTenants.Store(tenant, true)
+	}
+	return tenantScope{tenant: tenant}, nil
+}
+
+// requestTenant resolves the tenant a request is scoped to: explicit (a request body
+// field), else the "tenant" query parameter, else the X-Tenant header. Unknown tenants are
+// rejected with 404 and false is returned.
+func (s *AuthService) requestTenant(w http.ResponseWriter, r *http.Request, explicit string) (tenantScope, bool) {
+	tenant := explicit
+	if tenant == "" {
+		tenant = r.URL.Query().Get("tenant")
+	}
+	if tenant == "" {
+		tenant = r.Header.Get(tenantHeader)
+	}
+
+	scope, err := s.tenantScope(tenant)
+	switch {
+	case errors.Is(err, errTenantNotFound):
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("Tenant not found: %s", tenant))
+		return scope, false
+	case err != nil:
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return scope, false
+	}
+	return scope, true
+}
+
+// migratePolicyTenants assigns ABAC policies created by tenant templates before policies
+// had a tenant to their tenant, recognized by the "<tenant>/" prefix of their ID
+func migratePolicyTenants(db *gorm.DB) error {
+	var tenants []Tenant
+	if err := db.Find(&tenants).Error; err != nil {
+		return err
+	}
+	for _, tenant := range tenants {
+		prefix := tenant.Name + "/"
+		err := db.Model(&ABACPolicy{}).
+			Where("tenant = ? AND substr(id, 1, ?) = ?", "", len(prefix), prefix).
+			Update("tenant", tenant.Name).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
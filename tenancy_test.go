@@ -0,0 +1,203 @@
+// Multi-Model Authorization Microservice - Tenant Scoping Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"casbin-authorization-server/authzpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func setupTenancyService(t *testing.T) *AuthService {
+	service := setupTestService(t)
+	for _, name := range []string{"acme", "globex"} {
+		if _, _, err := service.CreateTenant(name, ""); err != nil {
+			t.Fatalf("Failed to create tenant %s: %v", name, err)
+		}
+	}
+	return service
+}
+
+func TestTenancy_DataIsIsolatedPerTenant(t *testing.T) {
+	service := setupTenancyService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/acl/policies", service.addACLPolicyHandler).Methods("POST")
+	router.HandleFunc("/api/v1/acl/policies", service.getACLPoliciesHandler).Methods("GET")
+	router.HandleFunc("/api/v1/users/{userId}/roles", service.addUserRoleHandler).Methods("POST")
+	router.HandleFunc("/api/v1/users/{userId}/roles", service.getUserRolesHandler).Methods("GET")
+
+	send := func(method, url, tenant, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if tenant != "" {
+			req.Header.Set(tenantHeader, tenant)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The same names are set up in acme only
+	if rr := send("POST", "/api/v1/acl/policies?tenant=acme", "", `{"subject": "alice", "object": "report", "action": "read"}`); rr.Code != http.StatusCreated {
+		t.Fatalf("Failed to add ACL policy: %d %s", rr.Code, rr.Body.String())
+	}
+	send("POST", "/api/v1/users/alice/roles", "acme", `{"role": "admin"}`)
+	send("PUT", "/api/v1/users/alice/attributes", "acme", `{"attributes": {"department": "finance"}}`)
+	send("POST", "/api/v1/relationships", "acme", `{"subject": "alice", "relationship": "owner", "object": "ledger"}`)
+	send("POST", "/api/v1/abac/policies", "acme", `{"id": "finance", "name": "Finance", "effect": "allow", "conditions": [{"type": "user", "field": "department", "operator": "eq", "value": "finance"}]}`)
+
+	checks := []struct {
+		model, object, action string
+	}{
+		{"acl", "report", "read"},
+		{"rbac", "root", "delete"}, // Granted to acme/admin by the default template
+		{"abac", "ledger", "read"},
+		{"rebac", "ledger", "write"},
+	}
+	for _, check := range checks {
+		for tenant, expected := range map[string]int{"acme": http.StatusOK, "globex": http.StatusForbidden, "": http.StatusForbidden} {
+			body := `{"tenant": "` + tenant + `", "model": "` + check.model + `", "subject": "alice", "object": "` + check.object + `", "action": "` + check.action + `"}`
+			if rr := send("POST", "/api/v1/authorizations", "", body); rr.Code != expected {
+				t.Errorf("%s check in tenant %q: expected %d, got %d", check.model, tenant, expected, rr.Code)
+			}
+		}
+	}
+
+	// Lists only show the tenant's data, with tenant-local names
+	var policies struct {
+		Policies [][]string `json:"policies"`
+	}
+	json.Unmarshal(send("GET", "/api/v1/acl/policies", "acme", "").Body.Bytes(), &policies)
+	if len(policies.Policies) != 1 || strings.Join(policies.Policies[0], ",") != "alice,report,read,allow" {
+		t.Errorf("Expected acme's policy with local names, got %v", policies.Policies)
+	}
+	json.Unmarshal(send("GET", "/api/v1/acl/policies", "globex", "").Body.Bytes(), &policies)
+	if len(policies.Policies) != 0 {
+		t.Errorf("Expected no policies in globex, got %v", policies.Policies)
+	}
+
+	var roles struct {
+		Roles []string `json:"roles"`
+	}
+	json.Unmarshal(send("GET", "/api/v1/users/alice/roles", "acme", "").Body.Bytes(), &roles)
+	if strings.Join(roles.Roles, ",") != "admin" {
+		t.Errorf("Expected the local admin role, got %v", roles.Roles)
+	}
+
+	var relationships struct {
+		Relationships []Relationship `json:"relationships"`
+	}
+	json.Unmarshal(send("GET", "/api/v1/relationships", "globex", "").Body.Bytes(), &relationships)
+	if len(relationships.Relationships) != 1 || relationships.Relationships[0] != (Relationship{"admins", "owner", "root"}) {
+		t.Errorf("Expected only globex's template tuple, got %+v", relationships.Relationships)
+	}
+	json.Unmarshal(send("GET", "/api/v1/relationships?subject=alice", "acme", "").Body.Bytes(), &relationships)
+	if len(relationships.Relationships) != 1 || relationships.Relationships[0] != (Relationship{"alice", "owner", "ledger"}) {
+		t.Errorf("Expected acme's tuple with local names, got %+v", relationships.Relationships)
+	}
+
+	if rr := send("GET", "/api/v1/abac/policies/finance", "globex", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected acme's ABAC policy to be hidden from globex, got %d", rr.Code)
+	}
+	if rr := send("GET", "/api/v1/abac/policies/finance", "acme", ""); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"tenant":"acme"`) {
+		t.Errorf("Expected acme's ABAC policy, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	if rr := send("GET", "/api/v1/acl/policies", "initech", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown tenant, got %d", rr.Code)
+	}
+}
+
+func TestTenancy_ABACPoliciesOnlyApplyToTheirTenant(t *testing.T) {
+	service := setupTenancyService(t)
+	acme, _ := service.tenantScope("acme")
+	globex, _ := service.tenantScope("globex")
+
+	for _, scope := range []tenantScope{acme, globex} {
+		service.saveUserAttribute(scope.qualify("bob"), "tenant", scope.tenant)
+		service.saveObjectAttribute(scope.qualify("report"), "tenant", scope.tenant)
+	}
+	service.policyEngine.AddPolicy(&ABACPolicy{
+		ID:       "globex/deny-all",
+		Name:     "Globex denies everything",
+		Effect:   "deny",
+		Priority: 100,
+		Tenant:   "globex",
+		Conditions: []PolicyCondition{
+			{Type: "action", Field: "action", Operator: "eq", Value: "read"},
+		},
+	})
+
+	// The template's members-read policy allows acme; globex's deny does not leak into acme
+	if allowed, _ := service.EnforceInTenant(acme, ModelABAC, "bob", "report", "read", nil, freshnessDefault); !allowed {
+		t.Error("Expected acme's template policy to allow bob")
+	}
+	if allowed, _ := service.EnforceInTenant(globex, ModelABAC, "bob", "report", "read", nil, freshnessDefault); allowed {
+		t.Error("Expected globex's deny policy to apply in globex")
+	}
+
+	// Tenant policies do not apply to global checks
+	service.saveUserAttribute("bob", "tenant", "globex")
+	service.saveObjectAttribute("report", "tenant", "globex")
+	if decision := service.matchABACAttributes("bob", "report", "read", nil, false); decision.Policy != nil {
+		t.Errorf("Expected no tenant policy to decide a global check, got %s", decision.Policy.ID)
+	}
+
+	explanation, err := service.explainInTenant(acme, ModelABAC, "bob", "report", "read", nil, freshnessDefault, nil)
+	if err != nil {
+		t.Fatalf("Failed to explain: %v", err)
+	}
+	if len(explanation.Policies) != 1 || explanation.Policies[0].ID != "acme/members-read" {
+		t.Errorf("Expected only acme's policies to be explained, got %+v", explanation.Policies)
+	}
+}
+
+func TestTenancy_MigratesTemplatePolicies(t *testing.T) {
+	service := setupTenancyService(t)
+	service.db.Model(&ABACPolicy{}).Where("id = ?", "acme/members-read").Update("tenant", "")
+	service.policyEngine.AddPolicy(&ABACPolicy{ID: "acmecorp/other", Name: "Other", Effect: "allow"})
+
+	if err := migratePolicyTenants(service.db); err != nil {
+		t.Fatalf("Migration failed: %v", err)
+	}
+	var migrated, other ABACPolicy
+	service.db.First(&migrated, "id = ?", "acme/members-read")
+	service.db.First(&other, "id = ?", "acmecorp/other")
+	if migrated.Tenant != "acme" || other.Tenant != "" {
+		t.Errorf("Expected only acme's template policy to be assigned, got %q and %q", migrated.Tenant, other.Tenant)
+	}
+}
+
+func TestTenancy_GRPCEnforce(t *testing.T) {
+	service := setupTenancyService(t)
+	service.aclEnforcer.AddPolicy("acme/alice", "acme/report", "read", "allow")
+	client := authzpb.NewAuthorizationServiceClient(setupTestGRPC(t, service))
+
+	check := &authzpb.EnforceRequest{Model: "acl", Subject: "alice", Object: "report", Action: "read", Tenant: "acme"}
+	if resp, err := client.Enforce(context.Background(), check); err != nil || !resp.Allowed {
+		t.Errorf("Expected access in acme, got %v %v", resp, err)
+	}
+
+	check.Tenant = ""
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-tenant", "globex")
+	if resp, err := client.Enforce(ctx, check); err != nil || resp.Allowed {
+		t.Errorf("Expected no access in globex, got %v %v", resp, err)
+	}
+
+	check.Tenant = "initech"
+	if _, err := client.Enforce(context.Background(), check); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for an unknown tenant, got %v", err)
+	}
+}
@@ -0,0 +1,420 @@
+// Multi-Model Authorization Microservice - Tenants and Bootstrap Templates
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// tenantPlaceholder is replaced with the tenant name when a template is applied
+const tenantPlaceholder = "{tenant}"
+
+// defaultTenantTemplate is the template applied when a tenant is created without one
+const defaultTenantTemplate = "default"
+
+var (
+	errTenantExists          = errors.New("tenant already exists")
+	errTenantTemplateUnknown = errors.New("tenant template not found")
+	errInvalidTenantName     = errors.New("tenant name must be lowercase letters, digits, '-' or '_'")
+	tenantNamePattern        = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+)
+
+// Tenant represents a tenant created through the tenants API
+type Tenant struct {
+	Name      string       `json:"name" gorm:"primaryKey"`
+	Template  string       `json:"template"`
+	Quota     *TenantQuota `json:"quota,omitempty" gorm:"serializer:json"` // Nil for tenants with the default quota
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// TemplateRolePermission is a role permission granted by a tenant template
+type TemplateRolePermission struct {
+	Role   string `json:"role"`
+	Object string `json:"object"`
+	Action string `json:"action"`
+}
+
+// TenantTemplate is a vetted baseline of roles, ABAC policies and relationships applied
+// to new tenants. Any value may contain the {tenant} placeholder.
+type TenantTemplate struct {
+	Name          string                   `json:"name"`
+	Description   string                   `json:"description,omitempty"`
+	Roles         []TemplateRolePermission `json:"roles"`
+	ABACPolicies  []ABACPolicy             `json:"abac_policies"`
+	Relationships []Relationship           `json:"relationships"`
+}
+
+// TenantTemplateRecord stores a registered tenant template in the database
+type TenantTemplateRecord struct {
+	Name       string `gorm:"primaryKey"`
+	Definition string // JSON-encoded TenantTemplate
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// CreateTenantRequest represents a request to create a tenant
+type CreateTenantRequest struct {
+	Name     string `json:"name"`
+	Template string `json:"template"` // Template to bootstrap the tenant from ("default" when empty)
+}
+
+// builtinTenantTemplate returns the default baseline: admin, editor and viewer roles on the
+// tenant's root object, an ABAC policy scoped to the tenant and an owning admins group
+func builtinTenantTemplate() *TenantTemplate {
+	return &TenantTemplate{
+		Name:        defaultTenantTemplate,
+		Description: "Admin, editor and viewer roles on the tenant root object",
+		Roles: []TemplateRolePermission{
+			{Role: "{tenant}/admin", Object: "{tenant}/root", Action: "read"},
+			{Role: "{tenant}/admin", Object: "{tenant}/root", Action: "write"},
+			{Role: "{tenant}/admin", Object: "{tenant}/root", Action: "delete"},
+			{Role: "{tenant}/editor", Object: "{tenant}/root", Action: "read"},
+			{Role: "{tenant}/editor", Object: "{tenant}/root", Action: "write"},
+			{Role: "{tenant}/viewer", Object: "{tenant}/root", Action: "read"},
+		},
+		ABACPolicies: []ABACPolicy{
+			{
+				ID:          "members-read",
+				Name:        "{tenant} members can read tenant objects",
+				Description: "Users of the tenant may read objects owned by the tenant",
+				Effect:      "allow",
+				Priority:    10,
+				Conditions: []PolicyCondition{
+					{Type: "user", Field: "tenant", Operator: "eq", Value: "{tenant}", LogicOp: "and"},
+					{Type: "object", Field: "tenant", Operator: "eq", Value: "{tenant}", LogicOp: "and"},
+					{Type: "action", Field: "action", Operator: "eq", Value: "read"},
+				},
+			},
+		},
+		Relationships: []Relationship{
+			{Subject: "{tenant}/admins", Relationship: "owner", Object: "{tenant}/root"},
+		},
+	}
+}
+
+// instantiate returns a copy of the template with the tenant placeholder replaced.
+// ABAC policies belong to the tenant, and their IDs are prefixed with the tenant name to
+// keep them unique.
+func (t *TenantTemplate) instantiate(tenant string) *TenantTemplate {
+	sub := func(value string) string {
+		return strings.ReplaceAll(value, tenantPlaceholder, tenant)
+	}
+
+	out := &TenantTemplate{Name: t.Name, Description: t.Description}
+	for _, role := range t.Roles {
+		out.Roles = append(out.Roles, TemplateRolePermission{
+			Role:   sub(role.Role),
+			Object: sub(role.Object),
+			Action: sub(role.Action),
+		})
+	}
+	for _, policy := range t.ABACPolicies {
+		instance := ABACPolicy{
+			ID:          tenant + "/" + sub(policy.ID),
+			Name:        sub(policy.Name),
+			Description: sub(policy.Description),
+			Effect:      policy.Effect,
+			Priority:    policy.Priority,
+			Actions:     policy.Actions,
+			Obligations: policy.Obligations,
+			Advice:      policy.Advice,
+			Tenant:      tenant,
+		}
+		instantiate := func(condition PolicyCondition) PolicyCondition {
+			return PolicyCondition{
+				Type:      condition.Type,
+				Field:     sub(condition.Field),
+				Operator:  condition.Operator,
+				Value:     sub(condition.Value),
+				LogicOp:   condition.LogicOp,
+				ValueFrom: sub(condition.ValueFrom),
+			}
+		}
+		for _, condition := range policy.Conditions {
+			instance.Conditions = append(instance.Conditions, instantiate(condition))
+		}
+		instance.Group = policy.Group.mapConditions(instantiate)
+		out.ABACPolicies = append(out.ABACPolicies, instance)
+	}
+	for _, rel := range t.Relationships {
+		out.Relationships = append(out.Relationships, Relationship{
+			Subject:      sub(rel.Subject),
+			Relationship: sub(rel.Relationship),
+			Object:       sub(rel.Object),
+		})
+	}
+	return out
+}
+
+// validate checks that a template is complete enough to be applied
+func (t *TenantTemplate) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("template name is required")
+	}
+	for _, role := range t.Roles {
+		if role.Role == "" || role.Object == "" || role.Action == "" {
+			return fmt.Errorf("template roles require role, object and action")
+		}
+	}
+	for _, policy := range t.ABACPolicies {
+		if policy.ID == "" || policy.Name == "" {
+			return fmt.Errorf("template ABAC policies require id and name")
+		}
+		if policy.Effect != "allow" && policy.Effect != "deny" {
+			return fmt.Errorf("template ABAC policy %s: effect must be 'allow' or 'deny'", policy.ID)
+		}
+		if err := validateConditionGroup(&policy); err != nil {
+			return fmt.Errorf("template ABAC policy %s: %v", policy.ID, err)
+		}
+		if err := validatePolicyExpression(&policy); err != nil {
+			return fmt.Errorf("template ABAC policy %s: %v", policy.ID, err)
+		}
+	}
+	for _, rel := range t.Relationships {
+		if rel.Subject == "" || rel.Relationship == "" || rel.Object == "" {
+			return fmt.Errorf("template relationships require subject, relationship and object")
+		}
+	}
+	return nil
+}
+
+// GetTenantTemplate returns a registered template, falling back to the built-in default
+func (s *AuthService) GetTenantTemplate(name string) (*TenantTemplate, error) {
+	var record TenantTemplateRecord
+	err := s.db.First(&record, "name = ?", name).Error
+	if err == nil {
+		var template TenantTemplate
+		if err := json.Unmarshal([]byte(record.Definition), &template); err != nil {
+			return nil, fmt.Errorf("failed to decode template %s: %v", name, err)
+		}
+		return &template, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load template: %v", err)
+	}
+	if name == defaultTenantTemplate {
+		return builtinTenantTemplate(), nil
+	}
+	return nil, errTenantTemplateUnknown
+}
+
+// SaveTenantTemplate registers or replaces a tenant template
+func (s *AuthService) SaveTenantTemplate(template *TenantTemplate) error {
+	if err := template.validate(); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(template)
+	if err != nil {
+		return fmt.Errorf("failed to encode template: %v", err)
+	}
+	record := TenantTemplateRecord{Name: template.Name, Definition: string(encoded)}
+	if err := s.db.Save(&record).Error; err != nil {
+		return fmt.Errorf("failed to save template: %v", err)
+	}
+	return nil
+}
+
+// CreateTenant records a new tenant and applies its bootstrap template. Everything the
+// template creates is labeled "tenant:<name>" so the baseline can be listed and exported.
+func (s *AuthService) CreateTenant(name, templateName string) (*Tenant, *TenantTemplate, error) {
+	if !tenantNamePattern.MatchString(name) || name == "templates" {
+		return nil, nil, errInvalidTenantName
+	}
+	if templateName == "" {
+		templateName = defaultTenantTemplate
+	}
+
+	var count int64
+	if err := s.db.Model(&Tenant{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to look up tenant: %v", err)
+	}
+	if count > 0 {
+		return nil, nil, errTenantExists
+	}
+
+	template, err := s.GetTenantTemplate(templateName)
+	if err != nil {
+		return nil, nil, err
+	}
+	applied := template.instantiate(name)
+	label := []string{"tenant:" + name}
+
+	for _, role := range applied.Roles {
+		if _, err := addRule(s.rbacEnforcer, role.Role, role.Object, role.Action, effectAllow); err != nil {
+			return nil, nil, fmt.Errorf("failed to add role permission: %v", err)
+		}
+		if err := s.setLabels(labelKindRBAC, labelKey(role.Role, role.Object, role.Action), label); err != nil {
+			return nil, nil, err
+		}
+		s.recordPolicyMetadata(labelKindRBAC, labelKey(role.Role, role.Object, role.Action), "tenant-bootstrap")
+	}
+
+	for i := range applied.ABACPolicies {
+		policy := applied.ABACPolicies[i]
+		policy.CreatedAt = time.Now()
+		policy.UpdatedAt = time.Now()
+		if err := s.policyEngine.AddPolicy(&policy); err != nil {
+			return nil, nil, err
+		}
+		if err := s.setLabels(labelKindABAC, policy.ID, label); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, rel := range applied.Relationships {
+		if err := s.relationshipGraph.AddRelationship(rel.Subject, rel.Relationship, rel.Object); err != nil {
+			return nil, nil, fmt.Errorf("failed to add relationship: %v", err)
+		}
+		if err := s.setLabels(labelKindRelationship, labelKey(rel.Subject, rel.Relationship, rel.Object), label); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	tenant := &Tenant{Name: name, Template: templateName}
+	if err := s.db.Create(tenant).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to save tenant: %v", err)
+	}
+	s.knownTenants.Store(name, true)
+
+	return tenant, applied, nil
+}
+
+// createTenantHandler creates a tenant and bootstraps it from a template
+func (s *AuthService) createTenantHandler(w http.ResponseWriter, r *http.Request) {
+	var req CreateTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	tenant, applied, err := s.CreateTenant(req.Name, req.Template)
+	switch {
+	case errors.Is(err, errTenantExists):
+		writeJSONError(w, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, errTenantTemplateUnknown), errors.Is(err, errInvalidTenantName):
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	case err != nil:
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create tenant: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Tenant created successfully",
+		"tenant":  tenant,
+		"applied": applied,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// getTenantsHandler lists all tenants
+func (s *AuthService) getTenantsHandler(w http.ResponseWriter, r *http.Request) {
+	var tenants []Tenant
+	if err := s.db.Order("name").Find(&tenants).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve tenants: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"tenants": tenants,
+		"count":   len(tenants),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getTenantHandler returns a single tenant
+func (s *AuthService) getTenantHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	var tenant Tenant
+	if err := s.db.First(&tenant, "name = ?", name).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			writeJSONError(w, http.StatusNotFound, "Tenant not found")
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve tenant: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tenant)
+}
+
+// saveTenantTemplateHandler registers or replaces a tenant template
+func (s *AuthService) saveTenantTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var template TenantTemplate
+	if err := json.NewDecoder(r.Body).Decode(&template); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if err := template.validate(); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := s.SaveTenantTemplate(&template); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save template: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"message":  "Tenant template saved successfully",
+		"template": template,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getTenantTemplatesHandler lists the built-in and registered tenant templates
+func (s *AuthService) getTenantTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	var records []TenantTemplateRecord
+	if err := s.db.Order("name").Find(&records).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve templates: %v", err))
+		return
+	}
+
+	templates := make([]TenantTemplate, 0, len(records)+1)
+	hasDefault := false
+	for _, record := range records {
+		var template TenantTemplate
+		if err := json.Unmarshal([]byte(record.Definition), &template); err != nil {
+			continue
+		}
+		hasDefault = hasDefault || template.Name == defaultTenantTemplate
+		templates = append(templates, template)
+	}
+	if !hasDefault {
+		templates = append([]TenantTemplate{*builtinTenantTemplate()}, templates...)
+	}
+
+	response := map[string]interface{}{
+		"templates": templates,
+		"count":     len(templates),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,125 @@
+// Multi-Model Authorization Microservice - Tenant Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupTenantRouter(service *AuthService) http.Handler {
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/tenants", service.createTenantHandler).Methods("POST")
+	router.HandleFunc("/api/v1/tenants", service.getTenantsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/tenants/templates", service.saveTenantTemplateHandler).Methods("POST")
+	router.HandleFunc("/api/v1/tenants/templates", service.getTenantTemplatesHandler).Methods("GET")
+	router.HandleFunc("/api/v1/tenants/{name}", service.getTenantHandler).Methods("GET")
+	return router
+}
+
+func TestTenants_DefaultTemplateBootstrap(t *testing.T) {
+	service := setupTestService(t)
+
+	tenant, applied, err := service.CreateTenant("acme", "")
+	if err != nil {
+		t.Fatalf("CreateTenant failed: %v", err)
+	}
+	if tenant.Template != defaultTenantTemplate || len(applied.Roles) == 0 {
+		t.Fatalf("Unexpected tenant bootstrap: %+v %+v", tenant, applied)
+	}
+
+	service.rbacEnforcer.AddGroupingPolicy("alice", "acme/admin")
+	if allowed, _ := service.Enforce(ModelRBAC, "alice", "acme/root", "delete", nil); !allowed {
+		t.Error("Expected tenant admin role to be bootstrapped")
+	}
+
+	if _, exists := service.policyEngine.policies["acme/members-read"]; !exists {
+		t.Error("Expected tenant-scoped ABAC policy to be created")
+	}
+	service.saveUserAttribute("acme/bob", "tenant", "acme")
+	service.saveObjectAttribute("acme/report", "tenant", "acme")
+	scope, _ := service.tenantScope("acme")
+	if allowed, _ := service.EnforceInTenant(scope, ModelABAC, "bob", "report", "read", nil, freshnessDefault); !allowed {
+		t.Error("Expected tenant ABAC policy to allow tenant members to read")
+	}
+
+	if allowed, _ := service.relationshipGraph.CheckReBACAccess("acme/admins", "acme/root", "admin"); !allowed {
+		t.Error("Expected baseline relationship to be created")
+	}
+
+	export, err := service.ExportPolicies("tenant:acme")
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(export.RBACPolicies) != len(applied.Roles) || len(export.ABACPolicies) != 1 || len(export.Relationships) != 1 {
+		t.Errorf("Expected tenant baseline to be labeled, got %+v", export)
+	}
+
+	if _, _, err := service.CreateTenant("acme", ""); err != errTenantExists {
+		t.Errorf("Expected errTenantExists, got %v", err)
+	}
+}
+
+func TestTenants_API(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTenantRouter(service)
+
+	template := TenantTemplate{
+		Name:  "readonly",
+		Roles: []TemplateRolePermission{{Role: "{tenant}/reader", Object: "{tenant}/docs", Action: "read"}},
+	}
+	body, _ := json.Marshal(template)
+	req, _ := http.NewRequest("POST", "/api/v1/tenants/templates", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected template to be saved, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"create with template", `{"name": "globex", "template": "readonly"}`, http.StatusCreated},
+		{"duplicate tenant", `{"name": "globex"}`, http.StatusConflict},
+		{"unknown template", `{"name": "initech", "template": "missing"}`, http.StatusBadRequest},
+		{"invalid name", `{"name": "Bad Name"}`, http.StatusBadRequest},
+		{"reserved name", `{"name": "templates"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/api/v1/tenants", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	if allowed, _ := service.rbacEnforcer.HasPolicy("globex/reader", "globex/docs", "read", "allow"); !allowed {
+		t.Error("Expected registered template to be applied")
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/tenants/globex", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("Expected tenant lookup to succeed, got %d", rr.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/api/v1/tenants/templates", nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var response map[string]interface{}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if response["count"] != float64(2) {
+		t.Errorf("Expected built-in and registered templates, got %v", response["count"])
+	}
+}
@@ -0,0 +1,401 @@
+// Multi-Model Authorization Microservice - Point-in-Time Queries
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/casbin/casbin/v2"
+)
+
+// errHistoryNotRetained is returned for points in time older than the relationship change log
+var errHistoryNotRetained = errors.New("relationship history is not retained that far back")
+
+// parseAsOf parses an as_of timestamp (RFC 3339), which must not lie in the future. Errors
+// describe the value, e.g. "must not be in the future".
+func parseAsOf(value string) (time.Time, error) {
+	asOf, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, errors.New("must be an RFC 3339 timestamp")
+	}
+	if asOf.After(time.Now()) {
+		return time.Time{}, errors.New("must not be in the future")
+	}
+	// Timestamps are stored in local time, so compare in it
+	return asOf.Local(), nil
+}
+
+// rulesAsOf returns the ACL/RBAC rules, role assignments or resource group memberships of a
+// change kind as they were at asOf. The current rules are rewound by the revisions recorded
+// since, so changes that bypassed the API (e.g. direct database edits) are not undone.
+func (s *AuthService) rulesAsOf(kind string, asOf time.Time) ([][]string, error) {
+	var current [][]string
+	var err error
+	switch kind {
+	case changeKindACL:
+		current, err = s.aclEnforcer.GetPolicy()
+	case changeKindRBACPolicy:
+		current, err = s.rbacEnforcer.GetPolicy()
+	case changeKindRBACRole:
+		current, err = s.rbacEnforcer.GetGroupingPolicy()
+	case changeKindRBACResourceGroup:
+		current, err = s.rbacEnforcer.GetNamedGroupingPolicy("g2")
+	default:
+		return nil, fmt.Errorf("no history is kept for %s", kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var revisions []PolicyRevision
+	changed := s.db.Model(&PolicyRevision{}).Select("policy_key").Where("kind = ? AND created_at > ?", kind, asOf)
+	if err := s.db.Where("kind = ? AND policy_key IN (?)", kind, changed).Order("version").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to read policy history: %v", err)
+	}
+
+	rules := make(map[string][]string, len(current))
+	order := make([]string, 0, len(current))
+	for _, rule := range current {
+		key := labelKey(rule[:2]...)
+		if kind == changeKindACL || kind == changeKindRBACPolicy {
+			key = ruleKey(rule)
+		}
+		rules[key] = rule
+		order = append(order, key)
+	}
+
+	// Per policy, the last revision up to asOf decides, or the first one after it when the
+	// policy has not changed before: an addition means the policy did not exist yet
+	byKey := make(map[string][]PolicyRevision)
+	for _, revision := range revisions {
+		byKey[revision.PolicyKey] = append(byKey[revision.PolicyKey], revision)
+	}
+	for key, history := range byKey {
+		deciding := history[0]
+		if deciding.CreatedAt.After(asOf) {
+			if deciding.Action == changeAdded {
+				delete(rules, key)
+				continue
+			}
+		} else {
+			for _, revision := range history {
+				if revision.CreatedAt.After(asOf) {
+					break
+				}
+				deciding = revision
+			}
+			if deciding.Action == changeRemoved {
+				delete(rules, key)
+				continue
+			}
+		}
+
+		rule, err := revisionRule(kind, deciding.State)
+		if err != nil {
+			return nil, fmt.Errorf("invalid revision state of %s: %v", key, err)
+		}
+		if _, exists := rules[key]; !exists {
+			order = append(order, key)
+		}
+		rules[key] = rule
+	}
+
+	historical := make([][]string, 0, len(rules))
+	for _, key := range order {
+		if rule, exists := rules[key]; exists {
+			historical = append(historical, rule)
+		}
+	}
+	return historical, nil
+}
+
+// revisionRule decodes the rule of a revision of an ACL/RBAC rule, role assignment or
+// resource group membership
+func revisionRule(kind string, state json.RawMessage) ([]string, error) {
+	var fields map[string]string
+	if err := json.Unmarshal(state, &fields); err != nil {
+		return nil, err
+	}
+	switch kind {
+	case changeKindRBACRole:
+		return []string{fields["user"], fields["role"]}, nil
+	case changeKindRBACResourceGroup:
+		return []string{fields["object"], fields["group"]}, nil
+	}
+	effect, err := normalizeEffect(fields["effect"])
+	if err != nil {
+		return nil, err
+	}
+	return []string{fields["subject"], fields["object"], fields["action"], effect}, nil
+}
+
+// relationshipsAsOf returns the stored tuples as they were at asOf, rewinding the current
+// tuples by the relationship change log. The change log only reaches back as far as its
+// retention period (REBAC_WATCH_RETENTION).
+func (s *AuthService) relationshipsAsOf(asOf time.Time) ([]RelationshipRecord, error) {
+	retention := s.watchRetention
+	if retention <= 0 {
+		retention = defaultWatchRetention
+	}
+	if asOf.Before(time.Now().Add(-retention)) {
+		return nil, errHistoryNotRetained
+	}
+
+	var records []RelationshipRecord
+	if err := s.db.Order("id").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load relationships: %v", err)
+	}
+	var changes []RelationshipChange
+	if err := s.db.Where("created_at > ?", asOf).Order("id desc").Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to read relationship history: %v", err)
+	}
+
+	// Undo the changes newest first: an addition stored one copy of a tuple, a removal
+	// deleted every copy
+	for _, change := range changes {
+		switch change.Action {
+		case changeAdded:
+			for i := len(records) - 1; i >= 0; i-- {
+				if records[i].Subject == change.Subject && records[i].Relationship == change.Relationship && records[i].Object == change.Object {
+					records = append(records[:i], records[i+1:]...)
+					break
+				}
+			}
+		case changeRemoved:
+			records = append(records, RelationshipRecord{
+				Subject:      change.Subject,
+				Relationship: change.Relationship,
+				Object:       change.Object,
+				ExpiresAt:    change.ExpiresAt,
+				Caveat:       change.Caveat,
+			})
+		}
+	}
+
+	live := records[:0]
+	for _, record := range records {
+		if record.ExpiresAt == nil || record.ExpiresAt.After(asOf) {
+			live = append(live, record)
+		}
+	}
+	return live, nil
+}
+
+// historicalGraph returns a graph holding only the given tuples. It shares the relation
+// rewrite rules and permissions of rg, which are not versioned.
+func (rg *RelationshipGraph) historicalGraph(records []RelationshipRecord) *RelationshipGraph {
+	unlock := rg.readLock()
+	defer unlock()
+
+	graph := &RelationshipGraph{
+		mu:            &sync.RWMutex{},
+		relationships: make(map[string][]Relationship),
+		reverse:       newReverseIndex(),
+		adjacency:     newAdjacencyIndex(),
+		db:            rg.db,
+		permissions:   rg.permissions,
+		namespaces:    rg.namespaces,
+		actions:       rg.actions,
+		groupDepth:    rg.groupDepth,
+	}
+	for _, record := range records {
+		rel := Relationship{Subject: record.Subject, Relationship: record.Relationship, Object: record.Object}
+		graph.trackCaveat(rel, record.Caveat)
+		graph.addToMemory(rel.Subject, rel.Relationship, rel.Object)
+	}
+	return graph
+}
+
+// historicalEnforcer returns an enforcer with the model of enforcer and the given rules
+func historicalEnforcer(enforcer *casbin.SyncedEnforcer, rules [][]string, roles [][]string, groups [][]string) (*casbin.Enforcer, error) {
+	enforcer.GetLock().RLock()
+	m := enforcer.GetModel().Copy()
+	enforcer.GetLock().RUnlock()
+	m.ClearPolicy()
+	historical, err := casbin.NewEnforcer(m)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) > 0 {
+		if _, err := historical.AddPolicies(rules); err != nil {
+			return nil, err
+		}
+	}
+	if len(roles) > 0 {
+		if _, err := historical.AddGroupingPolicies(roles); err != nil {
+			return nil, err
+		}
+	}
+	if len(groups) > 0 {
+		if _, err := historical.AddNamedGroupingPolicies("g2", groups); err != nil {
+			return nil, err
+		}
+	}
+	return historical, nil
+}
+
+// EnforceAsOf performs an authorization check within a tenant against the rules and tuples
+// as they were at asOf. ABAC checks are not supported, since attributes are not versioned.
+// RBAC rule conditions, caveats and relation rewrite rules are evaluated as they are now.
+func (s *AuthService) EnforceAsOf(scope tenantScope, model AccessControlModel, subject, object, action string, attributes map[string]string, asOf time.Time) (bool, error) {
+	subject, object = scope.qualify(subject), scope.qualify(object)
+	if model == "" {
+		model = ModelRBAC
+	}
+	if !s.currentSettings().modelEnabled(model) {
+		return false, fmt.Errorf("model %s is disabled", model)
+	}
+
+	switch model {
+	case ModelACL:
+		rules, err := s.rulesAsOf(changeKindACL, asOf)
+		if err != nil {
+			return false, err
+		}
+		enforcer, err := historicalEnforcer(s.aclEnforcer, rules, nil, nil)
+		if err != nil {
+			return false, err
+		}
+		registerACLFunctions(enforcer)
+		s.addImpliedActionFunction(enforcer)
+		allowed, _, err := enforceRule(enforcer, subject, object, action)
+		return allowed, err
+	case ModelRBAC:
+		rules, err := s.rulesAsOf(changeKindRBACPolicy, asOf)
+		if err != nil {
+			return false, err
+		}
+		roles, err := s.rulesAsOf(changeKindRBACRole, asOf)
+		if err != nil {
+			return false, err
+		}
+		groups, err := s.rulesAsOf(changeKindRBACResourceGroup, asOf)
+		if err != nil {
+			return false, err
+		}
+		enforcer, err := historicalEnforcer(s.rbacEnforcer, rules, roles, groups)
+		if err != nil {
+			return false, err
+		}
+		s.addRuleConditionFunction(enforcer)
+		s.addImpliedActionFunction(enforcer)
+		allowed, _, err := enforceRule(enforcer, subject, object, action)
+		return allowed, err
+	case ModelReBAC:
+		records, err := s.relationshipsAsOf(asOf)
+		if err != nil {
+			return false, err
+		}
+		graph := s.relationshipGraph.historicalGraph(records).withCaveatContext(attributes)
+		allowed, _ := graph.CheckReBACAccess(subject, object, action)
+		return allowed, nil
+	case ModelABAC:
+		return false, fmt.Errorf("as_of is not supported for ABAC checks")
+	default:
+		return false, fmt.Errorf("invalid model specified: %s", model)
+	}
+}
+
+// relationshipListingAsOf lists the tuples of a subject (all subjects when empty) as they
+// were at asOf, with their expiries and caveats keyed like RelationshipExpirations and
+// RelationshipCaveats
+func (s *AuthService) relationshipListingAsOf(subject string, asOf time.Time) ([]Relationship, map[string]time.Time, map[string]*RelationshipCaveat, error) {
+	records, err := s.relationshipsAsOf(asOf)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	relationships := make([]Relationship, 0)
+	listed := make(map[string]bool)
+	expirations := make(map[string]time.Time)
+	caveats := make(map[string]*RelationshipCaveat)
+	permanent := make(map[string]bool)
+	unconditional := make(map[string]bool)
+	for _, record := range records {
+		if subject != "" && record.Subject != subject {
+			continue
+		}
+		rel := Relationship{Subject: record.Subject, Relationship: record.Relationship, Object: record.Object}
+		key := labelKey(rel.Subject, rel.Relationship, rel.Object)
+		if !listed[key] {
+			listed[key] = true
+			relationships = append(relationships, rel)
+		}
+
+		// A permanent copy of a tuple never expires and an unconditional one has no caveat
+		if record.ExpiresAt == nil {
+			permanent[key] = true
+		} else if expiresAt, exists := expirations[key]; !exists || record.ExpiresAt.After(expiresAt) {
+			expirations[key] = *record.ExpiresAt
+		}
+		if record.Caveat == nil {
+			unconditional[key] = true
+		} else {
+			caveats[key] = record.Caveat
+		}
+	}
+	for key := range permanent {
+		delete(expirations, key)
+	}
+	for key := range unconditional {
+		delete(caveats, key)
+	}
+	return relationships, expirations, caveats, nil
+}
+
+// historicalAuthorization answers an authorization check with as_of. The request has been
+// validated. Historical checks bypass the decision cache and are not audited, as they
+// decide nothing about current access.
+func (s *AuthService) historicalAuthorization(w http.ResponseWriter, scope tenantScope, request *EnforceRequest) {
+	asOf, err := parseAsOf(request.AsOf)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("as_of %v", err))
+		return
+	}
+
+	allowed, err := s.EnforceAsOf(scope, request.Model, request.Subject, request.Object, request.Action, request.Attributes, asOf)
+	if errors.Is(err, errHistoryNotRetained) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Authorization error: %v", err))
+		return
+	}
+
+	response := EnforceResponse{
+		Allowed: allowed,
+		Message: map[bool]string{true: "Access granted", false: "Access denied"}[allowed],
+		Model:   string(request.Model),
+		AsOf:    request.AsOf,
+	}
+	if !scope.global() {
+		response.Tenant = scope.tenant
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(map[bool]int{true: http.StatusOK, false: http.StatusForbidden}[allowed])
+	json.NewEncoder(w).Encode(response)
+}
+
+// parseAsOfQuery reads the optional as_of query parameter of a list endpoint, writing an
+// error response when it is invalid
+func parseAsOfQuery(w http.ResponseWriter, r *http.Request) (asOf time.Time, ok bool) {
+	value := r.URL.Query().Get("as_of")
+	if value == "" {
+		return time.Time{}, true
+	}
+	asOf, err := parseAsOf(value)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("as_of %v", err))
+		return time.Time{}, false
+	}
+	return asOf, true
+}
@@ -0,0 +1,127 @@
+// Multi-Model Authorization Microservice - Point-in-Time Query Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestTimeTravel_ChecksAndListings(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/authorizations", service.authorizationHandler).Methods("POST")
+	router.HandleFunc("/api/v1/rbac/policies", service.addRBACPolicyHandler).Methods("POST")
+	router.HandleFunc("/api/v1/rbac/policies", service.getRBACPoliciesHandler).Methods("GET")
+	router.HandleFunc("/api/v1/users/{userId}/roles", service.addUserRoleHandler).Methods("POST")
+	router.HandleFunc("/api/v1/users/{userId}/roles/{roleId}", service.deleteUserRoleHandler).Methods("DELETE")
+	router.HandleFunc("/api/v1/relationships", service.addRelationshipHandler).Methods("POST")
+	router.HandleFunc("/api/v1/relationships", service.getRelationshipsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/relationships/{id}", service.deleteRelationshipHandler).Methods("DELETE")
+	if err := service.enableDecisionCache(100, time.Minute); err != nil {
+		t.Fatalf("Failed to enable the decision cache: %v", err)
+	}
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	check := func(model, subject, object, asOf string) int {
+		body, _ := json.Marshal(EnforceRequest{Model: AccessControlModel(model), Subject: subject, Object: object, Action: "read", AsOf: asOf})
+		return send("POST", "/api/v1/authorizations", string(body)).Code
+	}
+
+	send("POST", "/api/v1/rbac/policies", `{"subject": "reader", "object": "report", "action": "read"}`)
+	send("POST", "/api/v1/users/bob/roles", `{"role": "reader"}`)
+	send("POST", "/api/v1/relationships", `{"subject": "alice", "relationship": "owner", "object": "doc1"}`)
+	if code := check("rbac", "bob", "report", ""); code != http.StatusOK {
+		t.Fatalf("Expected bob to read the report, got %d", code)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	before := time.Now().Format(time.RFC3339Nano)
+	time.Sleep(10 * time.Millisecond)
+
+	send("DELETE", "/api/v1/users/bob/roles/reader", "")
+	send("POST", "/api/v1/users/carol/roles", `{"role": "reader"}`)
+	send("POST", "/api/v1/rbac/policies", `{"subject": "reader", "object": "wiki", "action": "read"}`)
+	send("DELETE", "/api/v1/relationships/alice:owner:doc1", "")
+
+	for _, tc := range []struct {
+		model, subject, object, asOf string
+		expected                     int
+	}{
+		{"rbac", "bob", "report", before, http.StatusOK},
+		{"rbac", "bob", "report", "", http.StatusForbidden},
+		{"rbac", "carol", "report", before, http.StatusForbidden},
+		{"rbac", "carol", "wiki", "", http.StatusOK},
+		{"rbac", "carol", "wiki", before, http.StatusForbidden},
+		{"rebac", "alice", "doc1", before, http.StatusOK},
+		{"rebac", "alice", "doc1", "", http.StatusForbidden},
+	} {
+		if code := check(tc.model, tc.subject, tc.object, tc.asOf); code != tc.expected {
+			t.Errorf("Expected %s check of %s on %s as of %q to return %d, got %d", tc.model, tc.subject, tc.object, tc.asOf, tc.expected, code)
+		}
+	}
+
+	var policies struct {
+		Policies [][]string `json:"policies"`
+	}
+	json.Unmarshal(send("GET", "/api/v1/rbac/policies?as_of="+url.QueryEscape(before), "").Body.Bytes(), &policies)
+	if len(policies.Policies) != 1 || policies.Policies[0][1] != "report" {
+		t.Errorf("Expected only the report rule as of before, got %v", policies.Policies)
+	}
+
+	var relationships struct {
+		Relationships []Relationship `json:"relationships"`
+	}
+	json.Unmarshal(send("GET", "/api/v1/relationships?subject=alice&as_of="+url.QueryEscape(before), "").Body.Bytes(), &relationships)
+	if len(relationships.Relationships) != 1 || relationships.Relationships[0].Object != "doc1" {
+		t.Errorf("Expected alice's removed tuple to be listed as of before, got %v", relationships.Relationships)
+	}
+}
+
+func TestTimeTravel_Validation(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/authorizations", service.authorizationHandler).Methods("POST")
+	router.HandleFunc("/api/v1/relationships", service.getRelationshipsHandler).Methods("GET")
+	service.watchRetention = time.Hour
+
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+	past := time.Now().Add(-time.Minute).Format(time.RFC3339)
+	expired := time.Now().Add(-2 * time.Hour).Format(time.RFC3339)
+	for _, body := range []string{
+		`{"model": "rbac", "subject": "alice", "object": "doc", "action": "read", "as_of": "last tuesday"}`,
+		`{"model": "rbac", "subject": "alice", "object": "doc", "action": "read", "as_of": "` + future + `"}`,
+		// Attributes are not versioned
+		`{"model": "abac", "subject": "alice", "object": "doc", "action": "read", "as_of": "` + past + `"}`,
+		`{"model": "rbac", "subject": "alice", "object": "doc", "action": "read", "as_of": "` + past + `", "explain": true}`,
+		// The relationship change log does not reach back that far
+		`{"model": "rebac", "subject": "alice", "object": "doc", "action": "read", "as_of": "` + expired + `"}`,
+	} {
+		req, _ := http.NewRequest("POST", "/api/v1/authorizations", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d %s", body, rr.Code, rr.Body.String())
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/api/v1/relationships?as_of="+url.QueryEscape(expired), nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a listing beyond the retention, got %d", rr.Code)
+	}
+}
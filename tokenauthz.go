@@ -0,0 +1,503 @@
+// Multi-Model Authorization Microservice - Token Authorization
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultJWKSRefresh is how long fetched signing keys are used before they are refetched
+	defaultJWKSRefresh = 10 * time.Minute
+
+	// jwksRefetchInterval bounds how often a token with an unknown key ID refetches the keys
+	jwksRefetchInterval = 30 * time.Second
+
+	// defaultTokenRolesClaim is the claim listing the roles of a token's subject
+	defaultTokenRolesClaim = "roles"
+)
+
+// registeredClaims are the JWT claims describing the token rather than its subject; they are
+// not mapped to attributes unless listed explicitly
+var registeredClaims = map[string]bool{"iss": true, "sub": true, "aud": true, "exp": true, "nbf": true, "iat": true, "jti": true}
+
+// TokenAuthorizationRequest asks whether the subject of a JWT may perform an action
+type TokenAuthorizationRequest struct {
+	Token      string             `json:"token"` // JWT of the end user, signed by a key of the configured JWKS
+	Model      AccessControlModel `json:"model"`
+	Object     string             `json:"object"`
+	Action     string             `json:"action"`
+	Attributes RequestAttributes  `json:"attributes,omitempty"` // Further request attributes; claims of the same name win
+	Tenant     string             `json:"tenant,omitempty"`
+}
+
+// TokenAuthorizationResponse is an authorization decision about the subject of a JWT
+type TokenAuthorizationResponse struct {
+	EnforceResponse
+	Subject   string   `json:"subject"`
+	Roles     []string `json:"roles,omitempty"`      // RBAC roles read from the token
+	GrantedBy string   `json:"granted_by,omitempty"` // The subject or token role that was granted access
+}
+
+// tokenAuthorization verifies end-user JWTs against a JWKS and maps their claims to the
+// subject, RBAC roles and ABAC request attributes of an authorization check
+type tokenAuthorization struct {
+	keys            *jwksKeySet
+	issuer          string            // Required iss claim (optional)
+	audience        string            // Required aud entry (optional)
+	subjectClaim    string            // Claim naming the subject, "sub" by default
+	rolesClaim      string            // Claim listing RBAC roles, nested claims as dot paths
+	claimAttributes map[string]string // Claim to attribute name; nil maps every non-registered claim
+}
+
+// verifiedToken is what a verified token says about its subject
+type verifiedToken struct {
+	subject    string
+	roles      []string
+	attributes map[string]string
+}
+
+// tokenAuthorizationFromEnv configures token authorization from TOKEN_AUTHZ_JWKS_URL and the
+// other TOKEN_AUTHZ_* settings. It returns nil when no JWKS is configured.
+func tokenAuthorizationFromEnv() (*tokenAuthorization, error) {
+	jwksURL := os.Getenv("TOKEN_AUTHZ_JWKS_URL")
+	if jwksURL == "" {
+		return nil, nil
+	}
+
+	refresh := defaultJWKSRefresh
+	if refreshStr := os.Getenv("TOKEN_AUTHZ_JWKS_REFRESH"); refreshStr != "" {
+		parsed, err := time.ParseDuration(refreshStr)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid TOKEN_AUTHZ_JWKS_REFRESH value: %s", refreshStr)
+		}
+		refresh = parsed
+	}
+
+	ta := &tokenAuthorization{
+		keys:         newJWKSKeySet(jwksURL, refresh),
+		issuer:       os.Getenv("TOKEN_AUTHZ_ISSUER"),
+		audience:     os.Getenv("TOKEN_AUTHZ_AUDIENCE"),
+		subjectClaim: os.Getenv("TOKEN_AUTHZ_SUBJECT_CLAIM"),
+		rolesClaim:   os.Getenv("TOKEN_AUTHZ_ROLES_CLAIM"),
+	}
+	if ta.subjectClaim == "" {
+		ta.subjectClaim = "sub"
+	}
+	if ta.rolesClaim == "" {
+		ta.rolesClaim = defaultTokenRolesClaim
+	}
+	if claims := os.Getenv("TOKEN_AUTHZ_CLAIMS"); claims != "" {
+		mapping, err := parseAttributeMapping(claims)
+		if err != nil {
+			return nil, fmt.Errorf("invalid TOKEN_AUTHZ_CLAIMS value: %v", err)
+		}
+		ta.claimAttributes = mapping
+	}
+	return ta, nil
+}
+
+// verify checks a token's signature and registered claims and reads its subject, roles and
+// attributes
+func (ta *tokenAuthorization) verify(token string, now time.Time) (*verifiedToken, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature")
+	}
+	key, err := ta.keys.key(header.Kid, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyJWTSignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	var claims map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	decoder.UseNumber()
+	if err := decoder.Decode(&claims); err != nil || claims == nil {
+		return nil, fmt.Errorf("invalid token claims")
+	}
+	if exp, ok := numericClaim(claims["exp"]); ok && !now.Before(time.Unix(exp, 0)) {
+		return nil, fmt.Errorf("token expired")
+	}
+	if nbf, ok := numericClaim(claims["nbf"]); ok && now.Before(time.Unix(nbf, 0)) {
+		return nil, fmt.Errorf("token not yet valid")
+	}
+	if ta.issuer != "" && claims["iss"] != ta.issuer {
+		return nil, fmt.Errorf("unexpected token issuer")
+	}
+	if ta.audience != "" && !claimContains(claims["aud"], ta.audience) {
+		return nil, fmt.Errorf("unexpected token audience")
+	}
+
+	flattened := flattenClaims(claims)
+	verified := &verifiedToken{subject: flattened[ta.subjectClaim], attributes: make(map[string]string)}
+	if verified.subject == "" {
+		return nil, fmt.Errorf("token has no %s claim", ta.subjectClaim)
+	}
+	for _, role := range strings.Split(flattened[ta.rolesClaim], ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			verified.roles = append(verified.roles, role)
+		}
+	}
+	for path, value := range flattened {
+		if ta.claimAttributes == nil {
+			if !registeredClaims[path] {
+				verified.attributes[path] = value
+			}
+		} else if name, mapped := ta.claimAttributes[path]; mapped {
+			verified.attributes[name] = value
+		}
+	}
+	return verified, nil
+}
+
+// flattenClaims flattens the claims of a token like the attributes of a request: nested
+// claims become dot paths and arrays comma-separated lists. Claims that can't be expressed
+// as attributes, such as arrays of objects, are left out.
+func flattenClaims(claims map[string]interface{}) map[string]string {
+	flattened := make(RequestAttributes)
+	for name, value := range claims {
+		claim := make(RequestAttributes)
+		if err := claim.add("", map[string]interface{}{name: value}, 0); err != nil {
+			continue
+		}
+		for path, formatted := range claim {
+			flattened[path] = formatted
+		}
+	}
+	return flattened
+}
+
+// numericClaim reads a NumericDate claim such as exp
+func numericClaim(claim interface{}) (int64, bool) {
+	number, ok := claim.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	value, err := number.Float64()
+	if err != nil {
+		return 0, false
+	}
+	return int64(value), true
+}
+
+// verifyJWTSignature checks an RS*, PS* or ES* signature over the signing input of a token
+func verifyJWTSignature(alg string, key crypto.PublicKey, input, signature []byte) error {
+	if len(alg) != 5 {
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	var hash crypto.Hash
+	switch alg[2:] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	}
+	if hash == 0 {
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	hasher := hash.New()
+	hasher.Write(input)
+	digest := hasher.Sum(nil)
+
+	switch alg[:2] {
+	case "RS", "PS":
+		rsaKey, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("token algorithm %s does not match its key", alg)
+		}
+		var err error
+		if alg[:2] == "RS" {
+			err = rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature)
+		} else {
+			err = rsa.VerifyPSS(rsaKey, hash, digest, signature, nil)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid token signature")
+		}
+	case "ES":
+		ecKey, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("token algorithm %s does not match its key", alg)
+		}
+		// JWS encodes ECDSA signatures as the fixed-size concatenation of r and s
+		size := (ecKey.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return fmt.Errorf("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(ecKey, digest, r, s) {
+			return fmt.Errorf("invalid token signature")
+		}
+	default:
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	return nil
+}
+
+// jwksKeySet holds the signing keys published at a JWKS URL, refetched periodically and
+// when a token names a key that is not known yet, e.g. after a key rotation
+type jwksKeySet struct {
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey // Keyed by key ID
+	fetched time.Time
+}
+
+// newJWKSKeySet creates a key set fetched lazily from url
+func newJWKSKeySet(url string, refresh time.Duration) *jwksKeySet {
+	return &jwksKeySet{url: url, refresh: refresh, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+// key returns the key with the given ID. A token without a key ID may only be verified by
+// a key set holding a single key.
+func (ks *jwksKeySet) key(kid string, now time.Time) (crypto.PublicKey, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	_, known := ks.keys[kid]
+	stale := now.Sub(ks.fetched) >= ks.refresh
+	if ks.keys == nil || stale || (!known && now.Sub(ks.fetched) >= jwksRefetchInterval) {
+		keys, err := ks.fetch()
+		if err != nil && ks.keys == nil {
+			return nil, err
+		}
+		if err == nil {
+			ks.keys = keys
+		}
+		// Keep using the keys fetched before when the endpoint is unavailable
+		ks.fetched = now
+	}
+
+	if kid == "" && len(ks.keys) == 1 {
+		for _, key := range ks.keys {
+			return key, nil
+		}
+	}
+	if key, ok := ks.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown token signing key %q", kid)
+}
+
+// jsonWebKey is a public key of a JWKS document
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// fetch downloads the key set. Encryption keys and keys of unsupported types are skipped.
+func (ks *jwksKeySet) fetch() (map[string]crypto.PublicKey, error) {
+	resp, err := ks.client.Get(ks.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch token signing keys: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch token signing keys: status %d", resp.StatusCode)
+	}
+
+	var document struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid token signing keys: %v", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(document.Keys))
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+// publicKey decodes an RSA or EC key
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, fmt.Errorf("EC key is not on its curve")
+		}
+		return key, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+	}
+}
+
+// EnforceToken checks access for the subject of a verified token. With RBAC, the roles of
+// the token act like role assignments: the subject is granted access when it or one of
+// its roles is, unless a deny rule matches the subject or any of the roles. It returns who
+// was granted access.
+func (s *AuthService) EnforceToken(scope tenantScope, model AccessControlModel, token *verifiedToken, object, action string, attributes map[string]string) (bool, string, error) {
+	if model == "" {
+		model = ModelRBAC
+	}
+	allowed, err := s.EnforceInTenant(scope, model, token.subject, object, action, attributes, freshnessDefault)
+	if err != nil || allowed || model != ModelRBAC || len(token.roles) == 0 {
+		if allowed {
+			return true, token.subject, err
+		}
+		return false, "", err
+	}
+
+	_, denied, err := enforceRule(s.rbacEnforcer, scope.qualify(token.subject), scope.qualify(object), action)
+	if err != nil || denied {
+		return false, "", err
+	}
+	grantedBy := ""
+	for _, role := range token.roles {
+		roleAllowed, roleDenied, err := enforceRule(s.rbacEnforcer, scope.qualify(role), scope.qualify(object), action)
+		if err != nil || roleDenied {
+			return false, "", err
+		}
+		if roleAllowed && grantedBy == "" {
+			grantedBy = role
+		}
+	}
+	return grantedBy != "", grantedBy, nil
+}
+
+// tokenAuthorizationHandler authorizes the subject of an end-user JWT, so callers don't
+// have to extract subjects, roles and attributes from tokens themselves
+func (s *AuthService) tokenAuthorizationHandler(w http.ResponseWriter, r *http.Request) {
+	var request TokenAuthorizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if err := request.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	token, err := s.tokenAuthorization.verify(request.Token, time.Now())
+	if err != nil {
+		serviceMetrics.Inc("token_authorization_rejections_total")
+		writeJSONError(w, http.StatusUnauthorized, fmt.Sprintf("Invalid token: %v", err))
+		return
+	}
+
+	scope, ok := s.requestTenant(w, r, request.Tenant)
+	if !ok {
+		return
+	}
+
+	// Verified claims win over attributes the caller passes along
+	attributes := make(map[string]string, len(request.Attributes)+len(token.attributes))
+	for name, value := range request.Attributes {
+		attributes[name] = value
+	}
+	for name, value := range token.attributes {
+		attributes[name] = value
+	}
+
+	start := time.Now()
+	allowed, grantedBy, err := s.EnforceToken(scope, request.Model, token, request.Object, request.Action, attributes)
+	s.sloTracker.Observe(time.Since(start))
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Authorization error: %v", err))
+		return
+	}
+
+	subject, object := scope.qualify(token.subject), scope.qualify(request.Object)
+	s.recordDecision(requestIDFromContext(r.Context()), request.Model, subject, object, request.Action, attributes, allowed)
+	s.traceDecision(requestIDFromContext(r.Context()), request.Model, subject, object, request.Action, attributes, allowed)
+
+	response := TokenAuthorizationResponse{
+		EnforceResponse: EnforceResponse{
+			Allowed: allowed,
+			Message: map[bool]string{true: "Access granted", false: "Access denied"}[allowed],
+			Model:   string(request.Model),
+		},
+		Subject:   token.subject,
+		Roles:     token.roles,
+		GrantedBy: grantedBy,
+	}
+	if !scope.global() {
+		response.Tenant = scope.tenant
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(map[bool]int{true: http.StatusOK, false: http.StatusForbidden}[allowed])
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,145 @@
+// Multi-Model Authorization Microservice - Token Authorization Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// signTestToken creates an RS256 token with the given claims
+func signTestToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestTokenAuthorization_MapsClaims(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "k1", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer jwks.Close()
+
+	service := setupTestService(t)
+	service.tokenAuthorization = &tokenAuthorization{
+		keys:         newJWKSKeySet(jwks.URL, time.Minute),
+		issuer:       "https://idp.example.com",
+		subjectClaim: "sub",
+		rolesClaim:   "realm_access.roles",
+	}
+	service.rbacEnforcer.AddPolicy("analyst", "reports", "read", "allow")
+	service.rbacEnforcer.AddPolicy("contractor", "reports", "read", "deny")
+	service.policyEngine.AddPolicy(&ABACPolicy{
+		ID:         "eng-only",
+		Effect:     "allow",
+		Conditions: []PolicyCondition{{Type: "request", Field: "org.department", Operator: "eq", Value: "eng"}},
+	})
+	router := mux.NewRouter()
+	service.registerEnforcementRoutes(router.PathPrefix("/api/v1").Subrouter())
+
+	claims := func(extra map[string]interface{}) map[string]interface{} {
+		base := map[string]interface{}{"sub": "alice", "iss": "https://idp.example.com", "exp": time.Now().Add(time.Hour).Unix()}
+		for name, value := range extra {
+			base[name] = value
+		}
+		return base
+	}
+	check := func(token, model string, attributes string) (int, TokenAuthorizationResponse) {
+		body := `{"token": "` + token + `", "model": "` + model + `", "object": "reports", "action": "read", "attributes": ` + attributes + `}`
+		req, _ := http.NewRequest("POST", "/api/v1/authorizations/token", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var response TokenAuthorizationResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr.Code, response
+	}
+
+	// RBAC roles are read from a nested claim
+	analyst := signTestToken(t, key, "k1", claims(map[string]interface{}{"realm_access": map[string]interface{}{"roles": []string{"viewer", "analyst"}}}))
+	code, response := check(analyst, "rbac", "{}")
+	if code != http.StatusOK || response.Subject != "alice" || response.GrantedBy != "analyst" || len(response.Roles) != 2 {
+		t.Errorf("Expected alice to be granted access as analyst, got %d %+v", code, response)
+	}
+
+	// A deny rule on any token role wins
+	contractor := signTestToken(t, key, "k1", claims(map[string]interface{}{"realm_access": map[string]interface{}{"roles": []string{"analyst", "contractor"}}}))
+	if code, _ := check(contractor, "rbac", "{}"); code != http.StatusForbidden {
+		t.Errorf("Expected the contractor role to deny access, got %d", code)
+	}
+
+	// Claims become ABAC request attributes and win over those sent along
+	engineer := signTestToken(t, key, "k1", claims(map[string]interface{}{"org": map[string]interface{}{"department": "eng"}}))
+	if code, _ := check(engineer, "abac", "{}"); code != http.StatusOK {
+		t.Errorf("Expected the department claim to allow access, got %d", code)
+	}
+	sales := signTestToken(t, key, "k1", claims(map[string]interface{}{"org": map[string]interface{}{"department": "sales"}}))
+	if code, _ := check(sales, "abac", `{"org": {"department": "eng"}}`); code != http.StatusForbidden {
+		t.Errorf("Expected the claim to win over the request attribute, got %d", code)
+	}
+
+	// Invalid tokens are rejected
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	for name, token := range map[string]string{
+		"expired":       signTestToken(t, key, "k1", claims(map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()})),
+		"wrong issuer":  signTestToken(t, key, "k1", claims(map[string]interface{}{"iss": "https://evil.example.com"})),
+		"wrong key":     signTestToken(t, otherKey, "k1", claims(nil)),
+		"unknown kid":   signTestToken(t, key, "k2", claims(nil)),
+		"not a JWT":     "garbage",
+		"alg none":      base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"k1"}`)) + ".e30.",
+		"missing claim": signTestToken(t, key, "k1", map[string]interface{}{"iss": "https://idp.example.com"}),
+	} {
+		if code, _ := check(token, "rbac", "{}"); code != http.StatusUnauthorized {
+			t.Errorf("Expected %s token to be rejected with 401, got %d", name, code)
+		}
+	}
+}
+
+func TestTokenAuthorization_VerifiesECDSA(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	input := []byte("header.payload")
+	digest := sha256.Sum256(input)
+	r, s, _ := ecdsa.Sign(rand.Reader, key, digest[:])
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	if err := verifyJWTSignature("ES256", &key.PublicKey, input, signature); err != nil {
+		t.Errorf("Expected a valid ES256 signature, got %v", err)
+	}
+	if err := verifyJWTSignature("ES256", &key.PublicKey, []byte("header.tampered"), signature); err == nil {
+		t.Error("Expected a tampered input to fail verification")
+	}
+	if err := verifyJWTSignature("RS256", &key.PublicKey, input, signature); err == nil {
+		t.Error("Expected an RSA algorithm with an EC key to fail")
+	}
+	if err := verifyJWTSignature("HS256", &key.PublicKey, input, signature); err == nil {
+		t.Error("Expected HS256 to be unsupported")
+	}
+}
@@ -0,0 +1,315 @@
+// Multi-Model Authorization Microservice - Per-Subject Decision Tracing
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// defaultTraceDuration is how long a trace session lasts when no duration is given
+	defaultTraceDuration = 15 * time.Minute
+
+	// maxTraceDuration bounds trace sessions so forgotten ones cannot trace forever
+	maxTraceDuration = 24 * time.Hour
+
+	// traceHistorySize is the number of recent traces kept per session
+	traceHistorySize = 50
+)
+
+// DecisionTrace is the step-by-step evaluation of one traced decision
+type DecisionTrace struct {
+	RequestID string    `json:"request_id,omitempty"`
+	Model     string    `json:"model"`
+	Subject   string    `json:"subject"`
+	Object    string    `json:"object"`
+	Action    string    `json:"action"`
+	Allowed   bool      `json:"allowed"`
+	Steps     []string  `json:"steps"`
+	TracedAt  time.Time `json:"traced_at"`
+}
+
+// TraceSession enables verbose tracing of decisions about a subject, an object or both
+// until it expires
+type TraceSession struct {
+	ID        string          `json:"id"`
+	Subject   string          `json:"subject,omitempty"` // Empty matches any subject
+	Object    string          `json:"object,omitempty"`  // Empty matches any object
+	CreatedBy string          `json:"created_by,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Matched   int             `json:"matched"`
+	Traces    []DecisionTrace `json:"traces,omitempty"` // Most recent traces, oldest first
+}
+
+// TraceRequest represents a request to start a trace session
+type TraceRequest struct {
+	Subject  string `json:"subject"`
+	Object   string `json:"object"`
+	Duration string `json:"duration"` // Go duration, e.g. "30m" (default 15m, max 24h)
+}
+
+// matches reports whether a decision about subject and object is traced by the session
+func (ts *TraceSession) matches(subject, object string) bool {
+	return (ts.Subject == "" || ts.Subject == subject) && (ts.Object == "" || ts.Object == object)
+}
+
+// decisionTracer holds the active trace sessions. Decisions are only checked against the
+// sessions while at least one exists, so tracing costs nothing when unused.
+type decisionTracer struct {
+	mu       sync.Mutex
+	sessions map[string]*TraceSession
+	nextID   int
+	active   atomic.Int32
+}
+
+// newDecisionTracer creates a tracer without sessions
+func newDecisionTracer() *decisionTracer {
+	return &decisionTracer{sessions: make(map[string]*TraceSession)}
+}
+
+// Start opens a trace session for subject and object lasting duration
+func (dt *decisionTracer) Start(subject, object, createdBy string, duration time.Duration, now time.Time) TraceSession {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	dt.nextID++
+	session := &TraceSession{
+		ID:        fmt.Sprintf("trace-%d", dt.nextID),
+		Subject:   subject,
+		Object:    object,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		ExpiresAt: now.Add(duration),
+	}
+	dt.sessions[session.ID] = session
+	dt.active.Store(int32(len(dt.sessions)))
+	return *session
+}
+
+// Stop ends a trace session before it expires
+func (dt *decisionTracer) Stop(id string) bool {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	if _, exists := dt.sessions[id]; !exists {
+		return false
+	}
+	delete(dt.sessions, id)
+	dt.active.Store(int32(len(dt.sessions)))
+	return true
+}
+
+// expire drops sessions past their expiry. Callers must hold mu.
+func (dt *decisionTracer) expire(now time.Time) {
+	for id, session := range dt.sessions {
+		if !now.Before(session.ExpiresAt) {
+			log.Printf("[trace %s] Trace session expired after %d traced decisions", id, session.Matched)
+			delete(dt.sessions, id)
+		}
+	}
+	dt.active.Store(int32(len(dt.sessions)))
+}
+
+// Session returns a copy of an active session including its recent traces
+func (dt *decisionTracer) Session(id string, now time.Time) (TraceSession, bool) {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	dt.expire(now)
+	session, exists := dt.sessions[id]
+	if !exists {
+		return TraceSession{}, false
+	}
+	copied := *session
+	copied.Traces = append([]DecisionTrace{}, session.Traces...)
+	return copied, true
+}
+
+// Sessions lists the active sessions without their traces, oldest first
+func (dt *decisionTracer) Sessions(now time.Time) []TraceSession {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	dt.expire(now)
+	sessions := make([]TraceSession, 0, len(dt.sessions))
+	for _, session := range dt.sessions {
+		copied := *session
+		copied.Traces = nil
+		sessions = append(sessions, copied)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt) ||
+			(sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) && sessions[i].ID < sessions[j].ID)
+	})
+	return sessions
+}
+
+// matching returns the IDs of the active sessions tracing subject and object
+func (dt *decisionTracer) matching(subject, object string, now time.Time) []string {
+	if dt == nil || dt.active.Load() == 0 {
+		return nil
+	}
+
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	dt.expire(now)
+	var ids []string
+	for id, session := range dt.sessions {
+		if session.matches(subject, object) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// record stores a trace in the given sessions, keeping only the most recent ones
+func (dt *decisionTracer) record(ids []string, trace DecisionTrace) {
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+
+	for _, id := range ids {
+		session, exists := dt.sessions[id]
+		if !exists {
+			continue
+		}
+		session.Matched++
+		session.Traces = append(session.Traces, trace)
+		if len(session.Traces) > traceHistorySize {
+			session.Traces = session.Traces[len(session.Traces)-traceHistorySize:]
+		}
+	}
+}
+
+// traceDecision writes a detailed evaluation trace of a decision when a trace session
+// matches its subject or object
+func (s *AuthService) traceDecision(requestID string, model AccessControlModel, subject, object, action string, attributes map[string]string, allowed bool) {
+	ids := s.decisionTracer.matching(subject, object, time.Now())
+	if len(ids) == 0 {
+		return
+	}
+
+	if model == "" {
+		model = ModelRBAC
+	}
+	trace := DecisionTrace{
+		RequestID: requestID,
+		Model:     string(model),
+		Subject:   subject,
+		Object:    object,
+		Action:    action,
+		Allowed:   allowed,
+		Steps:     s.explainDecision(model, subject, object, action, attributes),
+		TracedAt:  time.Now(),
+	}
+	s.decisionTracer.record(ids, trace)
+
+	outcome := map[bool]string{true: "allowed", false: "denied"}[allowed]
+	for _, id := range ids {
+		log.Printf("[trace %s] [%s] %s decision: %s %s %s -> %s", id, requestID, model, subject, action, object, outcome)
+		for _, step := range trace.Steps {
+			log.Printf("[trace %s] [%s]   %s", id, requestID, step)
+		}
+	}
+}
+
+// explainDecision re-evaluates a decision and describes each step. It is only used for
+// traced requests, so it favors detail over speed.
+func (s *AuthService) explainDecision(model AccessControlModel, subject, object, action string, attributes map[string]string) []string {
+	explanation, err := s.explain(model, subject, object, action, attributes, freshnessDefault)
+	if err != nil {
+		return []string{fmt.Sprintf("evaluation error: %v", err)}
+	}
+	return explanation.Steps()
+}
+
+// startTraceHandler starts tracing decisions about a subject and/or object
+func (s *AuthService) startTraceHandler(w http.ResponseWriter, r *http.Request) {
+	var request TraceRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	if err := request.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	duration := defaultTraceDuration
+	if request.Duration != "" {
+		parsed, err := time.ParseDuration(request.Duration)
+		if err != nil || parsed <= 0 || parsed > maxTraceDuration {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("duration must be a positive duration of at most %s", maxTraceDuration))
+			return
+		}
+		duration = parsed
+	}
+
+	session := s.decisionTracer.Start(request.Subject, request.Object, actorFromRequest(r), duration, time.Now())
+	log.Printf("[trace %s] Tracing decisions for subject=%q object=%q until %s", session.ID, session.Subject, session.Object, session.ExpiresAt.Format(time.RFC3339))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session)
+}
+
+// getTracesHandler lists the active trace sessions
+func (s *AuthService) getTracesHandler(w http.ResponseWriter, r *http.Request) {
+	sessions := s.decisionTracer.Sessions(time.Now())
+
+	response := map[string]interface{}{
+		"sessions": sessions,
+		"count":    len(sessions),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// getTraceHandler returns a trace session with its most recent decision traces
+func (s *AuthService) getTraceHandler(w http.ResponseWriter, r *http.Request) {
+	session, exists := s.decisionTracer.Session(mux.Vars(r)["id"], time.Now())
+	if !exists {
+		writeJSONError(w, http.StatusNotFound, "Trace session not found or expired")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// stopTraceHandler ends a trace session before it expires
+func (s *AuthService) stopTraceHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	w.Header().Set("Content-Type", "application/json")
+
+	if !s.decisionTracer.Stop(id) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"code":    errorCode(http.StatusNotFound),
+			"removed": false,
+			"message": "Trace session not found or expired",
+		})
+		return
+	}
+
+	log.Printf("[trace %s] Trace session stopped", id)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"removed": true,
+		"message": "Trace session stopped",
+	})
+}
@@ -0,0 +1,146 @@
+// Multi-Model Authorization Microservice - Per-Subject Decision Tracing Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTracing_TracesOnlyMatchingDecisions(t *testing.T) {
+	service := setupTestService(t)
+	service.decisionTracer = newDecisionTracer()
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/traces", service.startTraceHandler).Methods("POST")
+	router.HandleFunc("/api/v1/traces", service.getTracesHandler).Methods("GET")
+	router.HandleFunc("/api/v1/traces/{id}", service.getTraceHandler).Methods("GET")
+	router.HandleFunc("/api/v1/traces/{id}", service.stopTraceHandler).Methods("DELETE")
+
+	service.rbacEnforcer.AddRoleForUser("bob", "contractor")
+	service.rbacEnforcer.AddRoleForUser("contractor", "staff")
+	service.rbacEnforcer.AddPolicy("staff", "finance_docs", "read", "allow")
+	service.rbacEnforcer.AddPolicy("contractor", "finance_docs", "delete", "deny")
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send("POST", "/api/v1/traces", `{"subject": "bob", "duration": "30m"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var session TraceSession
+	json.Unmarshal(rr.Body.Bytes(), &session)
+	if session.ID == "" || session.Subject != "bob" || session.ExpiresAt.Sub(session.CreatedAt) != 30*time.Minute {
+		t.Fatalf("Unexpected session: %+v", session)
+	}
+
+	send("POST", "/api/v1/authorizations", `{"model": "rbac", "subject": "bob", "object": "finance_docs", "action": "read"}`)
+	send("POST", "/api/v1/authorizations", `{"model": "rbac", "subject": "bob", "object": "finance_docs", "action": "delete"}`)
+	send("POST", "/api/v1/authorizations", `{"model": "rbac", "subject": "alice", "object": "finance_docs", "action": "read"}`)
+
+	rr = send("GET", "/api/v1/traces/"+session.ID, "")
+	json.Unmarshal(rr.Body.Bytes(), &session)
+	if session.Matched != 2 || len(session.Traces) != 2 {
+		t.Fatalf("Expected only bob's 2 decisions to be traced, got %+v", session)
+	}
+	read, deleted := session.Traces[0], session.Traces[1]
+	if !read.Allowed || !strings.Contains(strings.Join(read.Steps, "\n"), "staff, finance_docs, read, allow") {
+		t.Errorf("Expected the read trace to name the granting rule: %+v", read)
+	}
+	if deleted.Allowed || !strings.Contains(strings.Join(deleted.Steps, "\n"), "contractor, finance_docs, delete, deny") {
+		t.Errorf("Expected the delete trace to name the deny rule: %+v", deleted)
+	}
+
+	var listing struct {
+		Sessions []TraceSession `json:"sessions"`
+		Count    int            `json:"count"`
+	}
+	json.Unmarshal(send("GET", "/api/v1/traces", "").Body.Bytes(), &listing)
+	if listing.Count != 1 || listing.Sessions[0].Matched != 2 || len(listing.Sessions[0].Traces) != 0 {
+		t.Errorf("Expected one session listed without traces: %+v", listing)
+	}
+
+	if rr := send("DELETE", "/api/v1/traces/"+session.ID, ""); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 stopping the session, got %d", rr.Code)
+	}
+	if rr := send("DELETE", "/api/v1/traces/"+session.ID, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a stopped session, got %d", rr.Code)
+	}
+
+	for _, body := range []string{`{}`, `{"subject": "bob", "duration": "48h"}`, `{"object": "doc", "duration": "soon"}`} {
+		if rr := send("POST", "/api/v1/traces", body); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, rr.Code)
+		}
+	}
+}
+
+func TestTracing_ExplainsABACAndReBAC(t *testing.T) {
+	service := setupTestService(t)
+	service.decisionTracer = newDecisionTracer()
+	service.decisionTracer.Start("", "report", "", time.Hour, time.Now())
+
+	service.saveUserAttribute("carol", "department", "finance")
+	service.policyEngine.AddPolicy(&ABACPolicy{
+		ID:       "finance-read",
+		Name:     "Finance reads reports",
+		Effect:   "allow",
+		Priority: 10,
+		Conditions: []PolicyCondition{
+			{Type: "user", Field: "department", Operator: "eq", Value: "finance"},
+		},
+	})
+	service.traceDecision("req-1", ModelABAC, "carol", "report", "read", nil, true)
+
+	service.relationshipGraph.AddRelationship("dave", "viewer", "report")
+	service.traceDecision("req-2", ModelReBAC, "dave", "report", "read", nil, true)
+
+	// Decisions about other objects are not traced
+	service.traceDecision("req-3", ModelReBAC, "dave", "other", "read", nil, false)
+
+	session, _ := service.decisionTracer.Session("trace-1", time.Now())
+	if len(session.Traces) != 2 {
+		t.Fatalf("Expected 2 traces, got %+v", session.Traces)
+	}
+	abac := strings.Join(session.Traces[0].Steps, "\n")
+	if !strings.Contains(abac, "department:finance") || !strings.Contains(abac, "policy finance-read (priority 10, allow): matched") {
+		t.Errorf("Unexpected ABAC trace:\n%s", abac)
+	}
+	rebac := strings.Join(session.Traces[1].Steps, "\n")
+	if !strings.Contains(rebac, "dave -[viewer]-> report") {
+		t.Errorf("Unexpected ReBAC trace:\n%s", rebac)
+	}
+}
+
+func TestTracing_SessionsExpire(t *testing.T) {
+	tracer := newDecisionTracer()
+	now := time.Now()
+	session := tracer.Start("bob", "", "ops", time.Minute, now)
+
+	if ids := tracer.matching("bob", "doc", now); len(ids) != 1 || ids[0] != session.ID {
+		t.Fatalf("Expected the session to match, got %v", ids)
+	}
+	if ids := tracer.matching("bob", "doc", now.Add(2*time.Minute)); len(ids) != 0 {
+		t.Errorf("Expected the expired session not to match, got %v", ids)
+	}
+	if sessions := tracer.Sessions(now); len(sessions) != 0 {
+		t.Errorf("Expected expired sessions to be dropped, got %+v", sessions)
+	}
+
+	// A service without a tracer never traces
+	var missing *decisionTracer
+	if ids := missing.matching("bob", "doc", now); ids != nil {
+		t.Errorf("Expected no matches without a tracer, got %v", ids)
+	}
+}
@@ -0,0 +1,390 @@
+// Multi-Model Authorization Microservice - Transactions
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/casbin/casbin/v2/model"
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"gorm.io/gorm"
+)
+
+// maxTransactionMutations bounds the mutations of one transaction
+const maxTransactionMutations = 1000
+
+// Operations a transaction can apply
+const (
+	txOpAddRole         = "add_role"
+	txOpAddRelationship = "add_relationship"
+	txOpSetAttribute    = "set_attribute"
+)
+
+// Statuses of the mutations of a committed transaction
+const (
+	txStatusApplied   = "applied"
+	txStatusUnchanged = "unchanged"
+)
+
+// TransactionMutation is one change of a transaction. The fields used depend on Op: user and
+// role to add a role, subject, relationship, object and optionally expires_at and caveat to
+// add a relationship, and user, attribute, value and optionally type to set an attribute.
+type TransactionMutation struct {
+	Op           string              `json:"op"` // "add_role", "add_relationship" or "set_attribute"
+	User         string              `json:"user,omitempty"`
+	Role         string              `json:"role,omitempty"`
+	Subject      string              `json:"subject,omitempty"`
+	Relationship string              `json:"relationship,omitempty"`
+	Object       string              `json:"object,omitempty"`
+	ExpiresAt    *time.Time          `json:"expires_at,omitempty"`
+	Caveat       *RelationshipCaveat `json:"caveat,omitempty"`
+	Source       string              `json:"source,omitempty"` // Originating system of a tuple
+	Note         string              `json:"note,omitempty"`   // Why a tuple was written
+	Attribute    string              `json:"attribute,omitempty"`
+	Value        string              `json:"value,omitempty"`
+	Type         string              `json:"type,omitempty"` // Value type of the attribute
+}
+
+// TransactionRequest is a list of mutations applied together or not at all
+type TransactionRequest struct {
+	Mutations []TransactionMutation `json:"mutations"`
+}
+
+// TransactionResult reports the outcome of one mutation of a committed transaction
+type TransactionResult struct {
+	Index  int    `json:"index"` // Position of the mutation in the request
+	Op     string `json:"op"`
+	Status string `json:"status"` // "applied", or "unchanged" for a role the user already held
+}
+
+// MutationError reports the mutation that made a transaction fail
+type MutationError struct {
+	Index int
+	Err   error
+}
+
+func (e *MutationError) Error() string {
+	return fmt.Sprintf("mutations[%d]: %v", e.Index, e.Err)
+}
+
+func (e *MutationError) Unwrap() error {
+	return e.Err
+}
+
+// AddRelationshipsWith adds tuples along with whatever also writes in one database
+// transaction. Unlike AddRelationships it is all or nothing: a tuple that violates enforced
+// types or a constraint, reported as a *MutationError holding its position, or an error of
+// also leaves the database unchanged.
+func (rg *RelationshipGraph) AddRelationshipsWith(tuples []LabeledRelationship, also func(tx *gorm.DB) error) error {
+	unlock := rg.writeLock()
+	defer unlock()
+
+	err := rg.db.Transaction(func(tx *gorm.DB) error {
+		for i, tuple := range tuples {
+			if rg.enforceTypes {
+				if problems := rg.typeProblems(tuple.Subject, tuple.Relationship.Relationship, tuple.Object); len(problems) > 0 {
+					return &MutationError{Index: i, Err: &TypeError{Problems: problems}}
+				}
+			}
+			if err := rg.checkConstraintsIn(tx, tuple.Subject, tuple.Relationship.Relationship, tuple.Object); err != nil {
+				var cardinalityErr *CardinalityError
+				if errors.As(err, &cardinalityErr) {
+					return &MutationError{Index: i, Err: err}
+				}
+				return err
+			}
+			record := RelationshipRecord{
+				Subject:      tuple.Subject,
+				Relationship: tuple.Relationship.Relationship,
+				Object:       tuple.Object,
+				ExpiresAt:    tuple.ExpiresAt,
+				Caveat:       tuple.Caveat,
+			}
+			if tuple.Metadata != nil {
+				record.TupleMetadata = *tuple.Metadata
+			}
+			if err := tx.Create(&record).Error; err != nil {
+				return fmt.Errorf("failed to save relationship %d: %v", i, err)
+			}
+		}
+		return also(tx)
+	})
+	if err != nil || len(tuples) == 0 {
+		return err
+	}
+
+	rg.bumpRevision()
+	for _, tuple := range tuples {
+		rg.applyAdded(tuple.Subject, tuple.Relationship.Relationship, tuple.Object, tuple.ExpiresAt, tuple.Caveat)
+	}
+	// The transaction is a single write for consistency tokens
+	return rg.recordWrite()
+}
+
+// enforcerWatcher returns the watcher set on the enforcer of a model, if any
+func (s *AuthService) enforcerWatcher(accessModel AccessControlModel) *revisionWatcher {
+	if s.invalidator != nil && s.invalidator.enforcers[string(accessModel)] != nil {
+		return s.invalidator.enforcers[string(accessModel)]
+	}
+	if s.decisions != nil {
+		switch accessModel {
+		case ModelACL:
+			return s.decisions.acl
+		case ModelRBAC:
+			return s.decisions.rbac
+		}
+	}
+	return nil
+}
+
+// loadCommittedRoles adds role assignments already saved in the database to the RBAC
+// enforcer. Adding them through the enforcer would save them a second time, so its watcher
+// is told directly, which invalidates cached decisions and reaches other instances.
+func (s *AuthService) loadCommittedRoles(assignments [][]string) error {
+	if err := s.addCommittedRoles(assignments); err != nil {
+		return err
+	}
+	if watcher := s.enforcerWatcher(ModelRBAC); watcher != nil {
+		return watcher.Update()
+	}
+	return nil
+}
+
+// addCommittedRoles adds role assignments to the RBAC enforcer's model and role links,
+// holding the enforcer's lock that its own methods take
+func (s *AuthService) addCommittedRoles(assignments [][]string) error {
+	lock := s.rbacEnforcer.GetLock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	if err := s.rbacEnforcer.GetModel().AddPolicies("g", "g", assignments); err != nil {
+		return err
+	}
+	return s.rbacEnforcer.BuildIncrementalRoleLinks(model.PolicyAdd, "g", assignments)
+}
+
+// ApplyTransaction applies mutations, which hold qualified names, in one database
+// transaction: when one of them fails none takes effect. Each applied mutation is then
+// published like the single change it stands for.
+func (s *AuthService) ApplyTransaction(mutations []TransactionMutation, actor string) ([]TransactionResult, error) {
+	results := make([]TransactionResult, len(mutations))
+
+	var roles [][]string
+	var tuples []LabeledRelationship
+	var tupleMutations []int                  // Position of each tuple among the mutations
+	attributes := make(map[int]UserAttribute) // Resolved attributes by mutation
+	assigned := make(map[string]bool)
+	for i, mutation := range mutations {
+		results[i] = TransactionResult{Index: i, Op: mutation.Op, Status: txStatusApplied}
+		switch mutation.Op {
+		case txOpAddRole:
+			held, err := s.rbacEnforcer.HasGroupingPolicy(mutation.User, mutation.Role)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read roles: %v", err)
+			}
+			key := labelKey(mutation.User, mutation.Role)
+			if held || assigned[key] {
+				results[i].Status = txStatusUnchanged
+				continue
+			}
+			assigned[key] = true
+			roles = append(roles, []string{mutation.User, mutation.Role})
+		case txOpAddRelationship:
+			tupleMutations = append(tupleMutations, i)
+			tuples = append(tuples, LabeledRelationship{
+				Relationship: Relationship{Subject: mutation.Subject, Relationship: mutation.Relationship, Object: mutation.Object},
+				ExpiresAt:    mutation.ExpiresAt,
+				Caveat:       mutation.Caveat,
+				Metadata:     &TupleMetadata{CreatedBy: actor, Source: mutation.Source, Note: mutation.Note},
+			})
+		case txOpSetAttribute:
+			valueType, value, err := s.resolveAttributeValue("user", mutation.Attribute, mutation.Value, mutation.Type)
+			if err != nil {
+				return nil, &MutationError{Index: i, Err: err}
+			}
+			attributes[i] = UserAttribute{UserID: mutation.User, Attribute: mutation.Attribute, Value: value, ValueType: valueType}
+		}
+	}
+
+	err := s.relationshipGraph.AddRelationshipsWith(tuples, func(tx *gorm.DB) error {
+		for _, role := range roles {
+			rule := gormadapter.CasbinRule{Ptype: "g", V0: role[0], V1: role[1]}
+			if err := tx.Table("rbac_rules").Create(&rule).Error; err != nil {
+				return fmt.Errorf("failed to save role %s of %s: %v", role[1], role[0], err)
+			}
+		}
+		for i := range mutations {
+			if attribute, ok := attributes[i]; ok {
+				if err := saveUserAttributeIn(tx, attribute.UserID, attribute.Attribute, attribute.Value, attribute.ValueType); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	var mutationErr *MutationError
+	if errors.As(err, &mutationErr) {
+		return nil, &MutationError{Index: tupleMutations[mutationErr.Index], Err: mutationErr.Err}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(roles) > 0 {
+		if err := s.loadCommittedRoles(roles); err != nil {
+			return nil, fmt.Errorf("roles were saved but failed to load: %v", err)
+		}
+	}
+	for _, attribute := range attributes {
+		s.attributeTypes.set("user", attribute.Attribute, attribute.ValueType)
+		s.invalidateUserAttributes(attribute.UserID)
+	}
+
+	for i, mutation := range mutations {
+		if results[i].Status != txStatusApplied {
+			continue
+		}
+		switch mutation.Op {
+		case txOpAddRole:
+			key := labelKey(mutation.User, mutation.Role)
+			s.recordPolicyMetadata(labelKindRole, key, actor)
+			s.publishChange(changeKindRBACRole, changeAdded, key, roleChange(mutation.User, mutation.Role), actor)
+		case txOpAddRelationship:
+			change := relationshipChange(mutation.Subject, mutation.Relationship, mutation.Object, mutation.ExpiresAt)
+			change.Caveat = mutation.Caveat
+			change.Metadata = &TupleMetadata{CreatedBy: actor, Source: mutation.Source, Note: mutation.Note}
+			s.publishChange(changeKindRelationship, changeAdded, labelKey(mutation.Subject, mutation.Relationship, mutation.Object), change, actor)
+		case txOpSetAttribute:
+			attribute := attributes[i]
+			s.publishChange(changeKindUserAttribute, changeUpdated, attribute.UserID+"."+attribute.Attribute, attributeChange("user", attribute.UserID, attribute.Attribute, attribute.Value), actor)
+		}
+	}
+	return results, nil
+}
+
+// checkTransactionQuotas checks that a tenant has room for the roles, tuples and attributes
+// a transaction adds
+func (s *AuthService) checkTransactionQuotas(w http.ResponseWriter, scope tenantScope, mutations []TransactionMutation) bool {
+	if scope.global() {
+		return true
+	}
+	counts := make(map[string]int)
+	attributes := make(map[string][]string)
+	var users []string
+	for _, mutation := range mutations {
+		switch mutation.Op {
+		case txOpAddRole:
+			counts[quotaPolicies]++
+		case txOpAddRelationship:
+			counts[quotaRelationships]++
+		case txOpSetAttribute:
+			if _, seen := attributes[mutation.User]; !seen {
+				users = append(users, mutation.User)
+			}
+			attributes[mutation.User] = append(attributes[mutation.User], mutation.Attribute)
+		}
+	}
+	for _, dimension := range []string{quotaPolicies, quotaRelationships} {
+		if !s.checkQuota(w, scope, dimension, counts[dimension]) {
+			return false
+		}
+	}
+	for _, user := range users {
+		if !s.checkAttributeQuota(w, scope, "user", user, attributes[user]) {
+			return false
+		}
+	}
+	return true
+}
+
+// transactionHandler applies role, relationship and attribute mutations atomically
+func (s *AuthService) transactionHandler(w http.ResponseWriter, r *http.Request) {
+	var req TransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid request format")
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	var warnings, problems []string
+	mutations := make([]TransactionMutation, len(req.Mutations))
+	for i, mutation := range req.Mutations {
+		mutation.User, mutation.Role = scope.qualify(mutation.User), scope.qualify(mutation.Role)
+		mutation.Subject, mutation.Object = scope.qualify(mutation.Subject), scope.qualify(mutation.Object)
+		mutations[i] = mutation
+
+		switch mutation.Op {
+		case txOpAddRelationship:
+			warnings = append(warnings, s.relationshipTypeWarnings(mutation.Subject, mutation.Relationship, mutation.Object)...)
+		case txOpSetAttribute:
+			attributeProblems, err := s.validateAttributes("user", map[string]string{mutation.Attribute: mutation.Value})
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			problems = append(problems, attributeProblems...)
+		}
+	}
+	if !s.acceptSchemaProblems(w, problems) {
+		return
+	}
+	warnings = append(warnings, problems...)
+	if !s.checkTransactionQuotas(w, scope, mutations) {
+		return
+	}
+
+	results, err := s.ApplyTransaction(mutations, actorFromRequest(r))
+	var mutationErr *MutationError
+	if errors.As(err, &mutationErr) {
+		status := http.StatusBadRequest
+		var cardinalityErr *CardinalityError
+		if errors.As(err, &cardinalityErr) {
+			status = http.StatusConflict
+		}
+		writeErrorResponse(w, status, ErrorResponse{
+			Code:    errorCode(status),
+			Message: "Transaction rolled back: " + err.Error(),
+			Details: map[string]int{"index": mutationErr.Index},
+		})
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Transaction rolled back: %v", err))
+		return
+	}
+
+	applied := 0
+	for _, result := range results {
+		if result.Status == txStatusApplied {
+			applied++
+		}
+	}
+	response := map[string]interface{}{
+		"committed": true,
+		"results":   results,
+		"applied":   applied,
+		"unchanged": len(results) - applied,
+	}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	s.relationshipGraph.addConsistencyToken(response)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,107 @@
+// Multi-Model Authorization Microservice - Transaction Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransactions_AppliesAllMutations(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/transactions", service.transactionHandler).Methods("POST")
+	service.rbacEnforcer.AddPolicy("editor", "docs", "write", "allow")
+	service.rbacEnforcer.AddRoleForUser("bob", "editor")
+
+	body := `{"mutations": [
+		{"op": "add_role", "user": "alice", "role": "editor"},
+		{"op": "add_role", "user": "bob", "role": "editor"},
+		{"op": "add_relationship", "subject": "alice", "relationship": "owner", "object": "plan"},
+		{"op": "set_attribute", "user": "alice", "attribute": "department", "value": "finance"}
+	]}`
+	req, _ := http.NewRequest("POST", "/api/v1/transactions", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+	var response struct {
+		Results []TransactionResult `json:"results"`
+		Applied int                 `json:"applied"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if response.Applied != 3 || response.Results[1].Status != txStatusUnchanged {
+		t.Errorf("Expected bob's role to be unchanged, got %s", rr.Body.String())
+	}
+
+	if allowed, _ := service.Enforce(ModelRBAC, "alice", "docs", "write", nil); !allowed {
+		t.Error("Expected alice to get the editor role")
+	}
+	if allowed, _ := service.relationshipGraph.CheckReBACAccess("alice", "plan", "write"); !allowed {
+		t.Error("Expected alice to own the plan")
+	}
+	if attributes, _ := service.getUserAttributes("alice"); attributes["department"] != "finance" {
+		t.Errorf("Expected alice's department to be set, got %v", attributes)
+	}
+
+	// The role was saved once, so reloading from the database keeps it
+	if err := service.rbacEnforcer.LoadPolicy(); err != nil {
+		t.Fatalf("Failed to reload policies: %v", err)
+	}
+	if roles, _ := service.rbacEnforcer.GetRolesForUser("alice"); len(roles) != 1 {
+		t.Errorf("Expected the role to be stored, got %v", roles)
+	}
+}
+
+func TestTransactions_AllOrNothing(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/transactions", service.transactionHandler).Methods("POST")
+	service.relationshipGraph.constraints = []RelationshipConstraint{{Relationship: "owner", Per: "object", Max: 1}}
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/api/v1/transactions", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The second owner violates the constraint, so the earlier mutations are undone
+	rr := post(`{"mutations": [
+		{"op": "add_role", "user": "alice", "role": "editor"},
+		{"op": "set_attribute", "user": "alice", "attribute": "department", "value": "finance"},
+		{"op": "add_relationship", "subject": "alice", "relationship": "owner", "object": "plan"},
+		{"op": "add_relationship", "subject": "bob", "relationship": "owner", "object": "plan"}
+	]}`)
+	var errorResponse struct {
+		Details map[string]int `json:"details"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &errorResponse)
+	if rr.Code != http.StatusConflict || errorResponse.Details["index"] != 3 {
+		t.Fatalf("Expected a conflict on the fourth mutation, got %d %s", rr.Code, rr.Body.String())
+	}
+	if roles, _ := service.rbacEnforcer.GetRolesForUser("alice"); len(roles) != 0 {
+		t.Errorf("Expected no role, got %v", roles)
+	}
+	if attributes, _ := service.getUserAttributes("alice"); len(attributes) != 0 {
+		t.Errorf("Expected no attributes, got %v", attributes)
+	}
+	if relationships, _ := service.relationshipGraph.ListRelationships(""); len(relationships) != 0 {
+		t.Errorf("Expected no relationships, got %v", relationships)
+	}
+	service.rbacEnforcer.LoadPolicy()
+	if roles, _ := service.rbacEnforcer.GetRolesForUser("alice"); len(roles) != 0 {
+		t.Errorf("Expected no stored role, got %v", roles)
+	}
+
+	// Malformed mutations are rejected before anything is written
+	if rr := post(`{"mutations": [{"op": "add_role", "user": "alice"}, {"op": "remove_role"}]}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected a validation error, got %d %s", rr.Code, rr.Body.String())
+	}
+}
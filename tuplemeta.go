@@ -0,0 +1,61 @@
+// Multi-Model Authorization Microservice - ReBAC Tuple Metadata
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"time"
+)
+
+// TupleMetadata records who wrote a relationship tuple, from which system and why. It is
+// stored with the tuple and never affects checks.
+type TupleMetadata struct {
+	CreatedBy string `json:"created_by,omitempty"` // Authenticated client or X-Actor of the write
+	Source    string `json:"source,omitempty"`     // Originating system, such as an HR sync
+	Note      string `json:"note,omitempty"`
+}
+
+// ListedTupleMetadata is the metadata of a listed tuple along with when it was written
+type ListedTupleMetadata struct {
+	TupleMetadata
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// metadataBatchSize bounds the subjects and objects looked up by one metadata query
+const metadataBatchSize = 200
+
+// RelationshipMetadata returns the metadata of the given tuples, keyed by tuple. Of several
+// copies of a tuple the latest write wins, as for caveats. Tuples missing from the
+// database, such as expired ones, are left out.
+func (rg *RelationshipGraph) RelationshipMetadata(tuples []Relationship) (map[string]ListedTupleMetadata, error) {
+	wanted := make(map[string]bool, len(tuples))
+	subjects := make(map[string]bool)
+	for _, rel := range tuples {
+		wanted[labelKey(rel.Subject, rel.Relationship, rel.Object)] = true
+		subjects[rel.Subject] = true
+	}
+
+	var names []string
+	for subject := range subjects {
+		names = append(names, subject)
+	}
+	metadata := make(map[string]ListedTupleMetadata, len(tuples))
+	for start := 0; start < len(names); start += metadataBatchSize {
+		end := start + metadataBatchSize
+		if end > len(names) {
+			end = len(names)
+		}
+		var records []RelationshipRecord
+		if err := rg.db.Order("id").Where("subject IN ?", names[start:end]).Find(&records).Error; err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			key := labelKey(record.Subject, record.Relationship, record.Object)
+			if wanted[key] {
+				metadata[key] = ListedTupleMetadata{TupleMetadata: record.TupleMetadata, CreatedAt: record.CreatedAt}
+			}
+		}
+	}
+	return metadata, nil
+}
@@ -0,0 +1,61 @@
+// Multi-Model Authorization Microservice - ReBAC Tuple Metadata Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTupleMetadata_StoredAndListed(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/relationships", service.addRelationshipHandler).Methods("POST")
+	router.HandleFunc("/api/v1/relationships", service.getRelationshipsHandler).Methods("GET")
+	router.HandleFunc("/api/v1/relationships/bulk", service.bulkAddRelationshipsHandler).Methods("POST")
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Actor", "alice")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := send("POST", "/api/v1/relationships", `{"subject": "engineering", "relationship": "viewer", "object": "roadmap", "source": "drive-sharing", "note": "Q3 planning"}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := send("POST", "/api/v1/relationships/bulk", `{"relationships": [{"subject": "engineering", "relationship": "editor", "object": "specs", "source": "hr-sync"}]}`); rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	service.relationshipGraph.AddRelationship("engineering", "viewer", "wiki")
+
+	rr := send("GET", "/api/v1/relationships?subject=engineering", "")
+	var response struct {
+		Metadata map[string]ListedTupleMetadata `json:"metadata"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+
+	shared := response.Metadata["engineering:viewer:roadmap"]
+	if shared.CreatedBy != "alice" || shared.Source != "drive-sharing" || shared.Note != "Q3 planning" || shared.CreatedAt.IsZero() {
+		t.Errorf("Unexpected metadata of the shared tuple: %+v", shared)
+	}
+	if bulk := response.Metadata["engineering:editor:specs"]; bulk.CreatedBy != "alice" || bulk.Source != "hr-sync" {
+		t.Errorf("Unexpected metadata of the bulk tuple: %+v", bulk)
+	}
+	if internal, ok := response.Metadata["engineering:viewer:wiki"]; !ok || internal.CreatedBy != "" {
+		t.Errorf("Expected tuples written without metadata to list their creation time only, got %+v", internal)
+	}
+
+	long := strings.Repeat("x", maxIdentifierLength+1)
+	if rr := send("POST", "/api/v1/relationships", `{"subject": "bob", "relationship": "viewer", "object": "roadmap", "source": "`+long+`"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected an oversized source to be rejected, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,283 @@
+// Multi-Model Authorization Microservice - Typed ABAC Attribute Values
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Value types of ABAC attributes. Values are stored as strings in a canonical form of
+// their type, so comparisons do not depend on how the writer formatted them.
+const (
+	valueTypeString  = "string"
+	valueTypeNumber  = "number"
+	valueTypeBoolean = "boolean"
+	valueTypeDate    = "date" // RFC 3339 timestamp or YYYY-MM-DD day, stored as RFC 3339 in UTC
+	valueTypeList    = "list" // Comma-separated values
+)
+
+// validValueTypes lists the value types attributes can be stored with
+var validValueTypes = map[string]bool{
+	valueTypeString:  true,
+	valueTypeNumber:  true,
+	valueTypeBoolean: true,
+	valueTypeDate:    true,
+	valueTypeList:    true,
+}
+
+// environmentValueTypes types the environment attributes the service fills in itself
+var environmentValueTypes = map[string]string{
+	"time": valueTypeNumber,
+	"date": valueTypeDate,
+}
+
+// parseDateValue parses an RFC 3339 timestamp or a YYYY-MM-DD day
+func parseDateValue(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+// splitListValue splits a comma-separated list value into its trimmed, non-empty elements
+func splitListValue(value string) []string {
+	var elements []string
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.TrimSpace(element); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	return elements
+}
+
+// normalizeAttributeValue checks that value is of valueType and returns its canonical form
+func normalizeAttributeValue(valueType, value string) (string, error) {
+	switch valueType {
+	case "", valueTypeString:
+		return value, nil
+	case valueTypeNumber:
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return "", fmt.Errorf("expects a number, got %q", value)
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case valueTypeBoolean:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("expects a boolean, got %q", value)
+		}
+		return strconv.FormatBool(b), nil
+	case valueTypeDate:
+		t, err := parseDateValue(strings.TrimSpace(value))
+		if err != nil {
+			return "", fmt.Errorf("expects an RFC 3339 timestamp or YYYY-MM-DD date, got %q", value)
+		}
+		return t.UTC().Format(time.RFC3339), nil
+	case valueTypeList:
+		return strings.Join(splitListValue(value), ","), nil
+	default:
+		return "", fmt.Errorf("has unknown value type %q", valueType)
+	}
+}
+
+// attributeTypeIndex remembers the value type of every typed attribute name, so policy
+// evaluation can compare values by type without loading it with each attribute. Names
+// without an entry are strings. The zero value is an empty index.
+type attributeTypeIndex struct {
+	mu    sync.RWMutex
+	types map[string]map[string]string // Scope ("user" or "object") to attribute name to type
+}
+
+// load fills the index from the value types stored with user and object attributes
+func (ix *attributeTypeIndex) load(db *gorm.DB) error {
+	for scope, model := range map[string]interface{}{"user": &UserAttribute{}, "object": &ObjectAttribute{}} {
+		var rows []struct {
+			Attribute string
+			ValueType string
+		}
+		err := db.Model(model).Distinct("attribute", "value_type").
+			Where("value_type <> '' AND value_type <> ?", valueTypeString).Find(&rows).Error
+		if err != nil {
+			return fmt.Errorf("failed to load %s attribute types: %v", scope, err)
+		}
+		for _, row := range rows {
+			ix.set(scope, row.Attribute, row.ValueType)
+		}
+	}
+	return nil
+}
+
+// get returns the value type of an attribute name, or "" when it has not been typed
+func (ix *attributeTypeIndex) get(scope, name string) string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.types[scope][name]
+}
+
+// set records the value type of an attribute name. Maps are replaced rather than
+// modified, so snapshots can be read without locking.
+func (ix *attributeTypeIndex) set(scope, name, valueType string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+
+	if valueType == valueTypeString {
+		valueType = ""
+	}
+	if ix.types[scope][name] == valueType {
+		return
+	}
+
+	updated := make(map[string]string, len(ix.types[scope])+1)
+	for k, v := range ix.types[scope] {
+		updated[k] = v
+	}
+	if valueType == "" {
+		delete(updated, name)
+	} else {
+		updated[name] = valueType
+	}
+
+	scopes := make(map[string]map[string]string, len(ix.types)+1)
+	for k, v := range ix.types {
+		scopes[k] = v
+	}
+	scopes[scope] = updated
+	ix.types = scopes
+}
+
+// snapshot returns the typed attribute names of a scope; the map must not be modified
+func (ix *attributeTypeIndex) snapshot(scope string) map[string]string {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.types[scope]
+}
+
+// typesOf returns the value types of the named attributes
+func (ix *attributeTypeIndex) typesOf(scope string, attributes map[string]string) map[string]string {
+	types := make(map[string]string, len(attributes))
+	for name := range attributes {
+		if valueType := ix.get(scope, name); valueType != "" {
+			types[name] = valueType
+		} else {
+			types[name] = valueTypeString
+		}
+	}
+	return types
+}
+
+// resolveAttributeValue decides the value type of an attribute write and returns the
+// value in its canonical form. The type is the requested one, else the type the name
+// already has, else the type of its schema definition, else string. A requested type
+// must agree with the type the name already has.
+func (s *AuthService) resolveAttributeValue(scope, name, value, requested string) (valueType, normalized string, err error) {
+	known := s.attributeTypes.get(scope, name)
+	if requested != "" && !validValueTypes[requested] {
+		return "", "", fmt.Errorf("%s attribute %q has unknown value type %q", scope, name, requested)
+	}
+	if requested != "" && known != "" && requested != known {
+		return "", "", fmt.Errorf("%s attribute %q is a %s, not a %s", scope, name, known, requested)
+	}
+
+	valueType = requested
+	if valueType == "" {
+		valueType = known
+	}
+	if valueType == "" {
+		var definition AttributeDefinition
+		if s.db.Where("scope = ? AND name = ?", scope, name).Limit(1).Find(&definition).Error == nil && validValueTypes[definition.Type] {
+			valueType = definition.Type
+		}
+	}
+	if valueType == "" {
+		valueType = valueTypeString
+	}
+
+	normalized, err = normalizeAttributeValue(valueType, value)
+	if err != nil {
+		return "", "", fmt.Errorf("%s attribute %q %v", scope, name, err)
+	}
+	return valueType, normalized, nil
+}
+
+// checkAttributeValues validates an attribute write against the requested and known value
+// types before anything is saved
+func (s *AuthService) checkAttributeValues(scope string, attributes, types map[string]string) error {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, _, err := s.resolveAttributeValue(scope, name, attributes[name], types[name]); err != nil {
+			return err
+		}
+	}
+	for name := range types {
+		if _, set := attributes[name]; !set {
+			return fmt.Errorf("type given for %s attribute %q, which is not being set", scope, name)
+		}
+	}
+	return nil
+}
+
+// conditionValueType returns the value type a condition's attribute is compared as
+func (pe *PolicyEngine) conditionValueType(condition *PolicyCondition, ctx *PolicyEvaluationContext) string {
+	switch condition.Type {
+	case "user":
+		return ctx.UserAttributeTypes[condition.Field]
+	case "object":
+		return ctx.ObjectAttributeTypes[condition.Field]
+	case "environment":
+		return environmentValueTypes[condition.Field]
+	default:
+		return ""
+	}
+}
+
+// typedEqual compares two values by the equality of their type
+func typedEqual(actual, expected, valueType string) bool {
+	switch valueType {
+	case valueTypeNumber, valueTypeBoolean, valueTypeDate:
+		a, errA := normalizeAttributeValue(valueType, actual)
+		e, errE := normalizeAttributeValue(valueType, expected)
+		if errA == nil && errE == nil {
+			return a == e
+		}
+	case valueTypeList:
+		a, e := splitListValue(actual), splitListValue(expected)
+		sort.Strings(a)
+		sort.Strings(e)
+		return strings.Join(a, ",") == strings.Join(e, ",")
+	}
+	return actual == expected
+}
+
+// compareDates orders two dates; ok is false unless both parse
+func compareDates(actual, expected string) (order int, ok bool) {
+	a, errA := parseDateValue(strings.TrimSpace(actual))
+	e, errE := parseDateValue(strings.TrimSpace(expected))
+	if errA != nil || errE != nil {
+		return 0, false
+	}
+	return a.Compare(e), true
+}
+
+// listContains reports whether a list value has element
+func listContains(list, element string) bool {
+	for _, value := range splitListValue(list) {
+		if value == strings.TrimSpace(element) {
+			return true
+		}
+	}
+	return false
+}
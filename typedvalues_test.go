@@ -0,0 +1,105 @@
+// Multi-Model Authorization Microservice - Typed ABAC Attribute Value Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTypedValues_ValidatedAndNormalized(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/users/{userId}/attributes", service.setUserAttributesHandler).Methods("PUT")
+
+	put := func(body string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest("PUT", "/api/v1/users/alice/attributes", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var response map[string]interface{}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr.Code, response
+	}
+
+	code, response := put(`{
+		"attributes": {"clearance": "3.0", "hired": "2023-05-01", "contractor": "TRUE", "teams": " eng, ops ,", "department": "sales"},
+		"types": {"clearance": "number", "hired": "date", "contractor": "boolean", "teams": "list"}
+	}`)
+	if code != http.StatusOK {
+		t.Fatalf("Failed to set typed attributes: %d", code)
+	}
+	attributes := response["attributes"].(map[string]interface{})
+	types := response["types"].(map[string]interface{})
+	if attributes["clearance"] != "3" || attributes["hired"] != "2023-05-01T00:00:00Z" || attributes["contractor"] != "true" || attributes["teams"] != "eng,ops" {
+		t.Errorf("Expected canonical values, got %v", attributes)
+	}
+	if types["hired"] != "date" || types["department"] != "string" {
+		t.Errorf("Unexpected types: %v", types)
+	}
+
+	// Values must parse as their type, including the type a name already has
+	for _, body := range []string{
+		`{"attributes": {"hired": "last spring"}}`,
+		`{"attributes": {"started": "2023-13-45"}, "types": {"started": "date"}}`,
+		`{"attributes": {"clearance": "high"}, "types": {"clearance": "string"}}`,
+		`{"attributes": {"level": "1"}, "types": {"level": "decimal"}}`,
+	} {
+		if code, _ := put(body); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", body, code)
+		}
+	}
+	if err := service.saveUserAttribute("bob", "clearance", "secret"); err == nil {
+		t.Error("Expected an untyped write to keep the number type")
+	}
+
+	var stored UserAttribute
+	service.db.Where("user_id = ? AND attribute = ?", "alice", "teams").First(&stored)
+	if stored.ValueType != valueTypeList {
+		t.Errorf("Expected the value type to be stored, got %q", stored.ValueType)
+	}
+}
+
+func TestTypedValues_TypedComparisons(t *testing.T) {
+	service := setupTestService(t)
+	service.saveTypedUserAttribute("alice", "hired", "2023-05-01", valueTypeDate)
+	service.saveTypedUserAttribute("alice", "contractor", "false", valueTypeBoolean)
+	service.saveTypedUserAttribute("alice", "teams", "eng,ops", valueTypeList)
+	service.saveTypedUserAttribute("alice", "clearance", "10", valueTypeNumber)
+	service.saveUserAttribute("alice", "office", "eng,ops")
+
+	cases := []struct {
+		condition PolicyCondition
+		expected  bool
+	}{
+		{PolicyCondition{Type: "user", Field: "hired", Operator: "before", Value: "2024-01-01"}, true},
+		{PolicyCondition{Type: "user", Field: "hired", Operator: "after", Value: "2023-05-01T12:00:00+02:00"}, false},
+		{PolicyCondition{Type: "user", Field: "hired", Operator: "eq", Value: "2023-05-01T00:00:00Z"}, true},
+		{PolicyCondition{Type: "user", Field: "hired", Operator: "before", Value: "soon"}, false},
+		{PolicyCondition{Type: "user", Field: "contractor", Operator: "eq", Value: "0"}, true},
+		{PolicyCondition{Type: "user", Field: "teams", Operator: "contains", Value: "ops"}, true},
+		{PolicyCondition{Type: "user", Field: "teams", Operator: "contains", Value: "op"}, false}, // Whole elements only
+		{PolicyCondition{Type: "user", Field: "teams", Operator: "in", Value: "eng,ops,sales"}, true},
+		{PolicyCondition{Type: "user", Field: "teams", Operator: "eq", Value: "ops,eng"}, true},
+		{PolicyCondition{Type: "user", Field: "office", Operator: "contains", Value: "op"}, true}, // Strings keep substring matching
+		{PolicyCondition{Type: "user", Field: "clearance", Operator: "eq", Value: "10.0"}, true},
+		{PolicyCondition{Type: "user", Field: "clearance", Operator: "gt", Value: "9"}, true},
+	}
+	ctx := service.abacEvaluationContext("alice", "doc", "read", nil, false)
+	for _, c := range cases {
+		if got := service.policyEngine.evaluateCondition(&c.condition, ctx); got != c.expected {
+			t.Errorf("%s %s %s: expected %v", c.condition.Field, c.condition.Operator, c.condition.Value, c.expected)
+		}
+	}
+
+	// Types are loaded back from the stored records
+	var index attributeTypeIndex
+	if err := index.load(service.db); err != nil || index.get("user", "hired") != valueTypeDate || index.get("user", "office") != "" {
+		t.Errorf("Expected types to be reloaded, got %v (%v)", index.snapshot("user"), err)
+	}
+}
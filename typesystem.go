@@ -0,0 +1,101 @@
+// Multi-Model Authorization Microservice - ReBAC Type System
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Namespace definitions double as object types: once one is defined, tuples are checked
+// against them. The object of a tuple must belong to a defined namespace, which must
+// define the relationship with "this", and the subject must be of one of the relation's
+// subject_types when it lists any.
+
+// subjectTypePattern matches subject_types entries such as "user" or "group#member"
+var subjectTypePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*(#[a-z][a-z0-9_]*)?$`)
+
+// TypeError is returned for a tuple that violates the declared types when they are enforced
+type TypeError struct {
+	Problems []string `json:"problems"`
+}
+
+func (e *TypeError) Error() string {
+	return "relationship type violation: " + strings.Join(e.Problems, "; ")
+}
+
+// subjectType returns the type of a subject: its namespace, followed by the relation for a
+// userset such as "group:eng#member", or "" when the subject has no type
+func subjectType(subject string) string {
+	if object, relation, ok := splitUserset(subject); ok {
+		if namespace := objectNamespace(object); namespace != "" {
+			return namespace + "#" + relation
+		}
+		return ""
+	}
+	return objectNamespace(subject)
+}
+
+// typeProblems reports why a tuple violates the declared types, or nil when it does not or
+// no types are declared
+func (rg *RelationshipGraph) typeProblems(subject, relationship, object string) []string {
+	if len(rg.namespaces) == 0 {
+		return nil
+	}
+
+	objectType := objectNamespace(object)
+	def := rg.namespaces[objectType]
+	if def == nil {
+		if objectType == "" {
+			return []string{"object has no type; name it <type>:<id>"}
+		}
+		return []string{fmt.Sprintf("object type %s is not declared", objectType)}
+	}
+	rewrite, defined := def.Relations[relationship]
+	if !defined {
+		return []string{fmt.Sprintf("type %s has no relation %s", objectType, relationship)}
+	}
+	if !rewrite.This {
+		return []string{fmt.Sprintf("relation %s of type %s is computed and cannot be written", relationship, objectType)}
+	}
+	if len(rewrite.SubjectTypes) == 0 {
+		return nil
+	}
+
+	actual := subjectType(subject)
+	for _, allowed := range rewrite.SubjectTypes {
+		if actual == allowed {
+			return nil
+		}
+	}
+	if actual == "" {
+		actual = "untyped"
+	}
+	return []string{fmt.Sprintf("relation %s of type %s does not accept %s subjects, only %s",
+		relationship, objectType, actual, strings.Join(rewrite.SubjectTypes, ", "))}
+}
+
+// TupleTypeProblems reports why a tuple violates the declared types, or nil
+func (rg *RelationshipGraph) TupleTypeProblems(subject, relationship, object string) []string {
+	unlock := rg.readLock()
+	defer unlock()
+
+	return rg.typeProblems(subject, relationship, object)
+}
+
+// relationshipTypeWarnings returns the type problems of a tuple about to be written when
+// types are only reported; in enforce mode the write itself rejects them
+func (s *AuthService) relationshipTypeWarnings(subject, relationship, object string) []string {
+	if s.relationshipGraph.enforceTypes {
+		return nil
+	}
+	warnings := s.relationshipGraph.TupleTypeProblems(subject, relationship, object)
+	if len(warnings) > 0 {
+		log.Printf("Relationship type warnings: %s", strings.Join(warnings, "; "))
+	}
+	return warnings
+}
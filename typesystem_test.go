@@ -0,0 +1,125 @@
+// Multi-Model Authorization Microservice - ReBAC Type System Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTypeSystem_ChecksTuplesAgainstNamespaces(t *testing.T) {
+	service := setupTestService(t)
+	rg := service.relationshipGraph
+
+	// Without declared types anything goes
+	if problems := rg.TupleTypeProblems("document1", "member", "alice"); problems != nil {
+		t.Errorf("Expected no problems without types, got %v", problems)
+	}
+
+	team := &NamespaceDefinition{Name: "team", Relations: map[string]RelationRewrite{
+		"member": {This: true, SubjectTypes: []string{"user", "team#member"}},
+	}}
+	document := &NamespaceDefinition{Name: "document", Relations: map[string]RelationRewrite{
+		"parent": {This: true, SubjectTypes: []string{"folder"}},
+		"editor": {This: true},
+		"viewer": {ComputedUsersets: []string{"editor"}},
+	}}
+	for _, def := range []*NamespaceDefinition{team, document} {
+		if err := rg.SetNamespace(def); err != nil {
+			t.Fatalf("Failed to define %s: %v", def.Name, err)
+		}
+	}
+
+	cases := []struct {
+		subject, relationship, object string
+		valid                         bool
+	}{
+		{"user:alice", "member", "team:eng", true},
+		{"team:eng#member", "member", "team:all", true},
+		{"acme/user:alice", "member", "acme/team:eng", true},
+		{"anyone", "editor", "document:plan", true},
+		{"document1", "member", "alice", false},
+		{"user:alice", "member", "project:x", false},
+		{"user:alice", "owner", "document:plan", false},
+		{"user:alice", "viewer", "document:plan", false},
+		{"alice", "member", "team:eng", false},
+		{"team:eng", "member", "team:all", false},
+		{"user:alice", "parent", "document:plan", false},
+	}
+	for _, c := range cases {
+		problems := rg.TupleTypeProblems(c.subject, c.relationship, c.object)
+		if (len(problems) == 0) != c.valid {
+			t.Errorf("%s %s %s: expected valid=%v, got %v", c.subject, c.relationship, c.object, c.valid, problems)
+		}
+	}
+
+	// Enforced types reject the write
+	rg.enforceTypes = true
+	var typeErr *TypeError
+	if err := rg.AddRelationship("alice", "member", "team:eng"); !errors.As(err, &typeErr) {
+		t.Errorf("Expected a type error, got %v", err)
+	}
+	if err := rg.AddRelationship("user:alice", "member", "team:eng"); err != nil {
+		t.Errorf("Failed to add a valid tuple: %v", err)
+	}
+
+	invalid := NamespaceDefinition{Name: "folder", Relations: map[string]RelationRewrite{
+		"viewer": {ComputedUsersets: []string{"owner"}, SubjectTypes: []string{"user"}},
+		"owner":  {This: true},
+	}}
+	if err := invalid.validate(); err == nil {
+		t.Error("Expected subject_types without this to be rejected")
+	}
+	invalid.Relations["viewer"] = RelationRewrite{This: true, SubjectTypes: []string{"User"}}
+	if err := invalid.validate(); err == nil {
+		t.Error("Expected an invalid subject type to be rejected")
+	}
+}
+
+func TestTypeSystem_WarnsOrRejectsWrites(t *testing.T) {
+	service := setupTestService(t)
+	service.relationshipGraph.SetNamespace(&NamespaceDefinition{Name: "team", Relations: map[string]RelationRewrite{
+		"member": {This: true, SubjectTypes: []string{"user"}},
+	}})
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/relationships/bulk", service.bulkAddRelationshipsHandler).Methods("POST")
+	post := func(url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Warn mode writes the tuple and reports the problem
+	rr := post("/api/v1/relationships", `{"subject": "team:eng", "relationship": "member", "object": "alice"}`)
+	var response struct {
+		Warnings []string `json:"warnings"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if rr.Code != http.StatusOK || len(response.Warnings) != 1 {
+		t.Errorf("Expected the tuple to be written with a warning, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	service.relationshipGraph.enforceTypes = true
+	if rr := post("/api/v1/relationships", `{"subject": "alice", "relationship": "member", "object": "team:eng"}`); rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an untyped subject, got %d", rr.Code)
+	}
+	rr = post("/api/v1/relationships/bulk", `{"relationships": [
+		{"subject": "user:alice", "relationship": "member", "object": "team:eng"},
+		{"subject": "alice", "relationship": "member", "object": "team:eng"}
+	]}`)
+	var bulk struct {
+		Results []BulkRelationshipResult `json:"results"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &bulk)
+	if len(bulk.Results) != 2 || bulk.Results[0].Status != bulkStatusCreated || bulk.Results[1].Status != bulkStatusFailed {
+		t.Errorf("Expected only the typed tuple to be created, got %s", rr.Body.String())
+	}
+}
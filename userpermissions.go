@@ -0,0 +1,133 @@
+// Multi-Model Authorization Microservice - Effective User Permissions
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// UserPermission is an object and action a user is allowed through RBAC
+type UserPermission struct {
+	Object string   `json:"object"`
+	Action string   `json:"action"`
+	Via    []string `json:"via"` // The user, roles or groups whose allow rules grant the permission
+}
+
+// UserPermissions returns the effective RBAC permissions of a user: the allow rules of the
+// user, their direct and inherited roles and, with group bindings, the roles of their
+// groups. Rules on a resource group stand for each of its members. Candidates are checked
+// like an authorization request, so permissions a deny rule overrides are left out. Only
+// objects starting with objectPrefix are returned.
+func (s *AuthService) UserPermissions(scope tenantScope, user, objectPrefix string) ([]UserPermission, error) {
+	user = scope.qualify(user)
+	if objectPrefix != "" {
+		objectPrefix = scope.qualify(objectPrefix)
+	}
+
+	subjects := []string{user}
+	roles, err := s.rbacEnforcer.GetImplicitRolesForUser(user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve roles: %v", err)
+	}
+	subjects = append(subjects, roles...)
+	if s.rbacGroupBindings {
+		_, groups := s.relationshipGraph.GroupsForSubject(user, s.currentSettings().maxDepthLimit)
+		for _, group := range groups {
+			groupRoles, err := s.rbacEnforcer.GetImplicitRolesForUser(group)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve roles of group %s: %v", group, err)
+			}
+			subjects = append(subjects, group)
+			subjects = append(subjects, groupRoles...)
+		}
+	}
+
+	// Collect the allow rules of every subject as candidate permissions
+	candidates := make(map[[2]string]*UserPermission)
+	seen := make(map[string]bool)
+	for _, subject := range subjects {
+		if seen[subject] {
+			continue
+		}
+		seen[subject] = true
+
+		rules, err := s.rbacEnforcer.GetFilteredPolicy(0, subject)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read RBAC policies: %v", err)
+		}
+		for _, rule := range rules {
+			if len(rule) < 3 || ruleEffect(rule) != effectAllow {
+				continue
+			}
+			members, err := s.ResourceGroupMembers(rule[1])
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve members of resource group %s: %v", rule[1], err)
+			}
+			for _, object := range append([]string{rule[1]}, members...) {
+				if !strings.HasPrefix(object, objectPrefix) || !scope.owns(object) {
+					continue
+				}
+				key := [2]string{object, rule[2]}
+				if candidates[key] == nil {
+					candidates[key] = &UserPermission{Object: scope.local(object), Action: rule[2]}
+				}
+				candidates[key].Via = append(candidates[key].Via, scope.local(subject))
+			}
+		}
+	}
+
+	permissions := make([]UserPermission, 0, len(candidates))
+	for key, permission := range candidates {
+		allowed, err := s.EnforceInTenant(scope, ModelRBAC, user, key[0], key[1], nil, freshnessDefault)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			permissions = append(permissions, *permission)
+		}
+	}
+	sort.Slice(permissions, func(i, j int) bool {
+		if permissions[i].Object != permissions[j].Object {
+			return permissions[i].Object < permissions[j].Object
+		}
+		return permissions[i].Action < permissions[j].Action
+	})
+	return permissions, nil
+}
+
+// getUserPermissionsHandler lists the effective RBAC permissions of a user, optionally
+// limited to objects with a prefix
+func (s *AuthService) getUserPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+	prefix := r.URL.Query().Get("prefix")
+
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	permissions, err := s.UserPermissions(scope, userID, prefix)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Permission retrieval error: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"user":        userID,
+		"prefix":      prefix,
+		"permissions": permissions,
+		"count":       len(permissions),
+		"model":       "rbac",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
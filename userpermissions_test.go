@@ -0,0 +1,74 @@
+// Multi-Model Authorization Microservice - Effective User Permissions Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUserPermissions_FlattenedRoles(t *testing.T) {
+	service := setupTestService(t)
+	service.rbacGroupBindings = true
+	addRule(service.rbacEnforcer, "viewer", "/billing/invoices", "read", effectAllow)
+	addRule(service.rbacEnforcer, "editor", "/billing/invoices", "write", effectAllow)
+	addRule(service.rbacEnforcer, "editor", "/billing/refunds", "write", effectAllow)
+	addRule(service.rbacEnforcer, "editor", "/hr/reports", "read", effectAllow)
+	addRule(service.rbacEnforcer, "contractor", "/billing/refunds", "write", effectDeny)
+	addRule(service.rbacEnforcer, "deployer", "/deploy/prod", "run", effectAllow)
+	service.rbacEnforcer.AddGroupingPolicy("editor", "viewer")
+	service.rbacEnforcer.AddGroupingPolicy("alice", "editor")
+	service.rbacEnforcer.AddGroupingPolicy("alice", "contractor")
+	service.rbacEnforcer.AddGroupingPolicy("engineering", "deployer")
+	service.relationshipGraph.AddRelationship("alice", "member", "engineering")
+
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/users/{userId}/permissions", service.getUserPermissionsHandler).Methods("GET")
+	list := func(url string) []string {
+		req, _ := http.NewRequest("GET", url, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", rr.Code)
+		}
+		var response struct {
+			Permissions []UserPermission `json:"permissions"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		var listed []string
+		for _, p := range response.Permissions {
+			listed = append(listed, p.Object+" "+p.Action+" via "+strings.Join(p.Via, ","))
+		}
+		return listed
+	}
+
+	// Inherited and group-bound roles are expanded; the denied refund permission is left out
+	expected := []string{
+		"/billing/invoices read via viewer",
+		"/billing/invoices write via editor",
+		"/deploy/prod run via deployer",
+		"/hr/reports read via editor",
+	}
+	if got := list("/api/v1/users/alice/permissions"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected permissions:\n%s", strings.Join(got, "\n"))
+	}
+	if got := list("/api/v1/users/alice/permissions?prefix=/billing/"); len(got) != 2 {
+		t.Errorf("Expected the prefix to filter objects, got %v", got)
+	}
+	if got := list("/api/v1/users/mallory/permissions"); len(got) != 0 {
+		t.Errorf("Expected no permissions without roles, got %v", got)
+	}
+
+	// Every listed permission agrees with an authorization check
+	permissions, _ := service.UserPermissions(tenantScope{}, "alice", "")
+	for _, p := range permissions {
+		if allowed, _ := service.Enforce(ModelRBAC, "alice", p.Object, p.Action, nil); !allowed {
+			t.Errorf("Listed permission %s %s is not allowed", p.Object, p.Action)
+		}
+	}
+}
@@ -0,0 +1,499 @@
+// Multi-Model Authorization Microservice - Request Validation
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// maxIdentifierLength bounds subjects, objects, actions, roles, relationships and IDs
+	maxIdentifierLength = 256
+	// maxAttributeNameLength bounds attribute names and labels
+	maxAttributeNameLength = 128
+	// maxAttributeValueLength bounds attribute values and free text such as descriptions
+	maxAttributeValueLength = 4096
+	// maxAttributesPerRequest bounds the attributes set or passed by one request
+	maxAttributesPerRequest = 256
+	// maxPathVariableLength bounds path variables, which may join several identifiers as the
+	// "subject:object:action" policy IDs do
+	maxPathVariableLength = 3*maxIdentifierLength + 2
+)
+
+// FieldError describes why a field of a request is invalid
+type FieldError struct {
+	Field   string `json:"field"` // JSON path of the field, e.g. "policies[2].subject"
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a request
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + " " + field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// writeValidationError answers a request failing validation. Field errors are listed in the
+// details of a "validation_failed" envelope; other errors are plain bad requests.
+func writeValidationError(w http.ResponseWriter, err error) {
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeErrorResponse(w, http.StatusBadRequest, ErrorResponse{
+		Code:    "validation_failed",
+		Message: validationErr.Error(),
+		Details: validationErr.Fields,
+	})
+}
+
+// validator collects the field errors of a request
+type validator struct {
+	fields []FieldError
+}
+
+// fail records an invalid field
+func (v *validator) fail(field, format string, args ...interface{}) {
+	v.fields = append(v.fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// check records err, when not nil, against field
+func (v *validator) check(field string, err error) {
+	if err != nil {
+		v.fail(field, "%v", err)
+	}
+}
+
+// identifier checks a required name: present, bounded and free of control characters and
+// surrounding whitespace
+func (v *validator) identifier(field, value string) {
+	if value == "" {
+		v.fail(field, "is required")
+		return
+	}
+	v.optionalIdentifier(field, value)
+}
+
+// optionalIdentifier checks a name that may be omitted
+func (v *validator) optionalIdentifier(field, value string) {
+	v.boundedName(field, value, maxIdentifierLength)
+}
+
+// boundedName checks a name of at most maxLength bytes
+func (v *validator) boundedName(field, value string, maxLength int) {
+	switch {
+	case value == "":
+	case len(value) > maxLength:
+		v.fail(field, "must be at most %d bytes", maxLength)
+	case !utf8.ValidString(value) || strings.IndexFunc(value, unicode.IsControl) >= 0:
+		v.fail(field, "must not contain control characters")
+	case strings.TrimSpace(value) != value:
+		v.fail(field, "must not start or end with whitespace")
+	}
+}
+
+// text checks free text such as descriptions and reasons
+func (v *validator) text(field, value string) {
+	if len(value) > maxAttributeValueLength {
+		v.fail(field, "must be at most %d bytes", maxAttributeValueLength)
+	}
+}
+
+// oneOf checks an optional value against its allowed values
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	if value == "" {
+		return
+	}
+	for _, candidate := range allowed {
+		if value == candidate {
+			return
+		}
+	}
+	v.fail(field, "must be one of %s", strings.Join(allowed, ", "))
+}
+
+// model checks an optional access control model
+func (v *validator) model(field string, model AccessControlModel) {
+	v.oneOf(field, string(model), string(ModelACL), string(ModelRBAC), string(ModelABAC), string(ModelReBAC))
+}
+
+// attributeName checks an attribute name or label: present, bounded and without whitespace
+// or control characters
+func (v *validator) attributeName(field, name string) {
+	switch {
+	case name == "":
+		v.fail(field, "must not be empty")
+	case len(name) > maxAttributeNameLength:
+		v.fail(field, "must be at most %d bytes", maxAttributeNameLength)
+	case !utf8.ValidString(name) || strings.IndexFunc(name, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) >= 0:
+		v.fail(field, "must not contain whitespace or control characters")
+	}
+}
+
+// attributes checks the names and values of an attribute map, in name order
+func (v *validator) attributes(field string, attributes map[string]string) {
+	if len(attributes) > maxAttributesPerRequest {
+		v.fail(field, "must hold at most %d attributes", maxAttributesPerRequest)
+		return
+	}
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v.attributeName(field+"."+name, name)
+		v.text(field+"."+name, attributes[name])
+	}
+}
+
+// valueFrom checks an optional "<type>.<field>" reference to the attribute a condition
+// compares with
+func (v *validator) valueFrom(field, reference string) {
+	if reference == "" {
+		return
+	}
+	if _, ok := attributeReference(reference); !ok {
+		v.fail(field, "must reference an attribute as '<type>.<field>', e.g. 'user.ward'")
+		return
+	}
+	v.attributeName(field, reference)
+}
+
+// labels checks the labels attached to a resource; blank labels are ignored when stored
+func (v *validator) labels(field string, labels []string) {
+	if len(labels) > maxAttributesPerRequest {
+		v.fail(field, "must hold at most %d labels", maxAttributesPerRequest)
+		return
+	}
+	for i, label := range labels {
+		if label = strings.TrimSpace(label); label != "" {
+			v.attributeName(fmt.Sprintf("%s[%d]", field, i), label)
+		}
+	}
+}
+
+// obligations checks the obligations or advice of a policy: each needs an ID, and its
+// attributes are checked like request attributes
+func (v *validator) obligations(field string, obligations []Obligation) {
+	if len(obligations) > maxAttributesPerRequest {
+		v.fail(field, "must hold at most %d entries", maxAttributesPerRequest)
+		return
+	}
+	for i, obligation := range obligations {
+		entry := fmt.Sprintf("%s[%d]", field, i)
+		v.identifier(entry+".id", obligation.ID)
+		v.attributes(entry+".attributes", obligation.Attributes)
+	}
+}
+
+// err returns the collected field errors, or nil when the request is valid
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
+
+// validate checks an authorization request
+func (req *EnforceRequest) validate() error {
+	var v validator
+	v.model("model", req.Model)
+	v.identifier("subject", req.Subject)
+	v.identifier("object", req.Object)
+	v.identifier("action", req.Action)
+	v.attributes("attributes", req.Attributes)
+	if !validFreshness(req.Freshness) {
+		v.fail("freshness", "must be default or %s", freshnessStrong)
+	}
+	v.optionalIdentifier("tenant", req.Tenant)
+	if len(req.ContextualTuples) > 0 && req.Model != ModelReBAC && req.Model != ModelRBAC && req.Model != "" {
+		v.fail("contextual_tuples", "are only supported for ReBAC and RBAC checks")
+	}
+	if len(req.ContextualTuples) > maxContextualTuples {
+		v.fail("contextual_tuples", "must hold at most %d tuples", maxContextualTuples)
+	} else {
+		for i, tuple := range req.ContextualTuples {
+			field := fmt.Sprintf("contextual_tuples[%d]", i)
+			v.identifier(field+".subject", tuple.Subject)
+			v.identifier(field+".relationship", tuple.Relationship)
+			v.identifier(field+".object", tuple.Object)
+		}
+	}
+	if req.AsOf != "" {
+		if _, err := parseAsOf(req.AsOf); err != nil {
+			v.check("as_of", err)
+		} else if req.Model == ModelABAC {
+			v.fail("as_of", "is not supported for ABAC checks, attributes are not versioned")
+		} else if len(req.ContextualTuples) > 0 || req.Explain || req.Freshness != "" || req.ConsistencyToken != "" {
+			v.fail("as_of", "cannot be combined with contextual_tuples, explain, freshness or consistency_token")
+		}
+	}
+	return v.err()
+}
+
+// validate checks a policy added or removed through the generic policy endpoints
+func (req *PolicyRequest) validate() error {
+	var v validator
+	v.model("model", req.Model)
+	v.identifier("subject", req.Subject)
+	v.identifier("object", req.Object)
+	v.identifier("action", req.Action)
+	v.oneOf("effect", req.Effect, effectAllow, effectDeny)
+	v.labels("labels", req.Labels)
+	if req.Condition != nil && req.Model != "" && req.Model != ModelRBAC {
+		v.fail("condition", "is only supported for RBAC policies")
+	} else if req.Condition != nil {
+		v.check("condition", req.Condition.validate(1))
+		i := 0
+		req.Condition.walk(func(condition *PolicyCondition) {
+			field := fmt.Sprintf("condition.conditions[%d]", i)
+			v.attributeName(field+".field", condition.Field)
+			v.text(field+".value", condition.Value)
+			v.valueFrom(field+".value_from", condition.ValueFrom)
+			i++
+		})
+	}
+	return v.err()
+}
+
+// validate checks a role assignment
+func (req *RoleRequest) validate() error {
+	var v validator
+	v.identifier("user", req.User)
+	v.identifier("role", req.Role)
+	return v.err()
+}
+
+// validate checks a relationship tuple
+func (req *RelationshipRequest) validate() error {
+	var v validator
+	v.identifier("subject", req.Subject)
+	v.identifier("relationship", req.Relationship)
+	v.identifier("object", req.Object)
+	return v.err()
+}
+
+// validate checks a relationship tuple to add along with its labels, expiry and caveat
+func (req *AddRelationshipRequest) validate() error {
+	var v validator
+	v.identifier("subject", req.Subject)
+	v.identifier("relationship", req.Relationship)
+	v.identifier("object", req.Object)
+	v.labels("labels", req.Labels)
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		v.fail("expires_at", "must be in the future")
+	}
+	if req.Caveat != nil {
+		v.check("caveat", req.Caveat.validate())
+	}
+	v.optionalIdentifier("source", req.Source)
+	v.text("note", req.Note)
+	return v.err()
+}
+
+// validate checks a role assigned to a user
+func (req *UserRoleRequest) validate() error {
+	var v validator
+	v.identifier("role", req.Role)
+	v.labels("labels", req.Labels)
+	return v.err()
+}
+
+// validate checks the mutations of a transaction and the fields each operation uses
+func (req *TransactionRequest) validate() error {
+	var v validator
+	switch {
+	case len(req.Mutations) == 0:
+		v.fail("mutations", "is required")
+	case len(req.Mutations) > maxTransactionMutations:
+		v.fail("mutations", "must hold at most %d mutations", maxTransactionMutations)
+		return v.err()
+	}
+	for i, mutation := range req.Mutations {
+		field := fmt.Sprintf("mutations[%d]", i)
+		switch mutation.Op {
+		case txOpAddRole:
+			v.identifier(field+".user", mutation.User)
+			v.identifier(field+".role", mutation.Role)
+		case txOpAddRelationship:
+			v.identifier(field+".subject", mutation.Subject)
+			v.identifier(field+".relationship", mutation.Relationship)
+			v.identifier(field+".object", mutation.Object)
+			if mutation.ExpiresAt != nil && !mutation.ExpiresAt.After(time.Now()) {
+				v.fail(field+".expires_at", "must be in the future")
+			}
+			if mutation.Caveat != nil {
+				v.check(field+".caveat", mutation.Caveat.validate())
+			}
+			v.optionalIdentifier(field+".source", mutation.Source)
+			v.text(field+".note", mutation.Note)
+		case txOpSetAttribute:
+			v.identifier(field+".user", mutation.User)
+			v.attributeName(field+".attribute", mutation.Attribute)
+			v.text(field+".value", mutation.Value)
+		case "":
+			v.fail(field+".op", "is required")
+		default:
+			v.fail(field+".op", "must be one of %s", strings.Join([]string{txOpAddRole, txOpAddRelationship, txOpSetAttribute}, ", "))
+		}
+	}
+	return v.err()
+}
+
+// validate checks the attributes set on a user
+func (req *UserAttributesRequest) validate() error {
+	var v validator
+	if len(req.Attributes) == 0 {
+		v.fail("attributes", "is required")
+	}
+	v.attributes("attributes", req.Attributes)
+	v.attributes("types", req.Types)
+	return v.err()
+}
+
+// validate checks the attributes set on an object
+func (req *ObjectAttributesRequest) validate() error {
+	var v validator
+	if len(req.Attributes) == 0 {
+		v.fail("attributes", "is required")
+	}
+	v.identifier("object", req.Object)
+	v.attributes("attributes", req.Attributes)
+	v.attributes("types", req.Types)
+	return v.err()
+}
+
+// validate checks a relationship permission check
+func (req *PermissionCheckRequest) validate() error {
+	var v validator
+	v.identifier("relationship", req.Relationship)
+	v.identifier("permission", req.Permission)
+	return v.err()
+}
+
+// validate checks an object added to a resource group
+func (req *ResourceGroupRequest) validate() error {
+	var v validator
+	v.identifier("group", req.Group)
+	v.labels("labels", req.Labels)
+	return v.err()
+}
+
+// validate checks a parent role added to a role
+func (req *RoleParentRequest) validate() error {
+	var v validator
+	v.identifier("parent", req.Parent)
+	v.labels("labels", req.Labels)
+	return v.err()
+}
+
+// validate checks a tenant to create; the tenant name format is checked on creation
+func (req *CreateTenantRequest) validate() error {
+	var v validator
+	v.identifier("name", req.Name)
+	v.optionalIdentifier("template", req.Template)
+	return v.err()
+}
+
+// validate checks the reason given for rejecting a change
+func (req *RejectChangeRequest) validate() error {
+	var v validator
+	v.text("reason", req.Reason)
+	return v.err()
+}
+
+// validate checks a trace session to start
+func (req *TraceRequest) validate() error {
+	var v validator
+	if req.Subject == "" && req.Object == "" {
+		v.fail("subject", "or object is required")
+	}
+	v.optionalIdentifier("subject", req.Subject)
+	v.optionalIdentifier("object", req.Object)
+	return v.err()
+}
+
+// validate checks an authorization request made with an end-user token
+func (req *TokenAuthorizationRequest) validate() error {
+	var v validator
+	if req.Token == "" {
+		v.fail("token", "is required")
+	}
+	v.model("model", req.Model)
+	v.identifier("object", req.Object)
+	v.identifier("action", req.Action)
+	v.attributes("attributes", req.Attributes)
+	v.optionalIdentifier("tenant", req.Tenant)
+	return v.err()
+}
+
+// validate checks the fields of an ABAC policy; conditions are checked separately against
+// their operators and the attribute schema
+func (policy *ABACPolicy) validate() error {
+	var v validator
+	v.identifier("id", policy.ID)
+	v.identifier("name", policy.Name)
+	v.text("description", policy.Description)
+	if policy.Effect == "" {
+		v.fail("effect", "is required")
+	}
+	v.oneOf("effect", policy.Effect, effectAllow, effectDeny)
+	v.check("actions", validatePolicyActions(policy.Actions))
+	v.text("expression", policy.Expression)
+	for i, condition := range policy.Conditions {
+		field := fmt.Sprintf("conditions[%d]", i)
+		v.attributeName(field+".field", condition.Field)
+		v.text(field+".value", condition.Value)
+		v.valueFrom(field+".value_from", condition.ValueFrom)
+	}
+	v.obligations("obligations", policy.Obligations)
+	v.obligations("advice", policy.Advice)
+	v.labels("labels", policy.Labels)
+	v.optionalIdentifier("tenant", policy.Tenant)
+	return v.err()
+}
+
+// validatePathMiddleware rejects requests whose path variables, such as user and object IDs,
+// would not be accepted as identifiers in a request body
+func validatePathMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		var v validator
+		for _, name := range names {
+			// ext_authz forwards the checked request's path, which is not an identifier
+			if name != "path" {
+				v.boundedName(name, vars[name], maxPathVariableLength)
+			}
+		}
+		if err := v.err(); err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,80 @@
+// Multi-Model Authorization Microservice - Request Validation Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestValidation_ErrorEnvelope(t *testing.T) {
+	service := setupTestService(t)
+	router := mux.NewRouter()
+	service.registerAdminRoutes(router.PathPrefix("/api/v1").Subrouter())
+	router.Use(validatePathMiddleware)
+	router.NotFoundHandler = routeNotFoundHandler(router)
+	router.MethodNotAllowedHandler = http.HandlerFunc(methodNotAllowedHandler)
+
+	send := func(method, path, body string) (*httptest.ResponseRecorder, ErrorResponse) {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var response ErrorResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		return rr, response
+	}
+
+	// Every invalid field is reported
+	rr, response := send("POST", "/api/v1/acl/policies", `{"subject": " alice", "object": "`+strings.Repeat("x", maxIdentifierLength+1)+`", "effect": "maybe", "labels": ["app billing"]}`)
+	if rr.Code != http.StatusBadRequest || response.Code != "validation_failed" || rr.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected a validation error envelope, got %d %s", rr.Code, rr.Body.String())
+	}
+	var fields []string
+	for _, detail := range response.Details.([]interface{}) {
+		fields = append(fields, detail.(map[string]interface{})["field"].(string))
+	}
+	if strings.Join(fields, ",") != "subject,object,action,effect,labels[0]" {
+		t.Errorf("Unexpected invalid fields: %v (%s)", fields, response.Message)
+	}
+
+	// Errors raised by handlers use the same envelope
+	for _, check := range []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{"POST", "/api/v1/acl/policies", `{"subject": `, http.StatusBadRequest, "bad_request"},
+		{"POST", "/api/v1/rbac/roles/editor/parents", `{"parent": "\u0007bell"}`, http.StatusBadRequest, "validation_failed"},
+		{"DELETE", "/api/v1/users/alice/roles/reader", "", http.StatusNotFound, "not_found"},
+		{"GET", "/api/v1/users/" + strings.Repeat("u", maxPathVariableLength+1) + "/roles", "", http.StatusBadRequest, "validation_failed"},
+		{"GET", "/api/v1/no-such-endpoint", "", http.StatusNotFound, "not_found"},
+		{"PATCH", "/api/v1/acl/policies", "", http.StatusMethodNotAllowed, "method_not_allowed"},
+	} {
+		rr, response := send(check.method, check.path, check.body)
+		if rr.Code != check.status || response.Code != check.code || response.Message == "" {
+			t.Errorf("%s %s: expected %d %s, got %d %s", check.method, check.path, check.status, check.code, rr.Code, rr.Body.String())
+		}
+	}
+	if rr, _ := send("PATCH", "/api/v1/acl/policies", ""); rr.Header().Get("Allow") != "GET, POST, DELETE" {
+		t.Errorf("Expected the allowed methods to be listed, got %q", rr.Header().Get("Allow"))
+	}
+
+	// Invalid items of a batch fail on their own
+	rr, _ = send("POST", "/api/v1/acl/policies/bulk", `{"policies": [{"subject": "alice", "object": "doc1", "action": "read"}, {"subject": "bob", "object": "doc1", "action": "re\nad"}]}`)
+	var bulk struct {
+		Results []BulkPolicyResult `json:"results"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &bulk)
+	if rr.Code != http.StatusOK || len(bulk.Results) != 2 || bulk.Results[0].Status != bulkStatusCreated || !strings.Contains(bulk.Results[1].Error, "action must not contain control characters") {
+		t.Errorf("Expected only the second policy to fail, got %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,107 @@
+// Multi-Model Authorization Microservice - Attribute Visibility
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// attributeResourcePrefix prefixes the resource checked for an attribute category
+	attributeResourcePrefix = "attributes/"
+
+	// attributeReadAction is the action checked to reveal an attribute category
+	attributeReadAction = "read"
+)
+
+// attributeCategory returns the category of an attribute name: the part before the
+// first ".", or the whole name for attributes without one (e.g. "finance.salary" is
+// in category "finance", "department" in category "department")
+func attributeCategory(name string) string {
+	if idx := strings.Index(name, "."); idx > 0 {
+		return name[:idx]
+	}
+	return name
+}
+
+// VisibleObjectAttributes returns the attributes of object that subject may see, along with
+// the categories that were redacted. Each category is revealed when subject may perform
+// "read" on the resource "attributes/<category>" under the given model.
+func (s *AuthService) VisibleObjectAttributes(model AccessControlModel, subject, object string) (map[string]string, []string, error) {
+	attributes := s.getObjectAttributes(object)
+
+	visible := make(map[string]string)
+	decisions := make(map[string]bool)
+	for name, value := range attributes {
+		category := attributeCategory(name)
+		allowed, checked := decisions[category]
+		if !checked {
+			var err error
+			allowed, err = s.Enforce(model, subject, attributeResourcePrefix+category, attributeReadAction, nil)
+			if err != nil {
+				return nil, nil, err
+			}
+			decisions[category] = allowed
+		}
+		if allowed {
+			visible[name] = value
+		}
+	}
+
+	redacted := make([]string, 0)
+	for category, allowed := range decisions {
+		if !allowed {
+			redacted = append(redacted, category)
+		}
+	}
+	sort.Strings(redacted)
+
+	return visible, redacted, nil
+}
+
+// getVisibleObjectAttributesHandler returns the object attributes a subject is allowed to see
+func (s *AuthService) getVisibleObjectAttributesHandler(w http.ResponseWriter, r *http.Request) {
+	objectId := mux.Vars(r)["objectId"]
+	subject := r.URL.Query().Get("subject")
+	if subject == "" {
+		writeJSONError(w, http.StatusBadRequest, "subject parameter is required")
+		return
+	}
+
+	model := AccessControlModel(r.URL.Query().Get("model"))
+	switch model {
+	case "":
+		model = ModelRBAC
+	case ModelACL, ModelRBAC, ModelABAC, ModelReBAC:
+	default:
+		writeJSONError(w, http.StatusBadRequest, "Invalid model specified")
+		return
+	}
+
+	visible, redacted, err := s.VisibleObjectAttributes(model, subject, objectId)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Visibility check error: %v", err))
+		return
+	}
+
+	response := map[string]interface{}{
+		"object":            objectId,
+		"subject":           subject,
+		"attributes":        visible,
+		"redacted":          redacted,
+		"count":             len(visible),
+		"enforcement_model": model,
+		"model":             "abac",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
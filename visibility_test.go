@@ -0,0 +1,80 @@
+// Multi-Model Authorization Microservice - Attribute Visibility Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVisibility_RedactsCategoriesByRole(t *testing.T) {
+	service := setupTestService(t)
+	router := setupTestRouter(service)
+	router.HandleFunc("/api/v1/objects/{objectId}/attributes/visible", service.getVisibleObjectAttributesHandler).Methods("GET")
+
+	service.saveObjectAttribute("employee42", "department", "engineering")
+	service.saveObjectAttribute("employee42", "finance.salary", "120000")
+	service.saveObjectAttribute("employee42", "finance.bonus", "5000")
+	service.saveObjectAttribute("employee42", "medical.notes", "redacted")
+
+	service.rbacEnforcer.AddPolicy("staff", "attributes/department", "read", "allow")
+	service.rbacEnforcer.AddPolicy("payroll", "attributes/finance", "read", "allow")
+	service.rbacEnforcer.AddGroupingPolicy("alice", "staff")
+	service.rbacEnforcer.AddGroupingPolicy("bob", "staff")
+	service.rbacEnforcer.AddGroupingPolicy("bob", "payroll")
+
+	visible, redacted, err := service.VisibleObjectAttributes(ModelRBAC, "alice", "employee42")
+	if err != nil {
+		t.Fatalf("Visibility check failed: %v", err)
+	}
+	if len(visible) != 1 || visible["department"] != "engineering" {
+		t.Errorf("Expected alice to see only the department, got %v", visible)
+	}
+	if len(redacted) != 2 || redacted[0] != "finance" || redacted[1] != "medical" {
+		t.Errorf("Unexpected redacted categories: %v", redacted)
+	}
+
+	req, _ := http.NewRequest("GET", "/api/v1/objects/employee42/attributes/visible?subject=bob", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	var response struct {
+		Attributes map[string]string `json:"attributes"`
+		Redacted   []string          `json:"redacted"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if rr.Code != http.StatusOK || len(response.Attributes) != 3 || response.Attributes["finance.bonus"] != "5000" || len(response.Redacted) != 1 {
+		t.Errorf("Unexpected visibility for bob: %d %s", rr.Code, rr.Body.String())
+	}
+
+	for _, url := range []string{
+		"/api/v1/objects/employee42/attributes/visible",
+		"/api/v1/objects/employee42/attributes/visible?subject=bob&model=unknown",
+	} {
+		req, _ = http.NewRequest("GET", url, nil)
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", url, rr.Code)
+		}
+	}
+}
+
+func TestVisibility_ReBACCategories(t *testing.T) {
+	service := setupTestService(t)
+
+	service.saveObjectAttribute("invoice1", "billing.amount", "100")
+	service.relationshipGraph.AddRelationship("finance_team", "viewer", "attributes/billing")
+	service.relationshipGraph.AddRelationship("carol", "member", "finance_team")
+
+	visible, _, err := service.VisibleObjectAttributes(ModelReBAC, "carol", "invoice1")
+	if err != nil || visible["billing.amount"] != "100" {
+		t.Errorf("Expected group member to see billing attributes, got %v (%v)", visible, err)
+	}
+	if visible, _, _ := service.VisibleObjectAttributes(ModelReBAC, "dave", "invoice1"); len(visible) != 0 {
+		t.Errorf("Expected outsider to see nothing, got %v", visible)
+	}
+}
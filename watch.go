@@ -0,0 +1,251 @@
+// Multi-Model Authorization Microservice - Relationship Watch API
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultWatchRetention is how long relationship changes stay in the change log
+	defaultWatchRetention = 24 * time.Hour
+
+	// watchHeartbeatInterval is how often an idle watch stream sends a keep-alive comment
+	watchHeartbeatInterval = 15 * time.Second
+
+	// watchBatchSize bounds the changes read from the change log at once
+	watchBatchSize = 500
+)
+
+// RelationshipChange is an entry of the relationship change log. Its ID is the sequence
+// number of the change, which increases across every instance sharing the database.
+type RelationshipChange struct {
+	ID           uint64              `json:"sequence" gorm:"primaryKey"`
+	Action       string              `json:"action"` // "added" or "removed"
+	Subject      string              `json:"subject" gorm:"index"`
+	Relationship string              `json:"relationship"`
+	Object       string              `json:"object" gorm:"index"`
+	ExpiresAt    *time.Time          `json:"expires_at,omitempty"`
+	Caveat       *RelationshipCaveat `json:"caveat,omitempty" gorm:"serializer:json"`
+	Actor        string              `json:"actor,omitempty"`
+	CreatedAt    time.Time           `json:"time" gorm:"index"`
+}
+
+// relationshipWatchers wakes the open watch streams of this instance when the change log
+// grows. The zero value is ready to use.
+type relationshipWatchers struct {
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+}
+
+// subscribe returns a channel signalled after changes were logged
+func (rw *relationshipWatchers) subscribe() chan struct{} {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.subscribers == nil {
+		rw.subscribers = make(map[chan struct{}]struct{})
+	}
+	wake := make(chan struct{}, 1)
+	rw.subscribers[wake] = struct{}{}
+	return wake
+}
+
+// unsubscribe stops signalling a channel
+func (rw *relationshipWatchers) unsubscribe(wake chan struct{}) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	delete(rw.subscribers, wake)
+}
+
+// notify signals every subscriber; a subscriber that has not caught up yet is already signalled
+func (rw *relationshipWatchers) notify() {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	for wake := range rw.subscribers {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// logRelationshipChange appends a relationship change to the change log and wakes the watch
+// streams. The change has already been applied, so a failure is only logged.
+func (s *AuthService) logRelationshipChange(action string, data interface{}, actor string) {
+	var tuple LabeledRelationship
+	encoded, err := json.Marshal(data)
+	if err == nil {
+		err = json.Unmarshal(encoded, &tuple)
+	}
+	if err != nil {
+		log.Printf("Failed to log relationship change: %v", err)
+		return
+	}
+
+	change := RelationshipChange{
+		Action:       action,
+		Subject:      tuple.Subject,
+		Relationship: tuple.Relationship.Relationship,
+		Object:       tuple.Object,
+		ExpiresAt:    tuple.ExpiresAt,
+		Caveat:       tuple.Caveat,
+		Actor:        actor,
+	}
+	if err := s.db.Create(&change).Error; err != nil {
+		log.Printf("Failed to log relationship change: %v", err)
+		return
+	}
+	s.watchers.notify()
+}
+
+// trimRelationshipChanges deletes changes older than the retention period. The newest change
+// is always kept, so sequence numbers are never reused.
+func (s *AuthService) trimRelationshipChanges(now time.Time) (int64, error) {
+	retention := s.watchRetention
+	if retention <= 0 {
+		retention = defaultWatchRetention
+	}
+	result := s.db.Where("created_at < ? AND id < (SELECT MAX(id) FROM relationship_changes)", now.Add(-retention)).
+		Delete(&RelationshipChange{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to trim relationship change log: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// watchRetentionFromEnv reads REBAC_WATCH_RETENTION, e.g. "24h" or "7d"
+func watchRetentionFromEnv() (time.Duration, error) {
+	retentionStr := os.Getenv("REBAC_WATCH_RETENTION")
+	if retentionStr == "" {
+		return defaultWatchRetention, nil
+	}
+	retention, err := parseRetentionPeriod(retentionStr)
+	if err != nil || retention <= 0 {
+		return 0, fmt.Errorf("invalid REBAC_WATCH_RETENTION value: %s", retentionStr)
+	}
+	return retention, nil
+}
+
+// changeLogBounds returns the sequence numbers of the oldest and newest logged changes,
+// both zero when the log is empty
+func changeLogBounds(db *gorm.DB) (oldest, newest uint64, err error) {
+	var bounds struct {
+		Oldest uint64
+		Newest uint64
+	}
+	err = db.Model(&RelationshipChange{}).Select("COALESCE(MIN(id), 0) AS oldest, COALESCE(MAX(id), 0) AS newest").Scan(&bounds).Error
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read relationship change log: %v", err)
+	}
+	return bounds.Oldest, bounds.Newest, nil
+}
+
+// watchRelationshipsHandler streams relationship changes as server-sent events, each with
+// its sequence number as the event ID. Without ?since= (or a Last-Event-ID header from a
+// reconnecting client) the stream starts with the next change. Changes may be filtered by
+// subject and object.
+func (s *AuthService) watchRelationshipsHandler(w http.ResponseWriter, r *http.Request) {
+	scope, ok := s.requestTenant(w, r, "")
+	if !ok {
+		return
+	}
+
+	sinceStr := r.URL.Query().Get("since")
+	if sinceStr == "" {
+		sinceStr = r.Header.Get("Last-Event-ID")
+	}
+	oldest, newest, err := changeLogBounds(s.db)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	since := newest
+	if sinceStr != "" {
+		since, err = strconv.ParseUint(sinceStr, 10, 64)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "since must be a sequence number")
+			return
+		}
+		// The changes after since were trimmed, or the log was reset
+		if since > newest || (oldest > 0 && oldest > since+1) {
+			writeJSONError(w, http.StatusGone, fmt.Sprintf("Changes after sequence %d are no longer available; list the relationships again and watch from sequence %d", since, newest))
+			return
+		}
+	}
+
+	query := s.db.Model(&RelationshipChange{})
+	if subject := r.URL.Query().Get("subject"); subject != "" {
+		query = query.Where("subject = ?", scope.qualify(subject))
+	}
+	if object := r.URL.Query().Get("object"); object != "" {
+		query = query.Where("object = ?", scope.qualify(object))
+	}
+
+	// Streams outlive the server's write timeout
+	controller := http.NewResponseController(w)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to start watch: %v", err))
+		return
+	}
+
+	wake := s.watchers.subscribe()
+	defer s.watchers.unsubscribe(wake)
+	serviceMetrics.Inc("relationship_watch_streams_total")
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(watchHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		// Send every change logged since the last one sent
+		for {
+			var changes []RelationshipChange
+			if err := query.Session(&gorm.Session{}).Where("id > ?", since).Order("id").Limit(watchBatchSize).Find(&changes).Error; err != nil {
+				log.Printf("Relationship watch failed: %v", err)
+				return
+			}
+			for _, change := range changes {
+				since = change.ID
+				rel := Relationship{Subject: change.Subject, Relationship: change.Relationship, Object: change.Object}
+				if !scope.ownsRelationship(rel) {
+					continue
+				}
+				change.Subject, change.Object = scope.local(change.Subject), scope.local(change.Object)
+				data, _ := json.Marshal(change)
+				fmt.Fprintf(w, "id: %d\nevent: relationship.%s\ndata: %s\n\n", change.ID, change.Action, data)
+			}
+			if len(changes) < watchBatchSize {
+				break
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-wake:
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+	}
+}
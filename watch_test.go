@@ -0,0 +1,125 @@
+// Multi-Model Authorization Microservice - Relationship Watch API Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// readWatchEvent reads the next event of a watch stream, skipping keep-alives
+func readWatchEvent(t *testing.T, reader *bufio.Reader) (id, event string, change RelationshipChange) {
+	t.Helper()
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read watch stream: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &change); err != nil {
+				t.Fatalf("Failed to decode watch event: %v", err)
+			}
+		case line == "" && event != "":
+			return id, event, change
+		}
+	}
+}
+
+func TestWatch_ReplaysAndStreamsRelationshipChanges(t *testing.T) {
+	service := setupTestService(t)
+	router := mux.NewRouter()
+	service.registerAdminRoutes(router.PathPrefix("/api/v1").Subrouter())
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// Every connection to ":memory:" opens a separate empty database
+	sqlDB, _ := service.db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	addRelationship := func(subject, object string) {
+		body := `{"subject": "` + subject + `", "relationship": "viewer", "object": "` + object + `"}`
+		req, _ := http.NewRequest("POST", "/api/v1/relationships", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Failed to add relationship: %d %s", rr.Code, rr.Body.String())
+		}
+	}
+	addRelationship("alice", "doc1")
+	addRelationship("bob", "doc2")
+
+	resp, err := http.Get(server.URL + "/api/v1/relationships/watch?since=0&object=doc1")
+	if err != nil {
+		t.Fatalf("Failed to open watch stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Unexpected watch response: %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	reader := bufio.NewReader(resp.Body)
+
+	// The log is replayed from the start, without doc2
+	id, event, change := readWatchEvent(t, reader)
+	if id != "1" || event != "relationship.added" || change.Subject != "alice" || change.ID != 1 {
+		t.Errorf("Unexpected replayed event %s %s %+v", id, event, change)
+	}
+
+	// Later changes are streamed as they happen
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, event, change = readWatchEvent(t, reader)
+	}()
+	addRelationship("carol", "doc2")
+	req, _ := http.NewRequest("DELETE", "/api/v1/relationships/alice:viewer:doc1", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a streamed change")
+	}
+	if event != "relationship.removed" || change.Subject != "alice" || change.ID != 4 {
+		t.Errorf("Unexpected streamed event %s %+v", event, change)
+	}
+}
+
+func TestWatch_ExpiredSequenceIsGone(t *testing.T) {
+	service := setupTestService(t)
+	router := mux.NewRouter()
+	service.registerAdminRoutes(router.PathPrefix("/api/v1").Subrouter())
+
+	for _, subject := range []string{"alice", "bob"} {
+		service.logRelationshipChange("added", Relationship{Subject: subject, Relationship: "viewer", Object: "doc1"}, "")
+	}
+	service.db.Model(&RelationshipChange{}).Where("id = 1").Update("created_at", time.Now().Add(-48*time.Hour))
+	if trimmed, err := service.trimRelationshipChanges(time.Now()); err != nil || trimmed != 1 {
+		t.Fatalf("Expected the old change to be trimmed, got %d %v", trimmed, err)
+	}
+
+	for since, status := range map[string]int{"0": http.StatusGone, "7": http.StatusGone, "x": http.StatusBadRequest} {
+		req, _ := http.NewRequest("GET", "/api/v1/relationships/watch?since="+since, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != status {
+			t.Errorf("since=%s: expected %d, got %d", since, status, rr.Code)
+		}
+	}
+}
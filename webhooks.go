@@ -0,0 +1,378 @@
+// Multi-Model Authorization Microservice - Change Webhooks
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Change actions reported in webhook events
+const (
+	changeAdded   = "added"
+	changeRemoved = "removed"
+	changeUpdated = "updated"
+)
+
+const (
+	// webhookQueueSize bounds the events waiting for delivery; further events are dropped
+	webhookQueueSize = 1000
+
+	// webhookMaxAttempts is how often a delivery is tried before it is given up
+	webhookMaxAttempts = 3
+
+	// webhookSignatureHeader carries "sha256=<hex HMAC-SHA256 of the body keyed by the secret>"
+	webhookSignatureHeader = "X-Webhook-Signature"
+)
+
+// Webhook is a registered receiver of change events
+type Webhook struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`                        // Only returned when the webhook is created
+	Events    []string  `json:"events,omitempty" gorm:"serializer:json"` // Event types or kinds to receive (all when empty)
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// wants reports whether the webhook subscribed to an event, by its type (e.g. "acl.added")
+// or its kind (e.g. "acl")
+func (h *Webhook) wants(event *ChangeEvent) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, filter := range h.Events {
+		if filter == "*" || filter == event.Type || filter == event.Kind {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeEvent describes one change to authorization data. Kinds and keys are those of
+// export diffs, e.g. kind "acl" with key "alice:doc1:read".
+type ChangeEvent struct {
+	ID     string      `json:"id"`
+	Type   string      `json:"type"` // "<kind>.<action>"
+	Kind   string      `json:"kind"`
+	Action string      `json:"action"`
+	Key    string      `json:"key"`
+	Data   interface{} `json:"data,omitempty"`
+	Actor  string      `json:"actor,omitempty"`
+	Time   time.Time   `json:"time"`
+}
+
+// webhookDispatcher delivers change events to registered webhooks. Events are queued and
+// delivered in order by a single worker so publishing never blocks a write.
+type webhookDispatcher struct {
+	db      *gorm.DB
+	client  *http.Client
+	backoff time.Duration // Wait before the first retry, doubled for each further retry
+
+	mu    sync.RWMutex
+	hooks []Webhook
+
+	queue   chan *ChangeEvent
+	pending sync.WaitGroup
+}
+
+// newWebhookDispatcher loads the registered webhooks and starts the delivery worker
+func newWebhookDispatcher(db *gorm.DB) (*webhookDispatcher, error) {
+	wd := &webhookDispatcher{
+		db:      db,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		backoff: time.Second,
+		queue:   make(chan *ChangeEvent, webhookQueueSize),
+	}
+	if err := wd.reload(); err != nil {
+		return nil, err
+	}
+
+	go wd.run()
+	return wd, nil
+}
+
+// reload refreshes the in-memory webhook list from the database
+func (wd *webhookDispatcher) reload() error {
+	var hooks []Webhook
+	if err := wd.db.Order("created_at").Find(&hooks).Error; err != nil {
+		return fmt.Errorf("failed to load webhooks: %v", err)
+	}
+
+	wd.mu.Lock()
+	wd.hooks = hooks
+	wd.mu.Unlock()
+	return nil
+}
+
+// publish queues an event for delivery. It is a no-op while no webhooks are registered.
+func (wd *webhookDispatcher) publish(event *ChangeEvent) {
+	wd.mu.RLock()
+	registered := len(wd.hooks) > 0
+	wd.mu.RUnlock()
+	if !registered {
+		return
+	}
+
+	wd.pending.Add(1)
+	select {
+	case wd.queue <- event:
+	default:
+		wd.pending.Done()
+		serviceMetrics.Inc("webhook_events_dropped_total")
+		log.Printf("Webhook queue full, dropping %s event for %s", event.Type, event.Key)
+	}
+}
+
+// wait blocks until all queued events have been delivered or given up
+func (wd *webhookDispatcher) wait() {
+	wd.pending.Wait()
+}
+
+// run delivers queued events to every subscribed webhook
+func (wd *webhookDispatcher) run() {
+	for event := range wd.queue {
+		body, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("Failed to encode %s event: %v", event.Type, err)
+			wd.pending.Done()
+			continue
+		}
+
+		wd.mu.RLock()
+		hooks := wd.hooks
+		wd.mu.RUnlock()
+
+		for i := range hooks {
+			if hooks[i].wants(event) {
+				wd.deliver(&hooks[i], event, body)
+			}
+		}
+		wd.pending.Done()
+	}
+}
+
+// deliver posts a signed event to a webhook, retrying with exponential backoff until it
+// is acknowledged with a 2xx status
+func (wd *webhookDispatcher) deliver(hook *Webhook, event *ChangeEvent, body []byte) {
+	backoff := wd.backoff
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		err := wd.post(hook, event, body)
+		if err == nil {
+			serviceMetrics.Inc("webhook_deliveries_total")
+			return
+		}
+		if attempt == webhookMaxAttempts {
+			serviceMetrics.Inc("webhook_delivery_failures_total")
+			log.Printf("Failed to deliver %s event %s to webhook %s: %v", event.Type, event.ID, hook.ID, err)
+			return
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post makes a single delivery attempt
+func (wd *webhookDispatcher) post(hook *Webhook, event *ChangeEvent, body []byte) error {
+	req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", hook.ID)
+	req.Header.Set("X-Webhook-Event", event.Type)
+	req.Header.Set("X-Webhook-Delivery", event.ID)
+	req.Header.Set(webhookSignatureHeader, signWebhookBody(hook.Secret, body))
+
+	resp, err := wd.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// signWebhookBody computes the signature receivers use to verify an event came from this service
+func signWebhookBody(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookSecret generates a random signing secret
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// publishChange notifies webhooks of a change to a policy, role, attribute or relationship
+func (s *AuthService) publishChange(kind, action, key string, data interface{}, actor string) {
+	if versionedKind(kind) {
+		s.recordRevision(kind, action, key, data, actor)
+	}
+	if kind == changeKindRelationship {
+		s.logRelationshipChange(action, data, actor)
+	}
+	s.broadcastInvalidation(kind, action, key, data)
+	if s.webhooks == nil {
+		return
+	}
+	s.webhooks.publish(&ChangeEvent{
+		ID:     newRequestID(),
+		Type:   kind + "." + action,
+		Kind:   kind,
+		Action: action,
+		Key:    key,
+		Data:   data,
+		Actor:  actor,
+		Time:   time.Now(),
+	})
+}
+
+// ruleChangeKind returns the change kind of ACL or RBAC rules from their label kind
+func ruleChangeKind(labelKind string) string {
+	if labelKind == labelKindACL {
+		return changeKindACL
+	}
+	return changeKindRBACPolicy
+}
+
+// ruleChange is the event data of an ACL/RBAC rule; effect is empty for removals
+func ruleChange(subject, object, action, effect string) map[string]string {
+	change := map[string]string{"subject": subject, "object": object, "action": action}
+	if effect != "" {
+		change["effect"] = effect
+	}
+	return change
+}
+
+// roleChange is the event data of a role assignment
+func roleChange(user, role string) map[string]string {
+	return map[string]string{"user": user, "role": role}
+}
+
+// resourceGroupChange is the event data of an RBAC resource group membership
+func resourceGroupChange(object, group string) map[string]string {
+	return map[string]string{"object": object, "group": group}
+}
+
+// attributeChange is the event data of a user or object attribute; value is empty for removals
+func attributeChange(scope, id, attribute, value string) map[string]string {
+	change := map[string]string{scope: id, "attribute": attribute}
+	if value != "" {
+		change["value"] = value
+	}
+	return change
+}
+
+// relationshipChange is the event data of a relationship tuple
+func relationshipChange(subject, relationship, object string, expiresAt *time.Time) LabeledRelationship {
+	return LabeledRelationship{
+		Relationship: Relationship{Subject: subject, Relationship: relationship, Object: object},
+		ExpiresAt:    expiresAt,
+	}
+}
+
+// createWebhookHandler registers a webhook. The signing secret is generated unless given
+// and is only returned in this response.
+func (s *AuthService) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	var hook Webhook
+	if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid JSON format")
+		return
+	}
+
+	target, err := url.Parse(hook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		writeJSONError(w, http.StatusBadRequest, "url must be an absolute http or https URL")
+		return
+	}
+
+	if hook.Secret == "" {
+		if hook.Secret, err = newWebhookSecret(); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	hook.ID = newRequestID()
+	hook.CreatedAt = time.Now()
+
+	if err := s.db.Create(&hook).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save webhook: %v", err))
+		return
+	}
+	if err := s.webhooks.reload(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Webhook registered successfully",
+		"webhook": hook,
+	})
+}
+
+// getWebhooksHandler lists the registered webhooks without their secrets
+func (s *AuthService) getWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	var hooks []Webhook
+	if err := s.db.Order("created_at").Find(&hooks).Error; err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retrieve webhooks: %v", err))
+		return
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"webhooks": hooks,
+		"count":    len(hooks),
+	})
+}
+
+// deleteWebhookHandler unregisters a webhook
+func (s *AuthService) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	result := s.db.Delete(&Webhook{}, "id = ?", id)
+	if result.Error != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete webhook: %v", result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		writeJSONError(w, http.StatusNotFound, fmt.Sprintf("Webhook not found: %s", id))
+		return
+	}
+	if err := s.webhooks.reload(); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Webhook deleted successfully",
+		"id":      id,
+	})
+}
@@ -0,0 +1,182 @@
+// Multi-Model Authorization Microservice - Change Webhook Tests
+// Copyright (c) 2024 Multi-Model Authorization Microservice
+// Licensed under the MIT License. See LICENSE file for details.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver records the events posted to it, failing the first failures deliveries
+type webhookReceiver struct {
+	mu       sync.Mutex
+	events   []ChangeEvent
+	failures int
+	secret   string
+	t        *testing.T
+}
+
+func (wr *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	if wr.failures > 0 {
+		wr.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if wr.secret != "" && r.Header.Get(webhookSignatureHeader) != signWebhookBody(wr.secret, body) {
+		wr.t.Errorf("Invalid signature %q", r.Header.Get(webhookSignatureHeader))
+	}
+	var event ChangeEvent
+	json.Unmarshal(body, &event)
+	if r.Header.Get("X-Webhook-Event") != event.Type {
+		wr.t.Errorf("Expected event header %s, got %s", event.Type, r.Header.Get("X-Webhook-Event"))
+	}
+	wr.events = append(wr.events, event)
+}
+
+func (wr *webhookReceiver) types() []string {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	types := make([]string, 0, len(wr.events))
+	for _, event := range wr.events {
+		types = append(types, event.Type+" "+event.Key)
+	}
+	return types
+}
+
+func setupWebhookService(t *testing.T) (*AuthService, http.Handler) {
+	service := setupTestService(t)
+	var err error
+	if service.webhooks, err = newWebhookDispatcher(service.db); err != nil {
+		t.Fatalf("Failed to start webhook dispatcher: %v", err)
+	}
+	service.webhooks.backoff = time.Millisecond
+
+	router := setupTestRouter(service)
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/webhooks", service.createWebhookHandler).Methods("POST")
+	api.HandleFunc("/webhooks", service.getWebhooksHandler).Methods("GET")
+	api.HandleFunc("/webhooks/{id}", service.deleteWebhookHandler).Methods("DELETE")
+	api.HandleFunc("/acl/policies", service.addACLPolicyHandler).Methods("POST")
+	api.HandleFunc("/acl/policies/{id}", service.deleteACLPolicyHandler).Methods("DELETE")
+	api.HandleFunc("/relationships/{id}", service.deleteRelationshipHandler).Methods("DELETE")
+	return service, router
+}
+
+func TestWebhooks_SignedEventsForChanges(t *testing.T) {
+	service, router := setupWebhookService(t)
+	receiver := &webhookReceiver{t: t, failures: 1}
+	target := httptest.NewServer(receiver)
+	defer target.Close()
+
+	send := func(method, url, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Actor", "deployer")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send("POST", "/api/v1/webhooks", `{"url": "`+target.URL+`"}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Failed to register webhook: %d %s", rr.Code, rr.Body.String())
+	}
+	var created struct {
+		Webhook Webhook `json:"webhook"`
+	}
+	json.Unmarshal(rr.Body.Bytes(), &created)
+	if len(created.Webhook.Secret) != 64 {
+		t.Fatalf("Expected a generated secret, got %q", created.Webhook.Secret)
+	}
+	receiver.secret = created.Webhook.Secret
+
+	send("POST", "/api/v1/acl/policies", `{"subject": "alice", "object": "doc1", "action": "read"}`)
+	send("POST", "/api/v1/relationships", `{"subject": "bob", "relationship": "viewer", "object": "doc1"}`)
+	send("PUT", "/api/v1/users/alice/attributes", `{"attributes": {"department": "finance"}}`)
+	send("POST", "/api/v1/abac/policies", `{"id": "finance", "name": "Finance", "effect": "allow"}`)
+	send("DELETE", "/api/v1/relationships/bob:viewer:doc1", "")
+	send("DELETE", "/api/v1/acl/policies/alice:doc1:read", "")
+	service.webhooks.wait()
+
+	// The first delivery failed once and was retried; events arrive in order
+	expected := []string{
+		"acl.added alice:doc1:read",
+		"relationship.added bob:viewer:doc1",
+		"user_attribute.updated alice.department",
+		"abac_policy.added finance",
+		"relationship.removed bob:viewer:doc1",
+		"acl.removed alice:doc1:read",
+	}
+	if got := receiver.types(); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected events:\n%s", strings.Join(got, "\n"))
+	}
+	first := receiver.events[0]
+	if first.Actor != "deployer" || first.ID == "" || first.Data.(map[string]interface{})["effect"] != "allow" {
+		t.Errorf("Unexpected event payload: %+v", first)
+	}
+
+	// Secrets are not listed
+	rr = send("GET", "/api/v1/webhooks", "")
+	if !strings.Contains(rr.Body.String(), created.Webhook.ID) || strings.Contains(rr.Body.String(), created.Webhook.Secret) {
+		t.Errorf("Expected the webhook to be listed without its secret, got %s", rr.Body.String())
+	}
+}
+
+func TestWebhooks_FiltersAndUnregistering(t *testing.T) {
+	service, router := setupWebhookService(t)
+	receiver := &webhookReceiver{t: t, secret: "shared"}
+	target := httptest.NewServer(receiver)
+	defer target.Close()
+
+	register := func(body string) string {
+		req, _ := http.NewRequest("POST", "/api/v1/webhooks", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		var created struct {
+			Webhook Webhook `json:"webhook"`
+		}
+		json.Unmarshal(rr.Body.Bytes(), &created)
+		return created.Webhook.ID
+	}
+	id := register(`{"url": "` + target.URL + `", "secret": "shared", "events": ["relationship", "acl.removed"]}`)
+
+	req, _ := http.NewRequest("POST", "/api/v1/webhooks", strings.NewReader(`{"url": "ftp://example.com"}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a non-HTTP URL, got %d", rr.Code)
+	}
+
+	service.publishChange(changeKindACL, changeAdded, "a:b:c", nil, "x")
+	service.publishChange(changeKindACL, changeRemoved, "a:b:c", nil, "x")
+	service.publishChange(changeKindRelationship, changeAdded, "a:r:b", nil, "x")
+	service.webhooks.wait()
+	if got := receiver.types(); strings.Join(got, ",") != "acl.removed a:b:c,relationship.added a:r:b" {
+		t.Errorf("Expected only subscribed events, got %v", got)
+	}
+
+	req, _ = http.NewRequest("DELETE", "/api/v1/webhooks/"+id, nil)
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Failed to delete webhook: %d", rr.Code)
+	}
+	service.publishChange(changeKindRelationship, changeRemoved, "a:r:b", nil, "x")
+	service.webhooks.wait()
+	if got := receiver.types(); len(got) != 2 {
+		t.Errorf("Expected no events after unregistering, got %v", got)
+	}
+}